@@ -0,0 +1,169 @@
+package acl
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/maurofran/filesystem"
+	"github.com/maurofran/filesystem/internal/glob"
+)
+
+// Principal is the identity on behalf of which operations are performed.
+type Principal struct {
+	ID    string
+	Roles []string
+}
+
+func (p Principal) hasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+type contextKey struct{}
+
+// NewContext will return a copy of provided context carrying the principal.
+func NewContext(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// FromContext will extract the principal from provided context.
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(contextKey{}).(Principal)
+	return p, ok
+}
+
+// Permission enumeration.
+type Permission int
+
+// Permission values (read, write and delete).
+const (
+	PermissionRead Permission = iota + 1
+	PermissionWrite
+	PermissionDelete
+)
+
+var permissions = [...]string{"Read", "Write", "Delete"}
+
+func (p Permission) String() string {
+	return permissions[p-1]
+}
+
+// AccessDeniedError is the error returned when a principal is not allowed to perform an operation.
+type AccessDeniedError interface {
+	error
+	Path() filesystem.Path
+	Principal() Principal
+	Permission() Permission
+}
+
+type accessDeniedError struct {
+	path       filesystem.Path
+	principal  Principal
+	permission Permission
+}
+
+// Path is the path of the file the operation was attempted on.
+func (e accessDeniedError) Path() filesystem.Path {
+	return e.path
+}
+
+// Principal is the principal who attempted the operation.
+func (e accessDeniedError) Principal() Principal {
+	return e.principal
+}
+
+// Permission is the missing permission.
+func (e accessDeniedError) Permission() Permission {
+	return e.permission
+}
+
+func (e accessDeniedError) Error() string {
+	return fmt.Sprintf("Principal %q has no %s permission on path %s", e.principal.ID, e.permission, e.path)
+}
+
+// IsAccessDeniedError will check if provided error is an access denied error.
+func IsAccessDeniedError(err error) bool {
+	_, ok := err.(AccessDeniedError)
+	return ok
+}
+
+// Authorizer is the interface exposed by objects deciding whether a principal may access a path.
+type Authorizer interface {
+	// Authorize will return an AccessDeniedError if the principal lacks permission on path.
+	Authorize(p Principal, perm Permission, path filesystem.Path) error
+}
+
+// Rule grants permissions on paths matching a pattern to principals or roles.
+type Rule struct {
+	// Pattern is the glob the path must match; "**" spans directories and "{principal}" is replaced with the
+	// principal ID, e.g. "tenants/{principal}/**".
+	Pattern string
+	// Principals are the IDs of principals the rule applies to; "*" stands for any authenticated principal.
+	Principals []string
+	// Roles are the roles the rule applies to.
+	Roles []string
+	// Permissions are the granted permissions.
+	Permissions []Permission
+}
+
+func (r Rule) appliesTo(p Principal) bool {
+	for _, id := range r.Principals {
+		if id == p.ID || id == "*" && p.ID != "" {
+			return true
+		}
+	}
+	for _, role := range r.Roles {
+		if p.hasRole(role) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Rule) grants(perm Permission) bool {
+	for _, g := range r.Permissions {
+		if g == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// Rules is an authorizer denying everything not explicitly granted by one of its rules.
+type Rules []Rule
+
+// NewRules will validate provided rules and return them as an authorizer.
+func NewRules(rules ...Rule) (Rules, error) {
+	for _, r := range rules {
+		if err := glob.Validate(strings.Replace(r.Pattern, "{principal}", "x", -1)); err != nil {
+			return nil, err
+		}
+	}
+	return Rules(rules), nil
+}
+
+// Authorize will check if any of the rules grants the permission on path to the principal. The principal ID is
+// matched literally in place of "{principal}", so rules using it never apply to empty IDs or IDs holding a slash.
+func (rs Rules) Authorize(p Principal, perm Permission, path filesystem.Path) error {
+	for _, r := range rs {
+		if !r.grants(perm) || !r.appliesTo(p) {
+			continue
+		}
+		pattern := r.Pattern
+		if strings.Contains(pattern, "{principal}") {
+			if p.ID == "" || strings.Contains(p.ID, "/") {
+				continue
+			}
+			pattern = strings.Replace(pattern, "{principal}", glob.Escape(p.ID), -1)
+		}
+		if glob.Match(pattern, string(path)) {
+			return nil
+		}
+	}
+	return accessDeniedError{path, p, perm}
+}
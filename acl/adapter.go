@@ -0,0 +1,214 @@
+package acl
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/maurofran/filesystem"
+)
+
+// Wrap will decorate provided adapter so that every operation is authorized for the principal carried by the
+// context of the call; without a principal in context operations are authorized for the zero principal.
+func Wrap(a filesystem.Adapter, auth Authorizer) filesystem.Adapter {
+	return &adapter{adapter: a, auth: auth}
+}
+
+type adapter struct {
+	adapter filesystem.Adapter
+	auth    Authorizer
+}
+
+func (a *adapter) authorize(ctx context.Context, perm Permission, path filesystem.Path) error {
+	p, _ := FromContext(ctx)
+	return a.auth.Authorize(p, perm, path)
+}
+
+func (a *adapter) Has(ctx context.Context, path filesystem.Path) (bool, error) {
+	if err := a.authorize(ctx, PermissionRead, path); err != nil {
+		return false, err
+	}
+	return a.adapter.Has(ctx, path)
+}
+
+func (a *adapter) Read(ctx context.Context, path filesystem.Path) ([]byte, error) {
+	if err := a.authorize(ctx, PermissionRead, path); err != nil {
+		return nil, err
+	}
+	return a.adapter.Read(ctx, path)
+}
+
+func (a *adapter) ReadStream(ctx context.Context, path filesystem.Path) (io.ReadCloser, error) {
+	if err := a.authorize(ctx, PermissionRead, path); err != nil {
+		return nil, err
+	}
+	return a.adapter.ReadStream(ctx, path)
+}
+
+func (a *adapter) Write(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	if err := a.authorize(ctx, PermissionWrite, path); err != nil {
+		return err
+	}
+	return a.adapter.Write(ctx, path, content, cfg)
+}
+
+func (a *adapter) WriteStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	if err := a.authorize(ctx, PermissionWrite, path); err != nil {
+		return err
+	}
+	return a.adapter.WriteStream(ctx, path, r, cfg)
+}
+
+func (a *adapter) Update(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	if err := a.authorize(ctx, PermissionWrite, path); err != nil {
+		return err
+	}
+	return a.adapter.Update(ctx, path, content, cfg)
+}
+
+func (a *adapter) UpdateStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	if err := a.authorize(ctx, PermissionWrite, path); err != nil {
+		return err
+	}
+	return a.adapter.UpdateStream(ctx, path, r, cfg)
+}
+
+func (a *adapter) Put(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	if err := a.authorize(ctx, PermissionWrite, path); err != nil {
+		return err
+	}
+	return a.adapter.Put(ctx, path, content, cfg)
+}
+
+func (a *adapter) PutStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	if err := a.authorize(ctx, PermissionWrite, path); err != nil {
+		return err
+	}
+	return a.adapter.PutStream(ctx, path, r, cfg)
+}
+
+func (a *adapter) Delete(ctx context.Context, path filesystem.Path) error {
+	if err := a.authorize(ctx, PermissionDelete, path); err != nil {
+		return err
+	}
+	return a.adapter.Delete(ctx, path)
+}
+
+func (a *adapter) ReadAndDelete(ctx context.Context, path filesystem.Path) ([]byte, error) {
+	if err := a.authorize(ctx, PermissionRead, path); err != nil {
+		return nil, err
+	}
+	if err := a.authorize(ctx, PermissionDelete, path); err != nil {
+		return nil, err
+	}
+	return a.adapter.ReadAndDelete(ctx, path)
+}
+
+func (a *adapter) Move(ctx context.Context, path, newpath filesystem.Path) error {
+	if err := a.authorize(ctx, PermissionRead, path); err != nil {
+		return err
+	}
+	if err := a.authorize(ctx, PermissionDelete, path); err != nil {
+		return err
+	}
+	if err := a.authorize(ctx, PermissionWrite, newpath); err != nil {
+		return err
+	}
+	return a.adapter.Move(ctx, path, newpath)
+}
+
+func (a *adapter) Copy(ctx context.Context, path, newpath filesystem.Path) error {
+	if err := a.authorize(ctx, PermissionRead, path); err != nil {
+		return err
+	}
+	if err := a.authorize(ctx, PermissionWrite, newpath); err != nil {
+		return err
+	}
+	return a.adapter.Copy(ctx, path, newpath)
+}
+
+func (a *adapter) GetMimeType(ctx context.Context, path filesystem.Path) (string, error) {
+	if err := a.authorize(ctx, PermissionRead, path); err != nil {
+		return "", err
+	}
+	return a.adapter.GetMimeType(ctx, path)
+}
+
+func (a *adapter) GetTimestamp(ctx context.Context, path filesystem.Path) (time.Time, error) {
+	if err := a.authorize(ctx, PermissionRead, path); err != nil {
+		return time.Time{}, err
+	}
+	return a.adapter.GetTimestamp(ctx, path)
+}
+
+func (a *adapter) GetFileSize(ctx context.Context, path filesystem.Path) (int64, error) {
+	if err := a.authorize(ctx, PermissionRead, path); err != nil {
+		return 0, err
+	}
+	return a.adapter.GetFileSize(ctx, path)
+}
+
+func (a *adapter) GetMetadata(ctx context.Context, path filesystem.Path) (filesystem.Metadata, error) {
+	if err := a.authorize(ctx, PermissionRead, path); err != nil {
+		return nil, err
+	}
+	return a.adapter.GetMetadata(ctx, path)
+}
+
+func (a *adapter) CreateDir(ctx context.Context, path filesystem.Path, cfg filesystem.Config) error {
+	if err := a.authorize(ctx, PermissionWrite, path); err != nil {
+		return err
+	}
+	return a.adapter.CreateDir(ctx, path, cfg)
+}
+
+// DeleteDir will delete the directory at path if the principal is allowed to delete it and every entry it contains.
+func (a *adapter) DeleteDir(ctx context.Context, path filesystem.Path) error {
+	if err := a.authorize(ctx, PermissionDelete, path); err != nil {
+		return err
+	}
+	listing, err := a.adapter.ListContents(ctx, path, true)
+	if err != nil && !filesystem.IsFileNotFound(err) {
+		return err
+	}
+	for _, md := range listing {
+		if err := a.authorize(ctx, PermissionDelete, md.Path); err != nil {
+			return err
+		}
+	}
+	return a.adapter.DeleteDir(ctx, path)
+}
+
+func (a *adapter) GetVisibility(ctx context.Context, path filesystem.Path) (filesystem.Visibility, error) {
+	if err := a.authorize(ctx, PermissionRead, path); err != nil {
+		return "", err
+	}
+	return a.adapter.GetVisibility(ctx, path)
+}
+
+func (a *adapter) SetVisibility(ctx context.Context, path filesystem.Path, v filesystem.Visibility) error {
+	if err := a.authorize(ctx, PermissionWrite, path); err != nil {
+		return err
+	}
+	return a.adapter.SetVisibility(ctx, path, v)
+}
+
+// ListContents will list the entries of path the principal is allowed to read.
+func (a *adapter) ListContents(ctx context.Context, path filesystem.Path, recursive bool) ([]filesystem.Metadata, error) {
+	listing, err := a.adapter.ListContents(ctx, path, recursive)
+	if err != nil {
+		return nil, err
+	}
+	var result []filesystem.Metadata
+	for _, md := range listing {
+		if a.authorize(ctx, PermissionRead, md.Path) == nil {
+			result = append(result, md)
+		}
+	}
+	return result, nil
+}
+
+// Close will close the decorated adapter.
+func (a *adapter) Close() error {
+	return filesystem.Close(a.adapter)
+}
@@ -1,54 +1,57 @@
 package filesystem
 
 import (
+	"context"
 	"io"
 	"time"
 )
 
 // Adapter is interface exposed by objects who provide access to underlying file system.
+//
+// Every method accepts a context, which adapters should honor for cancellation and deadlines of remote calls.
 type Adapter interface {
 	// Has will check if a file exists.
-	Has(path Path) (bool, error)
+	Has(ctx context.Context, path Path) (bool, error)
 	// Read the file at provided path.
-	Read(path Path) (string, error)
+	Read(ctx context.Context, path Path) ([]byte, error)
 	// ReadStream will read the file at provided path as a stream.
-	ReadStream(path Path) (io.ReadCloser, error)
+	ReadStream(ctx context.Context, path Path) (io.ReadCloser, error)
 	// Write the supplied content at supplied path, creating the file.
-	Write(path Path, content string, cfg Config) error
+	Write(ctx context.Context, path Path, content []byte, cfg Config) error
 	// WriteStream will write the content of provided reader at supplied path, creating the file.
-	WriteStream(path Path, r io.Reader, cfg Config) error
+	WriteStream(ctx context.Context, path Path, r io.Reader, cfg Config) error
 	// Update the supplied content at supplied path, returning an error if file does not exists.
-	Update(path Path, content string, cfg Config) error
+	Update(ctx context.Context, path Path, content []byte, cfg Config) error
 	// Update with the content of supplied reader at supplied path, returning an error if file does not exists
-	UpdateStream(path Path, r io.Reader, cfg Config) error
+	UpdateStream(ctx context.Context, path Path, r io.Reader, cfg Config) error
 	// Put the supplied content at supplied path, creating the file if does not exists.
-	Put(path Path, content string, cfg Config) error
+	Put(ctx context.Context, path Path, content []byte, cfg Config) error
 	// Puth the content of supplied reader at supplied path, creating the file if does not exists.
-	PutStream(path Path, r io.Reader, cfg Config) error
+	PutStream(ctx context.Context, path Path, r io.Reader, cfg Config) error
 	// Deletes a file at provided path.
-	Delete(path Path) error
+	Delete(ctx context.Context, path Path) error
 	// ReadAndDelete will read the file at provided path and delete after read.
-	ReadAndDelete(path Path) (string, error)
+	ReadAndDelete(ctx context.Context, path Path) ([]byte, error)
 	// Move the file at supplied path to new path.
-	Move(path, newpath Path) error
+	Move(ctx context.Context, path, newpath Path) error
 	// Copy the file at supplied path to new path.
-	Copy(path, newpath Path) error
+	Copy(ctx context.Context, path, newpath Path) error
 	// GetMimeType will retrieve the mime type of file at supplied path.
-	GetMimeType(path Path) (string, error)
+	GetMimeType(ctx context.Context, path Path) (string, error)
 	// GetTimestamp will retrieve the timestamp of file at supplied path.
-	GetTimestamp(path Path) (time.Time, error)
+	GetTimestamp(ctx context.Context, path Path) (time.Time, error)
 	// GetFileSize will retrieve the size of file at supplied path.
-	GetFileSize(path Path) (int64, error)
+	GetFileSize(ctx context.Context, path Path) (int64, error)
 	// GetMetadata will retrieve the metadata of file at supplied path.
-	GetMetadata(path Path) (Metadata, error)
+	GetMetadata(ctx context.Context, path Path) (Metadata, error)
 	// CreateDir will create a new directory at provided path.
-	CreateDir(path Path, cfg Config) error
+	CreateDir(ctx context.Context, path Path, cfg Config) error
 	// DeleteDir will delete the directory at provided path.
-	DeleteDir(path Path) error
+	DeleteDir(ctx context.Context, path Path) error
 	// Get the visibility of file at supplied path.
-	GetVisibility(path Path) (Visibility, error)
+	GetVisibility(ctx context.Context, path Path) (Visibility, error)
 	// Set the visibility of file at supplied path.
-	SetVisibility(path Path, v Visibility) error
+	SetVisibility(ctx context.Context, path Path, v Visibility) error
 	// List the contents of given path.
-	ListContents(path Path, recursive bool) ([]Metadata, error)
+	ListContents(ctx context.Context, path Path, recursive bool) ([]Metadata, error)
 }
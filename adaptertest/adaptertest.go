@@ -0,0 +1,788 @@
+// Package adaptertest provides an acceptance suite for filesystem.Adapter implementations.
+//
+// Adapter authors call Run from their own tests, handing it a factory of empty adapters:
+//
+//	func TestAdapter(t *testing.T) {
+//		adaptertest.Run(t, func(t *testing.T) filesystem.Adapter {
+//			return myadapter.New(t.TempDir())
+//		})
+//	}
+//
+// The optional interfaces the adapter implements are checked too, their tests being skipped otherwise.
+package adaptertest
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maurofran/filesystem"
+)
+
+// Factory creates a new empty adapter for a test. Any cleanup should be registered with t.Cleanup.
+type Factory func(t *testing.T) filesystem.Adapter
+
+var cfg = filesystem.Config{}
+
+type test struct {
+	name string
+	fn   func(ctx context.Context, t *testing.T, a filesystem.Adapter)
+}
+
+var tests = []test{
+	{"WriteAndRead", testWriteAndRead},
+	{"WriteEmptyFile", testWriteEmptyFile},
+	{"WriteBinaryContent", testWriteBinaryContent},
+	{"WriteCreatesParentDirectories", testWriteCreatesParentDirectories},
+	{"WriteStreamAndReadStream", testWriteStreamAndReadStream},
+	{"Has", testHas},
+	{"ReadMissingFile", testReadMissingFile},
+	{"ReadStreamMissingFile", testReadStreamMissingFile},
+	{"Update", testUpdate},
+	{"UpdateMissingFile", testUpdateMissingFile},
+	{"UpdateStream", testUpdateStream},
+	{"Put", testPut},
+	{"PutStream", testPutStream},
+	{"Delete", testDelete},
+	{"DeleteMissingFile", testDeleteMissingFile},
+	{"ReadAndDelete", testReadAndDelete},
+	{"Move", testMove},
+	{"Copy", testCopy},
+	{"GetFileSize", testGetFileSize},
+	{"GetMimeType", testGetMimeType},
+	{"GetTimestamp", testGetTimestamp},
+	{"GetMetadata", testGetMetadata},
+	{"MetadataOfMissingFile", testMetadataOfMissingFile},
+	{"Visibility", testVisibility},
+	{"CreateDir", testCreateDir},
+	{"DeleteDir", testDeleteDir},
+	{"ListContents", testListContents},
+	{"ListContentsRecursive", testListContentsRecursive},
+	{"ListContentsMetadata", testListContentsMetadata},
+	{"NormalizedPaths", testNormalizedPaths},
+	{"WriteExistingFile", testWriteExistingFile},
+	{"Overwrite", testOverwrite},
+	{"CopyOverExistingFile", testCopyOverExistingFile},
+	{"RangeReader", testRangeReader},
+	{"ChecksumProvider", testChecksumProvider},
+	{"UserMetadataProvider", testUserMetadataProvider},
+	{"ConditionalWriter", testConditionalWriter},
+	{"AtomicWriter", testAtomicWriter},
+	{"RandomWriter", testRandomWriter},
+	{"DirCopier", testDirCopier},
+	{"DirMover", testDirMover},
+	{"ContentsIterator", testContentsIterator},
+	{"PageLister", testPageLister},
+	{"Versioned", testVersioned},
+}
+
+// Run will run the whole acceptance suite, each test against a new adapter.
+func Run(t *testing.T, factory Factory) {
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			tc.fn(context.Background(), t, factory(t))
+		})
+	}
+}
+
+func write(ctx context.Context, t *testing.T, a filesystem.Adapter, path filesystem.Path, content string) {
+	t.Helper()
+	if err := a.Write(ctx, path, []byte(content), cfg); err != nil {
+		t.Fatalf("Write(%q) failed: %v", path, err)
+	}
+}
+
+func assertContent(ctx context.Context, t *testing.T, a filesystem.Adapter, path filesystem.Path, expected string) {
+	t.Helper()
+	content, err := a.Read(ctx, path)
+	if err != nil {
+		t.Fatalf("Read(%q) failed: %v", path, err)
+	}
+	if string(content) != expected {
+		t.Fatalf("Read(%q) = %q, expected %q", path, content, expected)
+	}
+}
+
+func assertHas(ctx context.Context, t *testing.T, a filesystem.Adapter, path filesystem.Path, expected bool) {
+	t.Helper()
+	has, err := a.Has(ctx, path)
+	if err != nil {
+		t.Fatalf("Has(%q) failed: %v", path, err)
+	}
+	if has != expected {
+		t.Fatalf("Has(%q) = %v, expected %v", path, has, expected)
+	}
+}
+
+func assertFileNotFound(t *testing.T, op string, err error) {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("%s succeeded on a missing file", op)
+	}
+	if !filesystem.IsFileNotFound(err) {
+		t.Fatalf("%s failed with %v, expected a FileNotFoundError", op, err)
+	}
+}
+
+func listPaths(ctx context.Context, t *testing.T, a filesystem.Adapter, path filesystem.Path, recursive bool) []string {
+	t.Helper()
+	listing, err := a.ListContents(ctx, path, recursive)
+	if err != nil {
+		t.Fatalf("ListContents(%q, %v) failed: %v", path, recursive, err)
+	}
+	paths := make([]string, 0, len(listing))
+	for _, md := range listing {
+		paths = append(paths, string(md.Path))
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func readAll(t *testing.T, op string, rc io.ReadCloser, err error) string {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("%s failed: %v", op, err)
+	}
+	defer rc.Close()
+	b, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("reading %s failed: %v", op, err)
+	}
+	return string(b)
+}
+
+// skipUnsupported will skip the test if err is an UnsupportedOperationError, adapters being allowed to implement
+// optional interfaces for part of their configurations only.
+func skipUnsupported(t *testing.T, op string, err error) {
+	t.Helper()
+	if filesystem.IsUnsupportedOperationError(err) {
+		t.Skipf("%s is not supported: %v", op, err)
+	}
+}
+
+func testWriteAndRead(ctx context.Context, t *testing.T, a filesystem.Adapter) {
+	write(ctx, t, a, "file.txt", "content")
+	assertContent(ctx, t, a, "file.txt", "content")
+}
+
+func testWriteEmptyFile(ctx context.Context, t *testing.T, a filesystem.Adapter) {
+	write(ctx, t, a, "empty.txt", "")
+	assertHas(ctx, t, a, "empty.txt", true)
+	assertContent(ctx, t, a, "empty.txt", "")
+}
+
+func testWriteBinaryContent(ctx context.Context, t *testing.T, a filesystem.Adapter) {
+	b := make([]byte, 256)
+	for i := range b {
+		b[i] = byte(i)
+	}
+	write(ctx, t, a, "binary.bin", string(b))
+	assertContent(ctx, t, a, "binary.bin", string(b))
+}
+
+func testWriteCreatesParentDirectories(ctx context.Context, t *testing.T, a filesystem.Adapter) {
+	write(ctx, t, a, "a/b/c/file.txt", "nested")
+	assertContent(ctx, t, a, "a/b/c/file.txt", "nested")
+}
+
+func testWriteStreamAndReadStream(ctx context.Context, t *testing.T, a filesystem.Adapter) {
+	content := strings.Repeat("stream", 100000)
+	if err := a.WriteStream(ctx, "stream.txt", strings.NewReader(content), cfg); err != nil {
+		t.Fatalf("WriteStream failed: %v", err)
+	}
+	r, err := a.ReadStream(ctx, "stream.txt")
+	if err != nil {
+		t.Fatalf("ReadStream failed: %v", err)
+	}
+	defer r.Close()
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("reading stream failed: %v", err)
+	}
+	if string(b) != content {
+		t.Fatalf("ReadStream returned %d bytes, expected %d", len(b), len(content))
+	}
+}
+
+func testHas(ctx context.Context, t *testing.T, a filesystem.Adapter) {
+	assertHas(ctx, t, a, "file.txt", false)
+	write(ctx, t, a, "file.txt", "content")
+	assertHas(ctx, t, a, "file.txt", true)
+}
+
+func testReadMissingFile(ctx context.Context, t *testing.T, a filesystem.Adapter) {
+	_, err := a.Read(ctx, "missing.txt")
+	assertFileNotFound(t, "Read", err)
+}
+
+func testReadStreamMissingFile(ctx context.Context, t *testing.T, a filesystem.Adapter) {
+	r, err := a.ReadStream(ctx, "missing.txt")
+	if err == nil {
+		r.Close()
+	}
+	assertFileNotFound(t, "ReadStream", err)
+}
+
+func testUpdate(ctx context.Context, t *testing.T, a filesystem.Adapter) {
+	write(ctx, t, a, "file.txt", "content")
+	if err := a.Update(ctx, "file.txt", []byte("updated"), cfg); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	assertContent(ctx, t, a, "file.txt", "updated")
+}
+
+func testUpdateMissingFile(ctx context.Context, t *testing.T, a filesystem.Adapter) {
+	assertFileNotFound(t, "Update", a.Update(ctx, "missing.txt", []byte("content"), cfg))
+	assertHas(ctx, t, a, "missing.txt", false)
+}
+
+func testUpdateStream(ctx context.Context, t *testing.T, a filesystem.Adapter) {
+	write(ctx, t, a, "file.txt", "content")
+	if err := a.UpdateStream(ctx, "file.txt", strings.NewReader("updated"), cfg); err != nil {
+		t.Fatalf("UpdateStream failed: %v", err)
+	}
+	assertContent(ctx, t, a, "file.txt", "updated")
+	assertFileNotFound(t, "UpdateStream", a.UpdateStream(ctx, "missing.txt", strings.NewReader("x"), cfg))
+}
+
+func testPut(ctx context.Context, t *testing.T, a filesystem.Adapter) {
+	if err := a.Put(ctx, "file.txt", []byte("created"), cfg); err != nil {
+		t.Fatalf("Put on a missing file failed: %v", err)
+	}
+	assertContent(ctx, t, a, "file.txt", "created")
+	if err := a.Put(ctx, "file.txt", []byte("replaced"), cfg); err != nil {
+		t.Fatalf("Put on an existing file failed: %v", err)
+	}
+	assertContent(ctx, t, a, "file.txt", "replaced")
+}
+
+func testPutStream(ctx context.Context, t *testing.T, a filesystem.Adapter) {
+	if err := a.PutStream(ctx, "file.txt", strings.NewReader("created"), cfg); err != nil {
+		t.Fatalf("PutStream on a missing file failed: %v", err)
+	}
+	assertContent(ctx, t, a, "file.txt", "created")
+	if err := a.PutStream(ctx, "file.txt", strings.NewReader("replaced"), cfg); err != nil {
+		t.Fatalf("PutStream on an existing file failed: %v", err)
+	}
+	assertContent(ctx, t, a, "file.txt", "replaced")
+}
+
+func testDelete(ctx context.Context, t *testing.T, a filesystem.Adapter) {
+	write(ctx, t, a, "file.txt", "content")
+	if err := a.Delete(ctx, "file.txt"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	assertHas(ctx, t, a, "file.txt", false)
+}
+
+func testDeleteMissingFile(ctx context.Context, t *testing.T, a filesystem.Adapter) {
+	assertFileNotFound(t, "Delete", a.Delete(ctx, "missing.txt"))
+}
+
+func testReadAndDelete(ctx context.Context, t *testing.T, a filesystem.Adapter) {
+	write(ctx, t, a, "file.txt", "content")
+	content, err := a.ReadAndDelete(ctx, "file.txt")
+	if err != nil {
+		t.Fatalf("ReadAndDelete failed: %v", err)
+	}
+	if string(content) != "content" {
+		t.Fatalf("ReadAndDelete = %q, expected %q", content, "content")
+	}
+	assertHas(ctx, t, a, "file.txt", false)
+	_, err = a.ReadAndDelete(ctx, "file.txt")
+	assertFileNotFound(t, "ReadAndDelete", err)
+}
+
+func testMove(ctx context.Context, t *testing.T, a filesystem.Adapter) {
+	write(ctx, t, a, "source.txt", "content")
+	if err := a.Move(ctx, "source.txt", "dir/target.txt"); err != nil {
+		t.Fatalf("Move failed: %v", err)
+	}
+	assertHas(ctx, t, a, "source.txt", false)
+	assertContent(ctx, t, a, "dir/target.txt", "content")
+	assertFileNotFound(t, "Move", a.Move(ctx, "source.txt", "other.txt"))
+}
+
+func testCopy(ctx context.Context, t *testing.T, a filesystem.Adapter) {
+	write(ctx, t, a, "source.txt", "content")
+	if err := a.Copy(ctx, "source.txt", "dir/target.txt"); err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+	assertContent(ctx, t, a, "source.txt", "content")
+	assertContent(ctx, t, a, "dir/target.txt", "content")
+	assertFileNotFound(t, "Copy", a.Copy(ctx, "missing.txt", "other.txt"))
+}
+
+func testGetFileSize(ctx context.Context, t *testing.T, a filesystem.Adapter) {
+	write(ctx, t, a, "file.txt", "content")
+	size, err := a.GetFileSize(ctx, "file.txt")
+	if err != nil {
+		t.Fatalf("GetFileSize failed: %v", err)
+	}
+	if size != int64(len("content")) {
+		t.Fatalf("GetFileSize = %d, expected %d", size, len("content"))
+	}
+}
+
+func testGetMimeType(ctx context.Context, t *testing.T, a filesystem.Adapter) {
+	write(ctx, t, a, "file.txt", "plain text content")
+	mimeType, err := a.GetMimeType(ctx, "file.txt")
+	if err != nil {
+		t.Fatalf("GetMimeType failed: %v", err)
+	}
+	if !strings.HasPrefix(mimeType, "text/plain") {
+		t.Fatalf("GetMimeType = %q, expected text/plain", mimeType)
+	}
+}
+
+func testGetTimestamp(ctx context.Context, t *testing.T, a filesystem.Adapter) {
+	before := time.Now().Add(-time.Hour)
+	write(ctx, t, a, "file.txt", "content")
+	ts, err := a.GetTimestamp(ctx, "file.txt")
+	if err != nil {
+		t.Fatalf("GetTimestamp failed: %v", err)
+	}
+	if ts.Before(before) || ts.After(time.Now().Add(time.Hour)) {
+		t.Fatalf("GetTimestamp = %v, expected a time close to now", ts)
+	}
+}
+
+func testGetMetadata(ctx context.Context, t *testing.T, a filesystem.Adapter) {
+	write(ctx, t, a, "dir/file.txt", "content")
+	md, err := a.GetMetadata(ctx, "dir/file.txt")
+	if err != nil {
+		t.Fatalf("GetMetadata failed: %v", err)
+	}
+	if md.Path != "dir/file.txt" {
+		t.Fatalf("metadata path = %q, expected %q", md.Path, "dir/file.txt")
+	}
+	if md.Type != filesystem.TypeFile {
+		t.Fatalf("metadata type = %q, expected %q", md.Type, filesystem.TypeFile)
+	}
+}
+
+func testMetadataOfMissingFile(ctx context.Context, t *testing.T, a filesystem.Adapter) {
+	_, err := a.GetMetadata(ctx, "missing.txt")
+	assertFileNotFound(t, "GetMetadata", err)
+	_, err = a.GetFileSize(ctx, "missing.txt")
+	assertFileNotFound(t, "GetFileSize", err)
+	_, err = a.GetTimestamp(ctx, "missing.txt")
+	assertFileNotFound(t, "GetTimestamp", err)
+	_, err = a.GetVisibility(ctx, "missing.txt")
+	assertFileNotFound(t, "GetVisibility", err)
+}
+
+func testVisibility(ctx context.Context, t *testing.T, a filesystem.Adapter) {
+	write(ctx, t, a, "file.txt", "content")
+	for _, v := range []filesystem.Visibility{filesystem.VisibilityPrivate, filesystem.VisibilityPublic} {
+		if err := a.SetVisibility(ctx, "file.txt", v); err != nil {
+			t.Fatalf("SetVisibility(%v) failed: %v", v, err)
+		}
+		got, err := a.GetVisibility(ctx, "file.txt")
+		if err != nil {
+			t.Fatalf("GetVisibility failed: %v", err)
+		}
+		if got != v {
+			t.Fatalf("GetVisibility = %v, expected %v", got, v)
+		}
+	}
+	assertFileNotFound(t, "SetVisibility", a.SetVisibility(ctx, "missing.txt", filesystem.VisibilityPublic))
+}
+
+func testCreateDir(ctx context.Context, t *testing.T, a filesystem.Adapter) {
+	if err := a.CreateDir(ctx, "dir/sub", cfg); err != nil {
+		t.Fatalf("CreateDir failed: %v", err)
+	}
+	write(ctx, t, a, "dir/sub/file.txt", "content")
+	paths := listPaths(ctx, t, a, "dir", false)
+	if len(paths) != 1 || paths[0] != "dir/sub" {
+		t.Fatalf("ListContents(dir) = %v, expected [dir/sub]", paths)
+	}
+}
+
+func testDeleteDir(ctx context.Context, t *testing.T, a filesystem.Adapter) {
+	write(ctx, t, a, "dir/file.txt", "content")
+	write(ctx, t, a, "dir/sub/file.txt", "content")
+	write(ctx, t, a, "other.txt", "content")
+	if err := a.DeleteDir(ctx, "dir"); err != nil {
+		t.Fatalf("DeleteDir failed: %v", err)
+	}
+	assertHas(ctx, t, a, "dir/file.txt", false)
+	assertHas(ctx, t, a, "dir/sub/file.txt", false)
+	assertHas(ctx, t, a, "other.txt", true)
+}
+
+func testListContents(ctx context.Context, t *testing.T, a filesystem.Adapter) {
+	write(ctx, t, a, "a.txt", "a")
+	write(ctx, t, a, "dir/b.txt", "b")
+	write(ctx, t, a, "dir/sub/c.txt", "c")
+	paths := listPaths(ctx, t, a, filesystem.RootPath, false)
+	if strings.Join(paths, ",") != "a.txt,dir" {
+		t.Fatalf("ListContents(root) = %v, expected [a.txt dir]", paths)
+	}
+	paths = listPaths(ctx, t, a, "dir", false)
+	if strings.Join(paths, ",") != "dir/b.txt,dir/sub" {
+		t.Fatalf("ListContents(dir) = %v, expected [dir/b.txt dir/sub]", paths)
+	}
+}
+
+func testListContentsRecursive(ctx context.Context, t *testing.T, a filesystem.Adapter) {
+	write(ctx, t, a, "a.txt", "a")
+	write(ctx, t, a, "dir/b.txt", "b")
+	write(ctx, t, a, "dir/sub/c.txt", "c")
+	paths := listPaths(ctx, t, a, "dir", true)
+	if strings.Join(paths, ",") != "dir/b.txt,dir/sub,dir/sub/c.txt" {
+		t.Fatalf("ListContents(dir, recursive) = %v, expected [dir/b.txt dir/sub dir/sub/c.txt]", paths)
+	}
+}
+
+func testListContentsMetadata(ctx context.Context, t *testing.T, a filesystem.Adapter) {
+	write(ctx, t, a, "dir/file.txt", "content")
+	listing, err := a.ListContents(ctx, filesystem.RootPath, true)
+	if err != nil {
+		t.Fatalf("ListContents failed: %v", err)
+	}
+	for _, md := range listing {
+		expected := filesystem.TypeFile
+		if md.Path == "dir" {
+			expected = filesystem.TypeDir
+		}
+		if md.Type != expected {
+			t.Fatalf("type of %q = %q, expected %q", md.Path, md.Type, expected)
+		}
+		if expected == filesystem.TypeFile && md.Size != int64(len("content")) {
+			t.Fatalf("size of %q = %d, expected %d", md.Path, md.Size, len("content"))
+		}
+	}
+}
+
+func testNormalizedPaths(ctx context.Context, t *testing.T, a filesystem.Adapter) {
+	if err := a.CreateDir(ctx, "dir/empty", cfg); err != nil {
+		t.Fatalf("CreateDir failed: %v", err)
+	}
+	write(ctx, t, a, "dir/sub/file.txt", "content")
+	listing, err := a.ListContents(ctx, filesystem.RootPath, true)
+	if err != nil {
+		t.Fatalf("ListContents failed: %v", err)
+	}
+	for _, md := range listing {
+		if p, err := filesystem.NewPath(string(md.Path)); err != nil || p != md.Path {
+			t.Fatalf("ListContents returned path %q, which is not normalized", md.Path)
+		}
+	}
+	md, err := a.GetMetadata(ctx, "dir/sub/file.txt")
+	if err != nil {
+		t.Fatalf("GetMetadata failed: %v", err)
+	}
+	if md.Path != "dir/sub/file.txt" {
+		t.Fatalf("metadata path = %q, expected %q", md.Path, "dir/sub/file.txt")
+	}
+}
+
+func testWriteExistingFile(ctx context.Context, t *testing.T, a filesystem.Adapter) {
+	write(ctx, t, a, "file.txt", "content")
+	// Adapters either replace the file or refuse to write it, leaving it untouched.
+	expected := "content"
+	if err := a.Write(ctx, "file.txt", []byte("rewritten"), cfg); err == nil {
+		expected = "rewritten"
+	}
+	assertContent(ctx, t, a, "file.txt", expected)
+}
+
+func testOverwrite(ctx context.Context, t *testing.T, a filesystem.Adapter) {
+	write(ctx, t, a, "file.txt", "a longer content")
+	if err := a.Update(ctx, "file.txt", []byte("short"), cfg); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	assertContent(ctx, t, a, "file.txt", "short")
+	if err := a.PutStream(ctx, "file.txt", strings.NewReader("tiny"), cfg); err != nil {
+		t.Fatalf("PutStream failed: %v", err)
+	}
+	assertContent(ctx, t, a, "file.txt", "tiny")
+	size, err := a.GetFileSize(ctx, "file.txt")
+	if err != nil {
+		t.Fatalf("GetFileSize failed: %v", err)
+	}
+	if size != int64(len("tiny")) {
+		t.Fatalf("GetFileSize = %d after overwrite, expected %d", size, len("tiny"))
+	}
+}
+
+func testCopyOverExistingFile(ctx context.Context, t *testing.T, a filesystem.Adapter) {
+	write(ctx, t, a, "source.txt", "source")
+	write(ctx, t, a, "target.txt", "a longer target")
+	if err := a.Copy(ctx, "source.txt", "target.txt"); err != nil {
+		t.Fatalf("Copy over an existing file failed: %v", err)
+	}
+	assertContent(ctx, t, a, "target.txt", "source")
+}
+
+func testRangeReader(ctx context.Context, t *testing.T, a filesystem.Adapter) {
+	rr, ok := a.(filesystem.RangeReader)
+	if !ok {
+		t.Skip("adapter is not a RangeReader")
+	}
+	write(ctx, t, a, "file.txt", "0123456789")
+	for _, tc := range []struct {
+		offset, length int64
+		expected       string
+	}{{0, 3, "012"}, {4, 2, "45"}, {7, -1, "789"}, {8, 5, "89"}, {10, 1, ""}} {
+		rc, err := rr.ReadRange(ctx, "file.txt", tc.offset, tc.length)
+		if got := readAll(t, "ReadRange", rc, err); got != tc.expected {
+			t.Fatalf("ReadRange(%d, %d) = %q, expected %q", tc.offset, tc.length, got, tc.expected)
+		}
+	}
+	_, err := rr.ReadRange(ctx, "missing.txt", 0, 1)
+	assertFileNotFound(t, "ReadRange", err)
+}
+
+func testChecksumProvider(ctx context.Context, t *testing.T, a filesystem.Adapter) {
+	cp, ok := a.(filesystem.ChecksumProvider)
+	if !ok {
+		t.Skip("adapter is not a ChecksumProvider")
+	}
+	write(ctx, t, a, "file.txt", "content")
+	sum, err := cp.GetChecksum(ctx, "file.txt", filesystem.ChecksumMD5)
+	if filesystem.IsUnsupportedChecksumError(err) {
+		t.Skipf("no MD5 checksum: %v", err)
+	}
+	if err != nil {
+		t.Fatalf("GetChecksum failed: %v", err)
+	}
+	expected := md5.Sum([]byte("content"))
+	if sum != hex.EncodeToString(expected[:]) {
+		t.Fatalf("GetChecksum = %q, expected %q", sum, hex.EncodeToString(expected[:]))
+	}
+}
+
+func testUserMetadataProvider(ctx context.Context, t *testing.T, a filesystem.Adapter) {
+	p, ok := a.(filesystem.UserMetadataProvider)
+	if !ok {
+		t.Skip("adapter is not a UserMetadataProvider")
+	}
+	write(ctx, t, a, "file.txt", "content")
+	err := p.SetUserMetadata(ctx, "file.txt", map[string]string{"owner": "alice"})
+	skipUnsupported(t, "SetUserMetadata", err)
+	if err != nil {
+		t.Fatalf("SetUserMetadata failed: %v", err)
+	}
+	um, err := p.GetUserMetadata(ctx, "file.txt")
+	if err != nil {
+		t.Fatalf("GetUserMetadata failed: %v", err)
+	}
+	if len(um) != 1 || um["owner"] != "alice" {
+		t.Fatalf("GetUserMetadata = %v, expected map[owner:alice]", um)
+	}
+	assertContent(ctx, t, a, "file.txt", "content")
+	_, err = p.GetUserMetadata(ctx, "missing.txt")
+	assertFileNotFound(t, "GetUserMetadata", err)
+}
+
+func testConditionalWriter(ctx context.Context, t *testing.T, a filesystem.Adapter) {
+	cw, ok := a.(filesystem.ConditionalWriter)
+	if !ok {
+		t.Skip("adapter is not a ConditionalWriter")
+	}
+	err := cw.WriteIf(ctx, "file.txt", []byte("created"), filesystem.IfNotExists(), cfg)
+	skipUnsupported(t, "WriteIf", err)
+	if err != nil {
+		t.Fatalf("WriteIf(IfNotExists) on a missing file failed: %v", err)
+	}
+	assertContent(ctx, t, a, "file.txt", "created")
+	err = cw.WriteIf(ctx, "file.txt", []byte("replaced"), filesystem.IfNotExists(), cfg)
+	if !filesystem.IsPreconditionFailed(err) {
+		t.Fatalf("WriteIf(IfNotExists) on an existing file = %v, expected a PreconditionFailedError", err)
+	}
+	assertContent(ctx, t, a, "file.txt", "created")
+	md, err := a.GetMetadata(ctx, "file.txt")
+	if err != nil {
+		t.Fatalf("GetMetadata failed: %v", err)
+	}
+	if md.ETag != "" {
+		if err := cw.WriteIf(ctx, "file.txt", []byte("replaced"), filesystem.IfMatch(md.ETag), cfg); err != nil {
+			t.Fatalf("WriteIf(IfMatch) with the current ETag failed: %v", err)
+		}
+		assertContent(ctx, t, a, "file.txt", "replaced")
+	}
+	if err := cw.DeleteIf(ctx, "file.txt", filesystem.IfMatch("stale")); !filesystem.IsPreconditionFailed(err) {
+		t.Fatalf("DeleteIf(IfMatch) with a stale ETag = %v, expected a PreconditionFailedError", err)
+	}
+	assertHas(ctx, t, a, "file.txt", true)
+	if err := cw.DeleteIf(ctx, "file.txt", filesystem.IfMatch("*")); err != nil {
+		t.Fatalf("DeleteIf(IfMatch(*)) failed: %v", err)
+	}
+	assertHas(ctx, t, a, "file.txt", false)
+}
+
+func testAtomicWriter(ctx context.Context, t *testing.T, a filesystem.Adapter) {
+	aw, ok := a.(filesystem.AtomicWriter)
+	if !ok {
+		t.Skip("adapter is not an AtomicWriter")
+	}
+	write(ctx, t, a, "file.txt", "a longer content")
+	err := aw.WriteAtomic(ctx, "file.txt", strings.NewReader("atomic"), cfg)
+	skipUnsupported(t, "WriteAtomic", err)
+	if err != nil {
+		t.Fatalf("WriteAtomic failed: %v", err)
+	}
+	assertContent(ctx, t, a, "file.txt", "atomic")
+	if paths := listPaths(ctx, t, a, filesystem.RootPath, true); strings.Join(paths, ",") != "file.txt" {
+		t.Fatalf("ListContents after WriteAtomic = %v, expected [file.txt]", paths)
+	}
+}
+
+func testRandomWriter(ctx context.Context, t *testing.T, a filesystem.Adapter) {
+	rw, ok := a.(filesystem.RandomWriter)
+	if !ok {
+		t.Skip("adapter is not a RandomWriter")
+	}
+	write(ctx, t, a, "file.txt", "0123456789")
+	err := rw.WriteAt(ctx, "file.txt", []byte("ab"), 3)
+	skipUnsupported(t, "WriteAt", err)
+	if err != nil {
+		t.Fatalf("WriteAt failed: %v", err)
+	}
+	assertContent(ctx, t, a, "file.txt", "012ab56789")
+	if err := rw.WriteAt(ctx, "file.txt", []byte("xyz"), 9); err != nil {
+		t.Fatalf("WriteAt past the end failed: %v", err)
+	}
+	assertContent(ctx, t, a, "file.txt", "012ab5678xyz")
+	if err := rw.WriteAt(ctx, "new.txt", []byte("new"), 0); err != nil {
+		t.Fatalf("WriteAt on a missing file failed: %v", err)
+	}
+	assertContent(ctx, t, a, "new.txt", "new")
+}
+
+func testDirCopier(ctx context.Context, t *testing.T, a filesystem.Adapter) {
+	dc, ok := a.(filesystem.DirCopier)
+	if !ok {
+		t.Skip("adapter is not a DirCopier")
+	}
+	write(ctx, t, a, "dir/a.txt", "a")
+	write(ctx, t, a, "dir/sub/b.txt", "b")
+	err := dc.CopyDir(ctx, "dir", "copy", cfg)
+	skipUnsupported(t, "CopyDir", err)
+	if err != nil {
+		t.Fatalf("CopyDir failed: %v", err)
+	}
+	assertContent(ctx, t, a, "dir/a.txt", "a")
+	assertContent(ctx, t, a, "copy/a.txt", "a")
+	assertContent(ctx, t, a, "copy/sub/b.txt", "b")
+}
+
+func testDirMover(ctx context.Context, t *testing.T, a filesystem.Adapter) {
+	dm, ok := a.(filesystem.DirMover)
+	if !ok {
+		t.Skip("adapter is not a DirMover")
+	}
+	write(ctx, t, a, "dir/a.txt", "a")
+	write(ctx, t, a, "dir/sub/b.txt", "b")
+	write(ctx, t, a, "dirty.txt", "sibling")
+	err := dm.MoveDir(ctx, "dir", "moved")
+	skipUnsupported(t, "MoveDir", err)
+	if err != nil {
+		t.Fatalf("MoveDir failed: %v", err)
+	}
+	assertHas(ctx, t, a, "dir/a.txt", false)
+	assertContent(ctx, t, a, "moved/a.txt", "a")
+	assertContent(ctx, t, a, "moved/sub/b.txt", "b")
+	assertContent(ctx, t, a, "dirty.txt", "sibling")
+}
+
+func testContentsIterator(ctx context.Context, t *testing.T, a filesystem.Adapter) {
+	ci, ok := a.(filesystem.ContentsIterator)
+	if !ok {
+		t.Skip("adapter is not a ContentsIterator")
+	}
+	write(ctx, t, a, "a.txt", "a")
+	write(ctx, t, a, "dir/b.txt", "b")
+	it, err := ci.IterateContents(ctx, filesystem.RootPath, true)
+	skipUnsupported(t, "IterateContents", err)
+	if err != nil {
+		t.Fatalf("IterateContents failed: %v", err)
+	}
+	var paths []string
+	for {
+		md, err := it.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next failed: %v", err)
+		}
+		paths = append(paths, string(md.Path))
+	}
+	sort.Strings(paths)
+	if expected := listPaths(ctx, t, a, filesystem.RootPath, true); strings.Join(paths, ",") != strings.Join(expected, ",") {
+		t.Fatalf("IterateContents = %v, expected %v", paths, expected)
+	}
+}
+
+func testPageLister(ctx context.Context, t *testing.T, a filesystem.Adapter) {
+	pl, ok := a.(filesystem.PageLister)
+	if !ok {
+		t.Skip("adapter is not a PageLister")
+	}
+	for _, name := range []filesystem.Path{"a.txt", "b.txt", "c.txt", "d.txt", "e.txt"} {
+		write(ctx, t, a, name, "content")
+	}
+	var paths []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 10 {
+			t.Fatal("ListPage did not reach the last page")
+		}
+		page, err := pl.ListPage(ctx, filesystem.RootPath, false, cursor, 2)
+		skipUnsupported(t, "ListPage", err)
+		if err != nil {
+			t.Fatalf("ListPage failed: %v", err)
+		}
+		if len(page.Entries) > 2 {
+			t.Fatalf("ListPage returned %d entries, expected at most 2", len(page.Entries))
+		}
+		for _, md := range page.Entries {
+			paths = append(paths, string(md.Path))
+		}
+		if cursor = page.Cursor; cursor == "" {
+			break
+		}
+	}
+	sort.Strings(paths)
+	if strings.Join(paths, ",") != "a.txt,b.txt,c.txt,d.txt,e.txt" {
+		t.Fatalf("ListPage returned %v, expected [a.txt b.txt c.txt d.txt e.txt]", paths)
+	}
+}
+
+func testVersioned(ctx context.Context, t *testing.T, a filesystem.Adapter) {
+	v, ok := a.(filesystem.Versioned)
+	if !ok {
+		t.Skip("adapter is not Versioned")
+	}
+	write(ctx, t, a, "file.txt", "first")
+	if err := a.Put(ctx, "file.txt", []byte("second"), cfg); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	versions, err := v.ListVersions(ctx, "file.txt")
+	skipUnsupported(t, "ListVersions", err)
+	if err != nil {
+		t.Fatalf("ListVersions failed: %v", err)
+	}
+	if len(versions) < 2 {
+		t.Fatalf("ListVersions returned %d versions, expected 2", len(versions))
+	}
+	oldest := versions[len(versions)-1].VersionID
+	rc, err := v.ReadVersion(ctx, "file.txt", oldest)
+	if got := readAll(t, "ReadVersion", rc, err); got != "first" {
+		t.Fatalf("ReadVersion(oldest) = %q, expected %q", got, "first")
+	}
+	if err := v.RestoreVersion(ctx, "file.txt", oldest); err != nil {
+		t.Fatalf("RestoreVersion failed: %v", err)
+	}
+	assertContent(ctx, t, a, "file.txt", "first")
+}
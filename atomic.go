@@ -0,0 +1,71 @@
+package filesystem
+
+import (
+	"context"
+	"io"
+)
+
+// AtomicWriter is implemented by adapters with a dedicated mechanism to write files atomically, so that readers
+// never observe partially written files. Decorators may return an UnsupportedOperationError when the adapter they
+// decorate is not an AtomicWriter, so that the write falls back as WriteAtomic describes.
+type AtomicWriter interface {
+	WriteAtomic(ctx context.Context, path Path, r io.Reader, cfg Config) error
+}
+
+// WriteAtomic will write the content of provided reader at supplied path through adapter, so that the file only
+// changes once the content is completely written. AtomicWriter adapters write natively, adapters reporting the
+// AtomicWrites capability write as usual; otherwise the content is written to a temporary file next to path and
+// moved over it, which is atomic only if the adapter moves files with a rename, and the temporary file is deleted
+// on failure. It is meant for decorators; use the method of Interface otherwise.
+func WriteAtomic(ctx context.Context, a Adapter, path Path, r io.Reader, cfg Config) error {
+	if w, ok := a.(AtomicWriter); ok {
+		err := w.WriteAtomic(ctx, path, r, cfg)
+		if !IsUnsupportedOperationError(err) {
+			return err
+		}
+	}
+	if CapabilitiesOf(a).AtomicWrites {
+		return a.WriteStream(ctx, path, r, cfg)
+	}
+	tmp, err := tempPath(path)
+	if err != nil {
+		return err
+	}
+	if err := a.WriteStream(ctx, tmp, r, cfg); err != nil {
+		a.Delete(ctx, tmp)
+		return err
+	}
+	if err := a.Move(ctx, tmp, path); err != nil {
+		a.Delete(ctx, tmp)
+		return err
+	}
+	return nil
+}
+
+// tempPath will return a random hidden path in the directory of path, for its content to be written to.
+func tempPath(path Path) (Path, error) {
+	id, err := randomID()
+	if err != nil {
+		return "", err
+	}
+	return path.Dir().Join("." + path.Base() + ".tmp-" + id), nil
+}
+
+// WriteAtomic will write the content of provided reader at supplied path, creating the file, so that readers never
+// observe it partially written.
+func (fs *filesystem) WriteAtomic(ctx context.Context, path Path, r io.Reader, opts ...WriteOption) error {
+	path, err := NewPath(string(path))
+	if err != nil {
+		return err
+	}
+	return WriteAtomic(ctx, fs.adapter, path, trackWrite(ctx, "WriteAtomic", path, r), fs.writeConfig(opts))
+}
+
+// WriteAtomic will write the content of provided reader at supplied path atomically.
+func (mm *mountManager) WriteAtomic(ctx context.Context, path Path, r io.Reader, opts ...WriteOption) error {
+	mgr, subPath, err := mm.managerFor(path)
+	if err != nil {
+		return err
+	}
+	return mgr.WriteAtomic(ctx, subPath, r, opts...)
+}
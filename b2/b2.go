@@ -0,0 +1,613 @@
+// Package b2 provides an adapter storing files on Backblaze B2 through its native API.
+//
+// Content is verified with SHA1 on upload, and content larger than the recommended part size is uploaded as a
+// large file, in parts uploaded concurrently. B2 keeps every version of a file: deletes remove all of them unless
+// versions are kept, in which case the file is hidden. Directories are name prefixes, CreateDir storing a ".bzEmpty"
+// marker file as the B2 web console does. Visibility is a property of the bucket.
+package b2
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/maurofran/filesystem"
+	"github.com/maurofran/filesystem/multipart"
+	"github.com/maurofran/filesystem/retry"
+)
+
+// Settings read by NewFromConfig.
+const (
+	// ConfigKeyID is the application key ID.
+	ConfigKeyID = "key_id"
+	// ConfigApplicationKey is the application key.
+	ConfigApplicationKey = "application_key"
+	// ConfigBucket is the name of the bucket.
+	ConfigBucket = "bucket"
+	// ConfigKeepVersions makes deletes hide files instead of deleting their versions.
+	ConfigKeepVersions = "keep_versions"
+)
+
+const (
+	dirMarker       = ".bzEmpty"
+	autoMimeType    = "b2/x-auto"
+	lastModifiedKey = "src_last_modified_millis"
+)
+
+// Options are the options of the adapter.
+type Options struct {
+	// HTTPClient is the client used for API calls, http.DefaultClient if nil.
+	HTTPClient *http.Client
+	// KeepVersions makes deletes hide files, keeping their previous versions, instead of deleting every version.
+	KeepVersions bool
+	// PartSize is the size of the parts of large files, the size recommended by B2 if not positive.
+	PartSize int64
+	// Concurrency is the number of parts of large files uploaded in parallel, multipart.DefaultConcurrency if not
+	// positive.
+	Concurrency int
+}
+
+// Adapter is an adapter storing files in a B2 bucket.
+type Adapter struct {
+	client *client
+	bucket string
+	opts   Options
+
+	mu         sync.Mutex
+	bucketID   string
+	bucketType string
+}
+
+// New will create an adapter storing files in provided bucket, authorizing with provided application key.
+func New(keyID, applicationKey, bucket string, opts Options) *Adapter {
+	return &Adapter{client: newClient(opts.HTTPClient, keyID, applicationKey), bucket: bucket, opts: opts}
+}
+
+func init() {
+	filesystem.RegisterAdapter("b2", func(_ context.Context, settings map[string]interface{}) (filesystem.Adapter, error) {
+		return NewFromConfig(settings), nil
+	})
+}
+
+// NewFromConfig will create an adapter from provided settings.
+func NewFromConfig(settings map[string]interface{}) *Adapter {
+	str := func(key string) string {
+		s, _ := settings[key].(string)
+		return s
+	}
+	keep, _ := settings[ConfigKeepVersions].(bool)
+	return New(str(ConfigKeyID), str(ConfigApplicationKey), str(ConfigBucket), Options{KeepVersions: keep})
+}
+
+// bucketInfo will resolve the ID and type of the bucket.
+func (a *Adapter) bucketInfo(ctx context.Context) (string, string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.bucketID != "" {
+		return a.bucketID, a.bucketType, nil
+	}
+	auth, err := a.client.authorization(ctx, false)
+	if err != nil {
+		return "", "", err
+	}
+	var out struct {
+		Buckets []struct {
+			BucketID   string `json:"bucketId"`
+			BucketType string `json:"bucketType"`
+		} `json:"buckets"`
+	}
+	err = a.client.call(ctx, "b2_list_buckets", map[string]string{"accountId": auth.AccountID, "bucketName": a.bucket}, &out)
+	if err != nil {
+		return "", "", err
+	}
+	if len(out.Buckets) == 0 {
+		return "", "", fmt.Errorf("b2: bucket %s not found", a.bucket)
+	}
+	a.bucketID, a.bucketType = out.Buckets[0].BucketID, out.Buckets[0].BucketType
+	return a.bucketID, a.bucketType, nil
+}
+
+func (a *Adapter) partSize(ctx context.Context) (int64, error) {
+	if a.opts.PartSize > 0 {
+		return a.opts.PartSize, nil
+	}
+	auth, err := a.client.authorization(ctx, false)
+	if err != nil {
+		return 0, err
+	}
+	return auth.RecommendedPartSize, nil
+}
+
+func isNotFound(err error) bool {
+	var e *APIError
+	return errors.As(err, &e) && (e.Status == http.StatusNotFound || e.Code == "file_not_present")
+}
+
+func mapError(p filesystem.Path, err error) error {
+	if isNotFound(err) {
+		return filesystem.NewFileNotFoundError(p)
+	}
+	return err
+}
+
+// head will retrieve the headers of the latest version of the file at path.
+func (a *Adapter) head(ctx context.Context, p filesystem.Path) (http.Header, error) {
+	resp, err := a.client.download(ctx, http.MethodHead, a.bucket, string(p), nil)
+	if err != nil {
+		return nil, mapError(p, err)
+	}
+	resp.Body.Close()
+	return resp.Header, nil
+}
+
+func headerMetadata(p filesystem.Path, h http.Header) filesystem.Metadata {
+	size, _ := strconv.ParseInt(h.Get("Content-Length"), 10, 64)
+	return &filesystem.FileInfo{
+		Path:      p,
+		Type:      filesystem.TypeFile,
+		Size:      size,
+		MimeType:  h.Get("Content-Type"),
+		Timestamp: timestamp(h.Get("X-Bz-Info-"+lastModifiedKey), h.Get("X-Bz-Upload-Timestamp")),
+		Extra:     map[string]interface{}{"id": h.Get("X-Bz-File-Id")},
+	}
+}
+
+// timestamp will parse the modification time recorded by the uploader, or else the upload time, in milliseconds.
+func timestamp(values ...string) time.Time {
+	for _, v := range values {
+		if ms, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return time.UnixMilli(ms)
+		}
+	}
+	return time.Time{}
+}
+
+func contentType(p filesystem.Path, cfg filesystem.Config) string {
+	if mt, ok := cfg.Get(filesystem.ConfigMimeType, "").(string); ok && mt != "" {
+		return mt
+	}
+	if mt := mime.TypeByExtension(path.Ext(string(p))); mt != "" {
+		return mt
+	}
+	return autoMimeType
+}
+
+func fileInfo() map[string]string {
+	return map[string]string{lastModifiedKey: strconv.FormatInt(time.Now().UnixMilli(), 10)}
+}
+
+// put will upload the content of r at path, as a large file when it exceeds the part size.
+func (a *Adapter) put(ctx context.Context, p filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	bucketID, _, err := a.bucketInfo(ctx)
+	if err != nil {
+		return err
+	}
+	partSize, err := a.partSize(ctx)
+	if err != nil {
+		return err
+	}
+	first := make([]byte, partSize)
+	n, err := io.ReadFull(r, first)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	if int64(n) < partSize {
+		_, err = a.client.upload(ctx, bucketID, string(p), contentType(p, cfg), first[:n], fileInfo())
+		return err
+	}
+	// A single byte more is needed to know that the content does not fit a single upload.
+	more := make([]byte, 1)
+	if m, _ := io.ReadFull(r, more); m == 0 {
+		_, err = a.client.upload(ctx, bucketID, string(p), contentType(p, cfg), first, fileInfo())
+		return err
+	}
+	opts := multipart.Options{
+		PartSize:    partSize,
+		Concurrency: a.opts.Concurrency,
+		Retry:       retry.Policy{Retryable: isTransient},
+	}
+	content := io.MultiReader(bytes.NewReader(first), bytes.NewReader(more), r)
+	return a.client.uploadLarge(ctx, bucketID, string(p), contentType(p, cfg), content, opts, fileInfo())
+}
+
+// Has will check if a file exists.
+func (a *Adapter) Has(ctx context.Context, path filesystem.Path) (bool, error) {
+	_, err := a.head(ctx, path)
+	if filesystem.IsFileNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Read the file at provided path.
+func (a *Adapter) Read(ctx context.Context, path filesystem.Path) ([]byte, error) {
+	rc, err := a.ReadStream(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// ReadStream will read the file at provided path as a stream.
+func (a *Adapter) ReadStream(ctx context.Context, path filesystem.Path) (io.ReadCloser, error) {
+	resp, err := a.client.download(ctx, http.MethodGet, a.bucket, string(path), nil)
+	if err != nil {
+		return nil, mapError(path, err)
+	}
+	return resp.Body, nil
+}
+
+// ReadRange will read length bytes of the file at provided path starting at offset, with a range request.
+func (a *Adapter) ReadRange(ctx context.Context, path filesystem.Path, offset, length int64) (io.ReadCloser, error) {
+	if length == 0 {
+		if _, err := a.head(ctx, path); err != nil {
+			return nil, err
+		}
+		return io.NopCloser(bytes.NewReader(nil)), nil
+	}
+	rng := fmt.Sprintf("bytes=%d-", offset)
+	if length > 0 {
+		rng += strconv.FormatInt(offset+length-1, 10)
+	}
+	resp, err := a.client.download(ctx, http.MethodGet, a.bucket, string(path), http.Header{"Range": {rng}})
+	var e *APIError
+	if errors.As(err, &e) && e.Status == http.StatusRequestedRangeNotSatisfiable {
+		return io.NopCloser(bytes.NewReader(nil)), nil
+	}
+	if err != nil {
+		return nil, mapError(path, err)
+	}
+	return resp.Body, nil
+}
+
+// Write the supplied content at supplied path, creating the file.
+func (a *Adapter) Write(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	return a.put(ctx, path, bytes.NewReader(content), cfg)
+}
+
+// WriteStream will write the content of provided reader at supplied path, creating the file.
+func (a *Adapter) WriteStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	return a.put(ctx, path, r, cfg)
+}
+
+// Update the supplied content at supplied path, returning an error if file does not exists.
+func (a *Adapter) Update(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	if _, err := a.head(ctx, path); err != nil {
+		return err
+	}
+	return a.put(ctx, path, bytes.NewReader(content), cfg)
+}
+
+// Update with the content of supplied reader at supplied path, returning an error if file does not exists
+func (a *Adapter) UpdateStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	if _, err := a.head(ctx, path); err != nil {
+		return err
+	}
+	return a.put(ctx, path, r, cfg)
+}
+
+// Put the supplied content at supplied path, creating the file if does not exists.
+func (a *Adapter) Put(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	return a.put(ctx, path, bytes.NewReader(content), cfg)
+}
+
+// Puth the content of supplied reader at supplied path, creating the file if does not exists.
+func (a *Adapter) PutStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	return a.put(ctx, path, r, cfg)
+}
+
+// versions will list every version of the file named name.
+func (a *Adapter) versions(ctx context.Context, bucketID, name string) ([]file, error) {
+	var result []file
+	req := map[string]interface{}{"bucketId": bucketID, "startFileName": name, "prefix": name, "maxFileCount": 1000}
+	for {
+		var out struct {
+			Files        []file  `json:"files"`
+			NextFileName *string `json:"nextFileName"`
+			NextFileID   *string `json:"nextFileId"`
+		}
+		if err := a.client.call(ctx, "b2_list_file_versions", req, &out); err != nil {
+			return nil, err
+		}
+		for _, f := range out.Files {
+			if f.FileName == name {
+				result = append(result, f)
+			}
+		}
+		if out.NextFileName == nil || *out.NextFileName != name {
+			return result, nil
+		}
+		req["startFileName"], req["startFileId"] = *out.NextFileName, *out.NextFileID
+	}
+}
+
+// deleteFile will delete every version of the file named name, or hide it when versions are kept.
+func (a *Adapter) deleteFile(ctx context.Context, bucketID, name string) error {
+	if a.opts.KeepVersions {
+		return a.client.call(ctx, "b2_hide_file", map[string]string{"bucketId": bucketID, "fileName": name}, nil)
+	}
+	versions, err := a.versions(ctx, bucketID, name)
+	if err != nil {
+		return err
+	}
+	for _, v := range versions {
+		err := a.client.call(ctx, "b2_delete_file_version", map[string]string{"fileName": name, "fileId": v.FileID}, nil)
+		if err != nil && !isNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete will delete every version of the file at provided path, or hide it when versions are kept.
+func (a *Adapter) Delete(ctx context.Context, path filesystem.Path) error {
+	if _, err := a.head(ctx, path); err != nil {
+		return err
+	}
+	bucketID, _, err := a.bucketInfo(ctx)
+	if err != nil {
+		return err
+	}
+	return a.deleteFile(ctx, bucketID, string(path))
+}
+
+// ReadAndDelete will read the file at provided path and delete after read.
+func (a *Adapter) ReadAndDelete(ctx context.Context, path filesystem.Path) ([]byte, error) {
+	content, err := a.Read(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return content, a.Delete(ctx, path)
+}
+
+// Move the file at supplied path to new path, copying it server side and deleting the source.
+func (a *Adapter) Move(ctx context.Context, path, newpath filesystem.Path) error {
+	if err := a.Copy(ctx, path, newpath); err != nil {
+		return err
+	}
+	return a.Delete(ctx, path)
+}
+
+// Copy the file at supplied path to new path, server side. B2 copies files of up to 5GB in a single call.
+func (a *Adapter) Copy(ctx context.Context, path, newpath filesystem.Path) error {
+	h, err := a.head(ctx, path)
+	if err != nil {
+		return err
+	}
+	return mapError(path, a.client.call(ctx, "b2_copy_file", map[string]string{
+		"sourceFileId": h.Get("X-Bz-File-Id"), "fileName": string(newpath),
+	}, nil))
+}
+
+// CopyTo will copy the file at supplied path to new path of target server side, when target is an adapter of a
+// bucket of the same account. The copy is requested with the application key of the adapter, which must allow
+// writing to the bucket of target.
+func (a *Adapter) CopyTo(ctx context.Context, path filesystem.Path, target filesystem.Adapter, newpath filesystem.Path) (bool, error) {
+	t, ok := target.(*Adapter)
+	if !ok {
+		return false, nil
+	}
+	src, err := a.client.authorization(ctx, false)
+	if err != nil {
+		return false, err
+	}
+	dst, err := t.client.authorization(ctx, false)
+	if err != nil {
+		return false, err
+	}
+	if src.AccountID != dst.AccountID {
+		return false, nil
+	}
+	bucketID, _, err := t.bucketInfo(ctx)
+	if err != nil {
+		return true, err
+	}
+	h, err := a.head(ctx, path)
+	if err != nil {
+		return true, err
+	}
+	return true, mapError(path, a.client.call(ctx, "b2_copy_file", map[string]string{
+		"sourceFileId": h.Get("X-Bz-File-Id"), "destinationBucketId": bucketID, "fileName": string(newpath),
+	}, nil))
+}
+
+// GetMimeType will retrieve the mime type of file at supplied path.
+func (a *Adapter) GetMimeType(ctx context.Context, path filesystem.Path) (string, error) {
+	h, err := a.head(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	return h.Get("Content-Type"), nil
+}
+
+// GetTimestamp will retrieve the timestamp of file at supplied path.
+func (a *Adapter) GetTimestamp(ctx context.Context, path filesystem.Path) (time.Time, error) {
+	h, err := a.head(ctx, path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return headerMetadata(path, h).Timestamp, nil
+}
+
+// GetFileSize will retrieve the size of file at supplied path.
+func (a *Adapter) GetFileSize(ctx context.Context, path filesystem.Path) (int64, error) {
+	h, err := a.head(ctx, path)
+	if err != nil {
+		return 0, err
+	}
+	return headerMetadata(path, h).Size, nil
+}
+
+// GetMetadata will retrieve the metadata of file at supplied path, including the B2 file "id" of its latest
+// version.
+func (a *Adapter) GetMetadata(ctx context.Context, path filesystem.Path) (filesystem.Metadata, error) {
+	h, err := a.head(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return headerMetadata(path, h), nil
+}
+
+// GetChecksum will return the SHA1 checksum stored by B2, for files uploaded in a single part or with their
+// large file SHA1 recorded.
+func (a *Adapter) GetChecksum(ctx context.Context, path filesystem.Path, algo filesystem.ChecksumAlgo) (string, error) {
+	if algo != filesystem.ChecksumSHA1 {
+		return "", filesystem.NewUnsupportedChecksumError(path, algo)
+	}
+	h, err := a.head(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	for _, sum := range []string{h.Get("X-Bz-Content-Sha1"), h.Get("X-Bz-Info-large_file_sha1")} {
+		if sum = strings.TrimPrefix(sum, "unverified:"); len(sum) == 40 {
+			return sum, nil
+		}
+	}
+	return "", filesystem.NewUnsupportedChecksumError(path, algo)
+}
+
+// CreateDir will create a new directory at provided path, as a marker file.
+func (a *Adapter) CreateDir(ctx context.Context, path filesystem.Path, cfg filesystem.Config) error {
+	return a.put(ctx, path.Join(dirMarker), bytes.NewReader(nil), cfg)
+}
+
+// DeleteDir will delete the directory at provided path, with every version of the files under it.
+func (a *Adapter) DeleteDir(ctx context.Context, path filesystem.Path) error {
+	bucketID, _, err := a.bucketInfo(ctx)
+	if err != nil {
+		return err
+	}
+	files, err := a.listFiles(ctx, bucketID, dirPrefix(path), "")
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return filesystem.NewFileNotFoundError(path)
+	}
+	for _, f := range files {
+		if err := a.deleteFile(ctx, bucketID, f.FileName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get the visibility of file at supplied path, which is the visibility of the bucket.
+func (a *Adapter) GetVisibility(ctx context.Context, path filesystem.Path) (filesystem.Visibility, error) {
+	if _, err := a.head(ctx, path); err != nil {
+		return "", err
+	}
+	_, bucketType, err := a.bucketInfo(ctx)
+	if err != nil {
+		return "", err
+	}
+	if bucketType == "allPublic" {
+		return filesystem.VisibilityPublic, nil
+	}
+	return filesystem.VisibilityPrivate, nil
+}
+
+// Set the visibility of file at supplied path, failing unless it is the visibility of the bucket.
+func (a *Adapter) SetVisibility(ctx context.Context, path filesystem.Path, v filesystem.Visibility) error {
+	current, err := a.GetVisibility(ctx, path)
+	if err != nil || current == v {
+		return err
+	}
+	return fmt.Errorf("b2: unable to make %s %s, visibility is set per bucket", path, v)
+}
+
+func dirPrefix(p filesystem.Path) string {
+	if p == filesystem.RootPath {
+		return ""
+	}
+	return string(p) + "/"
+}
+
+// listFiles will list the latest version of files and, with a delimiter, the folders under prefix.
+func (a *Adapter) listFiles(ctx context.Context, bucketID, prefix, delimiter string) ([]file, error) {
+	var result []file
+	req := map[string]interface{}{"bucketId": bucketID, "prefix": prefix, "maxFileCount": 1000}
+	if delimiter != "" {
+		req["delimiter"] = delimiter
+	}
+	for {
+		var out struct {
+			Files        []file  `json:"files"`
+			NextFileName *string `json:"nextFileName"`
+		}
+		if err := a.client.call(ctx, "b2_list_file_names", req, &out); err != nil {
+			return nil, err
+		}
+		result = append(result, out.Files...)
+		if out.NextFileName == nil {
+			return result, nil
+		}
+		req["startFileName"] = *out.NextFileName
+	}
+}
+
+// List the contents of given path.
+func (a *Adapter) ListContents(ctx context.Context, path filesystem.Path, recursive bool) ([]filesystem.Metadata, error) {
+	bucketID, _, err := a.bucketInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	delimiter := "/"
+	if recursive {
+		delimiter = ""
+	}
+	files, err := a.listFiles(ctx, bucketID, dirPrefix(path), delimiter)
+	if err != nil {
+		return nil, err
+	}
+	dirs := make(map[filesystem.Path]bool)
+	var result []filesystem.Metadata
+	addDir := func(p filesystem.Path) {
+		if p != path && p != filesystem.RootPath && !dirs[p] {
+			dirs[p] = true
+			result = append(result, &filesystem.FileInfo{Path: p, Type: filesystem.TypeDir})
+		}
+	}
+	for _, f := range files {
+		p := filesystem.Path(strings.TrimSuffix(f.FileName, "/"))
+		if f.Action == "folder" {
+			addDir(p)
+			continue
+		}
+		// Recursive listings have no folder entries: directories are derived from the file names.
+		for dir := p.Dir(); recursive && len(dir) > len(path); dir = dir.Dir() {
+			addDir(dir)
+		}
+		if p.Base() == dirMarker {
+			continue
+		}
+		result = append(result, &filesystem.FileInfo{
+			Path:      p,
+			Type:      filesystem.TypeFile,
+			Size:      f.ContentLength,
+			MimeType:  f.ContentType,
+			Timestamp: timestamp(f.FileInfo[lastModifiedKey], strconv.FormatInt(f.UploadTimestamp, 10)),
+			Extra:     map[string]interface{}{"id": f.FileID},
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Path < result[j].Path
+	})
+	return result, nil
+}
+
+// Capabilities will report the capabilities of the adapter, copies happening server side.
+func (a *Adapter) Capabilities() filesystem.Capabilities {
+	return filesystem.Capabilities{NativeCopy: true, Checksums: true, RangeReads: true, AtomicWrites: true}
+}
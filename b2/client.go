@@ -0,0 +1,333 @@
+package b2
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/maurofran/filesystem/multipart"
+	"github.com/maurofran/filesystem/retry"
+)
+
+const defaultAPIURL = "https://api.backblazeb2.com"
+
+// APIError is the error returned by the B2 API.
+type APIError struct {
+	Status  int    `json:"status"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("b2: %s (%d): %s", e.Code, e.Status, e.Message)
+}
+
+type authorization struct {
+	AccountID               string `json:"accountId"`
+	AuthorizationToken      string `json:"authorizationToken"`
+	APIURL                  string `json:"apiUrl"`
+	DownloadURL             string `json:"downloadUrl"`
+	RecommendedPartSize     int64  `json:"recommendedPartSize"`
+	AbsoluteMinimumPartSize int64  `json:"absoluteMinimumPartSize"`
+}
+
+type uploadURL struct {
+	URL   string `json:"uploadUrl"`
+	Token string `json:"authorizationToken"`
+}
+
+// file is the description of a file version returned by the API.
+type file struct {
+	FileID          string            `json:"fileId"`
+	FileName        string            `json:"fileName"`
+	Action          string            `json:"action"`
+	ContentLength   int64             `json:"contentLength"`
+	ContentType     string            `json:"contentType"`
+	ContentSha1     string            `json:"contentSha1"`
+	FileInfo        map[string]string `json:"fileInfo"`
+	UploadTimestamp int64             `json:"uploadTimestamp"`
+}
+
+// client is a client of the native B2 API, authorizing lazily and again when the token expires.
+type client struct {
+	http   *http.Client
+	keyID  string
+	key    string
+	apiURL string
+
+	mu      sync.Mutex
+	auth    *authorization
+	uploads map[string][]uploadURL
+}
+
+func newClient(hc *http.Client, keyID, key string) *client {
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	return &client{http: hc, keyID: keyID, key: key, apiURL: defaultAPIURL, uploads: make(map[string][]uploadURL)}
+}
+
+func (c *client) authorization(ctx context.Context, renew bool) (*authorization, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.auth != nil && !renew {
+		return c.auth, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.apiURL+"/b2api/v2/b2_authorize_account", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(c.keyID, c.key)
+	var auth authorization
+	if err := c.do(req, &auth); err != nil {
+		return nil, err
+	}
+	c.auth = &auth
+	c.uploads = make(map[string][]uploadURL)
+	return c.auth, nil
+}
+
+// do will send req, decoding the JSON response into out or the error response into an APIError.
+func (c *client) do(req *http.Request, out interface{}) error {
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		apiErr := &APIError{Status: resp.StatusCode}
+		if err := json.NewDecoder(resp.Body).Decode(apiErr); err != nil {
+			apiErr.Code, apiErr.Message = http.StatusText(resp.StatusCode), err.Error()
+		}
+		return apiErr
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func isExpired(err error) bool {
+	e, ok := err.(*APIError)
+	return ok && e.Status == http.StatusUnauthorized && (e.Code == "expired_auth_token" || e.Code == "bad_auth_token")
+}
+
+// call will invoke an API operation, authorizing again once if the token expired.
+func (c *client) call(ctx context.Context, op string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	for renew := false; ; renew = true {
+		auth, err := c.authorization(ctx, renew)
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, auth.APIURL+"/b2api/v2/"+op, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", auth.AuthorizationToken)
+		if err = c.do(req, out); !isExpired(err) || renew {
+			return err
+		}
+	}
+}
+
+// escape will percent-encode a file name for headers and download URLs, keeping slashes.
+func escape(name string) string {
+	return strings.ReplaceAll(url.PathEscape(name), "%2F", "/")
+}
+
+// download will GET or HEAD a file by name, returning the response to be closed by caller.
+func (c *client) download(ctx context.Context, method, bucket, name string, header http.Header) (*http.Response, error) {
+	for renew := false; ; renew = true {
+		auth, err := c.authorization(ctx, renew)
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequestWithContext(ctx, method, auth.DownloadURL+"/file/"+escape(bucket)+"/"+escape(name), nil)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		req.Header.Set("Authorization", auth.AuthorizationToken)
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusPartialContent {
+			return resp, nil
+		}
+		apiErr := &APIError{Status: resp.StatusCode, Code: http.StatusText(resp.StatusCode)}
+		if method != http.MethodHead {
+			json.NewDecoder(resp.Body).Decode(apiErr)
+		}
+		resp.Body.Close()
+		if !isExpired(apiErr) || renew {
+			return nil, apiErr
+		}
+	}
+}
+
+// getUploadURL will take an upload URL of the bucket from the pool, or acquire a new one. B2 upload URLs accept
+// one upload at a time.
+func (c *client) getUploadURL(ctx context.Context, bucketID string) (uploadURL, error) {
+	c.mu.Lock()
+	if pool := c.uploads[bucketID]; len(pool) > 0 {
+		u := pool[len(pool)-1]
+		c.uploads[bucketID] = pool[:len(pool)-1]
+		c.mu.Unlock()
+		return u, nil
+	}
+	c.mu.Unlock()
+	var u uploadURL
+	err := c.call(ctx, "b2_get_upload_url", map[string]string{"bucketId": bucketID}, &u)
+	return u, err
+}
+
+// putUploadURL will return an upload URL to the pool after a successful upload.
+func (c *client) putUploadURL(bucketID string, u uploadURL) {
+	c.mu.Lock()
+	c.uploads[bucketID] = append(c.uploads[bucketID], u)
+	c.mu.Unlock()
+}
+
+// upload will upload content as a single file, verifying its SHA1. Upload URLs are discarded on failure, as
+// recommended by B2, and the upload attempted once more with a new one.
+func (c *client) upload(ctx context.Context, bucketID, name, contentType string, content []byte, info map[string]string) (*file, error) {
+	sum := sha1.Sum(content)
+	sha := hex.EncodeToString(sum[:])
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		var u uploadURL
+		if u, err = c.getUploadURL(ctx, bucketID); err != nil {
+			return nil, err
+		}
+		req, rerr := http.NewRequestWithContext(ctx, http.MethodPost, u.URL, bytes.NewReader(content))
+		if rerr != nil {
+			return nil, rerr
+		}
+		req.Header.Set("Authorization", u.Token)
+		req.Header.Set("X-Bz-File-Name", escape(name))
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("X-Bz-Content-Sha1", sha)
+		for k, v := range info {
+			req.Header.Set("X-Bz-Info-"+k, url.QueryEscape(v))
+		}
+		req.ContentLength = int64(len(content))
+		var f file
+		if err = c.do(req, &f); err == nil {
+			c.putUploadURL(bucketID, u)
+			if f.ContentSha1 != sha {
+				return nil, fmt.Errorf("b2: SHA1 mismatch uploading %s: sent %s, stored %s", name, sha, f.ContentSha1)
+			}
+			return &f, nil
+		}
+		if e, ok := err.(*APIError); ok && e.Status < 500 && e.Status != http.StatusUnauthorized && e.Status != http.StatusRequestTimeout {
+			return nil, err
+		}
+	}
+	return nil, err
+}
+
+// uploadLarge will upload the content of r as a large file, in parts uploaded concurrently.
+func (c *client) uploadLarge(ctx context.Context, bucketID, name, contentType string, r io.Reader, opts multipart.Options, info map[string]string) error {
+	var started file
+	err := c.call(ctx, "b2_start_large_file", map[string]interface{}{
+		"bucketId": bucketID, "fileName": name, "contentType": contentType, "fileInfo": info,
+	}, &started)
+	if err != nil {
+		return err
+	}
+	// Unfinished large files are billed until canceled, which the engine does on failure.
+	return multipart.Run(ctx, &largeUpload{c: c, fileID: started.FileID}, r, opts)
+}
+
+// isTransient will check if provided error is worth retrying: B2 asks to retry failed uploads on a new upload URL,
+// except for client errors other than expired tokens and timeouts.
+func isTransient(err error) bool {
+	if e, ok := err.(*APIError); ok {
+		return e.Status >= 500 || e.Status == http.StatusUnauthorized || e.Status == http.StatusRequestTimeout ||
+			e.Status == http.StatusTooManyRequests
+	}
+	return retry.IsTransient(err)
+}
+
+// largeUpload is a large file being uploaded. Upload part URLs accept one upload at a time, so each concurrent
+// part upload takes its own from the pool.
+type largeUpload struct {
+	c      *client
+	fileID string
+	mu     sync.Mutex
+	urls   []uploadURL
+}
+
+func (u *largeUpload) uploadURL(ctx context.Context) (uploadURL, error) {
+	u.mu.Lock()
+	if n := len(u.urls); n > 0 {
+		url := u.urls[n-1]
+		u.urls = u.urls[:n-1]
+		u.mu.Unlock()
+		return url, nil
+	}
+	u.mu.Unlock()
+	var url uploadURL
+	err := u.c.call(ctx, "b2_get_upload_part_url", map[string]string{"fileId": u.fileID}, &url)
+	return url, err
+}
+
+// UploadPart will upload a part, verifying its SHA1, which identifies it. Upload URLs are discarded on failure, as
+// recommended by B2.
+func (u *largeUpload) UploadPart(ctx context.Context, number int, part []byte) (string, error) {
+	url, err := u.uploadURL(ctx)
+	if err != nil {
+		return "", err
+	}
+	sum := sha1.Sum(part)
+	sha := hex.EncodeToString(sum[:])
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url.URL, bytes.NewReader(part))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", url.Token)
+	req.Header.Set("X-Bz-Part-Number", strconv.Itoa(number))
+	req.Header.Set("X-Bz-Content-Sha1", sha)
+	req.ContentLength = int64(len(part))
+	var uploaded struct {
+		ContentSha1 string `json:"contentSha1"`
+	}
+	if err := u.c.do(req, &uploaded); err != nil {
+		return "", err
+	}
+	u.mu.Lock()
+	u.urls = append(u.urls, url)
+	u.mu.Unlock()
+	if uploaded.ContentSha1 != sha {
+		return "", fmt.Errorf("b2: SHA1 mismatch uploading part %d: sent %s, stored %s", number, sha, uploaded.ContentSha1)
+	}
+	return sha, nil
+}
+
+func (u *largeUpload) Complete(ctx context.Context, parts []multipart.Part) error {
+	sums := make([]string, len(parts))
+	for i, p := range parts {
+		sums[i] = p.ID
+	}
+	return u.c.call(ctx, "b2_finish_large_file", map[string]interface{}{"fileId": u.fileID, "partSha1Array": sums}, nil)
+}
+
+func (u *largeUpload) Abort(ctx context.Context) error {
+	return u.c.call(ctx, "b2_cancel_large_file", map[string]string{"fileId": u.fileID}, nil)
+}
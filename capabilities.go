@@ -0,0 +1,93 @@
+package filesystem
+
+// Capabilities are the features an adapter supports natively, letting generic code pick a strategy instead of
+// guessing. A false flag does not mean the operation fails, only that it is emulated, e.g. by streaming content.
+type Capabilities struct {
+	// NativeCopy reports whether Copy happens on the backend, without streaming content through the client.
+	NativeCopy bool
+	// NativeMove reports whether Move is a rename, as opposed to a copy followed by a delete.
+	NativeMove bool
+	// NativeAppend reports whether the backend can append to a file without rewriting it.
+	NativeAppend bool
+	// Checksums reports whether the adapter returns checksums without reading content, for some algorithms.
+	Checksums bool
+	// TemporaryURLs reports whether the adapter can issue temporary URLs granting access to files.
+	TemporaryURLs bool
+	// DirectoryMetadata reports whether directories exist on their own, with metadata, as opposed to being name
+	// prefixes.
+	DirectoryMetadata bool
+	// RangeReads reports whether part of a file is read without reading what precedes it.
+	RangeReads bool
+	// NativeDirCopy and NativeDirMove report whether whole directories are copied or moved in a single operation.
+	NativeDirCopy bool
+	NativeDirMove bool
+	// ContentsIteration reports whether listings are iterated lazily, e.g. page by page.
+	ContentsIteration bool
+	// UserMetadata reports whether user defined key/value metadata can be attached to files.
+	UserMetadata bool
+	// Pagination reports whether listings are paged natively, resuming from a cursor.
+	Pagination bool
+	// ConditionalWrites reports whether writes and deletes can be made conditional on the ETag of the file.
+	ConditionalWrites bool
+	// Versioning reports whether previous versions of files are kept, to be listed, read and restored.
+	Versioning bool
+	// AtomicWrites reports whether written files only change once their content is completely written, so that
+	// readers never observe them partially written.
+	AtomicWrites bool
+	// RandomWrites reports whether part of a file is written in place, without rewriting it.
+	RandomWrites bool
+}
+
+// CapabilityReporter is implemented by adapters declaring their capabilities. Decorators forwarding optional
+// interfaces should implement it too, reporting the capabilities of the adapter they decorate.
+type CapabilityReporter interface {
+	Capabilities() Capabilities
+}
+
+// CapabilitiesOf will report the capabilities of provided adapter: the ones it declares, if it implements
+// CapabilityReporter, or else the ones inferred from the optional interfaces it implements.
+func CapabilitiesOf(a Adapter) Capabilities {
+	if r, ok := a.(CapabilityReporter); ok {
+		return r.Capabilities()
+	}
+	var c Capabilities
+	if _, ok := a.(RangeReader); ok {
+		c.RangeReads = true
+	}
+	if _, ok := a.(ChecksumProvider); ok {
+		c.Checksums = true
+	}
+	if _, ok := a.(DirCopier); ok {
+		c.NativeDirCopy = true
+	}
+	if _, ok := a.(DirMover); ok {
+		c.NativeDirMove = true
+	}
+	if _, ok := a.(ContentsIterator); ok {
+		c.ContentsIteration = true
+	}
+	if _, ok := a.(UserMetadataProvider); ok {
+		c.UserMetadata = true
+	}
+	if _, ok := a.(PageLister); ok {
+		c.Pagination = true
+	}
+	if _, ok := a.(ConditionalWriter); ok {
+		c.ConditionalWrites = true
+	}
+	if _, ok := a.(Versioned); ok {
+		c.Versioning = true
+	}
+	if _, ok := a.(AtomicWriter); ok {
+		c.AtomicWrites = true
+	}
+	if _, ok := a.(RandomWriter); ok {
+		c.RandomWrites = true
+	}
+	return c
+}
+
+// Capabilities will report the capabilities of the adapter.
+func (fs *filesystem) Capabilities() Capabilities {
+	return CapabilitiesOf(fs.adapter)
+}
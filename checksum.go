@@ -0,0 +1,125 @@
+package filesystem
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+)
+
+// ChecksumAlgo enumeration.
+type ChecksumAlgo int
+
+// ChecksumAlgo values. Checksums are returned hex encoded, CRC32C as the big endian hex of its 4 bytes.
+const (
+	ChecksumMD5 ChecksumAlgo = iota + 1
+	ChecksumSHA1
+	ChecksumSHA256
+	ChecksumCRC32C
+)
+
+var checksumAlgos = [...]string{"MD5", "SHA1", "SHA256", "CRC32C"}
+
+func (a ChecksumAlgo) String() string {
+	return checksumAlgos[a-1]
+}
+
+// New will create a new hash computing the checksum.
+func (a ChecksumAlgo) New() hash.Hash {
+	switch a {
+	case ChecksumMD5:
+		return md5.New()
+	case ChecksumSHA1:
+		return sha1.New()
+	case ChecksumSHA256:
+		return sha256.New()
+	case ChecksumCRC32C:
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	}
+	panic(fmt.Sprintf("unknown checksum algorithm %d", a))
+}
+
+// ChecksumProvider is implemented by adapters able to return checksums natively, e.g. from object store ETags.
+// Adapters return an UnsupportedChecksumError for the files or algorithms they have no checksum for, in which case
+// the file is hashed by streaming its content.
+type ChecksumProvider interface {
+	GetChecksum(ctx context.Context, path Path, algo ChecksumAlgo) (string, error)
+}
+
+// UnsupportedChecksumError is the error returned by a ChecksumProvider with no checksum of requested algorithm.
+type UnsupportedChecksumError interface {
+	error
+	Path() Path
+	Algo() ChecksumAlgo
+}
+
+type unsupportedChecksumError struct {
+	path Path
+	algo ChecksumAlgo
+}
+
+func (e unsupportedChecksumError) Path() Path {
+	return e.path
+}
+
+func (e unsupportedChecksumError) Algo() ChecksumAlgo {
+	return e.algo
+}
+
+func (e unsupportedChecksumError) Error() string {
+	return fmt.Sprintf("No %s checksum available for %s", e.algo, e.path)
+}
+
+// NewUnsupportedChecksumError will create a new unsupported checksum error.
+func NewUnsupportedChecksumError(path Path, algo ChecksumAlgo) error {
+	return unsupportedChecksumError{path, algo}
+}
+
+// IsUnsupportedChecksumError will check if provided error is an unsupported checksum error.
+func IsUnsupportedChecksumError(err error) bool {
+	_, ok := err.(UnsupportedChecksumError)
+	return ok
+}
+
+// Checksum will compute the checksum of content read from r.
+func Checksum(r io.Reader, algo ChecksumAlgo) (string, error) {
+	h := algo.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// GetChecksum will retrieve the checksum of file at supplied path, natively when adapter provides it.
+func (fs *filesystem) GetChecksum(ctx context.Context, path Path, algo ChecksumAlgo) (string, error) {
+	path, err := NewPath(string(path))
+	if err != nil {
+		return "", err
+	}
+	if p, ok := fs.adapter.(ChecksumProvider); ok {
+		sum, err := p.GetChecksum(ctx, path, algo)
+		if !IsUnsupportedChecksumError(err) {
+			return sum, err
+		}
+	}
+	r, err := fs.adapter.ReadStream(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	return Checksum(r, algo)
+}
+
+// GetChecksum will retrieve the checksum of file at supplied path.
+func (mm *mountManager) GetChecksum(ctx context.Context, path Path, algo ChecksumAlgo) (string, error) {
+	mgr, subPath, err := mm.managerFor(path)
+	if err != nil {
+		return "", err
+	}
+	return mgr.GetChecksum(ctx, subPath, algo)
+}
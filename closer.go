@@ -0,0 +1,14 @@
+package filesystem
+
+import "io"
+
+// Close will release the resources held by provided adapter or file system, if it implements io.Closer.
+//
+// Adapters holding connection pools, caches, queues or watchers should implement io.Closer; file systems created
+// with New, decorators and the mount manager forward Close to what they wrap.
+func Close(v interface{}) error {
+	if c, ok := v.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
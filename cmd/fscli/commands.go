@@ -0,0 +1,467 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/maurofran/filesystem"
+	"github.com/maurofran/filesystem/scoped"
+	"github.com/maurofran/filesystem/sync"
+)
+
+// remote will check if arg is the path of a mounted file system rather than a local file.
+func remote(arg string) bool {
+	return strings.Contains(arg, "://")
+}
+
+// splitMount will split a remote path into its mount prefix and the path within the mount.
+func splitMount(arg string) (string, filesystem.Path) {
+	prefix, p, _ := strings.Cut(arg, "://")
+	return prefix, filesystem.Path(strings.Trim(p, "/"))
+}
+
+// join will join name to a remote path.
+func join(arg, name string) string {
+	if strings.HasSuffix(arg, "/") {
+		return arg + name
+	}
+	return arg + "/" + name
+}
+
+// base will return the last element of a local or remote path.
+func base(arg string) string {
+	if remote(arg) {
+		_, p := splitMount(arg)
+		return p.Base()
+	}
+	return filepath.Base(arg)
+}
+
+func requireRemote(args ...string) error {
+	for _, arg := range args {
+		if !remote(arg) {
+			return fmt.Errorf("%s: not a prefix://path of a mounted file system", arg)
+		}
+	}
+	return nil
+}
+
+func ls(ctx context.Context, m *mounts, args []string) error {
+	flags := flag.NewFlagSet("ls", flag.ContinueOnError)
+	recursive := flags.Bool("r", false, "list nested directories too")
+	long := flags.Bool("l", false, "print size, modification time and visibility")
+	args, err := parseFlags(flags, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	if err := requireRemote(args[0]); err != nil {
+		return err
+	}
+	_, dir := splitMount(args[0])
+	contents, err := m.manager.ListContents(ctx, filesystem.Path(args[0]), *recursive)
+	if err != nil {
+		return err
+	}
+	sort.Slice(contents, func(i, j int) bool { return contents[i].Path < contents[j].Path })
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	for _, md := range contents {
+		name := strings.TrimPrefix(strings.TrimPrefix(string(md.Path), string(dir)), "/")
+		if md.IsDir() {
+			name += "/"
+		}
+		if !*long {
+			fmt.Println(name)
+			continue
+		}
+		var size, modified string
+		if !md.IsDir() {
+			size = fmt.Sprint(md.Size)
+		}
+		if !md.Timestamp.IsZero() {
+			modified = md.Timestamp.Local().Format(time.DateTime)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t %s\n", size, modified, md.Visibility, name)
+	}
+	return w.Flush()
+}
+
+func cat(ctx context.Context, m *mounts, args []string) error {
+	args, err := parseFlags(flag.NewFlagSet("cat", flag.ContinueOnError), args, 1, -1)
+	if err != nil {
+		return err
+	}
+	if err := requireRemote(args...); err != nil {
+		return err
+	}
+	for _, arg := range args {
+		rc, err := m.manager.ReadStream(ctx, filesystem.Path(arg))
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(os.Stdout, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func cp(ctx context.Context, m *mounts, args []string) error {
+	return transfer(ctx, m, "cp", args, false)
+}
+
+func mv(ctx context.Context, m *mounts, args []string) error {
+	return transfer(ctx, m, "mv", args, true)
+}
+
+// transfer will copy or move a file, or a directory with the -r flag, between mounts or between a mount and the local
+// file system. A destination that is an existing directory, or ends with a slash, receives the source under its
+// name.
+func transfer(ctx context.Context, m *mounts, name string, args []string, move bool) error {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	recursive := flags.Bool("r", false, "transfer directories and their content")
+	args, err := parseFlags(flags, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	src, dst := args[0], args[1]
+	if !remote(src) && !remote(dst) {
+		return fmt.Errorf("%s: at least one of source and destination must be a prefix://path", name)
+	}
+	isDir, err := m.isDir(ctx, dst)
+	if err != nil {
+		return err
+	}
+	if isDir || strings.HasSuffix(dst, "/") {
+		if remote(dst) {
+			dst = join(dst, base(src))
+		} else {
+			dst = filepath.Join(dst, base(src))
+		}
+	}
+	srcDir, err := m.isDir(ctx, src)
+	if err != nil {
+		return err
+	}
+	if srcDir && !*recursive {
+		return fmt.Errorf("%s: is a directory, use -r", src)
+	}
+	switch {
+	case remote(src) && remote(dst) && srcDir && move:
+		return m.manager.MoveDir(ctx, filesystem.Path(src), filesystem.Path(dst))
+	case remote(src) && remote(dst) && srcDir:
+		return m.manager.CopyDir(ctx, filesystem.Path(src), filesystem.Path(dst))
+	case remote(src) && remote(dst) && move:
+		return m.manager.Move(ctx, filesystem.Path(src), filesystem.Path(dst))
+	case remote(src) && remote(dst):
+		return m.manager.Copy(ctx, filesystem.Path(src), filesystem.Path(dst))
+	case remote(src):
+		err = m.download(ctx, src, dst, srcDir)
+	default:
+		err = m.upload(ctx, src, dst, srcDir)
+	}
+	if err != nil || !move {
+		return err
+	}
+	if !remote(src) {
+		return os.RemoveAll(src)
+	}
+	if srcDir {
+		return m.manager.DeleteDir(ctx, filesystem.Path(src))
+	}
+	_, err = m.manager.Delete(ctx, filesystem.Path(src))
+	return err
+}
+
+// isDir will check if the local or remote path is an existing directory.
+func (m *mounts) isDir(ctx context.Context, arg string) (bool, error) {
+	if remote(arg) {
+		if _, p := splitMount(arg); p == filesystem.RootPath {
+			return true, nil
+		}
+		return m.manager.DirectoryExists(ctx, filesystem.Path(arg))
+	}
+	fi, err := os.Stat(arg)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil && fi.IsDir(), err
+}
+
+// download will copy the remote file or directory at src to the local path dst.
+func (m *mounts) download(ctx context.Context, src, dst string, dir bool) error {
+	if !dir {
+		return m.downloadFile(ctx, filesystem.Path(src), dst)
+	}
+	_, root := splitMount(src)
+	contents, err := m.manager.ListContents(ctx, filesystem.Path(src), true)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dst, 0o755); err != nil {
+		return err
+	}
+	for _, md := range contents {
+		rel := strings.TrimPrefix(strings.TrimPrefix(string(md.Path), string(root)), "/")
+		local := filepath.Join(dst, filepath.FromSlash(rel))
+		if md.IsDir() {
+			err = os.MkdirAll(local, 0o755)
+		} else {
+			err = m.downloadFile(ctx, filesystem.Path(join(src, rel)), local)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *mounts) downloadFile(ctx context.Context, src filesystem.Path, dst string) error {
+	rc, err := m.manager.ReadStream(ctx, src)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, rc); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// upload will copy the local file or directory at src to the remote path dst.
+func (m *mounts) upload(ctx context.Context, src, dst string, dir bool) error {
+	if !dir {
+		return m.uploadFile(ctx, src, filesystem.Path(dst))
+	}
+	return filepath.WalkDir(src, func(local string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, local)
+		if err != nil {
+			return err
+		}
+		target := dst
+		if rel != "." {
+			target = join(dst, filepath.ToSlash(rel))
+		}
+		if d.IsDir() {
+			return m.manager.CreateDir(ctx, filesystem.Path(target))
+		}
+		return m.uploadFile(ctx, local, filesystem.Path(target))
+	})
+}
+
+func (m *mounts) uploadFile(ctx context.Context, src string, dst filesystem.Path) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return m.manager.PutStream(ctx, dst, f)
+}
+
+func rm(ctx context.Context, m *mounts, args []string) error {
+	flags := flag.NewFlagSet("rm", flag.ContinueOnError)
+	recursive := flags.Bool("r", false, "delete directories and their content")
+	args, err := parseFlags(flags, args, 1, -1)
+	if err != nil {
+		return err
+	}
+	if err := requireRemote(args...); err != nil {
+		return err
+	}
+	for _, arg := range args {
+		dir, err := m.isDir(ctx, arg)
+		if err != nil {
+			return err
+		}
+		switch {
+		case dir && !*recursive:
+			return fmt.Errorf("%s: is a directory, use -r", arg)
+		case dir:
+			err = m.manager.DeleteDir(ctx, filesystem.Path(arg))
+		default:
+			_, err = m.manager.Delete(ctx, filesystem.Path(arg))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// patterns collects the values of a repeated flag.
+type patterns []string
+
+func (p *patterns) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *patterns) Set(s string) error {
+	*p = append(*p, s)
+	return nil
+}
+
+// syncCmd will synchronize a remote directory with a remote or local one, printing the files copied and deleted.
+func syncCmd(ctx context.Context, m *mounts, args []string) error {
+	flags := flag.NewFlagSet("sync", flag.ContinueOnError)
+	mirror := flags.Bool("mirror", false, "delete the destination files missing from the source")
+	dryRun := flags.Bool("dry-run", false, "report the changes without applying them")
+	concurrency := flags.Int("concurrency", 4, "number of files transferred in parallel")
+	var include, exclude patterns
+	flags.Var(&include, "include", "synchronize only the files matching the `glob`, may be repeated")
+	flags.Var(&exclude, "exclude", "skip the files matching the `glob`, may be repeated")
+	args, err := parseFlags(flags, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	if err := requireRemote(args[1]); err != nil {
+		return err
+	}
+	src, err := m.scope(args[0])
+	if err != nil {
+		return err
+	}
+	dst, err := m.scope(args[1])
+	if err != nil {
+		return err
+	}
+	opts := sync.SyncOptions{
+		DryRun:      *dryRun,
+		Concurrency: *concurrency,
+		Include:     include,
+		Exclude:     exclude,
+	}
+	if *mirror {
+		opts.Mode = sync.Mirror
+	}
+	report, err := sync.Sync(ctx, src, dst, opts)
+	if err != nil {
+		return err
+	}
+	for _, p := range report.Copied {
+		fmt.Println("copied", p)
+	}
+	for _, p := range report.Deleted {
+		fmt.Println("deleted", p)
+	}
+	fmt.Printf("%d copied, %d deleted, %d unchanged, %d failed\n",
+		len(report.Copied), len(report.Deleted), report.Unchanged, len(report.Failed))
+	for p, err := range report.Failed {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", p, err)
+	}
+	if len(report.Failed) > 0 {
+		return fmt.Errorf("%d files failed", len(report.Failed))
+	}
+	return nil
+}
+
+// scope will return a file system whose root is the remote directory at arg, or the local directory at arg, served
+// read-only.
+func (m *mounts) scope(arg string) (filesystem.Interface, error) {
+	if !remote(arg) {
+		return filesystem.New(filesystem.FromFS(os.DirFS(arg))), nil
+	}
+	prefix, p := splitMount(arg)
+	a, ok := m.adapters[prefix]
+	if !ok {
+		return nil, fmt.Errorf("%s: no file system mounted at %q", arg, prefix)
+	}
+	if p != filesystem.RootPath {
+		a = scoped.New(a, p)
+	}
+	return filesystem.New(a, m.options[prefix]...), nil
+}
+
+func mkdir(ctx context.Context, m *mounts, args []string) error {
+	args, err := parseFlags(flag.NewFlagSet("mkdir", flag.ContinueOnError), args, 1, -1)
+	if err != nil {
+		return err
+	}
+	if err := requireRemote(args...); err != nil {
+		return err
+	}
+	for _, arg := range args {
+		if err := m.manager.CreateDir(ctx, filesystem.Path(arg)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func stat(ctx context.Context, m *mounts, args []string) error {
+	args, err := parseFlags(flag.NewFlagSet("stat", flag.ContinueOnError), args, 1, -1)
+	if err != nil {
+		return err
+	}
+	if err := requireRemote(args...); err != nil {
+		return err
+	}
+	for i, arg := range args {
+		if i > 0 {
+			fmt.Println()
+		}
+		dir, err := m.isDir(ctx, arg)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+		fmt.Fprintf(w, "Path:\t%s\n", arg)
+		if dir {
+			di, err := m.manager.GetDirectoryMetadata(ctx, filesystem.Path(arg))
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "Type:\t%s\n", filesystem.TypeDir)
+			fmt.Fprintf(w, "Files:\t%d\n", di.Files)
+			fmt.Fprintf(w, "Directories:\t%d\n", di.Dirs)
+			fmt.Fprintf(w, "Size:\t%d\n", di.Size)
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			continue
+		}
+		md, err := m.manager.GetMetadata(ctx, filesystem.Path(arg))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Type:\t%s\n", md.Type)
+		fmt.Fprintf(w, "Size:\t%d\n", md.Size)
+		for _, field := range [][2]string{
+			{"Mime type", md.MimeType},
+			{"Visibility", string(md.Visibility)},
+			{"ETag", md.ETag},
+			{"Version", md.VersionID},
+		} {
+			if field[1] != "" {
+				fmt.Fprintf(w, "%s:\t%s\n", field[0], field[1])
+			}
+		}
+		if !md.Timestamp.IsZero() {
+			fmt.Fprintf(w, "Modified:\t%s\n", md.Timestamp.Local().Format(time.RFC3339))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/maurofran/filesystem"
+	_ "github.com/maurofran/filesystem/b2"
+	"github.com/maurofran/filesystem/fsconfig"
+	_ "github.com/maurofran/filesystem/gdrive"
+	_ "github.com/maurofran/filesystem/gitfs"
+	_ "github.com/maurofran/filesystem/s3"
+	_ "github.com/maurofran/filesystem/smb"
+)
+
+// mounts holds the adapters of the configured mounts, and the mount manager exposing them.
+type mounts struct {
+	adapters map[string]filesystem.Adapter
+	options  map[string][]filesystem.NewOption
+	manager  filesystem.MountManager
+}
+
+func newMounts(ctx context.Context, cfg *fsconfig.Config) (*mounts, error) {
+	m := &mounts{
+		adapters: make(map[string]filesystem.Adapter),
+		options:  make(map[string][]filesystem.NewOption),
+		manager:  filesystem.EmptyMountManager(),
+	}
+	for prefix, mount := range cfg.Mounts {
+		opts, err := mount.Options()
+		if err != nil {
+			m.manager.Close()
+			return nil, fmt.Errorf("mount %s: %w", prefix, err)
+		}
+		a, err := mount.Adapter(ctx)
+		if err != nil {
+			m.manager.Close()
+			return nil, fmt.Errorf("mount %s: %w", prefix, err)
+		}
+		m.adapters[prefix], m.options[prefix] = a, opts
+		if err := m.manager.Mount(prefix, filesystem.New(a, opts...)); err != nil {
+			m.manager.Close()
+			return nil, err
+		}
+	}
+	return m, nil
+}
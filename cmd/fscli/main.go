@@ -0,0 +1,133 @@
+// Command fscli interacts with the file systems mounted by a configuration file, for operations and debugging.
+//
+// Usage:
+//
+//	fscli [-config file] <command> [flags] [arguments]
+//
+// The commands are ls, cat, cp, mv, rm, sync, mkdir and stat. Files of mounted file systems are addressed with the
+// "prefix://path" syntax of the mount manager; other arguments are local files, which cp, mv and sync accept as
+// source or destination.
+//
+// The configuration file, "fscli.yaml" unless set by the -config flag or the FSCLI_CONFIG environment variable, is
+// loaded by the fsconfig package: it maps the prefixes of mounts to the type of their adapter, its settings and
+// decorators:
+//
+//	mounts:
+//	  backups:
+//	    type: s3
+//	    settings:
+//	      bucket: my-backups
+//	      region: eu-west-1
+//	  scratch:
+//	    type: memory
+//
+// It is decoded as JSON if its extension is ".json". The adapters of this module are available: memory, null, dir,
+// s3, b2, smb, gdrive and git.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+
+	"github.com/maurofran/filesystem/fsconfig"
+)
+
+type command struct {
+	usage string
+	run   func(ctx context.Context, m *mounts, args []string) error
+}
+
+var commands = map[string]command{
+	"ls":    {"ls [-r] [-l] prefix://path", ls},
+	"cat":   {"cat prefix://path...", cat},
+	"cp":    {"cp [-r] source destination", cp},
+	"mv":    {"mv [-r] source destination", mv},
+	"rm":    {"rm [-r] prefix://path...", rm},
+	"sync":  {"sync [-mirror] [-dry-run] [-concurrency n] [-include glob] [-exclude glob] source prefix://path", syncCmd},
+	"mkdir": {"mkdir prefix://path...", mkdir},
+	"stat":  {"stat prefix://path...", stat},
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	err := run(ctx, os.Args[1:])
+	stop()
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "fscli:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("fscli", flag.ContinueOnError)
+	config := os.Getenv("FSCLI_CONFIG")
+	if config == "" {
+		config = "fscli.yaml"
+	}
+	flags.StringVar(&config, "config", config, "configuration `file` of the mounts")
+	flags.Usage = func() { usage(flags.Output(), flags) }
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return flag.ErrHelp
+	}
+	cmd, ok := commands[flags.Arg(0)]
+	if !ok {
+		flags.Usage()
+		return fmt.Errorf("unknown command %q", flags.Arg(0))
+	}
+	cfg, err := fsconfig.Load(config)
+	if err != nil {
+		return err
+	}
+	m, err := newMounts(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer m.manager.Close()
+	err = cmd.run(ctx, m, flags.Args()[1:])
+	if errors.Is(err, errUsage) {
+		return fmt.Errorf("usage: fscli %s", cmd.usage)
+	}
+	return err
+}
+
+func usage(w io.Writer, flags *flag.FlagSet) {
+	fmt.Fprintln(w, "usage: fscli [-config file] <command> [flags] [arguments]")
+	flags.PrintDefaults()
+	fmt.Fprintln(w, "\ncommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintln(w, "  fscli", commands[name].usage)
+	}
+}
+
+// errUsage is the error of commands given the wrong number of arguments.
+var errUsage = errors.New("wrong number of arguments")
+
+// parseFlags will parse the flags of a command, checking the number of remaining arguments is at least min, and at
+// most max if not negative.
+func parseFlags(flags *flag.FlagSet, args []string, min, max int) ([]string, error) {
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if flags.NArg() < min || max >= 0 && flags.NArg() > max {
+		return nil, errUsage
+	}
+	return flags.Args(), nil
+}
@@ -0,0 +1,165 @@
+package filesystem
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Codec is the interface of objects marshalling values to file content and back.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+type gobCodec struct{}
+
+func (gobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+type yamlCodec struct{}
+
+func (yamlCodec) Marshal(v interface{}) ([]byte, error) {
+	return yaml.Marshal(v)
+}
+
+func (yamlCodec) Unmarshal(data []byte, v interface{}) error {
+	return yaml.Unmarshal(data, v)
+}
+
+var (
+	// JSON is the codec encoding values as JSON documents.
+	JSON Codec = jsonCodec{}
+	// YAML is the codec encoding values as YAML documents.
+	YAML Codec = yamlCodec{}
+	// Gob is the codec encoding values as gob streams.
+	Gob Codec = gobCodec{}
+)
+
+// CodecOptions are the options used to read and write typed values.
+type CodecOptions struct {
+	// Codec is the codec to use, JSON if nil.
+	Codec Codec
+	// Compress enables gzip compression of the encoded content.
+	Compress bool
+	// Atomic writes the content with WriteAtomic, so that readers never see a partially written document.
+	Atomic bool
+}
+
+func (o CodecOptions) codec() Codec {
+	if o.Codec == nil {
+		return JSON
+	}
+	return o.Codec
+}
+
+// CodecError is the error returned when the content of a file cannot be encoded or decoded.
+type CodecError interface {
+	error
+	Path() Path
+	Unwrap() error
+}
+
+type codecError struct {
+	message string
+	path    Path
+	err     error
+}
+
+// Path is the path of the file.
+func (e codecError) Path() Path {
+	return e.path
+}
+
+// Unwrap will return the underlying codec error.
+func (e codecError) Unwrap() error {
+	return e.err
+}
+
+func (e codecError) Error() string {
+	return fmt.Sprintf(e.message, e.path, e.err)
+}
+
+// IsCodecError will check if provided error is a codec error.
+func IsCodecError(err error) bool {
+	_, ok := err.(CodecError)
+	return ok
+}
+
+func encodeError(path Path, err error) CodecError {
+	return codecError{"Unable to encode %s: %v", path, err}
+}
+
+func decodeError(path Path, err error) CodecError {
+	return codecError{"Unable to decode %s: %v", path, err}
+}
+
+// ReadAs will read the file at provided path, decoding its content as a value of type T.
+func ReadAs[T any](ctx context.Context, fs Interface, path Path, opts CodecOptions) (T, error) {
+	var v T
+	data, err := fs.Read(ctx, path)
+	if err != nil {
+		return v, err
+	}
+	if opts.Compress {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return v, decodeError(path, err)
+		}
+		if data, err = io.ReadAll(zr); err != nil {
+			return v, decodeError(path, err)
+		}
+	}
+	if err := opts.codec().Unmarshal(data, &v); err != nil {
+		return v, decodeError(path, err)
+	}
+	return v, nil
+}
+
+// WriteAs will encode provided value and put it at provided path, creating the file if does not exists.
+func WriteAs[T any](ctx context.Context, fs Interface, path Path, v T, opts CodecOptions) error {
+	data, err := opts.codec().Marshal(v)
+	if err != nil {
+		return encodeError(path, err)
+	}
+	if opts.Compress {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(data); err != nil {
+			return encodeError(path, err)
+		}
+		if err := zw.Close(); err != nil {
+			return encodeError(path, err)
+		}
+		data = buf.Bytes()
+	}
+	if !opts.Atomic {
+		return fs.Put(ctx, path, data)
+	}
+	return fs.WriteAtomic(ctx, path, bytes.NewReader(data))
+}
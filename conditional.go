@@ -0,0 +1,87 @@
+package filesystem
+
+import "context"
+
+// Precondition is the condition the current state of a file must satisfy for a conditional operation to succeed.
+// The zero value always holds.
+type Precondition struct {
+	// IfMatch requires the file to exist with this ETag, or any ETag if "*".
+	IfMatch string
+	// IfNoneMatch requires the file not to have this ETag, or not to exist if "*".
+	IfNoneMatch string
+}
+
+// IfMatch will return the precondition holding when the file exists with provided ETag.
+func IfMatch(etag string) Precondition {
+	return Precondition{IfMatch: etag}
+}
+
+// IfNotExists will return the precondition holding when the file does not exist.
+func IfNotExists() Precondition {
+	return Precondition{IfNoneMatch: "*"}
+}
+
+// Holds will check if the precondition holds for a file with provided ETag, exists telling whether there is a file
+// at all. It is meant for adapters checking preconditions themselves.
+func (p Precondition) Holds(etag string, exists bool) bool {
+	if p.IfMatch != "" && (!exists || (p.IfMatch != "*" && p.IfMatch != etag)) {
+		return false
+	}
+	if p.IfNoneMatch != "" && exists && (p.IfNoneMatch == "*" || p.IfNoneMatch == etag) {
+		return false
+	}
+	return true
+}
+
+// ConditionalWriter is implemented by adapters able to check a precondition and write or delete a file atomically,
+// such as S3 conditional requests. Operations whose precondition does not hold fail with a PreconditionFailedError.
+type ConditionalWriter interface {
+	WriteIf(ctx context.Context, path Path, content []byte, cond Precondition, cfg Config) error
+	DeleteIf(ctx context.Context, path Path, cond Precondition) error
+}
+
+// WriteIf will write the supplied content at supplied path if the precondition holds, replacing the file if any,
+// failing with an UnsupportedOperationError if adapter cannot write conditionally.
+func (fs *filesystem) WriteIf(ctx context.Context, path Path, content []byte, cond Precondition, opts ...WriteOption) error {
+	path, err := NewPath(string(path))
+	if err != nil {
+		return err
+	}
+	w, ok := fs.adapter.(ConditionalWriter)
+	if !ok {
+		return NewUnsupportedOperationError(path, "WriteIf")
+	}
+	return w.WriteIf(ctx, path, content, cond, fs.writeConfig(opts))
+}
+
+// DeleteIf will delete the file at supplied path if the precondition holds, failing with an
+// UnsupportedOperationError if adapter cannot delete conditionally.
+func (fs *filesystem) DeleteIf(ctx context.Context, path Path, cond Precondition) error {
+	path, err := NewPath(string(path))
+	if err != nil {
+		return err
+	}
+	w, ok := fs.adapter.(ConditionalWriter)
+	if !ok {
+		return NewUnsupportedOperationError(path, "DeleteIf")
+	}
+	return w.DeleteIf(ctx, path, cond)
+}
+
+// WriteIf will write the supplied content at supplied path if the precondition holds.
+func (mm *mountManager) WriteIf(ctx context.Context, path Path, content []byte, cond Precondition, opts ...WriteOption) error {
+	mgr, subPath, err := mm.managerFor(path)
+	if err != nil {
+		return err
+	}
+	return mgr.WriteIf(ctx, subPath, content, cond, opts...)
+}
+
+// DeleteIf will delete the file at supplied path if the precondition holds.
+func (mm *mountManager) DeleteIf(ctx context.Context, path Path, cond Precondition) error {
+	mgr, subPath, err := mm.managerFor(path)
+	if err != nil {
+		return err
+	}
+	return mgr.DeleteIf(ctx, subPath, cond)
+}
@@ -8,7 +8,7 @@ type Config struct {
 
 // EmptyConfig will create a new empty configuration.
 func EmptyConfig() *Config {
-	return &Config{}
+	return &Config{settings: make(map[string]interface{})}
 }
 
 // Get a setting.
@@ -0,0 +1,37 @@
+package filesystem
+
+import "context"
+
+// CrossCopier is implemented by adapters able to copy a file to another adapter sharing their backend natively, e.g.
+// between two buckets of the same S3 service, without streaming its content through the client.
+type CrossCopier interface {
+	// CopyTo will copy the file at path to newpath of target, reporting false without copying when target does not
+	// share the backend of the adapter.
+	CopyTo(ctx context.Context, path Path, target Adapter, newpath Path) (bool, error)
+}
+
+// copyAcross will copy the file at path of src to newpath of dst natively, when both are file systems created by New
+// whose adapters share their backend, reporting whether the file was copied.
+func copyAcross(ctx context.Context, src Interface, path Path, dst Interface, newpath Path) (bool, error) {
+	from, ok := src.(*filesystem)
+	if !ok {
+		return false, nil
+	}
+	to, ok := dst.(*filesystem)
+	if !ok {
+		return false, nil
+	}
+	cc, ok := from.adapter.(CrossCopier)
+	if !ok {
+		return false, nil
+	}
+	path, err := NewPath(string(path))
+	if err != nil {
+		return false, err
+	}
+	newpath, err = NewPath(string(newpath))
+	if err != nil {
+		return false, err
+	}
+	return cc.CopyTo(ctx, path, to.adapter, newpath)
+}
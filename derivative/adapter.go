@@ -0,0 +1,86 @@
+package derivative
+
+import (
+	"context"
+	"io"
+
+	"github.com/maurofran/filesystem"
+)
+
+// Wrap will decorate provided adapter so that variants of a file are invalidated whenever the file changes. When
+// eager is true they are regenerated right away instead of on next Get.
+func Wrap(a filesystem.Adapter, m *Manager, eager bool) filesystem.Adapter {
+	return &adapter{Adapter: a, manager: m, eager: eager}
+}
+
+type adapter struct {
+	filesystem.Adapter
+	manager *Manager
+	eager   bool
+}
+
+func (a *adapter) changed(ctx context.Context, path filesystem.Path, err error) error {
+	if err != nil || a.manager.IsDerived(path) {
+		return err
+	}
+	if a.eager {
+		return a.manager.GenerateAll(ctx, path)
+	}
+	return a.manager.Invalidate(ctx, path)
+}
+
+func (a *adapter) removed(ctx context.Context, path filesystem.Path, err error) error {
+	if err != nil || a.manager.IsDerived(path) {
+		return err
+	}
+	return a.manager.Invalidate(ctx, path)
+}
+
+func (a *adapter) Write(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	return a.changed(ctx, path, a.Adapter.Write(ctx, path, content, cfg))
+}
+
+func (a *adapter) WriteStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	return a.changed(ctx, path, a.Adapter.WriteStream(ctx, path, r, cfg))
+}
+
+func (a *adapter) Update(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	return a.changed(ctx, path, a.Adapter.Update(ctx, path, content, cfg))
+}
+
+func (a *adapter) UpdateStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	return a.changed(ctx, path, a.Adapter.UpdateStream(ctx, path, r, cfg))
+}
+
+func (a *adapter) Put(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	return a.changed(ctx, path, a.Adapter.Put(ctx, path, content, cfg))
+}
+
+func (a *adapter) PutStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	return a.changed(ctx, path, a.Adapter.PutStream(ctx, path, r, cfg))
+}
+
+func (a *adapter) Copy(ctx context.Context, path, newpath filesystem.Path) error {
+	return a.changed(ctx, newpath, a.Adapter.Copy(ctx, path, newpath))
+}
+
+func (a *adapter) Move(ctx context.Context, path, newpath filesystem.Path) error {
+	if err := a.removed(ctx, path, a.Adapter.Move(ctx, path, newpath)); err != nil {
+		return err
+	}
+	return a.changed(ctx, newpath, nil)
+}
+
+func (a *adapter) Delete(ctx context.Context, path filesystem.Path) error {
+	return a.removed(ctx, path, a.Adapter.Delete(ctx, path))
+}
+
+func (a *adapter) ReadAndDelete(ctx context.Context, path filesystem.Path) ([]byte, error) {
+	content, err := a.Adapter.ReadAndDelete(ctx, path)
+	return content, a.removed(ctx, path, err)
+}
+
+// Close will close the decorated adapter.
+func (a *adapter) Close() error {
+	return filesystem.Close(a.Adapter)
+}
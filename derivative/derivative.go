@@ -0,0 +1,167 @@
+package derivative
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/maurofran/filesystem"
+	"github.com/maurofran/filesystem/internal/imaging"
+)
+
+// Transformer produces a derived variant of a file.
+type Transformer interface {
+	// Transform will write to w the variant of content read from r.
+	Transform(w io.Writer, r io.Reader) error
+}
+
+// TransformerFunc is a function acting as a transformer.
+type TransformerFunc func(w io.Writer, r io.Reader) error
+
+// Transform will invoke the function.
+func (f TransformerFunc) Transform(w io.Writer, r io.Reader) error {
+	return f(w, r)
+}
+
+// Thumbnail will create a transformer fitting images within width x height, encoded in provided format ("jpeg",
+// "png" or "gif", empty to keep the source one).
+func Thumbnail(width, height int, format string) Transformer {
+	return TransformerFunc(func(w io.Writer, r io.Reader) error {
+		return imaging.Thumbnail(w, r, width, height, format)
+	})
+}
+
+// Manager generates derived variants of files on demand and caches them under a prefix, at
+// <prefix>/<variant>/<source path>.
+type Manager struct {
+	fs       filesystem.Interface
+	prefix   filesystem.Path
+	mu       sync.RWMutex
+	variants map[string]Transformer
+}
+
+// New will create a new manager storing variants on file system under provided prefix.
+func New(fs filesystem.Interface, prefix filesystem.Path) *Manager {
+	return &Manager{fs: fs, prefix: prefix, variants: make(map[string]Transformer)}
+}
+
+// Register will add a variant produced by provided transformer.
+func (m *Manager) Register(variant string, t Transformer) {
+	m.mu.Lock()
+	m.variants[variant] = t
+	m.mu.Unlock()
+}
+
+// Path is the path where the variant of file at provided path is stored.
+func (m *Manager) Path(path filesystem.Path, variant string) filesystem.Path {
+	return m.prefix.Join(variant, string(path))
+}
+
+// IsDerived will check if provided path is the path of a variant. Under a root prefix, paths whose first segment
+// names a registered variant are variants.
+func (m *Manager) IsDerived(path filesystem.Path) bool {
+	if m.prefix != filesystem.RootPath {
+		return strings.HasPrefix(string(path), string(m.prefix)+"/")
+	}
+	i := strings.Index(string(path), "/")
+	if i < 0 {
+		return false
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.variants[string(path)[:i]]
+	return ok
+}
+
+func (m *Manager) transformer(variant string) (Transformer, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	t, ok := m.variants[variant]
+	if !ok {
+		return nil, fmt.Errorf("Variant %s is not registered", variant)
+	}
+	return t, nil
+}
+
+// Get will return the variant of file at provided path, generating it if missing or older than the source.
+func (m *Manager) Get(ctx context.Context, path filesystem.Path, variant string) (io.ReadCloser, error) {
+	derived := m.Path(path, variant)
+	fresh, err := m.isFresh(ctx, path, derived)
+	if err != nil {
+		return nil, err
+	}
+	if !fresh {
+		if err := m.Generate(ctx, path, variant); err != nil {
+			return nil, err
+		}
+	}
+	return m.fs.ReadStream(ctx, derived)
+}
+
+func (m *Manager) isFresh(ctx context.Context, path, derived filesystem.Path) (bool, error) {
+	exists, err := m.fs.Has(ctx, derived)
+	if err != nil || !exists {
+		return false, err
+	}
+	sourceTime, err := m.fs.GetTimestamp(ctx, path)
+	if err != nil {
+		return false, err
+	}
+	derivedTime, err := m.fs.GetTimestamp(ctx, derived)
+	if err != nil {
+		return false, err
+	}
+	return !derivedTime.Before(sourceTime), nil
+}
+
+// Generate will (re)generate the variant of file at provided path.
+func (m *Manager) Generate(ctx context.Context, path filesystem.Path, variant string) error {
+	t, err := m.transformer(variant)
+	if err != nil {
+		return err
+	}
+	src, err := m.fs.ReadStream(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(t.Transform(pw, src))
+	}()
+	err = m.fs.PutStream(ctx, m.Path(path, variant), pr)
+	pr.CloseWithError(err)
+	return err
+}
+
+// GenerateAll will (re)generate every registered variant of file at provided path.
+func (m *Manager) GenerateAll(ctx context.Context, path filesystem.Path) error {
+	for _, variant := range m.variantNames() {
+		if err := m.Generate(ctx, path, variant); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Invalidate will delete every cached variant of file at provided path.
+func (m *Manager) Invalidate(ctx context.Context, path filesystem.Path) error {
+	for _, variant := range m.variantNames() {
+		if _, err := m.fs.Delete(ctx, m.Path(path, variant)); err != nil && !filesystem.IsFileNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Manager) variantNames() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	names := make([]string, 0, len(m.variants))
+	for name := range m.variants {
+		names = append(names, name)
+	}
+	return names
+}
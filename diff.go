@@ -0,0 +1,138 @@
+package filesystem
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// ChangeKind is the kind of a change between two trees.
+type ChangeKind string
+
+// Change kinds of Change.
+const (
+	ChangeAdded     ChangeKind = "added"
+	ChangeRemoved   ChangeKind = "removed"
+	ChangeModified  ChangeKind = "modified"
+	ChangeUnchanged ChangeKind = "unchanged"
+)
+
+// ChangeReason is what tells a modified file apart from its previous state.
+type ChangeReason string
+
+// Change reasons of modified files.
+const (
+	ReasonSize      ChangeReason = "size"
+	ReasonChecksum  ChangeReason = "checksum"
+	ReasonTimestamp ChangeReason = "timestamp"
+)
+
+// Change is a difference of a file between two trees.
+type Change struct {
+	// Kind is the kind of the change.
+	Kind ChangeKind
+	// Reason tells why a file is modified, empty for the other kinds.
+	Reason ChangeReason
+	// Path is the path of the file, relative to the compared directory.
+	Path Path
+	// Old is the metadata of the file in the first tree, nil if added.
+	Old Metadata
+	// New is the metadata of the file in the second tree, nil if removed.
+	New Metadata
+}
+
+// DiffOptions configures a comparison.
+type DiffOptions struct {
+	// Checksum is the algorithm whose checksums tell apart files of the same size, if not zero. Otherwise files of
+	// the same size are modified when the second one is newer, as a copy usually is newer than its source.
+	Checksum ChecksumAlgo
+	// Filter selects the files to compare by their relative path, every file if nil.
+	Filter func(path Path) bool
+	// Unchanged reports the files that did not change too, with ChangeUnchanged kind.
+	Unchanged bool
+}
+
+// Diff will compare the files in the directory at supplied path of a with the ones of b, reporting the changes
+// turning the first tree into the second one, sorted by path. Directories are not compared.
+func Diff(ctx context.Context, a, b Interface, path Path, opts DiffOptions) ([]Change, error) {
+	oldFiles, err := diffListing(ctx, a, path, opts.Filter)
+	if err != nil {
+		return nil, err
+	}
+	newFiles, err := diffListing(ctx, b, path, opts.Filter)
+	if err != nil {
+		return nil, err
+	}
+	var changes []Change
+	for p, n := range newFiles {
+		o, ok := oldFiles[p]
+		if !ok {
+			changes = append(changes, Change{Kind: ChangeAdded, Path: p, New: n})
+			continue
+		}
+		reason, err := diffFiles(ctx, a, b, o, n, opts.Checksum)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case reason != "":
+			changes = append(changes, Change{Kind: ChangeModified, Reason: reason, Path: p, Old: o, New: n})
+		case opts.Unchanged:
+			changes = append(changes, Change{Kind: ChangeUnchanged, Path: p, Old: o, New: n})
+		}
+	}
+	for p, o := range oldFiles {
+		if _, ok := newFiles[p]; !ok {
+			changes = append(changes, Change{Kind: ChangeRemoved, Path: p, Old: o})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+// diffListing will list the files in the directory at supplied path selected by filter, by relative path.
+func diffListing(ctx context.Context, fs Interface, path Path, filter func(Path) bool) (map[Path]Metadata, error) {
+	listing, err := fs.ListContents(ctx, path, true)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[Path]Metadata, len(listing))
+	for _, md := range listing {
+		if md.IsDir() {
+			continue
+		}
+		rel := Path(strings.TrimPrefix(string(md.Path), string(path)+"/"))
+		if path == RootPath {
+			rel = md.Path
+		}
+		if filter == nil || filter(rel) {
+			files[rel] = md
+		}
+	}
+	return files, nil
+}
+
+// diffFiles will tell why the file of b differs from the one of a, or return an empty reason if it does not.
+func diffFiles(ctx context.Context, a, b Interface, o, n Metadata, algo ChecksumAlgo) (ChangeReason, error) {
+	if o.Size >= 0 && n.Size >= 0 && o.Size != n.Size {
+		return ReasonSize, nil
+	}
+	if algo != 0 {
+		oldSum, err := a.GetChecksum(ctx, o.Path, algo)
+		if err != nil {
+			return "", err
+		}
+		newSum, err := b.GetChecksum(ctx, n.Path, algo)
+		if err != nil {
+			return "", err
+		}
+		if oldSum != newSum {
+			return ReasonChecksum, nil
+		}
+		return "", nil
+	}
+	if !o.Timestamp.IsZero() && !n.Timestamp.IsZero() && n.Timestamp.After(o.Timestamp) {
+		return ReasonTimestamp, nil
+	}
+	return "", nil
+}
@@ -0,0 +1,148 @@
+package filesystem
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// DirectoryInfo describes the content of a directory, including the one of its subdirectories.
+type DirectoryInfo struct {
+	// Path is the path of the directory.
+	Path Path
+	// Files is the number of files in the directory.
+	Files int64
+	// Dirs is the number of subdirectories of the directory.
+	Dirs int64
+	// Size is the aggregate size of the files in the directory.
+	Size int64
+}
+
+// Entries is the number of entries in the directory, files and subdirectories.
+func (di DirectoryInfo) Entries() int64 {
+	return di.Files + di.Dirs
+}
+
+// DirectoryMetadataProvider is implemented by adapters able to describe the content of a directory without listing
+// it. Decorators may return an UnsupportedOperationError when the adapter they decorate is not a provider, so that
+// the directory is listed instead.
+type DirectoryMetadataProvider interface {
+	GetDirectoryMetadata(ctx context.Context, path Path) (DirectoryInfo, error)
+}
+
+// ConfigDirectoryMetadataTTL is the time.Duration directory metadata is cached for, set by
+// WithDirectoryMetadataCache.
+const ConfigDirectoryMetadataTTL = "dirmetadata.ttl"
+
+// WithDirectoryMetadataCache will make GetDirectoryMetadata cache its results for ttl. Cached results do not reflect
+// the writes made in the meantime, which suits dashboards better than consistency checks.
+func WithDirectoryMetadataCache(ttl time.Duration) NewOption {
+	return newOption(func(cfg *Config) {
+		cfg.Set(ConfigDirectoryMetadataTTL, ttl)
+	})
+}
+
+type cachedDirectoryInfo struct {
+	info    DirectoryInfo
+	expires time.Time
+}
+
+// dirInfoCache holds the directory metadata cached by a file system.
+type dirInfoCache struct {
+	mu      sync.Mutex
+	entries map[Path]cachedDirectoryInfo
+}
+
+func (c *dirInfoCache) get(path Path) (DirectoryInfo, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[path]
+	if !ok || time.Now().After(e.expires) {
+		delete(c.entries, path)
+		return DirectoryInfo{}, false
+	}
+	return e.info, true
+}
+
+func (c *dirInfoCache) put(info DirectoryInfo, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[Path]cachedDirectoryInfo)
+	}
+	c.entries[info.Path] = cachedDirectoryInfo{info: info, expires: time.Now().Add(ttl)}
+}
+
+// GetDirectoryMetadata will count the files and subdirectories of the directory at supplied path, and sum the sizes
+// of its files, natively when adapter supports it and walking the directory otherwise.
+func (fs *filesystem) GetDirectoryMetadata(ctx context.Context, path Path) (DirectoryInfo, error) {
+	path, err := NewPath(string(path))
+	if err != nil {
+		return DirectoryInfo{}, err
+	}
+	ttl, _ := fs.Config().Get(ConfigDirectoryMetadataTTL, time.Duration(0)).(time.Duration)
+	if ttl > 0 {
+		if info, ok := fs.dirInfos.get(path); ok {
+			return info, nil
+		}
+	}
+	info, err := fs.directoryMetadata(ctx, path)
+	if err == nil && ttl > 0 {
+		fs.dirInfos.put(info, ttl)
+	}
+	return info, err
+}
+
+func (fs *filesystem) directoryMetadata(ctx context.Context, path Path) (DirectoryInfo, error) {
+	if p, ok := fs.adapter.(DirectoryMetadataProvider); ok {
+		info, err := p.GetDirectoryMetadata(ctx, path)
+		if !IsUnsupportedOperationError(err) {
+			return info, err
+		}
+	}
+	exists, err := fs.DirectoryExists(ctx, path)
+	if err != nil {
+		return DirectoryInfo{}, err
+	}
+	if !exists {
+		return DirectoryInfo{}, NewFileNotFoundError(path)
+	}
+	it, err := fs.IterateContents(ctx, path, true)
+	if err != nil {
+		return DirectoryInfo{}, err
+	}
+	info := DirectoryInfo{Path: path}
+	for {
+		md, err := it.Next()
+		if err == io.EOF {
+			return info, nil
+		}
+		if err != nil {
+			return DirectoryInfo{}, err
+		}
+		if md.IsDir() {
+			info.Dirs++
+			continue
+		}
+		info.Files++
+		size := md.Size
+		if size < 0 {
+			if size, err = fs.adapter.GetFileSize(ctx, md.Path); err != nil {
+				return DirectoryInfo{}, err
+			}
+		}
+		info.Size += size
+	}
+}
+
+// GetDirectoryMetadata will describe the content of the directory at supplied path.
+func (mm *mountManager) GetDirectoryMetadata(ctx context.Context, path Path) (DirectoryInfo, error) {
+	mgr, subPath, err := mm.managerFor(path)
+	if err != nil {
+		return DirectoryInfo{}, err
+	}
+	info, err := mgr.GetDirectoryMetadata(ctx, subPath)
+	info.Path = path
+	return info, err
+}
@@ -0,0 +1,239 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// DirCopier is implemented by adapters able to copy a whole directory natively.
+type DirCopier interface {
+	CopyDir(ctx context.Context, path, newpath Path, cfg Config) error
+}
+
+// DirMover is implemented by adapters able to move a whole directory natively, e.g. with a rename.
+type DirMover interface {
+	MoveDir(ctx context.Context, path, newpath Path) error
+}
+
+// DirTransferError is the error returned when a directory copy or move stops half way. Completed holds the source
+// paths of the files already transferred, which are left in place.
+type DirTransferError interface {
+	error
+	Path() Path
+	Completed() []Path
+	Unwrap() error
+}
+
+type dirTransferError struct {
+	op        string
+	path      Path
+	completed []Path
+	err       error
+}
+
+// Path is the path of the file that could not be transferred.
+func (e dirTransferError) Path() Path {
+	return e.path
+}
+
+// Completed are the source paths of the files transferred before the failure.
+func (e dirTransferError) Completed() []Path {
+	return e.completed
+}
+
+// Unwrap will return the error of the failed transfer.
+func (e dirTransferError) Unwrap() error {
+	return e.err
+}
+
+func (e dirTransferError) Error() string {
+	return fmt.Sprintf("Unable to %s %s after %d files: %v", e.op, e.path, len(e.completed), e.err)
+}
+
+// IsDirTransferError will check if provided error is a directory transfer error.
+func IsDirTransferError(err error) bool {
+	_, ok := err.(DirTransferError)
+	return ok
+}
+
+// transferDir will copy or move the content of directory path of src to newpath of dst, walking the source
+// listing and leaving out the entries matched by ignore rules, if any. Files are moved or copied natively when
+// source and destination are the same file system or share their backend, and streamed otherwise.
+func transferDir(ctx context.Context, src Interface, path Path, dst Interface, newpath Path, same, move bool, ignore *IgnoreRules) error {
+	op := "copy"
+	if move {
+		op = "move"
+	}
+	if err := checkDirTransfer(op, path, newpath); err != nil {
+		return err
+	}
+	listing, err := src.ListContents(ctx, path, true)
+	if err != nil {
+		return err
+	}
+	if err := dst.CreateDir(ctx, newpath); err != nil {
+		return dirTransferError{op: op, path: path, err: err}
+	}
+	var completed []Path
+	for _, md := range listing {
+		if ignore.Ignored(md.Path, md.IsDir()) {
+			continue
+		}
+		p := md.Path
+		target := newpath.Join(strings.TrimPrefix(string(p), string(path)+"/"))
+		if md.IsDir() {
+			err = dst.CreateDir(ctx, target)
+		} else {
+			err = transferFile(ctx, src, p, dst, target, same, move)
+		}
+		if err != nil {
+			return dirTransferError{op, p, completed, err}
+		}
+		if !md.IsDir() {
+			completed = append(completed, p)
+		}
+	}
+	if move {
+		if err := src.DeleteDir(ctx, path); err != nil {
+			return dirTransferError{op, path, completed, err}
+		}
+	}
+	return nil
+}
+
+// checkDirTransfer will refuse to transfer a directory inside itself.
+func checkDirTransfer(op string, path, newpath Path) error {
+	if path == RootPath || newpath == path || strings.HasPrefix(string(newpath), string(path)+"/") {
+		return dirTransferError{op: op, path: path, err: fmt.Errorf("destination %s is inside source", newpath)}
+	}
+	return nil
+}
+
+func transferFile(ctx context.Context, src Interface, path Path, dst Interface, newpath Path, same, move bool) error {
+	if same {
+		if move {
+			return src.Move(ctx, path, newpath)
+		}
+		return src.Copy(ctx, path, newpath)
+	}
+	copied, err := copyAcross(ctx, src, path, dst, newpath)
+	if err != nil {
+		return err
+	}
+	if !copied {
+		rc, err := src.ReadStream(ctx, path)
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		if err := dst.PutStream(ctx, newpath, rc); err != nil {
+			return err
+		}
+	}
+	if move {
+		_, err = src.Delete(ctx, path)
+	}
+	return err
+}
+
+// CopyDir will copy the directory at supplied path, with all of its content, to new path.
+func (fs *filesystem) CopyDir(ctx context.Context, path, newpath Path) error {
+	path, newpath, err := normalizePaths(path, newpath)
+	if err != nil {
+		return err
+	}
+	if err := checkDirTransfer("copy", path, newpath); err != nil {
+		return err
+	}
+	if c, ok := fs.adapter.(DirCopier); ok {
+		return c.CopyDir(ctx, path, newpath, *fs.Config())
+	}
+	return transferDir(ctx, fs, path, fs, newpath, true, false, nil)
+}
+
+// MoveDir will move the directory at supplied path, with all of its content, to new path.
+func (fs *filesystem) MoveDir(ctx context.Context, path, newpath Path) error {
+	path, newpath, err := normalizePaths(path, newpath)
+	if err != nil {
+		return err
+	}
+	if err := checkDirTransfer("move", path, newpath); err != nil {
+		return err
+	}
+	if m, ok := fs.adapter.(DirMover); ok {
+		return m.MoveDir(ctx, path, newpath)
+	}
+	return transferDir(ctx, fs, path, fs, newpath, true, true, nil)
+}
+
+// CopyDirOptions are the options of CopyDirWith.
+type CopyDirOptions struct {
+	// Ignore leaves out of the copy the entries matched by ignore rules, such as the ones of the .fsignore file of
+	// the source directory loaded with LoadIgnoreRules.
+	Ignore *IgnoreRules
+}
+
+// CopyDirWith will copy the directory at supplied path of file system, with its content, to new path as CopyDir
+// does, applying provided options. Files are copied one by one, since native directory copies cannot leave entries
+// out.
+func CopyDirWith(ctx context.Context, fs Interface, path, newpath Path, opts CopyDirOptions) error {
+	if opts.Ignore == nil {
+		return fs.CopyDir(ctx, path, newpath)
+	}
+	return transferDir(ctx, fs, path, fs, newpath, true, false, opts.Ignore)
+}
+
+func normalizePaths(path, newpath Path) (Path, Path, error) {
+	path, err := NewPath(string(path))
+	if err != nil {
+		return "", "", err
+	}
+	newpath, err = NewPath(string(newpath))
+	if err != nil {
+		return "", "", err
+	}
+	return path, newpath, nil
+}
+
+// CopyDir will copy the directory at supplied path, with all of its content, to new path, possibly on another
+// mount. A copy across mounts stopping half way fails with a DirTransferError listing the files already copied; to
+// copy whole mounts concurrently and resume interrupted copies, use the SyncMounts function of the sync package.
+func (mm *mountManager) CopyDir(ctx context.Context, path, newpath Path) error {
+	mgr1, subPath1, err := mm.managerFor(path)
+	if err != nil {
+		return err
+	}
+	mgr2, subPath2, err := mm.managerFor(newpath)
+	if err != nil {
+		return err
+	}
+	if mm.sameMount(path, newpath) {
+		return mgr1.CopyDir(ctx, subPath1, subPath2)
+	}
+	return transferDir(ctx, mgr1, subPath1, mgr2, subPath2, false, false, nil)
+}
+
+// MoveDir will move the directory at supplied path, with all of its content, to new path, possibly on another
+// mount.
+func (mm *mountManager) MoveDir(ctx context.Context, path, newpath Path) error {
+	mgr1, subPath1, err := mm.managerFor(path)
+	if err != nil {
+		return err
+	}
+	mgr2, subPath2, err := mm.managerFor(newpath)
+	if err != nil {
+		return err
+	}
+	if mm.sameMount(path, newpath) {
+		return mgr1.MoveDir(ctx, subPath1, subPath2)
+	}
+	return transferDir(ctx, mgr1, subPath1, mgr2, subPath2, false, true, nil)
+}
+
+// sameMount will check if both paths are on the same mount, possibly through aliases.
+func (mm *mountManager) sameMount(path, newpath Path) bool {
+	prefix1, _, _ := mm.resolve(path)
+	prefix2, _, _ := mm.resolve(newpath)
+	return prefix1 == prefix2
+}
@@ -0,0 +1,167 @@
+// Package download fetches large files with several range reads in parallel, which improves throughput
+// significantly on high latency object stores, each range being retried on its own and the content optionally
+// verified against the checksum of the file.
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/maurofran/filesystem"
+	"github.com/maurofran/filesystem/retry"
+)
+
+// Defaults of Options.
+const (
+	DefaultPartSize    = 8 << 20
+	DefaultConcurrency = 4
+)
+
+// ErrChecksumMismatch is the error returned when the downloaded content does not match the checksum of the file.
+var ErrChecksumMismatch = errors.New("downloaded content does not match checksum")
+
+// Options configures a download.
+type Options struct {
+	// PartSize is the size of the ranges read, DefaultPartSize if not positive; the last range may be smaller.
+	PartSize int64
+	// Concurrency is the number of ranges read in parallel, DefaultConcurrency if not positive.
+	Concurrency int
+	// Retry is the policy each range read is retried with.
+	Retry retry.Policy
+	// Checksum is the algorithm whose checksum the downloaded content is verified against, if not zero. The
+	// checksum is retrieved with GetChecksum, which reads the whole file when the adapter does not provide it
+	// natively, and the content is read back from the destination, which must be an io.ReaderAt.
+	Checksum filesystem.ChecksumAlgo
+}
+
+func (o Options) withDefaults() Options {
+	if o.PartSize <= 0 {
+		o.PartSize = DefaultPartSize
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = DefaultConcurrency
+	}
+	return o
+}
+
+type part struct {
+	offset int64
+	length int64
+}
+
+// Download will write the content of the file at supplied path to w, reading ranges of it in parallel, and return
+// its size. On failure, w may hold part of the content.
+func Download(ctx context.Context, fs filesystem.Interface, path filesystem.Path, w io.WriterAt, opts Options) (int64, error) {
+	opts = opts.withDefaults()
+	ra, verify := w.(io.ReaderAt)
+	if opts.Checksum != 0 && !verify {
+		return 0, fmt.Errorf("verifying the %s checksum of %s needs a destination implementing io.ReaderAt", opts.Checksum, path)
+	}
+	size, err := fs.GetFileSize(ctx, path)
+	if err != nil {
+		return 0, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		once  sync.Once
+		first error
+		wg    sync.WaitGroup
+	)
+	fail := func(err error) {
+		once.Do(func() {
+			first = err
+			cancel()
+		})
+	}
+	parts := make(chan part)
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range parts {
+				err := opts.Retry.Do(ctx, func() error {
+					return fetch(ctx, fs, path, w, p)
+				})
+				if err != nil {
+					fail(err)
+				}
+			}
+		}()
+	}
+feed:
+	for offset := int64(0); offset < size; offset += opts.PartSize {
+		select {
+		case parts <- part{offset, min(opts.PartSize, size-offset)}:
+		case <-ctx.Done():
+			fail(ctx.Err())
+			break feed
+		}
+	}
+	close(parts)
+	wg.Wait()
+	if first != nil {
+		return 0, first
+	}
+	if opts.Checksum != 0 {
+		want, err := fs.GetChecksum(ctx, path, opts.Checksum)
+		if err != nil {
+			return 0, err
+		}
+		got, err := filesystem.Checksum(io.NewSectionReader(ra, 0, size), opts.Checksum)
+		if err != nil {
+			return 0, err
+		}
+		if got != want {
+			return 0, fmt.Errorf("%w: %s of %s is %s, expected %s", ErrChecksumMismatch, opts.Checksum, path, got, want)
+		}
+	}
+	return size, nil
+}
+
+// fetch will read the range of the file described by p into w.
+func fetch(ctx context.Context, fs filesystem.Interface, path filesystem.Path, w io.WriterAt, p part) error {
+	rc, err := fs.ReadRange(ctx, path, p.offset, p.length)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	n, err := io.Copy(io.NewOffsetWriter(w, p.offset), rc)
+	if err != nil {
+		return err
+	}
+	if n != p.length {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+// ToFile will download the file at supplied path to the local file with provided name, replacing it. The content
+// is downloaded to a temporary file in the same directory, renamed once complete and verified, and removed on
+// failure.
+func ToFile(ctx context.Context, fs filesystem.Interface, path filesystem.Path, name string, opts Options) (int64, error) {
+	f, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".download-*")
+	if err != nil {
+		return 0, err
+	}
+	size, err := Download(ctx, fs, path, f, opts)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), name)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return 0, err
+	}
+	return size, nil
+}
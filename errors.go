@@ -31,6 +31,18 @@ func pluginNotFoundError(method string) PluginError {
 	return &pluginError{message: "No plugin found for method %s", method: method}
 }
 
+func pluginTypeError(method string) PluginError {
+	return &pluginError{message: "Plugin %s does not handle the request or response types it was invoked with", method: method}
+}
+
+func pluginRoutingError(method string) PluginError {
+	return &pluginError{message: "Unable to route plugin %s: its path arguments must address a single mount", method: method}
+}
+
+func pluginsUnsupportedError(method string) PluginError {
+	return &pluginError{message: "Unable to add plugin %s: file system does not accept plugins", method: method}
+}
+
 // PathError is the error if provided path is not valid.
 type PathError interface {
 	error
@@ -61,6 +73,22 @@ func invalidPathError(path Path) PathError {
 	return pathError{"Path %s as an invalid prefix", path}
 }
 
+func pathOutsideRootError(path Path) PathError {
+	return pathError{"Path %s is outside of root", path}
+}
+
+func pathNotNormalizedError(path Path) PathError {
+	return pathError{"Path %s is not normalized", path}
+}
+
+func invalidCharactersError(path Path) PathError {
+	return pathError{"Path %q contains invalid characters", path}
+}
+
+func invalidUploadIDError(path Path) PathError {
+	return pathError{"Upload session of %s has an invalid ID", path}
+}
+
 // MountError is the error returned when a mount already exists.
 type MountError interface {
 	error
@@ -97,7 +125,25 @@ func mountExistsError(prefix string) MountError {
 
 // FileNotFoundError is the error raised when a file was not found.
 type FileNotFoundError interface {
+	error
 	Path() Path
+	// FileNotFound tells file not found errors apart from other path errors.
+	FileNotFound()
+}
+
+type fileNotFoundError struct {
+	path Path
+}
+
+// Path is the path of the missing file.
+func (e fileNotFoundError) Path() Path {
+	return e.path
+}
+
+func (e fileNotFoundError) FileNotFound() {}
+
+func (e fileNotFoundError) Error() string {
+	return fmt.Sprintf("File %s not found", e.path)
 }
 
 // IsFileNotFound will check if file is not found
@@ -105,3 +151,176 @@ func IsFileNotFound(err error) bool {
 	_, ok := err.(FileNotFoundError)
 	return ok
 }
+
+// NewFileNotFoundError will create the error adapters return when the file at provided path does not exist.
+func NewFileNotFoundError(path Path) FileNotFoundError {
+	return fileNotFoundError{path}
+}
+
+// ReadOnlyError is the error returned when writing to a read-only file system.
+type ReadOnlyError interface {
+	error
+	Path() Path
+}
+
+type readOnlyError struct {
+	path Path
+}
+
+// Path is the path that was to be written.
+func (e readOnlyError) Path() Path {
+	return e.path
+}
+
+func (e readOnlyError) Error() string {
+	return fmt.Sprintf("Unable to write %s: file system is read-only", e.path)
+}
+
+// IsReadOnlyError will check if provided error is a read-only error.
+func IsReadOnlyError(err error) bool {
+	_, ok := err.(ReadOnlyError)
+	return ok
+}
+
+// NewReadOnlyError will create the error adapters return when writing at provided path is not supported.
+func NewReadOnlyError(path Path) ReadOnlyError {
+	return readOnlyError{path}
+}
+
+// UnsupportedOperationError is the error returned when the adapter does not support an optional operation.
+type UnsupportedOperationError interface {
+	error
+	Path() Path
+	Operation() string
+}
+
+type unsupportedOperationError struct {
+	path      Path
+	operation string
+}
+
+// Path is the path the operation was attempted on.
+func (e unsupportedOperationError) Path() Path {
+	return e.path
+}
+
+// Operation is the name of the unsupported operation.
+func (e unsupportedOperationError) Operation() string {
+	return e.operation
+}
+
+func (e unsupportedOperationError) Error() string {
+	return fmt.Sprintf("Operation %s on %s not supported by adapter", e.operation, e.path)
+}
+
+// IsUnsupportedOperationError will check if provided error is an unsupported operation error.
+func IsUnsupportedOperationError(err error) bool {
+	_, ok := err.(UnsupportedOperationError)
+	return ok
+}
+
+// NewUnsupportedOperationError will create the error returned when adapter does not support provided operation.
+func NewUnsupportedOperationError(path Path, operation string) UnsupportedOperationError {
+	return unsupportedOperationError{path, operation}
+}
+
+// PreconditionFailedError is the error returned when the precondition of a conditional operation does not hold.
+type PreconditionFailedError interface {
+	error
+	Path() Path
+}
+
+type preconditionFailedError struct {
+	path Path
+}
+
+// Path is the path the conditional operation was attempted on.
+func (e preconditionFailedError) Path() Path {
+	return e.path
+}
+
+func (e preconditionFailedError) Error() string {
+	return fmt.Sprintf("Precondition on %s failed", e.path)
+}
+
+// IsPreconditionFailed will check if provided error is a precondition failed error.
+func IsPreconditionFailed(err error) bool {
+	_, ok := err.(PreconditionFailedError)
+	return ok
+}
+
+// NewPreconditionFailedError will create the error adapters return when the precondition on provided path does not
+// hold.
+func NewPreconditionFailedError(path Path) PreconditionFailedError {
+	return preconditionFailedError{path}
+}
+
+// LockedError is the error returned when a file cannot be locked because of a conflicting lock.
+type LockedError interface {
+	error
+	Path() Path
+}
+
+type lockedError struct {
+	path Path
+}
+
+// Path is the path that was to be locked.
+func (e lockedError) Path() Path {
+	return e.path
+}
+
+func (e lockedError) Error() string {
+	return fmt.Sprintf("File %s is locked", e.path)
+}
+
+// IsLockedError will check if provided error is a locked error.
+func IsLockedError(err error) bool {
+	_, ok := err.(LockedError)
+	return ok
+}
+
+// NewLockedError will create the error adapters return when the file at provided path holds a conflicting lock.
+func NewLockedError(path Path) LockedError {
+	return lockedError{path}
+}
+
+// UploadOffsetError is the error returned when a chunk of an upload session is not written at the offset the
+// session expects.
+type UploadOffsetError interface {
+	error
+	Path() Path
+	// Offset is the offset the session expects the next chunk at.
+	Offset() int64
+}
+
+type uploadOffsetError struct {
+	path   Path
+	offset int64
+}
+
+// Path is the path of the file being uploaded.
+func (e uploadOffsetError) Path() Path {
+	return e.path
+}
+
+// Offset is the offset the session expects the next chunk at.
+func (e uploadOffsetError) Offset() int64 {
+	return e.offset
+}
+
+func (e uploadOffsetError) Error() string {
+	return fmt.Sprintf("Upload of %s expects next chunk at offset %d", e.path, e.offset)
+}
+
+// IsUploadOffsetError will check if provided error is an upload offset error.
+func IsUploadOffsetError(err error) bool {
+	_, ok := err.(UploadOffsetError)
+	return ok
+}
+
+// NewUploadOffsetError will create the error adapters return when a chunk of the upload of the file at provided path
+// is not written at the expected offset.
+func NewUploadOffsetError(path Path, offset int64) UploadOffsetError {
+	return uploadOffsetError{path, offset}
+}
@@ -0,0 +1,165 @@
+// Package events provides an event bus notified of the writes, deletes and moves performed on file systems, letting
+// listeners invalidate caches, index content or emit webhooks from one place.
+package events
+
+import (
+	"context"
+	"sync"
+
+	"github.com/maurofran/filesystem"
+)
+
+// Type is the type of an event.
+type Type string
+
+// Event types emitted by the middleware of a bus.
+const (
+	// BeforeWrite is emitted before a file is written; a listener failing aborts the write.
+	BeforeWrite Type = "before.write"
+	// AfterWrite is emitted once a file was written.
+	AfterWrite Type = "after.write"
+	// AfterDelete is emitted once a file or a directory was deleted.
+	AfterDelete Type = "after.delete"
+	// AfterMove is emitted once a file or a directory was moved.
+	AfterMove Type = "after.move"
+)
+
+// Event is an operation notified to listeners.
+type Event struct {
+	// Type is the type of the event.
+	Type Type
+	// Operation is the name of the Interface method performing the operation, e.g. "PutStream".
+	Operation string
+	// Path is the path of the file or directory.
+	Path filesystem.Path
+	// NewPath is the destination of moves, empty for the other events.
+	NewPath filesystem.Path
+	// Size is the number of bytes written, -1 when unknown, as it is before writes.
+	Size int64
+	// Config holds the options given to writes, nil for the other events.
+	Config *filesystem.Config
+}
+
+// Listener is notified of events. The errors of listeners are only returned for BeforeWrite events, aborting the
+// write; they are ignored otherwise.
+type Listener func(ctx context.Context, e Event) error
+
+type subscription struct {
+	id       int
+	listener Listener
+}
+
+// Bus dispatches events to the listeners subscribed to their type. It is safe for concurrent use.
+type Bus struct {
+	mu        sync.RWMutex
+	next      int
+	listeners map[Type][]subscription
+}
+
+// NewBus will create a new bus without listeners.
+func NewBus() *Bus {
+	return &Bus{listeners: make(map[Type][]subscription)}
+}
+
+// Subscribe will subscribe provided listener to the events of provided type, returning the function unsubscribing
+// it.
+func (b *Bus) Subscribe(t Type, l Listener) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.next++
+	id := b.next
+	b.listeners[t] = append(b.listeners[t], subscription{id, l})
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		subs := b.listeners[t]
+		for i, s := range subs {
+			if s.id == id {
+				b.listeners[t] = append(subs[:i:i], subs[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Emit will notify provided event to the listeners subscribed to its type, in subscription order, stopping at the
+// first one failing.
+func (b *Bus) Emit(ctx context.Context, e Event) error {
+	b.mu.RLock()
+	subs := b.listeners[e.Type]
+	b.mu.RUnlock()
+	for _, s := range subs {
+		if err := s.listener(ctx, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// operationTypes are the event types emitted after each operation, the write operations emitting BeforeWrite too.
+// WriterAt emits its write events when the writer is opened.
+var operationTypes = map[string]Type{
+	"Write":          AfterWrite,
+	"Copy":           AfterWrite,
+	"CopyDir":        AfterWrite,
+	"WriterAt":       AfterWrite,
+	"RestoreVersion": AfterWrite,
+	"WriteIf":        AfterWrite,
+	"WriteStream":    AfterWrite,
+	"WriteAtomic":    AfterWrite,
+	"Update":         AfterWrite,
+	"UpdateStream":   AfterWrite,
+	"Put":            AfterWrite,
+	"PutStream":      AfterWrite,
+	"Delete":         AfterDelete,
+	"DeleteIf":       AfterDelete,
+	"ReadAndDelete":  AfterDelete,
+	"DeleteDir":      AfterDelete,
+	"Move":           AfterMove,
+	"MoveDir":        AfterMove,
+}
+
+// Middleware will create a middleware emitting the events of the operations performed on bus. After events are
+// only emitted for operations that succeeded.
+func (b *Bus) Middleware() filesystem.Middleware {
+	return func(next filesystem.Handler) filesystem.Handler {
+		return func(ctx context.Context, op *filesystem.Operation) error {
+			t, ok := operationTypes[op.Name]
+			if !ok {
+				return next(ctx, op)
+			}
+			if t == AfterWrite {
+				if err := b.Emit(ctx, newEvent(BeforeWrite, op)); err != nil {
+					return err
+				}
+			}
+			if err := next(ctx, op); err != nil {
+				return err
+			}
+			b.Emit(ctx, newEvent(t, op))
+			return nil
+		}
+	}
+}
+
+// copies are the operations writing their destination, the written path of their events.
+var copies = map[string]bool{"Copy": true, "CopyDir": true}
+
+func newEvent(t Type, op *filesystem.Operation) Event {
+	if copies[op.Name] {
+		return Event{Type: t, Operation: op.Name, Path: op.NewPath, Size: op.Bytes, Config: op.Config}
+	}
+	return Event{
+		Type:      t,
+		Operation: op.Name,
+		Path:      op.Path,
+		NewPath:   op.NewPath,
+		Size:      op.Bytes,
+		Config:    op.Config,
+	}
+}
+
+// Wrap will make provided file system emit the events of its operations on bus.
+func Wrap(fs filesystem.Interface, b *Bus) filesystem.Interface {
+	return filesystem.WithMiddleware(fs, b.Middleware())
+}
@@ -0,0 +1,91 @@
+package filesystem
+
+import (
+	"context"
+	"io"
+)
+
+// ExistenceChecker is implemented by adapters able to tell files and directories apart natively, e.g. checking an
+// object key and a key prefix respectively. Decorators may return an UnsupportedOperationError when the adapter they
+// decorate is not an ExistenceChecker, so that the entry is looked up listing its parent directory.
+type ExistenceChecker interface {
+	FileExists(ctx context.Context, path Path) (bool, error)
+	DirectoryExists(ctx context.Context, path Path) (bool, error)
+}
+
+// FileExists will check if a file exists at supplied path, directories not counting.
+func (fs *filesystem) FileExists(ctx context.Context, path Path) (bool, error) {
+	path, err := NewPath(string(path))
+	if err != nil {
+		return false, err
+	}
+	if c, ok := fs.adapter.(ExistenceChecker); ok {
+		exists, err := c.FileExists(ctx, path)
+		if !IsUnsupportedOperationError(err) {
+			return exists, err
+		}
+	}
+	md, err := fs.lookup(ctx, path)
+	return md != nil && !md.IsDir(), err
+}
+
+// DirectoryExists will check if a directory exists at supplied path. The root directory always exists.
+func (fs *filesystem) DirectoryExists(ctx context.Context, path Path) (bool, error) {
+	path, err := NewPath(string(path))
+	if err != nil {
+		return false, err
+	}
+	if path == RootPath {
+		return true, nil
+	}
+	if c, ok := fs.adapter.(ExistenceChecker); ok {
+		exists, err := c.DirectoryExists(ctx, path)
+		if !IsUnsupportedOperationError(err) {
+			return exists, err
+		}
+	}
+	md, err := fs.lookup(ctx, path)
+	return md != nil && md.IsDir(), err
+}
+
+// lookup will find the entry at path listing its parent directory, returning nil if there is none.
+func (fs *filesystem) lookup(ctx context.Context, path Path) (Metadata, error) {
+	it, err := fs.IterateContents(ctx, path.Dir(), false)
+	if IsFileNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer closeIterator(it)
+	for {
+		md, err := it.Next()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if md.Path == path {
+			return md, nil
+		}
+	}
+}
+
+// FileExists will check if a file exists at supplied path.
+func (mm *mountManager) FileExists(ctx context.Context, path Path) (bool, error) {
+	mgr, subPath, err := mm.managerFor(path)
+	if err != nil {
+		return false, err
+	}
+	return mgr.FileExists(ctx, subPath)
+}
+
+// DirectoryExists will check if a directory exists at supplied path.
+func (mm *mountManager) DirectoryExists(ctx context.Context, path Path) (bool, error) {
+	mgr, subPath, err := mm.managerFor(path)
+	if err != nil {
+		return false, err
+	}
+	return mgr.DirectoryExists(ctx, subPath)
+}
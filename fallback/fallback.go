@@ -0,0 +1,276 @@
+// Package fallback provides a read-through adapter serving files from a primary adapter and falling back to a
+// secondary one for the files the primary does not have, e.g. to migrate data lazily between storage backends.
+package fallback
+
+import (
+	"context"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/maurofran/filesystem"
+)
+
+// Options configures the fallback.
+type Options struct {
+	// CopyBack enables copying the files read from the secondary adapter to the primary one, so that the next reads
+	// are served by the primary.
+	CopyBack bool
+}
+
+// New will create an adapter reading from primary and, for missing files, from secondary. Writes go to the primary
+// only; files found on the secondary only are first copied to the primary by the operations modifying them, and
+// deletions apply to both so that deleted files do not reappear.
+func New(primary, secondary filesystem.Adapter, opts Options) filesystem.Adapter {
+	return &fallback{primary: primary, secondary: secondary, opts: opts}
+}
+
+type fallback struct {
+	primary   filesystem.Adapter
+	secondary filesystem.Adapter
+	opts      Options
+}
+
+// read will run fn on the primary and then, if the file is missing, on the secondary.
+func read[T any](f *fallback, fn func(a filesystem.Adapter) (T, error)) (T, error) {
+	v, err := fn(f.primary)
+	if filesystem.IsFileNotFound(err) {
+		return fn(f.secondary)
+	}
+	return v, err
+}
+
+// copyBack will copy the file at path from the secondary to the primary.
+func (f *fallback) copyBack(ctx context.Context, path filesystem.Path) error {
+	rc, err := f.secondary.ReadStream(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	cfg := filesystem.EmptyConfig()
+	if v, err := f.secondary.GetVisibility(ctx, path); err == nil {
+		cfg.Set(filesystem.ConfigVisibility, v)
+	}
+	return f.primary.PutStream(ctx, path, rc, *cfg)
+}
+
+// ensurePrimary will copy the file at path to the primary if only the secondary has it.
+func (f *fallback) ensurePrimary(ctx context.Context, path filesystem.Path) error {
+	ok, err := f.primary.Has(ctx, path)
+	if err != nil || ok {
+		return err
+	}
+	if ok, err = f.secondary.Has(ctx, path); err != nil || !ok {
+		return err
+	}
+	return f.copyBack(ctx, path)
+}
+
+func (f *fallback) Has(ctx context.Context, path filesystem.Path) (bool, error) {
+	ok, err := f.primary.Has(ctx, path)
+	if err != nil || ok {
+		return ok, err
+	}
+	return f.secondary.Has(ctx, path)
+}
+
+func (f *fallback) Read(ctx context.Context, path filesystem.Path) ([]byte, error) {
+	content, err := f.primary.Read(ctx, path)
+	if !filesystem.IsFileNotFound(err) {
+		return content, err
+	}
+	if content, err = f.secondary.Read(ctx, path); err != nil {
+		return nil, err
+	}
+	if f.opts.CopyBack {
+		if err := f.copyBack(ctx, path); err != nil {
+			return nil, err
+		}
+	}
+	return content, nil
+}
+
+func (f *fallback) ReadStream(ctx context.Context, path filesystem.Path) (io.ReadCloser, error) {
+	rc, err := f.primary.ReadStream(ctx, path)
+	if !filesystem.IsFileNotFound(err) {
+		return rc, err
+	}
+	if !f.opts.CopyBack {
+		return f.secondary.ReadStream(ctx, path)
+	}
+	if err := f.copyBack(ctx, path); err != nil {
+		return nil, err
+	}
+	return f.primary.ReadStream(ctx, path)
+}
+
+func (f *fallback) Write(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	return f.primary.Write(ctx, path, content, cfg)
+}
+
+func (f *fallback) WriteStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	return f.primary.WriteStream(ctx, path, r, cfg)
+}
+
+func (f *fallback) Update(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	if err := f.ensurePrimary(ctx, path); err != nil {
+		return err
+	}
+	return f.primary.Update(ctx, path, content, cfg)
+}
+
+func (f *fallback) UpdateStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	if err := f.ensurePrimary(ctx, path); err != nil {
+		return err
+	}
+	return f.primary.UpdateStream(ctx, path, r, cfg)
+}
+
+func (f *fallback) Put(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	return f.primary.Put(ctx, path, content, cfg)
+}
+
+func (f *fallback) PutStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	return f.primary.PutStream(ctx, path, r, cfg)
+}
+
+// Delete will delete the file from both adapters, failing only if neither has it.
+func (f *fallback) Delete(ctx context.Context, path filesystem.Path) error {
+	err1 := f.primary.Delete(ctx, path)
+	if err1 != nil && !filesystem.IsFileNotFound(err1) {
+		return err1
+	}
+	err2 := f.secondary.Delete(ctx, path)
+	if err2 != nil && !filesystem.IsFileNotFound(err2) {
+		return err2
+	}
+	if err1 != nil && err2 != nil {
+		return err1
+	}
+	return nil
+}
+
+func (f *fallback) ReadAndDelete(ctx context.Context, path filesystem.Path) ([]byte, error) {
+	content, err := read(f, func(a filesystem.Adapter) ([]byte, error) {
+		return a.Read(ctx, path)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return content, f.Delete(ctx, path)
+}
+
+func (f *fallback) Move(ctx context.Context, path, newpath filesystem.Path) error {
+	if err := f.ensurePrimary(ctx, path); err != nil {
+		return err
+	}
+	if err := f.primary.Move(ctx, path, newpath); err != nil {
+		return err
+	}
+	if err := f.secondary.Delete(ctx, path); err != nil && !filesystem.IsFileNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+func (f *fallback) Copy(ctx context.Context, path, newpath filesystem.Path) error {
+	if err := f.ensurePrimary(ctx, path); err != nil {
+		return err
+	}
+	return f.primary.Copy(ctx, path, newpath)
+}
+
+func (f *fallback) GetMimeType(ctx context.Context, path filesystem.Path) (string, error) {
+	return read(f, func(a filesystem.Adapter) (string, error) {
+		return a.GetMimeType(ctx, path)
+	})
+}
+
+func (f *fallback) GetTimestamp(ctx context.Context, path filesystem.Path) (time.Time, error) {
+	return read(f, func(a filesystem.Adapter) (time.Time, error) {
+		return a.GetTimestamp(ctx, path)
+	})
+}
+
+func (f *fallback) GetFileSize(ctx context.Context, path filesystem.Path) (int64, error) {
+	return read(f, func(a filesystem.Adapter) (int64, error) {
+		return a.GetFileSize(ctx, path)
+	})
+}
+
+func (f *fallback) GetMetadata(ctx context.Context, path filesystem.Path) (filesystem.Metadata, error) {
+	return read(f, func(a filesystem.Adapter) (filesystem.Metadata, error) {
+		return a.GetMetadata(ctx, path)
+	})
+}
+
+func (f *fallback) CreateDir(ctx context.Context, path filesystem.Path, cfg filesystem.Config) error {
+	return f.primary.CreateDir(ctx, path, cfg)
+}
+
+// DeleteDir will delete the directory from both adapters, failing only if neither has it.
+func (f *fallback) DeleteDir(ctx context.Context, path filesystem.Path) error {
+	err1 := f.primary.DeleteDir(ctx, path)
+	if err1 != nil && !filesystem.IsFileNotFound(err1) {
+		return err1
+	}
+	err2 := f.secondary.DeleteDir(ctx, path)
+	if err2 != nil && !filesystem.IsFileNotFound(err2) {
+		return err2
+	}
+	if err1 != nil && err2 != nil {
+		return err1
+	}
+	return nil
+}
+
+func (f *fallback) GetVisibility(ctx context.Context, path filesystem.Path) (filesystem.Visibility, error) {
+	return read(f, func(a filesystem.Adapter) (filesystem.Visibility, error) {
+		return a.GetVisibility(ctx, path)
+	})
+}
+
+func (f *fallback) SetVisibility(ctx context.Context, path filesystem.Path, v filesystem.Visibility) error {
+	if err := f.ensurePrimary(ctx, path); err != nil {
+		return err
+	}
+	return f.primary.SetVisibility(ctx, path, v)
+}
+
+// ListContents will merge the listings of both adapters, the primary winning for entries they both have.
+func (f *fallback) ListContents(ctx context.Context, path filesystem.Path, recursive bool) ([]filesystem.Metadata, error) {
+	primary, err1 := f.primary.ListContents(ctx, path, recursive)
+	if err1 != nil && !filesystem.IsFileNotFound(err1) {
+		return nil, err1
+	}
+	secondary, err2 := f.secondary.ListContents(ctx, path, recursive)
+	if err2 != nil && !filesystem.IsFileNotFound(err2) {
+		return nil, err2
+	}
+	if err1 != nil && err2 != nil {
+		return nil, err1
+	}
+	seen := make(map[filesystem.Path]bool, len(primary))
+	for _, md := range primary {
+		seen[md.Path] = true
+	}
+	result := primary
+	for _, md := range secondary {
+		if !seen[md.Path] {
+			result = append(result, md)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Path < result[j].Path
+	})
+	return result, nil
+}
+
+// Close will close both adapters, returning the first error encountered.
+func (f *fallback) Close() error {
+	err := filesystem.Close(f.primary)
+	if err2 := filesystem.Close(f.secondary); err == nil {
+		err = err2
+	}
+	return err
+}
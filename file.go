@@ -0,0 +1,250 @@
+package filesystem
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// File is a handle on an open file, for random access and incremental writes, or for libraries expecting *os.File
+// like values. Errors of its methods are *fs.PathError values, as the ones of *os.File.
+type File interface {
+	io.Reader
+	io.ReaderAt
+	io.Writer
+	io.WriterAt
+	io.Seeker
+	io.Closer
+	// Path is the path of the file.
+	Path() Path
+	// Stat will describe the file, as written so far.
+	Stat() (fs.FileInfo, error)
+	// Sync will write the changes made so far to the file system.
+	Sync() error
+}
+
+// Open will open the file at provided path with flag, a combination of the os.O_* flags, writing it with provided
+// options. Handles opened for reading only stream the file with ReadSeekStream. Handles opened for writing work on
+// a copy of the file in a local temporary file, written back atomically on Sync and Close, so changes are not
+// visible until then, and concurrent handles overwrite each other. It is the file system counterpart of os.OpenFile.
+func Open(ctx context.Context, fsys Interface, path Path, flag int, opts ...WriteOption) (File, error) {
+	md, err := fsys.GetMetadata(ctx, path)
+	exists := err == nil
+	if err != nil && (!IsFileNotFound(err) || flag&os.O_CREATE == 0) {
+		return nil, err
+	}
+	if exists && md.IsDir() {
+		return nil, &fs.PathError{Op: "open", Path: string(path), Err: fs.ErrInvalid}
+	}
+	if exists && flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL {
+		return nil, &fs.PathError{Op: "open", Path: string(path), Err: fs.ErrExist}
+	}
+	f := &file{ctx: ctx, fsys: fsys, path: path, flag: flag, opts: opts, md: &FileInfo{Path: path, Type: TypeFile}}
+	if exists {
+		*f.md = *md
+	}
+	if !f.writable() {
+		if !exists {
+			if err := fsys.Put(ctx, path, nil, opts...); err != nil {
+				return nil, err
+			}
+		}
+		if f.r, err = fsys.ReadSeekStream(ctx, path); err != nil {
+			return nil, err
+		}
+		return f, nil
+	}
+	if f.tmp, err = os.CreateTemp("", "filesystem-*"); err != nil {
+		return nil, err
+	}
+	f.dirty = !exists || flag&os.O_TRUNC != 0
+	if !f.dirty {
+		if err := f.load(); err != nil {
+			f.discard()
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// file is a File streaming the file when read only, and working on a local copy otherwise.
+type file struct {
+	ctx    context.Context
+	fsys   Interface
+	path   Path
+	flag   int
+	opts   []WriteOption
+	md     Metadata
+	r      io.ReadSeekCloser
+	tmp    *os.File
+	dirty  bool
+	closed bool
+}
+
+func (f *file) writable() bool {
+	return f.flag&(os.O_WRONLY|os.O_RDWR) != 0
+}
+
+func (f *file) readable() bool {
+	return f.flag&os.O_WRONLY == 0
+}
+
+func (f *file) pathError(op string, err error) error {
+	return &fs.PathError{Op: op, Path: string(f.path), Err: err}
+}
+
+// load will copy the content of the file to the local copy.
+func (f *file) load() error {
+	rc, err := f.fsys.ReadStream(f.ctx, f.path)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if _, err := io.Copy(f.tmp, rc); err != nil {
+		return err
+	}
+	_, err = f.tmp.Seek(0, io.SeekStart)
+	return err
+}
+
+// discard will close and remove the local copy.
+func (f *file) discard() {
+	f.tmp.Close()
+	os.Remove(f.tmp.Name())
+}
+
+func (f *file) Path() Path {
+	return f.path
+}
+
+func (f *file) Read(p []byte) (int, error) {
+	switch {
+	case f.closed:
+		return 0, f.pathError("read", fs.ErrClosed)
+	case !f.readable():
+		return 0, f.pathError("read", fs.ErrPermission)
+	case f.r != nil:
+		return f.r.Read(p)
+	}
+	return f.tmp.Read(p)
+}
+
+func (f *file) Write(p []byte) (int, error) {
+	switch {
+	case f.closed:
+		return 0, f.pathError("write", fs.ErrClosed)
+	case !f.writable():
+		return 0, f.pathError("write", fs.ErrPermission)
+	}
+	if f.flag&os.O_APPEND != 0 {
+		if _, err := f.tmp.Seek(0, io.SeekEnd); err != nil {
+			return 0, err
+		}
+	}
+	f.dirty = true
+	return f.tmp.Write(p)
+}
+
+func (f *file) ReadAt(p []byte, offset int64) (int, error) {
+	switch {
+	case f.closed:
+		return 0, f.pathError("read", fs.ErrClosed)
+	case !f.readable():
+		return 0, f.pathError("read", fs.ErrPermission)
+	case f.r != nil:
+		ra := rangeReaderAt{ctx: f.ctx, fsys: f.fsys, path: f.path, size: f.md.Size}
+		return ra.ReadAt(p, offset)
+	}
+	return f.tmp.ReadAt(p, offset)
+}
+
+func (f *file) WriteAt(p []byte, offset int64) (int, error) {
+	switch {
+	case f.closed:
+		return 0, f.pathError("write", fs.ErrClosed)
+	case !f.writable():
+		return 0, f.pathError("write", fs.ErrPermission)
+	case f.flag&os.O_APPEND != 0:
+		return 0, f.pathError("writeat", fs.ErrInvalid)
+	}
+	f.dirty = true
+	return f.tmp.WriteAt(p, offset)
+}
+
+func (f *file) Seek(offset int64, whence int) (int64, error) {
+	switch {
+	case f.closed:
+		return 0, f.pathError("seek", fs.ErrClosed)
+	case f.r != nil:
+		return f.r.Seek(offset, whence)
+	}
+	return f.tmp.Seek(offset, whence)
+}
+
+func (f *file) Stat() (fs.FileInfo, error) {
+	if f.closed {
+		return nil, f.pathError("stat", fs.ErrClosed)
+	}
+	if f.r != nil {
+		return fileInfo{f.md}, nil
+	}
+	fi, err := f.tmp.Stat()
+	if err != nil {
+		return nil, f.pathError("stat", err)
+	}
+	md := *f.md
+	md.Size = fi.Size()
+	if f.dirty {
+		md.Timestamp = fi.ModTime()
+	}
+	return fileInfo{&md}, nil
+}
+
+func (f *file) Sync() error {
+	switch {
+	case f.closed:
+		return f.pathError("sync", fs.ErrClosed)
+	case !f.dirty:
+		return nil
+	}
+	fi, err := f.tmp.Stat()
+	if err != nil {
+		return f.pathError("sync", err)
+	}
+	if err := f.fsys.WriteAtomic(f.ctx, f.path, io.NewSectionReader(f.tmp, 0, fi.Size()), f.opts...); err != nil {
+		return f.pathError("sync", err)
+	}
+	f.dirty = false
+	f.md.Timestamp = time.Now()
+	return nil
+}
+
+func (f *file) Close() error {
+	if f.closed {
+		return f.pathError("close", fs.ErrClosed)
+	}
+	if f.r != nil {
+		f.closed = true
+		return f.r.Close()
+	}
+	err := f.Sync()
+	f.closed = true
+	f.discard()
+	return err
+}
+
+// Open will open the file at provided path with flag, a combination of the os.O_* flags.
+func (fs *filesystem) Open(ctx context.Context, path Path, flag int, opts ...WriteOption) (File, error) {
+	path, err := NewPath(string(path))
+	if err != nil {
+		return nil, err
+	}
+	return Open(ctx, fs, path, flag, opts...)
+}
+
+// Open will open the file at provided path with flag, a combination of the os.O_* flags.
+func (mm *mountManager) Open(ctx context.Context, path Path, flag int, opts ...WriteOption) (File, error) {
+	return Open(ctx, mm, path, flag, opts...)
+}
@@ -0,0 +1,248 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"time"
+)
+
+// FromFS will create a read-only adapter serving the files of provided io/fs file system, such as an embed.FS or
+// the result of os.DirFS. Every write returns a ReadOnlyError.
+func FromFS(fsys fs.FS) Adapter {
+	return &fsAdapter{fsys: fsys}
+}
+
+type fsAdapter struct {
+	fsys fs.FS
+}
+
+// fsName will convert a path to an io/fs name, the root being ".".
+func fsName(p Path) string {
+	if p == RootPath {
+		return "."
+	}
+	return string(p)
+}
+
+func fsError(p Path, err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return NewFileNotFoundError(p)
+	}
+	return err
+}
+
+// stat will return the file info of the file at path, reporting directories as missing files.
+func (a *fsAdapter) stat(p Path) (fs.FileInfo, error) {
+	info, err := fs.Stat(a.fsys, fsName(p))
+	if err != nil {
+		return nil, fsError(p, err)
+	}
+	if info.IsDir() {
+		return nil, NewFileNotFoundError(p)
+	}
+	return info, nil
+}
+
+func fsMetadata(p Path, info fs.FileInfo) Metadata {
+	if info.IsDir() {
+		return &FileInfo{Path: p, Type: TypeDir, Timestamp: info.ModTime()}
+	}
+	return &FileInfo{Path: p, Type: TypeFile, Size: info.Size(), Timestamp: info.ModTime(), Visibility: VisibilityPublic}
+}
+
+// Has will check if a file exists.
+func (a *fsAdapter) Has(ctx context.Context, path Path) (bool, error) {
+	_, err := a.stat(path)
+	if IsFileNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Read the file at provided path.
+func (a *fsAdapter) Read(ctx context.Context, path Path) ([]byte, error) {
+	if _, err := a.stat(path); err != nil {
+		return nil, err
+	}
+	content, err := fs.ReadFile(a.fsys, fsName(path))
+	if err != nil {
+		return nil, fsError(path, err)
+	}
+	return content, nil
+}
+
+// ReadStream will read the file at provided path as a stream.
+func (a *fsAdapter) ReadStream(ctx context.Context, path Path) (io.ReadCloser, error) {
+	if _, err := a.stat(path); err != nil {
+		return nil, err
+	}
+	f, err := a.fsys.Open(fsName(path))
+	if err != nil {
+		return nil, fsError(path, err)
+	}
+	return f, nil
+}
+
+// Write is not supported.
+func (a *fsAdapter) Write(ctx context.Context, path Path, content []byte, cfg Config) error {
+	return NewReadOnlyError(path)
+}
+
+// WriteStream is not supported.
+func (a *fsAdapter) WriteStream(ctx context.Context, path Path, r io.Reader, cfg Config) error {
+	return NewReadOnlyError(path)
+}
+
+// Update is not supported.
+func (a *fsAdapter) Update(ctx context.Context, path Path, content []byte, cfg Config) error {
+	return NewReadOnlyError(path)
+}
+
+// UpdateStream is not supported.
+func (a *fsAdapter) UpdateStream(ctx context.Context, path Path, r io.Reader, cfg Config) error {
+	return NewReadOnlyError(path)
+}
+
+// Put is not supported.
+func (a *fsAdapter) Put(ctx context.Context, path Path, content []byte, cfg Config) error {
+	return NewReadOnlyError(path)
+}
+
+// PutStream is not supported.
+func (a *fsAdapter) PutStream(ctx context.Context, path Path, r io.Reader, cfg Config) error {
+	return NewReadOnlyError(path)
+}
+
+// Delete is not supported.
+func (a *fsAdapter) Delete(ctx context.Context, path Path) error {
+	return NewReadOnlyError(path)
+}
+
+// ReadAndDelete is not supported.
+func (a *fsAdapter) ReadAndDelete(ctx context.Context, path Path) ([]byte, error) {
+	return nil, NewReadOnlyError(path)
+}
+
+// Move is not supported.
+func (a *fsAdapter) Move(ctx context.Context, path, newpath Path) error {
+	return NewReadOnlyError(path)
+}
+
+// Copy is not supported.
+func (a *fsAdapter) Copy(ctx context.Context, path, newpath Path) error {
+	return NewReadOnlyError(newpath)
+}
+
+// GetMimeType will retrieve the mime type of file at supplied path, from its extension or its content.
+func (a *fsAdapter) GetMimeType(ctx context.Context, p Path) (string, error) {
+	if _, err := a.stat(p); err != nil {
+		return "", err
+	}
+	if mt := mime.TypeByExtension(path.Ext(string(p))); mt != "" {
+		return mt, nil
+	}
+	f, err := a.fsys.Open(fsName(p))
+	if err != nil {
+		return "", fsError(p, err)
+	}
+	defer f.Close()
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	return http.DetectContentType(head[:n]), nil
+}
+
+// GetTimestamp will retrieve the timestamp of file at supplied path.
+func (a *fsAdapter) GetTimestamp(ctx context.Context, path Path) (time.Time, error) {
+	info, err := a.stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+// GetFileSize will retrieve the size of file at supplied path.
+func (a *fsAdapter) GetFileSize(ctx context.Context, path Path) (int64, error) {
+	info, err := a.stat(path)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// GetMetadata will retrieve the metadata of file at supplied path.
+func (a *fsAdapter) GetMetadata(ctx context.Context, path Path) (Metadata, error) {
+	info, err := a.stat(path)
+	if err != nil {
+		return nil, err
+	}
+	return fsMetadata(path, info), nil
+}
+
+// CreateDir is not supported.
+func (a *fsAdapter) CreateDir(ctx context.Context, path Path, cfg Config) error {
+	return NewReadOnlyError(path)
+}
+
+// DeleteDir is not supported.
+func (a *fsAdapter) DeleteDir(ctx context.Context, path Path) error {
+	return NewReadOnlyError(path)
+}
+
+// GetVisibility will retrieve the visibility of file at supplied path, always public.
+func (a *fsAdapter) GetVisibility(ctx context.Context, path Path) (Visibility, error) {
+	if _, err := a.stat(path); err != nil {
+		return "", err
+	}
+	return VisibilityPublic, nil
+}
+
+// SetVisibility is not supported.
+func (a *fsAdapter) SetVisibility(ctx context.Context, path Path, v Visibility) error {
+	return NewReadOnlyError(path)
+}
+
+// ListContents will list the contents of given path.
+func (a *fsAdapter) ListContents(ctx context.Context, p Path, recursive bool) ([]Metadata, error) {
+	root := fsName(p)
+	var listing []Metadata
+	if !recursive {
+		entries, err := fs.ReadDir(a.fsys, root)
+		if err != nil {
+			return nil, fsError(p, err)
+		}
+		for _, e := range entries {
+			info, err := e.Info()
+			if err != nil {
+				return nil, err
+			}
+			listing = append(listing, fsMetadata(p.Join(e.Name()), info))
+		}
+		return listing, nil
+	}
+	err := fs.WalkDir(a.fsys, root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name == root {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		listing = append(listing, fsMetadata(Path(name), info))
+		return nil
+	})
+	if err != nil {
+		return nil, fsError(p, err)
+	}
+	return listing, nil
+}
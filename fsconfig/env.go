@@ -0,0 +1,140 @@
+package fsconfig
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// envFields set the fields of a mount from the variables named after them.
+var envFields = map[string]func(m *Mount, v string) error{
+	"TYPE":   func(m *Mount, v string) error { m.Type = v; return nil },
+	"PREFIX": func(m *Mount, v string) error { m.Prefix = v; return nil },
+	"ALIASES": func(m *Mount, v string) error {
+		for _, alias := range strings.Split(v, ",") {
+			if alias = strings.TrimSpace(alias); alias != "" {
+				m.Aliases = append(m.Aliases, alias)
+			}
+		}
+		return nil
+	},
+	"VISIBILITY": func(m *Mount, v string) error { m.Visibility = v; return nil },
+	"READONLY": func(m *Mount, v string) (err error) {
+		m.ReadOnly, err = strconv.ParseBool(v)
+		return
+	},
+	"SNIFF_MIME_TYPE": func(m *Mount, v string) (err error) {
+		m.SniffMimeType, err = strconv.ParseBool(v)
+		return
+	},
+	"RETRY_MAX_ATTEMPTS": func(m *Mount, v string) (err error) {
+		m.retry().MaxAttempts, err = strconv.Atoi(v)
+		return
+	},
+	"RETRY_INITIAL_BACKOFF": func(m *Mount, v string) error { m.retry().InitialBackoff = v; return nil },
+	"RETRY_MAX_BACKOFF":     func(m *Mount, v string) error { m.retry().MaxBackoff = v; return nil },
+	"RETRY_WRITES": func(m *Mount, v string) (err error) {
+		m.retry().RetryWrites, err = strconv.ParseBool(v)
+		return
+	},
+	"THROTTLE_READ_BYTES_PER_SECOND": func(m *Mount, v string) (err error) {
+		m.throttle().ReadBytesPerSecond, err = strconv.ParseInt(v, 10, 64)
+		return
+	},
+	"THROTTLE_WRITE_BYTES_PER_SECOND": func(m *Mount, v string) (err error) {
+		m.throttle().WriteBytesPerSecond, err = strconv.ParseInt(v, 10, 64)
+		return
+	},
+	"THROTTLE_READ_OPS_PER_SECOND": func(m *Mount, v string) (err error) {
+		m.throttle().ReadOpsPerSecond, err = strconv.ParseFloat(v, 64)
+		return
+	},
+	"THROTTLE_WRITE_OPS_PER_SECOND": func(m *Mount, v string) (err error) {
+		m.throttle().WriteOpsPerSecond, err = strconv.ParseFloat(v, 64)
+		return
+	},
+}
+
+func (m *Mount) retry() *Retry {
+	if m.Retry == nil {
+		m.Retry = &Retry{}
+	}
+	return m.Retry
+}
+
+func (m *Mount) throttle() *Throttle {
+	if m.Throttle == nil {
+		m.Throttle = &Throttle{}
+	}
+	return m.Throttle
+}
+
+// FromEnv will read a configuration from environment variables starting with prefix and an underscore. The
+// <PREFIX>_MOUNTS variable lists the prefixes of the mounts, separated by commas, and <PREFIX>_DEFAULT names the
+// default one; each mount is then configured by variables named after the upper case prefix, hyphens replaced by
+// underscores, and the fields of Mount, aliases being separated by commas:
+//
+//	FS_MOUNTS=backups,assets
+//	FS_DEFAULT=assets
+//	FS_BACKUPS_TYPE=s3
+//	FS_BACKUPS_SETTING_BUCKET=my-backups
+//	FS_BACKUPS_RETRY_MAX_ATTEMPTS=5
+//	FS_ASSETS_TYPE=dir
+//	FS_ASSETS_SETTING_PATH=/srv/assets
+//	FS_ASSETS_READONLY=true
+//
+// Settings are set by <PREFIX>_<MOUNT>_SETTING_<KEY> variables, the key being lower cased; "true" and "false" values
+// are booleans, other values strings.
+func FromEnv(prefix string) (*Config, error) {
+	cfg := &Config{Mounts: make(map[string]Mount), Default: os.Getenv(prefix + "_DEFAULT")}
+	for _, name := range strings.Split(os.Getenv(prefix+"_MOUNTS"), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		m, err := mountFromEnv(prefix + "_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_")
+		if err != nil {
+			return nil, fmt.Errorf("mount %s: %w", name, err)
+		}
+		cfg.Mounts[name] = m
+	}
+	return cfg, nil
+}
+
+func mountFromEnv(prefix string) (Mount, error) {
+	m := Mount{}
+	for _, kv := range os.Environ() {
+		k, v, _ := strings.Cut(kv, "=")
+		field, ok := strings.CutPrefix(k, prefix)
+		if !ok {
+			continue
+		}
+		if key, ok := strings.CutPrefix(field, "SETTING_"); ok {
+			if m.Settings == nil {
+				m.Settings = make(map[string]interface{})
+			}
+			m.Settings[strings.ToLower(key)] = settingValue(v)
+			continue
+		}
+		if set, ok := envFields[field]; ok {
+			if err := set(&m, v); err != nil {
+				return m, fmt.Errorf("%s: %w", k, err)
+			}
+		}
+	}
+	if m.Type == "" {
+		return m, fmt.Errorf("missing %sTYPE variable", prefix)
+	}
+	return m, nil
+}
+
+func settingValue(v string) interface{} {
+	switch v {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	return v
+}
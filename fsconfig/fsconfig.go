@@ -0,0 +1,270 @@
+// Package fsconfig builds a mount manager from a declarative configuration, so that applications can switch storage
+// by changing a file instead of code. Each mount names the type of its adapter, the settings the adapter is created
+// from, and the decorators applied to it:
+//
+//	mounts:
+//	  backups:
+//	    type: s3
+//	    settings:
+//	      bucket: my-backups
+//	      region: eu-west-1
+//	    prefix: nightly
+//	    retry:
+//	      max_attempts: 5
+//	      initial_backoff: 200ms
+//	    throttle:
+//	      write_bytes_per_second: 10485760
+//	  assets:
+//	    type: dir
+//	    aliases: [static]
+//	    settings:
+//	      path: /srv/assets
+//	    readonly: true
+//	default: assets
+//
+// Configurations are read from YAML or JSON files, where ${VAR} references are replaced by environment variables,
+// e.g. for credentials, or entirely from environment variables with FromEnv.
+//
+// Adapter types are the names adapters are registered by with filesystem.RegisterAdapter: importing the package of an
+// adapter makes its type available. The memory and null adapters are imported by this package.
+package fsconfig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/maurofran/filesystem"
+	_ "github.com/maurofran/filesystem/memory"
+	_ "github.com/maurofran/filesystem/null"
+	"github.com/maurofran/filesystem/readonly"
+	"github.com/maurofran/filesystem/retry"
+	"github.com/maurofran/filesystem/scoped"
+	"github.com/maurofran/filesystem/throttle"
+)
+
+// Config is a configuration: the file systems mounted, by prefix.
+type Config struct {
+	Mounts map[string]Mount `json:"mounts" yaml:"mounts"`
+	// Default is the prefix of the mount addressed by paths without prefix, if not empty.
+	Default string `json:"default,omitempty" yaml:"default,omitempty"`
+}
+
+// Mount is the definition of a mounted file system. Decorators are applied in the order of the fields: the adapter
+// is scoped to Prefix, throttled, retried, then made read-only.
+type Mount struct {
+	// Type is the name the adapter is registered by.
+	Type string `json:"type" yaml:"type"`
+	// Aliases are further prefixes the mount is addressed by.
+	Aliases []string `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+	// Settings are the settings the adapter is created from, specific to its type.
+	Settings map[string]interface{} `json:"settings,omitempty" yaml:"settings,omitempty"`
+	// Prefix confines the mount to the directory at this path of the adapter, if not empty.
+	Prefix string `json:"prefix,omitempty" yaml:"prefix,omitempty"`
+	// Throttle limits the rate of the operations, if not nil.
+	Throttle *Throttle `json:"throttle,omitempty" yaml:"throttle,omitempty"`
+	// Retry retries the operations failing with transient errors, if not nil.
+	Retry *Retry `json:"retry,omitempty" yaml:"retry,omitempty"`
+	// ReadOnly refuses every operation modifying the file system.
+	ReadOnly bool `json:"readonly,omitempty" yaml:"readonly,omitempty"`
+	// Visibility is the default visibility of written files and created directories, if not empty.
+	Visibility string `json:"visibility,omitempty" yaml:"visibility,omitempty"`
+	// SniffMimeType detects the mime type of files from their content.
+	SniffMimeType bool `json:"sniff_mime_type,omitempty" yaml:"sniff_mime_type,omitempty"`
+}
+
+// Retry configures the retry of a mount, see retry.Policy. Durations are written as accepted by time.ParseDuration.
+type Retry struct {
+	MaxAttempts    int     `json:"max_attempts,omitempty" yaml:"max_attempts,omitempty"`
+	InitialBackoff string  `json:"initial_backoff,omitempty" yaml:"initial_backoff,omitempty"`
+	MaxBackoff     string  `json:"max_backoff,omitempty" yaml:"max_backoff,omitempty"`
+	Multiplier     float64 `json:"multiplier,omitempty" yaml:"multiplier,omitempty"`
+	Jitter         float64 `json:"jitter,omitempty" yaml:"jitter,omitempty"`
+	RetryWrites    bool    `json:"retry_writes,omitempty" yaml:"retry_writes,omitempty"`
+}
+
+// Throttle configures the throttling of a mount, see throttle.Limits.
+type Throttle struct {
+	ReadBytesPerSecond  int64   `json:"read_bytes_per_second,omitempty" yaml:"read_bytes_per_second,omitempty"`
+	WriteBytesPerSecond int64   `json:"write_bytes_per_second,omitempty" yaml:"write_bytes_per_second,omitempty"`
+	ReadOpsPerSecond    float64 `json:"read_ops_per_second,omitempty" yaml:"read_ops_per_second,omitempty"`
+	WriteOpsPerSecond   float64 `json:"write_ops_per_second,omitempty" yaml:"write_ops_per_second,omitempty"`
+}
+
+// Load will read the configuration file at name, decoded as JSON if its extension is ".json" and as YAML otherwise.
+func Load(name string) (*Config, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	format := "yaml"
+	if strings.EqualFold(filepath.Ext(name), ".json") {
+		format = "json"
+	}
+	cfg, err := Parse(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return cfg, nil
+}
+
+// Parse will decode a configuration in provided format, "json" or "yaml", after replacing ${VAR} references with the
+// value of environment variables. A literal dollar sign is written $$.
+func Parse(data []byte, format string) (*Config, error) {
+	data = []byte(os.Expand(string(data), func(key string) string {
+		if key == "$" {
+			return "$"
+		}
+		return os.Getenv(key)
+	}))
+	cfg := &Config{}
+	var err error
+	switch format {
+	case "json":
+		err = json.Unmarshal(data, cfg)
+	case "yaml":
+		err = yaml.Unmarshal(data, cfg)
+	default:
+		err = fmt.Errorf("unknown format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Open will load the configuration file at name and build its mount manager.
+func Open(ctx context.Context, name string) (filesystem.MountManager, error) {
+	cfg, err := Load(name)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.Build(ctx)
+}
+
+// Build will create the adapters of the mounts and mount them with their aliases, closing the ones already created
+// on failure.
+func (c *Config) Build(ctx context.Context) (filesystem.MountManager, error) {
+	mm := filesystem.EmptyMountManager()
+	for prefix, m := range c.Mounts {
+		fs, err := m.Build(ctx)
+		if err == nil {
+			err = mm.Mount(prefix, fs)
+		}
+		if err != nil {
+			mm.Close()
+			return nil, fmt.Errorf("mount %s: %w", prefix, err)
+		}
+	}
+	for prefix, m := range c.Mounts {
+		for _, alias := range m.Aliases {
+			if err := mm.Alias(alias, prefix); err != nil {
+				mm.Close()
+				return nil, fmt.Errorf("mount %s: %w", prefix, err)
+			}
+		}
+	}
+	if c.Default != "" {
+		if err := mm.SetDefault(c.Default); err != nil {
+			mm.Close()
+			return nil, err
+		}
+	}
+	return mm, nil
+}
+
+// Build will create the file system of the mount.
+func (m Mount) Build(ctx context.Context) (filesystem.Interface, error) {
+	opts, err := m.Options()
+	if err != nil {
+		return nil, err
+	}
+	a, err := m.Adapter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return filesystem.New(a, opts...), nil
+}
+
+// Options will return the options the file system of the mount is created with.
+func (m Mount) Options() ([]filesystem.NewOption, error) {
+	var opts []filesystem.NewOption
+	if m.Visibility != "" {
+		v, err := filesystem.ParseVisibility(m.Visibility)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, filesystem.WithVisibility(v))
+	}
+	if m.SniffMimeType {
+		opts = append(opts, filesystem.WithMimeSniffing())
+	}
+	return opts, nil
+}
+
+// Adapter will create the adapter of the mount, decorated as configured.
+func (m Mount) Adapter(ctx context.Context) (filesystem.Adapter, error) {
+	var policy retry.Policy
+	if m.Retry != nil {
+		var err error
+		if policy, err = m.Retry.policy(); err != nil {
+			return nil, err
+		}
+	}
+	var prefix filesystem.Path
+	if m.Prefix != "" {
+		var err error
+		if prefix, err = filesystem.NewPath(m.Prefix); err != nil {
+			return nil, err
+		}
+	}
+	a, err := filesystem.NewByName(ctx, m.Type, m.Settings)
+	if err != nil {
+		return nil, err
+	}
+	if prefix != filesystem.RootPath {
+		a = scoped.New(a, prefix)
+	}
+	if m.Throttle != nil {
+		a = throttle.Wrap(a, throttle.Limits{
+			ReadBytesPerSecond:  m.Throttle.ReadBytesPerSecond,
+			WriteBytesPerSecond: m.Throttle.WriteBytesPerSecond,
+			ReadOpsPerSecond:    m.Throttle.ReadOpsPerSecond,
+			WriteOpsPerSecond:   m.Throttle.WriteOpsPerSecond,
+		})
+	}
+	if m.Retry != nil {
+		a = retry.Wrap(a, policy)
+	}
+	if m.ReadOnly {
+		a = readonly.Wrap(a)
+	}
+	return a, nil
+}
+
+func (r *Retry) policy() (retry.Policy, error) {
+	p := retry.Policy{
+		MaxAttempts: r.MaxAttempts,
+		Multiplier:  r.Multiplier,
+		Jitter:      r.Jitter,
+		RetryWrites: r.RetryWrites,
+	}
+	var err error
+	if r.InitialBackoff != "" {
+		if p.InitialBackoff, err = time.ParseDuration(r.InitialBackoff); err != nil {
+			return p, fmt.Errorf("retry: %w", err)
+		}
+	}
+	if r.MaxBackoff != "" {
+		if p.MaxBackoff, err = time.ParseDuration(r.MaxBackoff); err != nil {
+			return p, fmt.Errorf("retry: %w", err)
+		}
+	}
+	return p, nil
+}
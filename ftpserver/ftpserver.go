@@ -0,0 +1,151 @@
+// Package ftpserver serves file systems over FTP, and FTPS with explicit TLS, so that legacy clients can drop files
+// directly into object storage. Authentication is pluggable, each user being given the file system it sees, e.g. a
+// mount or a scoped prefix of a shared one.
+//
+// The server implements the commands clients need to browse and transfer files, passive and active data
+// connections, and resuming downloads with REST. Transfers are always binary: TYPE A is accepted but no line ending
+// conversion happens.
+package ftpserver
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/maurofran/filesystem"
+)
+
+// ErrAuthentication is the error authenticators return for unknown users and wrong passwords.
+var ErrAuthentication = errors.New("authentication failed")
+
+// ErrServerClosed is the error returned by Serve after the server is closed.
+var ErrServerClosed = errors.New("ftp server closed")
+
+// Authenticator will authenticate a user, returning the file system the user sees, or ErrAuthentication.
+type Authenticator func(ctx context.Context, user, password string) (filesystem.Interface, error)
+
+// Options configures a Server.
+type Options struct {
+	// Authenticator authenticates users; it is required.
+	Authenticator Authenticator
+	// TLSConfig enables FTPS with explicit TLS, negotiated with AUTH TLS, if not nil.
+	TLSConfig *tls.Config
+	// RequireTLS refuses to log users in before TLS is negotiated, and data connections without PROT P.
+	RequireTLS bool
+	// PublicIP is the IP address advertised to clients for passive data connections, the one of the control
+	// connection if empty, e.g. the public address of a server behind NAT.
+	PublicIP string
+	// MinPassivePort and MaxPassivePort bound the ports of passive data connections, any port if zero.
+	MinPassivePort int
+	MaxPassivePort int
+	// IdleTimeout closes control connections idle for longer, 5 minutes if zero.
+	IdleTimeout time.Duration
+	// Welcome is the message greeting clients.
+	Welcome string
+}
+
+// Server is an FTP server.
+type Server struct {
+	opts Options
+
+	mu        sync.Mutex
+	listeners map[net.Listener]bool
+	conns     map[net.Conn]bool
+	closed    bool
+}
+
+// New will create a server with provided options.
+func New(opts Options) *Server {
+	if opts.IdleTimeout == 0 {
+		opts.IdleTimeout = 5 * time.Minute
+	}
+	if opts.Welcome == "" {
+		opts.Welcome = "Service ready"
+	}
+	return &Server{opts: opts, listeners: make(map[net.Listener]bool), conns: make(map[net.Conn]bool)}
+}
+
+// ListenAndServe will listen on provided TCP address and serve the connections accepted.
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve will serve the connections accepted by l, until the server is closed or l fails.
+func (s *Server) Serve(l net.Listener) error {
+	if !s.track(l, nil) {
+		l.Close()
+		return ErrServerClosed
+	}
+	defer s.untrack(l, nil)
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if s.isClosed() {
+				return ErrServerClosed
+			}
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				continue
+			}
+			return err
+		}
+		if !s.track(nil, conn) {
+			conn.Close()
+			return ErrServerClosed
+		}
+		go func() {
+			defer s.untrack(nil, conn)
+			newSession(s, conn).serve()
+		}()
+	}
+}
+
+// Close will stop the listeners and close the connections.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for l := range s.listeners {
+		l.Close()
+	}
+	for c := range s.conns {
+		c.Close()
+	}
+	return nil
+}
+
+func (s *Server) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// track will register a listener or a connection, reporting false if the server is closed.
+func (s *Server) track(l net.Listener, c net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	if l != nil {
+		s.listeners[l] = true
+	}
+	if c != nil {
+		s.conns[c] = true
+	}
+	return true
+}
+
+func (s *Server) untrack(l net.Listener, c net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.listeners, l)
+	delete(s.conns, c)
+}
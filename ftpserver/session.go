@@ -0,0 +1,775 @@
+package ftpserver
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maurofran/filesystem"
+)
+
+// dataTimeout is the time clients have to open a data connection.
+const dataTimeout = 30 * time.Second
+
+// session is the state of a control connection.
+type session struct {
+	server *Server
+	conn   net.Conn
+	reader *bufio.Reader
+	ctx    context.Context
+
+	tls       bool
+	protected bool
+	user      string
+	fsys      filesystem.Interface
+	cwd       string
+	offset    int64
+	renaming  filesystem.Path
+	passive   net.Listener
+	active    string
+}
+
+func newSession(s *Server, conn net.Conn) *session {
+	return &session{server: s, conn: conn, reader: bufio.NewReader(conn), ctx: context.Background(), cwd: "/"}
+}
+
+// reply will send a reply, made of several lines if msg has line breaks.
+func (s *session) reply(code int, msg string) {
+	lines := strings.Split(msg, "\n")
+	for _, line := range lines[:len(lines)-1] {
+		fmt.Fprintf(s.conn, "%d-%s\r\n", code, line)
+	}
+	fmt.Fprintf(s.conn, "%d %s\r\n", code, lines[len(lines)-1])
+}
+
+func (s *session) serve() {
+	defer s.conn.Close()
+	defer s.closeData()
+	s.reply(220, s.server.opts.Welcome)
+	for {
+		s.conn.SetReadDeadline(time.Now().Add(s.server.opts.IdleTimeout))
+		line, err := s.reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd, arg, _ := strings.Cut(strings.TrimRight(line, "\r\n"), " ")
+		if !s.handle(strings.ToUpper(cmd), arg) {
+			return
+		}
+	}
+}
+
+type handler struct {
+	fn    func(s *session, arg string)
+	login bool
+}
+
+var handlers = map[string]handler{
+	"USER": {(*session).handleUSER, false},
+	"PASS": {(*session).handlePASS, false},
+	"AUTH": {(*session).handleAUTH, false},
+	"PBSZ": {(*session).handlePBSZ, false},
+	"PROT": {(*session).handlePROT, false},
+	"FEAT": {(*session).handleFEAT, false},
+	"SYST": {func(s *session, _ string) { s.reply(215, "UNIX Type: L8") }, false},
+	"NOOP": {func(s *session, _ string) { s.reply(200, "OK") }, false},
+	"OPTS": {(*session).handleOPTS, false},
+	"PWD":  {(*session).handlePWD, true},
+	"XPWD": {(*session).handlePWD, true},
+	"CWD":  {(*session).handleCWD, true},
+	"XCWD": {(*session).handleCWD, true},
+	"CDUP": {func(s *session, _ string) { s.handleCWD("..") }, true},
+	"TYPE": {(*session).handleTYPE, true},
+	"MODE": {(*session).handleMODE, true},
+	"STRU": {(*session).handleSTRU, true},
+	"PASV": {(*session).handlePASV, true},
+	"EPSV": {(*session).handleEPSV, true},
+	"PORT": {(*session).handlePORT, true},
+	"EPRT": {(*session).handleEPRT, true},
+	"LIST": {(*session).handleLIST, true},
+	"NLST": {(*session).handleNLST, true},
+	"MLSD": {(*session).handleMLSD, true},
+	"MLST": {(*session).handleMLST, true},
+	"REST": {(*session).handleREST, true},
+	"RETR": {(*session).handleRETR, true},
+	"STOR": {(*session).handleSTOR, true},
+	"APPE": {(*session).handleAPPE, true},
+	"DELE": {(*session).handleDELE, true},
+	"MKD":  {(*session).handleMKD, true},
+	"XMKD": {(*session).handleMKD, true},
+	"RMD":  {(*session).handleRMD, true},
+	"XRMD": {(*session).handleRMD, true},
+	"RNFR": {(*session).handleRNFR, true},
+	"RNTO": {(*session).handleRNTO, true},
+	"SIZE": {(*session).handleSIZE, true},
+	"MDTM": {(*session).handleMDTM, true},
+}
+
+// handle will run a command, returning false when the connection is to be closed.
+func (s *session) handle(cmd, arg string) bool {
+	if cmd == "QUIT" {
+		s.reply(221, "Goodbye")
+		return false
+	}
+	h, ok := handlers[cmd]
+	if !ok {
+		s.reply(502, "Command not implemented")
+		return true
+	}
+	if h.login && s.fsys == nil {
+		s.reply(530, "Not logged in")
+		return true
+	}
+	// The offset of REST and the file of RNFR only apply to the following command.
+	if cmd != "REST" {
+		defer func() { s.offset = 0 }()
+	}
+	if cmd != "RNFR" {
+		defer func() { s.renaming = "" }()
+	}
+	h.fn(s, arg)
+	return true
+}
+
+func (s *session) handleUSER(arg string) {
+	if s.server.opts.RequireTLS && !s.tls {
+		s.reply(530, "TLS required")
+		return
+	}
+	s.user, s.fsys = arg, nil
+	s.reply(331, "User name okay, need password")
+}
+
+func (s *session) handlePASS(arg string) {
+	if s.user == "" {
+		s.reply(503, "Login with USER first")
+		return
+	}
+	fsys, err := s.server.opts.Authenticator(s.ctx, s.user, arg)
+	if err != nil {
+		s.reply(530, "Login incorrect")
+		return
+	}
+	s.fsys, s.cwd = fsys, "/"
+	s.reply(230, "User logged in")
+}
+
+func (s *session) handleAUTH(arg string) {
+	if s.server.opts.TLSConfig == nil || !strings.EqualFold(arg, "TLS") && !strings.EqualFold(arg, "SSL") {
+		s.reply(504, "AUTH type not supported")
+		return
+	}
+	if s.tls {
+		s.reply(503, "TLS already negotiated")
+		return
+	}
+	s.reply(234, "AUTH TLS successful")
+	conn := tls.Server(s.conn, s.server.opts.TLSConfig)
+	if err := conn.Handshake(); err != nil {
+		s.conn.Close()
+		return
+	}
+	s.conn, s.reader, s.tls = conn, bufio.NewReader(conn), true
+}
+
+func (s *session) handlePBSZ(arg string) {
+	if !s.tls {
+		s.reply(503, "Negotiate TLS first")
+		return
+	}
+	s.reply(200, "PBSZ=0")
+}
+
+func (s *session) handlePROT(arg string) {
+	switch {
+	case !s.tls:
+		s.reply(503, "Negotiate TLS first")
+	case strings.EqualFold(arg, "P"):
+		s.protected = true
+		s.reply(200, "Protection level set to Private")
+	case strings.EqualFold(arg, "C") && !s.server.opts.RequireTLS:
+		s.protected = false
+		s.reply(200, "Protection level set to Clear")
+	default:
+		s.reply(536, "Protection level not supported")
+	}
+}
+
+func (s *session) handleFEAT(string) {
+	features := []string{"EPSV", "EPRT", "MDTM", "MLST type*;size*;modify*;", "PASV", "REST STREAM", "SIZE", "UTF8"}
+	if s.server.opts.TLSConfig != nil {
+		features = append(features, "AUTH TLS", "PBSZ", "PROT")
+	}
+	s.reply(211, "Features:\n "+strings.Join(features, "\n ")+"\nEnd")
+}
+
+func (s *session) handleOPTS(arg string) {
+	if strings.EqualFold(arg, "UTF8 ON") {
+		s.reply(200, "UTF8 enabled")
+		return
+	}
+	s.reply(501, "Option not supported")
+}
+
+// resolve will convert a command argument, absolute or relative to the working directory, to a path.
+func (s *session) resolve(arg string) (string, filesystem.Path, error) {
+	name := arg
+	if !strings.HasPrefix(name, "/") {
+		name = path.Join(s.cwd, name)
+	}
+	name = path.Clean("/" + name)
+	p, err := filesystem.NewPath(strings.TrimPrefix(name, "/"))
+	return name, p, err
+}
+
+// fail will reply with the code matching err, for a command about file at name.
+func (s *session) fail(name string, err error) {
+	switch {
+	case filesystem.IsFileNotFound(err), errors.Is(err, os.ErrNotExist):
+		s.reply(550, name+": No such file or directory")
+	case filesystem.IsPathError(err):
+		s.reply(553, name+": File name not allowed")
+	default:
+		s.reply(550, name+": "+err.Error())
+	}
+}
+
+// isDir will check if name is a directory, the root always being one.
+func (s *session) isDir(p filesystem.Path) (bool, error) {
+	if p == filesystem.RootPath {
+		return true, nil
+	}
+	return s.fsys.DirectoryExists(s.ctx, p)
+}
+
+func quote(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func (s *session) handlePWD(string) {
+	s.reply(257, quote(s.cwd)+" is the current directory")
+}
+
+func (s *session) handleCWD(arg string) {
+	name, p, err := s.resolve(arg)
+	if err == nil {
+		var ok bool
+		if ok, err = s.isDir(p); err == nil && !ok {
+			err = filesystem.NewFileNotFoundError(p)
+		}
+	}
+	if err != nil {
+		s.fail(arg, err)
+		return
+	}
+	s.cwd = name
+	s.reply(250, "Directory changed to "+name)
+}
+
+func (s *session) handleTYPE(arg string) {
+	switch strings.ToUpper(strings.TrimSpace(arg)) {
+	case "A", "A N", "I", "L 8":
+		s.reply(200, "Type set")
+	default:
+		s.reply(504, "Type not supported")
+	}
+}
+
+func (s *session) handleMODE(arg string) {
+	if strings.EqualFold(arg, "S") {
+		s.reply(200, "Mode set to Stream")
+		return
+	}
+	s.reply(504, "Mode not supported")
+}
+
+func (s *session) handleSTRU(arg string) {
+	if strings.EqualFold(arg, "F") {
+		s.reply(200, "Structure set to File")
+		return
+	}
+	s.reply(504, "Structure not supported")
+}
+
+func (s *session) handleREST(arg string) {
+	offset, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || offset < 0 {
+		s.reply(501, "Invalid offset")
+		return
+	}
+	s.offset = offset
+	s.reply(350, fmt.Sprintf("Restarting at %d", offset))
+}
+
+func (s *session) handleSIZE(arg string) {
+	_, p, err := s.resolve(arg)
+	var size int64
+	if err == nil {
+		size, err = s.fsys.GetFileSize(s.ctx, p)
+	}
+	if err != nil {
+		s.fail(arg, err)
+		return
+	}
+	s.reply(213, strconv.FormatInt(size, 10))
+}
+
+func (s *session) handleMDTM(arg string) {
+	_, p, err := s.resolve(arg)
+	var t time.Time
+	if err == nil {
+		t, err = s.fsys.GetTimestamp(s.ctx, p)
+	}
+	if err != nil {
+		s.fail(arg, err)
+		return
+	}
+	s.reply(213, t.UTC().Format("20060102150405"))
+}
+
+func (s *session) handleDELE(arg string) {
+	_, p, err := s.resolve(arg)
+	if err == nil {
+		_, err = s.fsys.Delete(s.ctx, p)
+	}
+	if err != nil {
+		s.fail(arg, err)
+		return
+	}
+	s.reply(250, "File deleted")
+}
+
+func (s *session) handleMKD(arg string) {
+	name, p, err := s.resolve(arg)
+	if err == nil {
+		err = s.fsys.CreateDir(s.ctx, p)
+	}
+	if err != nil {
+		s.fail(arg, err)
+		return
+	}
+	s.reply(257, quote(name)+" created")
+}
+
+func (s *session) handleRMD(arg string) {
+	_, p, err := s.resolve(arg)
+	if err == nil && p == filesystem.RootPath {
+		s.reply(550, "Root directory cannot be removed")
+		return
+	}
+	if err == nil {
+		err = s.fsys.DeleteDir(s.ctx, p)
+	}
+	if err != nil {
+		s.fail(arg, err)
+		return
+	}
+	s.reply(250, "Directory removed")
+}
+
+func (s *session) handleRNFR(arg string) {
+	_, p, err := s.resolve(arg)
+	if err == nil {
+		var ok bool
+		if ok, err = s.fsys.Has(s.ctx, p); err == nil && !ok {
+			err = filesystem.NewFileNotFoundError(p)
+		}
+	}
+	if err != nil {
+		s.fail(arg, err)
+		return
+	}
+	s.renaming = p
+	s.reply(350, "Ready for RNTO")
+}
+
+func (s *session) handleRNTO(arg string) {
+	from := s.renaming
+	if from == "" {
+		s.reply(503, "Use RNFR first")
+		return
+	}
+	_, p, err := s.resolve(arg)
+	if err == nil {
+		var dir bool
+		if dir, err = s.isDir(from); err == nil && dir {
+			err = s.fsys.MoveDir(s.ctx, from, p)
+		} else if err == nil {
+			err = s.fsys.Move(s.ctx, from, p)
+		}
+	}
+	if err != nil {
+		s.fail(arg, err)
+		return
+	}
+	s.reply(250, "Renamed")
+}
+
+// listing will return the entries of the directory named by arg, or the file it names, ignoring the ls options
+// clients send.
+func (s *session) listing(arg string) ([]filesystem.Metadata, error) {
+	var fields []string
+	for _, f := range strings.Fields(arg) {
+		if !strings.HasPrefix(f, "-") {
+			fields = append(fields, f)
+		}
+	}
+	_, p, err := s.resolve(strings.Join(fields, " "))
+	if err != nil {
+		return nil, err
+	}
+	dir, err := s.isDir(p)
+	if err != nil {
+		return nil, err
+	}
+	if !dir {
+		md, err := s.fsys.GetMetadata(s.ctx, p)
+		if err != nil {
+			return nil, err
+		}
+		return []filesystem.Metadata{md}, nil
+	}
+	contents, err := s.fsys.ListContents(s.ctx, p, false)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(contents, func(i, j int) bool { return contents[i].Path < contents[j].Path })
+	return contents, nil
+}
+
+func (s *session) handleLIST(arg string) {
+	s.sendListing(arg, func(w io.Writer, md filesystem.Metadata) {
+		mode, size := "-rw-r--r--", max(md.Size, 0)
+		if md.IsDir() {
+			mode, size = "drwxr-xr-x", 0
+		}
+		t := md.Timestamp
+		if t.IsZero() {
+			t = time.Unix(0, 0)
+		}
+		stamp := t.Format("Jan _2 15:04")
+		if time.Since(t) > 180*24*time.Hour || time.Until(t) > 0 {
+			stamp = t.Format("Jan _2  2006")
+		}
+		fmt.Fprintf(w, "%s 1 ftp ftp %12d %s %s\r\n", mode, size, stamp, md.Path.Base())
+	})
+}
+
+func (s *session) handleNLST(arg string) {
+	s.sendListing(arg, func(w io.Writer, md filesystem.Metadata) {
+		fmt.Fprintf(w, "%s\r\n", md.Path.Base())
+	})
+}
+
+func (s *session) handleMLSD(arg string) {
+	s.sendListing(arg, func(w io.Writer, md filesystem.Metadata) {
+		fmt.Fprintf(w, "%s %s\r\n", facts(md), md.Path.Base())
+	})
+}
+
+func (s *session) handleMLST(arg string) {
+	name, p, err := s.resolve(arg)
+	var md filesystem.Metadata
+	if err == nil {
+		var dir bool
+		if dir, err = s.isDir(p); err == nil && dir {
+			md = &filesystem.FileInfo{Path: p, Type: filesystem.TypeDir}
+		} else if err == nil {
+			md, err = s.fsys.GetMetadata(s.ctx, p)
+		}
+	}
+	if err != nil {
+		s.fail(arg, err)
+		return
+	}
+	s.reply(250, "Listing "+name+"\n "+facts(md)+" "+name+"\nEnd")
+}
+
+// facts will format the MLSx facts of an entry.
+func facts(md filesystem.Metadata) string {
+	if md.IsDir() {
+		return "type=dir;"
+	}
+	f := fmt.Sprintf("type=file;size=%d;", max(md.Size, 0))
+	if !md.Timestamp.IsZero() {
+		f += "modify=" + md.Timestamp.UTC().Format("20060102150405") + ";"
+	}
+	return f
+}
+
+func (s *session) sendListing(arg string, format func(w io.Writer, md filesystem.Metadata)) {
+	contents, err := s.listing(arg)
+	if err != nil {
+		s.closeData()
+		s.fail(arg, err)
+		return
+	}
+	s.transfer(func(conn net.Conn) error {
+		w := bufio.NewWriter(conn)
+		for _, md := range contents {
+			format(w, md)
+		}
+		return w.Flush()
+	})
+}
+
+func (s *session) handleRETR(arg string) {
+	_, p, err := s.resolve(arg)
+	var rc io.ReadCloser
+	if err == nil {
+		rc, err = s.fsys.ReadRange(s.ctx, p, s.offset, -1)
+	}
+	if err != nil {
+		s.closeData()
+		s.fail(arg, err)
+		return
+	}
+	defer rc.Close()
+	s.transfer(func(conn net.Conn) error {
+		_, err := io.Copy(conn, rc)
+		return err
+	})
+}
+
+func (s *session) handleSTOR(arg string) {
+	if s.offset != 0 {
+		s.store(arg, os.O_WRONLY|os.O_CREATE)
+		return
+	}
+	_, p, err := s.resolve(arg)
+	if err != nil {
+		s.closeData()
+		s.fail(arg, err)
+		return
+	}
+	s.transfer(func(conn net.Conn) error {
+		return s.fsys.PutStream(s.ctx, p, conn)
+	})
+}
+
+func (s *session) handleAPPE(arg string) {
+	s.store(arg, os.O_WRONLY|os.O_CREATE|os.O_APPEND)
+}
+
+// store will receive content into the file opened with flag, at the offset set by REST.
+func (s *session) store(arg string, flag int) {
+	offset := s.offset
+	_, p, err := s.resolve(arg)
+	var f filesystem.File
+	if err == nil {
+		f, err = s.fsys.Open(s.ctx, p, flag)
+	}
+	if err == nil && flag&os.O_APPEND == 0 {
+		_, err = f.Seek(offset, io.SeekStart)
+	}
+	if err != nil {
+		if f != nil {
+			f.Close()
+		}
+		s.closeData()
+		s.fail(arg, err)
+		return
+	}
+	s.transfer(func(conn net.Conn) error {
+		if _, err := io.Copy(f, conn); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	})
+}
+
+// transfer will open the data connection, securing it if asked with PROT P, and run fn with it, replying with the
+// outcome.
+func (s *session) transfer(fn func(conn net.Conn) error) {
+	conn, err := s.openData()
+	if err != nil {
+		s.reply(425, "Can't open data connection: "+err.Error())
+		return
+	}
+	s.reply(150, "Opening data connection")
+	if s.protected {
+		tc := tls.Server(conn, s.server.opts.TLSConfig)
+		if err := tc.Handshake(); err != nil {
+			conn.Close()
+			s.reply(425, "Can't secure data connection: "+err.Error())
+			return
+		}
+		conn = tc
+	}
+	err = fn(conn)
+	if cerr := conn.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		s.reply(451, "Transfer aborted: "+err.Error())
+		return
+	}
+	s.reply(226, "Transfer complete")
+}
+
+func (s *session) closeData() {
+	if s.passive != nil {
+		s.passive.Close()
+		s.passive = nil
+	}
+	s.active = ""
+}
+
+// listenPassive will listen for a passive data connection on the host of the control connection.
+func (s *session) listenPassive() (int, error) {
+	s.closeData()
+	host, _, err := net.SplitHostPort(s.conn.LocalAddr().String())
+	if err != nil {
+		return 0, err
+	}
+	opts := s.server.opts
+	if opts.MinPassivePort <= 0 || opts.MaxPassivePort < opts.MinPassivePort {
+		l, err := net.Listen("tcp", net.JoinHostPort(host, "0"))
+		if err != nil {
+			return 0, err
+		}
+		s.passive = l
+		return l.Addr().(*net.TCPAddr).Port, nil
+	}
+	for port := opts.MinPassivePort; port <= opts.MaxPassivePort; port++ {
+		if l, err := net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(port))); err == nil {
+			s.passive = l
+			return port, nil
+		}
+	}
+	return 0, errors.New("no passive port available")
+}
+
+func (s *session) handlePASV(string) {
+	port, err := s.listenPassive()
+	if err != nil {
+		s.reply(425, "Can't open passive connection: "+err.Error())
+		return
+	}
+	ip := s.server.opts.PublicIP
+	if ip == "" {
+		ip, _, _ = net.SplitHostPort(s.conn.LocalAddr().String())
+	}
+	ip4 := net.ParseIP(ip).To4()
+	if ip4 == nil {
+		s.closeData()
+		s.reply(425, "PASV needs an IPv4 address, use EPSV")
+		return
+	}
+	s.reply(227, fmt.Sprintf("Entering Passive Mode (%d,%d,%d,%d,%d,%d)", ip4[0], ip4[1], ip4[2], ip4[3], port>>8, port&0xff))
+}
+
+func (s *session) handleEPSV(arg string) {
+	if strings.EqualFold(arg, "ALL") {
+		s.reply(200, "EPSV ALL accepted")
+		return
+	}
+	port, err := s.listenPassive()
+	if err != nil {
+		s.reply(425, "Can't open passive connection: "+err.Error())
+		return
+	}
+	s.reply(229, fmt.Sprintf("Entering Extended Passive Mode (|||%d|)", port))
+}
+
+func (s *session) handlePORT(arg string) {
+	parts := strings.Split(arg, ",")
+	var n [6]int
+	var err error
+	if len(parts) != 6 {
+		err = errors.New("invalid address")
+	}
+	for i := 0; err == nil && i < 6; i++ {
+		n[i], err = strconv.Atoi(strings.TrimSpace(parts[i]))
+		if err == nil && (n[i] < 0 || n[i] > 255) {
+			err = errors.New("invalid address")
+		}
+	}
+	if err != nil {
+		s.reply(501, "Invalid PORT address")
+		return
+	}
+	s.setActive(fmt.Sprintf("%d.%d.%d.%d", n[0], n[1], n[2], n[3]), n[4]<<8|n[5])
+}
+
+func (s *session) handleEPRT(arg string) {
+	if len(arg) < 2 {
+		s.reply(501, "Invalid EPRT address")
+		return
+	}
+	parts := strings.Split(arg, arg[:1])
+	if len(parts) != 5 {
+		s.reply(501, "Invalid EPRT address")
+		return
+	}
+	port, err := strconv.Atoi(parts[3])
+	if err != nil || net.ParseIP(parts[2]) == nil {
+		s.reply(501, "Invalid EPRT address")
+		return
+	}
+	s.setActive(parts[2], port)
+}
+
+// setActive will make the next transfer connect to the client at provided address, which must be the one of the
+// control connection so that the server cannot be used to reach other hosts.
+func (s *session) setActive(ip string, port int) {
+	s.closeData()
+	host, _, _ := net.SplitHostPort(s.conn.RemoteAddr().String())
+	if !net.ParseIP(ip).Equal(net.ParseIP(host)) || port <= 0 || port > 65535 {
+		s.reply(504, "Active connection refused")
+		return
+	}
+	s.active = net.JoinHostPort(ip, strconv.Itoa(port))
+	s.reply(200, "Active connection set")
+}
+
+// openData will open the data connection negotiated.
+func (s *session) openData() (net.Conn, error) {
+	if s.server.opts.RequireTLS && !s.protected {
+		s.closeData()
+		return nil, errors.New("PROT P required")
+	}
+	var conn net.Conn
+	var err error
+	switch {
+	case s.passive != nil:
+		if tl, ok := s.passive.(*net.TCPListener); ok {
+			tl.SetDeadline(time.Now().Add(dataTimeout))
+		}
+		conn, err = s.acceptData()
+	case s.active != "":
+		conn, err = net.DialTimeout("tcp", s.active, dataTimeout)
+	default:
+		err = errors.New("use PASV or PORT first")
+	}
+	s.closeData()
+	return conn, err
+}
+
+// acceptData will accept the passive data connection from the host of the control connection, closing the ones
+// from other hosts so that they cannot steal transfers.
+func (s *session) acceptData() (net.Conn, error) {
+	host, _, _ := net.SplitHostPort(s.conn.RemoteAddr().String())
+	for {
+		conn, err := s.passive.Accept()
+		if err != nil {
+			return nil, err
+		}
+		ip, _, _ := net.SplitHostPort(conn.RemoteAddr().String())
+		if net.ParseIP(ip).Equal(net.ParseIP(host)) {
+			return conn, nil
+		}
+		conn.Close()
+	}
+}
@@ -0,0 +1,652 @@
+// Package gdrive provides an adapter storing files on Google Drive, in My Drive or in a shared drive.
+//
+// Drive addresses files by ID and allows several files with the same name in a folder: paths are resolved one
+// segment at a time, the first match winning, and resolved IDs are cached. Google-native documents have no
+// content of their own and are read by exporting them to the mime type configured for their kind.
+package gdrive
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+
+	"github.com/maurofran/filesystem"
+)
+
+// Settings read by NewFromConfig.
+const (
+	// ConfigCredentialsFile is the service account or OAuth credentials JSON file; application default credentials
+	// are used if empty.
+	ConfigCredentialsFile = "credentials_file"
+	// ConfigRootID is the ID of the folder used as root, "root" (My Drive) if empty.
+	ConfigRootID = "root_id"
+	// ConfigDriveID is the ID of the shared drive files are stored in; its root is used as root if no root ID is
+	// configured.
+	ConfigDriveID = "drive_id"
+)
+
+const (
+	folderMimeType = "application/vnd.google-apps.folder"
+	nativePrefix   = "application/vnd.google-apps."
+	fileFields     = "id, name, mimeType, size, modifiedTime, parents, properties"
+)
+
+// DefaultExportMimeTypes maps Google-native document types to the mime types they are exported to.
+var DefaultExportMimeTypes = map[string]string{
+	"application/vnd.google-apps.document":     "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	"application/vnd.google-apps.spreadsheet":  "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	"application/vnd.google-apps.presentation": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	"application/vnd.google-apps.drawing":      "image/png",
+}
+
+// Options are the options of the adapter.
+type Options struct {
+	// RootID is the ID of the folder used as root, "root" (My Drive) or the shared drive root if empty.
+	RootID string
+	// DriveID is the ID of the shared drive files are stored in, empty for My Drive.
+	DriveID string
+	// ExportMimeTypes maps Google-native document types to the mime types they are exported to, overriding
+	// DefaultExportMimeTypes.
+	ExportMimeTypes map[string]string
+}
+
+// Adapter is an adapter storing files on Google Drive.
+type Adapter struct {
+	service *drive.Service
+	opts    Options
+	mu      sync.Mutex
+	ids     map[filesystem.Path]string
+}
+
+// New will create an adapter on top of provided Drive service.
+func New(service *drive.Service, opts Options) *Adapter {
+	if opts.RootID == "" {
+		opts.RootID = "root"
+		if opts.DriveID != "" {
+			opts.RootID = opts.DriveID
+		}
+	}
+	return &Adapter{service: service, opts: opts, ids: map[filesystem.Path]string{filesystem.RootPath: opts.RootID}}
+}
+
+func init() {
+	filesystem.RegisterAdapter("gdrive", func(ctx context.Context, settings map[string]interface{}) (filesystem.Adapter, error) {
+		return NewFromConfig(ctx, settings)
+	})
+}
+
+// NewFromConfig will create an adapter from provided settings.
+func NewFromConfig(ctx context.Context, settings map[string]interface{}) (*Adapter, error) {
+	str := func(key string) string {
+		s, _ := settings[key].(string)
+		return s
+	}
+	var opts []option.ClientOption
+	if file := str(ConfigCredentialsFile); file != "" {
+		opts = append(opts, option.WithCredentialsFile(file))
+	}
+	service, err := drive.NewService(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return New(service, Options{RootID: str(ConfigRootID), DriveID: str(ConfigDriveID)}), nil
+}
+
+func isNotFound(err error) bool {
+	var ge *googleapi.Error
+	return errors.As(err, &ge) && ge.Code == http.StatusNotFound
+}
+
+func (a *Adapter) mapError(p filesystem.Path, err error) error {
+	if isNotFound(err) {
+		return filesystem.NewFileNotFoundError(p)
+	}
+	return err
+}
+
+// escape will quote a string literal of a Drive query.
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s)
+}
+
+// list will run a files query, following pages.
+func (a *Adapter) list(ctx context.Context, q string) ([]*drive.File, error) {
+	call := a.service.Files.List().Context(ctx).Q(q).Fields(googleapi.Field("nextPageToken, files(" + fileFields + ")")).
+		SupportsAllDrives(true).IncludeItemsFromAllDrives(true)
+	if a.opts.DriveID != "" {
+		call = call.Corpora("drive").DriveId(a.opts.DriveID)
+	}
+	var files []*drive.File
+	err := call.Pages(ctx, func(page *drive.FileList) error {
+		files = append(files, page.Files...)
+		return nil
+	})
+	return files, err
+}
+
+// child will find the entry named name in the folder with provided ID.
+func (a *Adapter) child(ctx context.Context, parentID, name string) (*drive.File, error) {
+	files, err := a.list(ctx, "name = '"+escape(name)+"' and '"+escape(parentID)+"' in parents and trashed = false")
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, nil
+	}
+	return files[0], nil
+}
+
+// resolve will find the ID of the entry at path, returning a FileNotFoundError if missing.
+func (a *Adapter) resolve(ctx context.Context, p filesystem.Path) (string, error) {
+	a.mu.Lock()
+	id, ok := a.ids[p]
+	a.mu.Unlock()
+	if ok {
+		return id, nil
+	}
+	dir, name := p.Split()
+	parentID, err := a.resolve(ctx, dir)
+	if err != nil {
+		return "", filesystem.NewFileNotFoundError(p)
+	}
+	f, err := a.child(ctx, parentID, name)
+	if err != nil {
+		return "", err
+	}
+	if f == nil {
+		return "", filesystem.NewFileNotFoundError(p)
+	}
+	a.cache(p, f.Id)
+	return f.Id, nil
+}
+
+func (a *Adapter) cache(p filesystem.Path, id string) {
+	a.mu.Lock()
+	a.ids[p] = id
+	a.mu.Unlock()
+}
+
+// forget will drop the cached IDs of path and everything under it.
+func (a *Adapter) forget(p filesystem.Path) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for k := range a.ids {
+		if k == p || p == filesystem.RootPath || strings.HasPrefix(string(k), string(p)+"/") {
+			delete(a.ids, k)
+		}
+	}
+	a.ids[filesystem.RootPath] = a.opts.RootID
+}
+
+// get will retrieve the entry at path.
+func (a *Adapter) get(ctx context.Context, p filesystem.Path) (*drive.File, error) {
+	id, err := a.resolve(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	f, err := a.service.Files.Get(id).Context(ctx).Fields(fileFields).SupportsAllDrives(true).Do()
+	if isNotFound(err) {
+		a.forget(p)
+	}
+	return f, a.mapError(p, err)
+}
+
+// getFile will retrieve the file at path, reporting folders as missing files.
+func (a *Adapter) getFile(ctx context.Context, p filesystem.Path) (*drive.File, error) {
+	f, err := a.get(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	if f.MimeType == folderMimeType {
+		return nil, filesystem.NewFileNotFoundError(p)
+	}
+	return f, nil
+}
+
+// mkdirAll will create the folder at path and its missing parents, returning its ID.
+func (a *Adapter) mkdirAll(ctx context.Context, p filesystem.Path) (string, error) {
+	id, err := a.resolve(ctx, p)
+	if err == nil || !filesystem.IsFileNotFound(err) {
+		return id, err
+	}
+	dir, name := p.Split()
+	parentID, err := a.mkdirAll(ctx, dir)
+	if err != nil {
+		return "", err
+	}
+	f, err := a.service.Files.Create(&drive.File{Name: name, MimeType: folderMimeType, Parents: []string{parentID}}).
+		Context(ctx).Fields("id").SupportsAllDrives(true).Do()
+	if err != nil {
+		return "", err
+	}
+	a.cache(p, f.Id)
+	return f.Id, nil
+}
+
+func (a *Adapter) exportMimeType(mimeType string) string {
+	if mt, ok := a.opts.ExportMimeTypes[mimeType]; ok {
+		return mt
+	}
+	return DefaultExportMimeTypes[mimeType]
+}
+
+// Has will check if a file exists.
+func (a *Adapter) Has(ctx context.Context, path filesystem.Path) (bool, error) {
+	_, err := a.getFile(ctx, path)
+	if filesystem.IsFileNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// FileExists will check if a file, not a folder, exists at supplied path.
+func (a *Adapter) FileExists(ctx context.Context, path filesystem.Path) (bool, error) {
+	return a.Has(ctx, path)
+}
+
+// DirectoryExists will check if a folder exists at supplied path.
+func (a *Adapter) DirectoryExists(ctx context.Context, path filesystem.Path) (bool, error) {
+	f, err := a.get(ctx, path)
+	if filesystem.IsFileNotFound(err) {
+		return false, nil
+	}
+	return err == nil && f.MimeType == folderMimeType, err
+}
+
+// Read the file at provided path.
+func (a *Adapter) Read(ctx context.Context, path filesystem.Path) ([]byte, error) {
+	rc, err := a.ReadStream(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// ReadStream will read the file at provided path as a stream, exporting Google-native documents.
+func (a *Adapter) ReadStream(ctx context.Context, path filesystem.Path) (io.ReadCloser, error) {
+	f, err := a.getFile(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	var resp *http.Response
+	if strings.HasPrefix(f.MimeType, nativePrefix) {
+		mt := a.exportMimeType(f.MimeType)
+		if mt == "" {
+			return nil, filesystem.NewReadOnlyError(path)
+		}
+		resp, err = a.service.Files.Export(f.Id, mt).Context(ctx).Download()
+	} else {
+		resp, err = a.service.Files.Get(f.Id).Context(ctx).SupportsAllDrives(true).Download()
+	}
+	if err != nil {
+		return nil, a.mapError(path, err)
+	}
+	return resp.Body, nil
+}
+
+// upload will create the file at path, or replace the content of the existing one.
+func (a *Adapter) upload(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config, mustExist bool) error {
+	f, err := a.getFile(ctx, path)
+	if err != nil && !filesystem.IsFileNotFound(err) {
+		return err
+	}
+	if f == nil && mustExist {
+		return err
+	}
+	mimeType, _ := cfg.Get(filesystem.ConfigMimeType, "").(string)
+	properties, _ := cfg.Get(filesystem.ConfigMetadata, nil).(map[string]string)
+	if f != nil {
+		_, err = a.service.Files.Update(f.Id, &drive.File{MimeType: mimeType, Properties: properties}).Context(ctx).Media(r).
+			SupportsAllDrives(true).Do()
+		if err != nil {
+			return a.mapError(path, err)
+		}
+	} else {
+		dir, name := path.Split()
+		parentID, err := a.mkdirAll(ctx, dir)
+		if err != nil {
+			return err
+		}
+		created, err := a.service.Files.Create(&drive.File{
+			Name:       name,
+			MimeType:   mimeType,
+			Parents:    []string{parentID},
+			Properties: properties,
+		}).
+			Context(ctx).Fields("id").Media(r).SupportsAllDrives(true).Do()
+		if err != nil {
+			return err
+		}
+		a.cache(path, created.Id)
+	}
+	if v, ok := cfg.Get(filesystem.ConfigVisibility, nil).(filesystem.Visibility); ok {
+		return a.SetVisibility(ctx, path, v)
+	}
+	return nil
+}
+
+// Write the supplied content at supplied path, creating the file.
+func (a *Adapter) Write(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	return a.upload(ctx, path, bytes.NewReader(content), cfg, false)
+}
+
+// WriteStream will write the content of provided reader at supplied path, creating the file.
+func (a *Adapter) WriteStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	return a.upload(ctx, path, r, cfg, false)
+}
+
+// Update the supplied content at supplied path, returning an error if file does not exists.
+func (a *Adapter) Update(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	return a.upload(ctx, path, bytes.NewReader(content), cfg, true)
+}
+
+// Update with the content of supplied reader at supplied path, returning an error if file does not exists
+func (a *Adapter) UpdateStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	return a.upload(ctx, path, r, cfg, true)
+}
+
+// Put the supplied content at supplied path, creating the file if does not exists.
+func (a *Adapter) Put(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	return a.upload(ctx, path, bytes.NewReader(content), cfg, false)
+}
+
+// Puth the content of supplied reader at supplied path, creating the file if does not exists.
+func (a *Adapter) PutStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	return a.upload(ctx, path, r, cfg, false)
+}
+
+// Deletes a file at provided path.
+func (a *Adapter) Delete(ctx context.Context, path filesystem.Path) error {
+	f, err := a.getFile(ctx, path)
+	if err != nil {
+		return err
+	}
+	if err := a.service.Files.Delete(f.Id).Context(ctx).SupportsAllDrives(true).Do(); err != nil {
+		return a.mapError(path, err)
+	}
+	a.forget(path)
+	return nil
+}
+
+// ReadAndDelete will read the file at provided path and delete after read.
+func (a *Adapter) ReadAndDelete(ctx context.Context, path filesystem.Path) ([]byte, error) {
+	content, err := a.Read(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return content, a.Delete(ctx, path)
+}
+
+// Move the file at supplied path to new path, changing its parent and name.
+func (a *Adapter) Move(ctx context.Context, path, newpath filesystem.Path) error {
+	f, err := a.getFile(ctx, path)
+	if err != nil {
+		return err
+	}
+	dir, name := newpath.Split()
+	parentID, err := a.mkdirAll(ctx, dir)
+	if err != nil {
+		return err
+	}
+	_, err = a.service.Files.Update(f.Id, &drive.File{Name: name}).Context(ctx).
+		AddParents(parentID).RemoveParents(strings.Join(f.Parents, ",")).SupportsAllDrives(true).Do()
+	if err != nil {
+		return a.mapError(path, err)
+	}
+	a.forget(path)
+	a.forget(newpath)
+	return nil
+}
+
+// Copy the file at supplied path to new path.
+func (a *Adapter) Copy(ctx context.Context, path, newpath filesystem.Path) error {
+	f, err := a.getFile(ctx, path)
+	if err != nil {
+		return err
+	}
+	dir, name := newpath.Split()
+	parentID, err := a.mkdirAll(ctx, dir)
+	if err != nil {
+		return err
+	}
+	copied, err := a.service.Files.Copy(f.Id, &drive.File{Name: name, Parents: []string{parentID}}).Context(ctx).
+		Fields("id").SupportsAllDrives(true).Do()
+	if err != nil {
+		return a.mapError(path, err)
+	}
+	a.cache(newpath, copied.Id)
+	return nil
+}
+
+func (a *Adapter) metadata(p filesystem.Path, f *drive.File) filesystem.Metadata {
+	t, _ := time.Parse(time.RFC3339, f.ModifiedTime)
+	md := &filesystem.FileInfo{Path: p, Type: filesystem.TypeDir, Timestamp: t, Extra: map[string]interface{}{"id": f.Id}}
+	if f.MimeType == folderMimeType {
+		return md
+	}
+	md.Type, md.Size, md.MimeType = filesystem.TypeFile, f.Size, f.MimeType
+	if mt := a.exportMimeType(f.MimeType); mt != "" {
+		md.MimeType = mt
+	}
+	return md
+}
+
+// GetMimeType will retrieve the mime type of file at supplied path, the export mime type of Google-native
+// documents.
+func (a *Adapter) GetMimeType(ctx context.Context, path filesystem.Path) (string, error) {
+	f, err := a.getFile(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	return a.metadata(path, f).MimeType, nil
+}
+
+// GetTimestamp will retrieve the timestamp of file at supplied path.
+func (a *Adapter) GetTimestamp(ctx context.Context, path filesystem.Path) (time.Time, error) {
+	f, err := a.getFile(ctx, path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return a.metadata(path, f).Timestamp, nil
+}
+
+// GetFileSize will retrieve the size of file at supplied path, 0 for Google-native documents.
+func (a *Adapter) GetFileSize(ctx context.Context, path filesystem.Path) (int64, error) {
+	f, err := a.getFile(ctx, path)
+	if err != nil {
+		return 0, err
+	}
+	return f.Size, nil
+}
+
+// GetMetadata will retrieve the metadata of file at supplied path, including its Drive "id".
+func (a *Adapter) GetMetadata(ctx context.Context, path filesystem.Path) (filesystem.Metadata, error) {
+	f, err := a.getFile(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return a.metadata(path, f), nil
+}
+
+// CreateDir will create a new folder at provided path, with its missing parents.
+func (a *Adapter) CreateDir(ctx context.Context, path filesystem.Path, cfg filesystem.Config) error {
+	_, err := a.mkdirAll(ctx, path)
+	return err
+}
+
+// DeleteDir will delete the folder at provided path, with its content.
+func (a *Adapter) DeleteDir(ctx context.Context, path filesystem.Path) error {
+	f, err := a.get(ctx, path)
+	if err != nil {
+		return err
+	}
+	if f.MimeType != folderMimeType {
+		return filesystem.NewFileNotFoundError(path)
+	}
+	if err := a.service.Files.Delete(f.Id).Context(ctx).SupportsAllDrives(true).Do(); err != nil {
+		return a.mapError(path, err)
+	}
+	a.forget(path)
+	return nil
+}
+
+// publicPermission will find the permission sharing the file with anyone, if any.
+func (a *Adapter) publicPermission(ctx context.Context, id string) (*drive.Permission, error) {
+	perms, err := a.service.Permissions.List(id).Context(ctx).Fields("permissions(id, type, role)").
+		SupportsAllDrives(true).Do()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range perms.Permissions {
+		if p.Type == "anyone" {
+			return p, nil
+		}
+	}
+	return nil, nil
+}
+
+// Get the visibility of file at supplied path, public when shared with anyone.
+func (a *Adapter) GetVisibility(ctx context.Context, path filesystem.Path) (filesystem.Visibility, error) {
+	f, err := a.getFile(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	p, err := a.publicPermission(ctx, f.Id)
+	if err != nil {
+		return "", a.mapError(path, err)
+	}
+	if p != nil {
+		return filesystem.VisibilityPublic, nil
+	}
+	return filesystem.VisibilityPrivate, nil
+}
+
+// Set the visibility of file at supplied path, sharing it with anyone as reader when public.
+func (a *Adapter) SetVisibility(ctx context.Context, path filesystem.Path, v filesystem.Visibility) error {
+	f, err := a.getFile(ctx, path)
+	if err != nil {
+		return err
+	}
+	p, err := a.publicPermission(ctx, f.Id)
+	if err != nil {
+		return a.mapError(path, err)
+	}
+	switch {
+	case v != filesystem.VisibilityPublic && v != filesystem.VisibilityPrivate:
+		return fmt.Errorf("gdrive: unable to make %s %s, only public and private are supported", path, v)
+	case v == filesystem.VisibilityPublic && p == nil:
+		_, err = a.service.Permissions.Create(f.Id, &drive.Permission{Type: "anyone", Role: "reader"}).Context(ctx).
+			SupportsAllDrives(true).Do()
+	case v == filesystem.VisibilityPrivate && p != nil:
+		err = a.service.Permissions.Delete(f.Id, p.Id).Context(ctx).SupportsAllDrives(true).Do()
+	}
+	return a.mapError(path, err)
+}
+
+// GetUserMetadata will retrieve the user metadata of file at supplied path, stored as Drive properties.
+func (a *Adapter) GetUserMetadata(ctx context.Context, path filesystem.Path) (map[string]string, error) {
+	f, err := a.getFile(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	md := f.Properties
+	if md == nil {
+		md = map[string]string{}
+	}
+	return md, nil
+}
+
+// SetUserMetadata will replace the user metadata of file at supplied path, stored as Drive properties.
+func (a *Adapter) SetUserMetadata(ctx context.Context, path filesystem.Path, metadata map[string]string) error {
+	f, err := a.getFile(ctx, path)
+	if err != nil {
+		return err
+	}
+	// Drive merges properties on update, so the ones not in metadata must be cleared explicitly.
+	update := &drive.File{Properties: metadata}
+	for k := range f.Properties {
+		if _, ok := metadata[k]; !ok {
+			update.NullFields = append(update.NullFields, "Properties."+k)
+		}
+	}
+	_, err = a.service.Files.Update(f.Id, update).Context(ctx).Fields("id").SupportsAllDrives(true).Do()
+	return a.mapError(path, err)
+}
+
+// List the contents of given path.
+func (a *Adapter) ListContents(ctx context.Context, path filesystem.Path, recursive bool) ([]filesystem.Metadata, error) {
+	id, err := a.resolve(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	files, err := a.list(ctx, "'"+escape(id)+"' in parents and trashed = false")
+	if err != nil {
+		return nil, a.mapError(path, err)
+	}
+	var result []filesystem.Metadata
+	for _, f := range files {
+		p := path.Join(f.Name)
+		a.cache(p, f.Id)
+		result = append(result, a.metadata(p, f))
+		if recursive && f.MimeType == folderMimeType {
+			children, err := a.ListContents(ctx, p, true)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, children...)
+		}
+	}
+	return result, nil
+}
+
+// ListPage will list a page of the folder at given path, the cursor being a Drive page token. Recursive listings
+// are not paged natively.
+func (a *Adapter) ListPage(ctx context.Context, path filesystem.Path, recursive bool, cursor string, limit int) (filesystem.Page, error) {
+	if recursive {
+		return filesystem.Page{}, filesystem.NewUnsupportedOperationError(path, "ListPage")
+	}
+	id, err := a.resolve(ctx, path)
+	if err != nil {
+		return filesystem.Page{}, err
+	}
+	call := a.service.Files.List().Context(ctx).Q("'" + escape(id) + "' in parents and trashed = false").
+		Fields(googleapi.Field("nextPageToken, files(" + fileFields + ")")).PageSize(int64(limit)).PageToken(cursor).
+		SupportsAllDrives(true).IncludeItemsFromAllDrives(true)
+	if a.opts.DriveID != "" {
+		call = call.Corpora("drive").DriveId(a.opts.DriveID)
+	}
+	list, err := call.Do()
+	if err != nil {
+		return filesystem.Page{}, a.mapError(path, err)
+	}
+	page := filesystem.Page{Cursor: list.NextPageToken}
+	for _, f := range list.Files {
+		p := path.Join(f.Name)
+		a.cache(p, f.Id)
+		page.Entries = append(page.Entries, a.metadata(p, f))
+	}
+	return page, nil
+}
+
+// Capabilities will report the capabilities of the adapter, folders being files of their own.
+func (a *Adapter) Capabilities() filesystem.Capabilities {
+	return filesystem.Capabilities{
+		NativeCopy:        true,
+		NativeMove:        true,
+		DirectoryMetadata: true,
+		UserMetadata:      true,
+		Pagination:        true,
+		AtomicWrites:      true,
+	}
+}
@@ -0,0 +1,566 @@
+// Package gitfs provides an adapter storing files in a git repository, through go-git.
+//
+// Every write creates a commit on the configured branch, built directly from the object database without touching
+// a worktree, so bare repositories are best suited. Reads resolve from a ref, the branch by default, so a tag or a
+// commit can be read while writes keep going to the branch. Git does not track empty directories: CreateDir stores
+// a ".gitkeep" file. GetMetadata reports the "commit", "author" and time of the last commit changing the file.
+package gitfs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/maurofran/filesystem"
+)
+
+// Settings read by NewFromConfig and, for the commit ones, by writes.
+const (
+	// ConfigPath is the path of the repository, initialized as a bare repository when missing.
+	ConfigPath = "path"
+	// ConfigBranch is the branch receiving the commits.
+	ConfigBranch = "branch"
+	// ConfigRef is the ref files are read from.
+	ConfigRef = "ref"
+	// ConfigAuthorName and ConfigAuthorEmail are the author of the commits.
+	ConfigAuthorName  = "author_name"
+	ConfigAuthorEmail = "author_email"
+	// ConfigCommitMessage is the message of the commit created by a write.
+	ConfigCommitMessage = "commit_message"
+)
+
+const (
+	defaultBranch = "main"
+	dirMarker     = ".gitkeep"
+)
+
+// Options are the options of the adapter.
+type Options struct {
+	// Branch is the branch receiving the commits, "main" if empty.
+	Branch string
+	// Ref is the revision files are read from, such as a tag or a commit hash, the branch if empty.
+	Ref string
+	// AuthorName and AuthorEmail are the default author of the commits.
+	AuthorName  string
+	AuthorEmail string
+}
+
+// Adapter is an adapter storing files in a git repository.
+type Adapter struct {
+	repo *git.Repository
+	opts Options
+
+	mu sync.Mutex
+}
+
+// New will create an adapter storing files in provided repository.
+func New(repo *git.Repository, opts Options) *Adapter {
+	if opts.Branch == "" {
+		opts.Branch = defaultBranch
+	}
+	if opts.Ref == "" {
+		opts.Ref = plumbing.NewBranchReferenceName(opts.Branch).String()
+	}
+	return &Adapter{repo: repo, opts: opts}
+}
+
+func init() {
+	filesystem.RegisterAdapter("git", func(_ context.Context, settings map[string]interface{}) (filesystem.Adapter, error) {
+		return NewFromConfig(settings)
+	})
+}
+
+// NewFromConfig will create an adapter from provided settings, opening the repository at "path" or initializing
+// a bare one.
+func NewFromConfig(settings map[string]interface{}) (*Adapter, error) {
+	str := func(key string) string {
+		s, _ := settings[key].(string)
+		return s
+	}
+	repo, err := git.PlainOpen(str(ConfigPath))
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		repo, err = git.PlainInit(str(ConfigPath), true)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return New(repo, Options{
+		Branch:      str(ConfigBranch),
+		Ref:         str(ConfigRef),
+		AuthorName:  str(ConfigAuthorName),
+		AuthorEmail: str(ConfigAuthorEmail),
+	}), nil
+}
+
+// tree will resolve the tree of the commit the files are read from, nil for an empty repository.
+func (a *Adapter) tree() (*object.Commit, *object.Tree, error) {
+	hash, err := a.repo.ResolveRevision(plumbing.Revision(a.opts.Ref))
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	commit, err := a.repo.CommitObject(*hash)
+	if err != nil {
+		return nil, nil, err
+	}
+	tree, err := commit.Tree()
+	return commit, tree, err
+}
+
+// file will find the file at path in the tree read from.
+func (a *Adapter) file(p filesystem.Path) (*object.File, error) {
+	_, tree, err := a.tree()
+	if err != nil {
+		return nil, err
+	}
+	if tree == nil {
+		return nil, filesystem.NewFileNotFoundError(p)
+	}
+	f, err := tree.File(string(p))
+	if errors.Is(err, object.ErrFileNotFound) {
+		return nil, filesystem.NewFileNotFoundError(p)
+	}
+	return f, err
+}
+
+// edit is a change of a commit, setting the entry at path or removing it when entry is nil.
+type edit struct {
+	path  string
+	entry *object.TreeEntry
+}
+
+// commit will apply provided edits to the tree of the branch and commit the result.
+func (a *Adapter) commit(cfg filesystem.Config, message string, edits ...edit) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	branch := plumbing.NewBranchReferenceName(a.opts.Branch)
+	head, err := a.repo.Reference(branch, true)
+	if err != nil && !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return err
+	}
+	var parents []plumbing.Hash
+	var tree *object.Tree
+	if head != nil {
+		parent, err := a.repo.CommitObject(head.Hash())
+		if err != nil {
+			return err
+		}
+		if tree, err = parent.Tree(); err != nil {
+			return err
+		}
+		parents = append(parents, parent.Hash)
+	}
+	var hash plumbing.Hash
+	if tree != nil {
+		hash = tree.Hash
+	}
+	for _, e := range edits {
+		if hash, err = a.updateTree(hash, strings.Split(e.path, "/"), e.entry); err != nil {
+			return err
+		}
+	}
+	if hash.IsZero() {
+		// An empty tree is still a valid tree.
+		if hash, err = a.storeTree(nil); err != nil {
+			return err
+		}
+	}
+	signature := object.Signature{Name: a.opts.AuthorName, Email: a.opts.AuthorEmail, When: time.Now()}
+	if name, ok := cfg.Get(ConfigAuthorName, "").(string); ok && name != "" {
+		signature.Name = name
+	}
+	if email, ok := cfg.Get(ConfigAuthorEmail, "").(string); ok && email != "" {
+		signature.Email = email
+	}
+	if msg, ok := cfg.Get(ConfigCommitMessage, "").(string); ok && msg != "" {
+		message = msg
+	}
+	commit := &object.Commit{Author: signature, Committer: signature, Message: message, TreeHash: hash, ParentHashes: parents}
+	obj := a.repo.Storer.NewEncodedObject()
+	if err := commit.Encode(obj); err != nil {
+		return err
+	}
+	commitHash, err := a.repo.Storer.SetEncodedObject(obj)
+	if err != nil {
+		return err
+	}
+	if err := a.repo.Storer.CheckAndSetReference(plumbing.NewHashReference(branch, commitHash), head); err != nil {
+		return err
+	}
+	if head == nil {
+		return a.adoptHead(branch)
+	}
+	return nil
+}
+
+// adoptHead will point HEAD to the branch when it points to a branch which does not exist, as in new repositories,
+// so that clones check out the branch.
+func (a *Adapter) adoptHead(branch plumbing.ReferenceName) error {
+	head, err := a.repo.Storer.Reference(plumbing.HEAD)
+	if err != nil || head.Type() != plumbing.SymbolicReference || head.Target() == branch {
+		return nil
+	}
+	if _, err := a.repo.Storer.Reference(head.Target()); !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil
+	}
+	return a.repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, branch))
+}
+
+// updateTree will set or remove the entry at parts in the tree with provided hash, zero for an empty tree,
+// returning the hash of the new tree, zero when it ends up empty.
+func (a *Adapter) updateTree(hash plumbing.Hash, parts []string, entry *object.TreeEntry) (plumbing.Hash, error) {
+	var entries []object.TreeEntry
+	if !hash.IsZero() {
+		tree, err := a.repo.TreeObject(hash)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		entries = tree.Entries
+	}
+	name := parts[0]
+	var result []object.TreeEntry
+	var current plumbing.Hash
+	for _, e := range entries {
+		if e.Name == name {
+			if e.Mode == filemode.Dir {
+				current = e.Hash
+			}
+			continue
+		}
+		result = append(result, e)
+	}
+	switch {
+	case len(parts) > 1:
+		sub, err := a.updateTree(current, parts[1:], entry)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		if !sub.IsZero() {
+			result = append(result, object.TreeEntry{Name: name, Mode: filemode.Dir, Hash: sub})
+		}
+	case entry != nil:
+		e := *entry
+		e.Name = name
+		result = append(result, e)
+	}
+	if len(result) == 0 {
+		return plumbing.ZeroHash, nil
+	}
+	return a.storeTree(result)
+}
+
+func (a *Adapter) storeTree(entries []object.TreeEntry) (plumbing.Hash, error) {
+	// Git orders entries by name, directories as if their name ended with a slash.
+	key := func(e object.TreeEntry) string {
+		if e.Mode == filemode.Dir {
+			return e.Name + "/"
+		}
+		return e.Name
+	}
+	sort.Slice(entries, func(i, j int) bool { return key(entries[i]) < key(entries[j]) })
+	obj := a.repo.Storer.NewEncodedObject()
+	if err := (&object.Tree{Entries: entries}).Encode(obj); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return a.repo.Storer.SetEncodedObject(obj)
+}
+
+// storeBlob will store the content of r as a blob, returning its entry.
+func (a *Adapter) storeBlob(r io.Reader) (*object.TreeEntry, error) {
+	obj := a.repo.Storer.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+	w, err := obj.Writer()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	hash, err := a.repo.Storer.SetEncodedObject(obj)
+	if err != nil {
+		return nil, err
+	}
+	return &object.TreeEntry{Mode: filemode.Regular, Hash: hash}, nil
+}
+
+func (a *Adapter) put(p filesystem.Path, r io.Reader, cfg filesystem.Config, op string) error {
+	entry, err := a.storeBlob(r)
+	if err != nil {
+		return err
+	}
+	return a.commit(cfg, fmt.Sprintf("%s %s", op, p), edit{path: string(p), entry: entry})
+}
+
+// Has will check if a file exists.
+func (a *Adapter) Has(ctx context.Context, path filesystem.Path) (bool, error) {
+	_, err := a.file(path)
+	if filesystem.IsFileNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Read the file at provided path.
+func (a *Adapter) Read(ctx context.Context, path filesystem.Path) ([]byte, error) {
+	rc, err := a.ReadStream(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// ReadStream will read the file at provided path as a stream.
+func (a *Adapter) ReadStream(ctx context.Context, path filesystem.Path) (io.ReadCloser, error) {
+	f, err := a.file(path)
+	if err != nil {
+		return nil, err
+	}
+	return f.Reader()
+}
+
+// Write the supplied content at supplied path, creating the file.
+func (a *Adapter) Write(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	return a.put(path, bytes.NewReader(content), cfg, "Write")
+}
+
+// WriteStream will write the content of provided reader at supplied path, creating the file.
+func (a *Adapter) WriteStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	return a.put(path, r, cfg, "Write")
+}
+
+// Update the supplied content at supplied path, returning an error if file does not exists.
+func (a *Adapter) Update(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	if _, err := a.file(path); err != nil {
+		return err
+	}
+	return a.put(path, bytes.NewReader(content), cfg, "Update")
+}
+
+// Update with the content of supplied reader at supplied path, returning an error if file does not exists
+func (a *Adapter) UpdateStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	if _, err := a.file(path); err != nil {
+		return err
+	}
+	return a.put(path, r, cfg, "Update")
+}
+
+// Put the supplied content at supplied path, creating the file if does not exists.
+func (a *Adapter) Put(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	return a.put(path, bytes.NewReader(content), cfg, "Put")
+}
+
+// Puth the content of supplied reader at supplied path, creating the file if does not exists.
+func (a *Adapter) PutStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	return a.put(path, r, cfg, "Put")
+}
+
+// Deletes a file at provided path, committing its removal.
+func (a *Adapter) Delete(ctx context.Context, path filesystem.Path) error {
+	if _, err := a.file(path); err != nil {
+		return err
+	}
+	return a.commit(*filesystem.EmptyConfig(), fmt.Sprintf("Delete %s", path), edit{path: string(path)})
+}
+
+// ReadAndDelete will read the file at provided path and delete after read.
+func (a *Adapter) ReadAndDelete(ctx context.Context, path filesystem.Path) ([]byte, error) {
+	content, err := a.Read(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return content, a.Delete(ctx, path)
+}
+
+// Move the file at supplied path to new path, in a single commit.
+func (a *Adapter) Move(ctx context.Context, path, newpath filesystem.Path) error {
+	f, err := a.file(path)
+	if err != nil {
+		return err
+	}
+	entry := &object.TreeEntry{Mode: f.Mode, Hash: f.Hash}
+	return a.commit(*filesystem.EmptyConfig(), fmt.Sprintf("Move %s to %s", path, newpath),
+		edit{path: string(path)}, edit{path: string(newpath), entry: entry})
+}
+
+// Copy the file at supplied path to new path, sharing its blob.
+func (a *Adapter) Copy(ctx context.Context, path, newpath filesystem.Path) error {
+	f, err := a.file(path)
+	if err != nil {
+		return err
+	}
+	entry := &object.TreeEntry{Mode: f.Mode, Hash: f.Hash}
+	return a.commit(*filesystem.EmptyConfig(), fmt.Sprintf("Copy %s to %s", path, newpath),
+		edit{path: string(newpath), entry: entry})
+}
+
+func mimeType(p filesystem.Path) string {
+	if mt := mime.TypeByExtension(p.Ext()); mt != "" {
+		return mt
+	}
+	return "application/octet-stream"
+}
+
+// GetMimeType will retrieve the mime type of file at supplied path, guessed from its extension.
+func (a *Adapter) GetMimeType(ctx context.Context, path filesystem.Path) (string, error) {
+	if _, err := a.file(path); err != nil {
+		return "", err
+	}
+	return mimeType(path), nil
+}
+
+// GetTimestamp will retrieve the timestamp of file at supplied path, the time of the last commit changing it.
+func (a *Adapter) GetTimestamp(ctx context.Context, path filesystem.Path) (time.Time, error) {
+	md, err := a.GetMetadata(ctx, path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return md.Timestamp, nil
+}
+
+// GetFileSize will retrieve the size of file at supplied path.
+func (a *Adapter) GetFileSize(ctx context.Context, path filesystem.Path) (int64, error) {
+	f, err := a.file(path)
+	if err != nil {
+		return 0, err
+	}
+	return f.Size, nil
+}
+
+// lastCommit will find the last commit, reachable from the ref read from, changing the file at path.
+func (a *Adapter) lastCommit(p filesystem.Path) (*object.Commit, error) {
+	head, _, err := a.tree()
+	if err != nil || head == nil {
+		return nil, err
+	}
+	name := string(p)
+	commits, err := a.repo.Log(&git.LogOptions{From: head.Hash, FileName: &name})
+	if err != nil {
+		return nil, err
+	}
+	defer commits.Close()
+	return commits.Next()
+}
+
+// GetMetadata will retrieve the metadata of file at supplied path, including the "commit" hash and "author" of the
+// last commit changing it. The ETag is the blob hash.
+func (a *Adapter) GetMetadata(ctx context.Context, path filesystem.Path) (filesystem.Metadata, error) {
+	f, err := a.file(path)
+	if err != nil {
+		return nil, err
+	}
+	commit, err := a.lastCommit(path)
+	if err != nil {
+		return nil, err
+	}
+	md := entryMetadata(path, f.Mode, f.Hash, f.Size)
+	md.Timestamp = commit.Author.When
+	md.Extra = map[string]interface{}{"commit": commit.Hash.String(), "author": commit.Author.String()}
+	return md, nil
+}
+
+func entryMetadata(p filesystem.Path, mode filemode.FileMode, hash plumbing.Hash, size int64) filesystem.Metadata {
+	if mode == filemode.Dir {
+		return &filesystem.FileInfo{Path: p, Type: filesystem.TypeDir}
+	}
+	return &filesystem.FileInfo{Path: p, Type: filesystem.TypeFile, Size: size, MimeType: mimeType(p), ETag: hash.String()}
+}
+
+// CreateDir will create a new directory at provided path, committing a marker file since git does not track
+// empty directories.
+func (a *Adapter) CreateDir(ctx context.Context, path filesystem.Path, cfg filesystem.Config) error {
+	return a.put(path.Join(dirMarker), bytes.NewReader(nil), cfg, "Create")
+}
+
+// DeleteDir will delete the directory at provided path, committing the removal of its contents.
+func (a *Adapter) DeleteDir(ctx context.Context, path filesystem.Path) error {
+	_, tree, err := a.tree()
+	if err != nil {
+		return err
+	}
+	if tree == nil {
+		return filesystem.NewFileNotFoundError(path)
+	}
+	if _, err := tree.Tree(string(path)); err != nil {
+		if errors.Is(err, object.ErrDirectoryNotFound) {
+			return filesystem.NewFileNotFoundError(path)
+		}
+		return err
+	}
+	return a.commit(*filesystem.EmptyConfig(), fmt.Sprintf("Delete %s", path), edit{path: string(path)})
+}
+
+// Get the visibility of file at supplied path. Git has no notion of visibility: files are public to whoever can
+// read the repository.
+func (a *Adapter) GetVisibility(ctx context.Context, path filesystem.Path) (filesystem.Visibility, error) {
+	if _, err := a.file(path); err != nil {
+		return "", err
+	}
+	return filesystem.VisibilityPublic, nil
+}
+
+// Set the visibility of file at supplied path, failing unless it is public.
+func (a *Adapter) SetVisibility(ctx context.Context, path filesystem.Path, v filesystem.Visibility) error {
+	current, err := a.GetVisibility(ctx, path)
+	if err != nil || current == v {
+		return err
+	}
+	return fmt.Errorf("gitfs: unable to make %s %s, git has no notion of visibility", path, v)
+}
+
+// List the contents of given path. Listings report the blob hash of files as ETag, not their last commit.
+func (a *Adapter) ListContents(ctx context.Context, path filesystem.Path, recursive bool) ([]filesystem.Metadata, error) {
+	_, tree, err := a.tree()
+	if err != nil || tree == nil {
+		return nil, err
+	}
+	if path != filesystem.RootPath {
+		if tree, err = tree.Tree(string(path)); err != nil {
+			if errors.Is(err, object.ErrDirectoryNotFound) {
+				return nil, filesystem.NewFileNotFoundError(path)
+			}
+			return nil, err
+		}
+	}
+	walker := object.NewTreeWalker(tree, recursive, nil)
+	defer walker.Close()
+	var result []filesystem.Metadata
+	for {
+		name, entry, err := walker.Next()
+		if err == io.EOF {
+			return result, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		var size int64
+		if entry.Mode != filemode.Dir {
+			if size, err = tree.Size(name); err != nil {
+				return nil, err
+			}
+		}
+		result = append(result, entryMetadata(path.Join(name), entry.Mode, entry.Hash, size))
+	}
+}
+
+// Capabilities will report the capabilities of the adapter, copies and moves sharing blobs.
+func (a *Adapter) Capabilities() filesystem.Capabilities {
+	return filesystem.Capabilities{NativeCopy: true, NativeMove: true, AtomicWrites: true}
+}
@@ -0,0 +1,16 @@
+package gitfs
+
+import "github.com/maurofran/filesystem"
+
+// WithCommitMessage will set the message of the commit created by a write.
+func WithCommitMessage(message string) filesystem.WriteOption {
+	return filesystem.WithSetting(ConfigCommitMessage, message)
+}
+
+// WithAuthor will set the author of the commit created by a write.
+func WithAuthor(name, email string) filesystem.WriteOption {
+	return filesystem.Option(func(cfg *filesystem.Config) {
+		cfg.Set(ConfigAuthorName, name)
+		cfg.Set(ConfigAuthorEmail, email)
+	})
+}
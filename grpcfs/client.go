@@ -0,0 +1,309 @@
+package grpcfs
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/maurofran/filesystem"
+)
+
+// Adapter is an adapter calling the adapter served by a remote process.
+type Adapter struct {
+	conn grpc.ClientConnInterface
+	opts []grpc.CallOption
+}
+
+// New will create an adapter calling the adapter served on provided connection. Provided options are applied to every
+// call.
+func New(conn grpc.ClientConnInterface, opts ...grpc.CallOption) *Adapter {
+	return &Adapter{conn: conn, opts: append([]grpc.CallOption{grpc.CallContentSubtype(codecName)}, opts...)}
+}
+
+func (a *Adapter) invoke(ctx context.Context, method string, req *Request) (*Response, error) {
+	res := new(Response)
+	if err := a.conn.Invoke(ctx, "/"+ServiceName+"/"+method, req, res, a.opts...); err != nil {
+		return nil, fromStatus(err, filesystem.Path(req.Path), method)
+	}
+	return res, nil
+}
+
+// Has will check if a file exists.
+func (a *Adapter) Has(ctx context.Context, path filesystem.Path) (bool, error) {
+	res, err := a.invoke(ctx, "Has", &Request{Path: string(path)})
+	if err != nil {
+		return false, err
+	}
+	return res.Exists, nil
+}
+
+// Read the file at provided path.
+func (a *Adapter) Read(ctx context.Context, path filesystem.Path) ([]byte, error) {
+	res, err := a.invoke(ctx, "Read", &Request{Path: string(path)})
+	if err != nil {
+		return nil, err
+	}
+	return res.Content, nil
+}
+
+// ReadStream will read the file at provided path as a stream, received in chunks.
+func (a *Adapter) ReadStream(ctx context.Context, path filesystem.Path) (io.ReadCloser, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	desc := &grpc.StreamDesc{StreamName: "ReadStream", ServerStreams: true}
+	stream, err := a.conn.NewStream(ctx, desc, "/"+ServiceName+"/ReadStream", a.opts...)
+	if err != nil {
+		cancel()
+		return nil, fromStatus(err, path, "ReadStream")
+	}
+	if err := stream.SendMsg(&Request{Path: string(path)}); err != nil {
+		cancel()
+		return nil, fromStatus(err, path, "ReadStream")
+	}
+	if err := stream.CloseSend(); err != nil {
+		cancel()
+		return nil, fromStatus(err, path, "ReadStream")
+	}
+	// Receive the first chunk, so that errors like a missing file are returned here.
+	first := new(Response)
+	if err := stream.RecvMsg(first); err != nil && err != io.EOF {
+		cancel()
+		return nil, fromStatus(err, path, "ReadStream")
+	}
+	return &streamContent{
+		streamReader: streamReader{recv: func() ([]byte, error) {
+			res := new(Response)
+			if err := stream.RecvMsg(res); err != nil {
+				if err == io.EOF {
+					return nil, err
+				}
+				return nil, fromStatus(err, path, "ReadStream")
+			}
+			return res.Content, nil
+		}, chunk: first.Content},
+		cancel: cancel,
+	}, nil
+}
+
+type streamContent struct {
+	streamReader
+	cancel context.CancelFunc
+}
+
+func (r *streamContent) Close() error {
+	r.cancel()
+	return nil
+}
+
+// Write the supplied content at supplied path, creating the file.
+func (a *Adapter) Write(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	_, err := a.invoke(ctx, "Write", &Request{Path: string(path), Content: content, Config: encodeConfig(cfg)})
+	return err
+}
+
+// WriteStream will write the content of provided reader at supplied path, creating the file.
+func (a *Adapter) WriteStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	return a.writeStream(ctx, "WriteStream", path, r, cfg)
+}
+
+// Update the supplied content at supplied path, returning an error if file does not exists.
+func (a *Adapter) Update(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	_, err := a.invoke(ctx, "Update", &Request{Path: string(path), Content: content, Config: encodeConfig(cfg)})
+	return err
+}
+
+// UpdateStream will update with the content of supplied reader at supplied path, returning an error if file does not
+// exists.
+func (a *Adapter) UpdateStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	return a.writeStream(ctx, "UpdateStream", path, r, cfg)
+}
+
+// Put the supplied content at supplied path, creating the file if does not exists.
+func (a *Adapter) Put(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	_, err := a.invoke(ctx, "Put", &Request{Path: string(path), Content: content, Config: encodeConfig(cfg)})
+	return err
+}
+
+// PutStream will put the content of supplied reader at supplied path, creating the file if does not exists.
+func (a *Adapter) PutStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	return a.writeStream(ctx, "PutStream", path, r, cfg)
+}
+
+// writeStream will send the path and config in the first request, then the content of r in chunks.
+func (a *Adapter) writeStream(ctx context.Context, method string, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	desc := &grpc.StreamDesc{StreamName: method, ClientStreams: true}
+	stream, err := a.conn.NewStream(ctx, desc, "/"+ServiceName+"/"+method, a.opts...)
+	if err != nil {
+		return fromStatus(err, path, method)
+	}
+	if err := stream.SendMsg(&Request{Path: string(path), Config: encodeConfig(cfg)}); err != nil {
+		return a.closeStream(stream, err, path, method)
+	}
+	buf := make([]byte, chunkSize)
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			if err := stream.SendMsg(&Request{Content: buf[:n]}); err != nil {
+				return a.closeStream(stream, err, path, method)
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		return fromStatus(err, path, method)
+	}
+	if err := stream.RecvMsg(new(Response)); err != nil {
+		return fromStatus(err, path, method)
+	}
+	return nil
+}
+
+// closeStream will return the status of a stream whose send failed: gRPC returns io.EOF from SendMsg when the server
+// ended the stream, the actual status being received by RecvMsg.
+func (a *Adapter) closeStream(stream grpc.ClientStream, err error, path filesystem.Path, method string) error {
+	if err == io.EOF {
+		err = stream.RecvMsg(new(Response))
+	}
+	return fromStatus(err, path, method)
+}
+
+// Delete a file at provided path.
+func (a *Adapter) Delete(ctx context.Context, path filesystem.Path) error {
+	_, err := a.invoke(ctx, "Delete", &Request{Path: string(path)})
+	return err
+}
+
+// ReadAndDelete will read the file at provided path and delete after read.
+func (a *Adapter) ReadAndDelete(ctx context.Context, path filesystem.Path) ([]byte, error) {
+	res, err := a.invoke(ctx, "ReadAndDelete", &Request{Path: string(path)})
+	if err != nil {
+		return nil, err
+	}
+	return res.Content, nil
+}
+
+// Move the file at supplied path to new path.
+func (a *Adapter) Move(ctx context.Context, path, newpath filesystem.Path) error {
+	_, err := a.invoke(ctx, "Move", &Request{Path: string(path), NewPath: string(newpath)})
+	return err
+}
+
+// Copy the file at supplied path to new path.
+func (a *Adapter) Copy(ctx context.Context, path, newpath filesystem.Path) error {
+	_, err := a.invoke(ctx, "Copy", &Request{Path: string(path), NewPath: string(newpath)})
+	return err
+}
+
+// GetMimeType will retrieve the mime type of file at supplied path.
+func (a *Adapter) GetMimeType(ctx context.Context, path filesystem.Path) (string, error) {
+	res, err := a.invoke(ctx, "GetMimeType", &Request{Path: string(path)})
+	if err != nil {
+		return "", err
+	}
+	return res.MimeType, nil
+}
+
+// GetTimestamp will retrieve the timestamp of file at supplied path.
+func (a *Adapter) GetTimestamp(ctx context.Context, path filesystem.Path) (time.Time, error) {
+	res, err := a.invoke(ctx, "GetTimestamp", &Request{Path: string(path)})
+	if err != nil {
+		return time.Time{}, err
+	}
+	return res.Timestamp, nil
+}
+
+// GetFileSize will retrieve the size of file at supplied path.
+func (a *Adapter) GetFileSize(ctx context.Context, path filesystem.Path) (int64, error) {
+	res, err := a.invoke(ctx, "GetFileSize", &Request{Path: string(path)})
+	if err != nil {
+		return 0, err
+	}
+	return res.Size, nil
+}
+
+// GetMetadata will retrieve the metadata of file at supplied path.
+func (a *Adapter) GetMetadata(ctx context.Context, path filesystem.Path) (filesystem.Metadata, error) {
+	res, err := a.invoke(ctx, "GetMetadata", &Request{Path: string(path)})
+	if err != nil {
+		return nil, err
+	}
+	if res.Metadata == nil {
+		return nil, filesystem.NewFileNotFoundError(path)
+	}
+	return decodeMetadata(res.Metadata), nil
+}
+
+// CreateDir will create a new directory at provided path.
+func (a *Adapter) CreateDir(ctx context.Context, path filesystem.Path, cfg filesystem.Config) error {
+	_, err := a.invoke(ctx, "CreateDir", &Request{Path: string(path), Config: encodeConfig(cfg)})
+	return err
+}
+
+// DeleteDir will delete the directory at provided path.
+func (a *Adapter) DeleteDir(ctx context.Context, path filesystem.Path) error {
+	_, err := a.invoke(ctx, "DeleteDir", &Request{Path: string(path)})
+	return err
+}
+
+// GetVisibility will get the visibility of file at supplied path.
+func (a *Adapter) GetVisibility(ctx context.Context, path filesystem.Path) (filesystem.Visibility, error) {
+	res, err := a.invoke(ctx, "GetVisibility", &Request{Path: string(path)})
+	if err != nil {
+		return "", err
+	}
+	return filesystem.Visibility(res.Visibility), nil
+}
+
+// SetVisibility will set the visibility of file at supplied path.
+func (a *Adapter) SetVisibility(ctx context.Context, path filesystem.Path, v filesystem.Visibility) error {
+	_, err := a.invoke(ctx, "SetVisibility", &Request{Path: string(path), Visibility: string(v)})
+	return err
+}
+
+// ListContents will list the contents of given path.
+func (a *Adapter) ListContents(ctx context.Context, path filesystem.Path, recursive bool) ([]filesystem.Metadata, error) {
+	res, err := a.invoke(ctx, "ListContents", &Request{Path: string(path), Recursive: recursive})
+	if err != nil {
+		return nil, err
+	}
+	listing := make([]filesystem.Metadata, len(res.Listing))
+	for i, fi := range res.Listing {
+		listing[i] = decodeMetadata(fi)
+	}
+	return listing, nil
+}
+
+// fromStatus will convert the status of a failed call back to the error of the remote adapter.
+func fromStatus(err error, path filesystem.Path, method string) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	switch st.Code() {
+	case codes.NotFound:
+		return filesystem.NewFileNotFoundError(path)
+	case codes.FailedPrecondition:
+		return filesystem.NewPreconditionFailedError(path)
+	case codes.Unimplemented:
+		return filesystem.NewUnsupportedOperationError(path, method)
+	case codes.PermissionDenied:
+		return filesystem.NewReadOnlyError(path)
+	case codes.Aborted:
+		return filesystem.NewLockedError(path)
+	case codes.Canceled:
+		return context.Canceled
+	case codes.DeadlineExceeded:
+		return context.DeadlineExceeded
+	}
+	return err
+}
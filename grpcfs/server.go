@@ -0,0 +1,273 @@
+package grpcfs
+
+import (
+	"context"
+	"io"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/maurofran/filesystem"
+)
+
+// Register will serve provided adapter on a gRPC server.
+func Register(s grpc.ServiceRegistrar, a filesystem.Adapter) {
+	s.RegisterService(serviceDesc(), &server{a})
+}
+
+type server struct {
+	adapter filesystem.Adapter
+}
+
+type unaryMethod func(s *server, ctx context.Context, req *Request) (*Response, error)
+
+var unaryMethods = map[string]unaryMethod{
+	"Has": func(s *server, ctx context.Context, req *Request) (*Response, error) {
+		ok, err := s.adapter.Has(ctx, filesystem.Path(req.Path))
+		return &Response{Exists: ok}, err
+	},
+	"Read": func(s *server, ctx context.Context, req *Request) (*Response, error) {
+		content, err := s.adapter.Read(ctx, filesystem.Path(req.Path))
+		return &Response{Content: content}, err
+	},
+	"Write": func(s *server, ctx context.Context, req *Request) (*Response, error) {
+		return &Response{}, s.adapter.Write(ctx, filesystem.Path(req.Path), req.Content, decodeConfig(req.Config))
+	},
+	"Update": func(s *server, ctx context.Context, req *Request) (*Response, error) {
+		return &Response{}, s.adapter.Update(ctx, filesystem.Path(req.Path), req.Content, decodeConfig(req.Config))
+	},
+	"Put": func(s *server, ctx context.Context, req *Request) (*Response, error) {
+		return &Response{}, s.adapter.Put(ctx, filesystem.Path(req.Path), req.Content, decodeConfig(req.Config))
+	},
+	"Delete": func(s *server, ctx context.Context, req *Request) (*Response, error) {
+		return &Response{}, s.adapter.Delete(ctx, filesystem.Path(req.Path))
+	},
+	"ReadAndDelete": func(s *server, ctx context.Context, req *Request) (*Response, error) {
+		content, err := s.adapter.ReadAndDelete(ctx, filesystem.Path(req.Path))
+		return &Response{Content: content}, err
+	},
+	"Move": func(s *server, ctx context.Context, req *Request) (*Response, error) {
+		return &Response{}, s.adapter.Move(ctx, filesystem.Path(req.Path), filesystem.Path(req.NewPath))
+	},
+	"Copy": func(s *server, ctx context.Context, req *Request) (*Response, error) {
+		return &Response{}, s.adapter.Copy(ctx, filesystem.Path(req.Path), filesystem.Path(req.NewPath))
+	},
+	"GetMimeType": func(s *server, ctx context.Context, req *Request) (*Response, error) {
+		mt, err := s.adapter.GetMimeType(ctx, filesystem.Path(req.Path))
+		return &Response{MimeType: mt}, err
+	},
+	"GetTimestamp": func(s *server, ctx context.Context, req *Request) (*Response, error) {
+		t, err := s.adapter.GetTimestamp(ctx, filesystem.Path(req.Path))
+		return &Response{Timestamp: t}, err
+	},
+	"GetFileSize": func(s *server, ctx context.Context, req *Request) (*Response, error) {
+		size, err := s.adapter.GetFileSize(ctx, filesystem.Path(req.Path))
+		return &Response{Size: size}, err
+	},
+	"GetMetadata": func(s *server, ctx context.Context, req *Request) (*Response, error) {
+		md, err := s.adapter.GetMetadata(ctx, filesystem.Path(req.Path))
+		if err != nil {
+			return nil, err
+		}
+		return &Response{Metadata: encodeMetadata(md)}, nil
+	},
+	"CreateDir": func(s *server, ctx context.Context, req *Request) (*Response, error) {
+		return &Response{}, s.adapter.CreateDir(ctx, filesystem.Path(req.Path), decodeConfig(req.Config))
+	},
+	"DeleteDir": func(s *server, ctx context.Context, req *Request) (*Response, error) {
+		return &Response{}, s.adapter.DeleteDir(ctx, filesystem.Path(req.Path))
+	},
+	"GetVisibility": func(s *server, ctx context.Context, req *Request) (*Response, error) {
+		v, err := s.adapter.GetVisibility(ctx, filesystem.Path(req.Path))
+		return &Response{Visibility: string(v)}, err
+	},
+	"SetVisibility": func(s *server, ctx context.Context, req *Request) (*Response, error) {
+		return &Response{}, s.adapter.SetVisibility(ctx, filesystem.Path(req.Path), filesystem.Visibility(req.Visibility))
+	},
+	"ListContents": func(s *server, ctx context.Context, req *Request) (*Response, error) {
+		listing, err := s.adapter.ListContents(ctx, filesystem.Path(req.Path), req.Recursive)
+		if err != nil {
+			return nil, err
+		}
+		res := &Response{Listing: make([]*FileInfo, len(listing))}
+		for i, md := range listing {
+			res.Listing[i] = encodeMetadata(md)
+		}
+		return res, nil
+	},
+}
+
+// writeMethod is a method writing content read from a stream.
+type writeMethod func(a filesystem.Adapter, ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error
+
+// writeMethods are the methods receiving content as a stream: the first request holds the path and config, the
+// following ones the chunks of content.
+var writeMethods = map[string]writeMethod{
+	"WriteStream":  filesystem.Adapter.WriteStream,
+	"UpdateStream": filesystem.Adapter.UpdateStream,
+	"PutStream":    filesystem.Adapter.PutStream,
+}
+
+func serviceDesc() *grpc.ServiceDesc {
+	desc := &grpc.ServiceDesc{
+		ServiceName: ServiceName,
+		HandlerType: (*interface{})(nil),
+		Streams: []grpc.StreamDesc{{
+			StreamName:    "ReadStream",
+			Handler:       readStream,
+			ServerStreams: true,
+		}},
+	}
+	for name, fn := range unaryMethods {
+		desc.Methods = append(desc.Methods, grpc.MethodDesc{MethodName: name, Handler: unaryHandler(name, fn)})
+	}
+	for name, fn := range writeMethods {
+		desc.Streams = append(desc.Streams, grpc.StreamDesc{
+			StreamName:    name,
+			Handler:       writeStream(fn),
+			ClientStreams: true,
+		})
+	}
+	return desc
+}
+
+func unaryHandler(name string, fn unaryMethod) grpc.MethodHandler {
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		req := new(Request)
+		if err := dec(req); err != nil {
+			return nil, err
+		}
+		handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+			if err := normalize(req.(*Request)); err != nil {
+				return nil, toStatus(err)
+			}
+			res, err := fn(srv.(*server), ctx, req.(*Request))
+			if err != nil {
+				return nil, toStatus(err)
+			}
+			return res, nil
+		}
+		if interceptor == nil {
+			return handler(ctx, req)
+		}
+		return interceptor(ctx, req, &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + ServiceName + "/" + name}, handler)
+	}
+}
+
+func readStream(srv interface{}, stream grpc.ServerStream) error {
+	req := new(Request)
+	if err := stream.RecvMsg(req); err != nil {
+		return err
+	}
+	if err := normalize(req); err != nil {
+		return toStatus(err)
+	}
+	rc, err := srv.(*server).adapter.ReadStream(stream.Context(), filesystem.Path(req.Path))
+	if err != nil {
+		return toStatus(err)
+	}
+	defer rc.Close()
+	buf := make([]byte, chunkSize)
+	for {
+		n, err := rc.Read(buf)
+		if n > 0 {
+			if err := stream.SendMsg(&Response{Content: buf[:n]}); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return toStatus(err)
+		}
+	}
+}
+
+func writeStream(fn writeMethod) grpc.StreamHandler {
+	return func(srv interface{}, stream grpc.ServerStream) error {
+		req := new(Request)
+		if err := stream.RecvMsg(req); err != nil {
+			return err
+		}
+		if err := normalize(req); err != nil {
+			return toStatus(err)
+		}
+		r := &streamReader{recv: func() ([]byte, error) {
+			chunk := new(Request)
+			if err := stream.RecvMsg(chunk); err != nil {
+				return nil, err
+			}
+			return chunk.Content, nil
+		}, chunk: req.Content}
+		if err := fn(srv.(*server).adapter, stream.Context(), filesystem.Path(req.Path), r, decodeConfig(req.Config)); err != nil {
+			return toStatus(err)
+		}
+		return stream.SendMsg(&Response{})
+	}
+}
+
+// normalize will normalize the paths of the request, as the core does before calling adapters, so that clients
+// cannot escape the root of the adapter. Invalid paths are refused with a PathError.
+func normalize(req *Request) error {
+	path, err := filesystem.NewPath(req.Path)
+	if err != nil {
+		return err
+	}
+	req.Path = string(path)
+	if req.NewPath != "" {
+		newpath, err := filesystem.NewPath(req.NewPath)
+		if err != nil {
+			return err
+		}
+		req.NewPath = string(newpath)
+	}
+	return nil
+}
+
+// streamReader reads the chunks received from a stream, up to its end.
+type streamReader struct {
+	recv  func() ([]byte, error)
+	chunk []byte
+}
+
+func (r *streamReader) Read(p []byte) (int, error) {
+	for len(r.chunk) == 0 {
+		chunk, err := r.recv()
+		if err != nil {
+			return 0, err
+		}
+		r.chunk = chunk
+	}
+	n := copy(p, r.chunk)
+	r.chunk = r.chunk[n:]
+	return n, nil
+}
+
+// toStatus will convert an error of the adapter to a gRPC status, so that the client can convert it back.
+func toStatus(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	code := codes.Unknown
+	switch {
+	case filesystem.IsFileNotFound(err):
+		code = codes.NotFound
+	case filesystem.IsPreconditionFailed(err):
+		code = codes.FailedPrecondition
+	case filesystem.IsUnsupportedOperationError(err):
+		code = codes.Unimplemented
+	case filesystem.IsReadOnlyError(err):
+		code = codes.PermissionDenied
+	case filesystem.IsLockedError(err):
+		code = codes.Aborted
+	case filesystem.IsPathError(err):
+		code = codes.InvalidArgument
+	case err == context.Canceled:
+		code = codes.Canceled
+	case err == context.DeadlineExceeded:
+		code = codes.DeadlineExceeded
+	}
+	return status.Error(code, err.Error())
+}
@@ -0,0 +1,143 @@
+// Package grpcfs exposes an adapter over gRPC, so that one process can serve its file system to others: Register
+// serves an adapter on a gRPC server, and New returns an adapter calling a remote one. Connections are secured with
+// the transport credentials of gRPC, and calls authenticated with a bearer token using TokenAuth and WithToken.
+//
+// The service mirrors the Adapter interface, one RPC per method, streams being sent in chunks. Messages are encoded
+// with a JSON codec registered with gRPC, so no code generation is involved.
+package grpcfs
+
+import (
+	"encoding/json"
+	"time"
+
+	"google.golang.org/grpc/encoding"
+
+	"github.com/maurofran/filesystem"
+)
+
+// ServiceName is the name of the gRPC service.
+const ServiceName = "filesystem.Adapter"
+
+// chunkSize is the size of the chunks streams are sent in.
+const chunkSize = 64 << 10
+
+// codecName is the content subtype of the codec messages are encoded with.
+const codecName = "filesystem-json"
+
+func init() {
+	encoding.RegisterCodec(codec{})
+}
+
+type codec struct{}
+
+func (codec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (codec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (codec) Name() string {
+	return codecName
+}
+
+// Request is the request message of every RPC, holding the arguments of the method.
+type Request struct {
+	Path       string  `json:"path,omitempty"`
+	NewPath    string  `json:"newPath,omitempty"`
+	Content    []byte  `json:"content,omitempty"`
+	Config     *Config `json:"config,omitempty"`
+	Visibility string  `json:"visibility,omitempty"`
+	Recursive  bool    `json:"recursive,omitempty"`
+}
+
+// Response is the response message of every RPC, holding the results of the method.
+type Response struct {
+	Exists     bool        `json:"exists,omitempty"`
+	Content    []byte      `json:"content,omitempty"`
+	MimeType   string      `json:"mimeType,omitempty"`
+	Timestamp  time.Time   `json:"timestamp,omitempty"`
+	Size       int64       `json:"size,omitempty"`
+	Visibility string      `json:"visibility,omitempty"`
+	Metadata   *FileInfo   `json:"metadata,omitempty"`
+	Listing    []*FileInfo `json:"listing,omitempty"`
+}
+
+// Config holds the settings of a write sent to the server: the ones set by the write options of the filesystem
+// package. Adapter specific settings are not sent.
+type Config struct {
+	Visibility    string            `json:"visibility,omitempty"`
+	MimeType      string            `json:"mimeType,omitempty"`
+	Metadata      map[string]string `json:"metadata,omitempty"`
+	SniffMimeType bool              `json:"sniffMimeType,omitempty"`
+}
+
+func encodeConfig(cfg filesystem.Config) *Config {
+	c := &Config{}
+	if v, ok := cfg.Get(filesystem.ConfigVisibility, nil).(filesystem.Visibility); ok {
+		c.Visibility = string(v)
+	}
+	c.MimeType, _ = cfg.Get(filesystem.ConfigMimeType, "").(string)
+	c.Metadata, _ = cfg.Get(filesystem.ConfigMetadata, nil).(map[string]string)
+	c.SniffMimeType, _ = cfg.Get(filesystem.ConfigSniffMimeType, false).(bool)
+	return c
+}
+
+func decodeConfig(c *Config) filesystem.Config {
+	cfg := filesystem.EmptyConfig()
+	if c == nil {
+		return *cfg
+	}
+	if c.Visibility != "" {
+		cfg.Set(filesystem.ConfigVisibility, filesystem.Visibility(c.Visibility))
+	}
+	if c.MimeType != "" {
+		cfg.Set(filesystem.ConfigMimeType, c.MimeType)
+	}
+	if c.Metadata != nil {
+		cfg.Set(filesystem.ConfigMetadata, c.Metadata)
+	}
+	if c.SniffMimeType {
+		cfg.Set(filesystem.ConfigSniffMimeType, true)
+	}
+	return *cfg
+}
+
+// FileInfo is the metadata of an entry. Adapter specific extras are not sent.
+type FileInfo struct {
+	Path       string    `json:"path"`
+	Type       string    `json:"type"`
+	Size       int64     `json:"size"`
+	MimeType   string    `json:"mimeType,omitempty"`
+	Timestamp  time.Time `json:"timestamp,omitempty"`
+	Visibility string    `json:"visibility,omitempty"`
+	ETag       string    `json:"etag,omitempty"`
+	VersionID  string    `json:"versionId,omitempty"`
+}
+
+func encodeMetadata(md filesystem.Metadata) *FileInfo {
+	return &FileInfo{
+		Path:       string(md.Path),
+		Type:       string(md.Type),
+		Size:       md.Size,
+		MimeType:   md.MimeType,
+		Timestamp:  md.Timestamp,
+		Visibility: string(md.Visibility),
+		ETag:       md.ETag,
+		VersionID:  md.VersionID,
+	}
+}
+
+func decodeMetadata(fi *FileInfo) filesystem.Metadata {
+	return &filesystem.FileInfo{
+		Path:       filesystem.Path(fi.Path),
+		Type:       filesystem.EntryType(fi.Type),
+		Size:       fi.Size,
+		MimeType:   fi.MimeType,
+		Timestamp:  fi.Timestamp,
+		Visibility: filesystem.Visibility(fi.Visibility),
+		ETag:       fi.ETag,
+		VersionID:  fi.VersionID,
+	}
+}
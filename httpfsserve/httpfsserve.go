@@ -0,0 +1,191 @@
+// Package httpfsserve serves the files of a file system over HTTP, as a drop-in static file server over any adapter:
+// content type, length, conditional requests on Last-Modified and ETag and range requests are handled as by
+// http.ServeContent, directories are served by their index file or rendered as listings, and only public files are
+// served unless the request is allowed private ones.
+package httpfsserve
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/maurofran/filesystem"
+)
+
+// Options configures a Handler.
+type Options struct {
+	// IndexFile is the name of the file served for a directory, if it has one, e.g. "index.html".
+	IndexFile string
+	// Listing renders the entries of directories without index file as an HTML page; otherwise they are not found.
+	Listing bool
+	// ShowHidden lists and serves hidden files too.
+	ShowHidden bool
+	// Private reports whether the request may read files that are not public, if not nil. Files whose visibility
+	// is not known are not public.
+	Private func(r *http.Request) bool
+}
+
+// Handler will return an HTTP handler serving the files of fsys, the path of the request URL being the path of the
+// file. Use http.StripPrefix to serve them under a prefix.
+func Handler(fsys filesystem.Interface, opts Options) http.Handler {
+	return &handler{fsys: fsys, opts: opts}
+}
+
+type handler struct {
+	fsys filesystem.Interface
+	opts Options
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	name := path.Clean("/" + r.URL.Path)
+	p, err := filesystem.NewPath(strings.TrimPrefix(name, "/"))
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	if !h.opts.ShowHidden && hidden(p) {
+		http.NotFound(w, r)
+		return
+	}
+	if p != filesystem.RootPath {
+		md, err := h.fsys.GetMetadata(r.Context(), p)
+		if err == nil && !md.IsDir() {
+			h.serveFile(w, r, md)
+			return
+		}
+		if err != nil && !filesystem.IsFileNotFound(err) {
+			serveError(w, err)
+			return
+		}
+		if ok, err := h.fsys.DirectoryExists(r.Context(), p); err != nil || !ok {
+			serveError(w, err)
+			return
+		}
+	}
+	if p != filesystem.RootPath && !strings.HasSuffix(r.URL.Path, "/") {
+		http.Redirect(w, r, path.Base(name)+"/", http.StatusMovedPermanently)
+		return
+	}
+	h.serveDir(w, r, p)
+}
+
+// hidden will check if any segment of p is hidden.
+func hidden(p filesystem.Path) bool {
+	for _, segment := range strings.Split(string(p), "/") {
+		if strings.HasPrefix(segment, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// readable will check if the file described by md may be read by the request.
+func (h *handler) readable(r *http.Request, md filesystem.Metadata) bool {
+	if h.opts.Private != nil && h.opts.Private(r) {
+		return true
+	}
+	v := md.Visibility
+	if v == "" {
+		v, _ = h.fsys.GetVisibility(r.Context(), md.Path)
+	}
+	return v == filesystem.VisibilityPublic
+}
+
+func (h *handler) serveFile(w http.ResponseWriter, r *http.Request, md filesystem.Metadata) {
+	if !h.readable(r, md) {
+		http.NotFound(w, r)
+		return
+	}
+	mimeType := md.MimeType
+	if mimeType == "" {
+		mimeType, _ = h.fsys.GetMimeType(r.Context(), md.Path)
+	}
+	if mimeType != "" {
+		w.Header().Set("Content-Type", mimeType)
+	}
+	if md.ETag != "" {
+		w.Header().Set("ETag", `"`+md.ETag+`"`)
+	}
+	rs, err := h.fsys.ReadSeekStream(r.Context(), md.Path)
+	if err != nil {
+		serveError(w, err)
+		return
+	}
+	defer rs.Close()
+	http.ServeContent(w, r, md.Path.Base(), md.Timestamp, rs)
+}
+
+func (h *handler) serveDir(w http.ResponseWriter, r *http.Request, p filesystem.Path) {
+	if h.opts.IndexFile != "" {
+		md, err := h.fsys.GetMetadata(r.Context(), p.Join(h.opts.IndexFile))
+		if err == nil && !md.IsDir() {
+			h.serveFile(w, r, md)
+			return
+		}
+	}
+	if !h.opts.Listing {
+		http.NotFound(w, r)
+		return
+	}
+	contents, err := h.fsys.ListContents(r.Context(), p, false)
+	if err != nil {
+		serveError(w, err)
+		return
+	}
+	var entries []entry
+	for _, md := range contents {
+		if !h.opts.ShowHidden && md.IsHidden() || !md.IsDir() && !h.readable(r, md) {
+			continue
+		}
+		name := md.Path.Base()
+		if md.IsDir() {
+			name += "/"
+		}
+		entries = append(entries, entry{Name: name, Href: (&url.URL{Path: name}).String(), Info: md})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	listing.Execute(w, struct {
+		Path    string
+		Entries []entry
+	}{"/" + string(p), entries})
+}
+
+type entry struct {
+	Name string
+	Href string
+	Info filesystem.Metadata
+}
+
+var listing = template.Must(template.New("listing").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Index of {{.Path}}</title></head>
+<body>
+<h1>Index of {{.Path}}</h1>
+<table>
+<tr><th>Name</th><th>Size</th><th>Modified</th></tr>
+{{range .Entries}}<tr><td><a href="{{.Href}}">{{.Name}}</a></td><td>{{if not .Info.IsDir}}{{.Info.Size}}{{end}}</td><td>{{if not .Info.Timestamp.IsZero}}{{.Info.Timestamp.UTC.Format "2006-01-02 15:04:05"}}{{end}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// serveError will reply with the status matching err, a missing file being not found.
+func serveError(w http.ResponseWriter, err error) {
+	switch {
+	case err == nil, filesystem.IsFileNotFound(err):
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+	case filesystem.IsPathError(err):
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+	default:
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,128 @@
+package filesystem
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"strings"
+
+	"github.com/maurofran/filesystem/internal/glob"
+)
+
+// IgnoreFileName is the name of files holding ignore patterns.
+const IgnoreFileName = ".fsignore"
+
+type ignorePattern struct {
+	pattern string
+	negate  bool
+	dirOnly bool
+}
+
+// IgnoreRules is a set of gitignore-style exclusion patterns, relative to a base directory.
+//
+// Blank lines and lines starting with # are skipped, a leading ! re-includes what previous patterns excluded, a
+// trailing slash matches directories only, and patterns without a slash match at any depth. Everything under an
+// ignored directory is ignored.
+type IgnoreRules struct {
+	base     Path
+	patterns []ignorePattern
+}
+
+// NewIgnoreRules will create rules relative to base directory from provided patterns.
+func NewIgnoreRules(base Path, patterns ...string) (*IgnoreRules, error) {
+	r := &IgnoreRules{base: base}
+	for _, line := range patterns {
+		if err := r.add(line); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// ParseIgnoreRules will read rules relative to base directory from an ignore file content.
+func ParseIgnoreRules(base Path, rd io.Reader) (*IgnoreRules, error) {
+	r := &IgnoreRules{base: base}
+	scanner := bufio.NewScanner(rd)
+	for scanner.Scan() {
+		if err := r.add(scanner.Text()); err != nil {
+			return nil, err
+		}
+	}
+	return r, scanner.Err()
+}
+
+// LoadIgnoreRules will read the ignore file of provided directory, returning empty rules if there is none.
+func LoadIgnoreRules(ctx context.Context, fs Interface, dir Path) (*IgnoreRules, error) {
+	file := dir.Join(IgnoreFileName)
+	exists, err := fs.Has(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return &IgnoreRules{base: dir}, nil
+	}
+	content, err := fs.Read(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+	return ParseIgnoreRules(dir, bytes.NewReader(content))
+}
+
+func (r *IgnoreRules) add(line string) error {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil
+	}
+	p := ignorePattern{}
+	if strings.HasPrefix(line, "!") {
+		p.negate, line = true, line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly, line = true, strings.TrimRight(line, "/")
+	}
+	if !strings.Contains(line, "/") {
+		line = "**/" + line
+	}
+	if err := glob.Validate(line); err != nil {
+		return err
+	}
+	p.pattern = line
+	r.patterns = append(r.patterns, p)
+	return nil
+}
+
+// Ignored will check if the entry at provided path is ignored, either itself or through one of its directories.
+// Entries outside of base directory are never ignored.
+func (r *IgnoreRules) Ignored(path Path, isDir bool) bool {
+	if r == nil || len(r.patterns) == 0 {
+		return false
+	}
+	rel := string(path)
+	if r.base != RootPath {
+		if !strings.HasPrefix(rel, string(r.base)+"/") {
+			return false
+		}
+		rel = rel[len(r.base)+1:]
+	}
+	segments := strings.Split(rel, "/")
+	for i := 1; i < len(segments); i++ {
+		if r.match(strings.Join(segments[:i], "/"), true) {
+			return true
+		}
+	}
+	return r.match(rel, isDir)
+}
+
+// match will return the outcome of the last pattern matching rel.
+func (r *IgnoreRules) match(rel string, isDir bool) bool {
+	ignored := false
+	for _, p := range r.patterns {
+		if (!p.dirOnly || isDir) && glob.Match(p.pattern, rel) {
+			ignored = !p.negate
+		}
+	}
+	return ignored
+}
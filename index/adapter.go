@@ -0,0 +1,108 @@
+package index
+
+import (
+	"context"
+	"io"
+
+	"github.com/maurofran/filesystem"
+)
+
+// Wrap will decorate provided adapter so that every successful mutation is reflected on provided index.
+//
+// Metadata of written files is retrieved from the adapter right after the write; if that fails the file is
+// dropped from the index rather than left stale, and Rebuild can be used to resynchronize.
+func Wrap(a filesystem.Adapter, idx *Index) filesystem.Adapter {
+	return &adapter{Adapter: a, index: idx}
+}
+
+type adapter struct {
+	filesystem.Adapter
+	index *Index
+}
+
+func (a *adapter) refresh(ctx context.Context, path filesystem.Path, err error) error {
+	if err != nil {
+		return err
+	}
+	md, err := a.Adapter.GetMetadata(ctx, path)
+	if err != nil {
+		a.index.Remove(path)
+		return nil
+	}
+	if md.Path != path {
+		md = md.Clone()
+		md.Path = path
+	}
+	a.index.Put(md)
+	return nil
+}
+
+func (a *adapter) Write(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	return a.refresh(ctx, path, a.Adapter.Write(ctx, path, content, cfg))
+}
+
+func (a *adapter) WriteStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	return a.refresh(ctx, path, a.Adapter.WriteStream(ctx, path, r, cfg))
+}
+
+func (a *adapter) Update(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	return a.refresh(ctx, path, a.Adapter.Update(ctx, path, content, cfg))
+}
+
+func (a *adapter) UpdateStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	return a.refresh(ctx, path, a.Adapter.UpdateStream(ctx, path, r, cfg))
+}
+
+func (a *adapter) Put(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	return a.refresh(ctx, path, a.Adapter.Put(ctx, path, content, cfg))
+}
+
+func (a *adapter) PutStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	return a.refresh(ctx, path, a.Adapter.PutStream(ctx, path, r, cfg))
+}
+
+func (a *adapter) Delete(ctx context.Context, path filesystem.Path) error {
+	if err := a.Adapter.Delete(ctx, path); err != nil {
+		return err
+	}
+	a.index.Remove(path)
+	return nil
+}
+
+func (a *adapter) ReadAndDelete(ctx context.Context, path filesystem.Path) ([]byte, error) {
+	content, err := a.Adapter.ReadAndDelete(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	a.index.Remove(path)
+	return content, nil
+}
+
+func (a *adapter) Move(ctx context.Context, path, newpath filesystem.Path) error {
+	if err := a.Adapter.Move(ctx, path, newpath); err != nil {
+		return err
+	}
+	a.index.Remove(path)
+	return a.refresh(ctx, newpath, nil)
+}
+
+func (a *adapter) Copy(ctx context.Context, path, newpath filesystem.Path) error {
+	return a.refresh(ctx, newpath, a.Adapter.Copy(ctx, path, newpath))
+}
+
+func (a *adapter) DeleteDir(ctx context.Context, path filesystem.Path) error {
+	if err := a.Adapter.DeleteDir(ctx, path); err != nil {
+		return err
+	}
+	a.index.RemoveDir(path)
+	return nil
+}
+
+func (a *adapter) SetVisibility(ctx context.Context, path filesystem.Path, v filesystem.Visibility) error {
+	return a.refresh(ctx, path, a.Adapter.SetVisibility(ctx, path, v))
+}
+
+// Close will close the decorated adapter.
+func (a *adapter) Close() error {
+	return filesystem.Close(a.Adapter)
+}
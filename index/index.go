@@ -0,0 +1,158 @@
+package index
+
+import (
+	"context"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/maurofran/filesystem"
+)
+
+// Query is the set of conditions an indexed file must satisfy to be returned. Zero values are ignored.
+type Query struct {
+	// Prefix restricts results to files under the given directory.
+	Prefix filesystem.Path
+	// Pattern is a path.Match pattern the file path must match.
+	Pattern string
+	// MimeType is the required mime type; a trailing "/*" matches a whole family, e.g. "image/*".
+	MimeType string
+	// MinSize is the minimum file size, in bytes.
+	MinSize int64
+	// MaxSize is the maximum file size, in bytes.
+	MaxSize int64
+	// ModifiedAfter restricts results to files modified after the given time.
+	ModifiedAfter time.Time
+	// ModifiedBefore restricts results to files modified before the given time.
+	ModifiedBefore time.Time
+	// Metadata holds metadata values the file must have.
+	Metadata map[string]interface{}
+}
+
+// Index is an in-memory queryable index of file metadata, safe for concurrent use.
+type Index struct {
+	mu      sync.RWMutex
+	entries map[filesystem.Path]filesystem.Metadata
+}
+
+// New will create a new empty index.
+func New() *Index {
+	return &Index{entries: make(map[filesystem.Path]filesystem.Metadata)}
+}
+
+// Put will add or replace the metadata of a file.
+func (i *Index) Put(md filesystem.Metadata) {
+	entry := md.Clone()
+	i.mu.Lock()
+	i.entries[entry.Path] = entry
+	i.mu.Unlock()
+}
+
+// Remove will remove the file at provided path from index.
+func (i *Index) Remove(path filesystem.Path) {
+	i.mu.Lock()
+	delete(i.entries, path)
+	i.mu.Unlock()
+}
+
+// RemoveDir will remove every file under provided directory from index.
+func (i *Index) RemoveDir(dirname filesystem.Path) {
+	i.mu.Lock()
+	for p := range i.entries {
+		if isUnder(p, dirname) {
+			delete(i.entries, p)
+		}
+	}
+	i.mu.Unlock()
+}
+
+// Len is the number of indexed files.
+func (i *Index) Len() int {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return len(i.entries)
+}
+
+// Rebuild will replace the index content with a full recursive listing of provided adapter.
+func (i *Index) Rebuild(ctx context.Context, a filesystem.Adapter) error {
+	listing, err := a.ListContents(ctx, filesystem.RootPath, true)
+	if err != nil {
+		return err
+	}
+	i.mu.Lock()
+	i.entries = make(map[filesystem.Path]filesystem.Metadata, len(listing))
+	i.mu.Unlock()
+	for _, md := range listing {
+		if !md.IsDir() {
+			i.Put(md)
+		}
+	}
+	return nil
+}
+
+// Query will return the metadata of all indexed files matching provided query, sorted by path.
+func (i *Index) Query(q Query) ([]filesystem.Metadata, error) {
+	if q.Pattern != "" {
+		if _, err := path.Match(q.Pattern, ""); err != nil {
+			return nil, err
+		}
+	}
+	i.mu.RLock()
+	var result []filesystem.Metadata
+	for _, md := range i.entries {
+		if q.matches(md) {
+			result = append(result, md)
+		}
+	}
+	i.mu.RUnlock()
+	sort.Slice(result, func(a, b int) bool {
+		return result[a].Path < result[b].Path
+	})
+	return result, nil
+}
+
+func (q Query) matches(md filesystem.Metadata) bool {
+	if q.Prefix != filesystem.RootPath && !isUnder(md.Path, q.Prefix) {
+		return false
+	}
+	if q.Pattern != "" {
+		if ok, _ := path.Match(q.Pattern, string(md.Path)); !ok {
+			return false
+		}
+	}
+	if q.MimeType != "" && !matchMimeType(q.MimeType, md.MimeType) {
+		return false
+	}
+	if q.MinSize > 0 && md.Size < q.MinSize {
+		return false
+	}
+	if q.MaxSize > 0 && md.Size > q.MaxSize {
+		return false
+	}
+	if !q.ModifiedAfter.IsZero() && !md.Timestamp.After(q.ModifiedAfter) {
+		return false
+	}
+	if !q.ModifiedBefore.IsZero() && !md.Timestamp.Before(q.ModifiedBefore) {
+		return false
+	}
+	for k, v := range q.Metadata {
+		if !reflect.DeepEqual(md.Get(k), v) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchMimeType(pattern, mimeType string) bool {
+	if strings.HasSuffix(pattern, "/*") {
+		return strings.HasPrefix(mimeType, pattern[:len(pattern)-1])
+	}
+	return pattern == mimeType
+}
+
+func isUnder(p, dirname filesystem.Path) bool {
+	return dirname == filesystem.RootPath || strings.HasPrefix(string(p), string(dirname)+"/")
+}
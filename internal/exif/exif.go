@@ -0,0 +1,172 @@
+// Package exif provides minimal decoding of the EXIF metadata of JPEG images.
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// tagNames maps the tags decoded from the image, camera and GPS directories to their names.
+var tagNames = map[uint16]string{
+	0x010F: "Make",
+	0x0110: "Model",
+	0x0112: "Orientation",
+	0x0131: "Software",
+	0x0132: "DateTime",
+	0x013B: "Artist",
+	0x8298: "Copyright",
+	0x829A: "ExposureTime",
+	0x829D: "FNumber",
+	0x8827: "ISOSpeedRatings",
+	0x9003: "DateTimeOriginal",
+	0x9004: "DateTimeDigitized",
+	0x920A: "FocalLength",
+	0xA002: "PixelXDimension",
+	0xA003: "PixelYDimension",
+	0xA434: "LensModel",
+}
+
+var gpsTagNames = map[uint16]string{
+	0x0001: "GPSLatitudeRef",
+	0x0002: "GPSLatitude",
+	0x0003: "GPSLongitudeRef",
+	0x0004: "GPSLongitude",
+	0x0006: "GPSAltitude",
+}
+
+const (
+	exifPointer = 0x8769
+	gpsPointer  = 0x8825
+)
+
+// typeSizes is the size of the values of each TIFF field type, zero for unsupported ones.
+var typeSizes = [...]int{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 7: 1, 9: 4, 10: 8}
+
+var errMalformed = errors.New("exif: malformed data")
+
+// Decode will extract the EXIF tags of the JPEG image b, formatted as strings: text as is, numbers in decimal and
+// rationals as "n/d", multiple values being comma separated. It returns nil if the image holds no EXIF data.
+func Decode(b []byte) (map[string]string, error) {
+	if len(b) < 2 || b[0] != 0xFF || b[1] != 0xD8 {
+		return nil, errors.New("exif: not a JPEG image")
+	}
+	for i := 2; i+4 <= len(b) && b[i] == 0xFF; {
+		marker := b[i+1]
+		if marker == 0xDA || marker == 0xD9 {
+			break
+		}
+		n := int(binary.BigEndian.Uint16(b[i+2:]))
+		if n < 2 || i+2+n > len(b) {
+			return nil, errMalformed
+		}
+		segment := b[i+4 : i+2+n]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return decodeTIFF(segment[6:])
+		}
+		i += 2 + n
+	}
+	return nil, nil
+}
+
+func decodeTIFF(t []byte) (map[string]string, error) {
+	if len(t) < 8 {
+		return nil, errMalformed
+	}
+	var order binary.ByteOrder
+	switch string(t[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, errMalformed
+	}
+	d := decoder{t: t, order: order, tags: make(map[string]string)}
+	pointers, err := d.ifd(order.Uint32(t[4:]), tagNames)
+	if err != nil {
+		return nil, err
+	}
+	if offset, ok := pointers[exifPointer]; ok {
+		if _, err := d.ifd(offset, tagNames); err != nil {
+			return nil, err
+		}
+	}
+	if offset, ok := pointers[gpsPointer]; ok {
+		if _, err := d.ifd(offset, gpsTagNames); err != nil {
+			return nil, err
+		}
+	}
+	return d.tags, nil
+}
+
+type decoder struct {
+	t     []byte
+	order binary.ByteOrder
+	tags  map[string]string
+}
+
+// ifd will decode the directory at offset, recording the named tags and returning the pointers to sub directories.
+func (d *decoder) ifd(offset uint32, names map[uint16]string) (map[uint16]uint32, error) {
+	if int64(offset)+2 > int64(len(d.t)) {
+		return nil, errMalformed
+	}
+	count := int(d.order.Uint16(d.t[offset:]))
+	start := int(offset) + 2
+	if start+count*12 > len(d.t) {
+		return nil, errMalformed
+	}
+	pointers := make(map[uint16]uint32)
+	for i := 0; i < count; i++ {
+		entry := d.t[start+i*12 : start+i*12+12]
+		tag, typ, n := d.order.Uint16(entry), d.order.Uint16(entry[2:]), d.order.Uint32(entry[4:])
+		if tag == exifPointer || tag == gpsPointer {
+			pointers[tag] = d.order.Uint32(entry[8:])
+			continue
+		}
+		name, ok := names[tag]
+		if !ok || int(typ) >= len(typeSizes) || typeSizes[typ] == 0 {
+			continue
+		}
+		size := int64(typeSizes[typ]) * int64(n)
+		value := entry[8:12]
+		if size > 4 {
+			at := int64(d.order.Uint32(entry[8:]))
+			if at+size > int64(len(d.t)) {
+				return nil, errMalformed
+			}
+			value = d.t[at : at+size]
+		}
+		d.tags[name] = d.format(typ, value[:size])
+	}
+	return pointers, nil
+}
+
+// format will render the values of provided type as a string.
+func (d *decoder) format(typ uint16, value []byte) string {
+	if typ == 2 {
+		return strings.TrimRight(string(value), "\x00 ")
+	}
+	size := typeSizes[typ]
+	values := make([]string, 0, len(value)/size)
+	for i := 0; i+size <= len(value); i += size {
+		v := value[i : i+size]
+		switch typ {
+		case 1, 7:
+			values = append(values, fmt.Sprint(v[0]))
+		case 3:
+			values = append(values, fmt.Sprint(d.order.Uint16(v)))
+		case 4:
+			values = append(values, fmt.Sprint(d.order.Uint32(v)))
+		case 9:
+			values = append(values, fmt.Sprint(int32(d.order.Uint32(v))))
+		case 5:
+			values = append(values, fmt.Sprintf("%d/%d", d.order.Uint32(v), d.order.Uint32(v[4:])))
+		case 10:
+			values = append(values, fmt.Sprintf("%d/%d", int32(d.order.Uint32(v)), int32(d.order.Uint32(v[4:]))))
+		}
+	}
+	return strings.Join(values, ",")
+}
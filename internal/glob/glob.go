@@ -0,0 +1,69 @@
+// Package glob matches slash separated paths against patterns.
+//
+// Patterns follow path.Match syntax segment by segment, with the addition of "**" which matches zero or more
+// whole segments. Leading slashes are ignored on both patterns and paths.
+package glob
+
+import (
+	"path"
+	"strings"
+)
+
+// Validate will check that provided pattern is well formed.
+func Validate(pattern string) error {
+	for _, segment := range split(pattern) {
+		if segment == "**" {
+			continue
+		}
+		if _, err := path.Match(segment, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Match will check if provided path matches the pattern. Malformed patterns never match.
+func Match(pattern, name string) bool {
+	return match(split(pattern), split(name))
+}
+
+// Escape will quote the metacharacters of s, so that it matches itself literally within a segment.
+func Escape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`*?[]\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func match(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := len(name); i >= 0; i-- {
+				if match(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+func split(s string) []string {
+	s = strings.Trim(s, "/")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "/")
+}
@@ -0,0 +1,113 @@
+// Package imaging provides minimal image resizing on top of the standard image packages.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"strings"
+)
+
+// Fit will compute the largest dimensions fitting within width x height that keep the aspect ratio of bounds. A
+// zero width or height leaves that dimension unconstrained. Images are never enlarged.
+func Fit(bounds image.Rectangle, width, height int) (int, int) {
+	w, h := bounds.Dx(), bounds.Dy()
+	if w == 0 || h == 0 {
+		return 0, 0
+	}
+	scale := 1.0
+	if width > 0 && float64(width)/float64(w) < scale {
+		scale = float64(width) / float64(w)
+	}
+	if height > 0 && float64(height)/float64(h) < scale {
+		scale = float64(height) / float64(h)
+	}
+	fw, fh := int(float64(w)*scale+0.5), int(float64(h)*scale+0.5)
+	if fw < 1 {
+		fw = 1
+	}
+	if fh < 1 {
+		fh = 1
+	}
+	return fw, fh
+}
+
+// Resize will scale the image to width x height, averaging the source pixels covered by each target pixel.
+func Resize(src image.Image, width, height int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	b := src.Bounds()
+	for y := 0; y < height; y++ {
+		y0 := b.Min.Y + y*b.Dy()/height
+		y1 := b.Min.Y + (y+1)*b.Dy()/height
+		if y1 == y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < width; x++ {
+			x0 := b.Min.X + x*b.Dx()/width
+			x1 := b.Min.X + (x+1)*b.Dx()/width
+			if x1 == x0 {
+				x1 = x0 + 1
+			}
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, bl, a, n = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca), n+1
+				}
+			}
+			dst.SetRGBA64(x, y, color.RGBA64{uint16(r / n), uint16(g / n), uint16(bl / n), uint16(a / n)})
+		}
+	}
+	return dst
+}
+
+// Encode will write the image in provided format: "jpeg", "png" or "gif".
+func Encode(w io.Writer, img image.Image, format string) error {
+	switch strings.ToLower(format) {
+	case "jpeg", "jpg":
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: 85})
+	case "png":
+		return png.Encode(w, img)
+	case "gif":
+		return gif.Encode(w, img, nil)
+	}
+	return errors.New("imaging: unsupported format " + format)
+}
+
+// MaxPixels is the largest number of pixels of the images Decode accepts, bounding the memory needed to decode them.
+const MaxPixels = 50000000
+
+// ErrTooLarge is the error returned by Decode for images larger than MaxPixels.
+var ErrTooLarge = errors.New("imaging: image exceeds the maximum number of pixels")
+
+// Decode will decode an image, refusing the ones whose header declares more than MaxPixels pixels.
+func Decode(r io.Reader) (image.Image, string, error) {
+	var header bytes.Buffer
+	cfg, _, err := image.DecodeConfig(io.TeeReader(r, &header))
+	if err != nil {
+		return nil, "", err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || int64(cfg.Width)*int64(cfg.Height) > MaxPixels {
+		return nil, "", ErrTooLarge
+	}
+	return image.Decode(io.MultiReader(&header, r))
+}
+
+// Thumbnail will decode an image, fit it within width x height and encode it in provided format. An empty format
+// keeps the source one.
+func Thumbnail(w io.Writer, r io.Reader, width, height int, format string) error {
+	src, srcFormat, err := Decode(r)
+	if err != nil {
+		return err
+	}
+	if format == "" {
+		format = srcFormat
+	}
+	fw, fh := Fit(src.Bounds(), width, height)
+	return Encode(w, Resize(src, fw, fh), format)
+}
@@ -0,0 +1,126 @@
+package inventory
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/maurofran/filesystem"
+)
+
+// Format enumeration.
+type Format int
+
+// Format values (CSV and JSON lines).
+const (
+	FormatCSV Format = iota + 1
+	FormatJSONL
+)
+
+// Options are the options of manifest generation.
+type Options struct {
+	// Format is the manifest format, CSV if zero.
+	Format Format
+	// Checksum enables the SHA-256 checksum column, which requires reading every file.
+	Checksum bool
+}
+
+// Entry is a manifest entry.
+type Entry struct {
+	Path       filesystem.Path `json:"path"`
+	Size       int64           `json:"size"`
+	Timestamp  time.Time       `json:"timestamp"`
+	Checksum   string          `json:"checksum,omitempty"`
+	Visibility string          `json:"visibility"`
+}
+
+var csvHeader = []string{"path", "size", "timestamp", "checksum", "visibility"}
+
+// Generate will write to w the manifest of every file under prefix.
+func Generate(ctx context.Context, fs filesystem.Interface, prefix filesystem.Path, w io.Writer, opts Options) error {
+	listing, err := fs.ListContents(ctx, prefix, true)
+	if err != nil {
+		return err
+	}
+	var cw *csv.Writer
+	var jw *json.Encoder
+	if opts.Format == FormatJSONL {
+		jw = json.NewEncoder(w)
+	} else {
+		cw = csv.NewWriter(w)
+		if err := cw.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+	for _, md := range listing {
+		if md.IsDir() {
+			continue
+		}
+		entry, err := newEntry(ctx, fs, md, opts)
+		if err != nil {
+			return err
+		}
+		if jw != nil {
+			err = jw.Encode(entry)
+		} else {
+			err = cw.Write([]string{
+				string(entry.Path),
+				strconv.FormatInt(entry.Size, 10),
+				entry.Timestamp.UTC().Format(time.RFC3339),
+				entry.Checksum,
+				entry.Visibility,
+			})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if cw != nil {
+		cw.Flush()
+		return cw.Error()
+	}
+	return nil
+}
+
+// Write will store the manifest of every file under prefix at provided path of destination file system.
+func Write(ctx context.Context, fs filesystem.Interface, prefix filesystem.Path, dst filesystem.Interface, path filesystem.Path, opts Options) error {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(Generate(ctx, fs, prefix, pw, opts))
+	}()
+	err := dst.PutStream(ctx, path, pr)
+	pr.CloseWithError(err)
+	return err
+}
+
+func newEntry(ctx context.Context, fs filesystem.Interface, md filesystem.Metadata, opts Options) (Entry, error) {
+	p := md.Path
+	entry := Entry{Path: p, Size: md.Size, Timestamp: md.Timestamp}
+	var err error
+	if md.Size < 0 {
+		if entry.Size, err = fs.GetFileSize(ctx, p); err != nil {
+			return entry, err
+		}
+	}
+	if entry.Timestamp.IsZero() {
+		if entry.Timestamp, err = fs.GetTimestamp(ctx, p); err != nil {
+			return entry, err
+		}
+	}
+	v := md.Visibility
+	if v == "" {
+		if v, err = fs.GetVisibility(ctx, p); err != nil {
+			return entry, err
+		}
+	}
+	entry.Visibility = v.String()
+	if opts.Checksum {
+		if entry.Checksum, err = fs.GetChecksum(ctx, p, filesystem.ChecksumSHA256); err != nil {
+			return entry, err
+		}
+	}
+	return entry, nil
+}
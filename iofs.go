@@ -0,0 +1,230 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"sort"
+	"time"
+)
+
+// FS will expose provided file system as an io/fs file system, also implementing fs.ReadDirFS, fs.StatFS and
+// fs.ReadFileFS. Every operation uses provided context.
+func FS(ctx context.Context, fsys Interface) fs.FS {
+	return &ioFS{ctx: ctx, fsys: fsys}
+}
+
+type ioFS struct {
+	ctx  context.Context
+	fsys Interface
+}
+
+// path will convert an io/fs name to a path, the root being ".".
+func (f *ioFS) path(op, name string) (Path, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return RootPath, nil
+	}
+	return Path(name), nil
+}
+
+func ioPathError(op, name string, err error) error {
+	if IsFileNotFound(err) {
+		err = fs.ErrNotExist
+	}
+	return &fs.PathError{Op: op, Path: name, Err: err}
+}
+
+// stat will find the metadata of a file, or of a directory by looking it up in the listing of its parent.
+func (f *ioFS) stat(op, name string) (Metadata, error) {
+	p, err := f.path(op, name)
+	if err != nil {
+		return nil, err
+	}
+	if p == RootPath {
+		return &FileInfo{Path: RootPath, Type: TypeDir}, nil
+	}
+	md, err := f.fsys.GetMetadata(f.ctx, p)
+	if err == nil {
+		return md, nil
+	}
+	if !IsFileNotFound(err) {
+		return nil, ioPathError(op, name, err)
+	}
+	listing, err := f.fsys.ListContents(f.ctx, p.Dir(), false)
+	if err != nil {
+		return nil, ioPathError(op, name, err)
+	}
+	for _, md := range listing {
+		if md.Path == p {
+			return md, nil
+		}
+	}
+	return nil, ioPathError(op, name, fs.ErrNotExist)
+}
+
+func (f *ioFS) Open(name string) (fs.File, error) {
+	md, err := f.stat("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if md.IsDir() {
+		return &ioDir{fsys: f, name: name, info: fileInfo{md}}, nil
+	}
+	return &ioFile{fsys: f, info: fileInfo{md}}, nil
+}
+
+func (f *ioFS) Stat(name string) (fs.FileInfo, error) {
+	md, err := f.stat("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return fileInfo{md}, nil
+}
+
+func (f *ioFS) ReadFile(name string) ([]byte, error) {
+	p, err := f.path("readfile", name)
+	if err != nil {
+		return nil, err
+	}
+	content, err := f.fsys.Read(f.ctx, p)
+	if err != nil {
+		return nil, ioPathError("readfile", name, err)
+	}
+	return content, nil
+}
+
+func (f *ioFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	md, err := f.stat("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !md.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+	listing, err := f.fsys.ListContents(f.ctx, md.Path, false)
+	if err != nil {
+		return nil, ioPathError("readdir", name, err)
+	}
+	entries := make([]fs.DirEntry, 0, len(listing))
+	for _, md := range listing {
+		entries = append(entries, fs.FileInfoToDirEntry(fileInfo{md}))
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+// fileInfo is the fs.FileInfo of an entry, described by its metadata.
+type fileInfo struct {
+	md Metadata
+}
+
+func (i fileInfo) Name() string {
+	if i.md.Path == RootPath {
+		return "."
+	}
+	return i.md.Path.Base()
+}
+
+func (i fileInfo) Size() int64 {
+	return max(i.md.Size, 0)
+}
+
+func (i fileInfo) Mode() fs.FileMode {
+	if i.IsDir() {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+func (i fileInfo) ModTime() time.Time {
+	return i.md.Timestamp
+}
+
+func (i fileInfo) IsDir() bool {
+	return i.md.IsDir()
+}
+
+// Sys will return the metadata of the entry.
+func (i fileInfo) Sys() interface{} {
+	return i.md
+}
+
+// ioFile is an open file, whose content is streamed on first read.
+type ioFile struct {
+	fsys *ioFS
+	info fileInfo
+	rc   io.ReadCloser
+}
+
+func (f *ioFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *ioFile) Read(b []byte) (int, error) {
+	if f.rc == nil {
+		rc, err := f.fsys.fsys.ReadStream(f.fsys.ctx, f.info.md.Path)
+		if err != nil {
+			return 0, ioPathError("read", string(f.info.md.Path), err)
+		}
+		f.rc = rc
+	}
+	return f.rc.Read(b)
+}
+
+func (f *ioFile) Close() error {
+	if f.rc == nil {
+		return nil
+	}
+	return f.rc.Close()
+}
+
+// ioDir is an open directory, whose entries are listed on first read.
+type ioDir struct {
+	fsys    *ioFS
+	name    string
+	info    fileInfo
+	entries []fs.DirEntry
+	read    bool
+}
+
+func (d *ioDir) Stat() (fs.FileInfo, error) {
+	return d.info, nil
+}
+
+func (d *ioDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: errors.New("is a directory")}
+}
+
+func (d *ioDir) Close() error {
+	return nil
+}
+
+func (d *ioDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.read {
+		entries, err := d.fsys.ReadDir(d.name)
+		if err != nil {
+			return nil, err
+		}
+		d.entries, d.read = entries, true
+	}
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(d.entries) {
+		n = len(d.entries)
+	}
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
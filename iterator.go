@@ -0,0 +1,68 @@
+package filesystem
+
+import (
+	"context"
+	"io"
+)
+
+// Iterator iterates over the entries of a listing. Next returns io.EOF once every entry was returned.
+type Iterator interface {
+	Next() (Metadata, error)
+}
+
+// ContentsIterator is implemented by adapters able to list contents lazily, e.g. one page at a time.
+type ContentsIterator interface {
+	IterateContents(ctx context.Context, path Path, recursive bool) (Iterator, error)
+}
+
+// IteratorFunc is a function implementing Iterator.
+type IteratorFunc func() (Metadata, error)
+
+// Next will return the next entry.
+func (f IteratorFunc) Next() (Metadata, error) {
+	return f()
+}
+
+// SliceIterator will create an iterator over provided listing.
+func SliceIterator(listing []Metadata) Iterator {
+	return IteratorFunc(func() (Metadata, error) {
+		if len(listing) == 0 {
+			return nil, io.EOF
+		}
+		md := listing[0]
+		listing = listing[1:]
+		return md, nil
+	})
+}
+
+// IterateContents will iterate over the contents of given path, lazily if adapter supports it.
+func (fs *filesystem) IterateContents(ctx context.Context, path Path, recursive bool) (Iterator, error) {
+	path, err := NewPath(string(path))
+	if err != nil {
+		return nil, err
+	}
+	if it, ok := fs.adapter.(ContentsIterator); ok {
+		return it.IterateContents(ctx, path, recursive)
+	}
+	listing, err := fs.adapter.ListContents(ctx, path, recursive)
+	if err != nil {
+		return nil, err
+	}
+	return SliceIterator(listing), nil
+}
+
+// IterateContents will iterate over the contents of given path, listed at once for paths prefixed by AllMounts.
+func (mm *mountManager) IterateContents(ctx context.Context, path Path, recursive bool) (Iterator, error) {
+	if _, ok := allMounts(path); ok {
+		listing, err := mm.ListContents(ctx, path, recursive)
+		if err != nil {
+			return nil, err
+		}
+		return SliceIterator(listing), nil
+	}
+	mgr, subPath, err := mm.managerFor(path)
+	if err != nil {
+		return nil, err
+	}
+	return mgr.IterateContents(ctx, subPath, recursive)
+}
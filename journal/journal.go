@@ -0,0 +1,245 @@
+// Package journal provides an adapter decorator keeping a write-ahead journal of multi-step operations, so that
+// they can be completed or rolled back after a crash.
+package journal
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/maurofran/filesystem"
+)
+
+// DefaultPrefix is the journal directory used when none is provided to Wrap.
+const DefaultPrefix filesystem.Path = ".journal"
+
+// Operations recorded in the journal.
+const (
+	opMove      = "move"
+	opWrite     = "write"
+	opDeleteDir = "deleteDir"
+)
+
+// record is a journaled operation, stored as a JSON file named after the time it began.
+type record struct {
+	Op      string          `json:"op"`
+	Path    filesystem.Path `json:"path"`
+	NewPath filesystem.Path `json:"newPath,omitempty"`
+	// Temp is the temporary file content is written to by atomic writes.
+	Temp filesystem.Path `json:"temp,omitempty"`
+	// Committed reports whether the content of an atomic write is completely written to Temp.
+	Committed bool `json:"committed,omitempty"`
+}
+
+// Adapter is an adapter decorator journaling moves, atomic writes and directory deletes. Each operation is recorded
+// before it starts and the record is dropped once it returns, so that the records left by a crash tell Recover which
+// operations to complete or roll back.
+type Adapter struct {
+	filesystem.Adapter
+	prefix filesystem.Path
+}
+
+// Wrap will decorate provided adapter so that operations are journaled in the directory at prefix, or DefaultPrefix
+// if empty. The journal directory is left out of listings.
+func Wrap(a filesystem.Adapter, prefix filesystem.Path) *Adapter {
+	if prefix == filesystem.RootPath {
+		prefix = DefaultPrefix
+	}
+	return &Adapter{Adapter: a, prefix: prefix}
+}
+
+// hidden will check if path is within the journal directory.
+func (a *Adapter) hidden(path filesystem.Path) bool {
+	return path == a.prefix || strings.HasPrefix(string(path), string(a.prefix)+"/")
+}
+
+// begin will record rec in the journal, returning the path of the record.
+func (a *Adapter) begin(ctx context.Context, rec record) (filesystem.Path, error) {
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	p := a.prefix.Join(fmt.Sprintf("%020d-%s.json", time.Now().UnixNano(), hex.EncodeToString(suffix)))
+	return p, a.save(ctx, p, rec)
+}
+
+func (a *Adapter) save(ctx context.Context, p filesystem.Path, rec record) error {
+	content, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return a.Adapter.Put(ctx, p, content, *filesystem.EmptyConfig())
+}
+
+// end will drop the record at p from the journal.
+func (a *Adapter) end(ctx context.Context, p filesystem.Path) error {
+	if err := a.Adapter.Delete(ctx, p); err != nil && !filesystem.IsFileNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// Move will move the file at path to newpath, journaling the move.
+func (a *Adapter) Move(ctx context.Context, path, newpath filesystem.Path) error {
+	p, err := a.begin(ctx, record{Op: opMove, Path: path, NewPath: newpath})
+	if err != nil {
+		return err
+	}
+	if err := a.Adapter.Move(ctx, path, newpath); err != nil {
+		a.end(ctx, p)
+		return err
+	}
+	return a.end(ctx, p)
+}
+
+// DeleteDir will delete the directory at path with all of its content, journaling the delete.
+func (a *Adapter) DeleteDir(ctx context.Context, path filesystem.Path) error {
+	p, err := a.begin(ctx, record{Op: opDeleteDir, Path: path})
+	if err != nil {
+		return err
+	}
+	if err := a.Adapter.DeleteDir(ctx, path); err != nil {
+		a.end(ctx, p)
+		return err
+	}
+	return a.end(ctx, p)
+}
+
+// WriteAtomic will write the content of provided reader at supplied path atomically. Adapters writing atomically
+// on their own are delegated to; otherwise the content is written to a temporary file moved over path, journaling
+// both steps.
+func (a *Adapter) WriteAtomic(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	if w, ok := a.Adapter.(filesystem.AtomicWriter); ok {
+		err := w.WriteAtomic(ctx, path, r, cfg)
+		if !filesystem.IsUnsupportedOperationError(err) {
+			return err
+		}
+	}
+	if filesystem.CapabilitiesOf(a.Adapter).AtomicWrites {
+		return a.Adapter.WriteStream(ctx, path, r, cfg)
+	}
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	rec := record{Op: opWrite, Path: path, Temp: path.Dir().Join("." + path.Base() + ".tmp-" + hex.EncodeToString(suffix))}
+	p, err := a.begin(ctx, rec)
+	if err != nil {
+		return err
+	}
+	if err := a.Adapter.WriteStream(ctx, rec.Temp, r, cfg); err != nil {
+		a.Adapter.Delete(ctx, rec.Temp)
+		a.end(ctx, p)
+		return err
+	}
+	rec.Committed = true
+	if err := a.save(ctx, p, rec); err != nil {
+		a.Adapter.Delete(ctx, rec.Temp)
+		a.end(ctx, p)
+		return err
+	}
+	if err := a.Adapter.Move(ctx, rec.Temp, path); err != nil {
+		a.Adapter.Delete(ctx, rec.Temp)
+		a.end(ctx, p)
+		return err
+	}
+	return a.end(ctx, p)
+}
+
+// ListContents will list the contents of given path, the journal excluded.
+func (a *Adapter) ListContents(ctx context.Context, path filesystem.Path, recursive bool) ([]filesystem.Metadata, error) {
+	listing, err := a.Adapter.ListContents(ctx, path, recursive)
+	if err != nil || a.hidden(path) {
+		return listing, err
+	}
+	out := listing[:0]
+	for _, md := range listing {
+		if !a.hidden(md.Path) {
+			out = append(out, md)
+		}
+	}
+	return out, nil
+}
+
+// Recover will settle the operations interrupted by a crash, in the order they began, returning how many were
+// settled: moves whose source still exists are completed, replacing the target; directory deletes are completed;
+// atomic writes are completed if their content was completely written and rolled back otherwise. It is meant to be
+// called on startup, before any other operation.
+func (a *Adapter) Recover(ctx context.Context) (int, error) {
+	listing, err := a.Adapter.ListContents(ctx, a.prefix, false)
+	if filesystem.IsFileNotFound(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	sort.Slice(listing, func(i, j int) bool { return listing[i].Path < listing[j].Path })
+	var settled int
+	for _, md := range listing {
+		if md.IsDir() || md.Path.Ext() != ".json" {
+			continue
+		}
+		content, err := a.Adapter.Read(ctx, md.Path)
+		if err != nil {
+			return settled, err
+		}
+		var rec record
+		if err := json.Unmarshal(content, &rec); err != nil {
+			return settled, fmt.Errorf("journal: invalid record %s: %v", md.Path, err)
+		}
+		if err := a.settle(ctx, rec); err != nil {
+			return settled, err
+		}
+		if err := a.end(ctx, md.Path); err != nil {
+			return settled, err
+		}
+		settled++
+	}
+	return settled, nil
+}
+
+// settle will complete or roll back the operation of rec.
+func (a *Adapter) settle(ctx context.Context, rec record) error {
+	switch rec.Op {
+	case opMove:
+		return a.replace(ctx, rec.Path, rec.NewPath)
+	case opDeleteDir:
+		if err := a.Adapter.DeleteDir(ctx, rec.Path); err != nil && !filesystem.IsFileNotFound(err) {
+			return err
+		}
+		return nil
+	case opWrite:
+		if !rec.Committed {
+			if err := a.Adapter.Delete(ctx, rec.Temp); err != nil && !filesystem.IsFileNotFound(err) {
+				return err
+			}
+			return nil
+		}
+		return a.replace(ctx, rec.Temp, rec.Path)
+	}
+	return fmt.Errorf("journal: unknown operation %s", rec.Op)
+}
+
+// replace will move the file at path over newpath, if path still exists.
+func (a *Adapter) replace(ctx context.Context, path, newpath filesystem.Path) error {
+	exists, err := a.Adapter.Has(ctx, path)
+	if err != nil || !exists {
+		return err
+	}
+	// Backends refusing to overwrite on move need the target removed first; path still holds the content.
+	if err := a.Adapter.Delete(ctx, newpath); err != nil && !filesystem.IsFileNotFound(err) {
+		return err
+	}
+	return a.Adapter.Move(ctx, path, newpath)
+}
+
+// Close will close the decorated adapter.
+func (a *Adapter) Close() error {
+	return filesystem.Close(a.Adapter)
+}
@@ -0,0 +1,179 @@
+package lifecycle
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/maurofran/filesystem"
+	"github.com/maurofran/filesystem/internal/glob"
+)
+
+// Action enumeration.
+type Action int
+
+// Action values (delete, move and compress).
+const (
+	ActionDelete Action = iota + 1
+	ActionMove
+	ActionCompress
+)
+
+var actions = [...]string{"Delete", "Move", "Compress"}
+
+func (a Action) String() string {
+	return actions[a-1]
+}
+
+// Rule is a lifecycle rule applied to files matching a pattern once they reach a given age.
+type Rule struct {
+	// Pattern is the glob the file path must match; "**" spans directories.
+	Pattern string
+	// Age is the time elapsed since last modification after which the action is applied.
+	Age time.Duration
+	// Action is the action to apply.
+	Action Action
+	// Destination is the directory files are moved under by ActionMove.
+	Destination filesystem.Path
+}
+
+func (r Rule) appliesTo(md filesystem.Metadata, now time.Time) bool {
+	p := md.Path
+	if !glob.Match(r.Pattern, string(p)) || now.Sub(md.Timestamp) < r.Age {
+		return false
+	}
+	switch r.Action {
+	case ActionMove:
+		return !strings.HasPrefix(string(p), string(r.Destination)+"/")
+	case ActionCompress:
+		return !strings.HasSuffix(string(p), ".gz")
+	}
+	return true
+}
+
+// Result is the outcome of applying a rule to a file.
+type Result struct {
+	Path filesystem.Path
+	Rule Rule
+	Err  error
+}
+
+// Manager evaluates lifecycle rules against a file system.
+type Manager struct {
+	fs    filesystem.Interface
+	rules []Rule
+	// Now is the clock used to compute file ages.
+	Now func() time.Time
+}
+
+// New will create a new manager applying provided rules to file system.
+func New(fs filesystem.Interface, rules ...Rule) (*Manager, error) {
+	for _, r := range rules {
+		if err := glob.Validate(r.Pattern); err != nil {
+			return nil, err
+		}
+	}
+	return &Manager{fs: fs, rules: rules, Now: time.Now}, nil
+}
+
+// Run will apply the rules once. They are evaluated against a recursive listing, applying to each file the
+// satisfied rule with the greatest age.
+func (m *Manager) Run(ctx context.Context) ([]Result, error) {
+	rules := m.rules
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	listing, err := m.fs.ListContents(ctx, filesystem.RootPath, true)
+	if err != nil {
+		return nil, err
+	}
+	now := m.Now()
+	var results []Result
+	for _, md := range listing {
+		if md.IsDir() {
+			continue
+		}
+		if md.Timestamp.IsZero() {
+			ts, err := m.fs.GetTimestamp(ctx, md.Path)
+			if err != nil {
+				results = append(results, Result{Path: md.Path, Err: err})
+				continue
+			}
+			md = &filesystem.FileInfo{Path: md.Path, Type: filesystem.TypeFile, Size: md.Size, Timestamp: ts}
+		}
+		var selected *Rule
+		for i, r := range rules {
+			if r.appliesTo(md, now) && (selected == nil || r.Age > selected.Age) {
+				selected = &rules[i]
+			}
+		}
+		if selected != nil {
+			results = append(results, Result{md.Path, *selected, m.apply(ctx, *selected, md.Path)})
+		}
+	}
+	return results, nil
+}
+
+func (m *Manager) apply(ctx context.Context, r Rule, path filesystem.Path) error {
+	switch r.Action {
+	case ActionMove:
+		return m.fs.Move(ctx, path, r.Destination.Join(string(path)))
+	case ActionCompress:
+		return m.compress(ctx, path)
+	}
+	_, err := m.fs.Delete(ctx, path)
+	return err
+}
+
+func (m *Manager) compress(ctx context.Context, path filesystem.Path) error {
+	src, err := m.fs.ReadStream(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	pr, pw := io.Pipe()
+	go func() {
+		zw := gzip.NewWriter(pw)
+		_, err := io.Copy(zw, src)
+		if err == nil {
+			err = zw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	if err := m.fs.WriteStream(ctx, path+".gz", pr); err != nil {
+		pr.CloseWithError(err)
+		return err
+	}
+	_, err = m.fs.Delete(ctx, path)
+	return err
+}
+
+// Start will run the rules every interval in background until the returned stop function is called or provided
+// context is done, handing the outcome of each run to provided callback.
+func (m *Manager) Start(ctx context.Context, interval time.Duration, callback func([]Result, error)) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	var once sync.Once
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				callback(m.Run(ctx))
+			case <-done:
+				return
+			case <-ctx.Done():
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	return func() {
+		once.Do(func() {
+			ticker.Stop()
+			close(done)
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package filesystem
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// ListOptions are the options of a listing.
+type ListOptions struct {
+	// Recursive enables listing of nested directories.
+	Recursive bool
+	// ExcludeHidden leaves out hidden entries, and everything under hidden directories.
+	ExcludeHidden bool
+	// Ignore leaves out the entries matched by ignore rules.
+	Ignore *IgnoreRules
+	// Cursor is the cursor of the page to list, as returned with the previous page, or empty for the first page.
+	// Only ListPage honors it.
+	Cursor string
+	// Limit is the maximum number of entries of a page, DefaultPageLimit if not positive. Only ListPage honors it.
+	Limit int
+	// Sort is the order of the entries, the one of the adapter if unsorted.
+	Sort Sort
+	// SortBuffer is the maximum number of entries held in memory while sorting a page, DefaultSortBuffer if not
+	// positive. Only ListPage honors it.
+	SortBuffer int
+}
+
+// List will list the contents of given path of file system, applying provided options.
+func List(ctx context.Context, fs Interface, path Path, opts ListOptions) ([]Metadata, error) {
+	listing, err := fs.ListContents(ctx, path, opts.Recursive)
+	if err == nil && opts.Sort.Field != Unsorted {
+		sort.Slice(listing, func(i, j int) bool { return opts.Sort.Less(listing[i], listing[j]) })
+	}
+	if err != nil || !opts.filters() {
+		return listing, err
+	}
+	f := newListFilter(path, opts)
+	result := make([]Metadata, 0, len(listing))
+	for _, md := range listing {
+		if f.keep(md) {
+			result = append(result, md)
+		}
+	}
+	return result, nil
+}
+
+// filters will check if options leave entries out of listings.
+func (opts ListOptions) filters() bool {
+	return opts.ExcludeHidden || opts.Ignore != nil
+}
+
+// listFilter leaves out of the listing of a directory the entries excluded by the options.
+type listFilter struct {
+	path       Path
+	opts       ListOptions
+	hiddenDirs map[Path]bool
+}
+
+func newListFilter(path Path, opts ListOptions) *listFilter {
+	return &listFilter{path: path, opts: opts, hiddenDirs: make(map[Path]bool)}
+}
+
+// keep will check if md is part of the listing. Entries must be checked in listing order, directories before
+// their content.
+func (f *listFilter) keep(md Metadata) bool {
+	if f.opts.Ignore.Ignored(md.Path, md.IsDir()) {
+		return false
+	}
+	if f.opts.ExcludeHidden &&
+		(md.IsHidden() || hasHiddenAncestor(f.path, md.Path) || underAny(md.Path, f.path, f.hiddenDirs)) {
+		if md.IsDir() {
+			f.hiddenDirs[md.Path] = true
+		}
+		return false
+	}
+	return true
+}
+
+// hasHiddenAncestor will check if any directory of p below dirname is named with a leading dot.
+func hasHiddenAncestor(dirname, p Path) bool {
+	rel := strings.TrimPrefix(string(p.Dir()), string(dirname))
+	for _, segment := range strings.Split(rel, "/") {
+		if strings.HasPrefix(segment, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// underAny will check if p is below one of provided directories, walking its ancestors up to dirname.
+func underAny(p, dirname Path, dirs map[Path]bool) bool {
+	if len(dirs) == 0 {
+		return false
+	}
+	for dir := p.Dir(); dir != dirname && dir != RootPath; dir = dir.Dir() {
+		if dirs[dir] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,265 @@
+package filesystem
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// lockPollInterval is the interval between attempts of locks waiting for conflicting locks to be released.
+const lockPollInterval = 100 * time.Millisecond
+
+// LockOptions are the options of a lock.
+type LockOptions struct {
+	// Shared requests a shared lock, which may be held along with other shared locks, instead of an exclusive one.
+	Shared bool
+	// TTL is the time after which a lock that was not refreshed expires, so that locks of crashed processes are
+	// eventually released. Zero means that the lock never expires.
+	TTL time.Duration
+	// Wait makes locking wait for conflicting locks to be released, until the context is done, instead of failing
+	// with a LockedError.
+	Wait bool
+}
+
+// Lock is an advisory lock held on a file: it coordinates the writers taking locks, without preventing others from
+// writing the file.
+type Lock interface {
+	// Path is the path of the locked file.
+	Path() Path
+	// Refresh will extend the lock by its TTL, failing with a LockedError if it expired meanwhile.
+	Refresh(ctx context.Context) error
+	// Unlock will release the lock.
+	Unlock(ctx context.Context) error
+}
+
+// Locker is implemented by adapters with a dedicated mechanism to lock files. Decorators may return an
+// UnsupportedOperationError when the adapter they decorate is not a Locker, so that locking falls back as
+// AcquireLock describes.
+type Locker interface {
+	Lock(ctx context.Context, path Path, opts LockOptions) (Lock, error)
+}
+
+// AcquireLock will lock the file at supplied path through adapter, failing with a LockedError if a conflicting lock
+// is held, unless options ask to wait for it. Locker adapters lock natively; otherwise the lock is a hidden lock
+// object next to path, updated with conditional writes, so the adapter must be a ConditionalWriter providing ETags.
+// Expiry of lock objects relies on the clocks of the lock holders. It is meant for decorators; use the method of
+// Interface otherwise.
+func AcquireLock(ctx context.Context, a Adapter, path Path, opts LockOptions) (Lock, error) {
+	if l, ok := a.(Locker); ok {
+		lock, err := l.Lock(ctx, path, opts)
+		if !IsUnsupportedOperationError(err) {
+			return lock, err
+		}
+	}
+	w, ok := a.(ConditionalWriter)
+	if !ok {
+		return nil, NewUnsupportedOperationError(path, "Lock")
+	}
+	id, err := randomID()
+	if err != nil {
+		return nil, err
+	}
+	lock := &objectLock{
+		adapter: a,
+		writer:  w,
+		path:    path,
+		object:  path.Dir().Join("." + path.Base() + ".lock"),
+		id:      id,
+		opts:    opts,
+	}
+	for {
+		err := lock.acquire(ctx)
+		if err == nil {
+			return lock, nil
+		}
+		if !IsLockedError(err) || !opts.Wait {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// WithLock will call fn holding a lock on the file at supplied path, releasing it once fn returns.
+func WithLock(ctx context.Context, fs Interface, path Path, opts LockOptions, fn func(ctx context.Context) error) error {
+	lock, err := fs.Lock(ctx, path, opts)
+	if err != nil {
+		return err
+	}
+	err = fn(ctx)
+	if uerr := lock.Unlock(ctx); err == nil {
+		err = uerr
+	}
+	return err
+}
+
+// randomID will return a random hexadecimal identifier.
+func randomID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// isRandomID will check if provided string has the format of the identifiers returned by randomID.
+func isRandomID(id string) bool {
+	if len(id) != 16 {
+		return false
+	}
+	for _, c := range id {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// lockRecord is the content of a lock object.
+type lockRecord struct {
+	Exclusive bool `json:"exclusive"`
+	// Holders are the expiry times of the locks held, by holder, the zero time never expiring.
+	Holders map[string]time.Time `json:"holders"`
+}
+
+// objectLock is a lock kept in a lock object.
+type objectLock struct {
+	adapter Adapter
+	writer  ConditionalWriter
+	path    Path
+	object  Path
+	id      string
+	opts    LockOptions
+}
+
+func (l *objectLock) Path() Path {
+	return l.path
+}
+
+func (l *objectLock) acquire(ctx context.Context) error {
+	return l.update(ctx, func(rec *lockRecord) error {
+		if len(rec.Holders) > 0 && (rec.Exclusive || !l.opts.Shared) {
+			return NewLockedError(l.path)
+		}
+		rec.Exclusive = !l.opts.Shared
+		rec.Holders[l.id] = l.expiry()
+		return nil
+	})
+}
+
+func (l *objectLock) Refresh(ctx context.Context) error {
+	return l.update(ctx, func(rec *lockRecord) error {
+		if _, ok := rec.Holders[l.id]; !ok {
+			return NewLockedError(l.path)
+		}
+		rec.Holders[l.id] = l.expiry()
+		return nil
+	})
+}
+
+func (l *objectLock) Unlock(ctx context.Context) error {
+	return l.update(ctx, func(rec *lockRecord) error {
+		delete(rec.Holders, l.id)
+		return nil
+	})
+}
+
+func (l *objectLock) expiry() time.Time {
+	if l.opts.TTL <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(l.opts.TTL)
+}
+
+// update will apply fn to the lock record without its expired locks, and store it, deleting the lock object once no
+// lock is held. It starts over whenever the lock object changed meanwhile.
+func (l *objectLock) update(ctx context.Context, fn func(rec *lockRecord) error) error {
+	for {
+		rec, etag, err := l.read(ctx)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		for id, exp := range rec.Holders {
+			if !exp.IsZero() && !exp.After(now) {
+				delete(rec.Holders, id)
+			}
+		}
+		if err := fn(&rec); err != nil {
+			return err
+		}
+		cond := IfNotExists()
+		if etag != "" {
+			cond = IfMatch(etag)
+		}
+		if len(rec.Holders) == 0 {
+			if etag == "" {
+				return nil
+			}
+			err = l.writer.DeleteIf(ctx, l.object, cond)
+		} else {
+			var content []byte
+			content, err = json.Marshal(rec)
+			if err != nil {
+				return err
+			}
+			err = l.writer.WriteIf(ctx, l.object, content, cond, *EmptyConfig())
+		}
+		if !IsPreconditionFailed(err) {
+			return err
+		}
+	}
+}
+
+// read will read the lock record and the ETag of the lock object, empty if there is none. The ETag is read first,
+// so that a record changed meanwhile fails the precondition of the next update.
+func (l *objectLock) read(ctx context.Context) (lockRecord, string, error) {
+	rec := lockRecord{Holders: make(map[string]time.Time)}
+	md, err := l.adapter.GetMetadata(ctx, l.object)
+	if IsFileNotFound(err) {
+		return rec, "", nil
+	}
+	if err != nil {
+		return rec, "", err
+	}
+	if md.ETag == "" {
+		return rec, "", NewUnsupportedOperationError(l.path, "Lock")
+	}
+	content, err := l.adapter.Read(ctx, l.object)
+	if IsFileNotFound(err) {
+		return rec, "", nil
+	}
+	if err != nil {
+		return rec, "", err
+	}
+	if err := json.Unmarshal(content, &rec); err != nil {
+		return rec, "", err
+	}
+	if rec.Holders == nil {
+		rec.Holders = make(map[string]time.Time)
+	}
+	return rec, md.ETag, nil
+}
+
+// Lock will acquire an advisory lock on the file at supplied path, the file not needing to exist.
+func (fs *filesystem) Lock(ctx context.Context, path Path, opts LockOptions) (Lock, error) {
+	path, err := NewPath(string(path))
+	if err != nil {
+		return nil, err
+	}
+	return AcquireLock(ctx, fs.adapter, path, opts)
+}
+
+// Lock will acquire an advisory lock on the file at supplied path.
+func (mm *mountManager) Lock(ctx context.Context, path Path, opts LockOptions) (Lock, error) {
+	mgr, subPath, err := mm.managerFor(path)
+	if err != nil {
+		return nil, err
+	}
+	return mgr.Lock(ctx, subPath, opts)
+}
@@ -1,75 +1,392 @@
 package filesystem
 
 import (
+	"context"
 	"io"
 	"time"
+
+	"github.com/maurofran/filesystem/mimedetect"
 )
 
 // Read is the interface exposed for file system reading
 type Read interface {
 	// Has will check if a file exists.
-	Has(path Path) (bool, error)
+	Has(ctx context.Context, path Path) (bool, error)
+	// FileExists will check if a file exists at supplied path, directories not counting.
+	FileExists(ctx context.Context, path Path) (bool, error)
+	// DirectoryExists will check if a directory exists at supplied path.
+	DirectoryExists(ctx context.Context, path Path) (bool, error)
 	// Read the file at provided path.
-	Read(path Path) (string, error)
+	Read(ctx context.Context, path Path) ([]byte, error)
 	// ReadStream will read the file at provided path as a stream.
-	ReadStream(path Path) (io.ReadCloser, error)
+	ReadStream(ctx context.Context, path Path) (io.ReadCloser, error)
+	// ReadSeekStream will read the file at provided path as a seekable stream.
+	ReadSeekStream(ctx context.Context, path Path) (io.ReadSeekCloser, error)
+	// ReaderAt will return a reader of the file at provided path at random offsets, using range reads.
+	ReaderAt(ctx context.Context, path Path) (SizedReaderAt, error)
+	// ReadRange will read length bytes of the file at provided path starting at offset, or up to the end of file
+	// if length is negative.
+	ReadRange(ctx context.Context, path Path, offset, length int64) (io.ReadCloser, error)
 	// GetMimeType will retrieve the mime type of file at supplied path.
-	GetMimeType(path Path) (string, error)
+	GetMimeType(ctx context.Context, path Path) (string, error)
 	// GetTimestamp will retrieve the timestamp of file at supplied path.
-	GetTimestamp(path Path) (time.Time, error)
+	GetTimestamp(ctx context.Context, path Path) (time.Time, error)
 	// GetFileSize will retrieve the size of file at supplied path.
-	GetFileSize(path Path) (int64, error)
+	GetFileSize(ctx context.Context, path Path) (int64, error)
 	// GetMetadata will retrieve the metadata of file at supplied path.
-	GetMetadata(path Path) (Metadata, error)
+	GetMetadata(ctx context.Context, path Path) (Metadata, error)
 	// Get the visibility of file at supplied path.
-	GetVisibility(path Path) (Visibility, error)
+	GetVisibility(ctx context.Context, path Path) (Visibility, error)
+	// GetUserMetadata will retrieve the user defined key/value metadata of file at supplied path.
+	GetUserMetadata(ctx context.Context, path Path) (map[string]string, error)
+	// GetChecksum will retrieve the checksum of file at supplied path, computed with provided algorithm.
+	GetChecksum(ctx context.Context, path Path, algo ChecksumAlgo) (string, error)
+	// GetDirectoryMetadata will count the files and subdirectories of the directory at supplied path, and sum the
+	// sizes of its files.
+	GetDirectoryMetadata(ctx context.Context, path Path) (DirectoryInfo, error)
 	// List the contents of given path.
-	ListContents(path Path, recursive bool) ([]Metadata, error)
+	ListContents(ctx context.Context, path Path, recursive bool) ([]Metadata, error)
+	// IterateContents will iterate over the contents of given path, without loading the whole listing when
+	// adapter supports it.
+	IterateContents(ctx context.Context, path Path, recursive bool) (Iterator, error)
+	// ListPage will list a page of the contents of given path, starting at the cursor of provided options.
+	ListPage(ctx context.Context, path Path, opts ListOptions) (Page, error)
+	// ListVersions will list the versions of file at supplied path, newest first.
+	ListVersions(ctx context.Context, path Path) ([]Metadata, error)
+	// ReadVersion will read provided version of file at supplied path as a stream.
+	ReadVersion(ctx context.Context, path Path, versionID string) (io.ReadCloser, error)
+	// Watch will watch the files in the directory at supplied path, and in its subdirectories if recursive, sending
+	// their changes until cancelled.
+	Watch(ctx context.Context, path Path, recursive bool) (<-chan Event, context.CancelFunc, error)
 }
 
 // Write is the interface exposed for file system writing.
 type Write interface {
 	// Write the supplied content at supplied path, creating the file.
-	Write(path Path, content string) error
+	Write(ctx context.Context, path Path, content []byte, opts ...WriteOption) error
+	// WriteIf will write the supplied content at supplied path if the precondition holds.
+	WriteIf(ctx context.Context, path Path, content []byte, cond Precondition, opts ...WriteOption) error
 	// WriteStream will write the content of provided reader at supplied path, creating the file.
-	WriteStream(path Path, r io.Reader) error
+	WriteStream(ctx context.Context, path Path, r io.Reader, opts ...WriteOption) error
+	// WriteAtomic will write the content of provided reader at supplied path, creating the file, so that readers
+	// never observe it partially written.
+	WriteAtomic(ctx context.Context, path Path, r io.Reader, opts ...WriteOption) error
 	// Deletes a file at provided path.
-	Delete(path Path) (bool, error)
+	Delete(ctx context.Context, path Path) (bool, error)
+	// DeleteIf will delete the file at supplied path if the precondition holds.
+	DeleteIf(ctx context.Context, path Path, cond Precondition) error
 	// ReadAndDelete will read the file at provided path and delete after read.
-	ReadAndDelete(path Path) (string, error)
+	ReadAndDelete(ctx context.Context, path Path) ([]byte, error)
 	// Move the file at supplied path to new path.
-	Move(path, newpath Path) error
+	Move(ctx context.Context, path, newpath Path) error
 	// Copy the file at supplied path to new path.
-	Copy(path, newpath Path) error
+	Copy(ctx context.Context, path, newpath Path) error
+	// CopyDir will copy the directory at supplied path, with all of its content, to new path.
+	CopyDir(ctx context.Context, path, newpath Path) error
+	// MoveDir will move the directory at supplied path, with all of its content, to new path.
+	MoveDir(ctx context.Context, path, newpath Path) error
 	// CreateDir will create a new directory at provided path.
-	CreateDir(path Path) error
+	CreateDir(ctx context.Context, path Path, opts ...DirOption) error
 	// DeleteDir will delete the directory at provided path.
-	DeleteDir(path Path) error
+	DeleteDir(ctx context.Context, path Path) error
 	// Set the visibility of file at supplied path.
-	SetVisibility(path Path, v Visibility) error
+	SetVisibility(ctx context.Context, path Path, v Visibility) error
+	// SetUserMetadata will replace the user defined key/value metadata of file at supplied path.
+	SetUserMetadata(ctx context.Context, path Path, metadata map[string]string) error
+	// RestoreVersion will make provided version the current content of file at supplied path.
+	RestoreVersion(ctx context.Context, path Path, versionID string) error
+	// Lock will acquire an advisory lock on the file at supplied path, to coordinate writers.
+	Lock(ctx context.Context, path Path, opts LockOptions) (Lock, error)
+	// BeginTransaction will begin a transaction staging writes and deletes, to apply them all at once on commit.
+	BeginTransaction() Transaction
+	// Open will open the file at provided path with flag, a combination of the os.O_* flags, as a File handle.
+	Open(ctx context.Context, path Path, flag int, opts ...WriteOption) (File, error)
+	// WriterAt will return a writer of the file at provided path at random offsets, if the adapter writes in place.
+	WriterAt(ctx context.Context, path Path) (io.WriterAt, error)
+	// BeginUpload will begin a resumable upload session of the file at supplied path, written on commit.
+	BeginUpload(ctx context.Context, path Path, opts ...WriteOption) (UploadSession, error)
+	// ResumeUpload will resume the upload session with provided state, e.g. after a process restart.
+	ResumeUpload(ctx context.Context, state UploadState, opts ...WriteOption) (UploadSession, error)
 }
 
 // Update is the interface exposed for file system update.
 type Update interface {
 	// Update the supplied content at supplied path, returning an error if file does not exists.
-	Update(path Path, content string) error
+	Update(ctx context.Context, path Path, content []byte, opts ...WriteOption) error
 	// Update with the content of supplied reader at supplied path, returning an error if file does not exists
-	UpdateStream(path Path, r io.Reader) error
+	UpdateStream(ctx context.Context, path Path, r io.Reader, opts ...WriteOption) error
 	// Put the supplied content at supplied path, creating the file if does not exists.
-	Put(path Path, content string) error
+	Put(ctx context.Context, path Path, content []byte, opts ...WriteOption) error
 	// Puth the content of supplied reader at supplied path, creating the file if does not exists.
-	PutStream(path Path, r io.Reader) error
+	PutStream(ctx context.Context, path Path, r io.Reader, opts ...WriteOption) error
 }
 
-// Interface is interface exposed by file system objects.
+// Interface is interface exposed by file system objects. Every method accepts a context, handed down to the adapter.
 type Interface interface {
 	Read
 	Write
 	Update
+	// Invoke will invoke the plugin handling method with provided arguments.
+	Invoke(ctx context.Context, method string, args ...interface{}) (interface{}, error)
 }
 
 type filesystem struct {
 	Configurable
 	Pluggable
-	adapter Adapter
+	adapter  Adapter
+	dirInfos dirInfoCache
+}
+
+// New will create a new file system on top of provided adapter, using provided options as defaults of every write.
+//
+// Paths are normalized before being handed to the adapter. Closing the returned file system closes the adapter, if
+// it implements io.Closer.
+func New(adapter Adapter, opts ...NewOption) Interface {
+	fs := &filesystem{adapter: adapter}
+	fs.plugins = make(map[string]Plugin)
+	cfg := EmptyConfig()
+	for _, o := range opts {
+		o.applyNew(cfg)
+	}
+	fs.SetConfig(cfg)
+	return fs
+}
+
+// Close will release the resources held by adapter.
+func (fs *filesystem) Close() error {
+	return Close(fs.adapter)
+}
+
+// Has will check if a file exists.
+func (fs *filesystem) Has(ctx context.Context, path Path) (bool, error) {
+	path, err := NewPath(string(path))
+	if err != nil {
+		return false, err
+	}
+	return fs.adapter.Has(ctx, path)
+}
+
+// Read the file at provided path.
+func (fs *filesystem) Read(ctx context.Context, path Path) ([]byte, error) {
+	path, err := NewPath(string(path))
+	if err != nil {
+		return nil, err
+	}
+	return fs.adapter.Read(ctx, path)
+}
+
+// ReadStream will read the file at provided path as a stream.
+func (fs *filesystem) ReadStream(ctx context.Context, path Path) (io.ReadCloser, error) {
+	path, err := NewPath(string(path))
+	if err != nil {
+		return nil, err
+	}
+	rc, err := fs.adapter.ReadStream(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return fs.trackRead(ctx, path, rc), nil
+}
+
+// Write the supplied content at supplied path, creating the file.
+func (fs *filesystem) Write(ctx context.Context, path Path, content []byte, opts ...WriteOption) error {
+	path, err := NewPath(string(path))
+	if err != nil {
+		return err
+	}
+	return fs.adapter.Write(ctx, path, content, fs.writeConfig(opts))
+}
+
+// WriteStream will write the content of provided reader at supplied path, creating the file.
+func (fs *filesystem) WriteStream(ctx context.Context, path Path, r io.Reader, opts ...WriteOption) error {
+	path, err := NewPath(string(path))
+	if err != nil {
+		return err
+	}
+	return fs.adapter.WriteStream(ctx, path, trackWrite(ctx, "WriteStream", path, r), fs.writeConfig(opts))
+}
+
+// Update the supplied content at supplied path, returning an error if file does not exists.
+func (fs *filesystem) Update(ctx context.Context, path Path, content []byte, opts ...WriteOption) error {
+	path, err := NewPath(string(path))
+	if err != nil {
+		return err
+	}
+	return fs.adapter.Update(ctx, path, content, fs.writeConfig(opts))
+}
+
+// Update with the content of supplied reader at supplied path, returning an error if file does not exists
+func (fs *filesystem) UpdateStream(ctx context.Context, path Path, r io.Reader, opts ...WriteOption) error {
+	path, err := NewPath(string(path))
+	if err != nil {
+		return err
+	}
+	return fs.adapter.UpdateStream(ctx, path, trackWrite(ctx, "UpdateStream", path, r), fs.writeConfig(opts))
+}
+
+// Put the supplied content at supplied path, creating the file if does not exists.
+func (fs *filesystem) Put(ctx context.Context, path Path, content []byte, opts ...WriteOption) error {
+	path, err := NewPath(string(path))
+	if err != nil {
+		return err
+	}
+	return fs.adapter.Put(ctx, path, content, fs.writeConfig(opts))
+}
+
+// Puth the content of supplied reader at supplied path, creating the file if does not exists.
+func (fs *filesystem) PutStream(ctx context.Context, path Path, r io.Reader, opts ...WriteOption) error {
+	path, err := NewPath(string(path))
+	if err != nil {
+		return err
+	}
+	return fs.adapter.PutStream(ctx, path, trackWrite(ctx, "PutStream", path, r), fs.writeConfig(opts))
+}
+
+// Deletes a file at provided path.
+func (fs *filesystem) Delete(ctx context.Context, path Path) (bool, error) {
+	path, err := NewPath(string(path))
+	if err != nil {
+		return false, err
+	}
+	if err := fs.adapter.Delete(ctx, path); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ReadAndDelete will read the file at provided path and delete after read.
+func (fs *filesystem) ReadAndDelete(ctx context.Context, path Path) ([]byte, error) {
+	path, err := NewPath(string(path))
+	if err != nil {
+		return nil, err
+	}
+	return fs.adapter.ReadAndDelete(ctx, path)
+}
+
+// Move the file at supplied path to new path.
+func (fs *filesystem) Move(ctx context.Context, path, newpath Path) error {
+	path, err := NewPath(string(path))
+	if err != nil {
+		return err
+	}
+	newpath, err = NewPath(string(newpath))
+	if err != nil {
+		return err
+	}
+	return fs.adapter.Move(ctx, path, newpath)
+}
+
+// Copy the file at supplied path to new path.
+func (fs *filesystem) Copy(ctx context.Context, path, newpath Path) error {
+	path, err := NewPath(string(path))
+	if err != nil {
+		return err
+	}
+	newpath, err = NewPath(string(newpath))
+	if err != nil {
+		return err
+	}
+	if err := fs.adapter.Copy(ctx, path, newpath); err != nil {
+		return err
+	}
+	fs.trackCopy(ctx, path, newpath)
+	return nil
+}
+
+// GetMimeType will retrieve the mime type of file at supplied path, as reported by adapter or, when adapter does
+// not know it or sniffing is forced with WithMimeSniffing, detected from its content.
+func (fs *filesystem) GetMimeType(ctx context.Context, path Path) (string, error) {
+	path, err := NewPath(string(path))
+	if err != nil {
+		return "", err
+	}
+	if sniff, _ := fs.Config().Get(ConfigSniffMimeType, false).(bool); !sniff {
+		mt, err := fs.adapter.GetMimeType(ctx, path)
+		if err != nil || mt != "" {
+			return mt, err
+		}
+	}
+	return fs.sniffMimeType(ctx, path)
+}
+
+// sniffMimeType will detect the mime type of file at supplied path from its leading bytes.
+func (fs *filesystem) sniffMimeType(ctx context.Context, path Path) (string, error) {
+	rc, err := fs.ReadRange(ctx, path, 0, mimedetect.SniffLen)
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	return mimedetect.DetectReader(path.Base(), rc)
+}
+
+// GetTimestamp will retrieve the timestamp of file at supplied path.
+func (fs *filesystem) GetTimestamp(ctx context.Context, path Path) (time.Time, error) {
+	path, err := NewPath(string(path))
+	if err != nil {
+		return time.Time{}, err
+	}
+	return fs.adapter.GetTimestamp(ctx, path)
+}
+
+// GetFileSize will retrieve the size of file at supplied path.
+func (fs *filesystem) GetFileSize(ctx context.Context, path Path) (int64, error) {
+	path, err := NewPath(string(path))
+	if err != nil {
+		return 0, err
+	}
+	return fs.adapter.GetFileSize(ctx, path)
+}
+
+// GetMetadata will retrieve the metadata of file at supplied path.
+func (fs *filesystem) GetMetadata(ctx context.Context, path Path) (Metadata, error) {
+	path, err := NewPath(string(path))
+	if err != nil {
+		return nil, err
+	}
+	return fs.adapter.GetMetadata(ctx, path)
+}
+
+// CreateDir will create a new directory at provided path.
+func (fs *filesystem) CreateDir(ctx context.Context, path Path, opts ...DirOption) error {
+	path, err := NewPath(string(path))
+	if err != nil {
+		return err
+	}
+	return fs.adapter.CreateDir(ctx, path, fs.dirConfig(opts))
+}
+
+// DeleteDir will delete the directory at provided path.
+func (fs *filesystem) DeleteDir(ctx context.Context, path Path) error {
+	path, err := NewPath(string(path))
+	if err != nil {
+		return err
+	}
+	return fs.adapter.DeleteDir(ctx, path)
+}
+
+// Get the visibility of file at supplied path.
+func (fs *filesystem) GetVisibility(ctx context.Context, path Path) (Visibility, error) {
+	path, err := NewPath(string(path))
+	if err != nil {
+		return "", err
+	}
+	return fs.adapter.GetVisibility(ctx, path)
+}
+
+// Set the visibility of file at supplied path.
+func (fs *filesystem) SetVisibility(ctx context.Context, path Path, v Visibility) error {
+	path, err := NewPath(string(path))
+	if err != nil {
+		return err
+	}
+	return fs.adapter.SetVisibility(ctx, path, v)
+}
+
+// List the contents of given path.
+func (fs *filesystem) ListContents(ctx context.Context, path Path, recursive bool) ([]Metadata, error) {
+	path, err := NewPath(string(path))
+	if err != nil {
+		return nil, err
+	}
+	return fs.adapter.ListContents(ctx, path, recursive)
 }
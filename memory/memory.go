@@ -0,0 +1,551 @@
+// Package memory provides an adapter keeping files in memory, meant for tests and ephemeral storage.
+package memory
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/maurofran/filesystem"
+)
+
+type file struct {
+	content    []byte
+	timestamp  time.Time
+	mimeType   string
+	visibility filesystem.Visibility
+	metadata   map[string]string
+	etag       string
+}
+
+// Adapter is an adapter storing files in memory, safe for concurrent use. Directories exist either because they
+// were created explicitly or because they contain files.
+type Adapter struct {
+	mu    sync.RWMutex
+	files map[filesystem.Path]*file
+	dirs  map[filesystem.Path]bool
+	// generation is the number of writes, each written file being tagged with it.
+	generation uint64
+	// Now is the clock used for file timestamps, time.Now if nil.
+	Now func() time.Time
+}
+
+func init() {
+	filesystem.RegisterAdapter("memory", func(context.Context, map[string]interface{}) (filesystem.Adapter, error) {
+		return New(), nil
+	})
+}
+
+// New will create a new empty memory adapter.
+func New() *Adapter {
+	return &Adapter{files: make(map[filesystem.Path]*file), dirs: make(map[filesystem.Path]bool)}
+}
+
+func (a *Adapter) now() time.Time {
+	if a.Now != nil {
+		return a.Now()
+	}
+	return time.Now()
+}
+
+// store will save content at path, creating the parent directories. Caller must hold the write lock.
+func (a *Adapter) store(p filesystem.Path, content []byte, cfg filesystem.Config) {
+	a.generation++
+	f := &file{
+		content:    content,
+		timestamp:  a.now(),
+		visibility: filesystem.VisibilityPublic,
+		etag:       strconv.FormatUint(a.generation, 10),
+	}
+	if old, ok := a.files[p]; ok {
+		f.visibility = old.visibility
+	}
+	if v, ok := cfg.Get(filesystem.ConfigVisibility, nil).(filesystem.Visibility); ok {
+		f.visibility = v
+	}
+	f.mimeType = detectMimeType(p, content, cfg)
+	if md, ok := cfg.Get(filesystem.ConfigMetadata, nil).(map[string]string); ok {
+		f.metadata = copyMetadata(md)
+	}
+	a.files[p] = f
+	for dir := p.Dir(); dir != filesystem.RootPath; dir = dir.Dir() {
+		a.dirs[dir] = true
+	}
+}
+
+// detectMimeType will find the mime type of content from the config, the file extension or the content itself.
+func detectMimeType(p filesystem.Path, content []byte, cfg filesystem.Config) string {
+	if mt, ok := cfg.Get(filesystem.ConfigMimeType, "").(string); ok && mt != "" {
+		return mt
+	}
+	if mt := mime.TypeByExtension(path.Ext(string(p))); mt != "" {
+		return mt
+	}
+	return http.DetectContentType(content)
+}
+
+// copyMetadata will copy user metadata, so that callers cannot alter the stored one.
+func copyMetadata(md map[string]string) map[string]string {
+	if md == nil {
+		return nil
+	}
+	cp := make(map[string]string, len(md))
+	for k, v := range md {
+		cp[k] = v
+	}
+	return cp
+}
+
+func (a *Adapter) get(p filesystem.Path) (*file, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	f, ok := a.files[p]
+	if !ok {
+		return nil, filesystem.NewFileNotFoundError(p)
+	}
+	return f, nil
+}
+
+func metadata(p filesystem.Path, f *file) filesystem.Metadata {
+	return &filesystem.FileInfo{
+		Path:       p,
+		Type:       filesystem.TypeFile,
+		Size:       int64(len(f.content)),
+		Timestamp:  f.timestamp,
+		MimeType:   f.mimeType,
+		Visibility: f.visibility,
+		ETag:       f.etag,
+	}
+}
+
+// Has will check if a file exists.
+func (a *Adapter) Has(ctx context.Context, path filesystem.Path) (bool, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	_, ok := a.files[path]
+	return ok, nil
+}
+
+// FileExists will check if a file exists at supplied path.
+func (a *Adapter) FileExists(ctx context.Context, path filesystem.Path) (bool, error) {
+	return a.Has(ctx, path)
+}
+
+// DirectoryExists will check if a directory exists at supplied path.
+func (a *Adapter) DirectoryExists(ctx context.Context, path filesystem.Path) (bool, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return path == filesystem.RootPath || a.dirs[path], nil
+}
+
+// Read the file at provided path.
+func (a *Adapter) Read(ctx context.Context, path filesystem.Path) ([]byte, error) {
+	f, err := a.get(path)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.Clone(f.content), nil
+}
+
+// ReadStream will read the file at provided path as a stream.
+func (a *Adapter) ReadStream(ctx context.Context, path filesystem.Path) (io.ReadCloser, error) {
+	f, err := a.get(path)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(f.content)), nil
+}
+
+// ReadRange will read length bytes of the file at provided path starting at offset.
+func (a *Adapter) ReadRange(ctx context.Context, path filesystem.Path, offset, length int64) (io.ReadCloser, error) {
+	f, err := a.get(path)
+	if err != nil {
+		return nil, err
+	}
+	content := f.content[min(offset, int64(len(f.content))):]
+	if length >= 0 && length < int64(len(content)) {
+		content = content[:length]
+	}
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+// Write the supplied content at supplied path, creating the file.
+func (a *Adapter) Write(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.store(path, bytes.Clone(content), cfg)
+	return nil
+}
+
+// WriteStream will write the content of provided reader at supplied path, creating the file.
+func (a *Adapter) WriteStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return a.Write(ctx, path, b, cfg)
+}
+
+// Update the supplied content at supplied path, returning an error if file does not exists.
+func (a *Adapter) Update(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, ok := a.files[path]; !ok {
+		return filesystem.NewFileNotFoundError(path)
+	}
+	a.store(path, bytes.Clone(content), cfg)
+	return nil
+}
+
+// Update with the content of supplied reader at supplied path, returning an error if file does not exists
+func (a *Adapter) UpdateStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return a.Update(ctx, path, b, cfg)
+}
+
+// Put the supplied content at supplied path, creating the file if does not exists.
+func (a *Adapter) Put(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	return a.Write(ctx, path, content, cfg)
+}
+
+// Puth the content of supplied reader at supplied path, creating the file if does not exists.
+func (a *Adapter) PutStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	return a.WriteStream(ctx, path, r, cfg)
+}
+
+// Deletes a file at provided path.
+func (a *Adapter) Delete(ctx context.Context, path filesystem.Path) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, ok := a.files[path]; !ok {
+		return filesystem.NewFileNotFoundError(path)
+	}
+	delete(a.files, path)
+	return nil
+}
+
+// WriteIf will write the supplied content at supplied path if the precondition holds for the current file.
+func (a *Adapter) WriteIf(ctx context.Context, path filesystem.Path, content []byte, cond filesystem.Precondition, cfg filesystem.Config) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.holds(path, cond) {
+		return filesystem.NewPreconditionFailedError(path)
+	}
+	a.store(path, bytes.Clone(content), cfg)
+	return nil
+}
+
+// DeleteIf will delete the file at provided path if the precondition holds for it.
+func (a *Adapter) DeleteIf(ctx context.Context, path filesystem.Path, cond filesystem.Precondition) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, ok := a.files[path]; !ok {
+		return filesystem.NewFileNotFoundError(path)
+	}
+	if !a.holds(path, cond) {
+		return filesystem.NewPreconditionFailedError(path)
+	}
+	delete(a.files, path)
+	return nil
+}
+
+// holds will check cond against the file at path. Caller must hold the lock.
+func (a *Adapter) holds(path filesystem.Path, cond filesystem.Precondition) bool {
+	f, ok := a.files[path]
+	if !ok {
+		return cond.Holds("", false)
+	}
+	return cond.Holds(f.etag, true)
+}
+
+// ReadAndDelete will read the file at provided path and delete after read.
+func (a *Adapter) ReadAndDelete(ctx context.Context, path filesystem.Path) ([]byte, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	f, ok := a.files[path]
+	if !ok {
+		return nil, filesystem.NewFileNotFoundError(path)
+	}
+	delete(a.files, path)
+	return f.content, nil
+}
+
+// Move the file at supplied path to new path.
+func (a *Adapter) Move(ctx context.Context, path, newpath filesystem.Path) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	f, ok := a.files[path]
+	if !ok {
+		return filesystem.NewFileNotFoundError(path)
+	}
+	delete(a.files, path)
+	a.files[newpath] = f
+	for dir := newpath.Dir(); dir != filesystem.RootPath; dir = dir.Dir() {
+		a.dirs[dir] = true
+	}
+	return nil
+}
+
+// Copy the file at supplied path to new path.
+func (a *Adapter) Copy(ctx context.Context, path, newpath filesystem.Path) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	f, ok := a.files[path]
+	if !ok {
+		return filesystem.NewFileNotFoundError(path)
+	}
+	cp := *f
+	cp.timestamp = a.now()
+	a.files[newpath] = &cp
+	for dir := newpath.Dir(); dir != filesystem.RootPath; dir = dir.Dir() {
+		a.dirs[dir] = true
+	}
+	return nil
+}
+
+// GetMimeType will retrieve the mime type of file at supplied path.
+func (a *Adapter) GetMimeType(ctx context.Context, path filesystem.Path) (string, error) {
+	f, err := a.get(path)
+	if err != nil {
+		return "", err
+	}
+	return f.mimeType, nil
+}
+
+// GetTimestamp will retrieve the timestamp of file at supplied path.
+func (a *Adapter) GetTimestamp(ctx context.Context, path filesystem.Path) (time.Time, error) {
+	f, err := a.get(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return f.timestamp, nil
+}
+
+// GetFileSize will retrieve the size of file at supplied path.
+func (a *Adapter) GetFileSize(ctx context.Context, path filesystem.Path) (int64, error) {
+	f, err := a.get(path)
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(f.content)), nil
+}
+
+// GetMetadata will retrieve the metadata of file at supplied path.
+func (a *Adapter) GetMetadata(ctx context.Context, path filesystem.Path) (filesystem.Metadata, error) {
+	f, err := a.get(path)
+	if err != nil {
+		return nil, err
+	}
+	return metadata(path, f), nil
+}
+
+// CreateDir will create a new directory at provided path.
+func (a *Adapter) CreateDir(ctx context.Context, path filesystem.Path, cfg filesystem.Config) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for dir := path; dir != filesystem.RootPath; dir = dir.Dir() {
+		a.dirs[dir] = true
+	}
+	return nil
+}
+
+// DeleteDir will delete the directory at provided path, with all of its content.
+func (a *Adapter) DeleteDir(ctx context.Context, path filesystem.Path) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for p := range a.files {
+		if under(p, path) {
+			delete(a.files, p)
+		}
+	}
+	for p := range a.dirs {
+		if p == path || under(p, path) {
+			delete(a.dirs, p)
+		}
+	}
+	return nil
+}
+
+// GetVisibility will retrieve the visibility of file at supplied path.
+func (a *Adapter) GetVisibility(ctx context.Context, path filesystem.Path) (filesystem.Visibility, error) {
+	f, err := a.get(path)
+	if err != nil {
+		return "", err
+	}
+	return f.visibility, nil
+}
+
+// SetVisibility will set the visibility of file at supplied path.
+func (a *Adapter) SetVisibility(ctx context.Context, path filesystem.Path, v filesystem.Visibility) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	f, ok := a.files[path]
+	if !ok {
+		return filesystem.NewFileNotFoundError(path)
+	}
+	f.visibility = v
+	return nil
+}
+
+// GetDirectoryMetadata will describe the directory at supplied path.
+func (a *Adapter) GetDirectoryMetadata(ctx context.Context, path filesystem.Path) (filesystem.DirectoryInfo, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if path != filesystem.RootPath && !a.dirs[path] {
+		return filesystem.DirectoryInfo{}, filesystem.NewFileNotFoundError(path)
+	}
+	info := filesystem.DirectoryInfo{Path: path}
+	for p, f := range a.files {
+		if under(p, path) {
+			info.Files++
+			info.Size += int64(len(f.content))
+		}
+	}
+	for p := range a.dirs {
+		if under(p, path) {
+			info.Dirs++
+		}
+	}
+	return info, nil
+}
+
+// GetUserMetadata will retrieve the user metadata of file at supplied path.
+func (a *Adapter) GetUserMetadata(ctx context.Context, path filesystem.Path) (map[string]string, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	f, ok := a.files[path]
+	if !ok {
+		return nil, filesystem.NewFileNotFoundError(path)
+	}
+	md := copyMetadata(f.metadata)
+	if md == nil {
+		md = map[string]string{}
+	}
+	return md, nil
+}
+
+// SetUserMetadata will replace the user metadata of file at supplied path.
+func (a *Adapter) SetUserMetadata(ctx context.Context, path filesystem.Path, metadata map[string]string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	f, ok := a.files[path]
+	if !ok {
+		return filesystem.NewFileNotFoundError(path)
+	}
+	f.metadata = copyMetadata(metadata)
+	return nil
+}
+
+// ListContents will list the contents of given path, sorted by path.
+func (a *Adapter) ListContents(ctx context.Context, path filesystem.Path, recursive bool) ([]filesystem.Metadata, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	var listing []filesystem.Metadata
+	for p, f := range a.files {
+		if under(p, path) && (recursive || p.Dir() == path) {
+			listing = append(listing, metadata(p, f))
+		}
+	}
+	for p := range a.dirs {
+		if under(p, path) && (recursive || p.Dir() == path) {
+			listing = append(listing, &filesystem.FileInfo{Path: p, Type: filesystem.TypeDir})
+		}
+	}
+	sort.Slice(listing, func(i, j int) bool {
+		return listing[i].Path < listing[j].Path
+	})
+	return listing, nil
+}
+
+// under will check if p is strictly under dir.
+func under(p, dir filesystem.Path) bool {
+	if dir == filesystem.RootPath {
+		return p != filesystem.RootPath
+	}
+	return strings.HasPrefix(string(p), string(dir)+"/")
+}
+
+// IterateSorted will iterate over the contents of given path in provided order.
+func (a *Adapter) IterateSorted(ctx context.Context, path filesystem.Path, recursive bool, s filesystem.Sort) (filesystem.Iterator, error) {
+	listing, err := a.ListContents(ctx, path, recursive)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(listing, func(i, j int) bool { return s.Less(listing[i], listing[j]) })
+	return filesystem.SliceIterator(listing), nil
+}
+
+// MoveDir will move the directory at provided path, with all of its content, to new path in a single step.
+func (a *Adapter) MoveDir(ctx context.Context, path, newpath filesystem.Path) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	rebase := func(p filesystem.Path) filesystem.Path {
+		return newpath.Join(strings.TrimPrefix(string(p), string(path)))
+	}
+	for p, f := range a.files {
+		if under(p, path) {
+			delete(a.files, p)
+			a.files[rebase(p)] = f
+		}
+	}
+	for p := range a.dirs {
+		if p == path || under(p, path) {
+			delete(a.dirs, p)
+			a.dirs[rebase(p)] = true
+		}
+	}
+	for dir := newpath; dir != filesystem.RootPath; dir = dir.Dir() {
+		a.dirs[dir] = true
+	}
+	return nil
+}
+
+// WriteAt will write p at offset of the file at provided path, growing it as needed and creating it if missing.
+func (a *Adapter) WriteAt(ctx context.Context, path filesystem.Path, p []byte, offset int64) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	old, ok := a.files[path]
+	if !ok {
+		content := make([]byte, offset+int64(len(p)))
+		copy(content[offset:], p)
+		a.store(path, content, *filesystem.EmptyConfig())
+		return nil
+	}
+	// Readers may hold the current content, so it is copied rather than changed in place.
+	content := make([]byte, max(int64(len(old.content)), offset+int64(len(p))))
+	copy(content, old.content)
+	copy(content[offset:], p)
+	a.generation++
+	f := *old
+	f.content = content
+	f.timestamp = a.now()
+	f.etag = strconv.FormatUint(a.generation, 10)
+	a.files[path] = &f
+	return nil
+}
+
+// Capabilities will report the capabilities of the adapter, directories existing on their own.
+func (a *Adapter) Capabilities() filesystem.Capabilities {
+	return filesystem.Capabilities{
+		NativeCopy:        true,
+		NativeMove:        true,
+		DirectoryMetadata: true,
+		RangeReads:        true,
+		NativeDirMove:     true,
+		UserMetadata:      true,
+		ConditionalWrites: true,
+		AtomicWrites:      true,
+		RandomWrites:      true,
+	}
+}
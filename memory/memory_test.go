@@ -0,0 +1,15 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/maurofran/filesystem"
+	"github.com/maurofran/filesystem/adaptertest"
+	"github.com/maurofran/filesystem/memory"
+)
+
+func TestAdapter(t *testing.T) {
+	adaptertest.Run(t, func(t *testing.T) filesystem.Adapter {
+		return memory.New()
+	})
+}
@@ -1,4 +1,82 @@
 package filesystem
 
+import (
+	"strings"
+	"time"
+)
+
+// EntryType is the type of an entry, file or directory.
+type EntryType string
+
+// Entry types of FileInfo.
+const (
+	TypeFile EntryType = "file"
+	TypeDir  EntryType = "dir"
+)
+
+// FileInfo describes a file or a directory.
+type FileInfo struct {
+	// Path is the path of the entry.
+	Path Path
+	// Type is the entry type, TypeFile or TypeDir. Every entry returned by adapters has it set.
+	Type EntryType
+	// Size is the size of the file, negative if not known.
+	Size int64
+	// MimeType is the mime type of the file, or an empty string if not known.
+	MimeType string
+	// Timestamp is the last modification time of the entry, or the zero time if not known.
+	Timestamp time.Time
+	// Visibility is the visibility of the entry, or empty if not known.
+	Visibility Visibility
+	// ETag is the entity tag of the file, or an empty string if the adapter does not provide one.
+	ETag string
+	// VersionID is the version of the file on adapters keeping versions, such as S3 with versioning enabled, or an
+	// empty string.
+	VersionID string
+	// Content is the content of the file, or nil if adapter did not provide it along with metadata.
+	Content []byte
+	// Extra holds adapter specific metadata, such as "hidden" or "id".
+	Extra map[string]interface{}
+}
+
 // Metadata is the type used to provide metadata about files.
-type Metadata map[string]interface{}
+type Metadata = *FileInfo
+
+// IsDir will check if the entry is a directory.
+func (fi *FileInfo) IsDir() bool {
+	return fi.Type == TypeDir
+}
+
+// IsHidden will check if the entry is hidden, either flagged so by adapter with a "hidden" extra or named with a
+// leading dot.
+func (fi *FileInfo) IsHidden() bool {
+	if hidden, ok := fi.Get("hidden").(bool); ok {
+		return hidden
+	}
+	return strings.HasPrefix(fi.Path.Base(), ".")
+}
+
+// Get will return the extra metadata with provided key, or nil.
+func (fi *FileInfo) Get(key string) interface{} {
+	return fi.Extra[key]
+}
+
+// Set will set the extra metadata with provided key.
+func (fi *FileInfo) Set(key string, value interface{}) {
+	if fi.Extra == nil {
+		fi.Extra = make(map[string]interface{})
+	}
+	fi.Extra[key] = value
+}
+
+// Clone will return a copy of the entry, with its own extra metadata.
+func (fi *FileInfo) Clone() *FileInfo {
+	c := *fi
+	if fi.Extra != nil {
+		c.Extra = make(map[string]interface{}, len(fi.Extra))
+		for k, v := range fi.Extra {
+			c.Extra[k] = v
+		}
+	}
+	return &c
+}
@@ -0,0 +1,501 @@
+package filesystem
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// Operation describes an operation performed on a file system, as seen by middlewares.
+type Operation struct {
+	// Name is the name of the Interface method, e.g. "Write".
+	Name string
+	// Path is the path the operation is performed on.
+	Path Path
+	// NewPath is the destination of Move, Copy, MoveDir and CopyDir, empty for the other operations.
+	NewPath Path
+	// Bytes is the number of bytes read or written, set once the operation completed; -1 when unknown, e.g. for
+	// read streams, which are consumed after the operation returned.
+	Bytes int64
+	// Config holds the options given to writes, without the defaults of the file system; nil for the other
+	// operations.
+	Config *Config
+}
+
+// readOperations are the names of the operations leaving the file system unchanged.
+var readOperations = map[string]bool{
+	"Has": true, "FileExists": true, "DirectoryExists": true, "Read": true, "ReadStream": true, "ReadSeekStream": true,
+	"ReaderAt": true, "ReadRange": true, "GetMimeType": true, "GetTimestamp": true, "GetFileSize": true,
+	"GetMetadata": true, "GetVisibility": true, "GetUserMetadata": true, "GetChecksum": true,
+	"GetDirectoryMetadata": true, "ListContents": true, "IterateContents": true, "ListPage": true,
+	"ListVersions": true, "ReadVersion": true, "Watch": true,
+}
+
+// IsRead will check if the operation leaves the file system unchanged.
+func (op *Operation) IsRead() bool {
+	return readOperations[op.Name]
+}
+
+// Handler performs an operation.
+type Handler func(ctx context.Context, op *Operation) error
+
+// Middleware wraps the handler performing each operation, e.g. to log it, audit it or measure it. Middlewares may
+// replace the context handed to next, which is propagated down to the adapter.
+type Middleware func(next Handler) Handler
+
+// Observe will create a middleware calling fn after each operation, with its duration and error.
+func Observe(fn func(ctx context.Context, op Operation, d time.Duration, err error)) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, op *Operation) error {
+			start := time.Now()
+			err := next(ctx, op)
+			fn(ctx, *op, time.Since(start), err)
+			return err
+		}
+	}
+}
+
+// WithMiddleware will decorate provided file system so that every operation goes through provided middlewares,
+// the first one being the outermost. Closing the returned file system closes the decorated one.
+func WithMiddleware(fs Interface, mw ...Middleware) Interface {
+	return &middlewareFS{Interface: fs, mw: mw}
+}
+
+type middlewareFS struct {
+	Interface
+	mw []Middleware
+}
+
+// run will perform op with fn through the middlewares.
+func (m *middlewareFS) run(ctx context.Context, op *Operation, fn Handler) error {
+	h := fn
+	for i := len(m.mw) - 1; i >= 0; i-- {
+		h = m.mw[i](h)
+	}
+	return h(ctx, op)
+}
+
+func newOperation(name string, path Path) *Operation {
+	return &Operation{Name: name, Path: path, Bytes: -1}
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n *int64
+}
+
+func (c countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	*c.n += int64(n)
+	return n, err
+}
+
+// optionsConfig will build the configuration holding provided write options alone.
+func optionsConfig(opts []WriteOption) *Config {
+	cfg := EmptyConfig()
+	for _, o := range opts {
+		o.applyWrite(cfg)
+	}
+	return cfg
+}
+
+func (m *middlewareFS) write(ctx context.Context, name string, path Path, content []byte, opts []WriteOption, fn func(ctx context.Context) error) error {
+	op := newOperation(name, path)
+	op.Config = optionsConfig(opts)
+	return m.run(ctx, op, func(ctx context.Context, op *Operation) error {
+		err := fn(ctx)
+		if err == nil {
+			op.Bytes = int64(len(content))
+		}
+		return err
+	})
+}
+
+func (m *middlewareFS) writeStream(ctx context.Context, name string, path Path, r io.Reader, opts []WriteOption, fn func(ctx context.Context, r io.Reader) error) error {
+	op := newOperation(name, path)
+	op.Config = optionsConfig(opts)
+	return m.run(ctx, op, func(ctx context.Context, op *Operation) error {
+		op.Bytes = 0
+		return fn(ctx, countingReader{r, &op.Bytes})
+	})
+}
+
+// Close will close the decorated file system.
+func (m *middlewareFS) Close() error {
+	return Close(m.Interface)
+}
+
+func (m *middlewareFS) Has(ctx context.Context, path Path) (ok bool, err error) {
+	err = m.run(ctx, newOperation("Has", path), func(ctx context.Context, op *Operation) error {
+		ok, err = m.Interface.Has(ctx, path)
+		return err
+	})
+	return ok, err
+}
+
+func (m *middlewareFS) FileExists(ctx context.Context, path Path) (ok bool, err error) {
+	err = m.run(ctx, newOperation("FileExists", path), func(ctx context.Context, op *Operation) error {
+		ok, err = m.Interface.FileExists(ctx, path)
+		return err
+	})
+	return ok, err
+}
+
+func (m *middlewareFS) DirectoryExists(ctx context.Context, path Path) (ok bool, err error) {
+	err = m.run(ctx, newOperation("DirectoryExists", path), func(ctx context.Context, op *Operation) error {
+		ok, err = m.Interface.DirectoryExists(ctx, path)
+		return err
+	})
+	return ok, err
+}
+
+func (m *middlewareFS) Read(ctx context.Context, path Path) (content []byte, err error) {
+	err = m.run(ctx, newOperation("Read", path), func(ctx context.Context, op *Operation) error {
+		content, err = m.Interface.Read(ctx, path)
+		op.Bytes = int64(len(content))
+		return err
+	})
+	return content, err
+}
+
+func (m *middlewareFS) ReadStream(ctx context.Context, path Path) (rc io.ReadCloser, err error) {
+	err = m.run(ctx, newOperation("ReadStream", path), func(ctx context.Context, op *Operation) error {
+		rc, err = m.Interface.ReadStream(ctx, path)
+		return err
+	})
+	return rc, err
+}
+
+func (m *middlewareFS) ReadRange(ctx context.Context, path Path, offset, length int64) (rc io.ReadCloser, err error) {
+	err = m.run(ctx, newOperation("ReadRange", path), func(ctx context.Context, op *Operation) error {
+		rc, err = m.Interface.ReadRange(ctx, path, offset, length)
+		return err
+	})
+	return rc, err
+}
+
+func (m *middlewareFS) ReadSeekStream(ctx context.Context, path Path) (rsc io.ReadSeekCloser, err error) {
+	err = m.run(ctx, newOperation("ReadSeekStream", path), func(ctx context.Context, op *Operation) error {
+		rsc, err = m.Interface.ReadSeekStream(ctx, path)
+		return err
+	})
+	return rsc, err
+}
+
+func (m *middlewareFS) ReaderAt(ctx context.Context, path Path) (ra SizedReaderAt, err error) {
+	err = m.run(ctx, newOperation("ReaderAt", path), func(ctx context.Context, op *Operation) error {
+		ra, err = m.Interface.ReaderAt(ctx, path)
+		return err
+	})
+	return ra, err
+}
+
+func (m *middlewareFS) GetMimeType(ctx context.Context, path Path) (mt string, err error) {
+	err = m.run(ctx, newOperation("GetMimeType", path), func(ctx context.Context, op *Operation) error {
+		mt, err = m.Interface.GetMimeType(ctx, path)
+		return err
+	})
+	return mt, err
+}
+
+func (m *middlewareFS) GetTimestamp(ctx context.Context, path Path) (t time.Time, err error) {
+	err = m.run(ctx, newOperation("GetTimestamp", path), func(ctx context.Context, op *Operation) error {
+		t, err = m.Interface.GetTimestamp(ctx, path)
+		return err
+	})
+	return t, err
+}
+
+func (m *middlewareFS) GetFileSize(ctx context.Context, path Path) (size int64, err error) {
+	err = m.run(ctx, newOperation("GetFileSize", path), func(ctx context.Context, op *Operation) error {
+		size, err = m.Interface.GetFileSize(ctx, path)
+		return err
+	})
+	return size, err
+}
+
+func (m *middlewareFS) GetMetadata(ctx context.Context, path Path) (md Metadata, err error) {
+	err = m.run(ctx, newOperation("GetMetadata", path), func(ctx context.Context, op *Operation) error {
+		md, err = m.Interface.GetMetadata(ctx, path)
+		return err
+	})
+	return md, err
+}
+
+func (m *middlewareFS) GetVisibility(ctx context.Context, path Path) (v Visibility, err error) {
+	err = m.run(ctx, newOperation("GetVisibility", path), func(ctx context.Context, op *Operation) error {
+		v, err = m.Interface.GetVisibility(ctx, path)
+		return err
+	})
+	return v, err
+}
+
+func (m *middlewareFS) GetUserMetadata(ctx context.Context, path Path) (md map[string]string, err error) {
+	err = m.run(ctx, newOperation("GetUserMetadata", path), func(ctx context.Context, op *Operation) error {
+		md, err = m.Interface.GetUserMetadata(ctx, path)
+		return err
+	})
+	return md, err
+}
+
+func (m *middlewareFS) GetDirectoryMetadata(ctx context.Context, path Path) (info DirectoryInfo, err error) {
+	err = m.run(ctx, newOperation("GetDirectoryMetadata", path), func(ctx context.Context, op *Operation) error {
+		info, err = m.Interface.GetDirectoryMetadata(ctx, path)
+		return err
+	})
+	return info, err
+}
+
+func (m *middlewareFS) GetChecksum(ctx context.Context, path Path, algo ChecksumAlgo) (sum string, err error) {
+	err = m.run(ctx, newOperation("GetChecksum", path), func(ctx context.Context, op *Operation) error {
+		sum, err = m.Interface.GetChecksum(ctx, path, algo)
+		return err
+	})
+	return sum, err
+}
+
+func (m *middlewareFS) ListContents(ctx context.Context, path Path, recursive bool) (listing []Metadata, err error) {
+	err = m.run(ctx, newOperation("ListContents", path), func(ctx context.Context, op *Operation) error {
+		listing, err = m.Interface.ListContents(ctx, path, recursive)
+		return err
+	})
+	return listing, err
+}
+
+func (m *middlewareFS) ListVersions(ctx context.Context, path Path) (versions []Metadata, err error) {
+	err = m.run(ctx, newOperation("ListVersions", path), func(ctx context.Context, op *Operation) error {
+		versions, err = m.Interface.ListVersions(ctx, path)
+		return err
+	})
+	return versions, err
+}
+
+func (m *middlewareFS) ReadVersion(ctx context.Context, path Path, versionID string) (rc io.ReadCloser, err error) {
+	err = m.run(ctx, newOperation("ReadVersion", path), func(ctx context.Context, op *Operation) error {
+		rc, err = m.Interface.ReadVersion(ctx, path, versionID)
+		return err
+	})
+	return rc, err
+}
+
+func (m *middlewareFS) IterateContents(ctx context.Context, path Path, recursive bool) (it Iterator, err error) {
+	err = m.run(ctx, newOperation("IterateContents", path), func(ctx context.Context, op *Operation) error {
+		it, err = m.Interface.IterateContents(ctx, path, recursive)
+		return err
+	})
+	return it, err
+}
+
+func (m *middlewareFS) ListPage(ctx context.Context, path Path, opts ListOptions) (page Page, err error) {
+	err = m.run(ctx, newOperation("ListPage", path), func(ctx context.Context, op *Operation) error {
+		page, err = m.Interface.ListPage(ctx, path, opts)
+		return err
+	})
+	return page, err
+}
+
+func (m *middlewareFS) Write(ctx context.Context, path Path, content []byte, opts ...WriteOption) error {
+	return m.write(ctx, "Write", path, content, opts, func(ctx context.Context) error {
+		return m.Interface.Write(ctx, path, content, opts...)
+	})
+}
+
+func (m *middlewareFS) WriteIf(ctx context.Context, path Path, content []byte, cond Precondition, opts ...WriteOption) error {
+	return m.write(ctx, "WriteIf", path, content, opts, func(ctx context.Context) error {
+		return m.Interface.WriteIf(ctx, path, content, cond, opts...)
+	})
+}
+
+func (m *middlewareFS) WriteStream(ctx context.Context, path Path, r io.Reader, opts ...WriteOption) error {
+	return m.writeStream(ctx, "WriteStream", path, r, opts, func(ctx context.Context, r io.Reader) error {
+		return m.Interface.WriteStream(ctx, path, r, opts...)
+	})
+}
+
+func (m *middlewareFS) WriteAtomic(ctx context.Context, path Path, r io.Reader, opts ...WriteOption) error {
+	return m.writeStream(ctx, "WriteAtomic", path, r, opts, func(ctx context.Context, r io.Reader) error {
+		return m.Interface.WriteAtomic(ctx, path, r, opts...)
+	})
+}
+
+// Open will open the file at provided path, its operations going through the middlewares.
+func (m *middlewareFS) Open(ctx context.Context, path Path, flag int, opts ...WriteOption) (File, error) {
+	return Open(ctx, m, path, flag, opts...)
+}
+
+func (m *middlewareFS) WriterAt(ctx context.Context, path Path) (wa io.WriterAt, err error) {
+	err = m.run(ctx, newOperation("WriterAt", path), func(ctx context.Context, op *Operation) error {
+		wa, err = m.Interface.WriterAt(ctx, path)
+		return err
+	})
+	return wa, err
+}
+
+func (m *middlewareFS) Update(ctx context.Context, path Path, content []byte, opts ...WriteOption) error {
+	return m.write(ctx, "Update", path, content, opts, func(ctx context.Context) error {
+		return m.Interface.Update(ctx, path, content, opts...)
+	})
+}
+
+func (m *middlewareFS) UpdateStream(ctx context.Context, path Path, r io.Reader, opts ...WriteOption) error {
+	return m.writeStream(ctx, "UpdateStream", path, r, opts, func(ctx context.Context, r io.Reader) error {
+		return m.Interface.UpdateStream(ctx, path, r, opts...)
+	})
+}
+
+func (m *middlewareFS) Put(ctx context.Context, path Path, content []byte, opts ...WriteOption) error {
+	return m.write(ctx, "Put", path, content, opts, func(ctx context.Context) error {
+		return m.Interface.Put(ctx, path, content, opts...)
+	})
+}
+
+func (m *middlewareFS) PutStream(ctx context.Context, path Path, r io.Reader, opts ...WriteOption) error {
+	return m.writeStream(ctx, "PutStream", path, r, opts, func(ctx context.Context, r io.Reader) error {
+		return m.Interface.PutStream(ctx, path, r, opts...)
+	})
+}
+
+func (m *middlewareFS) Delete(ctx context.Context, path Path) (ok bool, err error) {
+	err = m.run(ctx, newOperation("Delete", path), func(ctx context.Context, op *Operation) error {
+		ok, err = m.Interface.Delete(ctx, path)
+		return err
+	})
+	return ok, err
+}
+
+func (m *middlewareFS) DeleteIf(ctx context.Context, path Path, cond Precondition) error {
+	return m.run(ctx, newOperation("DeleteIf", path), func(ctx context.Context, op *Operation) error {
+		return m.Interface.DeleteIf(ctx, path, cond)
+	})
+}
+
+func (m *middlewareFS) ReadAndDelete(ctx context.Context, path Path) (content []byte, err error) {
+	err = m.run(ctx, newOperation("ReadAndDelete", path), func(ctx context.Context, op *Operation) error {
+		content, err = m.Interface.ReadAndDelete(ctx, path)
+		op.Bytes = int64(len(content))
+		return err
+	})
+	return content, err
+}
+
+func (m *middlewareFS) transfer(ctx context.Context, name string, path, newpath Path, fn func(ctx context.Context) error) error {
+	op := newOperation(name, path)
+	op.NewPath = newpath
+	return m.run(ctx, op, func(ctx context.Context, op *Operation) error {
+		return fn(ctx)
+	})
+}
+
+func (m *middlewareFS) Move(ctx context.Context, path, newpath Path) error {
+	return m.transfer(ctx, "Move", path, newpath, func(ctx context.Context) error {
+		return m.Interface.Move(ctx, path, newpath)
+	})
+}
+
+func (m *middlewareFS) Copy(ctx context.Context, path, newpath Path) error {
+	return m.transfer(ctx, "Copy", path, newpath, func(ctx context.Context) error {
+		return m.Interface.Copy(ctx, path, newpath)
+	})
+}
+
+func (m *middlewareFS) MoveDir(ctx context.Context, path, newpath Path) error {
+	return m.transfer(ctx, "MoveDir", path, newpath, func(ctx context.Context) error {
+		return m.Interface.MoveDir(ctx, path, newpath)
+	})
+}
+
+func (m *middlewareFS) CopyDir(ctx context.Context, path, newpath Path) error {
+	return m.transfer(ctx, "CopyDir", path, newpath, func(ctx context.Context) error {
+		return m.Interface.CopyDir(ctx, path, newpath)
+	})
+}
+
+func (m *middlewareFS) CreateDir(ctx context.Context, path Path, opts ...DirOption) error {
+	return m.run(ctx, newOperation("CreateDir", path), func(ctx context.Context, op *Operation) error {
+		return m.Interface.CreateDir(ctx, path, opts...)
+	})
+}
+
+func (m *middlewareFS) DeleteDir(ctx context.Context, path Path) error {
+	return m.run(ctx, newOperation("DeleteDir", path), func(ctx context.Context, op *Operation) error {
+		return m.Interface.DeleteDir(ctx, path)
+	})
+}
+
+func (m *middlewareFS) SetVisibility(ctx context.Context, path Path, v Visibility) error {
+	return m.run(ctx, newOperation("SetVisibility", path), func(ctx context.Context, op *Operation) error {
+		return m.Interface.SetVisibility(ctx, path, v)
+	})
+}
+
+func (m *middlewareFS) RestoreVersion(ctx context.Context, path Path, versionID string) error {
+	return m.run(ctx, newOperation("RestoreVersion", path), func(ctx context.Context, op *Operation) error {
+		return m.Interface.RestoreVersion(ctx, path, versionID)
+	})
+}
+
+func (m *middlewareFS) SetUserMetadata(ctx context.Context, path Path, metadata map[string]string) error {
+	return m.run(ctx, newOperation("SetUserMetadata", path), func(ctx context.Context, op *Operation) error {
+		return m.Interface.SetUserMetadata(ctx, path, metadata)
+	})
+}
+
+func (m *middlewareFS) BeginUpload(ctx context.Context, path Path, opts ...WriteOption) (s UploadSession, err error) {
+	err = m.run(ctx, newOperation("BeginUpload", path), func(ctx context.Context, op *Operation) error {
+		s, err = m.Interface.BeginUpload(ctx, path, opts...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &middlewareUpload{UploadSession: s, m: m, opts: opts}, nil
+}
+
+func (m *middlewareFS) ResumeUpload(ctx context.Context, state UploadState, opts ...WriteOption) (s UploadSession, err error) {
+	err = m.run(ctx, newOperation("ResumeUpload", state.Path), func(ctx context.Context, op *Operation) error {
+		s, err = m.Interface.ResumeUpload(ctx, state, opts...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &middlewareUpload{UploadSession: s, m: m, opts: opts}, nil
+}
+
+// middlewareUpload is an upload session of a decorated file system, whose commit goes through the middlewares as the
+// WriteAtomic of the file with the content received.
+type middlewareUpload struct {
+	UploadSession
+	m    *middlewareFS
+	opts []WriteOption
+}
+
+func (u *middlewareUpload) Commit(ctx context.Context) error {
+	state := u.State()
+	op := newOperation("WriteAtomic", state.Path)
+	op.Config = optionsConfig(u.opts)
+	return u.m.run(ctx, op, func(ctx context.Context, op *Operation) error {
+		err := u.UploadSession.Commit(ctx)
+		if err == nil {
+			op.Bytes = state.Offset
+		}
+		return err
+	})
+}
+
+func (m *middlewareFS) Lock(ctx context.Context, path Path, opts LockOptions) (l Lock, err error) {
+	err = m.run(ctx, newOperation("Lock", path), func(ctx context.Context, op *Operation) error {
+		l, err = m.Interface.Lock(ctx, path, opts)
+		return err
+	})
+	return l, err
+}
+
+func (m *middlewareFS) Watch(ctx context.Context, path Path, recursive bool) (events <-chan Event, cancel context.CancelFunc, err error) {
+	err = m.run(ctx, newOperation("Watch", path), func(ctx context.Context, op *Operation) error {
+		events, cancel, err = m.Interface.Watch(ctx, path, recursive)
+		return err
+	})
+	return events, cancel, err
+}
@@ -0,0 +1,248 @@
+package migrate
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/maurofran/filesystem"
+)
+
+// Report is the outcome of a migration run.
+type Report struct {
+	// Copied is the number of files copied by this run.
+	Copied int
+	// Skipped is the number of files already copied by previous runs and unchanged since.
+	Skipped int
+	// Failed holds the files that could not be copied, with the last error raised.
+	Failed map[filesystem.Path]error
+	// Mismatched holds the files whose copy failed verification.
+	Mismatched []filesystem.Path
+}
+
+// copied is the source metadata of a copied file, telling whether it changed since.
+type copied struct {
+	Size      int64     `json:"size"`
+	Timestamp time.Time `json:"timestamp"`
+	ETag      string    `json:"etag,omitempty"`
+}
+
+func copiedOf(md filesystem.Metadata) copied {
+	return copied{Size: md.Size, Timestamp: md.Timestamp, ETag: md.ETag}
+}
+
+// state is the checkpoint persisted between runs.
+type state struct {
+	Done map[filesystem.Path]copied `json:"copied"`
+}
+
+// isDone will check if file of provided metadata was copied and has not changed since.
+func (s state) isDone(md filesystem.Metadata) bool {
+	c, ok := s.Done[md.Path]
+	return ok && c.Size == md.Size && c.Timestamp.Equal(md.Timestamp) && c.ETag == md.ETag
+}
+
+// Migrator copies every file of a source file system to a destination one, checkpointing progress so that an
+// interrupted migration can be resumed. Files changed since they were copied are copied again.
+type Migrator struct {
+	// Source is the file system files are copied from.
+	Source filesystem.Interface
+	// Destination is the file system files are copied to.
+	Destination filesystem.Interface
+	// StateFile is the local file where progress is persisted. Progress is not persisted if empty.
+	StateFile string
+	// Concurrency is the number of files copied in parallel, 1 if not positive.
+	Concurrency int
+	// Retries is the number of additional attempts made for each failing file.
+	Retries int
+	// Verify enables a final pass comparing the size of each copied file with its source, and its MD5 checksum when
+	// both file systems provide checksums natively.
+	Verify bool
+
+	mu    sync.Mutex
+	state state
+	dirty int
+}
+
+const checkpointEvery = 100
+
+// Run will copy the files not copied yet by previous runs.
+func (m *Migrator) Run(ctx context.Context) (Report, error) {
+	report := Report{Failed: make(map[filesystem.Path]error)}
+	if err := m.load(); err != nil {
+		return report, err
+	}
+	listing, err := m.Source.ListContents(ctx, filesystem.RootPath, true)
+	if err != nil {
+		return report, err
+	}
+	var files []filesystem.Metadata
+	for _, md := range listing {
+		if md.IsDir() {
+			continue
+		}
+		if m.state.isDone(md) {
+			report.Skipped++
+		} else {
+			files = append(files, md)
+		}
+	}
+	queue := make(chan filesystem.Metadata)
+	var wg sync.WaitGroup
+	workers := m.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for md := range queue {
+				err := m.copyWithRetry(ctx, md.Path)
+				m.mu.Lock()
+				if err != nil {
+					report.Failed[md.Path] = err
+				} else {
+					report.Copied++
+					m.markDone(md)
+				}
+				m.mu.Unlock()
+			}
+		}()
+	}
+	for _, md := range files {
+		queue <- md
+	}
+	close(queue)
+	wg.Wait()
+	if m.Verify {
+		if report.Mismatched, err = m.verify(ctx, listing); err != nil {
+			return report, err
+		}
+	}
+	return report, m.save()
+}
+
+func (m *Migrator) copyWithRetry(ctx context.Context, path filesystem.Path) error {
+	var err error
+	for attempt := 0; attempt <= m.Retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Duration(attempt) * 100 * time.Millisecond):
+			}
+		}
+		if err = m.copy(ctx, path); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func (m *Migrator) copy(ctx context.Context, path filesystem.Path) error {
+	r, err := m.Source.ReadStream(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return m.Destination.PutStream(ctx, path, r)
+}
+
+func (m *Migrator) verify(ctx context.Context, listing []filesystem.Metadata) ([]filesystem.Path, error) {
+	var mismatched []filesystem.Path
+	checksums := nativeChecksums(m.Source) && nativeChecksums(m.Destination)
+	for _, md := range listing {
+		p := md.Path
+		if md.IsDir() || !m.state.isDone(md) {
+			continue
+		}
+		match, err := m.matches(ctx, p, checksums)
+		if err != nil {
+			return nil, err
+		}
+		if !match {
+			mismatched = append(mismatched, p)
+			delete(m.state.Done, p)
+		}
+	}
+	return mismatched, nil
+}
+
+// matches will check if the copy of file at path has the size, and the checksum if asked, of its source.
+func (m *Migrator) matches(ctx context.Context, p filesystem.Path, checksums bool) (bool, error) {
+	srcSize, err := m.Source.GetFileSize(ctx, p)
+	if err != nil {
+		return false, err
+	}
+	dstSize, err := m.Destination.GetFileSize(ctx, p)
+	if err != nil || dstSize != srcSize {
+		return false, nil
+	}
+	if !checksums {
+		return true, nil
+	}
+	srcSum, err := m.Source.GetChecksum(ctx, p, filesystem.ChecksumMD5)
+	if err != nil {
+		return false, err
+	}
+	dstSum, err := m.Destination.GetChecksum(ctx, p, filesystem.ChecksumMD5)
+	return err == nil && dstSum == srcSum, nil
+}
+
+// nativeChecksums will check if provided file system reports checksums without reading content.
+func nativeChecksums(fs filesystem.Interface) bool {
+	r, ok := fs.(filesystem.CapabilityReporter)
+	return ok && r.Capabilities().Checksums
+}
+
+// markDone will record a copied file, persisting the checkpoint every now and then. Must be called with lock held.
+func (m *Migrator) markDone(md filesystem.Metadata) {
+	m.state.Done[md.Path] = copiedOf(md)
+	m.dirty++
+	if m.dirty >= checkpointEvery {
+		// A failed checkpoint only means more files to copy again on resume.
+		if m.save() == nil {
+			m.dirty = 0
+		}
+	}
+}
+
+func (m *Migrator) load() error {
+	m.state = state{Done: make(map[filesystem.Path]copied)}
+	if m.StateFile == "" {
+		return nil
+	}
+	b, err := os.ReadFile(m.StateFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, &m.state); err != nil {
+		return err
+	}
+	if m.state.Done == nil {
+		m.state.Done = make(map[filesystem.Path]copied)
+	}
+	return nil
+}
+
+// save will atomically replace the state file with current state.
+func (m *Migrator) save() error {
+	if m.StateFile == "" {
+		return nil
+	}
+	b, err := json.Marshal(m.state)
+	if err != nil {
+		return err
+	}
+	tmp := m.StateFile + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, m.StateFile)
+}
@@ -0,0 +1,87 @@
+// Package mimedetect detects the mime type of files from their content and name.
+//
+// Content is sniffed with http.DetectContentType, which only considers the first SniffLen bytes. Sniffing cannot
+// tell apart most text formats, so a generic result is refined with the mime type registered for the file extension,
+// looked up first among the ones added with Register and then with mime.TypeByExtension.
+package mimedetect
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+)
+
+// SniffLen is the number of leading bytes considered when sniffing content.
+const SniffLen = 512
+
+// Generic mime types returned by http.DetectContentType when content does not match a known signature.
+const (
+	octetStream = "application/octet-stream"
+	plainText   = "text/plain; charset=utf-8"
+)
+
+var (
+	mu         sync.RWMutex
+	extensions = map[string]string{
+		".csv":  "text/csv; charset=utf-8",
+		".md":   "text/markdown; charset=utf-8",
+		".toml": "application/toml",
+		".txt":  "text/plain; charset=utf-8",
+		".yaml": "application/yaml",
+		".yml":  "application/yaml",
+	}
+)
+
+// Register will associate provided mime type with a file extension, such as ".md", taking precedence over the
+// system mime types.
+func Register(ext, mimeType string) {
+	mu.Lock()
+	defer mu.Unlock()
+	extensions[strings.ToLower(ext)] = mimeType
+}
+
+// ByExtension will find the mime type of a file from the extension of its name, or return an empty string.
+func ByExtension(name string) string {
+	ext := strings.ToLower(path.Ext(name))
+	if ext == "" {
+		return ""
+	}
+	mu.RLock()
+	mt, ok := extensions[ext]
+	mu.RUnlock()
+	if ok {
+		return mt
+	}
+	return mime.TypeByExtension(ext)
+}
+
+// Sniff will find the mime type of content from its leading bytes, always returning a valid mime type.
+func Sniff(head []byte) string {
+	return http.DetectContentType(head)
+}
+
+// Detect will find the mime type of a file from its leading bytes, using the extension of its name when content
+// alone only gives a generic mime type.
+func Detect(name string, head []byte) string {
+	mt := Sniff(head)
+	if mt != octetStream && mt != plainText {
+		return mt
+	}
+	if byExt := ByExtension(name); byExt != "" {
+		return byExt
+	}
+	return mt
+}
+
+// DetectReader will find the mime type of a file reading at most SniffLen bytes from r.
+func DetectReader(name string, r io.Reader) (string, error) {
+	head := make([]byte, SniffLen)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	return Detect(name, head[:n]), nil
+}
@@ -0,0 +1,377 @@
+// Package mirror provides an adapter replicating every change of a primary adapter to replica adapters, reading
+// from the primary and failing over to the replicas when it is unavailable.
+package mirror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/maurofran/filesystem"
+)
+
+// ErrClosed is the error replication fails with when a change is queued after the adapter was closed.
+var ErrClosed = errors.New("mirror: adapter closed")
+
+// ReplicationError is the error raised when a change applied to the primary could not be applied to a replica.
+type ReplicationError interface {
+	error
+	Path() filesystem.Path
+	// Replica is the index of the failing replica.
+	Replica() int
+	Unwrap() error
+}
+
+type replicationError struct {
+	path    filesystem.Path
+	replica int
+	err     error
+}
+
+// Path is the path of the change that failed.
+func (e replicationError) Path() filesystem.Path {
+	return e.path
+}
+
+// Replica is the index of the failing replica.
+func (e replicationError) Replica() int {
+	return e.replica
+}
+
+// Unwrap will return the error raised by the replica.
+func (e replicationError) Unwrap() error {
+	return e.err
+}
+
+func (e replicationError) Error() string {
+	return fmt.Sprintf("Unable to replicate %s to replica %d: %v", e.path, e.replica, e.err)
+}
+
+// IsReplicationError will check if provided error is a replication error.
+func IsReplicationError(err error) bool {
+	_, ok := err.(ReplicationError)
+	return ok
+}
+
+// Options configures the replication.
+type Options struct {
+	// Async enables asynchronous replication: changes are applied to the primary and queued for the replicas,
+	// the call returning as soon as the primary succeeded.
+	Async bool
+	// QueueSize bounds the number of queued changes in asynchronous mode, 1000 if not positive. Calls block while
+	// the queue is full.
+	QueueSize int
+	// OnError is called with the replication errors raised in asynchronous mode, which are otherwise dropped.
+	OnError func(err error)
+}
+
+// change is a change to apply to a replica.
+type change struct {
+	path filesystem.Path
+	fn   func(ctx context.Context, replica filesystem.Adapter) error
+	ctx  context.Context
+}
+
+// New will create an adapter mirroring primary to replicas. Reads are served by the primary, failing over to
+// the replicas in order when it fails with an error other than a missing file. Writes must succeed on the primary
+// and are then applied to each replica; in synchronous mode a ReplicationError is returned if a replica fails.
+//
+// In asynchronous mode the returned adapter must be closed to drain the queue.
+func New(primary filesystem.Adapter, replicas []filesystem.Adapter, opts Options) filesystem.Adapter {
+	m := &mirror{primary: primary, replicas: replicas, opts: opts}
+	if opts.Async {
+		size := opts.QueueSize
+		if size <= 0 {
+			size = 1000
+		}
+		m.queue = make(chan change, size)
+		m.done = make(chan struct{})
+		go m.work()
+	}
+	return m
+}
+
+// Replicas will return the replicas of provided adapter if it was created by New, nil otherwise.
+func Replicas(a filesystem.Adapter) []filesystem.Adapter {
+	if m, ok := a.(*mirror); ok {
+		return append([]filesystem.Adapter(nil), m.replicas...)
+	}
+	return nil
+}
+
+type mirror struct {
+	primary  filesystem.Adapter
+	replicas []filesystem.Adapter
+	opts     Options
+	queue    chan change
+	done     chan struct{}
+	mu       sync.RWMutex
+	closed   bool
+}
+
+func (m *mirror) work() {
+	defer close(m.done)
+	for c := range m.queue {
+		if err := m.apply(c); err != nil && m.opts.OnError != nil {
+			m.opts.OnError(err)
+		}
+	}
+}
+
+// apply will apply a change to every replica, returning the first error.
+func (m *mirror) apply(c change) error {
+	var first error
+	for i, r := range m.replicas {
+		if err := c.fn(c.ctx, r); err != nil && first == nil {
+			first = replicationError{c.path, i, err}
+		}
+	}
+	return first
+}
+
+// replicate will apply a change already applied to the primary to the replicas.
+func (m *mirror) replicate(ctx context.Context, path filesystem.Path, fn func(ctx context.Context, replica filesystem.Adapter) error) error {
+	if m.queue == nil {
+		return m.apply(change{path, fn, ctx})
+	}
+	// The change outlives the call, so it must not be canceled with it.
+	c := change{path, fn, context.WithoutCancel(ctx)}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.closed {
+		return replicationError{path, -1, ErrClosed}
+	}
+	select {
+	case m.queue <- c:
+		return nil
+	case <-ctx.Done():
+		return replicationError{path, -1, ctx.Err()}
+	}
+}
+
+// copyFromPrimary will replicate the file at path by reading it back from the primary, used for streamed writes.
+func (m *mirror) copyFromPrimary(path filesystem.Path, cfg filesystem.Config) func(ctx context.Context, replica filesystem.Adapter) error {
+	return func(ctx context.Context, replica filesystem.Adapter) error {
+		rc, err := m.primary.ReadStream(ctx, path)
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		return replica.PutStream(ctx, path, rc, cfg)
+	}
+}
+
+// put will replicate provided content, with Put so that lagging replicas converge.
+func put(path filesystem.Path, content []byte, cfg filesystem.Config) func(ctx context.Context, replica filesystem.Adapter) error {
+	content = append([]byte(nil), content...)
+	return func(ctx context.Context, replica filesystem.Adapter) error {
+		return replica.Put(ctx, path, content, cfg)
+	}
+}
+
+// ignoreNotFound will hide the error of replicas already missing a deleted file.
+func ignoreNotFound(err error) error {
+	if filesystem.IsFileNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// failover will try fn on the primary and then on each replica, while failing with errors other than missing
+// files or invalid paths.
+func failover[T any](m *mirror, fn func(a filesystem.Adapter) (T, error)) (T, error) {
+	v, err := fn(m.primary)
+	for _, r := range m.replicas {
+		if err == nil || filesystem.IsFileNotFound(err) || filesystem.IsPathError(err) {
+			break
+		}
+		v, err = fn(r)
+	}
+	return v, err
+}
+
+func (m *mirror) Has(ctx context.Context, path filesystem.Path) (bool, error) {
+	return failover(m, func(a filesystem.Adapter) (bool, error) {
+		return a.Has(ctx, path)
+	})
+}
+
+func (m *mirror) Read(ctx context.Context, path filesystem.Path) ([]byte, error) {
+	return failover(m, func(a filesystem.Adapter) ([]byte, error) {
+		return a.Read(ctx, path)
+	})
+}
+
+func (m *mirror) ReadStream(ctx context.Context, path filesystem.Path) (io.ReadCloser, error) {
+	return failover(m, func(a filesystem.Adapter) (io.ReadCloser, error) {
+		return a.ReadStream(ctx, path)
+	})
+}
+
+func (m *mirror) Write(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	if err := m.primary.Write(ctx, path, content, cfg); err != nil {
+		return err
+	}
+	return m.replicate(ctx, path, put(path, content, cfg))
+}
+
+func (m *mirror) WriteStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	if err := m.primary.WriteStream(ctx, path, r, cfg); err != nil {
+		return err
+	}
+	return m.replicate(ctx, path, m.copyFromPrimary(path, cfg))
+}
+
+func (m *mirror) Update(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	if err := m.primary.Update(ctx, path, content, cfg); err != nil {
+		return err
+	}
+	return m.replicate(ctx, path, put(path, content, cfg))
+}
+
+func (m *mirror) UpdateStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	if err := m.primary.UpdateStream(ctx, path, r, cfg); err != nil {
+		return err
+	}
+	return m.replicate(ctx, path, m.copyFromPrimary(path, cfg))
+}
+
+func (m *mirror) Put(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	if err := m.primary.Put(ctx, path, content, cfg); err != nil {
+		return err
+	}
+	return m.replicate(ctx, path, put(path, content, cfg))
+}
+
+func (m *mirror) PutStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	if err := m.primary.PutStream(ctx, path, r, cfg); err != nil {
+		return err
+	}
+	return m.replicate(ctx, path, m.copyFromPrimary(path, cfg))
+}
+
+func (m *mirror) deleteReplicas(ctx context.Context, path filesystem.Path) error {
+	return m.replicate(ctx, path, func(ctx context.Context, replica filesystem.Adapter) error {
+		return ignoreNotFound(replica.Delete(ctx, path))
+	})
+}
+
+func (m *mirror) Delete(ctx context.Context, path filesystem.Path) error {
+	if err := m.primary.Delete(ctx, path); err != nil {
+		return err
+	}
+	return m.deleteReplicas(ctx, path)
+}
+
+func (m *mirror) ReadAndDelete(ctx context.Context, path filesystem.Path) ([]byte, error) {
+	content, err := m.primary.ReadAndDelete(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return content, m.deleteReplicas(ctx, path)
+}
+
+func (m *mirror) Move(ctx context.Context, path, newpath filesystem.Path) error {
+	if err := m.primary.Move(ctx, path, newpath); err != nil {
+		return err
+	}
+	return m.replicate(ctx, path, func(ctx context.Context, replica filesystem.Adapter) error {
+		return replica.Move(ctx, path, newpath)
+	})
+}
+
+func (m *mirror) Copy(ctx context.Context, path, newpath filesystem.Path) error {
+	if err := m.primary.Copy(ctx, path, newpath); err != nil {
+		return err
+	}
+	return m.replicate(ctx, newpath, func(ctx context.Context, replica filesystem.Adapter) error {
+		return replica.Copy(ctx, path, newpath)
+	})
+}
+
+func (m *mirror) GetMimeType(ctx context.Context, path filesystem.Path) (string, error) {
+	return failover(m, func(a filesystem.Adapter) (string, error) {
+		return a.GetMimeType(ctx, path)
+	})
+}
+
+func (m *mirror) GetTimestamp(ctx context.Context, path filesystem.Path) (time.Time, error) {
+	return failover(m, func(a filesystem.Adapter) (time.Time, error) {
+		return a.GetTimestamp(ctx, path)
+	})
+}
+
+func (m *mirror) GetFileSize(ctx context.Context, path filesystem.Path) (int64, error) {
+	return failover(m, func(a filesystem.Adapter) (int64, error) {
+		return a.GetFileSize(ctx, path)
+	})
+}
+
+func (m *mirror) GetMetadata(ctx context.Context, path filesystem.Path) (filesystem.Metadata, error) {
+	return failover(m, func(a filesystem.Adapter) (filesystem.Metadata, error) {
+		return a.GetMetadata(ctx, path)
+	})
+}
+
+func (m *mirror) CreateDir(ctx context.Context, path filesystem.Path, cfg filesystem.Config) error {
+	if err := m.primary.CreateDir(ctx, path, cfg); err != nil {
+		return err
+	}
+	return m.replicate(ctx, path, func(ctx context.Context, replica filesystem.Adapter) error {
+		return replica.CreateDir(ctx, path, cfg)
+	})
+}
+
+func (m *mirror) DeleteDir(ctx context.Context, path filesystem.Path) error {
+	if err := m.primary.DeleteDir(ctx, path); err != nil {
+		return err
+	}
+	return m.replicate(ctx, path, func(ctx context.Context, replica filesystem.Adapter) error {
+		return ignoreNotFound(replica.DeleteDir(ctx, path))
+	})
+}
+
+func (m *mirror) GetVisibility(ctx context.Context, path filesystem.Path) (filesystem.Visibility, error) {
+	return failover(m, func(a filesystem.Adapter) (filesystem.Visibility, error) {
+		return a.GetVisibility(ctx, path)
+	})
+}
+
+func (m *mirror) SetVisibility(ctx context.Context, path filesystem.Path, v filesystem.Visibility) error {
+	if err := m.primary.SetVisibility(ctx, path, v); err != nil {
+		return err
+	}
+	return m.replicate(ctx, path, func(ctx context.Context, replica filesystem.Adapter) error {
+		return replica.SetVisibility(ctx, path, v)
+	})
+}
+
+func (m *mirror) ListContents(ctx context.Context, path filesystem.Path, recursive bool) ([]filesystem.Metadata, error) {
+	return failover(m, func(a filesystem.Adapter) ([]filesystem.Metadata, error) {
+		return a.ListContents(ctx, path, recursive)
+	})
+}
+
+// Close will wait for queued changes to be replicated, then close primary and replicas, returning the first
+// error encountered. Changes made afterwards are applied to the primary only, replication failing with ErrClosed.
+func (m *mirror) Close() error {
+	if m.queue != nil {
+		m.mu.Lock()
+		if !m.closed {
+			m.closed = true
+			close(m.queue)
+		}
+		m.mu.Unlock()
+		<-m.done
+	}
+	first := filesystem.Close(m.primary)
+	for _, r := range m.replicas {
+		if err := filesystem.Close(r); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
@@ -0,0 +1,272 @@
+package filesystem
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MountOption decorates a file system as it is mounted, attaching cross-cutting behaviors to a single mount. Options
+// given to Mount are applied so that the first one is the outermost.
+type MountOption func(fs Interface) Interface
+
+// WithMountMiddleware will make the operations on the mount go through provided middlewares, see WithMiddleware.
+func WithMountMiddleware(mw ...Middleware) MountOption {
+	return func(fs Interface) Interface {
+		return WithMiddleware(fs, mw...)
+	}
+}
+
+// WithReadOnly will make the mount refuse every operation modifying it with a ReadOnlyError.
+func WithReadOnly() MountOption {
+	return func(fs Interface) Interface {
+		return readOnlyFS{WithMiddleware(fs, refuseWrites)}
+	}
+}
+
+// refuseWrites is the middleware refusing the operations modifying the file system.
+func refuseWrites(next Handler) Handler {
+	return func(ctx context.Context, op *Operation) error {
+		if !op.IsRead() {
+			return NewReadOnlyError(op.Path)
+		}
+		return next(ctx, op)
+	}
+}
+
+// readOnlyFS refuses the upload sessions too, which do not go through middlewares.
+type readOnlyFS struct {
+	Interface
+}
+
+func (fs readOnlyFS) Close() error {
+	return Close(fs.Interface)
+}
+
+func (fs readOnlyFS) BeginUpload(ctx context.Context, path Path, opts ...WriteOption) (UploadSession, error) {
+	return nil, NewReadOnlyError(path)
+}
+
+func (fs readOnlyFS) ResumeUpload(ctx context.Context, state UploadState, opts ...WriteOption) (UploadSession, error) {
+	return nil, NewReadOnlyError(state.Path)
+}
+
+// Lock is refused too, since locks are files written to the mount.
+func (fs readOnlyFS) Lock(ctx context.Context, path Path, opts LockOptions) (Lock, error) {
+	return nil, NewReadOnlyError(path)
+}
+
+// CacheConfig configures the cache of a mount.
+type CacheConfig struct {
+	// TTL is how long entries are cached, one minute if not positive.
+	TTL time.Duration
+	// MaxEntries is the number of entries cached at most, 1000 if not positive.
+	MaxEntries int
+	// MaxFileSize is the size of the largest file whose content is cached by Read; content is not cached if zero.
+	MaxFileSize int64
+}
+
+// WithCache will cache the metadata of the files of the mount and, up to cfg.MaxFileSize, their content. Entries are
+// invalidated by the operations modifying the files through the mount, and expire after cfg.TTL, so that changes
+// made elsewhere show eventually.
+func WithCache(cfg CacheConfig) MountOption {
+	if cfg.TTL <= 0 {
+		cfg.TTL = time.Minute
+	}
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = 1000
+	}
+	return func(fs Interface) Interface {
+		c := &cachedFS{cfg: cfg, entries: make(map[cacheKey]cacheEntry)}
+		c.Interface = WithMiddleware(fs, c.invalidation)
+		return c
+	}
+}
+
+type cacheKey struct {
+	op   string
+	path Path
+}
+
+type cacheEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+type cachedFS struct {
+	Interface
+	cfg     CacheConfig
+	mu      sync.Mutex
+	entries map[cacheKey]cacheEntry
+}
+
+// key will return the key of the entry of op on path, normalized so that every spelling of a path shares its
+// entries, reporting false for invalid paths, which are not cached.
+func (c *cachedFS) key(op string, path Path) (cacheKey, bool) {
+	path, err := NewPath(string(path))
+	return cacheKey{op, path}, err == nil
+}
+
+func (c *cachedFS) get(op string, path Path) (interface{}, bool) {
+	key, ok := c.key(op, path)
+	if !ok {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok || time.Now().After(e.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return e.value, true
+}
+
+func (c *cachedFS) put(op string, path Path, value interface{}) {
+	key, ok := c.key(op, path)
+	if !ok {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if len(c.entries) >= c.cfg.MaxEntries {
+		for key, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, key)
+			}
+		}
+	}
+	for key := range c.entries {
+		if len(c.entries) < c.cfg.MaxEntries {
+			break
+		}
+		delete(c.entries, key)
+	}
+	c.entries[key] = cacheEntry{value: value, expires: now.Add(c.cfg.TTL)}
+}
+
+// invalidate will drop the entries of path and of the files it contains, every entry if path is invalid.
+func (c *cachedFS) invalidate(path Path) {
+	path, err := NewPath(string(path))
+	if err != nil {
+		path = RootPath
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.entries {
+		if path == RootPath || key.path == path || strings.HasPrefix(string(key.path), string(path)+"/") {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// invalidation is the middleware invalidating the entries of the files modified by operations.
+func (c *cachedFS) invalidation(next Handler) Handler {
+	return func(ctx context.Context, op *Operation) error {
+		if op.IsRead() {
+			return next(ctx, op)
+		}
+		defer c.invalidate(op.Path)
+		if op.NewPath != "" {
+			defer c.invalidate(op.NewPath)
+		}
+		return next(ctx, op)
+	}
+}
+
+// cached will return the cached result of op on path, or compute it with fn and cache it on success.
+func cached[T any](c *cachedFS, op string, path Path, fn func() (T, error)) (T, error) {
+	if v, ok := c.get(op, path); ok {
+		return v.(T), nil
+	}
+	v, err := fn()
+	if err == nil {
+		c.put(op, path, v)
+	}
+	return v, err
+}
+
+func (c *cachedFS) Close() error {
+	return Close(c.Interface)
+}
+
+func (c *cachedFS) Read(ctx context.Context, path Path) ([]byte, error) {
+	if v, ok := c.get("Read", path); ok {
+		return append([]byte(nil), v.([]byte)...), nil
+	}
+	content, err := c.Interface.Read(ctx, path)
+	if err == nil && int64(len(content)) <= c.cfg.MaxFileSize {
+		c.put("Read", path, append([]byte(nil), content...))
+	}
+	return content, err
+}
+
+func (c *cachedFS) Has(ctx context.Context, path Path) (bool, error) {
+	return cached(c, "Has", path, func() (bool, error) {
+		return c.Interface.Has(ctx, path)
+	})
+}
+
+func (c *cachedFS) GetMimeType(ctx context.Context, path Path) (string, error) {
+	return cached(c, "GetMimeType", path, func() (string, error) {
+		return c.Interface.GetMimeType(ctx, path)
+	})
+}
+
+func (c *cachedFS) GetTimestamp(ctx context.Context, path Path) (time.Time, error) {
+	return cached(c, "GetTimestamp", path, func() (time.Time, error) {
+		return c.Interface.GetTimestamp(ctx, path)
+	})
+}
+
+func (c *cachedFS) GetFileSize(ctx context.Context, path Path) (int64, error) {
+	return cached(c, "GetFileSize", path, func() (int64, error) {
+		return c.Interface.GetFileSize(ctx, path)
+	})
+}
+
+func (c *cachedFS) GetMetadata(ctx context.Context, path Path) (Metadata, error) {
+	md, err := cached(c, "GetMetadata", path, func() (Metadata, error) {
+		return c.Interface.GetMetadata(ctx, path)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return md.Clone(), nil
+}
+
+func (c *cachedFS) GetVisibility(ctx context.Context, path Path) (Visibility, error) {
+	return cached(c, "GetVisibility", path, func() (Visibility, error) {
+		return c.Interface.GetVisibility(ctx, path)
+	})
+}
+
+// BeginUpload will begin an upload session invalidating the entries of the file on commit.
+func (c *cachedFS) BeginUpload(ctx context.Context, path Path, opts ...WriteOption) (UploadSession, error) {
+	s, err := c.Interface.BeginUpload(ctx, path, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return cachedUpload{s, c}, nil
+}
+
+// ResumeUpload will resume an upload session invalidating the entries of the file on commit.
+func (c *cachedFS) ResumeUpload(ctx context.Context, state UploadState, opts ...WriteOption) (UploadSession, error) {
+	s, err := c.Interface.ResumeUpload(ctx, state, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return cachedUpload{s, c}, nil
+}
+
+type cachedUpload struct {
+	UploadSession
+	cache *cachedFS
+}
+
+func (u cachedUpload) Commit(ctx context.Context) error {
+	defer u.cache.invalidate(u.State().Path)
+	return u.UploadSession.Commit(ctx)
+}
@@ -1,9 +1,13 @@
 package filesystem
 
 import (
+	"context"
 	"io"
+	"reflect"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -21,26 +25,92 @@ func splitPath(path Path) (string, Path, error) {
 	return prefix, subPath, nil
 }
 
-// MountManager is the interface exposed by objects that allows to mount more file systems.
+// AllMounts is the prefix of the paths addressing every mount at once, e.g. "*://reports" to list the contents of the
+// reports directory of every mount.
+const AllMounts = "*"
+
+// MountInfo describes a mounted file system.
+type MountInfo struct {
+	// Prefix is the prefix the file system is mounted with.
+	Prefix string
+	// Aliases are the further prefixes the file system is addressed by, sorted.
+	Aliases []string
+	// Default reports whether the paths without prefix address the file system.
+	Default bool
+}
+
+// MountManager is the interface exposed by objects that allows to mount more file systems. It is safe for concurrent
+// use: file systems can be mounted and unmounted while operations are running, an operation using the file system
+// mounted when it resolved its path.
+//
+// A file system can be addressed by more prefixes, by aliasing its prefix, and paths without prefix can address a
+// default mount, so that a mount manager can replace a plain file system in existing code. Contents are listed across
+// every mount with paths prefixed by AllMounts.
 type MountManager interface {
 	Interface
-	// Mount the provided manager with provided prefix.
-	Mount(prefix string, mgr Interface) error
-	// Unmount the provided prefix.
+	io.Closer
+	// Mount the provided manager with provided prefix, decorated by provided options.
+	Mount(prefix string, mgr Interface, opts ...MountOption) error
+	// Unmount the provided prefix. Unmounting the prefix of a file system removes its aliases too, while unmounting
+	// an alias leaves the file system mounted. The default mount is cleared along with its file system, and repointed
+	// to the prefix of the file system when only its alias is unmounted.
 	Unmount(prefix string) error
+	// Mounted will return the file system mounted with provided prefix.
+	Mounted(prefix string) (Interface, error)
+	// Alias will make the file system mounted with prefix available with alias too.
+	Alias(alias, prefix string) error
+	// SetDefault will make the paths without prefix address the file system mounted with provided prefix, or be
+	// refused again when prefix is empty.
+	SetDefault(prefix string) error
+	// ListMounts will describe the mounted file systems, sorted by prefix.
+	ListMounts() []MountInfo
 }
 
 type mountManager struct {
+	mu       sync.RWMutex
 	managers map[string]Interface
+	aliases  map[string]string
+	fallback string
 }
 
 // EmptyMountManager will create a new empty mount manager.
 func EmptyMountManager() MountManager {
-	return &mountManager{}
+	return &mountManager{managers: make(map[string]Interface), aliases: make(map[string]string)}
+}
+
+// Close will close every mounted file system once, even if mounted with more prefixes, returning the first error
+// encountered.
+func (mm *mountManager) Close() error {
+	mm.mu.RLock()
+	managers := make([]Interface, 0, len(mm.managers))
+	seen := make(map[Interface]bool)
+	for _, mgr := range mm.managers {
+		// File systems of non comparable types cannot be told apart, so they are closed as mounted.
+		if reflect.TypeOf(mgr).Comparable() {
+			if seen[mgr] {
+				continue
+			}
+			seen[mgr] = true
+		}
+		managers = append(managers, mgr)
+	}
+	mm.mu.RUnlock()
+	var first error
+	for _, mgr := range managers {
+		if err := Close(mgr); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
 }
 
-func (mm *mountManager) Mount(prefix string, mgr Interface) error {
-	if _, ok := mm.managers[prefix]; ok {
+func (mm *mountManager) Mount(prefix string, mgr Interface, opts ...MountOption) error {
+	for i := len(opts) - 1; i >= 0; i-- {
+		mgr = opts[i](mgr)
+	}
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	if mm.exists(prefix) {
 		return mountExistsError(prefix)
 	}
 	mm.managers[prefix] = mgr
@@ -48,126 +118,230 @@ func (mm *mountManager) Mount(prefix string, mgr Interface) error {
 }
 
 func (mm *mountManager) Unmount(prefix string) error {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	if target, ok := mm.aliases[prefix]; ok {
+		if mm.fallback == prefix {
+			mm.fallback = target
+		}
+		delete(mm.aliases, prefix)
+		return nil
+	}
 	if _, ok := mm.managers[prefix]; !ok {
 		return mountNotFoundError(prefix)
 	}
+	if mm.fallback != "" && mm.canonical(mm.fallback) == prefix {
+		mm.fallback = ""
+	}
 	delete(mm.managers, prefix)
+	for alias, target := range mm.aliases {
+		if target == prefix {
+			delete(mm.aliases, alias)
+		}
+	}
 	return nil
 }
 
-func (mm *mountManager) managerFor(path Path) (Interface, Path, error) {
+func (mm *mountManager) Alias(alias, prefix string) error {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	if mm.exists(alias) {
+		return mountExistsError(alias)
+	}
+	prefix = mm.canonical(prefix)
+	if _, ok := mm.managers[prefix]; !ok {
+		return mountNotFoundError(prefix)
+	}
+	mm.aliases[alias] = prefix
+	return nil
+}
+
+func (mm *mountManager) SetDefault(prefix string) error {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	if prefix != "" && !mm.exists(prefix) {
+		return mountNotFoundError(prefix)
+	}
+	mm.fallback = prefix
+	return nil
+}
+
+func (mm *mountManager) Mounted(prefix string) (Interface, error) {
+	mm.mu.RLock()
+	mgr, ok := mm.managers[mm.canonical(prefix)]
+	mm.mu.RUnlock()
+	if !ok {
+		return nil, mountNotFoundError(prefix)
+	}
+	return mgr, nil
+}
+
+func (mm *mountManager) ListMounts() []MountInfo {
+	mm.mu.RLock()
+	defer mm.mu.RUnlock()
+	mounts := make([]MountInfo, 0, len(mm.managers))
+	for prefix := range mm.managers {
+		info := MountInfo{Prefix: prefix, Default: mm.fallback != "" && mm.canonical(mm.fallback) == prefix}
+		for alias, target := range mm.aliases {
+			if target == prefix {
+				info.Aliases = append(info.Aliases, alias)
+			}
+		}
+		sort.Strings(info.Aliases)
+		mounts = append(mounts, info)
+	}
+	sort.Slice(mounts, func(i, j int) bool { return mounts[i].Prefix < mounts[j].Prefix })
+	return mounts
+}
+
+// exists will check if prefix is mounted or aliased.
+func (mm *mountManager) exists(prefix string) bool {
+	_, mounted := mm.managers[prefix]
+	_, aliased := mm.aliases[prefix]
+	return mounted || aliased
+}
+
+// canonical will resolve provided prefix if it is an alias.
+func (mm *mountManager) canonical(prefix string) string {
+	if target, ok := mm.aliases[prefix]; ok {
+		return target
+	}
+	return prefix
+}
+
+// resolve will split provided path in the canonical prefix of its mount and its path on the mount, paths without
+// prefix belonging to the default mount.
+func (mm *mountManager) resolve(path Path) (string, Path, error) {
+	if _, ok := allMounts(path); ok {
+		return "", "", invalidPathError(path)
+	}
 	prefix, subPath, err := splitPath(path)
+	mm.mu.RLock()
+	defer mm.mu.RUnlock()
+	if err != nil {
+		if mm.fallback == "" {
+			return "", "", err
+		}
+		prefix, subPath = mm.fallback, path
+	}
+	return mm.canonical(prefix), subPath, nil
+}
+
+func (mm *mountManager) managerFor(path Path) (Interface, Path, error) {
+	prefix, subPath, err := mm.resolve(path)
 	if err != nil {
 		return nil, "", err
 	}
-	mgr, ok := mm.managers[prefix]
-	if !ok {
-		return nil, "", mountNotFoundError(prefix)
+	mgr, err := mm.Mounted(prefix)
+	if err != nil {
+		return nil, "", err
 	}
 	return mgr, subPath, nil
 }
 
 // Has will check if a file exists.
-func (mm *mountManager) Has(path Path) (bool, error) {
+func (mm *mountManager) Has(ctx context.Context, path Path) (bool, error) {
 	mgr, subPath, err := mm.managerFor(path)
 	if err != nil {
 		return false, err
 	}
-	return mgr.Has(subPath)
+	return mgr.Has(ctx, subPath)
 }
 
 // Read the file at provided path.
-func (mm *mountManager) Read(path Path) (string, error) {
+func (mm *mountManager) Read(ctx context.Context, path Path) ([]byte, error) {
 	mgr, subPath, err := mm.managerFor(path)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	return mgr.Read(subPath)
+	return mgr.Read(ctx, subPath)
 }
 
 // ReadStream will read the file at provided path as a stream.
-func (mm *mountManager) ReadStream(path Path) (io.ReadCloser, error) {
+func (mm *mountManager) ReadStream(ctx context.Context, path Path) (io.ReadCloser, error) {
 	mgr, subPath, err := mm.managerFor(path)
 	if err != nil {
 		return nil, err
 	}
-	return mgr.ReadStream(subPath)
+	return mgr.ReadStream(ctx, subPath)
 }
 
 // Write the supplied content at supplied path, creating the file.
-func (mm *mountManager) Write(path Path, content string) error {
+func (mm *mountManager) Write(ctx context.Context, path Path, content []byte, opts ...WriteOption) error {
 	mgr, subPath, err := mm.managerFor(path)
 	if err != nil {
 		return err
 	}
-	return mgr.Write(subPath, content)
+	return mgr.Write(ctx, subPath, content, opts...)
 }
 
 // WriteStream will write the content of provided reader at supplied path, creating the file.
-func (mm *mountManager) WriteStream(path Path, r io.Reader) error {
+func (mm *mountManager) WriteStream(ctx context.Context, path Path, r io.Reader, opts ...WriteOption) error {
 	mgr, subPath, err := mm.managerFor(path)
 	if err != nil {
 		return err
 	}
-	return mgr.WriteStream(subPath, r)
+	return mgr.WriteStream(ctx, subPath, r, opts...)
 }
 
 // Update the supplied content at supplied path, returning an error if file does not exists.
-func (mm *mountManager) Update(path Path, content string) error {
+func (mm *mountManager) Update(ctx context.Context, path Path, content []byte, opts ...WriteOption) error {
 	mgr, subPath, err := mm.managerFor(path)
 	if err != nil {
 		return err
 	}
-	return mgr.Update(subPath, content)
+	return mgr.Update(ctx, subPath, content, opts...)
 }
 
 // Update with the content of supplied reader at supplied path, returning an error if file does not exists
-func (mm *mountManager) UpdateStream(path Path, r io.Reader) error {
+func (mm *mountManager) UpdateStream(ctx context.Context, path Path, r io.Reader, opts ...WriteOption) error {
 	mgr, subPath, err := mm.managerFor(path)
 	if err != nil {
 		return err
 	}
-	return mgr.UpdateStream(subPath, r)
+	return mgr.UpdateStream(ctx, subPath, r, opts...)
 }
 
 // Put the supplied content at supplied path, creating the file if does not exists.
-func (mm *mountManager) Put(path Path, content string) error {
+func (mm *mountManager) Put(ctx context.Context, path Path, content []byte, opts ...WriteOption) error {
 	mgr, subPath, err := mm.managerFor(path)
 	if err != nil {
 		return err
 	}
-	return mgr.Put(subPath, content)
+	return mgr.Put(ctx, subPath, content, opts...)
 }
 
 // Puth the content of supplied reader at supplied path, creating the file if does not exists.
-func (mm *mountManager) PutStream(path Path, r io.Reader) error {
+func (mm *mountManager) PutStream(ctx context.Context, path Path, r io.Reader, opts ...WriteOption) error {
 	mgr, subPath, err := mm.managerFor(path)
 	if err != nil {
 		return err
 	}
-	return mgr.PutStream(subPath, r)
+	return mgr.PutStream(ctx, subPath, r, opts...)
 }
 
 // Deletes a file at provided path.
-func (mm *mountManager) Delete(path Path) (bool, error) {
+func (mm *mountManager) Delete(ctx context.Context, path Path) (bool, error) {
 	mgr, subPath, err := mm.managerFor(path)
 	if err != nil {
 		return false, err
 	}
-	return mgr.Delete(subPath)
+	return mgr.Delete(ctx, subPath)
 }
 
 // ReadAndDelete will read the file at provided path and delete after read.
-func (mm *mountManager) ReadAndDelete(path Path) (string, error) {
+func (mm *mountManager) ReadAndDelete(ctx context.Context, path Path) ([]byte, error) {
 	mgr, subPath, err := mm.managerFor(path)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	return mgr.ReadAndDelete(subPath)
+	return mgr.ReadAndDelete(ctx, subPath)
 }
 
-// Move the file at supplied path to new path.
-func (mm *mountManager) Move(path, newpath Path) error {
+// Move the file at supplied path to new path, delegating to the file system when both are on the same mount. Across
+// mounts, the file is copied natively when the adapter of the source is a CrossCopier sharing the backend of the
+// destination, and streamed otherwise, then deleted.
+func (mm *mountManager) Move(ctx context.Context, path, newpath Path) error {
 	mgr1, subPath1, err := mm.managerFor(path)
 	if err != nil {
 		return err
@@ -176,25 +350,34 @@ func (mm *mountManager) Move(path, newpath Path) error {
 	if err != nil {
 		return err
 	}
-	if &mgr1 == &mgr2 {
-		// The source and target managers are the same
-		return mgr1.Move(subPath1, subPath2)
+	if mm.sameMount(path, newpath) {
+		return mgr1.Move(ctx, subPath1, subPath2)
 	}
-	source, err := mgr1.ReadStream(subPath1)
-	defer source.Close()
+	copied, err := copyAcross(ctx, mgr1, subPath1, mgr2, subPath2)
+	if err != nil {
+		return err
+	}
+	if copied {
+		_, err = mgr1.Delete(ctx, subPath1)
+		return err
+	}
+	source, err := mgr1.ReadStream(ctx, subPath1)
 	if err != nil {
 		return err
 	}
-	err = mgr2.WriteStream(subPath2, source)
+	defer source.Close()
+	err = mgr2.WriteStream(ctx, subPath2, source)
 	if err != nil {
 		return err
 	}
-	_, err = mgr1.Delete(subPath1)
+	_, err = mgr1.Delete(ctx, subPath1)
 	return err
 }
 
-// Copy the file at supplied path to new path.
-func (mm *mountManager) Copy(path, newpath Path) error {
+// Copy the file at supplied path to new path, delegating to the file system when both are on the same mount. Across
+// mounts, the file is copied natively when the adapter of the source is a CrossCopier sharing the backend of the
+// destination, and streamed otherwise.
+func (mm *mountManager) Copy(ctx context.Context, path, newpath Path) error {
 	mgr1, subPath1, err := mm.managerFor(path)
 	if err != nil {
 		return err
@@ -203,94 +386,146 @@ func (mm *mountManager) Copy(path, newpath Path) error {
 	if err != nil {
 		return err
 	}
-	if &mgr1 == &mgr2 {
-		return mgr1.Copy(subPath1, subPath2)
+	if mm.sameMount(path, newpath) {
+		return mgr1.Copy(ctx, subPath1, subPath2)
 	}
-	source, err := mgr1.ReadStream(subPath1)
-	defer source.Close()
+	if copied, err := copyAcross(ctx, mgr1, subPath1, mgr2, subPath2); err != nil || copied {
+		return err
+	}
+	source, err := mgr1.ReadStream(ctx, subPath1)
 	if err != nil {
 		return err
 	}
-	return mgr2.WriteStream(subPath2, source)
+	defer source.Close()
+	return mgr2.WriteStream(ctx, subPath2, source)
 }
 
 // GetMimeType will retrieve the mime type of file at supplied path.
-func (mm *mountManager) GetMimeType(path Path) (string, error) {
+func (mm *mountManager) GetMimeType(ctx context.Context, path Path) (string, error) {
 	mgr, subPath, err := mm.managerFor(path)
 	if err != nil {
 		return "", err
 	}
-	return mgr.GetMimeType(subPath)
+	return mgr.GetMimeType(ctx, subPath)
 }
 
 // GetTimestamp will retrieve the timestamp of file at supplied path.
-func (mm *mountManager) GetTimestamp(path Path) (time.Time, error) {
+func (mm *mountManager) GetTimestamp(ctx context.Context, path Path) (time.Time, error) {
 	mgr, subPath, err := mm.managerFor(path)
 	if err != nil {
 		return time.Now(), err
 	}
-	return mgr.GetTimestamp(subPath)
+	return mgr.GetTimestamp(ctx, subPath)
 }
 
 // GetFileSize will retrieve the size of file at supplied path.
-func (mm *mountManager) GetFileSize(path Path) (int64, error) {
+func (mm *mountManager) GetFileSize(ctx context.Context, path Path) (int64, error) {
 	mgr, subPath, err := mm.managerFor(path)
 	if err != nil {
 		return 0, err
 	}
-	return mgr.GetFileSize(subPath)
+	return mgr.GetFileSize(ctx, subPath)
 }
 
 // GetMetadata will retrieve the metadata of file at supplied path.
-func (mm *mountManager) GetMetadata(path Path) (Metadata, error) {
+func (mm *mountManager) GetMetadata(ctx context.Context, path Path) (Metadata, error) {
 	mgr, subPath, err := mm.managerFor(path)
 	if err != nil {
 		return nil, err
 	}
-	return mgr.GetMetadata(subPath)
+	return mgr.GetMetadata(ctx, subPath)
 }
 
 // CreateDir will create a new directory at provided path.
-func (mm *mountManager) CreateDir(path Path) error {
+func (mm *mountManager) CreateDir(ctx context.Context, path Path, opts ...DirOption) error {
 	mgr, subPath, err := mm.managerFor(path)
 	if err != nil {
 		return err
 	}
-	return mgr.CreateDir(subPath)
+	return mgr.CreateDir(ctx, subPath, opts...)
 }
 
 // DeleteDir will delete the directory at provided path.
-func (mm *mountManager) DeleteDir(path Path) error {
+func (mm *mountManager) DeleteDir(ctx context.Context, path Path) error {
 	mgr, subPath, err := mm.managerFor(path)
 	if err != nil {
 		return err
 	}
-	return mgr.DeleteDir(subPath)
+	return mgr.DeleteDir(ctx, subPath)
 }
 
 // Get the visibility of file at supplied path.
-func (mm *mountManager) GetVisibility(path Path) (Visibility, error) {
+func (mm *mountManager) GetVisibility(ctx context.Context, path Path) (Visibility, error) {
 	mgr, subPath, err := mm.managerFor(path)
 	if err != nil {
-		return 0, err
+		return "", err
 	}
-	return mgr.GetVisibility(subPath)
+	return mgr.GetVisibility(ctx, subPath)
 }
 
 // Set the visibility of file at supplied path.
-func (mm *mountManager) SetVisibility(path Path, v Visibility) error {
+func (mm *mountManager) SetVisibility(ctx context.Context, path Path, v Visibility) error {
 	mgr, subPath, err := mm.managerFor(path)
 	if err != nil {
 		return err
 	}
-	return mgr.SetVisibility(subPath, v)
+	return mgr.SetVisibility(ctx, subPath, v)
 }
 
-// List the contents of given path.
-func (mm *mountManager) ListContents(path Path, recursive bool) ([]Metadata, error) {
+// List the contents of given path. Paths prefixed by AllMounts list the contents of the path on every mount having
+// it, sorted by mount, each entry holding the prefix of its mount in the "mount" extra metadata.
+func (mm *mountManager) ListContents(ctx context.Context, path Path, recursive bool) ([]Metadata, error) {
+	if subPath, ok := allMounts(path); ok {
+		return mm.listAll(ctx, subPath, recursive)
+	}
 	mgr, subPath, err := mm.managerFor(path)
 	if err != nil {
 		return nil, err
 	}
-	return mgr.ListContents(subPath, recursive)
+	return mgr.ListContents(ctx, subPath, recursive)
+}
+
+// allMounts will return the path on every mount addressed by provided path, if prefixed by AllMounts.
+func allMounts(path Path) (Path, bool) {
+	subPath, ok := strings.CutPrefix(string(path), AllMounts+"://")
+	return Path(subPath), ok
+}
+
+// listAll will list the contents of path on every mount concurrently, skipping the mounts not having it.
+func (mm *mountManager) listAll(ctx context.Context, path Path, recursive bool) ([]Metadata, error) {
+	mounts := mm.ListMounts()
+	listings := make([][]Metadata, len(mounts))
+	errs := make([]error, len(mounts))
+	var wg sync.WaitGroup
+	for i, m := range mounts {
+		mgr, err := mm.Mounted(m.Prefix)
+		if err != nil {
+			// Unmounted meanwhile.
+			continue
+		}
+		wg.Add(1)
+		go func(i int, prefix string, mgr Interface) {
+			defer wg.Done()
+			listing, err := mgr.ListContents(ctx, path, recursive)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			for j, md := range listing {
+				md = md.Clone()
+				md.Set("mount", prefix)
+				listing[j] = md
+			}
+			listings[i] = listing
+		}(i, m.Prefix, mgr)
+	}
+	wg.Wait()
+	var all []Metadata
+	for i, listing := range listings {
+		if err := errs[i]; err != nil && !IsFileNotFound(err) {
+			return nil, err
+		}
+		all = append(all, listing...)
+	}
+	return all, nil
 }
@@ -0,0 +1,160 @@
+// Package multipart provides the engine adapters use to upload large streams in parts, several parts being uploaded
+// concurrently, each one retried on its own, and the upload aborted on failure so that no orphan parts are left.
+package multipart
+
+import (
+	"context"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/maurofran/filesystem/retry"
+)
+
+// Defaults of Options.
+const (
+	DefaultPartSize    = 8 << 20
+	DefaultConcurrency = 4
+)
+
+// Part is an uploaded part.
+type Part struct {
+	// Number is the number of the part, starting at 1.
+	Number int
+	// Size is the size of the part.
+	Size int64
+	// ID is the identifier of the part returned by the backend, such as its ETag or checksum.
+	ID string
+}
+
+// Upload is a multipart upload started on a backend, implemented by adapters. UploadPart may be called concurrently.
+type Upload interface {
+	// UploadPart will upload the part with provided number, returning its identifier.
+	UploadPart(ctx context.Context, number int, content []byte) (string, error)
+	// Complete will assemble the uploaded parts, sorted by number, into the file.
+	Complete(ctx context.Context, parts []Part) error
+	// Abort will discard the upload and its uploaded parts.
+	Abort(ctx context.Context) error
+}
+
+// Options configures an upload.
+type Options struct {
+	// PartSize is the size of the parts, DefaultPartSize if not positive; the last part may be smaller.
+	PartSize int64
+	// Concurrency is the number of parts uploaded in parallel, DefaultConcurrency if not positive. Up to
+	// Concurrency+1 parts are held in memory.
+	Concurrency int
+	// Retry is the policy each part upload is retried with.
+	Retry retry.Policy
+}
+
+func (o Options) withDefaults() Options {
+	if o.PartSize <= 0 {
+		o.PartSize = DefaultPartSize
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = DefaultConcurrency
+	}
+	return o
+}
+
+type job struct {
+	number  int
+	content []byte
+}
+
+// Run will upload the content of r through up, in parts, and complete it. If reading r, uploading a part or
+// completing fails, the upload is aborted, even if the context is done, and the first error returned. An empty
+// stream is uploaded as a single empty part.
+func Run(ctx context.Context, up Upload, r io.Reader, opts Options) error {
+	opts = opts.withDefaults()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		mu    sync.Mutex
+		parts []Part
+		first error
+		wg    sync.WaitGroup
+	)
+	fail := func(err error) {
+		mu.Lock()
+		if first == nil {
+			first = err
+			cancel()
+		}
+		mu.Unlock()
+	}
+	// Buffers are allocated on first use and recycled, bounding memory to Concurrency+1 parts.
+	buffers := make(chan []byte, opts.Concurrency+1)
+	for i := 0; i < cap(buffers); i++ {
+		buffers <- nil
+	}
+	jobs := make(chan job)
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				var id string
+				err := opts.Retry.Do(ctx, func() (err error) {
+					id, err = up.UploadPart(ctx, j.number, j.content)
+					return err
+				})
+				if err != nil {
+					fail(err)
+				} else {
+					mu.Lock()
+					parts = append(parts, Part{Number: j.number, Size: int64(len(j.content)), ID: id})
+					mu.Unlock()
+				}
+				buffers <- j.content[:cap(j.content)]
+			}
+		}()
+	}
+	read(ctx, r, opts.PartSize, buffers, jobs, fail)
+	close(jobs)
+	wg.Wait()
+	if first == nil {
+		sort.Slice(parts, func(i, k int) bool { return parts[i].Number < parts[k].Number })
+		if err := up.Complete(ctx, parts); err != nil {
+			first = err
+		}
+	}
+	if first != nil {
+		up.Abort(context.WithoutCancel(ctx))
+	}
+	return first
+}
+
+// read will read r in parts of partSize bytes into the free buffers, handing them to the workers.
+func read(ctx context.Context, r io.Reader, partSize int64, buffers chan []byte, jobs chan<- job, fail func(error)) {
+	for number := 1; ; number++ {
+		var buf []byte
+		select {
+		case buf = <-buffers:
+		case <-ctx.Done():
+			fail(ctx.Err())
+			return
+		}
+		if buf == nil {
+			buf = make([]byte, partSize)
+		}
+		n, err := io.ReadFull(r, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			fail(err)
+			return
+		}
+		if n == 0 && number > 1 {
+			return
+		}
+		select {
+		case jobs <- job{number, buf[:n]}:
+		case <-ctx.Done():
+			fail(ctx.Err())
+			return
+		}
+		if int64(n) < partSize {
+			return
+		}
+	}
+}
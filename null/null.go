@@ -0,0 +1,138 @@
+// Package null provides an adapter discarding every write and reporting every file as absent, for benchmarks and
+// environments where storage is disabled.
+package null
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/maurofran/filesystem"
+)
+
+// Adapter is the null adapter. Writes succeed, streamed content being consumed and discarded, while operations
+// on existing files fail with a FileNotFoundError.
+type Adapter struct{}
+
+func init() {
+	filesystem.RegisterAdapter("null", func(context.Context, map[string]interface{}) (filesystem.Adapter, error) {
+		return New(), nil
+	})
+}
+
+// New will create a new null adapter.
+func New() *Adapter {
+	return &Adapter{}
+}
+
+// Has will always report the file as absent.
+func (a *Adapter) Has(ctx context.Context, path filesystem.Path) (bool, error) {
+	return false, nil
+}
+
+// Read will always fail with a FileNotFoundError.
+func (a *Adapter) Read(ctx context.Context, path filesystem.Path) ([]byte, error) {
+	return nil, filesystem.NewFileNotFoundError(path)
+}
+
+// ReadStream will always fail with a FileNotFoundError.
+func (a *Adapter) ReadStream(ctx context.Context, path filesystem.Path) (io.ReadCloser, error) {
+	return nil, filesystem.NewFileNotFoundError(path)
+}
+
+// Write will discard content.
+func (a *Adapter) Write(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	return nil
+}
+
+// WriteStream will consume and discard the content of reader.
+func (a *Adapter) WriteStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	_, err := io.Copy(io.Discard, r)
+	return err
+}
+
+// Update will always fail with a FileNotFoundError.
+func (a *Adapter) Update(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	return filesystem.NewFileNotFoundError(path)
+}
+
+// UpdateStream will always fail with a FileNotFoundError.
+func (a *Adapter) UpdateStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	return filesystem.NewFileNotFoundError(path)
+}
+
+// Put will discard content.
+func (a *Adapter) Put(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	return nil
+}
+
+// PutStream will consume and discard the content of reader.
+func (a *Adapter) PutStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	_, err := io.Copy(io.Discard, r)
+	return err
+}
+
+// Delete will always fail with a FileNotFoundError.
+func (a *Adapter) Delete(ctx context.Context, path filesystem.Path) error {
+	return filesystem.NewFileNotFoundError(path)
+}
+
+// ReadAndDelete will always fail with a FileNotFoundError.
+func (a *Adapter) ReadAndDelete(ctx context.Context, path filesystem.Path) ([]byte, error) {
+	return nil, filesystem.NewFileNotFoundError(path)
+}
+
+// Move will always fail with a FileNotFoundError.
+func (a *Adapter) Move(ctx context.Context, path, newpath filesystem.Path) error {
+	return filesystem.NewFileNotFoundError(path)
+}
+
+// Copy will always fail with a FileNotFoundError.
+func (a *Adapter) Copy(ctx context.Context, path, newpath filesystem.Path) error {
+	return filesystem.NewFileNotFoundError(path)
+}
+
+// GetMimeType will always fail with a FileNotFoundError.
+func (a *Adapter) GetMimeType(ctx context.Context, path filesystem.Path) (string, error) {
+	return "", filesystem.NewFileNotFoundError(path)
+}
+
+// GetTimestamp will always fail with a FileNotFoundError.
+func (a *Adapter) GetTimestamp(ctx context.Context, path filesystem.Path) (time.Time, error) {
+	return time.Time{}, filesystem.NewFileNotFoundError(path)
+}
+
+// GetFileSize will always fail with a FileNotFoundError.
+func (a *Adapter) GetFileSize(ctx context.Context, path filesystem.Path) (int64, error) {
+	return 0, filesystem.NewFileNotFoundError(path)
+}
+
+// GetMetadata will always fail with a FileNotFoundError.
+func (a *Adapter) GetMetadata(ctx context.Context, path filesystem.Path) (filesystem.Metadata, error) {
+	return nil, filesystem.NewFileNotFoundError(path)
+}
+
+// CreateDir will do nothing.
+func (a *Adapter) CreateDir(ctx context.Context, path filesystem.Path, cfg filesystem.Config) error {
+	return nil
+}
+
+// DeleteDir will do nothing.
+func (a *Adapter) DeleteDir(ctx context.Context, path filesystem.Path) error {
+	return nil
+}
+
+// GetVisibility will always fail with a FileNotFoundError.
+func (a *Adapter) GetVisibility(ctx context.Context, path filesystem.Path) (filesystem.Visibility, error) {
+	return "", filesystem.NewFileNotFoundError(path)
+}
+
+// SetVisibility will always fail with a FileNotFoundError.
+func (a *Adapter) SetVisibility(ctx context.Context, path filesystem.Path, v filesystem.Visibility) error {
+	return filesystem.NewFileNotFoundError(path)
+}
+
+// ListContents will always return an empty listing.
+func (a *Adapter) ListContents(ctx context.Context, path filesystem.Path, recursive bool) ([]filesystem.Metadata, error) {
+	return nil, nil
+}
@@ -0,0 +1,117 @@
+package filesystem
+
+// Config keys set by the typed options, read by adapters.
+const (
+	// ConfigVisibility is the Visibility of written files and created directories.
+	ConfigVisibility = "visibility"
+	// ConfigMimeType is the mime type of written files, overriding detection.
+	ConfigMimeType = "mimetype"
+	// ConfigMetadata is the map[string]string of custom metadata of written files.
+	ConfigMetadata = "metadata"
+	// ConfigSniffMimeType is the bool forcing GetMimeType to sniff content instead of trusting the adapter.
+	ConfigSniffMimeType = "mimetype.sniff"
+)
+
+// NewOption is an option of New, configuring the file system.
+type NewOption interface {
+	applyNew(cfg *Config)
+}
+
+// WriteOption is an option of the operations writing files. Given to New, it sets the default of every write.
+type WriteOption interface {
+	NewOption
+	applyWrite(cfg *Config)
+}
+
+// DirOption is an option of the operations creating directories.
+type DirOption interface {
+	applyDir(cfg *Config)
+}
+
+// Option is an option applying to both file writes and directory creation.
+type Option func(cfg *Config)
+
+func (o Option) applyNew(cfg *Config) {
+	o(cfg)
+}
+
+func (o Option) applyWrite(cfg *Config) {
+	o(cfg)
+}
+
+func (o Option) applyDir(cfg *Config) {
+	o(cfg)
+}
+
+type writeOption func(cfg *Config)
+
+func (o writeOption) applyNew(cfg *Config) {
+	o(cfg)
+}
+
+func (o writeOption) applyWrite(cfg *Config) {
+	o(cfg)
+}
+
+type newOption func(cfg *Config)
+
+func (o newOption) applyNew(cfg *Config) {
+	o(cfg)
+}
+
+// WithVisibility will set the visibility of written files or created directories.
+func WithVisibility(v Visibility) Option {
+	return func(cfg *Config) {
+		cfg.Set(ConfigVisibility, v)
+	}
+}
+
+// WithMimeType will set the mime type of written files.
+func WithMimeType(mimeType string) WriteOption {
+	return writeOption(func(cfg *Config) {
+		cfg.Set(ConfigMimeType, mimeType)
+	})
+}
+
+// WithMetadata will set custom metadata on written files, for adapters supporting it.
+func WithMetadata(metadata map[string]string) WriteOption {
+	return writeOption(func(cfg *Config) {
+		cfg.Set(ConfigMetadata, metadata)
+	})
+}
+
+// WithMimeSniffing will make GetMimeType detect the mime type of files from their content, ignoring the one reported
+// by the adapter.
+func WithMimeSniffing() NewOption {
+	return newOption(func(cfg *Config) {
+		cfg.Set(ConfigSniffMimeType, true)
+	})
+}
+
+// WithSetting will set an adapter specific setting. Adapters should provide typed options built on it.
+func WithSetting(key string, value interface{}) Option {
+	return func(cfg *Config) {
+		cfg.Set(key, value)
+	}
+}
+
+// writeConfig will build the configuration of a write from provided options, falling back to the defaults.
+func (fs *filesystem) writeConfig(opts []WriteOption) Config {
+	cfg := EmptyConfig()
+	cfg.SetFallback(fs.Config())
+	for _, o := range opts {
+		o.applyWrite(cfg)
+	}
+	return *cfg
+}
+
+// dirConfig will build the configuration of a directory creation from provided options, falling back to the
+// defaults.
+func (fs *filesystem) dirConfig(opts []DirOption) Config {
+	cfg := EmptyConfig()
+	cfg.SetFallback(fs.Config())
+	for _, o := range opts {
+		o.applyDir(cfg)
+	}
+	return *cfg
+}
@@ -0,0 +1,109 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// DefaultPageLimit is the number of entries of a page when ListOptions do not set a limit.
+const DefaultPageLimit = 1000
+
+// Page is a page of the listing of a directory.
+type Page struct {
+	// Entries are the entries of the page. Filters may leave a page with less entries than the limit, or none, so
+	// only an empty Cursor marks the last page.
+	Entries []Metadata
+	// Cursor is the cursor of the next page, empty if this is the last one.
+	Cursor string
+}
+
+// PageLister is implemented by adapters able to list a directory one page at a time, resuming from a cursor such as
+// an S3 continuation token. Cursors are opaque to callers and only valid for the same path and recursive flag.
+// Adapters may return an UnsupportedOperationError for the listings they cannot page, e.g. recursive ones, which are
+// then paged by skipping the entries of the previous pages.
+type PageLister interface {
+	ListPage(ctx context.Context, path Path, recursive bool, cursor string, limit int) (Page, error)
+}
+
+// ListPage will list a page of the contents of given path, starting at the cursor of provided options. Filters are
+// applied to the entries of each page.
+func (fs *filesystem) ListPage(ctx context.Context, path Path, opts ListOptions) (Page, error) {
+	path, err := NewPath(string(path))
+	if err != nil {
+		return Page{}, err
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultPageLimit
+	}
+	page, err := fs.listPage(ctx, path, opts, limit)
+	if err != nil || !opts.filters() {
+		return page, err
+	}
+	f := newListFilter(path, opts)
+	entries := page.Entries[:0]
+	for _, md := range page.Entries {
+		if f.keep(md) {
+			entries = append(entries, md)
+		}
+	}
+	page.Entries = entries
+	return page, nil
+}
+
+// listPage will list a page natively when adapter supports it and the listing is unsorted.
+func (fs *filesystem) listPage(ctx context.Context, path Path, opts ListOptions, limit int) (Page, error) {
+	if l, ok := fs.adapter.(PageLister); ok && opts.Sort.Field == Unsorted {
+		page, err := l.ListPage(ctx, path, opts.Recursive, opts.Cursor, limit)
+		if !IsUnsupportedOperationError(err) {
+			return page, err
+		}
+	}
+	return fs.skipPage(ctx, path, opts, limit)
+}
+
+// skipPage will list a page of the contents of given path iterating over them, the cursor being the number of
+// entries of the previous pages.
+func (fs *filesystem) skipPage(ctx context.Context, path Path, opts ListOptions, limit int) (Page, error) {
+	var offset int
+	if opts.Cursor != "" {
+		var err error
+		if offset, err = strconv.Atoi(opts.Cursor); err != nil || offset < 0 {
+			return Page{}, fmt.Errorf("invalid cursor %q listing %s", opts.Cursor, path)
+		}
+	}
+	it, err := fs.iterateSorted(ctx, path, opts.Recursive, opts.Sort, opts.SortBuffer)
+	if err != nil {
+		return Page{}, err
+	}
+	defer closeIterator(it)
+	var page Page
+	for i := 0; ; i++ {
+		md, err := it.Next()
+		if err == io.EOF {
+			return page, nil
+		}
+		if err != nil {
+			return Page{}, err
+		}
+		if i < offset {
+			continue
+		}
+		if len(page.Entries) == limit {
+			page.Cursor = strconv.Itoa(i)
+			return page, nil
+		}
+		page.Entries = append(page.Entries, md)
+	}
+}
+
+// ListPage will list a page of the contents of given path.
+func (mm *mountManager) ListPage(ctx context.Context, path Path, opts ListOptions) (Page, error) {
+	mgr, subPath, err := mm.managerFor(path)
+	if err != nil {
+		return Page{}, err
+	}
+	return mgr.ListPage(ctx, subPath, opts)
+}
@@ -1,7 +1,166 @@
 package filesystem
 
+import (
+	"path"
+	"runtime"
+	"strings"
+)
+
 // Path is the type used to manage a path wihtin the file system.
 type Path string
 
 // RootPath is the root path.
 const RootPath Path = ""
+
+// NewPath will create a path from provided string, normalizing it in posix mode.
+func NewPath(s string) (Path, error) {
+	return NormalizePath(Path(s), NormalizationPosix)
+}
+
+// Join will join provided elements to the path, normalizing the result. A mount prefix, such as "s3://", is kept and
+// ".." elements never go above the root.
+func (p Path) Join(elem ...string) Path {
+	prefix, rest := splitMountPrefix(string(p))
+	return Path(prefix + strings.TrimPrefix(path.Join(append([]string{"/" + rest}, elem...)...), "/"))
+}
+
+// Dir is the directory containing the path, the root path for top level entries.
+func (p Path) Dir() Path {
+	dir, _ := p.Split()
+	return dir
+}
+
+// Base is the last element of the path, or an empty string for root path.
+func (p Path) Base() string {
+	_, file := p.Split()
+	return file
+}
+
+// Ext is the file name extension of the path, including the dot.
+func (p Path) Ext() string {
+	return path.Ext(string(p))
+}
+
+// Split will split the path into its directory and last element. A mount prefix, such as "s3://", is kept in the
+// directory.
+func (p Path) Split() (Path, string) {
+	prefix, rest := splitMountPrefix(string(p))
+	dir, file := path.Split(strings.TrimSuffix(rest, "/"))
+	return Path(prefix + strings.TrimSuffix(dir, "/")), file
+}
+
+// splitMountPrefix will split the mount prefix of p, a scheme or AllMounts followed by "://", from the rest of the
+// path.
+func splitMountPrefix(p string) (string, string) {
+	idx := strings.Index(p, "://")
+	if idx <= 0 {
+		return "", p
+	}
+	for i, c := range p[:idx] {
+		if !('a' <= c && c <= 'z' || i > 0 && ('0' <= c && c <= '9' || strings.ContainsRune("+-.", c)) || p[:idx] == AllMounts) {
+			return "", p
+		}
+	}
+	return p[:idx+3], p[idx+3:]
+}
+
+// IsAbs will check if the path starts at a filesystem root, with a slash or a windows volume.
+func (p Path) IsAbs() bool {
+	return strings.HasPrefix(string(p), "/") || windowsVolume(string(p)) != ""
+}
+
+// Validate will check that the path is normalized and contains no NUL characters. A mount prefix, such as "s3://",
+// is validated apart.
+func (p Path) Validate() error {
+	if strings.ContainsRune(string(p), 0) {
+		return invalidCharactersError(p)
+	}
+	prefix, rest := splitMountPrefix(string(p))
+	normalized, err := NewPath(rest)
+	if err != nil {
+		return err
+	}
+	if Path(prefix)+normalized != p {
+		return pathNotNormalizedError(p)
+	}
+	return nil
+}
+
+// NormalizationMode enumeration.
+type NormalizationMode int
+
+// NormalizationMode values (posix, windows and auto).
+const (
+	// NormalizationPosix treats slashes as the only separator; backslashes are regular characters.
+	NormalizationPosix NormalizationMode = iota + 1
+	// NormalizationWindows treats backslashes as separators and keeps drive letters and UNC shares as volumes.
+	NormalizationWindows
+	// NormalizationAuto uses windows normalization on Windows or when path starts with a volume, posix otherwise.
+	NormalizationAuto
+)
+
+// NormalizePath will clean the path according to mode, resolving "." and ".." segments and removing redundant
+// separators. Paths without a volume are made relative to root, while drive letters ("C:\data" becomes
+// "C:/data") and UNC shares ("\\server\share\x" becomes "//server/share/x") are kept as the root. A PathError is
+// returned if the path escapes its root.
+func NormalizePath(path Path, mode NormalizationMode) (Path, error) {
+	s := string(path)
+	if mode == NormalizationAuto {
+		mode = NormalizationPosix
+		if runtime.GOOS == "windows" || windowsVolume(s) != "" {
+			mode = NormalizationWindows
+		}
+	}
+	var volume string
+	if mode == NormalizationWindows {
+		volume = windowsVolume(s)
+		s = strings.Replace(s[len(volume):], "\\", "/", -1)
+		volume = strings.Replace(volume, "\\", "/", -1)
+	}
+	var segments []string
+	for _, segment := range strings.Split(s, "/") {
+		switch segment {
+		case "", ".":
+		case "..":
+			if len(segments) == 0 {
+				return "", pathOutsideRootError(path)
+			}
+			segments = segments[:len(segments)-1]
+		default:
+			segments = append(segments, segment)
+		}
+	}
+	normalized := strings.Join(segments, "/")
+	if volume != "" {
+		normalized = volume + "/" + normalized
+	}
+	return Path(normalized), nil
+}
+
+// windowsVolume will return the drive letter ("C:") or UNC share ("\\server\share") prefix of path, if any.
+func windowsVolume(s string) string {
+	if len(s) >= 2 && s[1] == ':' && ('a' <= s[0] && s[0] <= 'z' || 'A' <= s[0] && s[0] <= 'Z') {
+		return s[:2]
+	}
+	if len(s) < 3 || !isWindowsSeparator(s[0]) || !isWindowsSeparator(s[1]) || isWindowsSeparator(s[2]) {
+		return ""
+	}
+	// Skip the server name and the share name.
+	n := 2
+	for segment := 0; segment < 2; segment++ {
+		for n < len(s) && !isWindowsSeparator(s[n]) {
+			n++
+		}
+		if segment == 0 {
+			if n == len(s) {
+				return ""
+			}
+			n++
+		}
+	}
+	return s[:n]
+}
+
+func isWindowsSeparator(c byte) bool {
+	return c == '\\' || c == '/'
+}
@@ -0,0 +1,480 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// PathRules are constraints on the paths accepted by an adapter. Zero values are ignored.
+type PathRules struct {
+	// MaxLength is the maximum length of a path, in bytes.
+	MaxLength int
+	// MaxSegmentLength is the maximum length of each path segment, in bytes.
+	MaxSegmentLength int
+	// ForbiddenChars are the characters a path cannot contain.
+	ForbiddenChars string
+	// ReservedNames are the names a segment cannot have, compared case-insensitively and ignoring extensions.
+	ReservedNames []string
+}
+
+// WindowsPathRules are the constraints of paths on Windows file systems.
+var WindowsPathRules = PathRules{
+	MaxLength:        260,
+	MaxSegmentLength: 255,
+	ForbiddenChars:   "<>:\"\\|?*",
+	ReservedNames: []string{
+		"CON", "PRN", "AUX", "NUL",
+		"COM1", "COM2", "COM3", "COM4", "COM5", "COM6", "COM7", "COM8", "COM9",
+		"LPT1", "LPT2", "LPT3", "LPT4", "LPT5", "LPT6", "LPT7", "LPT8", "LPT9",
+	},
+}
+
+// Validate will check provided path against the rules, returning a PathError describing the first violation.
+func (r PathRules) Validate(path Path) error {
+	s := string(path)
+	if r.MaxLength > 0 && len(s) > r.MaxLength {
+		return pathRuleError{path, fmt.Sprintf("exceeds maximum length of %d", r.MaxLength)}
+	}
+	if r.ForbiddenChars != "" {
+		if i := strings.IndexAny(s, r.ForbiddenChars); i >= 0 {
+			return pathRuleError{path, fmt.Sprintf("contains forbidden character %q", s[i])}
+		}
+	}
+	for _, segment := range strings.Split(s, "/") {
+		if r.MaxSegmentLength > 0 && len(segment) > r.MaxSegmentLength {
+			return pathRuleError{path, fmt.Sprintf("has a segment exceeding maximum length of %d", r.MaxSegmentLength)}
+		}
+		name := segment
+		if i := strings.IndexByte(name, '.'); i >= 0 {
+			name = name[:i]
+		}
+		for _, reserved := range r.ReservedNames {
+			if strings.EqualFold(name, reserved) {
+				return pathRuleError{path, "contains reserved name " + segment}
+			}
+		}
+	}
+	return nil
+}
+
+// pathRuleError is the PathError of a path violating a rule, described by violation.
+type pathRuleError struct {
+	path      Path
+	violation string
+}
+
+// Path is the path of error.
+func (e pathRuleError) Path() Path {
+	return e.path
+}
+
+func (e pathRuleError) Error() string {
+	return "Path " + string(e.path) + " " + e.violation
+}
+
+// WithPathRules will decorate provided adapter so that every path is validated against rules before delegation.
+func WithPathRules(adapter Adapter, rules PathRules) Adapter {
+	return &pathRulesAdapter{adapter: adapter, rules: rules}
+}
+
+type pathRulesAdapter struct {
+	adapter Adapter
+	rules   PathRules
+}
+
+func (a *pathRulesAdapter) Has(ctx context.Context, path Path) (bool, error) {
+	if err := a.rules.Validate(path); err != nil {
+		return false, err
+	}
+	return a.adapter.Has(ctx, path)
+}
+
+func (a *pathRulesAdapter) Read(ctx context.Context, path Path) ([]byte, error) {
+	if err := a.rules.Validate(path); err != nil {
+		return nil, err
+	}
+	return a.adapter.Read(ctx, path)
+}
+
+func (a *pathRulesAdapter) ReadStream(ctx context.Context, path Path) (io.ReadCloser, error) {
+	if err := a.rules.Validate(path); err != nil {
+		return nil, err
+	}
+	return a.adapter.ReadStream(ctx, path)
+}
+
+func (a *pathRulesAdapter) Write(ctx context.Context, path Path, content []byte, cfg Config) error {
+	if err := a.rules.Validate(path); err != nil {
+		return err
+	}
+	return a.adapter.Write(ctx, path, content, cfg)
+}
+
+func (a *pathRulesAdapter) WriteStream(ctx context.Context, path Path, r io.Reader, cfg Config) error {
+	if err := a.rules.Validate(path); err != nil {
+		return err
+	}
+	return a.adapter.WriteStream(ctx, path, r, cfg)
+}
+
+func (a *pathRulesAdapter) Update(ctx context.Context, path Path, content []byte, cfg Config) error {
+	if err := a.rules.Validate(path); err != nil {
+		return err
+	}
+	return a.adapter.Update(ctx, path, content, cfg)
+}
+
+func (a *pathRulesAdapter) UpdateStream(ctx context.Context, path Path, r io.Reader, cfg Config) error {
+	if err := a.rules.Validate(path); err != nil {
+		return err
+	}
+	return a.adapter.UpdateStream(ctx, path, r, cfg)
+}
+
+func (a *pathRulesAdapter) Put(ctx context.Context, path Path, content []byte, cfg Config) error {
+	if err := a.rules.Validate(path); err != nil {
+		return err
+	}
+	return a.adapter.Put(ctx, path, content, cfg)
+}
+
+func (a *pathRulesAdapter) PutStream(ctx context.Context, path Path, r io.Reader, cfg Config) error {
+	if err := a.rules.Validate(path); err != nil {
+		return err
+	}
+	return a.adapter.PutStream(ctx, path, r, cfg)
+}
+
+func (a *pathRulesAdapter) Delete(ctx context.Context, path Path) error {
+	if err := a.rules.Validate(path); err != nil {
+		return err
+	}
+	return a.adapter.Delete(ctx, path)
+}
+
+func (a *pathRulesAdapter) ReadAndDelete(ctx context.Context, path Path) ([]byte, error) {
+	if err := a.rules.Validate(path); err != nil {
+		return nil, err
+	}
+	return a.adapter.ReadAndDelete(ctx, path)
+}
+
+func (a *pathRulesAdapter) Move(ctx context.Context, path, newpath Path) error {
+	if err := a.rules.Validate(path); err != nil {
+		return err
+	}
+	if err := a.rules.Validate(newpath); err != nil {
+		return err
+	}
+	return a.adapter.Move(ctx, path, newpath)
+}
+
+func (a *pathRulesAdapter) Copy(ctx context.Context, path, newpath Path) error {
+	if err := a.rules.Validate(path); err != nil {
+		return err
+	}
+	if err := a.rules.Validate(newpath); err != nil {
+		return err
+	}
+	return a.adapter.Copy(ctx, path, newpath)
+}
+
+func (a *pathRulesAdapter) GetMimeType(ctx context.Context, path Path) (string, error) {
+	if err := a.rules.Validate(path); err != nil {
+		return "", err
+	}
+	return a.adapter.GetMimeType(ctx, path)
+}
+
+func (a *pathRulesAdapter) GetTimestamp(ctx context.Context, path Path) (time.Time, error) {
+	if err := a.rules.Validate(path); err != nil {
+		return time.Time{}, err
+	}
+	return a.adapter.GetTimestamp(ctx, path)
+}
+
+func (a *pathRulesAdapter) GetFileSize(ctx context.Context, path Path) (int64, error) {
+	if err := a.rules.Validate(path); err != nil {
+		return 0, err
+	}
+	return a.adapter.GetFileSize(ctx, path)
+}
+
+func (a *pathRulesAdapter) GetMetadata(ctx context.Context, path Path) (Metadata, error) {
+	if err := a.rules.Validate(path); err != nil {
+		return nil, err
+	}
+	return a.adapter.GetMetadata(ctx, path)
+}
+
+func (a *pathRulesAdapter) CreateDir(ctx context.Context, path Path, cfg Config) error {
+	if err := a.rules.Validate(path); err != nil {
+		return err
+	}
+	return a.adapter.CreateDir(ctx, path, cfg)
+}
+
+func (a *pathRulesAdapter) DeleteDir(ctx context.Context, path Path) error {
+	if err := a.rules.Validate(path); err != nil {
+		return err
+	}
+	return a.adapter.DeleteDir(ctx, path)
+}
+
+func (a *pathRulesAdapter) GetVisibility(ctx context.Context, path Path) (Visibility, error) {
+	if err := a.rules.Validate(path); err != nil {
+		return "", err
+	}
+	return a.adapter.GetVisibility(ctx, path)
+}
+
+func (a *pathRulesAdapter) SetVisibility(ctx context.Context, path Path, v Visibility) error {
+	if err := a.rules.Validate(path); err != nil {
+		return err
+	}
+	return a.adapter.SetVisibility(ctx, path, v)
+}
+
+func (a *pathRulesAdapter) ListContents(ctx context.Context, path Path, recursive bool) ([]Metadata, error) {
+	if err := a.rules.Validate(path); err != nil {
+		return nil, err
+	}
+	return a.adapter.ListContents(ctx, path, recursive)
+}
+
+// IterateContents will iterate over the contents lazily when the decorated adapter supports it.
+func (a *pathRulesAdapter) IterateContents(ctx context.Context, path Path, recursive bool) (Iterator, error) {
+	ci, ok := a.adapter.(ContentsIterator)
+	if !ok {
+		listing, err := a.ListContents(ctx, path, recursive)
+		if err != nil {
+			return nil, err
+		}
+		return SliceIterator(listing), nil
+	}
+	if err := a.rules.Validate(path); err != nil {
+		return nil, err
+	}
+	return ci.IterateContents(ctx, path, recursive)
+}
+
+// ReadRange will read part of the file natively when the decorated adapter supports it.
+func (a *pathRulesAdapter) ReadRange(ctx context.Context, path Path, offset, length int64) (io.ReadCloser, error) {
+	r, ok := a.adapter.(RangeReader)
+	if !ok {
+		rc, err := a.ReadStream(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		return SliceStream(rc, offset, length)
+	}
+	if err := a.rules.Validate(path); err != nil {
+		return nil, err
+	}
+	return r.ReadRange(ctx, path, offset, length)
+}
+
+// GetChecksum will return the checksum natively provided by the decorated adapter, if any.
+func (a *pathRulesAdapter) GetChecksum(ctx context.Context, path Path, algo ChecksumAlgo) (string, error) {
+	cp, ok := a.adapter.(ChecksumProvider)
+	if !ok {
+		return "", NewUnsupportedChecksumError(path, algo)
+	}
+	if err := a.rules.Validate(path); err != nil {
+		return "", err
+	}
+	return cp.GetChecksum(ctx, path, algo)
+}
+
+// FileExists will check if a file exists natively when the decorated adapter supports it.
+func (a *pathRulesAdapter) FileExists(ctx context.Context, path Path) (bool, error) {
+	c, ok := a.adapter.(ExistenceChecker)
+	if !ok {
+		return false, NewUnsupportedOperationError(path, "FileExists")
+	}
+	if err := a.rules.Validate(path); err != nil {
+		return false, err
+	}
+	return c.FileExists(ctx, path)
+}
+
+// DirectoryExists will check if a directory exists natively when the decorated adapter supports it.
+func (a *pathRulesAdapter) DirectoryExists(ctx context.Context, path Path) (bool, error) {
+	c, ok := a.adapter.(ExistenceChecker)
+	if !ok {
+		return false, NewUnsupportedOperationError(path, "DirectoryExists")
+	}
+	if err := a.rules.Validate(path); err != nil {
+		return false, err
+	}
+	return c.DirectoryExists(ctx, path)
+}
+
+// GetDirectoryMetadata will describe the directory natively when the decorated adapter supports it.
+func (a *pathRulesAdapter) GetDirectoryMetadata(ctx context.Context, path Path) (DirectoryInfo, error) {
+	dp, ok := a.adapter.(DirectoryMetadataProvider)
+	if !ok {
+		return DirectoryInfo{}, NewUnsupportedOperationError(path, "GetDirectoryMetadata")
+	}
+	if err := a.rules.Validate(path); err != nil {
+		return DirectoryInfo{}, err
+	}
+	return dp.GetDirectoryMetadata(ctx, path)
+}
+
+// ListPage will list a page natively when the decorated adapter supports it.
+func (a *pathRulesAdapter) ListPage(ctx context.Context, path Path, recursive bool, cursor string, limit int) (Page, error) {
+	l, ok := a.adapter.(PageLister)
+	if !ok {
+		return Page{}, NewUnsupportedOperationError(path, "ListPage")
+	}
+	if err := a.rules.Validate(path); err != nil {
+		return Page{}, err
+	}
+	return l.ListPage(ctx, path, recursive, cursor, limit)
+}
+
+// IterateSorted will iterate in order natively when the decorated adapter supports it.
+func (a *pathRulesAdapter) IterateSorted(ctx context.Context, path Path, recursive bool, order Sort) (Iterator, error) {
+	si, ok := a.adapter.(SortedIterator)
+	if !ok {
+		return nil, NewUnsupportedOperationError(path, "IterateSorted")
+	}
+	if err := a.rules.Validate(path); err != nil {
+		return nil, err
+	}
+	return si.IterateSorted(ctx, path, recursive, order)
+}
+
+// GetUserMetadata will return the user metadata from the decorated adapter, if it supports it.
+func (a *pathRulesAdapter) GetUserMetadata(ctx context.Context, path Path) (map[string]string, error) {
+	mp, ok := a.adapter.(UserMetadataProvider)
+	if !ok {
+		return nil, NewUnsupportedOperationError(path, "GetUserMetadata")
+	}
+	if err := a.rules.Validate(path); err != nil {
+		return nil, err
+	}
+	return mp.GetUserMetadata(ctx, path)
+}
+
+// SetUserMetadata will set the user metadata through the decorated adapter, if it supports it.
+func (a *pathRulesAdapter) SetUserMetadata(ctx context.Context, path Path, metadata map[string]string) error {
+	mp, ok := a.adapter.(UserMetadataProvider)
+	if !ok {
+		return NewUnsupportedOperationError(path, "SetUserMetadata")
+	}
+	if err := a.rules.Validate(path); err != nil {
+		return err
+	}
+	return mp.SetUserMetadata(ctx, path, metadata)
+}
+
+// WriteAtomic will write atomically through the decorated adapter, if it has a dedicated mechanism.
+func (a *pathRulesAdapter) WriteAtomic(ctx context.Context, path Path, r io.Reader, cfg Config) error {
+	w, ok := a.adapter.(AtomicWriter)
+	if !ok {
+		return NewUnsupportedOperationError(path, "WriteAtomic")
+	}
+	if err := a.rules.Validate(path); err != nil {
+		return err
+	}
+	return w.WriteAtomic(ctx, path, r, cfg)
+}
+
+// WriteAt will write part of a file in place through the decorated adapter, if it supports it.
+func (a *pathRulesAdapter) WriteAt(ctx context.Context, path Path, p []byte, offset int64) error {
+	w, ok := a.adapter.(RandomWriter)
+	if !ok {
+		return NewUnsupportedOperationError(path, "WriteAt")
+	}
+	if err := a.rules.Validate(path); err != nil {
+		return err
+	}
+	return w.WriteAt(ctx, path, p, offset)
+}
+
+// WriteIf will write conditionally through the decorated adapter, if it supports it.
+func (a *pathRulesAdapter) WriteIf(ctx context.Context, path Path, content []byte, cond Precondition, cfg Config) error {
+	w, ok := a.adapter.(ConditionalWriter)
+	if !ok {
+		return NewUnsupportedOperationError(path, "WriteIf")
+	}
+	if err := a.rules.Validate(path); err != nil {
+		return err
+	}
+	return w.WriteIf(ctx, path, content, cond, cfg)
+}
+
+// DeleteIf will delete conditionally through the decorated adapter, if it supports it.
+func (a *pathRulesAdapter) DeleteIf(ctx context.Context, path Path, cond Precondition) error {
+	w, ok := a.adapter.(ConditionalWriter)
+	if !ok {
+		return NewUnsupportedOperationError(path, "DeleteIf")
+	}
+	if err := a.rules.Validate(path); err != nil {
+		return err
+	}
+	return w.DeleteIf(ctx, path, cond)
+}
+
+// ListVersions will list the versions from the decorated adapter, if it keeps versions.
+func (a *pathRulesAdapter) ListVersions(ctx context.Context, path Path) ([]Metadata, error) {
+	v, ok := a.adapter.(Versioned)
+	if !ok {
+		return nil, NewUnsupportedOperationError(path, "ListVersions")
+	}
+	if err := a.rules.Validate(path); err != nil {
+		return nil, err
+	}
+	return v.ListVersions(ctx, path)
+}
+
+// ReadVersion will read the version from the decorated adapter, if it keeps versions.
+func (a *pathRulesAdapter) ReadVersion(ctx context.Context, path Path, versionID string) (io.ReadCloser, error) {
+	v, ok := a.adapter.(Versioned)
+	if !ok {
+		return nil, NewUnsupportedOperationError(path, "ReadVersion")
+	}
+	if err := a.rules.Validate(path); err != nil {
+		return nil, err
+	}
+	return v.ReadVersion(ctx, path, versionID)
+}
+
+// RestoreVersion will restore the version through the decorated adapter, if it keeps versions.
+func (a *pathRulesAdapter) RestoreVersion(ctx context.Context, path Path, versionID string) error {
+	v, ok := a.adapter.(Versioned)
+	if !ok {
+		return NewUnsupportedOperationError(path, "RestoreVersion")
+	}
+	if err := a.rules.Validate(path); err != nil {
+		return err
+	}
+	return v.RestoreVersion(ctx, path, versionID)
+}
+
+// CopyTo will copy the file to another adapter natively when the decorated adapter supports it.
+func (a *pathRulesAdapter) CopyTo(ctx context.Context, path Path, target Adapter, newpath Path) (bool, error) {
+	cc, ok := a.adapter.(CrossCopier)
+	if !ok {
+		return false, nil
+	}
+	if err := a.rules.Validate(path); err != nil {
+		return false, err
+	}
+	return cc.CopyTo(ctx, path, target, newpath)
+}
+
+// Close will close the decorated adapter.
+func (a *pathRulesAdapter) Close() error {
+	return Close(a.adapter)
+}
+
+// Capabilities will report the capabilities of the decorated adapter.
+func (a *pathRulesAdapter) Capabilities() Capabilities {
+	return CapabilitiesOf(a.adapter)
+}
@@ -0,0 +1,114 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	"github.com/maurofran/filesystem"
+)
+
+// Wrap will decorate provided adapter so that content written and read flows through provided pipeline.
+func Wrap(a filesystem.Adapter, p *Pipeline) filesystem.Adapter {
+	return &adapter{Adapter: a, pipeline: p}
+}
+
+type adapter struct {
+	filesystem.Adapter
+	pipeline *Pipeline
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+func (a *adapter) process(direction Direction, path filesystem.Path, content []byte) ([]byte, error) {
+	r, err := a.pipeline.Run(direction, path, bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func (a *adapter) Read(ctx context.Context, path filesystem.Path) ([]byte, error) {
+	content, err := a.Adapter.Read(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return a.process(DirectionRead, path, content)
+}
+
+func (a *adapter) ReadStream(ctx context.Context, path filesystem.Path) (io.ReadCloser, error) {
+	rc, err := a.Adapter.ReadStream(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := a.pipeline.Run(DirectionRead, path, rc)
+	if err != nil {
+		rc.Close()
+		return nil, err
+	}
+	return readCloser{r, rc}, nil
+}
+
+func (a *adapter) ReadAndDelete(ctx context.Context, path filesystem.Path) ([]byte, error) {
+	content, err := a.Adapter.ReadAndDelete(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return a.process(DirectionRead, path, content)
+}
+
+func (a *adapter) Write(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	content, err := a.process(DirectionWrite, path, content)
+	if err != nil {
+		return err
+	}
+	return a.Adapter.Write(ctx, path, content, cfg)
+}
+
+func (a *adapter) WriteStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	r, err := a.pipeline.Run(DirectionWrite, path, r)
+	if err != nil {
+		return err
+	}
+	return a.Adapter.WriteStream(ctx, path, r, cfg)
+}
+
+func (a *adapter) Update(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	content, err := a.process(DirectionWrite, path, content)
+	if err != nil {
+		return err
+	}
+	return a.Adapter.Update(ctx, path, content, cfg)
+}
+
+func (a *adapter) UpdateStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	r, err := a.pipeline.Run(DirectionWrite, path, r)
+	if err != nil {
+		return err
+	}
+	return a.Adapter.UpdateStream(ctx, path, r, cfg)
+}
+
+func (a *adapter) Put(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	content, err := a.process(DirectionWrite, path, content)
+	if err != nil {
+		return err
+	}
+	return a.Adapter.Put(ctx, path, content, cfg)
+}
+
+func (a *adapter) PutStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	r, err := a.pipeline.Run(DirectionWrite, path, r)
+	if err != nil {
+		return err
+	}
+	return a.Adapter.PutStream(ctx, path, r, cfg)
+}
+
+// Close will close the decorated adapter.
+func (a *adapter) Close() error {
+	return filesystem.Close(a.Adapter)
+}
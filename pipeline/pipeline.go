@@ -0,0 +1,79 @@
+package pipeline
+
+import (
+	"io"
+
+	"github.com/maurofran/filesystem"
+	"github.com/maurofran/filesystem/internal/glob"
+)
+
+// Processor is the interface implemented by pipeline stages.
+type Processor interface {
+	// Process will return a reader providing the transformed content of file at provided path.
+	Process(path filesystem.Path, r io.Reader) (io.Reader, error)
+}
+
+// ProcessorFunc is a function acting as a processor.
+type ProcessorFunc func(path filesystem.Path, r io.Reader) (io.Reader, error)
+
+// Process will invoke the function.
+func (f ProcessorFunc) Process(path filesystem.Path, r io.Reader) (io.Reader, error) {
+	return f(path, r)
+}
+
+// Direction is the direction of content flowing through a pipeline.
+type Direction int
+
+// Direction values (write and read).
+const (
+	DirectionWrite Direction = iota + 1
+	DirectionRead
+)
+
+type stage struct {
+	direction Direction
+	pattern   string
+	processor Processor
+}
+
+// Pipeline is an ordered chain of processors, each applied to files matching a path pattern.
+type Pipeline struct {
+	stages []stage
+}
+
+// New will create a new empty pipeline.
+func New() *Pipeline {
+	return &Pipeline{}
+}
+
+// OnWrite will register a processor applied to content written to files matching provided pattern.
+func (p *Pipeline) OnWrite(pattern string, processor Processor) error {
+	return p.add(DirectionWrite, pattern, processor)
+}
+
+// OnRead will register a processor applied to content read from files matching provided pattern.
+func (p *Pipeline) OnRead(pattern string, processor Processor) error {
+	return p.add(DirectionRead, pattern, processor)
+}
+
+func (p *Pipeline) add(direction Direction, pattern string, processor Processor) error {
+	if err := glob.Validate(pattern); err != nil {
+		return err
+	}
+	p.stages = append(p.stages, stage{direction, pattern, processor})
+	return nil
+}
+
+// Run will chain all the processors registered for provided direction and path, in registration order.
+func (p *Pipeline) Run(direction Direction, path filesystem.Path, r io.Reader) (io.Reader, error) {
+	for _, s := range p.stages {
+		if s.direction != direction || !glob.Match(s.pattern, string(path)) {
+			continue
+		}
+		var err error
+		if r, err = s.processor.Process(path, r); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
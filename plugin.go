@@ -1,5 +1,10 @@
 package filesystem
 
+import (
+	"context"
+	"sync"
+)
+
 // Plugin is the interface implemented by plugins.
 type Plugin interface {
 	// The method name exposed by plugin.
@@ -7,34 +12,221 @@ type Plugin interface {
 	// Set the manager on plugin.
 	SetFileSystem(Interface)
 	// Handle the method invocation.
-	Handle(args ...interface{}) (interface{}, error)
+	Handle(ctx context.Context, args ...interface{}) (interface{}, error)
+}
+
+// PluginBinder is implemented by plugins able to return a copy of themselves operating on a file system, so that
+// invocations on different file systems, or concurrent ones, do not share the file system set on the plugin.
+type PluginBinder interface {
+	Bind(fs Interface) Plugin
 }
 
-// Pluggable is a base struct for pluggable behavior.
+// Pluggable is a base struct for pluggable behavior. It is safe for concurrent use.
 type Pluggable struct {
+	mu      sync.RWMutex
 	plugins map[string]Plugin
+	// locks serialize the invocations of the plugins that are not PluginBinder, which share their file system.
+	locks map[string]*sync.Mutex
 }
 
 // AddPlugin will add a plugin to pluggable
 func (p *Pluggable) AddPlugin(plugin Plugin) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.plugins == nil {
+		p.plugins = make(map[string]Plugin)
+	}
 	p.plugins[plugin.Method()] = plugin
 }
 
 // FindPlugin will find a plugin for given method.
 func (p *Pluggable) FindPlugin(method string) (Plugin, error) {
+	p.mu.RLock()
 	plugin, ok := p.plugins[method]
+	p.mu.RUnlock()
 	if !ok {
 		return nil, pluginNotFoundError(method)
 	}
 	return plugin, nil
 }
 
-// InvokePlugin will invoke the plugin on provided manager.
-func (p *Pluggable) InvokePlugin(filesystem Interface, method string, args ...interface{}) (interface{}, error) {
+// InvokePlugin will invoke the plugin on provided manager. PluginBinder plugins are invoked on a copy bound to the
+// manager; the invocations of other plugins are serialized, each setting the manager before handling.
+func (p *Pluggable) InvokePlugin(ctx context.Context, filesystem Interface, method string, args ...interface{}) (interface{}, error) {
 	plugin, err := p.FindPlugin(method)
 	if err != nil {
 		return nil, err
 	}
+	if b, ok := plugin.(PluginBinder); ok {
+		return b.Bind(filesystem).Handle(ctx, args...)
+	}
+	lock := p.lock(method)
+	lock.Lock()
+	defer lock.Unlock()
 	plugin.SetFileSystem(filesystem)
-	return plugin.Handle(args...)
+	return plugin.Handle(ctx, args...)
+}
+
+// lock will return the lock serializing the invocations of the plugin handling method.
+func (p *Pluggable) lock(method string) *sync.Mutex {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.locks == nil {
+		p.locks = make(map[string]*sync.Mutex)
+	}
+	l, ok := p.locks[method]
+	if !ok {
+		l = new(sync.Mutex)
+		p.locks[method] = l
+	}
+	return l
+}
+
+// pluginInvoker is implemented by the file systems invoking plugins on behalf of another file system, self, which
+// the plugins operate on: decorators invoke the plugins of the file system they decorate on themselves, so that
+// the operations of plugins are decorated too.
+type pluginInvoker interface {
+	invoke(ctx context.Context, self Interface, method string, args []interface{}) (interface{}, error)
+}
+
+// invokePlugin will invoke the plugin handling method of fs, operating on self.
+func invokePlugin(ctx context.Context, fs, self Interface, method string, args []interface{}) (interface{}, error) {
+	if inv, ok := fs.(pluginInvoker); ok {
+		return inv.invoke(ctx, self, method, args)
+	}
+	return fs.Invoke(ctx, method, args...)
+}
+
+// Invoke will invoke the plugin handling method with provided arguments.
+func (fs *filesystem) Invoke(ctx context.Context, method string, args ...interface{}) (interface{}, error) {
+	return fs.InvokePlugin(ctx, fs, method, args...)
+}
+
+func (fs *filesystem) invoke(ctx context.Context, self Interface, method string, args []interface{}) (interface{}, error) {
+	return fs.InvokePlugin(ctx, self, method, args...)
+}
+
+// Invoke will invoke the plugin handling method of the decorated file system, the operations of the plugin going
+// through the middlewares.
+func (m *middlewareFS) Invoke(ctx context.Context, method string, args ...interface{}) (interface{}, error) {
+	return invokePlugin(ctx, m.Interface, m, method, args)
+}
+
+func (m *middlewareFS) invoke(ctx context.Context, self Interface, method string, args []interface{}) (interface{}, error) {
+	return invokePlugin(ctx, m.Interface, self, method, args)
+}
+
+// Invoke will invoke the plugin handling method on the mount of the path arguments, whose paths are handed to the
+// plugin relative to the mount. Arguments with no path invoke the plugin of the default mount.
+func (mm *mountManager) Invoke(ctx context.Context, method string, args ...interface{}) (interface{}, error) {
+	prefix, routed := "", make([]interface{}, len(args))
+	for i, arg := range args {
+		routed[i] = arg
+		path, ok := arg.(Path)
+		if !ok {
+			continue
+		}
+		p, subPath, err := mm.resolve(path)
+		if err != nil {
+			return nil, err
+		}
+		if prefix != "" && p != prefix {
+			return nil, pluginRoutingError(method)
+		}
+		prefix, routed[i] = p, subPath
+	}
+	if prefix == "" {
+		mm.mu.RLock()
+		prefix = mm.canonical(mm.fallback)
+		mm.mu.RUnlock()
+		if prefix == "" {
+			return nil, pluginRoutingError(method)
+		}
+	}
+	mgr, err := mm.Mounted(prefix)
+	if err != nil {
+		return nil, err
+	}
+	return mgr.Invoke(ctx, method, routed...)
+}
+
+// PluginFunc is the function of a typed plugin, handling a request of type Req on the file system it is invoked on.
+type PluginFunc[Req, Resp any] func(ctx context.Context, fs Interface, req Req) (Resp, error)
+
+// pluginHost is implemented by the file systems accepting plugins.
+type pluginHost interface {
+	AddPlugin(plugin Plugin)
+	FindPlugin(method string) (Plugin, error)
+}
+
+// typedPlugin is a Plugin calling a PluginFunc, handed the request as single argument.
+type typedPlugin[Req, Resp any] struct {
+	method string
+	fn     PluginFunc[Req, Resp]
+	fs     Interface
+}
+
+func (p *typedPlugin[Req, Resp]) Method() string {
+	return p.method
+}
+
+func (p *typedPlugin[Req, Resp]) SetFileSystem(fs Interface) {
+	p.fs = fs
+}
+
+// Bind will return a copy of the plugin operating on fs.
+func (p *typedPlugin[Req, Resp]) Bind(fs Interface) Plugin {
+	return &typedPlugin[Req, Resp]{method: p.method, fn: p.fn, fs: fs}
+}
+
+func (p *typedPlugin[Req, Resp]) Handle(ctx context.Context, args ...interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, pluginTypeError(p.method)
+	}
+	req, ok := args[0].(Req)
+	if !ok {
+		return nil, pluginTypeError(p.method)
+	}
+	return p.fn(ctx, p.fs, req)
+}
+
+// RegisterPlugin will add to provided file system a plugin handling method with fn, so that it is invoked with
+// CallPlugin without type assertions. It is also invoked by InvokePlugin, with the request as single argument. It
+// fails with a PluginError if the file system does not accept plugins.
+func RegisterPlugin[Req, Resp any](fs Interface, method string, fn PluginFunc[Req, Resp]) error {
+	host, ok := fs.(pluginHost)
+	if !ok {
+		return pluginsUnsupportedError(method)
+	}
+	host.AddPlugin(&typedPlugin[Req, Resp]{method: method, fn: fn})
+	return nil
+}
+
+// CallPlugin will invoke the plugin handling method on provided file system with a typed request, returning its
+// typed response. Plugins added with RegisterPlugin to fs are called directly; otherwise the request is handed to
+// Invoke as single argument, so that a mount manager routes requests of type Path. It fails with a PluginError if no
+// plugin handles method, or if the plugin does not handle the request and response types.
+func CallPlugin[Req, Resp any](ctx context.Context, fs Interface, method string, req Req) (Resp, error) {
+	var zero Resp
+	if host, ok := fs.(pluginHost); ok {
+		plugin, err := host.FindPlugin(method)
+		if err != nil {
+			return zero, err
+		}
+		if p, ok := plugin.(*typedPlugin[Req, Resp]); ok {
+			return p.fn(ctx, fs, req)
+		}
+	}
+	v, err := fs.Invoke(ctx, method, req)
+	if err != nil {
+		return zero, err
+	}
+	if v == nil {
+		return zero, nil
+	}
+	resp, ok := v.(Resp)
+	if !ok {
+		return zero, pluginTypeError(method)
+	}
+	return resp, nil
 }
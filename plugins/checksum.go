@@ -0,0 +1,89 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+
+	"github.com/maurofran/filesystem"
+)
+
+// Md5 is the plugin returning the hex encoded MD5 checksum of a file, see filesystem.Interface.GetChecksum. It is
+// invoked with the path of the file.
+type Md5 struct {
+	plugin
+}
+
+// Method is the name of the method to be used to invoke the plugin.
+func (p *Md5) Method() string {
+	return "Md5"
+}
+
+// Bind will return a copy of the plugin operating on fs.
+func (p *Md5) Bind(fs filesystem.Interface) filesystem.Plugin {
+	c := *p
+	c.fs = fs
+	return &c
+}
+
+// Handle the invocation of plugin.
+func (p *Md5) Handle(ctx context.Context, args ...interface{}) (interface{}, error) {
+	return checksum(ctx, p.fs, args, filesystem.ChecksumMD5)
+}
+
+// Sha256 is the plugin returning the hex encoded SHA-256 checksum of a file. It is invoked with the path of the file.
+type Sha256 struct {
+	plugin
+}
+
+// Method is the name of the method to be used to invoke the plugin.
+func (p *Sha256) Method() string {
+	return "Sha256"
+}
+
+// Bind will return a copy of the plugin operating on fs.
+func (p *Sha256) Bind(fs filesystem.Interface) filesystem.Plugin {
+	c := *p
+	c.fs = fs
+	return &c
+}
+
+// Handle the invocation of plugin.
+func (p *Sha256) Handle(ctx context.Context, args ...interface{}) (interface{}, error) {
+	return checksum(ctx, p.fs, args, filesystem.ChecksumSHA256)
+}
+
+// Crc32c is the plugin returning the CRC32C checksum of a file, as the big endian hex of its 4 bytes. It is invoked
+// with the path of the file.
+type Crc32c struct {
+	plugin
+}
+
+// Method is the name of the method to be used to invoke the plugin.
+func (p *Crc32c) Method() string {
+	return "Crc32c"
+}
+
+// Bind will return a copy of the plugin operating on fs.
+func (p *Crc32c) Bind(fs filesystem.Interface) filesystem.Plugin {
+	c := *p
+	c.fs = fs
+	return &c
+}
+
+// Handle the invocation of plugin.
+func (p *Crc32c) Handle(ctx context.Context, args ...interface{}) (interface{}, error) {
+	return checksum(ctx, p.fs, args, filesystem.ChecksumCRC32C)
+}
+
+// checksum will return the checksum of the file of provided path argument, natively when the adapter provides it
+// and streaming the content of the file otherwise.
+func checksum(ctx context.Context, fs filesystem.Interface, args []interface{}, algo filesystem.ChecksumAlgo) (string, error) {
+	if len(args) != 1 {
+		return "", errors.New("path argument is required")
+	}
+	path, ok := args[0].(filesystem.Path)
+	if !ok {
+		return "", errors.New("path must be an instance of filesystem.Path")
+	}
+	return fs.GetChecksum(ctx, path, algo)
+}
@@ -1,6 +1,9 @@
 package plugins
 
-import "errors"
+import (
+	"context"
+	"errors"
+)
 import "github.com/maurofran/filesystem"
 
 // EmptyDir is the plugin that will remove directory content.
@@ -13,8 +16,15 @@ func (p *EmptyDir) Method() string {
 	return "EmptyDir"
 }
 
+// Bind will return a copy of the plugin operating on fs.
+func (p *EmptyDir) Bind(fs filesystem.Interface) filesystem.Plugin {
+	c := *p
+	c.fs = fs
+	return &c
+}
+
 // Handle the invocation of empty dirs
-func (p *EmptyDir) Handle(args ...interface{}) (interface{}, error) {
+func (p *EmptyDir) Handle(ctx context.Context, args ...interface{}) (interface{}, error) {
 	if len(args) != 1 {
 		return nil, errors.New("Missing dirname argument")
 	}
@@ -22,18 +32,18 @@ func (p *EmptyDir) Handle(args ...interface{}) (interface{}, error) {
 	if !ok {
 		return nil, errors.New("Invalid dirname argument: filesystem.Path required")
 	}
-	listing, err := p.fs.ListContents(dirname, false)
+	listing, err := p.fs.ListContents(ctx, dirname, false)
 	if err != nil {
 		return nil, err
 	}
 	for _, item := range listing {
-		itemPath := item["path"].(filesystem.Path)
-		if item["type"] == "dir" {
-			if err := p.fs.DeleteDir(itemPath); err != nil {
+		itemPath := item.Path
+		if item.IsDir() {
+			if err := p.fs.DeleteDir(ctx, itemPath); err != nil {
 				return nil, err
 			}
 		} else {
-			_, err := p.fs.Delete(itemPath)
+			_, err := p.fs.Delete(ctx, itemPath)
 			if err != nil {
 				return nil, err
 			}
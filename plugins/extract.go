@@ -0,0 +1,252 @@
+package plugins
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/maurofran/filesystem"
+)
+
+// Collision is the policy applied when an archive entry is extracted over an existing file.
+type Collision int
+
+const (
+	// CollisionOverwrite replaces the existing file.
+	CollisionOverwrite Collision = iota
+	// CollisionSkip keeps the existing file, leaving the entry out.
+	CollisionSkip
+	// CollisionFail stops the extraction with an ExistsError.
+	CollisionFail
+	// CollisionRename extracts the entry next to the existing file, numbering its name as in "name (1).ext".
+	CollisionRename
+)
+
+// ExtractOptions are the options of archive extraction.
+type ExtractOptions struct {
+	// Collision is the policy applied to the entries whose file already exists, overwriting it by default.
+	Collision Collision
+}
+
+// ExistsError is the error returned when extracting an entry over an existing file with CollisionFail policy.
+type ExistsError interface {
+	error
+	Path() filesystem.Path
+}
+
+type existsError struct {
+	path filesystem.Path
+}
+
+// Path is the path of the existing file.
+func (e existsError) Path() filesystem.Path {
+	return e.path
+}
+
+func (e existsError) Error() string {
+	return fmt.Sprintf("Unable to extract %s: file already exists", e.path)
+}
+
+// IsExistsError will check if provided error is an exists error.
+func IsExistsError(err error) bool {
+	_, ok := err.(ExistsError)
+	return ok
+}
+
+// ExtractArchive is the plugin extracting a zip, tar or tar.gz archive of the file system under a target directory,
+// the format being told by the extension of the archive. It is invoked with the path of the archive, the path of the
+// target directory and, optionally, the ExtractOptions, returning the []filesystem.Path of the extracted files.
+// Entries escaping the target directory are refused with a filesystem.PathError; entries other than regular files
+// and directories, such as links, are skipped.
+type ExtractArchive struct {
+	plugin
+}
+
+// Method is the name of the method to be used to invoke the plugin.
+func (p *ExtractArchive) Method() string {
+	return "ExtractArchive"
+}
+
+// Bind will return a copy of the plugin operating on fs.
+func (p *ExtractArchive) Bind(fs filesystem.Interface) filesystem.Plugin {
+	c := *p
+	c.fs = fs
+	return &c
+}
+
+// Handle the invocation of plugin.
+func (p *ExtractArchive) Handle(ctx context.Context, args ...interface{}) (interface{}, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return nil, errors.New("archive and target arguments are required")
+	}
+	archive, ok := args[0].(filesystem.Path)
+	if !ok {
+		return nil, errors.New("archive must be an instance of filesystem.Path")
+	}
+	target, ok := args[1].(filesystem.Path)
+	if !ok {
+		return nil, errors.New("target must be an instance of filesystem.Path")
+	}
+	var opts ExtractOptions
+	if len(args) == 3 {
+		if opts, ok = args[2].(ExtractOptions); !ok {
+			return nil, errors.New("options must be an instance of plugins.ExtractOptions")
+		}
+	}
+	x := &extractor{fs: p.fs, target: target, opts: opts}
+	name := strings.ToLower(string(archive))
+	var err error
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		err = x.zip(ctx, archive)
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		err = x.tar(ctx, archive, true)
+	case strings.HasSuffix(name, ".tar"):
+		err = x.tar(ctx, archive, false)
+	default:
+		return nil, fmt.Errorf("unsupported archive format of %s", archive)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return x.extracted, nil
+}
+
+// extractor writes the entries of an archive under the target directory.
+type extractor struct {
+	fs        filesystem.Interface
+	target    filesystem.Path
+	opts      ExtractOptions
+	extracted []filesystem.Path
+}
+
+// zip will extract the zip archive at path, spooling it to a temporary file for random access.
+func (x *extractor) zip(ctx context.Context, path filesystem.Path) error {
+	rc, err := x.fs.ReadStream(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	f, err := os.CreateTemp("", "extract-*.zip")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	size, err := io.Copy(f, rc)
+	if err != nil {
+		return err
+	}
+	zr, err := zip.NewReader(f, size)
+	if err != nil {
+		return err
+	}
+	for _, e := range zr.File {
+		mode := e.Mode()
+		if !mode.IsDir() && !mode.IsRegular() {
+			continue
+		}
+		if err := x.entry(ctx, e.Name, mode, func() (io.ReadCloser, error) { return e.Open() }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tar will extract the tar archive at path, gzip compressed if compressed is set.
+func (x *extractor) tar(ctx context.Context, path filesystem.Path, compressed bool) error {
+	rc, err := x.fs.ReadStream(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	var r io.Reader = rc
+	if compressed {
+		gz, err := gzip.NewReader(rc)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeDir && hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := x.entry(ctx, hdr.Name, hdr.FileInfo().Mode(), func() (io.ReadCloser, error) { return io.NopCloser(tr), nil }); err != nil {
+			return err
+		}
+	}
+}
+
+// entry will extract the entry with provided name and mode, reading the content of files from open.
+func (x *extractor) entry(ctx context.Context, name string, mode os.FileMode, open func() (io.ReadCloser, error)) error {
+	rel, err := filesystem.NewPath(strings.Replace(name, "\\", "/", -1))
+	if err != nil {
+		return err
+	}
+	if rel == filesystem.RootPath {
+		return nil
+	}
+	path := x.target.Join(string(rel))
+	visibility := filesystem.WithVisibility(filesystem.VisibilityPrivate)
+	if mode.Perm()&0004 != 0 {
+		visibility = filesystem.WithVisibility(filesystem.VisibilityPublic)
+	}
+	if mode.IsDir() {
+		return x.fs.CreateDir(ctx, path, visibility)
+	}
+	exists, err := x.fs.Has(ctx, path)
+	if err != nil {
+		return err
+	}
+	if exists {
+		switch x.opts.Collision {
+		case CollisionSkip:
+			return nil
+		case CollisionFail:
+			return existsError{path}
+		case CollisionRename:
+			if path, err = x.rename(ctx, path); err != nil {
+				return err
+			}
+		}
+	}
+	rc, err := open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if err := x.fs.PutStream(ctx, path, rc, visibility); err != nil {
+		return err
+	}
+	x.extracted = append(x.extracted, path)
+	return nil
+}
+
+// rename will return the first numbered variant of path that does not exist.
+func (x *extractor) rename(ctx context.Context, path filesystem.Path) (filesystem.Path, error) {
+	ext := path.Ext()
+	stem := strings.TrimSuffix(path.Base(), ext)
+	for i := 1; ; i++ {
+		candidate := path.Dir().Join(fmt.Sprintf("%s (%d)%s", stem, i, ext))
+		exists, err := x.fs.Has(ctx, candidate)
+		if err != nil || !exists {
+			return candidate, err
+		}
+	}
+}
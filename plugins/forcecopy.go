@@ -1,6 +1,7 @@
 package plugins
 
 import (
+	"context"
 	"errors"
 
 	"github.com/maurofran/filesystem"
@@ -16,8 +17,15 @@ func (p *ForceCopy) Method() string {
 	return "ForceCopy"
 }
 
+// Bind will return a copy of the plugin operating on fs.
+func (p *ForceCopy) Bind(fs filesystem.Interface) filesystem.Plugin {
+	c := *p
+	c.fs = fs
+	return &c
+}
+
 // Handle the invocation of plugin.
-func (p *ForceCopy) Handle(args ...interface{}) (interface{}, error) {
+func (p *ForceCopy) Handle(ctx context.Context, args ...interface{}) (interface{}, error) {
 	if len(args) != 2 {
 		return false, errors.New("path and newpath arguments are required")
 	}
@@ -29,7 +37,7 @@ func (p *ForceCopy) Handle(args ...interface{}) (interface{}, error) {
 	if !ok {
 		return false, errors.New("newPath must be an instance of filesystem.Path")
 	}
-	deleted, err := p.fs.Delete(newPath)
+	deleted, err := p.fs.Delete(ctx, newPath)
 	if err != nil {
 		if filesystem.IsFileNotFound(err) {
 			deleted = true
@@ -38,7 +46,7 @@ func (p *ForceCopy) Handle(args ...interface{}) (interface{}, error) {
 		}
 	}
 	if deleted {
-		err := p.fs.Copy(path, newPath)
+		err := p.fs.Copy(ctx, path, newPath)
 		return true, err
 	}
 	return false, nil
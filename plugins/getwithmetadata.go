@@ -0,0 +1,38 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+
+	"github.com/maurofran/filesystem"
+)
+
+// GetWithMetadata is the plugin reading a file along with its metadata, see filesystem.ReadWithMetadata. It is
+// invoked with the path of the file, returning a filesystem.Metadata whose Content holds the content of the file.
+type GetWithMetadata struct {
+	plugin
+}
+
+// Method is the name of the method to be used to invoke the plugin.
+func (p *GetWithMetadata) Method() string {
+	return "GetWithMetadata"
+}
+
+// Bind will return a copy of the plugin operating on fs.
+func (p *GetWithMetadata) Bind(fs filesystem.Interface) filesystem.Plugin {
+	c := *p
+	c.fs = fs
+	return &c
+}
+
+// Handle the invocation of plugin.
+func (p *GetWithMetadata) Handle(ctx context.Context, args ...interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, errors.New("path argument is required")
+	}
+	path, ok := args[0].(filesystem.Path)
+	if !ok {
+		return nil, errors.New("path must be an instance of filesystem.Path")
+	}
+	return filesystem.ReadWithMetadata(ctx, p.fs, path)
+}
@@ -0,0 +1,120 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+
+	"github.com/maurofran/filesystem"
+)
+
+// ListFiles is the plugin listing the files of a directory, leaving out its subdirectories. It is invoked with the
+// path of the directory and, optionally, whether to list nested directories, returning a []filesystem.Metadata.
+type ListFiles struct {
+	plugin
+}
+
+// Method is the name of the method to be used to invoke the plugin.
+func (p *ListFiles) Method() string {
+	return "ListFiles"
+}
+
+// Bind will return a copy of the plugin operating on fs.
+func (p *ListFiles) Bind(fs filesystem.Interface) filesystem.Plugin {
+	c := *p
+	c.fs = fs
+	return &c
+}
+
+// Handle the invocation of plugin.
+func (p *ListFiles) Handle(ctx context.Context, args ...interface{}) (interface{}, error) {
+	return list(ctx, p.fs, args, func(md filesystem.Metadata) bool {
+		return !md.IsDir()
+	})
+}
+
+// ListDirectories is the plugin listing the subdirectories of a directory. It is invoked with the path of the
+// directory and, optionally, whether to list nested directories, returning a []filesystem.Metadata.
+type ListDirectories struct {
+	plugin
+}
+
+// Method is the name of the method to be used to invoke the plugin.
+func (p *ListDirectories) Method() string {
+	return "ListDirectories"
+}
+
+// Bind will return a copy of the plugin operating on fs.
+func (p *ListDirectories) Bind(fs filesystem.Interface) filesystem.Plugin {
+	c := *p
+	c.fs = fs
+	return &c
+}
+
+// Handle the invocation of plugin.
+func (p *ListDirectories) Handle(ctx context.Context, args ...interface{}) (interface{}, error) {
+	return list(ctx, p.fs, args, func(md filesystem.Metadata) bool {
+		return md.IsDir()
+	})
+}
+
+// ListPaths is the plugin listing the paths of the entries of a directory, files and subdirectories. It is invoked
+// with the path of the directory and, optionally, whether to list nested directories, returning a
+// []filesystem.Path.
+type ListPaths struct {
+	plugin
+}
+
+// Method is the name of the method to be used to invoke the plugin.
+func (p *ListPaths) Method() string {
+	return "ListPaths"
+}
+
+// Bind will return a copy of the plugin operating on fs.
+func (p *ListPaths) Bind(fs filesystem.Interface) filesystem.Plugin {
+	c := *p
+	c.fs = fs
+	return &c
+}
+
+// Handle the invocation of plugin.
+func (p *ListPaths) Handle(ctx context.Context, args ...interface{}) (interface{}, error) {
+	listing, err := list(ctx, p.fs, args, func(md filesystem.Metadata) bool {
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]filesystem.Path, len(listing))
+	for i, md := range listing {
+		paths[i] = md.Path
+	}
+	return paths, nil
+}
+
+// list will list the directory of provided path and recursive arguments, keeping the entries accepted by keep.
+func list(ctx context.Context, fs filesystem.Interface, args []interface{}, keep func(md filesystem.Metadata) bool) ([]filesystem.Metadata, error) {
+	if len(args) != 1 && len(args) != 2 {
+		return nil, errors.New("path argument is required")
+	}
+	path, ok := args[0].(filesystem.Path)
+	if !ok {
+		return nil, errors.New("path must be an instance of filesystem.Path")
+	}
+	recursive := false
+	if len(args) == 2 {
+		if recursive, ok = args[1].(bool); !ok {
+			return nil, errors.New("recursive must be a bool")
+		}
+	}
+	listing, err := fs.ListContents(ctx, path, recursive)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]filesystem.Metadata, 0, len(listing))
+	for _, md := range listing {
+		if keep(md) {
+			result = append(result, md)
+		}
+	}
+	return result, nil
+}
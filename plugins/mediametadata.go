@@ -0,0 +1,195 @@
+package plugins
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"image"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maurofran/filesystem"
+	"github.com/maurofran/filesystem/internal/exif"
+)
+
+// MediaMetadata is the metadata of a media file.
+type MediaMetadata struct {
+	// Format is the format of the media, such as "jpeg" or "wav".
+	Format string
+	// Width and Height are the dimensions of images and videos, zero if not known.
+	Width, Height int
+	// Duration is the duration of audio and video files, zero if not known.
+	Duration time.Duration
+	// Exif holds the EXIF tags of images, keyed by tag name such as "Model" or "DateTimeOriginal".
+	Exif map[string]string
+}
+
+// UserMetadata will flatten the metadata to user metadata entries, prefixed with "media-".
+func (md MediaMetadata) UserMetadata() map[string]string {
+	um := map[string]string{"media-format": md.Format}
+	if md.Width > 0 || md.Height > 0 {
+		um["media-width"] = strconv.Itoa(md.Width)
+		um["media-height"] = strconv.Itoa(md.Height)
+	}
+	if md.Duration > 0 {
+		um["media-duration"] = md.Duration.String()
+	}
+	for tag, value := range md.Exif {
+		um["media-exif-"+strings.ToLower(tag)] = value
+	}
+	return um
+}
+
+// Probe extracts the metadata of the media files it recognizes.
+type Probe interface {
+	// Probe will fill md from the content of the file at path read from r, reporting whether it recognized it.
+	Probe(ctx context.Context, path filesystem.Path, r io.Reader, md *MediaMetadata) (bool, error)
+}
+
+// ProbeFunc is a function acting as a probe.
+type ProbeFunc func(ctx context.Context, path filesystem.Path, r io.Reader, md *MediaMetadata) (bool, error)
+
+// Probe will invoke the function.
+func (f ProbeFunc) Probe(ctx context.Context, path filesystem.Path, r io.Reader, md *MediaMetadata) (bool, error) {
+	return f(ctx, path, r, md)
+}
+
+// ImageProbe recognizes gif, jpeg and png images, reading their dimensions and, for jpeg, their EXIF tags.
+var ImageProbe Probe = ProbeFunc(func(ctx context.Context, path filesystem.Path, r io.Reader, md *MediaMetadata) (bool, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return false, err
+	}
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return false, nil
+	}
+	md.Format, md.Width, md.Height = format, cfg.Width, cfg.Height
+	if format == "jpeg" {
+		if md.Exif, err = exif.Decode(content); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+})
+
+// WAVProbe recognizes wav audio files, reading their duration.
+var WAVProbe Probe = ProbeFunc(func(ctx context.Context, path filesystem.Path, r io.Reader, md *MediaMetadata) (bool, error) {
+	var header [12]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil || string(header[:4]) != "RIFF" || string(header[8:]) != "WAVE" {
+		return false, nil
+	}
+	var byteRate uint32
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(r, chunk[:]); err != nil {
+			return false, nil
+		}
+		size := binary.LittleEndian.Uint32(chunk[4:])
+		switch string(chunk[:4]) {
+		case "fmt ":
+			if size < 12 || size > 64 {
+				return false, nil
+			}
+			fmtChunk := make([]byte, size+size%2)
+			if _, err := io.ReadFull(r, fmtChunk); err != nil {
+				return false, nil
+			}
+			byteRate = binary.LittleEndian.Uint32(fmtChunk[8:])
+		case "data":
+			if byteRate == 0 {
+				return false, nil
+			}
+			md.Format = "wav"
+			md.Duration = time.Duration(float64(size) / float64(byteRate) * float64(time.Second))
+			return true, nil
+		default:
+			if _, err := io.CopyN(io.Discard, r, int64(size+size%2)); err != nil {
+				return false, nil
+			}
+		}
+	}
+})
+
+// ExtractMetadata is the plugin extracting the metadata of media files through probes, ImageProbe and WAVProbe if
+// Probes is empty. It is invoked with the path of the file and, optionally, whether to store the metadata as user
+// metadata of the file, merged with the existing entries, returning the MediaMetadata. Files no probe recognizes are
+// refused with an error.
+type ExtractMetadata struct {
+	plugin
+	// Probes are the probes tried in order, the first one recognizing the file providing its metadata.
+	Probes []Probe
+}
+
+// Method is the name of the method to be used to invoke the plugin.
+func (p *ExtractMetadata) Method() string {
+	return "ExtractMetadata"
+}
+
+// Bind will return a copy of the plugin operating on fs.
+func (p *ExtractMetadata) Bind(fs filesystem.Interface) filesystem.Plugin {
+	c := *p
+	c.fs = fs
+	return &c
+}
+
+// Handle the invocation of plugin.
+func (p *ExtractMetadata) Handle(ctx context.Context, args ...interface{}) (interface{}, error) {
+	if len(args) != 1 && len(args) != 2 {
+		return nil, errors.New("path argument is required")
+	}
+	path, ok := args[0].(filesystem.Path)
+	if !ok {
+		return nil, errors.New("path must be an instance of filesystem.Path")
+	}
+	store := false
+	if len(args) == 2 {
+		if store, ok = args[1].(bool); !ok {
+			return nil, errors.New("store must be a bool")
+		}
+	}
+	md, err := p.probe(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if store {
+		um, err := p.fs.GetUserMetadata(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		if um == nil {
+			um = make(map[string]string)
+		}
+		for key, value := range md.UserMetadata() {
+			um[key] = value
+		}
+		if err := p.fs.SetUserMetadata(ctx, path, um); err != nil {
+			return nil, err
+		}
+	}
+	return md, nil
+}
+
+// probe will read the metadata of file at path with the first probe recognizing it.
+func (p *ExtractMetadata) probe(ctx context.Context, path filesystem.Path) (MediaMetadata, error) {
+	probes := p.Probes
+	if len(probes) == 0 {
+		probes = []Probe{ImageProbe, WAVProbe}
+	}
+	for _, probe := range probes {
+		var md MediaMetadata
+		rc, err := p.fs.ReadStream(ctx, path)
+		if err != nil {
+			return md, err
+		}
+		ok, err := probe.Probe(ctx, path, rc, &md)
+		rc.Close()
+		if err != nil || ok {
+			return md, err
+		}
+	}
+	return MediaMetadata{}, errors.New("unsupported media format of " + string(path))
+}
@@ -0,0 +1,123 @@
+package plugins
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/maurofran/filesystem"
+	"github.com/maurofran/filesystem/internal/imaging"
+)
+
+// ThumbnailSize is a resized variant generated by the ImageThumbnail plugin.
+type ThumbnailSize struct {
+	// Name identifies the variant in the path it is written to.
+	Name string
+	// Width and Height bound the dimensions of the variant, keeping the aspect ratio of the image, which is never
+	// enlarged. A zero width or height leaves that dimension unconstrained.
+	Width, Height int
+	// Format is the format of the variant, "jpeg", "png" or "gif", empty to keep the format of the image.
+	Format string
+}
+
+// ThumbnailOptions are the options of the ImageThumbnail plugin.
+type ThumbnailOptions struct {
+	// Sizes are the variants to generate, a single 256x256 "thumb" one if empty.
+	Sizes []ThumbnailSize
+	// Prefix is the directory the variants are written under, at <prefix>/<name>/<image path>. Variants are written
+	// next to the image, at <image dir>/<image name>_<name><ext>, if empty.
+	Prefix filesystem.Path
+}
+
+// ImageThumbnail is the plugin generating resized variants of an image. It is invoked with the path of the image
+// and, optionally, the ThumbnailOptions, returning the []filesystem.Path of the written variants. The extension of a
+// variant follows its format when it differs from the one of the image.
+type ImageThumbnail struct {
+	plugin
+}
+
+// Method is the name of the method to be used to invoke the plugin.
+func (p *ImageThumbnail) Method() string {
+	return "ImageThumbnail"
+}
+
+// Bind will return a copy of the plugin operating on fs.
+func (p *ImageThumbnail) Bind(fs filesystem.Interface) filesystem.Plugin {
+	c := *p
+	c.fs = fs
+	return &c
+}
+
+// Handle the invocation of plugin.
+func (p *ImageThumbnail) Handle(ctx context.Context, args ...interface{}) (interface{}, error) {
+	if len(args) != 1 && len(args) != 2 {
+		return nil, errors.New("path argument is required")
+	}
+	path, ok := args[0].(filesystem.Path)
+	if !ok {
+		return nil, errors.New("path must be an instance of filesystem.Path")
+	}
+	var opts ThumbnailOptions
+	if len(args) == 2 {
+		if opts, ok = args[1].(ThumbnailOptions); !ok {
+			return nil, errors.New("options must be an instance of plugins.ThumbnailOptions")
+		}
+	}
+	if len(opts.Sizes) == 0 {
+		opts.Sizes = []ThumbnailSize{{Name: "thumb", Width: 256, Height: 256}}
+	}
+	rc, err := p.fs.ReadStream(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	src, format, err := imaging.Decode(rc)
+	rc.Close()
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]filesystem.Path, 0, len(opts.Sizes))
+	for _, size := range opts.Sizes {
+		if size.Name == "" {
+			return nil, errors.New("size name is required")
+		}
+		variant := thumbnailPath(path, format, size, opts.Prefix)
+		w, h := imaging.Fit(src.Bounds(), size.Width, size.Height)
+		var buf bytes.Buffer
+		if err := imaging.Encode(&buf, imaging.Resize(src, w, h), formatOf(size.Format, format)); err != nil {
+			return nil, err
+		}
+		if err := p.fs.PutStream(ctx, variant, &buf); err != nil {
+			return nil, err
+		}
+		paths = append(paths, variant)
+	}
+	return paths, nil
+}
+
+// formatOf is the normalized format of a variant, falling back to the format of the image.
+func formatOf(format, fallback string) string {
+	switch format = strings.ToLower(format); format {
+	case "":
+		return fallback
+	case "jpg":
+		return "jpeg"
+	}
+	return format
+}
+
+// thumbnailPath is the path the variant of provided size of the image at path, in format, is written to.
+func thumbnailPath(path filesystem.Path, format string, size ThumbnailSize, prefix filesystem.Path) filesystem.Path {
+	ext := path.Ext()
+	if target := formatOf(size.Format, format); target != formatOf(strings.TrimPrefix(ext, "."), "") {
+		ext = "." + target
+		if target == "jpeg" {
+			ext = ".jpg"
+		}
+	}
+	stem := strings.TrimSuffix(string(path), path.Ext())
+	if prefix != "" {
+		return prefix.Join(size.Name, stem+ext)
+	}
+	return filesystem.Path(stem + "_" + size.Name + ext)
+}
@@ -0,0 +1,84 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/maurofran/filesystem"
+)
+
+// ZipDir is the plugin streaming a directory subtree into a zip archive, see filesystem.ExportZip. It is invoked
+// with the path of the directory, the target of the archive, either a filesystem.Path or an io.Writer, and,
+// optionally, the filesystem.ZipOptions. When the target path lies within the directory it is left out of the
+// archive.
+type ZipDir struct {
+	plugin
+}
+
+// Method is the name of the method to be used to invoke the plugin.
+func (p *ZipDir) Method() string {
+	return "ZipDir"
+}
+
+// Bind will return a copy of the plugin operating on fs.
+func (p *ZipDir) Bind(fs filesystem.Interface) filesystem.Plugin {
+	c := *p
+	c.fs = fs
+	return &c
+}
+
+// Handle the invocation of plugin.
+func (p *ZipDir) Handle(ctx context.Context, args ...interface{}) (interface{}, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return nil, errors.New("path and target arguments are required")
+	}
+	path, ok := args[0].(filesystem.Path)
+	if !ok {
+		return nil, errors.New("path must be an instance of filesystem.Path")
+	}
+	var opts filesystem.ZipOptions
+	if len(args) == 3 {
+		if opts, ok = args[2].(filesystem.ZipOptions); !ok {
+			return nil, errors.New("options must be an instance of filesystem.ZipOptions")
+		}
+	}
+	switch target := args[1].(type) {
+	case filesystem.Path:
+		return nil, p.zipTo(ctx, path, target, opts)
+	case io.Writer:
+		return nil, filesystem.ExportZip(ctx, p.fs, path, target, opts)
+	default:
+		return nil, errors.New("target must be an instance of filesystem.Path or io.Writer")
+	}
+}
+
+// zipTo will write the archive of the directory at path to the file at target.
+func (p *ZipDir) zipTo(ctx context.Context, path, target filesystem.Path, opts filesystem.ZipOptions) error {
+	if name, ok := strings.CutPrefix(string(target), string(path)+"/"); ok || path == filesystem.RootPath {
+		if path == filesystem.RootPath {
+			name = string(target)
+		}
+		opts.Exclude = append(append([]string(nil), opts.Exclude...), escape(name))
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(filesystem.ExportZip(ctx, p.fs, path, pw, opts))
+	}()
+	err := p.fs.PutStream(ctx, target, pr)
+	pr.CloseWithError(err)
+	return err
+}
+
+// escape will quote the glob metacharacters of name, so that it is matched literally.
+func escape(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if strings.ContainsRune(`*?[]\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,297 @@
+package policy
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"time"
+
+	"github.com/maurofran/filesystem"
+)
+
+// Wrap will decorate provided adapter so that every operation is checked against the engine rules before
+// being delegated.
+func Wrap(a filesystem.Adapter, e *Engine) filesystem.Adapter {
+	return &adapter{Adapter: a, engine: e}
+}
+
+type adapter struct {
+	filesystem.Adapter
+	engine *Engine
+}
+
+func (a *adapter) checkContent(path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	if err := a.engine.Check(OperationWrite, path); err != nil {
+		return err
+	}
+	if err := a.engine.CheckSize(path, int64(len(content))); err != nil {
+		return err
+	}
+	if a.engine.needsMimeType(path) {
+		head := content
+		if len(head) > 512 {
+			head = head[:512]
+		}
+		return a.engine.CheckMimeType(path, detectMimeType(path, head, cfg))
+	}
+	return nil
+}
+
+// checkStream will check a streamed write, returning the reader to be used in place of the original one.
+func (a *adapter) checkStream(path filesystem.Path, r io.Reader, cfg filesystem.Config) (io.Reader, error) {
+	if err := a.engine.Check(OperationWrite, path); err != nil {
+		return nil, err
+	}
+	if a.engine.needsMimeType(path) {
+		br := bufio.NewReaderSize(r, 512)
+		head, err := br.Peek(512)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if err := a.engine.CheckMimeType(path, detectMimeType(path, head, cfg)); err != nil {
+			return nil, err
+		}
+		r = br
+	}
+	if max := a.engine.maxSize(path); max > 0 {
+		r = &limitedReader{r: r, engine: a.engine, path: path, max: max}
+	}
+	return r, nil
+}
+
+// limitedReader fails with a violation error as soon as more bytes than allowed are read.
+type limitedReader struct {
+	r      io.Reader
+	engine *Engine
+	path   filesystem.Path
+	max    int64
+	n      int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.n += int64(n)
+	if l.n > l.max {
+		return n, l.engine.CheckSize(l.path, l.n)
+	}
+	return n, err
+}
+
+func (a *adapter) Read(ctx context.Context, path filesystem.Path) ([]byte, error) {
+	if err := a.engine.Check(OperationRead, path); err != nil {
+		return nil, err
+	}
+	return a.Adapter.Read(ctx, path)
+}
+
+func (a *adapter) ReadStream(ctx context.Context, path filesystem.Path) (io.ReadCloser, error) {
+	if err := a.engine.Check(OperationRead, path); err != nil {
+		return nil, err
+	}
+	return a.Adapter.ReadStream(ctx, path)
+}
+
+func (a *adapter) Has(ctx context.Context, path filesystem.Path) (bool, error) {
+	if err := a.engine.Check(OperationRead, path); err != nil {
+		return false, err
+	}
+	return a.Adapter.Has(ctx, path)
+}
+
+func (a *adapter) GetFileSize(ctx context.Context, path filesystem.Path) (int64, error) {
+	if err := a.engine.Check(OperationRead, path); err != nil {
+		return 0, err
+	}
+	return a.Adapter.GetFileSize(ctx, path)
+}
+
+func (a *adapter) GetMimeType(ctx context.Context, path filesystem.Path) (string, error) {
+	if err := a.engine.Check(OperationRead, path); err != nil {
+		return "", err
+	}
+	return a.Adapter.GetMimeType(ctx, path)
+}
+
+func (a *adapter) GetTimestamp(ctx context.Context, path filesystem.Path) (time.Time, error) {
+	if err := a.engine.Check(OperationRead, path); err != nil {
+		return time.Time{}, err
+	}
+	return a.Adapter.GetTimestamp(ctx, path)
+}
+
+func (a *adapter) GetVisibility(ctx context.Context, path filesystem.Path) (filesystem.Visibility, error) {
+	if err := a.engine.Check(OperationRead, path); err != nil {
+		return "", err
+	}
+	return a.Adapter.GetVisibility(ctx, path)
+}
+
+func (a *adapter) GetMetadata(ctx context.Context, path filesystem.Path) (filesystem.Metadata, error) {
+	if err := a.engine.Check(OperationRead, path); err != nil {
+		return nil, err
+	}
+	return a.Adapter.GetMetadata(ctx, path)
+}
+
+func (a *adapter) ListContents(ctx context.Context, path filesystem.Path, recursive bool) ([]filesystem.Metadata, error) {
+	if err := a.engine.Check(OperationRead, path); err != nil {
+		return nil, err
+	}
+	listing, err := a.Adapter.ListContents(ctx, path, recursive)
+	if err != nil {
+		return nil, err
+	}
+	allowed := listing[:0]
+	for _, md := range listing {
+		if a.engine.Check(OperationRead, md.Path) == nil {
+			allowed = append(allowed, md)
+		}
+	}
+	return allowed, nil
+}
+
+func (a *adapter) Write(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	if err := a.checkContent(path, content, cfg); err != nil {
+		return err
+	}
+	return a.Adapter.Write(ctx, path, content, cfg)
+}
+
+func (a *adapter) WriteStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	r, err := a.checkStream(path, r, cfg)
+	if err != nil {
+		return err
+	}
+	return a.Adapter.WriteStream(ctx, path, r, cfg)
+}
+
+func (a *adapter) Update(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	if err := a.checkContent(path, content, cfg); err != nil {
+		return err
+	}
+	return a.Adapter.Update(ctx, path, content, cfg)
+}
+
+func (a *adapter) UpdateStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	r, err := a.checkStream(path, r, cfg)
+	if err != nil {
+		return err
+	}
+	return a.Adapter.UpdateStream(ctx, path, r, cfg)
+}
+
+func (a *adapter) Put(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	if err := a.checkContent(path, content, cfg); err != nil {
+		return err
+	}
+	return a.Adapter.Put(ctx, path, content, cfg)
+}
+
+func (a *adapter) PutStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	r, err := a.checkStream(path, r, cfg)
+	if err != nil {
+		return err
+	}
+	return a.Adapter.PutStream(ctx, path, r, cfg)
+}
+
+func (a *adapter) Delete(ctx context.Context, path filesystem.Path) error {
+	if err := a.engine.Check(OperationDelete, path); err != nil {
+		return err
+	}
+	return a.Adapter.Delete(ctx, path)
+}
+
+func (a *adapter) ReadAndDelete(ctx context.Context, path filesystem.Path) ([]byte, error) {
+	if err := a.engine.Check(OperationRead, path); err != nil {
+		return nil, err
+	}
+	if err := a.engine.Check(OperationDelete, path); err != nil {
+		return nil, err
+	}
+	return a.Adapter.ReadAndDelete(ctx, path)
+}
+
+// checkTransfer will check the move or copy of file at path to newpath against the size and mime type rules of
+// newpath, using the metadata of the source file.
+func (a *adapter) checkTransfer(ctx context.Context, path, newpath filesystem.Path) error {
+	if err := a.engine.Check(OperationWrite, newpath); err != nil {
+		return err
+	}
+	if a.engine.maxSize(newpath) > 0 {
+		size, err := a.Adapter.GetFileSize(ctx, path)
+		if err != nil {
+			return err
+		}
+		if err := a.engine.CheckSize(newpath, size); err != nil {
+			return err
+		}
+	}
+	if a.engine.needsMimeType(newpath) {
+		mimeType, err := a.Adapter.GetMimeType(ctx, path)
+		if err != nil {
+			return err
+		}
+		return a.engine.CheckMimeType(newpath, mimeType)
+	}
+	return nil
+}
+
+func (a *adapter) Move(ctx context.Context, path, newpath filesystem.Path) error {
+	if err := a.engine.Check(OperationRead, path); err != nil {
+		return err
+	}
+	if err := a.engine.Check(OperationDelete, path); err != nil {
+		return err
+	}
+	if err := a.checkTransfer(ctx, path, newpath); err != nil {
+		return err
+	}
+	return a.Adapter.Move(ctx, path, newpath)
+}
+
+func (a *adapter) Copy(ctx context.Context, path, newpath filesystem.Path) error {
+	if err := a.engine.Check(OperationRead, path); err != nil {
+		return err
+	}
+	if err := a.checkTransfer(ctx, path, newpath); err != nil {
+		return err
+	}
+	return a.Adapter.Copy(ctx, path, newpath)
+}
+
+func (a *adapter) CreateDir(ctx context.Context, path filesystem.Path, cfg filesystem.Config) error {
+	if err := a.engine.Check(OperationWrite, path); err != nil {
+		return err
+	}
+	return a.Adapter.CreateDir(ctx, path, cfg)
+}
+
+// DeleteDir will delete the directory at path if the rules allow deleting it and every entry it contains.
+func (a *adapter) DeleteDir(ctx context.Context, path filesystem.Path) error {
+	if err := a.engine.Check(OperationDelete, path); err != nil {
+		return err
+	}
+	listing, err := a.Adapter.ListContents(ctx, path, true)
+	if err != nil && !filesystem.IsFileNotFound(err) {
+		return err
+	}
+	for _, md := range listing {
+		if err := a.engine.Check(OperationDelete, md.Path); err != nil {
+			return err
+		}
+	}
+	return a.Adapter.DeleteDir(ctx, path)
+}
+
+func (a *adapter) SetVisibility(ctx context.Context, path filesystem.Path, v filesystem.Visibility) error {
+	if err := a.engine.Check(OperationWrite, path); err != nil {
+		return err
+	}
+	return a.Adapter.SetVisibility(ctx, path, v)
+}
+
+// Close will close the decorated adapter.
+func (a *adapter) Close() error {
+	return filesystem.Close(a.Adapter)
+}
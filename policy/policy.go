@@ -0,0 +1,182 @@
+package policy
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/maurofran/filesystem"
+	"github.com/maurofran/filesystem/internal/glob"
+)
+
+// Operation enumeration.
+type Operation int
+
+// Operation values (read, write and delete).
+const (
+	OperationRead Operation = iota + 1
+	OperationWrite
+	OperationDelete
+)
+
+var operations = [...]string{"Read", "Write", "Delete"}
+
+func (o Operation) String() string {
+	return operations[o-1]
+}
+
+// Rule is a policy rule applied to files matching a pattern.
+type Rule struct {
+	// Pattern is the glob the file path must match for the rule to apply; "**" spans directories.
+	Pattern string
+	// Deny is the list of operations forbidden on matching files.
+	Deny []Operation
+	// MaxSize is the maximum size of written content, in bytes. Zero means unlimited.
+	MaxSize int64
+	// MimeTypes are the mime types allowed for written content; a trailing "/*" allows a whole family.
+	MimeTypes []string
+}
+
+func (r Rule) denies(op Operation) bool {
+	for _, d := range r.Deny {
+		if d == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Rule) allowsMimeType(mimeType string) bool {
+	if len(r.MimeTypes) == 0 {
+		return true
+	}
+	for _, allowed := range r.MimeTypes {
+		if allowed == mimeType || strings.HasSuffix(allowed, "/*") && strings.HasPrefix(mimeType, allowed[:len(allowed)-1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// ViolationError is the error returned when an operation violates a policy rule.
+type ViolationError interface {
+	error
+	Path() filesystem.Path
+	Rule() Rule
+}
+
+type violationError struct {
+	reason string
+	path   filesystem.Path
+	rule   Rule
+}
+
+// Path is the path of the file the operation was attempted on.
+func (e violationError) Path() filesystem.Path {
+	return e.path
+}
+
+// Rule is the violated rule.
+func (e violationError) Rule() Rule {
+	return e.rule
+}
+
+func (e violationError) Error() string {
+	return fmt.Sprintf("Policy %s violated on path %s: %s", e.rule.Pattern, e.path, e.reason)
+}
+
+// IsViolationError will check if provided error is a policy violation error.
+func IsViolationError(err error) bool {
+	_, ok := err.(ViolationError)
+	return ok
+}
+
+// Engine evaluates policy rules.
+type Engine struct {
+	rules []Rule
+}
+
+// New will create a new engine for provided rules, returning an error if a rule pattern is malformed.
+func New(rules ...Rule) (*Engine, error) {
+	for _, r := range rules {
+		if err := glob.Validate(r.Pattern); err != nil {
+			return nil, err
+		}
+	}
+	return &Engine{rules: rules}, nil
+}
+
+func (e *Engine) matching(p filesystem.Path) []Rule {
+	var rules []Rule
+	for _, r := range e.rules {
+		if glob.Match(r.Pattern, string(p)) {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+// Check will verify that operation is allowed on provided path.
+func (e *Engine) Check(op Operation, p filesystem.Path) error {
+	for _, r := range e.matching(p) {
+		if r.denies(op) {
+			return violationError{fmt.Sprintf("%s denied", op), p, r}
+		}
+	}
+	return nil
+}
+
+// CheckSize will verify that content of provided size may be written at provided path.
+func (e *Engine) CheckSize(p filesystem.Path, size int64) error {
+	for _, r := range e.matching(p) {
+		if r.MaxSize > 0 && size > r.MaxSize {
+			return violationError{fmt.Sprintf("size exceeds %d bytes", r.MaxSize), p, r}
+		}
+	}
+	return nil
+}
+
+// CheckMimeType will verify that content of provided mime type may be written at provided path.
+func (e *Engine) CheckMimeType(p filesystem.Path, mimeType string) error {
+	if mt, _, err := mime.ParseMediaType(mimeType); err == nil {
+		mimeType = mt
+	}
+	for _, r := range e.matching(p) {
+		if !r.allowsMimeType(mimeType) {
+			return violationError{fmt.Sprintf("mime type %s not allowed", mimeType), p, r}
+		}
+	}
+	return nil
+}
+
+func (e *Engine) needsMimeType(p filesystem.Path) bool {
+	for _, r := range e.matching(p) {
+		if len(r.MimeTypes) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Engine) maxSize(p filesystem.Path) int64 {
+	var max int64
+	for _, r := range e.matching(p) {
+		if r.MaxSize > 0 && (max == 0 || r.MaxSize < max) {
+			max = r.MaxSize
+		}
+	}
+	return max
+}
+
+// detectMimeType will find the mime type of content from the config, the file extension or the content itself.
+func detectMimeType(p filesystem.Path, head []byte, cfg filesystem.Config) string {
+	if mt, ok := cfg.Get(filesystem.ConfigMimeType, "").(string); ok && mt != "" {
+		return mt
+	}
+	if mt := mime.TypeByExtension(path.Ext(string(p))); mt != "" {
+		return mt
+	}
+	return http.DetectContentType(head)
+}
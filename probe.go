@@ -0,0 +1,147 @@
+package filesystem
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProbeReport describes backend behaviors detected empirically by Probe.
+type ProbeReport struct {
+	// CaseSensitive reports whether paths differing only by case address different files.
+	CaseSensitive bool
+	// WriteOverwrites reports whether Write replaces an existing file instead of failing.
+	WriteOverwrites bool
+	// MoveOverwrites reports whether Move replaces an existing target instead of failing.
+	MoveOverwrites bool
+	// AtomicMove reports whether a concurrent observer never saw both or neither of source and target during a
+	// Move. It is a best-effort observation, not a guarantee.
+	AtomicMove bool
+	// EmptyDirectories reports whether empty directories exist on their own, as opposed to prefix-style backends.
+	EmptyDirectories bool
+	// TimestampPrecision is the finest precision observed on file timestamps.
+	TimestampPrecision time.Duration
+}
+
+const probeMoveSize = 4 << 20
+
+// Probe will detect the behaviors of file system by performing operations in a scratch directory created under
+// dir, removed once done.
+func Probe(ctx context.Context, fs Interface, dir Path) (ProbeReport, error) {
+	var report ProbeReport
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return report, err
+	}
+	scratch := dir.Join(".probe-" + hex.EncodeToString(suffix))
+	if err := fs.CreateDir(ctx, scratch); err != nil {
+		return report, err
+	}
+	defer fs.DeleteDir(ctx, scratch)
+
+	var err error
+	if report.EmptyDirectories, err = probeEmptyDirectories(ctx, fs, scratch); err != nil {
+		return report, err
+	}
+	file := scratch.Join("probe.txt")
+	if err := fs.Write(ctx, file, []byte("probe")); err != nil {
+		return report, err
+	}
+	upper, err := fs.Has(ctx, Path(strings.ToUpper(string(file))))
+	if err != nil {
+		return report, err
+	}
+	report.CaseSensitive = !upper
+	report.WriteOverwrites = fs.Write(ctx, file, []byte("probe again")) == nil
+	if report.TimestampPrecision, err = probeTimestampPrecision(ctx, fs, scratch); err != nil {
+		return report, err
+	}
+	target := scratch.Join("target.txt")
+	if err := fs.Write(ctx, target, []byte("target")); err != nil {
+		return report, err
+	}
+	report.MoveOverwrites = fs.Move(ctx, file, target) == nil
+	if report.AtomicMove, err = probeAtomicMove(ctx, fs, scratch); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+func probeEmptyDirectories(ctx context.Context, fs Interface, scratch Path) (bool, error) {
+	if err := fs.CreateDir(ctx, scratch.Join("empty")); err != nil {
+		return false, err
+	}
+	listing, err := fs.ListContents(ctx, scratch, false)
+	if err != nil {
+		return false, err
+	}
+	for _, md := range listing {
+		if md.Path == scratch.Join("empty") {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// probeTimestampPrecision will return the finest precision of a few timestamps, each one being the coarsest unit
+// it is a multiple of.
+func probeTimestampPrecision(ctx context.Context, fs Interface, scratch Path) (time.Duration, error) {
+	precision := time.Second
+	for i := 0; i < 3; i++ {
+		file := scratch.Join("timestamp-" + string(rune('a'+i)))
+		if err := fs.Write(ctx, file, []byte("timestamp")); err != nil {
+			return 0, err
+		}
+		ts, err := fs.GetTimestamp(ctx, file)
+		if err != nil {
+			return 0, err
+		}
+		for _, p := range []time.Duration{time.Second, time.Millisecond, time.Microsecond, time.Nanosecond} {
+			if ts.Truncate(p).Equal(ts) {
+				if p < precision {
+					precision = p
+				}
+				break
+			}
+		}
+		time.Sleep(time.Millisecond + time.Microsecond)
+	}
+	return precision, nil
+}
+
+func probeAtomicMove(ctx context.Context, fs Interface, scratch Path) (bool, error) {
+	source, target := scratch.Join("move-source"), scratch.Join("move-target")
+	if err := fs.Write(ctx, source, bytes.Repeat([]byte("x"), probeMoveSize)); err != nil {
+		return false, err
+	}
+	atomic := true
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			// Source is only judged when target did not change around it.
+			before, err1 := fs.Has(ctx, target)
+			hasSource, err2 := fs.Has(ctx, source)
+			after, err3 := fs.Has(ctx, target)
+			if err1 == nil && err2 == nil && err3 == nil && before == after && hasSource == before {
+				atomic = false
+				return
+			}
+		}
+	}()
+	err := fs.Move(ctx, source, target)
+	close(done)
+	wg.Wait()
+	return atomic, err
+}
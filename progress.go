@@ -0,0 +1,111 @@
+package filesystem
+
+import (
+	"context"
+	"io"
+	"os"
+)
+
+// ProgressEvent reports the bytes transferred so far by a streaming operation.
+type ProgressEvent struct {
+	// Operation is the name of the Interface method transferring the file, e.g. "ReadStream".
+	Operation string
+	// Path is the path of the file.
+	Path Path
+	// Bytes is the number of bytes transferred so far.
+	Bytes int64
+	// Total is the number of bytes to transfer, -1 when not known.
+	Total int64
+}
+
+type progressKey struct{}
+
+// WithProgress will return a copy of provided context making the streaming operations performed with it report
+// their progress to fn: ReadStream as the stream is read, WriteStream, UpdateStream, PutStream and WriteAtomic as
+// their reader is consumed, and Copy once the file is copied. The function is called from the goroutine consuming
+// the stream, and must be quick not to slow the transfer down.
+func WithProgress(ctx context.Context, fn func(ProgressEvent)) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// progressFrom will extract the progress function from provided context, or nil.
+func progressFrom(ctx context.Context) func(ProgressEvent) {
+	fn, _ := ctx.Value(progressKey{}).(func(ProgressEvent))
+	return fn
+}
+
+// NewProgressReader will wrap r to report to fn the bytes read through it, as the progress of provided operation on
+// the file at supplied path, total being -1 when not known.
+func NewProgressReader(r io.Reader, op string, path Path, total int64, fn func(ProgressEvent)) io.Reader {
+	return &progressReader{r: r, fn: fn, ev: ProgressEvent{Operation: op, Path: path, Total: total}}
+}
+
+type progressReader struct {
+	r  io.Reader
+	fn func(ProgressEvent)
+	ev ProgressEvent
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.ev.Bytes += int64(n)
+		p.fn(p.ev)
+	}
+	return n, err
+}
+
+type progressReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// readerSize will return the number of bytes left in r, if it tells, or -1.
+func readerSize(r io.Reader) int64 {
+	switch r := r.(type) {
+	case interface{ Len() int }:
+		return int64(r.Len())
+	case *os.File:
+		if fi, err := r.Stat(); err == nil && fi.Mode().IsRegular() {
+			if off, err := r.Seek(0, io.SeekCurrent); err == nil {
+				return fi.Size() - off
+			}
+		}
+	}
+	return -1
+}
+
+// trackRead will make rc report the progress of reading the file at supplied path, if asked by the context.
+func (fs *filesystem) trackRead(ctx context.Context, path Path, rc io.ReadCloser) io.ReadCloser {
+	fn := progressFrom(ctx)
+	if fn == nil {
+		return rc
+	}
+	total, err := fs.adapter.GetFileSize(ctx, path)
+	if err != nil {
+		total = -1
+	}
+	return progressReadCloser{NewProgressReader(rc, "ReadStream", path, total, fn), rc}
+}
+
+// trackWrite will make r report the progress of provided write operation, if asked by the context.
+func trackWrite(ctx context.Context, op string, path Path, r io.Reader) io.Reader {
+	fn := progressFrom(ctx)
+	if fn == nil {
+		return r
+	}
+	return NewProgressReader(r, op, path, readerSize(r), fn)
+}
+
+// trackCopy will report the copy of the file at supplied path as complete, if asked by the context.
+func (fs *filesystem) trackCopy(ctx context.Context, path, newpath Path) {
+	fn := progressFrom(ctx)
+	if fn == nil {
+		return
+	}
+	size, err := fs.adapter.GetFileSize(ctx, newpath)
+	if err != nil {
+		size = -1
+	}
+	fn(ProgressEvent{Operation: "Copy", Path: path, Bytes: size, Total: size})
+}
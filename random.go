@@ -0,0 +1,110 @@
+package filesystem
+
+import (
+	"context"
+	"io"
+)
+
+// RandomWriter is implemented by adapters able to write part of a file in place, without rewriting it. Decorators
+// may return an UnsupportedOperationError when the adapter they decorate is not a RandomWriter.
+type RandomWriter interface {
+	// WriteAt will write p at offset of the file at provided path, growing it as needed and creating it if missing.
+	WriteAt(ctx context.Context, path Path, p []byte, offset int64) error
+}
+
+// SizedReaderAt is an io.ReaderAt knowing the size of its content, as expected by archive/zip.NewReader.
+type SizedReaderAt interface {
+	io.ReaderAt
+	Size() int64
+}
+
+// ReaderAt will return a reader of the file at provided path at random offsets, each read being a range read, so
+// that formats needing random access are read without downloading the whole file. Reads may happen concurrently.
+func (fs *filesystem) ReaderAt(ctx context.Context, path Path) (SizedReaderAt, error) {
+	path, err := NewPath(string(path))
+	if err != nil {
+		return nil, err
+	}
+	size, err := fs.adapter.GetFileSize(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return &rangeReaderAt{ctx: ctx, fsys: fs, path: path, size: size}, nil
+}
+
+// rangeReaderAt is a SizedReaderAt reading with range reads.
+type rangeReaderAt struct {
+	ctx  context.Context
+	fsys Interface
+	path Path
+	size int64
+}
+
+func (r *rangeReaderAt) Size() int64 {
+	return r.size
+}
+
+func (r *rangeReaderAt) ReadAt(p []byte, offset int64) (int, error) {
+	if offset >= r.size {
+		return 0, io.EOF
+	}
+	rc, err := r.fsys.ReadRange(r.ctx, r.path, offset, int64(len(p)))
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+	n, err := io.ReadFull(rc, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// WriterAt will return a writer of the file at provided path at random offsets, failing with an
+// UnsupportedOperationError unless the adapter reports the RandomWrites capability.
+func (fs *filesystem) WriterAt(ctx context.Context, path Path) (io.WriterAt, error) {
+	path, err := NewPath(string(path))
+	if err != nil {
+		return nil, err
+	}
+	w, ok := fs.adapter.(RandomWriter)
+	if !ok || !CapabilitiesOf(fs.adapter).RandomWrites {
+		return nil, NewUnsupportedOperationError(path, "WriterAt")
+	}
+	return &randomWriterAt{ctx: ctx, writer: w, path: path}, nil
+}
+
+// randomWriterAt is an io.WriterAt writing through a RandomWriter.
+type randomWriterAt struct {
+	ctx    context.Context
+	writer RandomWriter
+	path   Path
+}
+
+func (w *randomWriterAt) WriteAt(p []byte, offset int64) (int, error) {
+	if err := checkRange(w.path, offset); err != nil {
+		return 0, err
+	}
+	if err := w.writer.WriteAt(w.ctx, w.path, p, offset); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// ReaderAt will return a reader of the file at provided path at random offsets.
+func (mm *mountManager) ReaderAt(ctx context.Context, path Path) (SizedReaderAt, error) {
+	mgr, subPath, err := mm.managerFor(path)
+	if err != nil {
+		return nil, err
+	}
+	return mgr.ReaderAt(ctx, subPath)
+}
+
+// WriterAt will return a writer of the file at provided path at random offsets.
+func (mm *mountManager) WriterAt(ctx context.Context, path Path) (io.WriterAt, error) {
+	mgr, subPath, err := mm.managerFor(path)
+	if err != nil {
+		return nil, err
+	}
+	return mgr.WriterAt(ctx, subPath)
+}
@@ -0,0 +1,82 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// RangeReader is implemented by adapters able to read part of a file natively, e.g. with a range request.
+type RangeReader interface {
+	ReadRange(ctx context.Context, path Path, offset, length int64) (io.ReadCloser, error)
+}
+
+// checkRange will validate a range, returning an error if offset is negative.
+func checkRange(path Path, offset int64) error {
+	if offset < 0 {
+		return fmt.Errorf("invalid negative offset %d reading %s", offset, path)
+	}
+	return nil
+}
+
+// ReadRange will read length bytes of the file at provided path starting at offset, or up to the end of file if
+// length is negative. Reading past the end of file returns less bytes, or none.
+func (fs *filesystem) ReadRange(ctx context.Context, path Path, offset, length int64) (io.ReadCloser, error) {
+	path, err := NewPath(string(path))
+	if err != nil {
+		return nil, err
+	}
+	if err := checkRange(path, offset); err != nil {
+		return nil, err
+	}
+	if r, ok := fs.adapter.(RangeReader); ok {
+		return r.ReadRange(ctx, path, offset, length)
+	}
+	rc, err := fs.adapter.ReadStream(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return SliceStream(rc, offset, length)
+}
+
+// SliceStream will restrict provided stream to length bytes starting at offset, seeking when stream supports it
+// and discarding the leading bytes otherwise. The returned stream closes rc.
+func SliceStream(rc io.ReadCloser, offset, length int64) (io.ReadCloser, error) {
+	if offset > 0 {
+		var err error
+		if s, ok := rc.(io.Seeker); ok {
+			// Some seekers refuse offsets past the end of file.
+			var size int64
+			if size, err = s.Seek(0, io.SeekEnd); err == nil {
+				_, err = s.Seek(min(offset, size), io.SeekStart)
+			}
+		} else {
+			_, err = io.CopyN(io.Discard, rc, offset)
+			if err == io.EOF {
+				err = nil
+			}
+		}
+		if err != nil {
+			rc.Close()
+			return nil, err
+		}
+	}
+	if length < 0 {
+		return rc, nil
+	}
+	return limitedReadCloser{io.LimitReader(rc, length), rc}, nil
+}
+
+type limitedReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// ReadRange will read length bytes of the file at provided path starting at offset.
+func (mm *mountManager) ReadRange(ctx context.Context, path Path, offset, length int64) (io.ReadCloser, error) {
+	mgr, subPath, err := mm.managerFor(path)
+	if err != nil {
+		return nil, err
+	}
+	return mgr.ReadRange(ctx, subPath, offset, length)
+}
@@ -0,0 +1,39 @@
+package filesystem
+
+import "context"
+
+// ContentMetadataReader is implemented by adapters able to read a file along with its metadata in a single call,
+// e.g. from the response headers of a download.
+type ContentMetadataReader interface {
+	// ReadWithMetadata will return the metadata of the file at provided path, Content holding its content.
+	ReadWithMetadata(ctx context.Context, path Path) (Metadata, error)
+}
+
+// ReadWithMetadata will read the file at provided path of fs along with its metadata, Content holding its content:
+// in a single call for file systems created by New on a ContentMetadataReader adapter, and with GetMetadata and Read
+// otherwise.
+func ReadWithMetadata(ctx context.Context, fs Interface, path Path) (Metadata, error) {
+	if f, ok := fs.(*filesystem); ok {
+		if r, ok := f.adapter.(ContentMetadataReader); ok {
+			path, err := NewPath(string(path))
+			if err != nil {
+				return nil, err
+			}
+			return r.ReadWithMetadata(ctx, path)
+		}
+	}
+	md, err := fs.GetMetadata(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if md.Content != nil {
+		return md, nil
+	}
+	content, err := fs.Read(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	md = md.Clone()
+	md.Content = content
+	return md, nil
+}
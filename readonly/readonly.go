@@ -0,0 +1,249 @@
+// Package readonly provides an adapter decorator refusing every operation that would modify the file system.
+package readonly
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/maurofran/filesystem"
+)
+
+// OperationNotAllowedError is the error returned for write operations on a read-only adapter. It is also a
+// filesystem.ReadOnlyError.
+type OperationNotAllowedError interface {
+	error
+	Path() filesystem.Path
+	Operation() string
+}
+
+type operationNotAllowedError struct {
+	path      filesystem.Path
+	operation string
+}
+
+// Path is the path of the file the operation was attempted on.
+func (e operationNotAllowedError) Path() filesystem.Path {
+	return e.path
+}
+
+// Operation is the name of the refused operation.
+func (e operationNotAllowedError) Operation() string {
+	return e.operation
+}
+
+func (e operationNotAllowedError) Error() string {
+	return fmt.Sprintf("Operation %s not allowed on %s: file system is read-only", e.operation, e.path)
+}
+
+// IsOperationNotAllowedError will check if provided error is an operation not allowed error.
+func IsOperationNotAllowedError(err error) bool {
+	_, ok := err.(OperationNotAllowedError)
+	return ok
+}
+
+func notAllowed(operation string, path filesystem.Path) error {
+	return operationNotAllowedError{path, operation}
+}
+
+// Wrap will decorate provided adapter so that read operations are passed through and write operations fail with
+// an OperationNotAllowedError, without reaching the adapter.
+func Wrap(a filesystem.Adapter) filesystem.Adapter {
+	return &adapter{Adapter: a}
+}
+
+type adapter struct {
+	filesystem.Adapter
+}
+
+func (a *adapter) Write(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	return notAllowed("Write", path)
+}
+
+func (a *adapter) WriteStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	return notAllowed("WriteStream", path)
+}
+
+func (a *adapter) Update(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	return notAllowed("Update", path)
+}
+
+func (a *adapter) UpdateStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	return notAllowed("UpdateStream", path)
+}
+
+func (a *adapter) Put(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	return notAllowed("Put", path)
+}
+
+func (a *adapter) PutStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	return notAllowed("PutStream", path)
+}
+
+func (a *adapter) Delete(ctx context.Context, path filesystem.Path) error {
+	return notAllowed("Delete", path)
+}
+
+func (a *adapter) ReadAndDelete(ctx context.Context, path filesystem.Path) ([]byte, error) {
+	return nil, notAllowed("ReadAndDelete", path)
+}
+
+func (a *adapter) Move(ctx context.Context, path, newpath filesystem.Path) error {
+	return notAllowed("Move", path)
+}
+
+func (a *adapter) Copy(ctx context.Context, path, newpath filesystem.Path) error {
+	return notAllowed("Copy", newpath)
+}
+
+func (a *adapter) CreateDir(ctx context.Context, path filesystem.Path, cfg filesystem.Config) error {
+	return notAllowed("CreateDir", path)
+}
+
+func (a *adapter) DeleteDir(ctx context.Context, path filesystem.Path) error {
+	return notAllowed("DeleteDir", path)
+}
+
+func (a *adapter) SetVisibility(ctx context.Context, path filesystem.Path, v filesystem.Visibility) error {
+	return notAllowed("SetVisibility", path)
+}
+
+// ReadRange will read part of the file natively when the decorated adapter supports it.
+func (a *adapter) ReadRange(ctx context.Context, path filesystem.Path, offset, length int64) (io.ReadCloser, error) {
+	if r, ok := a.Adapter.(filesystem.RangeReader); ok {
+		return r.ReadRange(ctx, path, offset, length)
+	}
+	rc, err := a.Adapter.ReadStream(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return filesystem.SliceStream(rc, offset, length)
+}
+
+// GetChecksum will return the checksum natively provided by the decorated adapter, if any.
+func (a *adapter) GetChecksum(ctx context.Context, path filesystem.Path, algo filesystem.ChecksumAlgo) (string, error) {
+	if p, ok := a.Adapter.(filesystem.ChecksumProvider); ok {
+		return p.GetChecksum(ctx, path, algo)
+	}
+	return "", filesystem.NewUnsupportedChecksumError(path, algo)
+}
+
+// FileExists will check if a file exists natively when the decorated adapter supports it.
+func (a *adapter) FileExists(ctx context.Context, path filesystem.Path) (bool, error) {
+	if c, ok := a.Adapter.(filesystem.ExistenceChecker); ok {
+		return c.FileExists(ctx, path)
+	}
+	return false, filesystem.NewUnsupportedOperationError(path, "FileExists")
+}
+
+// DirectoryExists will check if a directory exists natively when the decorated adapter supports it.
+func (a *adapter) DirectoryExists(ctx context.Context, path filesystem.Path) (bool, error) {
+	if c, ok := a.Adapter.(filesystem.ExistenceChecker); ok {
+		return c.DirectoryExists(ctx, path)
+	}
+	return false, filesystem.NewUnsupportedOperationError(path, "DirectoryExists")
+}
+
+// GetDirectoryMetadata will describe the directory natively when the decorated adapter supports it.
+func (a *adapter) GetDirectoryMetadata(ctx context.Context, path filesystem.Path) (filesystem.DirectoryInfo, error) {
+	if p, ok := a.Adapter.(filesystem.DirectoryMetadataProvider); ok {
+		return p.GetDirectoryMetadata(ctx, path)
+	}
+	return filesystem.DirectoryInfo{}, filesystem.NewUnsupportedOperationError(path, "GetDirectoryMetadata")
+}
+
+// ListPage will list a page natively when the decorated adapter supports it.
+func (a *adapter) ListPage(ctx context.Context, path filesystem.Path, recursive bool, cursor string, limit int) (filesystem.Page, error) {
+	if l, ok := a.Adapter.(filesystem.PageLister); ok {
+		return l.ListPage(ctx, path, recursive, cursor, limit)
+	}
+	return filesystem.Page{}, filesystem.NewUnsupportedOperationError(path, "ListPage")
+}
+
+// IterateSorted will iterate in order natively when the decorated adapter supports it.
+func (a *adapter) IterateSorted(ctx context.Context, path filesystem.Path, recursive bool, s filesystem.Sort) (filesystem.Iterator, error) {
+	if si, ok := a.Adapter.(filesystem.SortedIterator); ok {
+		return si.IterateSorted(ctx, path, recursive, s)
+	}
+	return nil, filesystem.NewUnsupportedOperationError(path, "IterateSorted")
+}
+
+// GetUserMetadata will return the user metadata from the decorated adapter, if it supports it.
+func (a *adapter) GetUserMetadata(ctx context.Context, path filesystem.Path) (map[string]string, error) {
+	if p, ok := a.Adapter.(filesystem.UserMetadataProvider); ok {
+		return p.GetUserMetadata(ctx, path)
+	}
+	return nil, filesystem.NewUnsupportedOperationError(path, "GetUserMetadata")
+}
+
+// SetUserMetadata will always fail.
+func (a *adapter) SetUserMetadata(ctx context.Context, path filesystem.Path, metadata map[string]string) error {
+	return notAllowed("SetUserMetadata", path)
+}
+
+// WriteAtomic will always fail.
+func (a *adapter) WriteAtomic(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	return notAllowed("WriteAtomic", path)
+}
+
+// WriteAt will always fail.
+func (a *adapter) WriteAt(ctx context.Context, path filesystem.Path, p []byte, offset int64) error {
+	return notAllowed("WriteAt", path)
+}
+
+// WriteIf will always fail.
+func (a *adapter) WriteIf(ctx context.Context, path filesystem.Path, content []byte, cond filesystem.Precondition, cfg filesystem.Config) error {
+	return notAllowed("WriteIf", path)
+}
+
+// DeleteIf will always fail.
+func (a *adapter) DeleteIf(ctx context.Context, path filesystem.Path, cond filesystem.Precondition) error {
+	return notAllowed("DeleteIf", path)
+}
+
+// ListVersions will list the versions from the decorated adapter, if it keeps versions.
+func (a *adapter) ListVersions(ctx context.Context, path filesystem.Path) ([]filesystem.Metadata, error) {
+	if v, ok := a.Adapter.(filesystem.Versioned); ok {
+		return v.ListVersions(ctx, path)
+	}
+	return nil, filesystem.NewUnsupportedOperationError(path, "ListVersions")
+}
+
+// ReadVersion will read the version from the decorated adapter, if it keeps versions.
+func (a *adapter) ReadVersion(ctx context.Context, path filesystem.Path, versionID string) (io.ReadCloser, error) {
+	if v, ok := a.Adapter.(filesystem.Versioned); ok {
+		return v.ReadVersion(ctx, path, versionID)
+	}
+	return nil, filesystem.NewUnsupportedOperationError(path, "ReadVersion")
+}
+
+// RestoreVersion will always fail.
+func (a *adapter) RestoreVersion(ctx context.Context, path filesystem.Path, versionID string) error {
+	return notAllowed("RestoreVersion", path)
+}
+
+// IterateContents will iterate over the contents lazily when the decorated adapter supports it.
+func (a *adapter) IterateContents(ctx context.Context, path filesystem.Path, recursive bool) (filesystem.Iterator, error) {
+	if it, ok := a.Adapter.(filesystem.ContentsIterator); ok {
+		return it.IterateContents(ctx, path, recursive)
+	}
+	listing, err := a.Adapter.ListContents(ctx, path, recursive)
+	if err != nil {
+		return nil, err
+	}
+	return filesystem.SliceIterator(listing), nil
+}
+
+// Close will close the decorated adapter.
+func (a *adapter) Close() error {
+	return filesystem.Close(a.Adapter)
+}
+
+// Capabilities will report the read capabilities of the decorated adapter, the write ones being cleared.
+func (a *adapter) Capabilities() filesystem.Capabilities {
+	c := filesystem.CapabilitiesOf(a.Adapter)
+	c.NativeCopy, c.NativeMove, c.NativeAppend = false, false, false
+	c.NativeDirCopy, c.NativeDirMove = false, false
+	c.UserMetadata, c.ConditionalWrites, c.AtomicWrites, c.RandomWrites = false, false, false, false
+	return c
+}
@@ -0,0 +1,72 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+)
+
+// AdapterFactory will create an adapter from provided settings, specific to the adapter.
+type AdapterFactory func(ctx context.Context, settings map[string]interface{}) (Adapter, error)
+
+var adapterFactories = struct {
+	sync.RWMutex
+	factories map[string]AdapterFactory
+}{factories: make(map[string]AdapterFactory)}
+
+func init() {
+	RegisterAdapter("dir", func(_ context.Context, settings map[string]interface{}) (Adapter, error) {
+		path, _ := settings["path"].(string)
+		if path == "" {
+			return nil, fmt.Errorf("dir: missing path setting")
+		}
+		return FromFS(os.DirFS(path)), nil
+	})
+}
+
+// RegisterAdapter will make the adapters created by factory available by provided name to NewByName. Adapter packages
+// register themselves from their init function, so that importing a package for its side effect is enough to create
+// its adapters by name. RegisterAdapter panics if the name is already registered.
+//
+// The "dir" adapter, serving the local directory of the "path" setting read-only, is always registered. The adapter
+// packages of this module register as "memory", "null", "s3", "b2", "smb", "gdrive" and "git", created from the
+// settings of their NewFromConfig function.
+func RegisterAdapter(name string, factory AdapterFactory) {
+	adapterFactories.Lock()
+	defer adapterFactories.Unlock()
+	if factory == nil {
+		panic("filesystem: RegisterAdapter factory is nil")
+	}
+	if _, ok := adapterFactories.factories[name]; ok {
+		panic("filesystem: RegisterAdapter called twice for adapter " + name)
+	}
+	adapterFactories.factories[name] = factory
+}
+
+// NewByName will create an adapter with the factory registered by provided name.
+func NewByName(ctx context.Context, name string, settings map[string]interface{}) (Adapter, error) {
+	adapterFactories.RLock()
+	factory, ok := adapterFactories.factories[name]
+	adapterFactories.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown adapter %q", name)
+	}
+	if settings == nil {
+		settings = make(map[string]interface{})
+	}
+	return factory(ctx, settings)
+}
+
+// Adapters will return the names of the registered adapters, sorted.
+func Adapters() []string {
+	adapterFactories.RLock()
+	defer adapterFactories.RUnlock()
+	names := make([]string, 0, len(adapterFactories.factories))
+	for name := range adapterFactories.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,112 @@
+package replay
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/maurofran/filesystem"
+)
+
+// Interaction is a recorded adapter call.
+type Interaction struct {
+	Op        string          `json:"op"`
+	Path      filesystem.Path `json:"path"`
+	NewPath   filesystem.Path `json:"newpath,omitempty"`
+	Recursive bool            `json:"recursive,omitempty"`
+	// PayloadHash is the SHA-256 of written content.
+	PayloadHash string `json:"payload_hash,omitempty"`
+	// Content is the content returned by reads.
+	Content    []byte                `json:"content,omitempty"`
+	Bool       bool                  `json:"bool,omitempty"`
+	Size       int64                 `json:"size,omitempty"`
+	Time       time.Time             `json:"time,omitempty"`
+	MimeType   string                `json:"mimetype,omitempty"`
+	Visibility filesystem.Visibility `json:"visibility,omitempty"`
+	Metadata   *metadata             `json:"metadata,omitempty"`
+	Listing    []metadata            `json:"listing,omitempty"`
+	Error      string                `json:"error,omitempty"`
+	ErrorKind  string                `json:"error_kind,omitempty"`
+}
+
+const errorKindFileNotFound = "file_not_found"
+
+func (i *Interaction) setError(err error) {
+	if err == nil {
+		return
+	}
+	i.Error = err.Error()
+	if filesystem.IsFileNotFound(err) {
+		i.ErrorKind = errorKindFileNotFound
+	}
+}
+
+func (i *Interaction) err() error {
+	if i.Error == "" {
+		return nil
+	}
+	if i.ErrorKind == errorKindFileNotFound {
+		return filesystem.NewFileNotFoundError(i.Path)
+	}
+	return errors.New(i.Error)
+}
+
+// metadata is the JSON form of filesystem.Metadata, telling unknown sizes and timestamps apart.
+type metadata struct {
+	Path       filesystem.Path        `json:"path"`
+	Type       filesystem.EntryType   `json:"type,omitempty"`
+	Size       *int64                 `json:"size,omitempty"`
+	Timestamp  *time.Time             `json:"timestamp,omitempty"`
+	MimeType   string                 `json:"mimetype,omitempty"`
+	Visibility filesystem.Visibility  `json:"visibility,omitempty"`
+	ETag       string                 `json:"etag,omitempty"`
+	Content    []byte                 `json:"content,omitempty"`
+	Extra      map[string]interface{} `json:"extra,omitempty"`
+}
+
+func encodeMetadata(md filesystem.Metadata) metadata {
+	m := metadata{
+		Path:       md.Path,
+		Type:       md.Type,
+		MimeType:   md.MimeType,
+		Visibility: md.Visibility,
+		ETag:       md.ETag,
+		Content:    md.Content,
+		Extra:      md.Extra,
+	}
+	if md.Size >= 0 {
+		size := md.Size
+		m.Size = &size
+	}
+	if !md.Timestamp.IsZero() {
+		ts := md.Timestamp
+		m.Timestamp = &ts
+	}
+	return m
+}
+
+func (m metadata) decode() filesystem.Metadata {
+	md := &filesystem.FileInfo{
+		Path:       m.Path,
+		Type:       m.Type,
+		Size:       -1,
+		MimeType:   m.MimeType,
+		Visibility: m.Visibility,
+		ETag:       m.ETag,
+		Content:    m.Content,
+		Extra:      m.Extra,
+	}
+	if m.Size != nil {
+		md.Size = *m.Size
+	}
+	if m.Timestamp != nil {
+		md.Timestamp = *m.Timestamp
+	}
+	return md
+}
+
+func payloadHash(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,231 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/maurofran/filesystem"
+)
+
+// Recorder is an adapter decorator capturing every call made to the decorated adapter.
+type Recorder struct {
+	adapter      filesystem.Adapter
+	mu           sync.Mutex
+	interactions []Interaction
+}
+
+// NewRecorder will create a recorder decorating provided adapter.
+func NewRecorder(a filesystem.Adapter) *Recorder {
+	return &Recorder{adapter: a}
+}
+
+func (r *Recorder) record(i Interaction, err error) {
+	i.setError(err)
+	r.mu.Lock()
+	r.interactions = append(r.interactions, i)
+	r.mu.Unlock()
+}
+
+// Save will write the recorded interactions as a JSON fixture.
+func (r *Recorder) Save(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r.interactions)
+}
+
+// SaveFile will write the recorded interactions as a JSON fixture to the named file.
+func (r *Recorder) SaveFile(name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := r.Save(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// hashingReader computes the SHA-256 of content read through it.
+type hashingReader struct {
+	io.Reader
+	sum hash.Hash
+}
+
+func newHashingReader(r io.Reader) *hashingReader {
+	h := sha256.New()
+	return &hashingReader{io.TeeReader(r, h), h}
+}
+
+func (h *hashingReader) hash() string {
+	return hex.EncodeToString(h.sum.Sum(nil))
+}
+
+func (r *Recorder) Has(ctx context.Context, path filesystem.Path) (bool, error) {
+	has, err := r.adapter.Has(ctx, path)
+	r.record(Interaction{Op: "Has", Path: path, Bool: has}, err)
+	return has, err
+}
+
+func (r *Recorder) Read(ctx context.Context, path filesystem.Path) ([]byte, error) {
+	content, err := r.adapter.Read(ctx, path)
+	r.record(Interaction{Op: "Read", Path: path, Content: content}, err)
+	return content, err
+}
+
+// ReadStream will read the whole stream in memory to record it.
+func (r *Recorder) ReadStream(ctx context.Context, path filesystem.Path) (io.ReadCloser, error) {
+	rc, err := r.adapter.ReadStream(ctx, path)
+	if err != nil {
+		r.record(Interaction{Op: "ReadStream", Path: path}, err)
+		return nil, err
+	}
+	defer rc.Close()
+	b, err := io.ReadAll(rc)
+	r.record(Interaction{Op: "ReadStream", Path: path, Content: b}, err)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(b)), nil
+}
+
+func (r *Recorder) Write(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	err := r.adapter.Write(ctx, path, content, cfg)
+	r.record(Interaction{Op: "Write", Path: path, PayloadHash: payloadHash(content)}, err)
+	return err
+}
+
+func (r *Recorder) WriteStream(ctx context.Context, path filesystem.Path, rd io.Reader, cfg filesystem.Config) error {
+	h := newHashingReader(rd)
+	err := r.adapter.WriteStream(ctx, path, h, cfg)
+	r.record(Interaction{Op: "WriteStream", Path: path, PayloadHash: h.hash()}, err)
+	return err
+}
+
+func (r *Recorder) Update(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	err := r.adapter.Update(ctx, path, content, cfg)
+	r.record(Interaction{Op: "Update", Path: path, PayloadHash: payloadHash(content)}, err)
+	return err
+}
+
+func (r *Recorder) UpdateStream(ctx context.Context, path filesystem.Path, rd io.Reader, cfg filesystem.Config) error {
+	h := newHashingReader(rd)
+	err := r.adapter.UpdateStream(ctx, path, h, cfg)
+	r.record(Interaction{Op: "UpdateStream", Path: path, PayloadHash: h.hash()}, err)
+	return err
+}
+
+func (r *Recorder) Put(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	err := r.adapter.Put(ctx, path, content, cfg)
+	r.record(Interaction{Op: "Put", Path: path, PayloadHash: payloadHash(content)}, err)
+	return err
+}
+
+func (r *Recorder) PutStream(ctx context.Context, path filesystem.Path, rd io.Reader, cfg filesystem.Config) error {
+	h := newHashingReader(rd)
+	err := r.adapter.PutStream(ctx, path, h, cfg)
+	r.record(Interaction{Op: "PutStream", Path: path, PayloadHash: h.hash()}, err)
+	return err
+}
+
+func (r *Recorder) Delete(ctx context.Context, path filesystem.Path) error {
+	err := r.adapter.Delete(ctx, path)
+	r.record(Interaction{Op: "Delete", Path: path}, err)
+	return err
+}
+
+func (r *Recorder) ReadAndDelete(ctx context.Context, path filesystem.Path) ([]byte, error) {
+	content, err := r.adapter.ReadAndDelete(ctx, path)
+	r.record(Interaction{Op: "ReadAndDelete", Path: path, Content: content}, err)
+	return content, err
+}
+
+func (r *Recorder) Move(ctx context.Context, path, newpath filesystem.Path) error {
+	err := r.adapter.Move(ctx, path, newpath)
+	r.record(Interaction{Op: "Move", Path: path, NewPath: newpath}, err)
+	return err
+}
+
+func (r *Recorder) Copy(ctx context.Context, path, newpath filesystem.Path) error {
+	err := r.adapter.Copy(ctx, path, newpath)
+	r.record(Interaction{Op: "Copy", Path: path, NewPath: newpath}, err)
+	return err
+}
+
+func (r *Recorder) GetMimeType(ctx context.Context, path filesystem.Path) (string, error) {
+	mimeType, err := r.adapter.GetMimeType(ctx, path)
+	r.record(Interaction{Op: "GetMimeType", Path: path, MimeType: mimeType}, err)
+	return mimeType, err
+}
+
+func (r *Recorder) GetTimestamp(ctx context.Context, path filesystem.Path) (time.Time, error) {
+	ts, err := r.adapter.GetTimestamp(ctx, path)
+	r.record(Interaction{Op: "GetTimestamp", Path: path, Time: ts}, err)
+	return ts, err
+}
+
+func (r *Recorder) GetFileSize(ctx context.Context, path filesystem.Path) (int64, error) {
+	size, err := r.adapter.GetFileSize(ctx, path)
+	r.record(Interaction{Op: "GetFileSize", Path: path, Size: size}, err)
+	return size, err
+}
+
+func (r *Recorder) GetMetadata(ctx context.Context, path filesystem.Path) (filesystem.Metadata, error) {
+	md, err := r.adapter.GetMetadata(ctx, path)
+	i := Interaction{Op: "GetMetadata", Path: path}
+	if md != nil {
+		m := encodeMetadata(md)
+		i.Metadata = &m
+	}
+	r.record(i, err)
+	return md, err
+}
+
+func (r *Recorder) CreateDir(ctx context.Context, path filesystem.Path, cfg filesystem.Config) error {
+	err := r.adapter.CreateDir(ctx, path, cfg)
+	r.record(Interaction{Op: "CreateDir", Path: path}, err)
+	return err
+}
+
+func (r *Recorder) DeleteDir(ctx context.Context, path filesystem.Path) error {
+	err := r.adapter.DeleteDir(ctx, path)
+	r.record(Interaction{Op: "DeleteDir", Path: path}, err)
+	return err
+}
+
+func (r *Recorder) GetVisibility(ctx context.Context, path filesystem.Path) (filesystem.Visibility, error) {
+	v, err := r.adapter.GetVisibility(ctx, path)
+	r.record(Interaction{Op: "GetVisibility", Path: path, Visibility: v}, err)
+	return v, err
+}
+
+func (r *Recorder) SetVisibility(ctx context.Context, path filesystem.Path, v filesystem.Visibility) error {
+	err := r.adapter.SetVisibility(ctx, path, v)
+	r.record(Interaction{Op: "SetVisibility", Path: path, Visibility: v}, err)
+	return err
+}
+
+func (r *Recorder) ListContents(ctx context.Context, path filesystem.Path, recursive bool) ([]filesystem.Metadata, error) {
+	listing, err := r.adapter.ListContents(ctx, path, recursive)
+	i := Interaction{Op: "ListContents", Path: path, Recursive: recursive}
+	for _, md := range listing {
+		i.Listing = append(i.Listing, encodeMetadata(md))
+	}
+	r.record(i, err)
+	return listing, err
+}
+
+// Close will close the decorated adapter.
+func (r *Recorder) Close() error {
+	return filesystem.Close(r.adapter)
+}
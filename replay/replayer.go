@@ -0,0 +1,225 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/maurofran/filesystem"
+)
+
+// Replayer is an adapter serving back recorded interactions, in recording order. A call that does not match the
+// next recorded interaction fails with a MismatchError.
+type Replayer struct {
+	mu           sync.Mutex
+	interactions []Interaction
+	next         int
+}
+
+// Load will create a replayer from a JSON fixture.
+func Load(r io.Reader) (*Replayer, error) {
+	var interactions []Interaction
+	if err := json.NewDecoder(r).Decode(&interactions); err != nil {
+		return nil, err
+	}
+	return &Replayer{interactions: interactions}, nil
+}
+
+// LoadFile will create a replayer from the named JSON fixture file.
+func LoadFile(name string) (*Replayer, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Load(f)
+}
+
+// Done will check that every recorded interaction was replayed.
+func (r *Replayer) Done() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.next < len(r.interactions) {
+		return fmt.Errorf("%d recorded interactions were not replayed", len(r.interactions)-r.next)
+	}
+	return nil
+}
+
+// MismatchError is the error returned when a call does not match the next recorded interaction.
+type MismatchError interface {
+	error
+	Expected() Interaction
+}
+
+type mismatchError struct {
+	expected Interaction
+	actual   Interaction
+}
+
+// Expected is the interaction that was expected, with a zero Op if none was left.
+func (e mismatchError) Expected() Interaction {
+	return e.expected
+}
+
+func (e mismatchError) Error() string {
+	if e.expected.Op == "" {
+		return fmt.Sprintf("Unexpected %s(%s): no recorded interaction left", e.actual.Op, e.actual.Path)
+	}
+	return fmt.Sprintf("Unexpected %s(%s): recorded %s(%s)", e.actual.Op, e.actual.Path, e.expected.Op, e.expected.Path)
+}
+
+// IsMismatchError will check if provided error is a mismatch error.
+func IsMismatchError(err error) bool {
+	_, ok := err.(MismatchError)
+	return ok
+}
+
+// replay will return the next interaction if it matches the call.
+func (r *Replayer) replay(call Interaction) (Interaction, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.next >= len(r.interactions) {
+		return Interaction{}, mismatchError{actual: call}
+	}
+	i := r.interactions[r.next]
+	if i.Op != call.Op || i.Path != call.Path || i.NewPath != call.NewPath || i.Recursive != call.Recursive ||
+		i.PayloadHash != call.PayloadHash || call.Op == "SetVisibility" && i.Visibility != call.Visibility {
+		return Interaction{}, mismatchError{i, call}
+	}
+	r.next++
+	return i, i.err()
+}
+
+func (r *Replayer) replayStream(op string, path filesystem.Path, rd io.Reader) error {
+	b, err := io.ReadAll(rd)
+	if err != nil {
+		return err
+	}
+	_, err = r.replay(Interaction{Op: op, Path: path, PayloadHash: payloadHash(b)})
+	return err
+}
+
+func (r *Replayer) Has(ctx context.Context, path filesystem.Path) (bool, error) {
+	i, err := r.replay(Interaction{Op: "Has", Path: path})
+	return i.Bool, err
+}
+
+func (r *Replayer) Read(ctx context.Context, path filesystem.Path) ([]byte, error) {
+	i, err := r.replay(Interaction{Op: "Read", Path: path})
+	return i.Content, err
+}
+
+func (r *Replayer) ReadStream(ctx context.Context, path filesystem.Path) (io.ReadCloser, error) {
+	i, err := r.replay(Interaction{Op: "ReadStream", Path: path})
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(i.Content)), nil
+}
+
+func (r *Replayer) Write(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	_, err := r.replay(Interaction{Op: "Write", Path: path, PayloadHash: payloadHash(content)})
+	return err
+}
+
+func (r *Replayer) WriteStream(ctx context.Context, path filesystem.Path, rd io.Reader, cfg filesystem.Config) error {
+	return r.replayStream("WriteStream", path, rd)
+}
+
+func (r *Replayer) Update(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	_, err := r.replay(Interaction{Op: "Update", Path: path, PayloadHash: payloadHash(content)})
+	return err
+}
+
+func (r *Replayer) UpdateStream(ctx context.Context, path filesystem.Path, rd io.Reader, cfg filesystem.Config) error {
+	return r.replayStream("UpdateStream", path, rd)
+}
+
+func (r *Replayer) Put(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	_, err := r.replay(Interaction{Op: "Put", Path: path, PayloadHash: payloadHash(content)})
+	return err
+}
+
+func (r *Replayer) PutStream(ctx context.Context, path filesystem.Path, rd io.Reader, cfg filesystem.Config) error {
+	return r.replayStream("PutStream", path, rd)
+}
+
+func (r *Replayer) Delete(ctx context.Context, path filesystem.Path) error {
+	_, err := r.replay(Interaction{Op: "Delete", Path: path})
+	return err
+}
+
+func (r *Replayer) ReadAndDelete(ctx context.Context, path filesystem.Path) ([]byte, error) {
+	i, err := r.replay(Interaction{Op: "ReadAndDelete", Path: path})
+	return i.Content, err
+}
+
+func (r *Replayer) Move(ctx context.Context, path, newpath filesystem.Path) error {
+	_, err := r.replay(Interaction{Op: "Move", Path: path, NewPath: newpath})
+	return err
+}
+
+func (r *Replayer) Copy(ctx context.Context, path, newpath filesystem.Path) error {
+	_, err := r.replay(Interaction{Op: "Copy", Path: path, NewPath: newpath})
+	return err
+}
+
+func (r *Replayer) GetMimeType(ctx context.Context, path filesystem.Path) (string, error) {
+	i, err := r.replay(Interaction{Op: "GetMimeType", Path: path})
+	return i.MimeType, err
+}
+
+func (r *Replayer) GetTimestamp(ctx context.Context, path filesystem.Path) (time.Time, error) {
+	i, err := r.replay(Interaction{Op: "GetTimestamp", Path: path})
+	return i.Time, err
+}
+
+func (r *Replayer) GetFileSize(ctx context.Context, path filesystem.Path) (int64, error) {
+	i, err := r.replay(Interaction{Op: "GetFileSize", Path: path})
+	return i.Size, err
+}
+
+func (r *Replayer) GetMetadata(ctx context.Context, path filesystem.Path) (filesystem.Metadata, error) {
+	i, err := r.replay(Interaction{Op: "GetMetadata", Path: path})
+	if i.Metadata == nil {
+		return nil, err
+	}
+	return i.Metadata.decode(), err
+}
+
+func (r *Replayer) CreateDir(ctx context.Context, path filesystem.Path, cfg filesystem.Config) error {
+	_, err := r.replay(Interaction{Op: "CreateDir", Path: path})
+	return err
+}
+
+func (r *Replayer) DeleteDir(ctx context.Context, path filesystem.Path) error {
+	_, err := r.replay(Interaction{Op: "DeleteDir", Path: path})
+	return err
+}
+
+func (r *Replayer) GetVisibility(ctx context.Context, path filesystem.Path) (filesystem.Visibility, error) {
+	i, err := r.replay(Interaction{Op: "GetVisibility", Path: path})
+	return i.Visibility, err
+}
+
+func (r *Replayer) SetVisibility(ctx context.Context, path filesystem.Path, v filesystem.Visibility) error {
+	_, err := r.replay(Interaction{Op: "SetVisibility", Path: path, Visibility: v})
+	return err
+}
+
+func (r *Replayer) ListContents(ctx context.Context, path filesystem.Path, recursive bool) ([]filesystem.Metadata, error) {
+	i, err := r.replay(Interaction{Op: "ListContents", Path: path, Recursive: recursive})
+	if err != nil {
+		return nil, err
+	}
+	listing := make([]filesystem.Metadata, 0, len(i.Listing))
+	for _, m := range i.Listing {
+		listing = append(listing, m.decode())
+	}
+	return listing, nil
+}
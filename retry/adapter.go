@@ -0,0 +1,379 @@
+package retry
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/maurofran/filesystem"
+)
+
+// Wrap will decorate provided adapter so that operations failing with a retryable error are attempted again
+// according to provided policy. Only idempotent operations are retried, unless the policy enables RetryWrites.
+func Wrap(a filesystem.Adapter, p Policy) filesystem.Adapter {
+	return &adapter{Adapter: a, policy: p}
+}
+
+type adapter struct {
+	filesystem.Adapter
+	policy Policy
+}
+
+// do will run an idempotent operation with retries.
+func (a *adapter) do(ctx context.Context, fn func() error) error {
+	return a.policy.Do(ctx, fn)
+}
+
+// doWrite will run an operation that is not idempotent, with retries only if enabled by policy.
+func (a *adapter) doWrite(ctx context.Context, fn func() error) error {
+	if !a.policy.RetryWrites {
+		return fn()
+	}
+	return a.policy.Do(ctx, fn)
+}
+
+func (a *adapter) Has(ctx context.Context, path filesystem.Path) (ok bool, err error) {
+	err = a.do(ctx, func() error {
+		ok, err = a.Adapter.Has(ctx, path)
+		return err
+	})
+	return ok, err
+}
+
+func (a *adapter) Read(ctx context.Context, path filesystem.Path) (content []byte, err error) {
+	err = a.do(ctx, func() error {
+		content, err = a.Adapter.Read(ctx, path)
+		return err
+	})
+	return content, err
+}
+
+// ReadStream will retry opening the stream; failures while reading it are not retried.
+func (a *adapter) ReadStream(ctx context.Context, path filesystem.Path) (rc io.ReadCloser, err error) {
+	err = a.do(ctx, func() error {
+		rc, err = a.Adapter.ReadStream(ctx, path)
+		return err
+	})
+	return rc, err
+}
+
+func (a *adapter) Write(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	return a.doWrite(ctx, func() error {
+		return a.Adapter.Write(ctx, path, content, cfg)
+	})
+}
+
+func (a *adapter) Update(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	return a.do(ctx, func() error {
+		return a.Adapter.Update(ctx, path, content, cfg)
+	})
+}
+
+func (a *adapter) Put(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	return a.do(ctx, func() error {
+		return a.Adapter.Put(ctx, path, content, cfg)
+	})
+}
+
+func (a *adapter) Delete(ctx context.Context, path filesystem.Path) error {
+	return a.doWrite(ctx, func() error {
+		return a.Adapter.Delete(ctx, path)
+	})
+}
+
+func (a *adapter) ReadAndDelete(ctx context.Context, path filesystem.Path) (content []byte, err error) {
+	err = a.doWrite(ctx, func() error {
+		content, err = a.Adapter.ReadAndDelete(ctx, path)
+		return err
+	})
+	return content, err
+}
+
+func (a *adapter) Move(ctx context.Context, path, newpath filesystem.Path) error {
+	return a.doWrite(ctx, func() error {
+		return a.Adapter.Move(ctx, path, newpath)
+	})
+}
+
+func (a *adapter) Copy(ctx context.Context, path, newpath filesystem.Path) error {
+	return a.do(ctx, func() error {
+		return a.Adapter.Copy(ctx, path, newpath)
+	})
+}
+
+func (a *adapter) GetMimeType(ctx context.Context, path filesystem.Path) (mt string, err error) {
+	err = a.do(ctx, func() error {
+		mt, err = a.Adapter.GetMimeType(ctx, path)
+		return err
+	})
+	return mt, err
+}
+
+func (a *adapter) GetTimestamp(ctx context.Context, path filesystem.Path) (t time.Time, err error) {
+	err = a.do(ctx, func() error {
+		t, err = a.Adapter.GetTimestamp(ctx, path)
+		return err
+	})
+	return t, err
+}
+
+func (a *adapter) GetFileSize(ctx context.Context, path filesystem.Path) (size int64, err error) {
+	err = a.do(ctx, func() error {
+		size, err = a.Adapter.GetFileSize(ctx, path)
+		return err
+	})
+	return size, err
+}
+
+func (a *adapter) GetMetadata(ctx context.Context, path filesystem.Path) (md filesystem.Metadata, err error) {
+	err = a.do(ctx, func() error {
+		md, err = a.Adapter.GetMetadata(ctx, path)
+		return err
+	})
+	return md, err
+}
+
+func (a *adapter) CreateDir(ctx context.Context, path filesystem.Path, cfg filesystem.Config) error {
+	return a.do(ctx, func() error {
+		return a.Adapter.CreateDir(ctx, path, cfg)
+	})
+}
+
+func (a *adapter) DeleteDir(ctx context.Context, path filesystem.Path) error {
+	return a.doWrite(ctx, func() error {
+		return a.Adapter.DeleteDir(ctx, path)
+	})
+}
+
+func (a *adapter) GetVisibility(ctx context.Context, path filesystem.Path) (v filesystem.Visibility, err error) {
+	err = a.do(ctx, func() error {
+		v, err = a.Adapter.GetVisibility(ctx, path)
+		return err
+	})
+	return v, err
+}
+
+func (a *adapter) SetVisibility(ctx context.Context, path filesystem.Path, v filesystem.Visibility) error {
+	return a.do(ctx, func() error {
+		return a.Adapter.SetVisibility(ctx, path, v)
+	})
+}
+
+func (a *adapter) ListContents(ctx context.Context, path filesystem.Path, recursive bool) (listing []filesystem.Metadata, err error) {
+	err = a.do(ctx, func() error {
+		listing, err = a.Adapter.ListContents(ctx, path, recursive)
+		return err
+	})
+	return listing, err
+}
+
+// ReadRange will retry opening the range, natively when the decorated adapter supports it.
+func (a *adapter) ReadRange(ctx context.Context, path filesystem.Path, offset, length int64) (rc io.ReadCloser, err error) {
+	r, ok := a.Adapter.(filesystem.RangeReader)
+	if !ok {
+		if rc, err = a.ReadStream(ctx, path); err != nil {
+			return nil, err
+		}
+		return filesystem.SliceStream(rc, offset, length)
+	}
+	err = a.do(ctx, func() error {
+		rc, err = r.ReadRange(ctx, path, offset, length)
+		return err
+	})
+	return rc, err
+}
+
+// GetChecksum will return the checksum natively provided by the decorated adapter, if any.
+func (a *adapter) GetChecksum(ctx context.Context, path filesystem.Path, algo filesystem.ChecksumAlgo) (sum string, err error) {
+	p, ok := a.Adapter.(filesystem.ChecksumProvider)
+	if !ok {
+		return "", filesystem.NewUnsupportedChecksumError(path, algo)
+	}
+	err = a.do(ctx, func() error {
+		sum, err = p.GetChecksum(ctx, path, algo)
+		return err
+	})
+	return sum, err
+}
+
+// FileExists will check if a file exists natively when the decorated adapter supports it.
+func (a *adapter) FileExists(ctx context.Context, path filesystem.Path) (ok bool, err error) {
+	c, isChecker := a.Adapter.(filesystem.ExistenceChecker)
+	if !isChecker {
+		return false, filesystem.NewUnsupportedOperationError(path, "FileExists")
+	}
+	err = a.do(ctx, func() error {
+		ok, err = c.FileExists(ctx, path)
+		return err
+	})
+	return ok, err
+}
+
+// DirectoryExists will check if a directory exists natively when the decorated adapter supports it.
+func (a *adapter) DirectoryExists(ctx context.Context, path filesystem.Path) (ok bool, err error) {
+	c, isChecker := a.Adapter.(filesystem.ExistenceChecker)
+	if !isChecker {
+		return false, filesystem.NewUnsupportedOperationError(path, "DirectoryExists")
+	}
+	err = a.do(ctx, func() error {
+		ok, err = c.DirectoryExists(ctx, path)
+		return err
+	})
+	return ok, err
+}
+
+// GetDirectoryMetadata will describe the directory natively when the decorated adapter supports it.
+func (a *adapter) GetDirectoryMetadata(ctx context.Context, path filesystem.Path) (info filesystem.DirectoryInfo, err error) {
+	p, ok := a.Adapter.(filesystem.DirectoryMetadataProvider)
+	if !ok {
+		return info, filesystem.NewUnsupportedOperationError(path, "GetDirectoryMetadata")
+	}
+	err = a.do(ctx, func() error {
+		info, err = p.GetDirectoryMetadata(ctx, path)
+		return err
+	})
+	return info, err
+}
+
+// ListPage will list a page natively when the decorated adapter supports it.
+func (a *adapter) ListPage(ctx context.Context, path filesystem.Path, recursive bool, cursor string, limit int) (page filesystem.Page, err error) {
+	l, ok := a.Adapter.(filesystem.PageLister)
+	if !ok {
+		return page, filesystem.NewUnsupportedOperationError(path, "ListPage")
+	}
+	err = a.do(ctx, func() error {
+		page, err = l.ListPage(ctx, path, recursive, cursor, limit)
+		return err
+	})
+	return page, err
+}
+
+// IterateSorted will iterate in order natively when the decorated adapter supports it.
+func (a *adapter) IterateSorted(ctx context.Context, path filesystem.Path, recursive bool, s filesystem.Sort) (it filesystem.Iterator, err error) {
+	si, ok := a.Adapter.(filesystem.SortedIterator)
+	if !ok {
+		return nil, filesystem.NewUnsupportedOperationError(path, "IterateSorted")
+	}
+	err = a.do(ctx, func() error {
+		it, err = si.IterateSorted(ctx, path, recursive, s)
+		return err
+	})
+	return it, err
+}
+
+// GetUserMetadata will return the user metadata from the decorated adapter, if it supports it.
+func (a *adapter) GetUserMetadata(ctx context.Context, path filesystem.Path) (md map[string]string, err error) {
+	p, ok := a.Adapter.(filesystem.UserMetadataProvider)
+	if !ok {
+		return nil, filesystem.NewUnsupportedOperationError(path, "GetUserMetadata")
+	}
+	err = a.do(ctx, func() error {
+		md, err = p.GetUserMetadata(ctx, path)
+		return err
+	})
+	return md, err
+}
+
+// SetUserMetadata will set the user metadata through the decorated adapter, if it supports it.
+func (a *adapter) SetUserMetadata(ctx context.Context, path filesystem.Path, metadata map[string]string) error {
+	p, ok := a.Adapter.(filesystem.UserMetadataProvider)
+	if !ok {
+		return filesystem.NewUnsupportedOperationError(path, "SetUserMetadata")
+	}
+	return a.do(ctx, func() error {
+		return p.SetUserMetadata(ctx, path, metadata)
+	})
+}
+
+// WriteAtomic will write atomically through the decorated adapter, if it has a dedicated mechanism. Streams cannot
+// be replayed, so it is not retried.
+func (a *adapter) WriteAtomic(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	w, ok := a.Adapter.(filesystem.AtomicWriter)
+	if !ok {
+		return filesystem.NewUnsupportedOperationError(path, "WriteAtomic")
+	}
+	return w.WriteAtomic(ctx, path, r, cfg)
+}
+
+// WriteAt will write part of a file in place through the decorated adapter, if it supports it, retrying as other
+// writes.
+func (a *adapter) WriteAt(ctx context.Context, path filesystem.Path, p []byte, offset int64) error {
+	w, ok := a.Adapter.(filesystem.RandomWriter)
+	if !ok {
+		return filesystem.NewUnsupportedOperationError(path, "WriteAt")
+	}
+	return a.doWrite(ctx, func() error {
+		return w.WriteAt(ctx, path, p, offset)
+	})
+}
+
+// WriteIf will write conditionally through the decorated adapter, if it supports it. A retried write whose first
+// attempt succeeded fails its precondition, so it is only retried if enabled by policy.
+func (a *adapter) WriteIf(ctx context.Context, path filesystem.Path, content []byte, cond filesystem.Precondition, cfg filesystem.Config) error {
+	w, ok := a.Adapter.(filesystem.ConditionalWriter)
+	if !ok {
+		return filesystem.NewUnsupportedOperationError(path, "WriteIf")
+	}
+	return a.doWrite(ctx, func() error {
+		return w.WriteIf(ctx, path, content, cond, cfg)
+	})
+}
+
+// DeleteIf will delete conditionally through the decorated adapter, if it supports it.
+func (a *adapter) DeleteIf(ctx context.Context, path filesystem.Path, cond filesystem.Precondition) error {
+	w, ok := a.Adapter.(filesystem.ConditionalWriter)
+	if !ok {
+		return filesystem.NewUnsupportedOperationError(path, "DeleteIf")
+	}
+	return a.doWrite(ctx, func() error {
+		return w.DeleteIf(ctx, path, cond)
+	})
+}
+
+// ListVersions will list the versions through the decorated adapter, if it keeps versions.
+func (a *adapter) ListVersions(ctx context.Context, path filesystem.Path) (versions []filesystem.Metadata, err error) {
+	v, ok := a.Adapter.(filesystem.Versioned)
+	if !ok {
+		return nil, filesystem.NewUnsupportedOperationError(path, "ListVersions")
+	}
+	err = a.do(ctx, func() error {
+		versions, err = v.ListVersions(ctx, path)
+		return err
+	})
+	return versions, err
+}
+
+// ReadVersion will read the version through the decorated adapter, if it keeps versions.
+func (a *adapter) ReadVersion(ctx context.Context, path filesystem.Path, versionID string) (rc io.ReadCloser, err error) {
+	v, ok := a.Adapter.(filesystem.Versioned)
+	if !ok {
+		return nil, filesystem.NewUnsupportedOperationError(path, "ReadVersion")
+	}
+	err = a.do(ctx, func() error {
+		rc, err = v.ReadVersion(ctx, path, versionID)
+		return err
+	})
+	return rc, err
+}
+
+// RestoreVersion will restore the version through the decorated adapter, if it keeps versions. Restoring the same
+// version twice leaves the same content, so it is retried.
+func (a *adapter) RestoreVersion(ctx context.Context, path filesystem.Path, versionID string) error {
+	v, ok := a.Adapter.(filesystem.Versioned)
+	if !ok {
+		return filesystem.NewUnsupportedOperationError(path, "RestoreVersion")
+	}
+	return a.do(ctx, func() error {
+		return v.RestoreVersion(ctx, path, versionID)
+	})
+}
+
+// Close will close the decorated adapter.
+func (a *adapter) Close() error {
+	return filesystem.Close(a.Adapter)
+}
+
+// Capabilities will report the capabilities of the decorated adapter.
+func (a *adapter) Capabilities() filesystem.Capabilities {
+	return filesystem.CapabilitiesOf(a.Adapter)
+}
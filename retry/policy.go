@@ -0,0 +1,144 @@
+// Package retry provides an adapter decorator retrying operations failing with transient errors, with exponential
+// backoff and jitter.
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/maurofran/filesystem"
+)
+
+// Policy configures how operations are retried.
+type Policy struct {
+	// MaxAttempts is the total number of attempts of each operation, 3 if not positive.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry, 100ms if not positive.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between two attempts, 10s if not positive.
+	MaxBackoff time.Duration
+	// Multiplier grows the delay after each retry, 2 if less than 1.
+	Multiplier float64
+	// Jitter is the fraction of each delay that is randomized, between 0 and 1.
+	Jitter float64
+	// Retryable classifies the errors worth retrying, IsTransient if nil.
+	Retryable func(err error) bool
+	// RetryWrites enables retrying the operations that are not idempotent, such as Write, Delete and Move, which
+	// may fail on retry if the first attempt actually succeeded. Streamed writes are never retried, as the reader
+	// is consumed by the first attempt.
+	RetryWrites bool
+}
+
+// DefaultPolicy is the policy used when retrying with a zero Policy.
+var DefaultPolicy = Policy{
+	MaxAttempts:    3,
+	InitialBackoff: 100 * time.Millisecond,
+	MaxBackoff:     10 * time.Second,
+	Multiplier:     2,
+	Jitter:         0.2,
+}
+
+// IsTransient will check if provided error may go away on retry. Errors reporting missing files, invalid paths,
+// read-only file systems, unsupported operations, failed preconditions, held locks, upload offset mismatches,
+// codec or plugin failures or a done context are permanent. Otherwise, errors exposing Temporary or Timeout methods
+// are transient if either says so, as are network errors and connections cut short, and every other error is
+// considered permanent.
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if filesystem.IsFileNotFound(err) || filesystem.IsPathError(err) || filesystem.IsReadOnlyError(err) ||
+		filesystem.IsMountError(err) || filesystem.IsUnsupportedChecksumError(err) ||
+		filesystem.IsUnsupportedOperationError(err) || filesystem.IsPreconditionFailed(err) ||
+		filesystem.IsLockedError(err) || filesystem.IsUploadOffsetError(err) || filesystem.IsCodecError(err) ||
+		filesystem.IsPluginError(err) {
+		return false
+	}
+	var temporary interface{ Temporary() bool }
+	if errors.As(err, &temporary) && temporary.Temporary() {
+		return true
+	}
+	var timeout interface{ Timeout() bool }
+	if errors.As(err, &timeout) && timeout.Timeout() {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE)
+}
+
+func (p Policy) withDefaults() Policy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = DefaultPolicy.MaxAttempts
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = DefaultPolicy.InitialBackoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = DefaultPolicy.MaxBackoff
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = DefaultPolicy.Multiplier
+	}
+	if p.Retryable == nil {
+		p.Retryable = IsTransient
+	}
+	return p
+}
+
+// backoff will compute the delay before provided retry, starting at 1.
+func (p Policy) backoff(retry int) time.Duration {
+	d := float64(p.InitialBackoff)
+	for i := 1; i < retry && d < float64(p.MaxBackoff); i++ {
+		d *= p.Multiplier
+	}
+	d = min(d, float64(p.MaxBackoff))
+	if p.Jitter > 0 {
+		d += d * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(d)
+}
+
+// Do will run fn until it succeeds, fails with an error that is not retryable, runs out of attempts or context is
+// done, returning the last error.
+func (p Policy) Do(ctx context.Context, fn func() error) error {
+	p = p.withDefaults()
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(); err == nil || attempt >= p.MaxAttempts || !p.Retryable(err) {
+			return err
+		}
+		t := time.NewTimer(p.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+	}
+}
+
+// streamedWrites are the names of the operations consuming a reader, which cannot be attempted again.
+var streamedWrites = map[string]bool{"WriteStream": true, "UpdateStream": true, "PutStream": true, "WriteAtomic": true}
+
+// Middleware will create a middleware retrying the operations failing with a retryable error according to provided
+// policy, e.g. to retry the operations of a single mount with filesystem.WithMountMiddleware. As with Wrap, only the
+// operations reading the file system are retried unless the policy enables RetryWrites, and streamed writes never
+// are.
+func Middleware(p Policy) filesystem.Middleware {
+	return func(next filesystem.Handler) filesystem.Handler {
+		return func(ctx context.Context, op *filesystem.Operation) error {
+			if !op.IsRead() && (!p.RetryWrites || streamedWrites[op.Name]) {
+				return next(ctx, op)
+			}
+			return p.Do(ctx, func() error {
+				return next(ctx, op)
+			})
+		}
+	}
+}
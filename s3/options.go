@@ -0,0 +1,19 @@
+package s3
+
+import "github.com/maurofran/filesystem"
+
+// WithStorageClass will set the storage class of written objects, e.g. "STANDARD_IA".
+func WithStorageClass(class string) filesystem.WriteOption {
+	return filesystem.WithSetting(ConfigStorageClass, class)
+}
+
+// WithServerSideEncryption will set the server-side encryption of written objects, using provided KMS key when
+// encryption is "aws:kms".
+func WithServerSideEncryption(encryption, kmsKeyID string) filesystem.WriteOption {
+	return filesystem.Option(func(cfg *filesystem.Config) {
+		cfg.Set(ConfigServerSideEncryption, encryption)
+		if kmsKeyID != "" {
+			cfg.Set(ConfigKMSKeyID, kmsKeyID)
+		}
+	})
+}
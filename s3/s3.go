@@ -0,0 +1,894 @@
+// Package s3 provides an adapter storing files on Amazon S3 or any S3-compatible service, such as MinIO.
+//
+// Directories are key prefixes: they exist as long as they contain objects, and CreateDir stores an empty marker
+// object with a trailing slash so that empty directories are listed too.
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+
+	"github.com/maurofran/filesystem"
+	"github.com/maurofran/filesystem/visibilityconv"
+)
+
+// Settings read by NewFromConfig, and per-call config keys honored by writes.
+const (
+	// ConfigBucket is the name of the bucket.
+	ConfigBucket = "bucket"
+	// ConfigRegion is the region of the bucket.
+	ConfigRegion = "region"
+	// ConfigEndpoint is the endpoint of an S3-compatible service; path-style addressing is used when set.
+	ConfigEndpoint = "endpoint"
+	// ConfigPrefix is the key prefix under which files are stored.
+	ConfigPrefix = "prefix"
+	// ConfigStorageClass is the storage class of written objects, e.g. "STANDARD_IA".
+	ConfigStorageClass = "storage_class"
+	// ConfigServerSideEncryption is the server-side encryption of written objects, "AES256" or "aws:kms".
+	ConfigServerSideEncryption = "server_side_encryption"
+	// ConfigKMSKeyID is the KMS key used when server-side encryption is "aws:kms".
+	ConfigKMSKeyID = "kms_key_id"
+)
+
+const (
+	allUsersURI           = "http://acs.amazonaws.com/groups/global/AllUsers"
+	authenticatedUsersURI = "http://acs.amazonaws.com/groups/global/AuthenticatedUsers"
+)
+
+// VisibilityAuthenticatedRead is the visibility of objects readable by any authenticated AWS user.
+const VisibilityAuthenticatedRead filesystem.Visibility = "authenticated-read"
+
+func init() {
+	filesystem.RegisterVisibility(VisibilityAuthenticatedRead)
+	filesystem.RegisterAdapter("s3", func(ctx context.Context, settings map[string]interface{}) (filesystem.Adapter, error) {
+		return NewFromConfig(ctx, settings)
+	})
+}
+
+// Adapter is an adapter storing files as objects of an S3 bucket.
+type Adapter struct {
+	client   *s3.Client
+	uploader *manager.Uploader
+	bucket   string
+	prefix   filesystem.Path
+	defaults *filesystem.Config
+}
+
+// New will create an adapter storing files in provided bucket, under provided key prefix.
+func New(client *s3.Client, bucket string, prefix filesystem.Path) *Adapter {
+	return &Adapter{
+		client:   client,
+		uploader: manager.NewUploader(client),
+		bucket:   bucket,
+		prefix:   prefix,
+		defaults: filesystem.EmptyConfig(),
+	}
+}
+
+// NewFromConfig will create an adapter from provided settings, loading credentials from the environment. Storage
+// class, server-side encryption and visibilityconv.ConfigACLs settings are used as defaults for every write.
+func NewFromConfig(ctx context.Context, settings map[string]interface{}) (*Adapter, error) {
+	cfg := filesystem.EmptyConfig()
+	for k, v := range settings {
+		cfg.Set(k, v)
+	}
+	str := func(key string) string {
+		s, _ := cfg.Get(key, "").(string)
+		return s
+	}
+	var opts []func(*awsconfig.LoadOptions) error
+	if region := str(ConfigRegion); region != "" {
+		opts = append(opts, awsconfig.WithRegion(region))
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	client := s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		if endpoint := str(ConfigEndpoint); endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+			o.UsePathStyle = true
+		}
+	})
+	a := New(client, str(ConfigBucket), filesystem.Path(str(ConfigPrefix)))
+	a.defaults = cfg
+	return a, nil
+}
+
+func (a *Adapter) key(p filesystem.Path) string {
+	return string(a.prefix.Join(string(p)))
+}
+
+func (a *Adapter) dirKey(p filesystem.Path) string {
+	if k := a.key(p); k != "" {
+		return k + "/"
+	}
+	return ""
+}
+
+func (a *Adapter) path(key string) filesystem.Path {
+	return filesystem.Path(strings.TrimPrefix(strings.TrimPrefix(key, string(a.prefix)), "/"))
+}
+
+// setting will look up a per-call setting, falling back to the adapter defaults.
+func (a *Adapter) setting(cfg filesystem.Config, key string) string {
+	if s, ok := cfg.Get(key, "").(string); ok && s != "" {
+		return s
+	}
+	s, _ := a.defaults.Get(key, "").(string)
+	return s
+}
+
+// isNotFound will check if err reports a missing object or bucket key.
+func isNotFound(err error) bool {
+	var ae smithy.APIError
+	if errors.As(err, &ae) {
+		switch ae.ErrorCode() {
+		case "NoSuchKey", "NotFound", "NoSuchVersion":
+			return true
+		}
+	}
+	return false
+}
+
+// isPreconditionFailed will check if err reports a conditional request whose condition does not hold, or that lost
+// the race with a concurrent conditional request.
+func isPreconditionFailed(err error) bool {
+	var ae smithy.APIError
+	if errors.As(err, &ae) {
+		switch ae.ErrorCode() {
+		case "PreconditionFailed", "ConditionalRequestConflict":
+			return true
+		}
+	}
+	return false
+}
+
+func (a *Adapter) mapError(p filesystem.Path, err error) error {
+	if isNotFound(err) {
+		return filesystem.NewFileNotFoundError(p)
+	}
+	if isPreconditionFailed(err) {
+		return filesystem.NewPreconditionFailedError(p)
+	}
+	return err
+}
+
+func (a *Adapter) put(ctx context.Context, p filesystem.Path, r io.Reader, head []byte, cfg filesystem.Config) error {
+	// The uploader switches to multipart upload for content larger than its part size.
+	_, err := a.uploader.Upload(ctx, a.putInput(p, r, head, cfg))
+	return err
+}
+
+// putInput will build the request storing the content of r at p, head being its leading bytes.
+func (a *Adapter) putInput(p filesystem.Path, r io.Reader, head []byte, cfg filesystem.Config) *s3.PutObjectInput {
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(a.bucket),
+		Key:         aws.String(a.key(p)),
+		Body:        r,
+		ContentType: aws.String(detectMimeType(p, head, cfg)),
+	}
+	if v, ok := cfg.Get(filesystem.ConfigVisibility, nil).(filesystem.Visibility); ok {
+		input.ACL = a.cannedACL(v, &cfg)
+	}
+	if md, ok := cfg.Get(filesystem.ConfigMetadata, nil).(map[string]string); ok {
+		input.Metadata = md
+	}
+	if sc := a.setting(cfg, ConfigStorageClass); sc != "" {
+		input.StorageClass = types.StorageClass(sc)
+	}
+	if sse := a.setting(cfg, ConfigServerSideEncryption); sse != "" {
+		input.ServerSideEncryption = types.ServerSideEncryption(sse)
+		if kms := a.setting(cfg, ConfigKMSKeyID); kms != "" {
+			input.SSEKMSKeyId = aws.String(kms)
+		}
+	}
+	return input
+}
+
+// detectMimeType will find the mime type of content from the config, the file extension or the content itself.
+func detectMimeType(p filesystem.Path, head []byte, cfg filesystem.Config) string {
+	if mt, ok := cfg.Get(filesystem.ConfigMimeType, "").(string); ok && mt != "" {
+		return mt
+	}
+	if mt := mime.TypeByExtension(path.Ext(string(p))); mt != "" {
+		return mt
+	}
+	return http.DetectContentType(head)
+}
+
+// cannedACL will convert provided visibility to a canned ACL, honoring the ACLs set with visibilityconv.WithACLs in
+// cfg, if any, or in the adapter settings. Other visibilities are used as canned ACLs verbatim.
+func (a *Adapter) cannedACL(v filesystem.Visibility, cfg *filesystem.Config) types.ObjectCannedACL {
+	acls := visibilityconv.ACLsFrom(cfg, visibilityconv.ACLsFrom(a.defaults, visibilityconv.S3ACLs))
+	return types.ObjectCannedACL(acls.ACL(v))
+}
+
+func (a *Adapter) head(ctx context.Context, p filesystem.Path) (*s3.HeadObjectOutput, error) {
+	out, err := a.client.HeadObject(ctx, &s3.HeadObjectInput{Bucket: aws.String(a.bucket), Key: aws.String(a.key(p))})
+	if err != nil {
+		return nil, a.mapError(p, err)
+	}
+	return out, nil
+}
+
+// Has will check if a file exists.
+func (a *Adapter) Has(ctx context.Context, path filesystem.Path) (bool, error) {
+	_, err := a.head(ctx, path)
+	if filesystem.IsFileNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// FileExists will check if an object exists at supplied path.
+func (a *Adapter) FileExists(ctx context.Context, path filesystem.Path) (bool, error) {
+	return a.Has(ctx, path)
+}
+
+// DirectoryExists will check if any object, a directory marker included, has supplied path as key prefix.
+func (a *Adapter) DirectoryExists(ctx context.Context, path filesystem.Path) (bool, error) {
+	out, err := a.client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+		Bucket:  aws.String(a.bucket),
+		Prefix:  aws.String(a.dirKey(path)),
+		MaxKeys: aws.Int32(1),
+	})
+	if err != nil {
+		return false, a.mapError(path, err)
+	}
+	return len(out.Contents) > 0, nil
+}
+
+// Read the file at provided path.
+func (a *Adapter) Read(ctx context.Context, path filesystem.Path) ([]byte, error) {
+	rc, err := a.ReadStream(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// ReadStream will read the file at provided path as a stream.
+func (a *Adapter) ReadStream(ctx context.Context, path filesystem.Path) (io.ReadCloser, error) {
+	out, err := a.client.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(a.bucket), Key: aws.String(a.key(path))})
+	if err != nil {
+		return nil, a.mapError(path, err)
+	}
+	return out.Body, nil
+}
+
+// ReadWithMetadata will read the file at provided path along with its metadata, taken from the headers of the
+// download.
+func (a *Adapter) ReadWithMetadata(ctx context.Context, path filesystem.Path) (filesystem.Metadata, error) {
+	out, err := a.client.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(a.bucket), Key: aws.String(a.key(path))})
+	if err != nil {
+		return nil, a.mapError(path, err)
+	}
+	defer out.Body.Close()
+	content, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &filesystem.FileInfo{
+		Path:      path,
+		Type:      filesystem.TypeFile,
+		Size:      int64(len(content)),
+		Timestamp: aws.ToTime(out.LastModified),
+		MimeType:  aws.ToString(out.ContentType),
+		ETag:      strings.Trim(aws.ToString(out.ETag), `"`),
+		VersionID: aws.ToString(out.VersionId),
+		Content:   content,
+	}, nil
+}
+
+// ReadRange will read length bytes of the file at provided path starting at offset, with a range request.
+func (a *Adapter) ReadRange(ctx context.Context, path filesystem.Path, offset, length int64) (io.ReadCloser, error) {
+	if length == 0 {
+		if _, err := a.head(ctx, path); err != nil {
+			return nil, err
+		}
+		return io.NopCloser(bytes.NewReader(nil)), nil
+	}
+	rng := fmt.Sprintf("bytes=%d-", offset)
+	if length > 0 {
+		rng += strconv.FormatInt(offset+length-1, 10)
+	}
+	out, err := a.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(a.bucket),
+		Key:    aws.String(a.key(path)),
+		Range:  aws.String(rng),
+	})
+	var ae smithy.APIError
+	if errors.As(err, &ae) && ae.ErrorCode() == "InvalidRange" {
+		// The range starts past the end of the object.
+		return io.NopCloser(bytes.NewReader(nil)), nil
+	}
+	if err != nil {
+		return nil, a.mapError(path, err)
+	}
+	return out.Body, nil
+}
+
+// Write the supplied content at supplied path, creating the file.
+func (a *Adapter) Write(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	return a.put(ctx, path, bytes.NewReader(content), content, cfg)
+}
+
+// WriteStream will write the content of provided reader at supplied path, creating the file. Large content is
+// sent with a multipart upload.
+func (a *Adapter) WriteStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	return a.put(ctx, path, io.MultiReader(bytes.NewReader(head[:n]), r), head[:n], cfg)
+}
+
+// Update the supplied content at supplied path, returning an error if file does not exists.
+func (a *Adapter) Update(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	if _, err := a.head(ctx, path); err != nil {
+		return err
+	}
+	return a.Write(ctx, path, content, cfg)
+}
+
+// Update with the content of supplied reader at supplied path, returning an error if file does not exists
+func (a *Adapter) UpdateStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	if _, err := a.head(ctx, path); err != nil {
+		return err
+	}
+	return a.WriteStream(ctx, path, r, cfg)
+}
+
+// Put the supplied content at supplied path, creating the file if does not exists.
+func (a *Adapter) Put(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	return a.Write(ctx, path, content, cfg)
+}
+
+// Puth the content of supplied reader at supplied path, creating the file if does not exists.
+func (a *Adapter) PutStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	return a.WriteStream(ctx, path, r, cfg)
+}
+
+// Deletes a file at provided path.
+func (a *Adapter) Delete(ctx context.Context, path filesystem.Path) error {
+	// S3 deletes of missing keys succeed, so existence is checked first.
+	if _, err := a.head(ctx, path); err != nil {
+		return err
+	}
+	_, err := a.client.DeleteObject(ctx, &s3.DeleteObjectInput{Bucket: aws.String(a.bucket), Key: aws.String(a.key(path))})
+	return err
+}
+
+// WriteIf will write the supplied content at supplied path with a conditional PutObject, in a single request. S3
+// only supports IfNoneMatch "*", i.e. IfNotExists.
+func (a *Adapter) WriteIf(ctx context.Context, path filesystem.Path, content []byte, cond filesystem.Precondition, cfg filesystem.Config) error {
+	if cond.IfNoneMatch != "" && cond.IfNoneMatch != "*" {
+		return filesystem.NewUnsupportedOperationError(path, "WriteIf")
+	}
+	input := a.putInput(path, bytes.NewReader(content), content, cfg)
+	if cond.IfMatch != "" {
+		input.IfMatch = aws.String(quoteETag(cond.IfMatch))
+	}
+	if cond.IfNoneMatch != "" {
+		input.IfNoneMatch = aws.String(cond.IfNoneMatch)
+	}
+	_, err := a.client.PutObject(ctx, input)
+	return a.mapError(path, err)
+}
+
+// DeleteIf will delete the file at provided path with a conditional DeleteObject. S3 only supports IfMatch on
+// deletes.
+func (a *Adapter) DeleteIf(ctx context.Context, path filesystem.Path, cond filesystem.Precondition) error {
+	if cond.IfNoneMatch != "" {
+		return filesystem.NewUnsupportedOperationError(path, "DeleteIf")
+	}
+	if cond.IfMatch == "" {
+		return a.Delete(ctx, path)
+	}
+	_, err := a.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket:  aws.String(a.bucket),
+		Key:     aws.String(a.key(path)),
+		IfMatch: aws.String(quoteETag(cond.IfMatch)),
+	})
+	return a.mapError(path, err)
+}
+
+// quoteETag will quote provided ETag as S3 expects in conditional requests, FileInfo holding them unquoted.
+func quoteETag(etag string) string {
+	if etag == "*" || strings.HasPrefix(etag, `"`) {
+		return etag
+	}
+	return `"` + etag + `"`
+}
+
+// ReadAndDelete will read the file at provided path and delete after read.
+func (a *Adapter) ReadAndDelete(ctx context.Context, path filesystem.Path) ([]byte, error) {
+	content, err := a.Read(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return content, a.Delete(ctx, path)
+}
+
+// Move the file at supplied path to new path, copying it and deleting the source.
+func (a *Adapter) Move(ctx context.Context, path, newpath filesystem.Path) error {
+	if err := a.Copy(ctx, path, newpath); err != nil {
+		return err
+	}
+	_, err := a.client.DeleteObject(ctx, &s3.DeleteObjectInput{Bucket: aws.String(a.bucket), Key: aws.String(a.key(path))})
+	return err
+}
+
+// Copy the file at supplied path to new path.
+func (a *Adapter) Copy(ctx context.Context, path, newpath filesystem.Path) error {
+	_, err := a.client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:     aws.String(a.bucket),
+		Key:        aws.String(a.key(newpath)),
+		CopySource: aws.String(url.PathEscape(a.bucket + "/" + a.key(path))),
+	})
+	return a.mapError(path, err)
+}
+
+// CopyTo will copy the file at supplied path to new path of target server side, when target is an adapter of the same
+// S3 service, in the same region. The copy is requested by the client of target, whose credentials must allow
+// reading the source object.
+func (a *Adapter) CopyTo(ctx context.Context, path filesystem.Path, target filesystem.Adapter, newpath filesystem.Path) (bool, error) {
+	t, ok := target.(*Adapter)
+	if !ok {
+		return false, nil
+	}
+	src, dst := a.client.Options(), t.client.Options()
+	if src.Region != dst.Region || aws.ToString(src.BaseEndpoint) != aws.ToString(dst.BaseEndpoint) {
+		return false, nil
+	}
+	_, err := t.client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:     aws.String(t.bucket),
+		Key:        aws.String(t.key(newpath)),
+		CopySource: aws.String(url.PathEscape(a.bucket + "/" + a.key(path))),
+	})
+	return true, a.mapError(path, err)
+}
+
+// GetMimeType will retrieve the mime type of file at supplied path.
+func (a *Adapter) GetMimeType(ctx context.Context, path filesystem.Path) (string, error) {
+	out, err := a.head(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(out.ContentType), nil
+}
+
+// GetTimestamp will retrieve the timestamp of file at supplied path.
+func (a *Adapter) GetTimestamp(ctx context.Context, path filesystem.Path) (time.Time, error) {
+	out, err := a.head(ctx, path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return aws.ToTime(out.LastModified), nil
+}
+
+// GetFileSize will retrieve the size of file at supplied path.
+func (a *Adapter) GetFileSize(ctx context.Context, path filesystem.Path) (int64, error) {
+	out, err := a.head(ctx, path)
+	if err != nil {
+		return 0, err
+	}
+	return aws.ToInt64(out.ContentLength), nil
+}
+
+// GetMetadata will retrieve the metadata of file at supplied path.
+func (a *Adapter) GetMetadata(ctx context.Context, path filesystem.Path) (filesystem.Metadata, error) {
+	out, err := a.head(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return &filesystem.FileInfo{
+		Path:      path,
+		Type:      filesystem.TypeFile,
+		Size:      aws.ToInt64(out.ContentLength),
+		Timestamp: aws.ToTime(out.LastModified),
+		MimeType:  aws.ToString(out.ContentType),
+		ETag:      strings.Trim(aws.ToString(out.ETag), `"`),
+		VersionID: aws.ToString(out.VersionId),
+	}, nil
+}
+
+// GetChecksum will return the MD5 checksum from the ETag of objects uploaded in a single part, or the CRC32C
+// checksum stored by S3 when the object was uploaded with one.
+func (a *Adapter) GetChecksum(ctx context.Context, path filesystem.Path, algo filesystem.ChecksumAlgo) (string, error) {
+	switch algo {
+	case filesystem.ChecksumMD5:
+		out, err := a.head(ctx, path)
+		if err != nil {
+			return "", err
+		}
+		// Multipart ETags are not the MD5 of the content and have a "-<parts>" suffix.
+		etag := strings.Trim(aws.ToString(out.ETag), `"`)
+		if etag != "" && !strings.Contains(etag, "-") {
+			return etag, nil
+		}
+	case filesystem.ChecksumCRC32C:
+		out, err := a.client.HeadObject(ctx, &s3.HeadObjectInput{
+			Bucket:       aws.String(a.bucket),
+			Key:          aws.String(a.key(path)),
+			ChecksumMode: types.ChecksumModeEnabled,
+		})
+		if err != nil {
+			return "", a.mapError(path, err)
+		}
+		// Checksums of multipart objects are checksums of the part checksums, with a "-<parts>" suffix.
+		if sum, err := base64.StdEncoding.DecodeString(aws.ToString(out.ChecksumCRC32C)); err == nil && len(sum) == 4 {
+			return hex.EncodeToString(sum), nil
+		}
+	}
+	return "", filesystem.NewUnsupportedChecksumError(path, algo)
+}
+
+// CreateDir will create a new directory at provided path, as an empty marker object.
+func (a *Adapter) CreateDir(ctx context.Context, path filesystem.Path, cfg filesystem.Config) error {
+	_, err := a.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(a.bucket),
+		Key:    aws.String(a.dirKey(path)),
+		Body:   bytes.NewReader(nil),
+	})
+	return err
+}
+
+// DeleteDir will delete the directory at provided path, with all of its content.
+func (a *Adapter) DeleteDir(ctx context.Context, path filesystem.Path) error {
+	p := s3.NewListObjectsV2Paginator(a.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(a.bucket),
+		Prefix: aws.String(a.dirKey(path)),
+	})
+	for p.HasMorePages() {
+		page, err := p.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		if len(page.Contents) == 0 {
+			continue
+		}
+		ids := make([]types.ObjectIdentifier, 0, len(page.Contents))
+		for _, obj := range page.Contents {
+			ids = append(ids, types.ObjectIdentifier{Key: obj.Key})
+		}
+		// Pages hold at most 1000 keys, the limit of a single DeleteObjects request.
+		out, err := a.client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(a.bucket),
+			Delete: &types.Delete{Objects: ids, Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			return err
+		}
+		// Keys failing to be deleted are reported in the response rather than failing the request.
+		if len(out.Errors) > 0 {
+			e := out.Errors[0]
+			return fmt.Errorf("Failed to delete %d objects of directory %s, first %s: %s", len(out.Errors), path,
+				aws.ToString(e.Key), aws.ToString(e.Message))
+		}
+	}
+	return nil
+}
+
+// GetVisibility will retrieve the visibility of file at supplied path. S3 does not keep the canned ACL of objects,
+// so it is inferred from the grants: public-read if anyone can read it, authenticated-read if any AWS user can, and
+// private otherwise.
+func (a *Adapter) GetVisibility(ctx context.Context, path filesystem.Path) (filesystem.Visibility, error) {
+	out, err := a.client.GetObjectAcl(ctx, &s3.GetObjectAclInput{Bucket: aws.String(a.bucket), Key: aws.String(a.key(path))})
+	if err != nil {
+		return "", a.mapError(path, err)
+	}
+	acl := types.ObjectCannedACLPrivate
+	for _, g := range out.Grants {
+		if g.Grantee == nil || g.Permission != types.PermissionRead && g.Permission != types.PermissionFullControl {
+			continue
+		}
+		switch aws.ToString(g.Grantee.URI) {
+		case allUsersURI:
+			acl = types.ObjectCannedACLPublicRead
+		case authenticatedUsersURI:
+			if acl == types.ObjectCannedACLPrivate {
+				acl = types.ObjectCannedACLAuthenticatedRead
+			}
+		}
+	}
+	v, _ := visibilityconv.ACLsFrom(a.defaults, visibilityconv.S3ACLs).Visibility(string(acl))
+	return v, nil
+}
+
+// SetVisibility will set the visibility of file at supplied path, as a canned ACL. Having no per-call config, it
+// only honors the ACLs of the adapter settings.
+func (a *Adapter) SetVisibility(ctx context.Context, path filesystem.Path, v filesystem.Visibility) error {
+	_, err := a.client.PutObjectAcl(ctx, &s3.PutObjectAclInput{
+		Bucket: aws.String(a.bucket),
+		Key:    aws.String(a.key(path)),
+		ACL:    a.cannedACL(v, nil),
+	})
+	return a.mapError(path, err)
+}
+
+// GetUserMetadata will retrieve the user metadata of object at supplied path.
+func (a *Adapter) GetUserMetadata(ctx context.Context, path filesystem.Path) (map[string]string, error) {
+	out, err := a.head(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	md := out.Metadata
+	if md == nil {
+		md = map[string]string{}
+	}
+	return md, nil
+}
+
+// SetUserMetadata will replace the user metadata of object at supplied path. Object metadata is immutable, so the
+// object is copied onto itself, preserving its content type, storage class, encryption and visibility.
+func (a *Adapter) SetUserMetadata(ctx context.Context, path filesystem.Path, metadata map[string]string) error {
+	out, err := a.head(ctx, path)
+	if err != nil {
+		return err
+	}
+	v, err := a.GetVisibility(ctx, path)
+	if err != nil {
+		return err
+	}
+	input := &s3.CopyObjectInput{
+		Bucket:               aws.String(a.bucket),
+		Key:                  aws.String(a.key(path)),
+		CopySource:           aws.String(url.PathEscape(a.bucket + "/" + a.key(path))),
+		MetadataDirective:    types.MetadataDirectiveReplace,
+		Metadata:             metadata,
+		ContentType:          out.ContentType,
+		ACL:                  a.cannedACL(v, nil),
+		ServerSideEncryption: out.ServerSideEncryption,
+		SSEKMSKeyId:          out.SSEKMSKeyId,
+	}
+	if out.StorageClass != "" {
+		input.StorageClass = types.StorageClass(out.StorageClass)
+	}
+	_, err = a.client.CopyObject(ctx, input)
+	return a.mapError(path, err)
+}
+
+// ListContents will list the contents of given path.
+func (a *Adapter) ListContents(ctx context.Context, path filesystem.Path, recursive bool) ([]filesystem.Metadata, error) {
+	it, err := a.IterateContents(ctx, path, recursive)
+	if err != nil {
+		return nil, err
+	}
+	var listing []filesystem.Metadata
+	for {
+		md, err := it.Next()
+		if err == io.EOF {
+			return listing, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		listing = append(listing, md)
+	}
+}
+
+// IterateContents will iterate over the contents of given path, fetching one page of keys at a time.
+func (a *Adapter) IterateContents(ctx context.Context, path filesystem.Path, recursive bool) (filesystem.Iterator, error) {
+	input := &s3.ListObjectsV2Input{Bucket: aws.String(a.bucket), Prefix: aws.String(a.dirKey(path))}
+	if !recursive {
+		input.Delimiter = aws.String("/")
+	}
+	return &iterator{
+		adapter:   a,
+		ctx:       ctx,
+		path:      path,
+		recursive: recursive,
+		pages:     s3.NewListObjectsV2Paginator(a.client, input),
+		dirs:      make(map[filesystem.Path]bool),
+	}, nil
+}
+
+// ListPage will list a page of the contents of given path. The cursor is the continuation token of S3 for shallow
+// listings and, for recursive ones, the last key of the previous page, so that the directories derived from keys are
+// listed once across pages.
+func (a *Adapter) ListPage(ctx context.Context, path filesystem.Path, recursive bool, cursor string, limit int) (filesystem.Page, error) {
+	input := &s3.ListObjectsV2Input{
+		Bucket:  aws.String(a.bucket),
+		Prefix:  aws.String(a.dirKey(path)),
+		MaxKeys: aws.Int32(int32(limit)),
+	}
+	it := &iterator{adapter: a, path: path, recursive: recursive, dirs: make(map[filesystem.Path]bool)}
+	switch {
+	case !recursive:
+		input.Delimiter = aws.String("/")
+		if cursor != "" {
+			input.ContinuationToken = aws.String(cursor)
+		}
+	case cursor != "":
+		input.StartAfter = aws.String(cursor)
+		last := a.path(cursor)
+		if strings.HasSuffix(cursor, "/") {
+			last = filesystem.Path(strings.TrimSuffix(string(last), "/"))
+			it.dirs[last] = true
+		}
+		for dir := last.Dir(); dir != filesystem.RootPath; dir = dir.Dir() {
+			it.dirs[dir] = true
+		}
+	}
+	out, err := a.client.ListObjectsV2(ctx, input)
+	if err != nil {
+		return filesystem.Page{}, a.mapError(path, err)
+	}
+	it.add(out)
+	page := filesystem.Page{Entries: it.pending}
+	if aws.ToBool(out.IsTruncated) {
+		if recursive {
+			page.Cursor = aws.ToString(out.Contents[len(out.Contents)-1].Key)
+		} else {
+			page.Cursor = aws.ToString(out.NextContinuationToken)
+		}
+	}
+	return page, nil
+}
+
+// iterator lists the objects under a prefix page by page. Directories seen across pages are remembered so that
+// each one is returned once.
+type iterator struct {
+	adapter   *Adapter
+	ctx       context.Context
+	path      filesystem.Path
+	recursive bool
+	pages     *s3.ListObjectsV2Paginator
+	dirs      map[filesystem.Path]bool
+	pending   []filesystem.Metadata
+}
+
+func (it *iterator) Next() (filesystem.Metadata, error) {
+	for len(it.pending) == 0 {
+		if !it.pages.HasMorePages() {
+			return nil, io.EOF
+		}
+		page, err := it.pages.NextPage(it.ctx)
+		if err != nil {
+			return nil, err
+		}
+		it.add(page)
+	}
+	md := it.pending[0]
+	it.pending = it.pending[1:]
+	return md, nil
+}
+
+func (it *iterator) addDir(dir filesystem.Path) {
+	if dir != it.path && dir != filesystem.RootPath && !it.dirs[dir] {
+		it.dirs[dir] = true
+		it.pending = append(it.pending, &filesystem.FileInfo{Path: dir, Type: filesystem.TypeDir})
+	}
+}
+
+func (it *iterator) add(page *s3.ListObjectsV2Output) {
+	for _, cp := range page.CommonPrefixes {
+		it.addDir(it.adapter.path(strings.TrimSuffix(aws.ToString(cp.Prefix), "/")))
+	}
+	for _, obj := range page.Contents {
+		key := aws.ToString(obj.Key)
+		file := it.adapter.path(key)
+		if it.recursive {
+			// Prefix-style directories are derived from the keys below them.
+			for dir := file.Dir(); strings.HasPrefix(string(dir), string(it.path)) && dir != it.path; dir = dir.Dir() {
+				it.addDir(dir)
+			}
+		}
+		if strings.HasSuffix(key, "/") {
+			it.addDir(filesystem.Path(strings.TrimSuffix(string(file), "/")))
+			continue
+		}
+		it.pending = append(it.pending, &filesystem.FileInfo{
+			Path:      file,
+			Type:      filesystem.TypeFile,
+			Size:      aws.ToInt64(obj.Size),
+			Timestamp: aws.ToTime(obj.LastModified),
+			ETag:      strings.Trim(aws.ToString(obj.ETag), `"`),
+		})
+	}
+}
+
+// ListVersions will list the versions of object at supplied path, newest first, delete markers excluded. Objects
+// of buckets without versioning have a single version, with ID "null".
+func (a *Adapter) ListVersions(ctx context.Context, path filesystem.Path) ([]filesystem.Metadata, error) {
+	key := a.key(path)
+	pages := s3.NewListObjectVersionsPaginator(a.client, &s3.ListObjectVersionsInput{
+		Bucket: aws.String(a.bucket),
+		Prefix: aws.String(key),
+	})
+	var versions []filesystem.Metadata
+	for pages.HasMorePages() {
+		out, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, a.mapError(path, err)
+		}
+		for _, v := range out.Versions {
+			// Versions are sorted by key, keys merely starting with the one of the object coming after it.
+			if k := aws.ToString(v.Key); k != key {
+				if k > key {
+					return a.versions(path, versions)
+				}
+				continue
+			}
+			versions = append(versions, &filesystem.FileInfo{
+				Path:      path,
+				Type:      filesystem.TypeFile,
+				Size:      aws.ToInt64(v.Size),
+				Timestamp: aws.ToTime(v.LastModified),
+				ETag:      strings.Trim(aws.ToString(v.ETag), `"`),
+				VersionID: aws.ToString(v.VersionId),
+			})
+		}
+	}
+	return a.versions(path, versions)
+}
+
+func (a *Adapter) versions(path filesystem.Path, versions []filesystem.Metadata) ([]filesystem.Metadata, error) {
+	if len(versions) == 0 {
+		return nil, filesystem.NewFileNotFoundError(path)
+	}
+	return versions, nil
+}
+
+// ReadVersion will read provided version of object at supplied path as a stream.
+func (a *Adapter) ReadVersion(ctx context.Context, path filesystem.Path, versionID string) (io.ReadCloser, error) {
+	out, err := a.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket:    aws.String(a.bucket),
+		Key:       aws.String(a.key(path)),
+		VersionId: aws.String(versionID),
+	})
+	if err != nil {
+		return nil, a.mapError(path, err)
+	}
+	return out.Body, nil
+}
+
+// RestoreVersion will copy provided version of object at supplied path over the current one, server side. The
+// current version is kept as a previous version, if the bucket has versioning enabled.
+func (a *Adapter) RestoreVersion(ctx context.Context, path filesystem.Path, versionID string) error {
+	source := url.PathEscape(a.bucket+"/"+a.key(path)) + "?versionId=" + url.QueryEscape(versionID)
+	_, err := a.client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:     aws.String(a.bucket),
+		Key:        aws.String(a.key(path)),
+		CopySource: aws.String(source),
+	})
+	return a.mapError(path, err)
+}
+
+// Capabilities will report the capabilities of the adapter, copies happening server side.
+func (a *Adapter) Capabilities() filesystem.Capabilities {
+	return filesystem.Capabilities{
+		NativeCopy:        true,
+		Checksums:         true,
+		RangeReads:        true,
+		ContentsIteration: true,
+		UserMetadata:      true,
+		Pagination:        true,
+		ConditionalWrites: true,
+		Versioning:        true,
+		AtomicWrites:      true,
+	}
+}
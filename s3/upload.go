@@ -0,0 +1,122 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/maurofran/filesystem"
+)
+
+// BeginUpload will begin a resumable upload session as a multipart upload, each chunk being uploaded as a part. S3
+// requires all the parts but the last to be at least 5 MiB, so chunks must be too.
+func (a *Adapter) BeginUpload(ctx context.Context, path filesystem.Path, cfg filesystem.Config) (filesystem.UploadSession, error) {
+	put := a.putInput(path, nil, nil, cfg)
+	out, err := a.client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:               put.Bucket,
+		Key:                  put.Key,
+		ContentType:          put.ContentType,
+		ACL:                  put.ACL,
+		Metadata:             put.Metadata,
+		StorageClass:         put.StorageClass,
+		ServerSideEncryption: put.ServerSideEncryption,
+		SSEKMSKeyId:          put.SSEKMSKeyId,
+	})
+	if err != nil {
+		return nil, a.mapError(path, err)
+	}
+	return &upload{adapter: a, path: path, id: aws.ToString(out.UploadId)}, nil
+}
+
+// ResumeUpload will resume the multipart upload with provided state, listing its uploaded parts.
+func (a *Adapter) ResumeUpload(ctx context.Context, state filesystem.UploadState, cfg filesystem.Config) (filesystem.UploadSession, error) {
+	u := &upload{adapter: a, path: state.Path, id: state.ID}
+	p := s3.NewListPartsPaginator(a.client, &s3.ListPartsInput{
+		Bucket:   aws.String(a.bucket),
+		Key:      aws.String(a.key(state.Path)),
+		UploadId: aws.String(state.ID),
+	})
+	for p.HasMorePages() {
+		page, err := p.NextPage(ctx)
+		if err != nil {
+			return nil, a.mapError(state.Path, err)
+		}
+		for _, part := range page.Parts {
+			if aws.ToInt32(part.PartNumber) != int32(len(u.parts)+1) {
+				return u, nil
+			}
+			u.parts = append(u.parts, types.CompletedPart{ETag: part.ETag, PartNumber: part.PartNumber})
+			u.offset += aws.ToInt64(part.Size)
+		}
+	}
+	return u, nil
+}
+
+// upload is an upload session backed by a multipart upload.
+type upload struct {
+	adapter *Adapter
+	path    filesystem.Path
+	id      string
+	offset  int64
+	parts   []types.CompletedPart
+}
+
+func (u *upload) State() filesystem.UploadState {
+	return filesystem.UploadState{Path: u.path, ID: u.id, Offset: u.offset}
+}
+
+func (u *upload) WriteChunk(ctx context.Context, offset int64, chunk []byte) error {
+	if offset != u.offset {
+		return filesystem.NewUploadOffsetError(u.path, u.offset)
+	}
+	if len(chunk) == 0 {
+		return nil
+	}
+	return u.uploadPart(ctx, chunk)
+}
+
+func (u *upload) uploadPart(ctx context.Context, chunk []byte) error {
+	number := aws.Int32(int32(len(u.parts) + 1))
+	out, err := u.adapter.client.UploadPart(ctx, &s3.UploadPartInput{
+		Bucket:        aws.String(u.adapter.bucket),
+		Key:           aws.String(u.adapter.key(u.path)),
+		UploadId:      aws.String(u.id),
+		PartNumber:    number,
+		Body:          bytes.NewReader(chunk),
+		ContentLength: aws.Int64(int64(len(chunk))),
+	})
+	if err != nil {
+		return u.adapter.mapError(u.path, err)
+	}
+	u.offset += int64(len(chunk))
+	u.parts = append(u.parts, types.CompletedPart{ETag: out.ETag, PartNumber: number})
+	return nil
+}
+
+func (u *upload) Commit(ctx context.Context) error {
+	if len(u.parts) == 0 {
+		// A multipart upload needs at least one part, which may be empty.
+		if err := u.uploadPart(ctx, nil); err != nil {
+			return err
+		}
+	}
+	_, err := u.adapter.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(u.adapter.bucket),
+		Key:             aws.String(u.adapter.key(u.path)),
+		UploadId:        aws.String(u.id),
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: u.parts},
+	})
+	return u.adapter.mapError(u.path, err)
+}
+
+func (u *upload) Abort(ctx context.Context) error {
+	_, err := u.adapter.client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(u.adapter.bucket),
+		Key:      aws.String(u.adapter.key(u.path)),
+		UploadId: aws.String(u.id),
+	})
+	return u.adapter.mapError(u.path, err)
+}
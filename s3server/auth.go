@@ -0,0 +1,209 @@
+package s3server
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSkew is the maximum difference between the time of a signed request and the one of the server.
+const maxSkew = 15 * time.Minute
+
+// authenticate will check that the request is signed with AWS signature version 4 in its Authorization header by one
+// of the credentials. The signature covers the declared hash of the payload, which payload checks against the body.
+func (h *handler) authenticate(r *http.Request) error {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 ") {
+		return errAccessDenied
+	}
+	fields := make(map[string]string)
+	for _, f := range strings.Split(strings.TrimPrefix(auth, "AWS4-HMAC-SHA256 "), ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(f), "=")
+		fields[k] = v
+	}
+	scope := strings.Split(fields["Credential"], "/")
+	if len(scope) != 5 || scope[3] != "s3" || scope[4] != "aws4_request" {
+		return errAccessDenied
+	}
+	secret, ok := h.opts.Credentials[scope[0]]
+	if !ok {
+		return errInvalidAccessKeyID
+	}
+	date := r.Header.Get("X-Amz-Date")
+	t, err := time.Parse("20060102T150405Z", date)
+	if err != nil || !strings.HasPrefix(date, scope[1]) {
+		return errAccessDenied
+	}
+	if d := time.Since(t); d > maxSkew || d < -maxSkew {
+		return errRequestTimeTooSkewed
+	}
+	payloadHash := r.Header.Get("X-Amz-Content-Sha256")
+	if payloadHash == "" {
+		payloadHash = "UNSIGNED-PAYLOAD"
+	}
+	signed := strings.Split(fields["SignedHeaders"], ";")
+	canonical := strings.Join([]string{
+		r.Method,
+		uriEncode(r.URL.Path, false),
+		canonicalQuery(r.URL.Query()),
+		canonicalHeaders(r, signed),
+		fields["SignedHeaders"],
+		payloadHash,
+	}, "\n")
+	digest := sha256.Sum256([]byte(canonical))
+	toSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		date,
+		strings.Join(scope[1:], "/"),
+		hex.EncodeToString(digest[:]),
+	}, "\n")
+	key := []byte("AWS4" + secret)
+	for _, s := range scope[1:] {
+		key = hmacSHA256(key, s)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, toSign))
+	if !hmac.Equal([]byte(signature), []byte(fields["Signature"])) {
+		return errSignatureDoesNotMatch
+	}
+	return nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// uriEncode will encode s as AWS does, escaping everything but unreserved characters, and slashes unless slash is
+// set.
+func uriEncode(s string, slash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !slash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func canonicalQuery(q url.Values) string {
+	var params []string
+	for k, vs := range q {
+		for _, v := range vs {
+			params = append(params, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	sort.Strings(params)
+	return strings.Join(params, "&")
+}
+
+func canonicalHeaders(r *http.Request, signed []string) string {
+	var b strings.Builder
+	for _, name := range signed {
+		var values []string
+		switch name {
+		case "host":
+			values = []string{r.Host}
+		case "content-length":
+			values = []string{strconv.FormatInt(r.ContentLength, 10)}
+		default:
+			values = r.Header.Values(name)
+		}
+		for i, v := range values {
+			values[i] = strings.Join(strings.Fields(v), " ")
+		}
+		b.WriteString(name + ":" + strings.Join(values, ",") + "\n")
+	}
+	return b.String()
+}
+
+// payload will return the content of a request body, decoding the aws-chunked encoding SDKs use to stream uploads.
+// Bodies declaring their SHA-256 hash fail on EOF if they do not match it; chunk signatures and trailing checksums
+// of streamed uploads are not verified.
+func payload(r *http.Request) (io.Reader, error) {
+	declared := r.Header.Get("X-Amz-Content-Sha256")
+	if strings.HasPrefix(declared, "STREAMING-") || strings.Contains(r.Header.Get("Content-Encoding"), "aws-chunked") {
+		return &chunkedReader{r: bufio.NewReader(r.Body)}, nil
+	}
+	if declared == "" || declared == "UNSIGNED-PAYLOAD" {
+		return r.Body, nil
+	}
+	return &hashingReader{r: r.Body, hash: sha256.New(), declared: strings.ToLower(declared)}, nil
+}
+
+// hashingReader hashes the content read, failing at the end of it if the hash differs from the declared one.
+type hashingReader struct {
+	r        io.Reader
+	hash     hash.Hash
+	declared string
+}
+
+func (h *hashingReader) Read(p []byte) (int, error) {
+	n, err := h.r.Read(p)
+	h.hash.Write(p[:n])
+	if err == io.EOF && hex.EncodeToString(h.hash.Sum(nil)) != h.declared {
+		err = errContentSHA256Mismatch
+	}
+	return n, err
+}
+
+// chunkedReader decodes the aws-chunked encoding: chunks are prefixed with their hexadecimal size and extensions
+// such as their signature, and the last one is empty, followed by optional trailers.
+type chunkedReader struct {
+	r    *bufio.Reader
+	left int64
+	done bool
+}
+
+func (c *chunkedReader) Read(p []byte) (int, error) {
+	if c.done {
+		return 0, io.EOF
+	}
+	if c.left == 0 {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			return 0, errIncompleteBody
+		}
+		size, _, _ := strings.Cut(strings.TrimSpace(line), ";")
+		n, err := strconv.ParseInt(size, 16, 64)
+		if err != nil || n < 0 {
+			return 0, errIncompleteBody
+		}
+		if n == 0 {
+			c.done = true
+			return 0, io.EOF
+		}
+		c.left = n
+	}
+	if int64(len(p)) > c.left {
+		p = p[:c.left]
+	}
+	n, err := c.r.Read(p)
+	c.left -= int64(n)
+	if c.left == 0 && err == nil {
+		// Each chunk ends with a line break.
+		if _, err := c.r.Discard(2); err != nil {
+			return n, errIncompleteBody
+		}
+	}
+	if err == io.EOF {
+		err = errIncompleteBody
+	}
+	return n, err
+}
@@ -0,0 +1,86 @@
+package s3server
+
+import (
+	"encoding/xml"
+	"net/http"
+
+	"github.com/maurofran/filesystem"
+)
+
+// apiError is an error of the S3 API, replied with its code and status.
+type apiError struct {
+	code    string
+	message string
+	status  int
+}
+
+func (e *apiError) Error() string {
+	return e.code + ": " + e.message
+}
+
+var (
+	errAccessDenied            = &apiError{"AccessDenied", "Access Denied", http.StatusForbidden}
+	errContentSHA256Mismatch   = &apiError{"XAmzContentSHA256Mismatch", "The payload does not match its declared hash", http.StatusBadRequest}
+	errBucketAlreadyOwnedByYou = &apiError{"BucketAlreadyOwnedByYou", "The bucket already exists", http.StatusConflict}
+	errInvalidAccessKeyID      = &apiError{"InvalidAccessKeyId", "The access key does not exist", http.StatusForbidden}
+	errInvalidArgument         = &apiError{"InvalidArgument", "Invalid argument", http.StatusBadRequest}
+	errInvalidBucketName       = &apiError{"InvalidBucketName", "The bucket name is not valid", http.StatusBadRequest}
+	errInvalidKey              = &apiError{"InvalidArgument", "The key is not valid", http.StatusBadRequest}
+	errMethodNotAllowed        = &apiError{"MethodNotAllowed", "The method is not allowed", http.StatusMethodNotAllowed}
+	errNoSuchBucket            = &apiError{"NoSuchBucket", "The bucket does not exist", http.StatusNotFound}
+	errNoSuchKey               = &apiError{"NoSuchKey", "The key does not exist", http.StatusNotFound}
+	errNotImplemented          = &apiError{"NotImplemented", "The operation is not implemented", http.StatusNotImplemented}
+	errRequestTimeTooSkewed    = &apiError{"RequestTimeTooSkewed", "The request time is too far from the server time", http.StatusForbidden}
+	errSignatureDoesNotMatch   = &apiError{"SignatureDoesNotMatch", "The signature does not match", http.StatusForbidden}
+	errIncompleteBody          = &apiError{"IncompleteBody", "The body is malformed or incomplete", http.StatusBadRequest}
+)
+
+// bucketError will return err, or the error of a missing bucket if nil.
+func bucketError(err error) error {
+	return orError(err, errNoSuchBucket)
+}
+
+// objectError will return the error of a missing object for missing files, err otherwise.
+func objectError(err error) error {
+	if filesystem.IsFileNotFound(err) {
+		return errNoSuchKey
+	}
+	return err
+}
+
+func orError(err, fallback error) error {
+	if err != nil {
+		return err
+	}
+	return fallback
+}
+
+// writeError will reply with the S3 error matching err.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	e, ok := err.(*apiError)
+	switch {
+	case ok:
+	case filesystem.IsFileNotFound(err):
+		e = errNoSuchKey
+	case filesystem.IsPathError(err):
+		e = errInvalidKey
+	case filesystem.IsReadOnlyError(err):
+		e = errAccessDenied
+	case filesystem.IsPreconditionFailed(err):
+		e = &apiError{"PreconditionFailed", err.Error(), http.StatusPreconditionFailed}
+	case filesystem.IsUnsupportedOperationError(err):
+		e = errNotImplemented
+	default:
+		e = &apiError{"InternalError", err.Error(), http.StatusInternalServerError}
+	}
+	if r.Method == http.MethodHead {
+		w.WriteHeader(e.status)
+		return
+	}
+	writeXML(w, e.status, struct {
+		XMLName  xml.Name `xml:"Error"`
+		Code     string
+		Message  string
+		Resource string
+	}{Code: e.code, Message: e.message, Resource: r.URL.Path})
+}
@@ -0,0 +1,178 @@
+package s3server
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/maurofran/filesystem"
+)
+
+// maxKeys is the maximum number of keys of a listing page.
+const maxKeys = 1000
+
+type listResult struct {
+	XMLName               xml.Name       `xml:"ListBucketResult"`
+	Xmlns                 string         `xml:"xmlns,attr"`
+	Name                  string         `xml:"Name"`
+	Prefix                string         `xml:"Prefix"`
+	Delimiter             string         `xml:"Delimiter,omitempty"`
+	MaxKeys               int            `xml:"MaxKeys"`
+	KeyCount              int            `xml:"KeyCount"`
+	IsTruncated           bool           `xml:"IsTruncated"`
+	ContinuationToken     string         `xml:"ContinuationToken,omitempty"`
+	NextContinuationToken string         `xml:"NextContinuationToken,omitempty"`
+	StartAfter            string         `xml:"StartAfter,omitempty"`
+	EncodingType          string         `xml:"EncodingType,omitempty"`
+	Contents              []object       `xml:"Contents"`
+	CommonPrefixes        []commonPrefix `xml:"CommonPrefixes"`
+}
+
+type object struct {
+	Key          string
+	LastModified string
+	ETag         string `xml:"ETag,omitempty"`
+	Size         int64
+	StorageClass string
+}
+
+type commonPrefix struct {
+	Prefix string
+}
+
+// entry is an object or a common prefix of a listing.
+type entry struct {
+	key string
+	md  filesystem.Metadata
+}
+
+// listObjects will list the objects of a bucket whose key starts with the prefix of the request. With the "/"
+// delimiter, the directory holding the prefix is listed and its directories are the common prefixes; without
+// delimiter, it is listed recursively. Other delimiters are not supported.
+func (h *handler) listObjects(w http.ResponseWriter, r *http.Request, bucket string) {
+	q := r.URL.Query()
+	res := &listResult{
+		Xmlns:             xmlns,
+		Name:              bucket,
+		Prefix:            q.Get("prefix"),
+		Delimiter:         q.Get("delimiter"),
+		MaxKeys:           maxKeys,
+		ContinuationToken: q.Get("continuation-token"),
+		StartAfter:        q.Get("start-after"),
+		EncodingType:      q.Get("encoding-type"),
+	}
+	if res.Delimiter != "" && res.Delimiter != "/" {
+		writeError(w, r, errNotImplemented)
+		return
+	}
+	if res.EncodingType != "" && res.EncodingType != "url" {
+		writeError(w, r, errInvalidArgument)
+		return
+	}
+	if s := q.Get("max-keys"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			writeError(w, r, errInvalidArgument)
+			return
+		}
+		if n < maxKeys {
+			res.MaxKeys = n
+		}
+	}
+	after := res.StartAfter
+	if res.ContinuationToken != "" {
+		token, err := base64.RawURLEncoding.DecodeString(res.ContinuationToken)
+		if err != nil {
+			writeError(w, r, errInvalidArgument)
+			return
+		}
+		after = string(token)
+	}
+	if ok, err := h.fsys.DirectoryExists(r.Context(), filesystem.Path(bucket)); err != nil || !ok {
+		writeError(w, r, bucketError(err))
+		return
+	}
+	entries, err := h.entries(r, bucket, res.Prefix, res.Delimiter == "")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	i := sort.Search(len(entries), func(i int) bool { return entries[i].key > after })
+	for ; i < len(entries) && res.KeyCount < res.MaxKeys; i++ {
+		e := entries[i]
+		after = e.key
+		if e.md.IsDir() {
+			res.CommonPrefixes = append(res.CommonPrefixes, commonPrefix{Prefix: res.encode(e.key)})
+		} else {
+			res.Contents = append(res.Contents, object{
+				Key:          res.encode(e.key),
+				LastModified: e.md.Timestamp.UTC().Format(timeFormat),
+				ETag:         etagOf(e.md),
+				Size:         e.md.Size,
+				StorageClass: "STANDARD",
+			})
+		}
+		res.KeyCount++
+	}
+	if i < len(entries) {
+		res.IsTruncated = true
+		res.NextContinuationToken = base64.RawURLEncoding.EncodeToString([]byte(after))
+	}
+	res.Prefix = res.encode(res.Prefix)
+	res.StartAfter = res.encode(res.StartAfter)
+	writeXML(w, http.StatusOK, res)
+}
+
+// entries will return the entries of a bucket whose key starts with prefix, sorted by key. Directories are only
+// listed when not recursive, their key ending with a slash.
+func (h *handler) entries(r *http.Request, bucket, prefix string, recursive bool) ([]entry, error) {
+	dir := filesystem.Path(bucket)
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		p, err := objectPath(bucket, prefix[:i])
+		if err != nil {
+			return nil, nil
+		}
+		dir = p
+	}
+	contents, err := h.fsys.ListContents(r.Context(), dir, recursive)
+	if filesystem.IsFileNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []entry
+	for _, md := range contents {
+		if recursive && md.IsDir() {
+			continue
+		}
+		key := strings.TrimPrefix(string(md.Path), bucket+"/")
+		if md.IsDir() {
+			key += "/"
+		}
+		if strings.HasPrefix(key, prefix) {
+			entries = append(entries, entry{key: key, md: md})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+	return entries, nil
+}
+
+// encode will encode key when the request asked for URL encoded keys.
+func (res *listResult) encode(key string) string {
+	if res.EncodingType != "url" {
+		return key
+	}
+	return strings.ReplaceAll(url.QueryEscape(key), "+", "%20")
+}
+
+func etagOf(md filesystem.Metadata) string {
+	if md.ETag == "" {
+		return ""
+	}
+	return `"` + md.ETag + `"`
+}
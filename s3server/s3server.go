@@ -0,0 +1,347 @@
+// Package s3server serves file systems over a subset of the S3 REST API, so that tools already speaking S3, such as
+// backup agents, can write into any adapter: the top level directories of the file system are the buckets, and the
+// keys of objects their paths within them.
+//
+// Supported operations are ListBuckets, CreateBucket, HeadBucket, ListObjectsV2, GetObject, HeadObject, PutObject,
+// CopyObject and DeleteObject, with path style requests only. Multipart uploads are not supported, so clients must
+// be configured to upload in a single request.
+package s3server
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/maurofran/filesystem"
+)
+
+// Options configures a Handler.
+type Options struct {
+	// Credentials maps the access keys of clients to their secret keys. Requests must be signed with AWS signature
+	// version 4 by one of them.
+	Credentials map[string]string
+	// Anonymous enables serving unsigned requests when Credentials is empty; every request is denied otherwise.
+	Anonymous bool
+	// Region is the region requests are signed for, "us-east-1" if empty.
+	Region string
+}
+
+// Handler will return an HTTP handler serving fsys over the S3 REST API.
+func Handler(fsys filesystem.Interface, opts Options) http.Handler {
+	if opts.Region == "" {
+		opts.Region = "us-east-1"
+	}
+	return &handler{fsys: fsys, opts: opts}
+}
+
+type handler struct {
+	fsys filesystem.Interface
+	opts Options
+}
+
+const xmlns = "http://s3.amazonaws.com/doc/2006-03-01/"
+
+// timeFormat is the format of the timestamps of responses.
+const timeFormat = "2006-01-02T15:04:05.000Z"
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if len(h.opts.Credentials) > 0 {
+		if err := h.authenticate(r); err != nil {
+			writeError(w, r, err)
+			return
+		}
+	} else if !h.opts.Anonymous {
+		writeError(w, r, errAccessDenied)
+		return
+	}
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	switch {
+	case bucket == "" && r.Method == http.MethodGet:
+		h.listBuckets(w, r)
+	case bucket == "":
+		writeError(w, r, errMethodNotAllowed)
+	case !validBucket(bucket):
+		writeError(w, r, errInvalidBucketName)
+	case key == "":
+		h.serveBucket(w, r, bucket)
+	default:
+		h.serveObject(w, r, bucket, key)
+	}
+}
+
+func (h *handler) serveBucket(w http.ResponseWriter, r *http.Request, bucket string) {
+	switch r.Method {
+	case http.MethodGet:
+		if r.URL.Query().Get("list-type") != "2" {
+			writeError(w, r, errNotImplemented)
+			return
+		}
+		h.listObjects(w, r, bucket)
+	case http.MethodHead:
+		if ok, err := h.fsys.DirectoryExists(r.Context(), filesystem.Path(bucket)); err != nil || !ok {
+			writeError(w, r, bucketError(err))
+		}
+	case http.MethodPut:
+		if ok, err := h.fsys.DirectoryExists(r.Context(), filesystem.Path(bucket)); err != nil || ok {
+			writeError(w, r, orError(err, errBucketAlreadyOwnedByYou))
+			return
+		}
+		if err := h.fsys.CreateDir(r.Context(), filesystem.Path(bucket)); err != nil {
+			writeError(w, r, err)
+			return
+		}
+		w.Header().Set("Location", "/"+bucket)
+	default:
+		writeError(w, r, errMethodNotAllowed)
+	}
+}
+
+func (h *handler) serveObject(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	p, err := objectPath(bucket, key)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead && r.URL.RawQuery != "" && !r.URL.Query().Has("x-id") {
+		// Multipart uploads, tagging, ACLs and the like are sub-resources of objects that are not supported.
+		writeError(w, r, errNotImplemented)
+		return
+	}
+	if ok, err := h.fsys.DirectoryExists(r.Context(), filesystem.Path(bucket)); err != nil || !ok {
+		writeError(w, r, bucketError(err))
+		return
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		h.getObject(w, r, p)
+	case http.MethodPut:
+		if src := r.Header.Get("x-amz-copy-source"); src != "" {
+			h.copyObject(w, r, p, src)
+			return
+		}
+		h.putObject(w, r, p, strings.HasSuffix(key, "/"))
+	case http.MethodDelete:
+		if err := h.deleteObject(r, p, strings.HasSuffix(key, "/")); err != nil {
+			writeError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, r, errMethodNotAllowed)
+	}
+}
+
+// objectPath will return the path of an object, refusing keys escaping their bucket.
+func objectPath(bucket, key string) (filesystem.Path, error) {
+	p, err := filesystem.NewPath(bucket + "/" + key)
+	if err != nil || !strings.HasPrefix(string(p), bucket+"/") {
+		return "", errInvalidKey
+	}
+	return p, nil
+}
+
+func (h *handler) getObject(w http.ResponseWriter, r *http.Request, p filesystem.Path) {
+	md, err := h.fsys.GetMetadata(r.Context(), p)
+	if err == nil && md.IsDir() {
+		err = filesystem.NewFileNotFoundError(p)
+	}
+	if err != nil {
+		writeError(w, r, objectError(err))
+		return
+	}
+	mimeType := md.MimeType
+	if mimeType == "" {
+		mimeType, _ = h.fsys.GetMimeType(r.Context(), p)
+	}
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", mimeType)
+	if md.ETag != "" {
+		w.Header().Set("ETag", `"`+md.ETag+`"`)
+	}
+	if md.VersionID != "" {
+		w.Header().Set("x-amz-version-id", md.VersionID)
+	}
+	if meta, err := h.fsys.GetUserMetadata(r.Context(), p); err == nil {
+		for k, v := range meta {
+			w.Header().Set("x-amz-meta-"+k, v)
+		}
+	}
+	rs, err := h.fsys.ReadSeekStream(r.Context(), p)
+	if err != nil {
+		writeError(w, r, objectError(err))
+		return
+	}
+	defer rs.Close()
+	http.ServeContent(w, r, p.Base(), md.Timestamp, rs)
+}
+
+func (h *handler) putObject(w http.ResponseWriter, r *http.Request, p filesystem.Path, dir bool) {
+	body, err := payload(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if dir {
+		// Zero length keys ending with a slash are the folders of S3 consoles.
+		var opts []filesystem.DirOption
+		if v := aclVisibility(r); v != "" {
+			opts = append(opts, filesystem.WithVisibility(v))
+		}
+		if err := h.fsys.CreateDir(r.Context(), p, opts...); err != nil {
+			writeError(w, r, err)
+			return
+		}
+		w.Header().Set("ETag", `"`+hex.EncodeToString(md5.New().Sum(nil))+`"`)
+		return
+	}
+	sum := md5.New()
+	if err := h.fsys.PutStream(r.Context(), p, io.TeeReader(body, sum), writeOptions(r)...); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", `"`+h.etag(r, p, sum)+`"`)
+}
+
+// deleteObject will delete the file at p, or the directory at p if empty and dir is set. Deleting missing objects
+// succeeds, as in S3.
+func (h *handler) deleteObject(r *http.Request, p filesystem.Path, dir bool) error {
+	if !dir {
+		if _, err := h.fsys.Delete(r.Context(), p); err != nil && !filesystem.IsFileNotFound(err) {
+			return err
+		}
+		return nil
+	}
+	contents, err := h.fsys.ListContents(r.Context(), p, false)
+	if err != nil || len(contents) > 0 {
+		if filesystem.IsFileNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if err := h.fsys.DeleteDir(r.Context(), p); err != nil && !filesystem.IsFileNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// etag will return the ETag of a written file, the MD5 digest of its content if the adapter reports none.
+func (h *handler) etag(r *http.Request, p filesystem.Path, sum hash.Hash) string {
+	if md, err := h.fsys.GetMetadata(r.Context(), p); err == nil && md.ETag != "" {
+		return md.ETag
+	}
+	return hex.EncodeToString(sum.Sum(nil))
+}
+
+func (h *handler) copyObject(w http.ResponseWriter, r *http.Request, p filesystem.Path, src string) {
+	src, _ = url.PathUnescape(strings.SplitN(src, "?", 2)[0])
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(src, "/"), "/")
+	if !validBucket(bucket) || key == "" {
+		writeError(w, r, errInvalidArgument)
+		return
+	}
+	from, err := objectPath(bucket, key)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if from != p {
+		if err := h.fsys.Copy(r.Context(), from, p); err != nil {
+			writeError(w, r, objectError(err))
+			return
+		}
+	}
+	md, err := h.fsys.GetMetadata(r.Context(), p)
+	if err != nil {
+		writeError(w, r, objectError(err))
+		return
+	}
+	writeXML(w, http.StatusOK, struct {
+		XMLName      xml.Name `xml:"CopyObjectResult"`
+		Xmlns        string   `xml:"xmlns,attr"`
+		LastModified string
+		ETag         string
+	}{Xmlns: xmlns, LastModified: md.Timestamp.UTC().Format(timeFormat), ETag: `"` + md.ETag + `"`})
+}
+
+// writeOptions will return the options of a write from the headers of the request.
+func writeOptions(r *http.Request) []filesystem.WriteOption {
+	var opts []filesystem.WriteOption
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		opts = append(opts, filesystem.WithMimeType(ct))
+	}
+	meta := make(map[string]string)
+	for k, v := range r.Header {
+		if name := strings.ToLower(k); strings.HasPrefix(name, "x-amz-meta-") && len(v) > 0 {
+			meta[strings.TrimPrefix(name, "x-amz-meta-")] = v[0]
+		}
+	}
+	if len(meta) > 0 {
+		opts = append(opts, filesystem.WithMetadata(meta))
+	}
+	if v := aclVisibility(r); v != "" {
+		opts = append(opts, filesystem.WithVisibility(v))
+	}
+	return opts
+}
+
+// aclVisibility will return the visibility matching the canned ACL of the request, if any.
+func aclVisibility(r *http.Request) filesystem.Visibility {
+	switch r.Header.Get("x-amz-acl") {
+	case "public-read", "public-read-write":
+		return filesystem.VisibilityPublic
+	case "private":
+		return filesystem.VisibilityPrivate
+	}
+	return ""
+}
+
+func (h *handler) listBuckets(w http.ResponseWriter, r *http.Request) {
+	contents, err := h.fsys.ListContents(r.Context(), filesystem.RootPath, false)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	type bucket struct {
+		Name         string
+		CreationDate string
+	}
+	var buckets []bucket
+	for _, md := range contents {
+		if md.IsDir() && validBucket(md.Path.Base()) {
+			buckets = append(buckets, bucket{Name: md.Path.Base(), CreationDate: md.Timestamp.UTC().Format(timeFormat)})
+		}
+	}
+	writeXML(w, http.StatusOK, struct {
+		XMLName xml.Name `xml:"ListAllMyBucketsResult"`
+		Xmlns   string   `xml:"xmlns,attr"`
+		Owner   struct{ ID, DisplayName string }
+		Buckets []bucket `xml:"Buckets>Bucket"`
+	}{Xmlns: xmlns, Buckets: buckets})
+}
+
+// validBucket will check if name is a valid bucket name: 3 to 63 lowercase letters, digits, dots and hyphens.
+func validBucket(name string) bool {
+	if len(name) < 3 || len(name) > 63 {
+		return false
+	}
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '.' || c == '-') {
+			return false
+		}
+	}
+	return name[0] != '.' && name[0] != '-'
+}
+
+func writeXML(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(code)
+	io.WriteString(w, xml.Header)
+	xml.NewEncoder(w).Encode(v)
+}
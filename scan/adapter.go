@@ -0,0 +1,126 @@
+package scan
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+
+	"github.com/maurofran/filesystem"
+)
+
+// Wrap will decorate provided adapter so that written content is scanned before being stored. Infected content
+// is rejected with an InfectedFileError and, when quarantine is not the root path, stored under it instead.
+//
+// Streams are spooled to a temporary file while being scanned, so they are never buffered in memory.
+func Wrap(a filesystem.Adapter, s Scanner, quarantine filesystem.Path) filesystem.Adapter {
+	return &adapter{Adapter: a, scanner: s, quarantine: quarantine}
+}
+
+type adapter struct {
+	filesystem.Adapter
+	scanner    Scanner
+	quarantine filesystem.Path
+}
+
+func (a *adapter) checkContent(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	threat, err := a.scanner.Scan(bytes.NewReader(content))
+	if err != nil || threat == "" {
+		return err
+	}
+	if a.quarantine == filesystem.RootPath {
+		return infectedFileError{path, threat, filesystem.RootPath}
+	}
+	quarantine := a.quarantine.Join(string(path))
+	if err := a.Adapter.Put(ctx, quarantine, content, cfg); err != nil {
+		return err
+	}
+	return infectedFileError{path, threat, quarantine}
+}
+
+// checkStream will scan a stream, returning the spooled copy of its content to be written in place of it. The
+// returned file must be closed with release.
+func (a *adapter) checkStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) (*os.File, error) {
+	f, err := os.CreateTemp("", "scan-")
+	if err != nil {
+		return nil, err
+	}
+	threat, err := a.scanner.Scan(io.TeeReader(r, f))
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		release(f)
+		return nil, err
+	}
+	if threat == "" {
+		return f, nil
+	}
+	defer release(f)
+	if a.quarantine == filesystem.RootPath {
+		return nil, infectedFileError{path, threat, filesystem.RootPath}
+	}
+	quarantine := a.quarantine.Join(string(path))
+	if err := a.Adapter.PutStream(ctx, quarantine, f, cfg); err != nil {
+		return nil, err
+	}
+	return nil, infectedFileError{path, threat, quarantine}
+}
+
+func release(f *os.File) {
+	f.Close()
+	os.Remove(f.Name())
+}
+
+func (a *adapter) Write(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	if err := a.checkContent(ctx, path, content, cfg); err != nil {
+		return err
+	}
+	return a.Adapter.Write(ctx, path, content, cfg)
+}
+
+func (a *adapter) WriteStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	f, err := a.checkStream(ctx, path, r, cfg)
+	if err != nil {
+		return err
+	}
+	defer release(f)
+	return a.Adapter.WriteStream(ctx, path, f, cfg)
+}
+
+func (a *adapter) Update(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	if err := a.checkContent(ctx, path, content, cfg); err != nil {
+		return err
+	}
+	return a.Adapter.Update(ctx, path, content, cfg)
+}
+
+func (a *adapter) UpdateStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	f, err := a.checkStream(ctx, path, r, cfg)
+	if err != nil {
+		return err
+	}
+	defer release(f)
+	return a.Adapter.UpdateStream(ctx, path, f, cfg)
+}
+
+func (a *adapter) Put(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	if err := a.checkContent(ctx, path, content, cfg); err != nil {
+		return err
+	}
+	return a.Adapter.Put(ctx, path, content, cfg)
+}
+
+func (a *adapter) PutStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	f, err := a.checkStream(ctx, path, r, cfg)
+	if err != nil {
+		return err
+	}
+	defer release(f)
+	return a.Adapter.PutStream(ctx, path, f, cfg)
+}
+
+// Close will close the decorated adapter.
+func (a *adapter) Close() error {
+	return filesystem.Close(a.Adapter)
+}
@@ -0,0 +1,96 @@
+package scan
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// ICAP is a scanner delegating to an ICAP server (RFC 3507) through a RESPMOD request.
+type ICAP struct {
+	// Address is the host:port of ICAP server.
+	Address string
+	// Service is the name of the scanning service, e.g. "avscan".
+	Service string
+	// Timeout is the maximum duration of a scan, zero means no timeout.
+	Timeout time.Duration
+}
+
+const icapResponseHeader = "HTTP/1.1 200 OK\r\n\r\n"
+
+// Scan will send content to ICAP server. A 204 reply means content is clean, while infections are reported
+// through the X-Infection-Found or X-Virus-ID headers.
+func (c *ICAP) Scan(r io.Reader) (string, error) {
+	conn, err := net.DialTimeout("tcp", c.Address, c.Timeout)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	if c.Timeout > 0 {
+		conn.SetDeadline(time.Now().Add(c.Timeout))
+	}
+	w := bufio.NewWriter(conn)
+	fmt.Fprintf(w, "RESPMOD icap://%s/%s ICAP/1.0\r\n", c.Address, c.Service)
+	fmt.Fprintf(w, "Host: %s\r\n", c.Address)
+	fmt.Fprintf(w, "Allow: 204\r\n")
+	fmt.Fprintf(w, "Encapsulated: res-hdr=0, res-body=%d\r\n\r\n", len(icapResponseHeader))
+	w.WriteString(icapResponseHeader)
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			fmt.Fprintf(w, "%x\r\n", n)
+			w.Write(buf[:n])
+			w.WriteString("\r\n")
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	w.WriteString("0\r\n\r\n")
+	if err := w.Flush(); err != nil {
+		return "", err
+	}
+	tp := textproto.NewReader(bufio.NewReader(conn))
+	status, err := tp.ReadLine()
+	if err != nil {
+		return "", err
+	}
+	header, err := tp.ReadMIMEHeader()
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	fields := strings.Fields(status)
+	if len(fields) < 2 {
+		return "", errors.New("icap: malformed status line " + status)
+	}
+	switch fields[1] {
+	case "204":
+		return "", nil
+	case "200":
+		return parseICAPThreat(header), nil
+	}
+	return "", errors.New("icap: " + status)
+}
+
+// parseICAPThreat will extract the threat name from response headers, returning an empty string if none.
+func parseICAPThreat(header textproto.MIMEHeader) string {
+	if found := header.Get("X-Infection-Found"); found != "" {
+		for _, field := range strings.Split(found, ";") {
+			field = strings.TrimSpace(field)
+			if strings.HasPrefix(field, "Threat=") {
+				return strings.TrimPrefix(field, "Threat=")
+			}
+		}
+		return found
+	}
+	return header.Get("X-Virus-ID")
+}
@@ -0,0 +1,62 @@
+package scan
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/maurofran/filesystem"
+)
+
+// Scanner is the interface exposed by virus scanners.
+type Scanner interface {
+	// Scan will return the name of the threat found in content, or an empty string if content is clean.
+	Scan(r io.Reader) (string, error)
+}
+
+// ScannerFunc is a function acting as a scanner.
+type ScannerFunc func(r io.Reader) (string, error)
+
+// Scan will invoke the function.
+func (f ScannerFunc) Scan(r io.Reader) (string, error) {
+	return f(r)
+}
+
+// InfectedFileError is the error returned when written content is infected.
+type InfectedFileError interface {
+	error
+	Path() filesystem.Path
+	Threat() string
+	// Quarantine is the path the content was quarantined at, or an empty path if it was discarded.
+	Quarantine() filesystem.Path
+}
+
+type infectedFileError struct {
+	path       filesystem.Path
+	threat     string
+	quarantine filesystem.Path
+}
+
+// Path is the path the content was written at.
+func (e infectedFileError) Path() filesystem.Path {
+	return e.path
+}
+
+// Threat is the name of the threat found by scanner.
+func (e infectedFileError) Threat() string {
+	return e.threat
+}
+
+// Quarantine is the path the content was quarantined at.
+func (e infectedFileError) Quarantine() filesystem.Path {
+	return e.quarantine
+}
+
+func (e infectedFileError) Error() string {
+	return fmt.Sprintf("File %s is infected by %s", e.path, e.threat)
+}
+
+// IsInfectedFileError will check if provided error is an infected file error.
+func IsInfectedFileError(err error) bool {
+	_, ok := err.(InfectedFileError)
+	return ok
+}
@@ -0,0 +1,556 @@
+// Package scoped provides an adapter decorator confining every operation under a path prefix, so that a single
+// bucket or disk can be partitioned, e.g. per tenant.
+package scoped
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/maurofran/filesystem"
+)
+
+// New will create an adapter exposing the directory at provided prefix of adapter as its root. Paths are
+// normalized before being prefixed, so that ".." segments cannot escape the prefix; paths escaping it fail with a
+// PathError. New panics if prefix itself is not a valid path.
+func New(adapter filesystem.Adapter, prefix filesystem.Path) filesystem.Adapter {
+	p, err := filesystem.NewPath(string(prefix))
+	if err != nil {
+		panic(fmt.Sprintf("scoped: invalid prefix %q: %v", prefix, err))
+	}
+	return &scoped{Adapter: adapter, prefix: p}
+}
+
+type scoped struct {
+	filesystem.Adapter
+	prefix filesystem.Path
+}
+
+// inner will map a path of the scoped adapter to the path of decorated adapter.
+func (s *scoped) inner(path filesystem.Path) (filesystem.Path, error) {
+	p, err := filesystem.NewPath(string(path))
+	if err != nil {
+		return "", err
+	}
+	return s.prefix.Join(string(p)), nil
+}
+
+// outer will map a path of decorated adapter to the path of the scoped adapter.
+func (s *scoped) outer(path filesystem.Path) filesystem.Path {
+	if s.prefix == filesystem.RootPath {
+		return path
+	}
+	if path == s.prefix {
+		return filesystem.RootPath
+	}
+	return filesystem.Path(strings.TrimPrefix(string(path), string(s.prefix)+"/"))
+}
+
+// metadata will map the path of provided metadata, returning a copy.
+func (s *scoped) metadata(md filesystem.Metadata) filesystem.Metadata {
+	if md == nil {
+		return nil
+	}
+	out := md.Clone()
+	out.Path = s.outer(md.Path)
+	return out
+}
+
+// mapError will hide the prefix from the paths of missing files.
+func (s *scoped) mapError(err error) error {
+	if e, ok := err.(filesystem.FileNotFoundError); ok {
+		return filesystem.NewFileNotFoundError(s.outer(e.Path()))
+	}
+	if e, ok := err.(filesystem.UnsupportedOperationError); ok {
+		return filesystem.NewUnsupportedOperationError(s.outer(e.Path()), e.Operation())
+	}
+	if e, ok := err.(filesystem.PreconditionFailedError); ok {
+		return filesystem.NewPreconditionFailedError(s.outer(e.Path()))
+	}
+	return err
+}
+
+func (s *scoped) Has(ctx context.Context, path filesystem.Path) (bool, error) {
+	p, err := s.inner(path)
+	if err != nil {
+		return false, err
+	}
+	ok, err := s.Adapter.Has(ctx, p)
+	return ok, s.mapError(err)
+}
+
+func (s *scoped) Read(ctx context.Context, path filesystem.Path) ([]byte, error) {
+	p, err := s.inner(path)
+	if err != nil {
+		return nil, err
+	}
+	content, err := s.Adapter.Read(ctx, p)
+	return content, s.mapError(err)
+}
+
+func (s *scoped) ReadStream(ctx context.Context, path filesystem.Path) (io.ReadCloser, error) {
+	p, err := s.inner(path)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := s.Adapter.ReadStream(ctx, p)
+	return rc, s.mapError(err)
+}
+
+func (s *scoped) Write(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	p, err := s.inner(path)
+	if err != nil {
+		return err
+	}
+	return s.mapError(s.Adapter.Write(ctx, p, content, cfg))
+}
+
+func (s *scoped) WriteStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	p, err := s.inner(path)
+	if err != nil {
+		return err
+	}
+	return s.mapError(s.Adapter.WriteStream(ctx, p, r, cfg))
+}
+
+func (s *scoped) Update(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	p, err := s.inner(path)
+	if err != nil {
+		return err
+	}
+	return s.mapError(s.Adapter.Update(ctx, p, content, cfg))
+}
+
+func (s *scoped) UpdateStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	p, err := s.inner(path)
+	if err != nil {
+		return err
+	}
+	return s.mapError(s.Adapter.UpdateStream(ctx, p, r, cfg))
+}
+
+func (s *scoped) Put(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	p, err := s.inner(path)
+	if err != nil {
+		return err
+	}
+	return s.mapError(s.Adapter.Put(ctx, p, content, cfg))
+}
+
+func (s *scoped) PutStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	p, err := s.inner(path)
+	if err != nil {
+		return err
+	}
+	return s.mapError(s.Adapter.PutStream(ctx, p, r, cfg))
+}
+
+func (s *scoped) Delete(ctx context.Context, path filesystem.Path) error {
+	p, err := s.inner(path)
+	if err != nil {
+		return err
+	}
+	return s.mapError(s.Adapter.Delete(ctx, p))
+}
+
+func (s *scoped) ReadAndDelete(ctx context.Context, path filesystem.Path) ([]byte, error) {
+	p, err := s.inner(path)
+	if err != nil {
+		return nil, err
+	}
+	content, err := s.Adapter.ReadAndDelete(ctx, p)
+	return content, s.mapError(err)
+}
+
+func (s *scoped) Move(ctx context.Context, path, newpath filesystem.Path) error {
+	p, err := s.inner(path)
+	if err != nil {
+		return err
+	}
+	np, err := s.inner(newpath)
+	if err != nil {
+		return err
+	}
+	return s.mapError(s.Adapter.Move(ctx, p, np))
+}
+
+func (s *scoped) Copy(ctx context.Context, path, newpath filesystem.Path) error {
+	p, err := s.inner(path)
+	if err != nil {
+		return err
+	}
+	np, err := s.inner(newpath)
+	if err != nil {
+		return err
+	}
+	return s.mapError(s.Adapter.Copy(ctx, p, np))
+}
+
+func (s *scoped) GetMimeType(ctx context.Context, path filesystem.Path) (string, error) {
+	p, err := s.inner(path)
+	if err != nil {
+		return "", err
+	}
+	mt, err := s.Adapter.GetMimeType(ctx, p)
+	return mt, s.mapError(err)
+}
+
+func (s *scoped) GetTimestamp(ctx context.Context, path filesystem.Path) (time.Time, error) {
+	p, err := s.inner(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, err := s.Adapter.GetTimestamp(ctx, p)
+	return t, s.mapError(err)
+}
+
+func (s *scoped) GetFileSize(ctx context.Context, path filesystem.Path) (int64, error) {
+	p, err := s.inner(path)
+	if err != nil {
+		return 0, err
+	}
+	size, err := s.Adapter.GetFileSize(ctx, p)
+	return size, s.mapError(err)
+}
+
+func (s *scoped) GetMetadata(ctx context.Context, path filesystem.Path) (filesystem.Metadata, error) {
+	p, err := s.inner(path)
+	if err != nil {
+		return nil, err
+	}
+	md, err := s.Adapter.GetMetadata(ctx, p)
+	if err != nil {
+		return nil, s.mapError(err)
+	}
+	return s.metadata(md), nil
+}
+
+func (s *scoped) CreateDir(ctx context.Context, path filesystem.Path, cfg filesystem.Config) error {
+	p, err := s.inner(path)
+	if err != nil {
+		return err
+	}
+	return s.mapError(s.Adapter.CreateDir(ctx, p, cfg))
+}
+
+func (s *scoped) DeleteDir(ctx context.Context, path filesystem.Path) error {
+	p, err := s.inner(path)
+	if err != nil {
+		return err
+	}
+	return s.mapError(s.Adapter.DeleteDir(ctx, p))
+}
+
+func (s *scoped) GetVisibility(ctx context.Context, path filesystem.Path) (filesystem.Visibility, error) {
+	p, err := s.inner(path)
+	if err != nil {
+		return "", err
+	}
+	v, err := s.Adapter.GetVisibility(ctx, p)
+	return v, s.mapError(err)
+}
+
+func (s *scoped) SetVisibility(ctx context.Context, path filesystem.Path, v filesystem.Visibility) error {
+	p, err := s.inner(path)
+	if err != nil {
+		return err
+	}
+	return s.mapError(s.Adapter.SetVisibility(ctx, p, v))
+}
+
+func (s *scoped) ListContents(ctx context.Context, path filesystem.Path, recursive bool) ([]filesystem.Metadata, error) {
+	p, err := s.inner(path)
+	if err != nil {
+		return nil, err
+	}
+	listing, err := s.Adapter.ListContents(ctx, p, recursive)
+	if err != nil {
+		return nil, s.mapError(err)
+	}
+	result := make([]filesystem.Metadata, len(listing))
+	for i, md := range listing {
+		result[i] = s.metadata(md)
+	}
+	return result, nil
+}
+
+// IterateContents will iterate over the contents lazily when the decorated adapter supports it.
+func (s *scoped) IterateContents(ctx context.Context, path filesystem.Path, recursive bool) (filesystem.Iterator, error) {
+	it, ok := s.Adapter.(filesystem.ContentsIterator)
+	if !ok {
+		listing, err := s.ListContents(ctx, path, recursive)
+		if err != nil {
+			return nil, err
+		}
+		return filesystem.SliceIterator(listing), nil
+	}
+	p, err := s.inner(path)
+	if err != nil {
+		return nil, err
+	}
+	inner, err := it.IterateContents(ctx, p, recursive)
+	if err != nil {
+		return nil, s.mapError(err)
+	}
+	return filesystem.IteratorFunc(func() (filesystem.Metadata, error) {
+		md, err := inner.Next()
+		return s.metadata(md), err
+	}), nil
+}
+
+// ReadRange will read part of the file natively when the decorated adapter supports it.
+func (s *scoped) ReadRange(ctx context.Context, path filesystem.Path, offset, length int64) (io.ReadCloser, error) {
+	r, ok := s.Adapter.(filesystem.RangeReader)
+	if !ok {
+		rc, err := s.ReadStream(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		return filesystem.SliceStream(rc, offset, length)
+	}
+	p, err := s.inner(path)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := r.ReadRange(ctx, p, offset, length)
+	return rc, s.mapError(err)
+}
+
+// GetChecksum will return the checksum natively provided by the decorated adapter, if any.
+func (s *scoped) GetChecksum(ctx context.Context, path filesystem.Path, algo filesystem.ChecksumAlgo) (string, error) {
+	cp, ok := s.Adapter.(filesystem.ChecksumProvider)
+	if !ok {
+		return "", filesystem.NewUnsupportedChecksumError(path, algo)
+	}
+	p, err := s.inner(path)
+	if err != nil {
+		return "", err
+	}
+	sum, err := cp.GetChecksum(ctx, p, algo)
+	if filesystem.IsUnsupportedChecksumError(err) {
+		return "", filesystem.NewUnsupportedChecksumError(path, algo)
+	}
+	return sum, s.mapError(err)
+}
+
+// FileExists will check if a file exists natively when the decorated adapter supports it.
+func (s *scoped) FileExists(ctx context.Context, path filesystem.Path) (bool, error) {
+	c, ok := s.Adapter.(filesystem.ExistenceChecker)
+	if !ok {
+		return false, filesystem.NewUnsupportedOperationError(path, "FileExists")
+	}
+	p, err := s.inner(path)
+	if err != nil {
+		return false, err
+	}
+	exists, err := c.FileExists(ctx, p)
+	return exists, s.mapError(err)
+}
+
+// DirectoryExists will check if a directory exists natively when the decorated adapter supports it.
+func (s *scoped) DirectoryExists(ctx context.Context, path filesystem.Path) (bool, error) {
+	c, ok := s.Adapter.(filesystem.ExistenceChecker)
+	if !ok {
+		return false, filesystem.NewUnsupportedOperationError(path, "DirectoryExists")
+	}
+	p, err := s.inner(path)
+	if err != nil {
+		return false, err
+	}
+	exists, err := c.DirectoryExists(ctx, p)
+	return exists, s.mapError(err)
+}
+
+// GetDirectoryMetadata will describe the directory natively when the decorated adapter supports it.
+func (s *scoped) GetDirectoryMetadata(ctx context.Context, path filesystem.Path) (filesystem.DirectoryInfo, error) {
+	dp, ok := s.Adapter.(filesystem.DirectoryMetadataProvider)
+	if !ok {
+		return filesystem.DirectoryInfo{}, filesystem.NewUnsupportedOperationError(path, "GetDirectoryMetadata")
+	}
+	p, err := s.inner(path)
+	if err != nil {
+		return filesystem.DirectoryInfo{}, err
+	}
+	info, err := dp.GetDirectoryMetadata(ctx, p)
+	info.Path = path
+	return info, s.mapError(err)
+}
+
+// ListPage will list a page natively when the decorated adapter supports it.
+func (s *scoped) ListPage(ctx context.Context, path filesystem.Path, recursive bool, cursor string, limit int) (filesystem.Page, error) {
+	l, ok := s.Adapter.(filesystem.PageLister)
+	if !ok {
+		return filesystem.Page{}, filesystem.NewUnsupportedOperationError(path, "ListPage")
+	}
+	p, err := s.inner(path)
+	if err != nil {
+		return filesystem.Page{}, err
+	}
+	page, err := l.ListPage(ctx, p, recursive, cursor, limit)
+	if err != nil {
+		return filesystem.Page{}, s.mapError(err)
+	}
+	for i, md := range page.Entries {
+		page.Entries[i] = s.metadata(md)
+	}
+	return page, nil
+}
+
+// IterateSorted will iterate in order natively when the decorated adapter supports it.
+func (s *scoped) IterateSorted(ctx context.Context, path filesystem.Path, recursive bool, order filesystem.Sort) (filesystem.Iterator, error) {
+	si, ok := s.Adapter.(filesystem.SortedIterator)
+	if !ok {
+		return nil, filesystem.NewUnsupportedOperationError(path, "IterateSorted")
+	}
+	p, err := s.inner(path)
+	if err != nil {
+		return nil, err
+	}
+	inner, err := si.IterateSorted(ctx, p, recursive, order)
+	if err != nil {
+		return nil, s.mapError(err)
+	}
+	return filesystem.IteratorFunc(func() (filesystem.Metadata, error) {
+		md, err := inner.Next()
+		return s.metadata(md), err
+	}), nil
+}
+
+// GetUserMetadata will return the user metadata from the decorated adapter, if it supports it.
+func (s *scoped) GetUserMetadata(ctx context.Context, path filesystem.Path) (map[string]string, error) {
+	mp, ok := s.Adapter.(filesystem.UserMetadataProvider)
+	if !ok {
+		return nil, filesystem.NewUnsupportedOperationError(path, "GetUserMetadata")
+	}
+	p, err := s.inner(path)
+	if err != nil {
+		return nil, err
+	}
+	md, err := mp.GetUserMetadata(ctx, p)
+	return md, s.mapError(err)
+}
+
+// SetUserMetadata will set the user metadata through the decorated adapter, if it supports it.
+func (s *scoped) SetUserMetadata(ctx context.Context, path filesystem.Path, metadata map[string]string) error {
+	mp, ok := s.Adapter.(filesystem.UserMetadataProvider)
+	if !ok {
+		return filesystem.NewUnsupportedOperationError(path, "SetUserMetadata")
+	}
+	p, err := s.inner(path)
+	if err != nil {
+		return err
+	}
+	return s.mapError(mp.SetUserMetadata(ctx, p, metadata))
+}
+
+// WriteAtomic will write atomically through the decorated adapter, if it has a dedicated mechanism.
+func (s *scoped) WriteAtomic(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	w, ok := s.Adapter.(filesystem.AtomicWriter)
+	if !ok {
+		return filesystem.NewUnsupportedOperationError(path, "WriteAtomic")
+	}
+	p, err := s.inner(path)
+	if err != nil {
+		return err
+	}
+	return s.mapError(w.WriteAtomic(ctx, p, r, cfg))
+}
+
+// WriteAt will write part of a file in place through the decorated adapter, if it supports it.
+func (s *scoped) WriteAt(ctx context.Context, path filesystem.Path, p []byte, offset int64) error {
+	w, ok := s.Adapter.(filesystem.RandomWriter)
+	if !ok {
+		return filesystem.NewUnsupportedOperationError(path, "WriteAt")
+	}
+	inner, err := s.inner(path)
+	if err != nil {
+		return err
+	}
+	return s.mapError(w.WriteAt(ctx, inner, p, offset))
+}
+
+// WriteIf will write conditionally through the decorated adapter, if it supports it.
+func (s *scoped) WriteIf(ctx context.Context, path filesystem.Path, content []byte, cond filesystem.Precondition, cfg filesystem.Config) error {
+	w, ok := s.Adapter.(filesystem.ConditionalWriter)
+	if !ok {
+		return filesystem.NewUnsupportedOperationError(path, "WriteIf")
+	}
+	p, err := s.inner(path)
+	if err != nil {
+		return err
+	}
+	return s.mapError(w.WriteIf(ctx, p, content, cond, cfg))
+}
+
+// DeleteIf will delete conditionally through the decorated adapter, if it supports it.
+func (s *scoped) DeleteIf(ctx context.Context, path filesystem.Path, cond filesystem.Precondition) error {
+	w, ok := s.Adapter.(filesystem.ConditionalWriter)
+	if !ok {
+		return filesystem.NewUnsupportedOperationError(path, "DeleteIf")
+	}
+	p, err := s.inner(path)
+	if err != nil {
+		return err
+	}
+	return s.mapError(w.DeleteIf(ctx, p, cond))
+}
+
+// ListVersions will list the versions from the decorated adapter, if it keeps versions.
+func (s *scoped) ListVersions(ctx context.Context, path filesystem.Path) ([]filesystem.Metadata, error) {
+	v, ok := s.Adapter.(filesystem.Versioned)
+	if !ok {
+		return nil, filesystem.NewUnsupportedOperationError(path, "ListVersions")
+	}
+	p, err := s.inner(path)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := v.ListVersions(ctx, p)
+	if err != nil {
+		return nil, s.mapError(err)
+	}
+	out := make([]filesystem.Metadata, len(versions))
+	for i, md := range versions {
+		out[i] = s.metadata(md)
+	}
+	return out, nil
+}
+
+// ReadVersion will read the version from the decorated adapter, if it keeps versions.
+func (s *scoped) ReadVersion(ctx context.Context, path filesystem.Path, versionID string) (io.ReadCloser, error) {
+	v, ok := s.Adapter.(filesystem.Versioned)
+	if !ok {
+		return nil, filesystem.NewUnsupportedOperationError(path, "ReadVersion")
+	}
+	p, err := s.inner(path)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := v.ReadVersion(ctx, p, versionID)
+	return rc, s.mapError(err)
+}
+
+// RestoreVersion will restore the version through the decorated adapter, if it keeps versions.
+func (s *scoped) RestoreVersion(ctx context.Context, path filesystem.Path, versionID string) error {
+	v, ok := s.Adapter.(filesystem.Versioned)
+	if !ok {
+		return filesystem.NewUnsupportedOperationError(path, "RestoreVersion")
+	}
+	p, err := s.inner(path)
+	if err != nil {
+		return err
+	}
+	return s.mapError(v.RestoreVersion(ctx, p, versionID))
+}
+
+// Close will close the decorated adapter.
+func (s *scoped) Close() error {
+	return filesystem.Close(s.Adapter)
+}
+
+// Capabilities will report the capabilities of the decorated adapter.
+func (s *scoped) Capabilities() filesystem.Capabilities {
+	return filesystem.CapabilitiesOf(s.Adapter)
+}
@@ -0,0 +1,149 @@
+package scrub
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/maurofran/filesystem"
+	"github.com/maurofran/filesystem/mirror"
+)
+
+// Corruption describes a file whose content does not match its stored checksum.
+type Corruption struct {
+	Path     filesystem.Path
+	Expected string
+	// Actual is the checksum of current content, empty if the file could not be read.
+	Actual string
+	// Err is the error raised reading the file or, when a mirror is configured, repairing it.
+	Err error
+	// Repaired reports whether the file was restored from a replica of the mirror.
+	Repaired bool
+}
+
+// Scrubber re-verifies stored checksums of files, a fraction of them per run.
+type Scrubber struct {
+	// FS is the file system holding the files.
+	FS filesystem.Interface
+	// Store holds the reference checksums.
+	Store Store
+	// Fraction is the share of stored checksums verified per run, between 0 and 1. Zero verifies all of them.
+	Fraction float64
+	// Mirror is the adapter created by mirror.New that FS is backed by, if any. Corrupted and missing files are
+	// restored from the first of its replicas holding intact content.
+	Mirror filesystem.Adapter
+	// Hash is the hash function used for checksums, SHA-256 if nil.
+	Hash func() hash.Hash
+
+	mu     sync.Mutex
+	cursor int
+}
+
+// streamReader is implemented by both file systems and adapters.
+type streamReader interface {
+	ReadStream(ctx context.Context, path filesystem.Path) (io.ReadCloser, error)
+}
+
+func (s *Scrubber) checksum(ctx context.Context, fs streamReader, path filesystem.Path) (string, error) {
+	newHash := s.Hash
+	if newHash == nil {
+		newHash = sha256.New
+	}
+	r, err := fs.ReadStream(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	h := newHash()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Record will compute and store the reference checksum of file at provided path.
+func (s *Scrubber) Record(ctx context.Context, path filesystem.Path) error {
+	sum, err := s.checksum(ctx, s.FS, path)
+	if err != nil {
+		return err
+	}
+	return s.Store.Set(ctx, path, sum)
+}
+
+// Run will verify the next batch of stored checksums, resuming where previous run stopped so that successive
+// runs cover all files. Files whose content does not match or that are missing are repaired from the replicas of
+// the mirror when one is configured; files that cannot be read for other reasons are reported only.
+func (s *Scrubber) Run(ctx context.Context) ([]Corruption, error) {
+	sums, err := s.Store.Checksums(ctx)
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]filesystem.Path, 0, len(sums))
+	for p := range sums {
+		paths = append(paths, p)
+	}
+	sort.Slice(paths, func(i, j int) bool { return paths[i] < paths[j] })
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	count := len(paths)
+	if s.Fraction > 0 && s.Fraction < 1 {
+		count = int(math.Ceil(float64(len(paths)) * s.Fraction))
+	}
+	s.mu.Lock()
+	start := s.cursor % len(paths)
+	s.cursor = start + count
+	s.mu.Unlock()
+	var corruptions []Corruption
+	for i := 0; i < count; i++ {
+		p := paths[(start+i)%len(paths)]
+		actual, err := s.checksum(ctx, s.FS, p)
+		if err == nil && actual == sums[p] {
+			continue
+		}
+		c := Corruption{Path: p, Expected: sums[p], Actual: actual, Err: err}
+		if s.Mirror != nil && (err == nil || filesystem.IsFileNotFound(err)) {
+			c.Repaired, c.Err = s.repair(ctx, p, sums[p])
+		}
+		corruptions = append(corruptions, c)
+	}
+	return corruptions, nil
+}
+
+// repair will restore the file at path from the first replica whose content matches expected, verifying the
+// restored content.
+func (s *Scrubber) repair(ctx context.Context, path filesystem.Path, expected string) (bool, error) {
+	for _, replica := range mirror.Replicas(s.Mirror) {
+		if sum, err := s.checksum(ctx, replica, path); err != nil || sum != expected {
+			continue
+		}
+		if err := s.restore(ctx, replica, path); err != nil {
+			return false, err
+		}
+		sum, err := s.checksum(ctx, s.FS, path)
+		if err != nil {
+			return false, err
+		}
+		if sum != expected {
+			return false, fmt.Errorf("Restored content of %s does not match its checksum", path)
+		}
+		return true, nil
+	}
+	return false, fmt.Errorf("No replica of %s is intact", path)
+}
+
+// restore will copy the file at path from replica to the file system.
+func (s *Scrubber) restore(ctx context.Context, replica filesystem.Adapter, path filesystem.Path) error {
+	r, err := replica.ReadStream(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return s.FS.PutStream(ctx, path, r)
+}
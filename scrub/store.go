@@ -0,0 +1,113 @@
+package scrub
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/maurofran/filesystem"
+)
+
+// Store holds the reference checksums of files.
+type Store interface {
+	// Checksums will return all the stored checksums by path.
+	Checksums(ctx context.Context) (map[filesystem.Path]string, error)
+	// Set will store the checksum of file at provided path.
+	Set(ctx context.Context, path filesystem.Path, sum string) error
+	// Remove will forget the checksum of file at provided path.
+	Remove(ctx context.Context, path filesystem.Path) error
+}
+
+type memoryStore struct {
+	mu   sync.RWMutex
+	sums map[filesystem.Path]string
+}
+
+// NewMemoryStore will create a store keeping checksums in memory.
+func NewMemoryStore() Store {
+	return &memoryStore{sums: make(map[filesystem.Path]string)}
+}
+
+func (s *memoryStore) Checksums(ctx context.Context) (map[filesystem.Path]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sums := make(map[filesystem.Path]string, len(s.sums))
+	for p, sum := range s.sums {
+		sums[p] = sum
+	}
+	return sums, nil
+}
+
+func (s *memoryStore) Set(ctx context.Context, path filesystem.Path, sum string) error {
+	s.mu.Lock()
+	s.sums[path] = sum
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *memoryStore) Remove(ctx context.Context, path filesystem.Path) error {
+	s.mu.Lock()
+	delete(s.sums, path)
+	s.mu.Unlock()
+	return nil
+}
+
+type fileStore struct {
+	mu   sync.Mutex
+	fs   filesystem.Interface
+	path filesystem.Path
+}
+
+// NewFileStore will create a store persisting checksums as a JSON document at provided path of file system.
+func NewFileStore(fs filesystem.Interface, path filesystem.Path) Store {
+	return &fileStore{fs: fs, path: path}
+}
+
+func (s *fileStore) load(ctx context.Context) (map[filesystem.Path]string, error) {
+	sums := make(map[filesystem.Path]string)
+	exists, err := s.fs.Has(ctx, s.path)
+	if err != nil || !exists {
+		return sums, err
+	}
+	content, err := s.fs.Read(ctx, s.path)
+	if err != nil {
+		return nil, err
+	}
+	return sums, json.Unmarshal(content, &sums)
+}
+
+func (s *fileStore) save(ctx context.Context, sums map[filesystem.Path]string) error {
+	b, err := json.Marshal(sums)
+	if err != nil {
+		return err
+	}
+	return s.fs.Put(ctx, s.path, b)
+}
+
+func (s *fileStore) Checksums(ctx context.Context) (map[filesystem.Path]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load(ctx)
+}
+
+func (s *fileStore) Set(ctx context.Context, path filesystem.Path, sum string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sums, err := s.load(ctx)
+	if err != nil {
+		return err
+	}
+	sums[path] = sum
+	return s.save(ctx, sums)
+}
+
+func (s *fileStore) Remove(ctx context.Context, path filesystem.Path) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sums, err := s.load(ctx)
+	if err != nil {
+		return err
+	}
+	delete(sums, path)
+	return s.save(ctx, sums)
+}
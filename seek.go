@@ -0,0 +1,131 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ReadSeekStream will read the file at provided path as a seekable stream, e.g. to serve it with http.ServeContent.
+// Adapters reporting the RangeReads capability read from the offset sought with a range read; otherwise the stream
+// of the adapter is returned as is when it seeks, and spilled to a temporary file, removed on close, when not.
+func (fs *filesystem) ReadSeekStream(ctx context.Context, path Path) (io.ReadSeekCloser, error) {
+	path, err := NewPath(string(path))
+	if err != nil {
+		return nil, err
+	}
+	r, ok := fs.adapter.(RangeReader)
+	if ok && CapabilitiesOf(fs.adapter).RangeReads {
+		size, err := fs.adapter.GetFileSize(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		return &rangeSeeker{ctx: ctx, reader: r, path: path, size: size}, nil
+	}
+	rc, err := fs.adapter.ReadStream(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if rsc, ok := rc.(io.ReadSeekCloser); ok {
+		return rsc, nil
+	}
+	defer rc.Close()
+	return spill(rc)
+}
+
+// spill will copy the content of r to a temporary file, returned positioned at its start and removed on close.
+func spill(r io.Reader) (io.ReadSeekCloser, error) {
+	f, err := os.CreateTemp("", "filesystem-*")
+	if err != nil {
+		return nil, err
+	}
+	tf := tempFile{f}
+	if _, err := io.Copy(f, r); err != nil {
+		tf.Close()
+		return nil, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		tf.Close()
+		return nil, err
+	}
+	return tf, nil
+}
+
+// tempFile is a temporary file removed on close.
+type tempFile struct {
+	*os.File
+}
+
+func (f tempFile) Close() error {
+	err := f.File.Close()
+	if rerr := os.Remove(f.Name()); err == nil {
+		err = rerr
+	}
+	return err
+}
+
+// rangeSeeker is a seekable stream reading from the current offset with a range read, started on the first read
+// following a seek.
+type rangeSeeker struct {
+	ctx     context.Context
+	reader  RangeReader
+	path    Path
+	size    int64
+	offset  int64
+	current io.ReadCloser
+}
+
+func (s *rangeSeeker) Read(p []byte) (int, error) {
+	if s.offset >= s.size {
+		return 0, io.EOF
+	}
+	if s.current == nil {
+		rc, err := s.reader.ReadRange(s.ctx, s.path, s.offset, -1)
+		if err != nil {
+			return 0, err
+		}
+		s.current = rc
+	}
+	n, err := s.current.Read(p)
+	s.offset += int64(n)
+	return n, err
+}
+
+func (s *rangeSeeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += s.offset
+	case io.SeekEnd:
+		offset += s.size
+	default:
+		return 0, fmt.Errorf("invalid whence %d seeking %s", whence, s.path)
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("invalid negative offset %d seeking %s", offset, s.path)
+	}
+	if offset != s.offset {
+		s.Close()
+		s.offset = offset
+	}
+	return offset, nil
+}
+
+func (s *rangeSeeker) Close() error {
+	if s.current == nil {
+		return nil
+	}
+	err := s.current.Close()
+	s.current = nil
+	return err
+}
+
+// ReadSeekStream will read the file at provided path as a seekable stream.
+func (mm *mountManager) ReadSeekStream(ctx context.Context, path Path) (io.ReadSeekCloser, error) {
+	mgr, subPath, err := mm.managerFor(path)
+	if err != nil {
+		return nil, err
+	}
+	return mgr.ReadSeekStream(ctx, subPath)
+}
@@ -0,0 +1,588 @@
+// Package smb provides an adapter storing files on SMB2/3 (Windows, CIFS) shares, through go-smb2.
+//
+// NTFS attributes of files are reported in metadata, as "hidden", "system", "readonly" and "archive" booleans.
+// go-smb2 does not expose security descriptors: visibility is the one configured for the share, mirroring its ACL,
+// and cannot be changed per file. DFS referrals are not resolved either; DFS links are configured instead, mapping
+// paths of the namespace to their UNC targets, which are connected to on demand with the same credentials.
+package smb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hirochachacha/go-smb2"
+	"github.com/maurofran/filesystem"
+)
+
+// Settings read by NewFromConfig.
+const (
+	// ConfigAddress is the address of the server, as host or host:port.
+	ConfigAddress = "address"
+	// ConfigShare is the name of the share.
+	ConfigShare = "share"
+	// ConfigUser is the user name.
+	ConfigUser = "user"
+	// ConfigPassword is the password.
+	ConfigPassword = "password"
+	// ConfigDomain is the domain of the user.
+	ConfigDomain = "domain"
+	// ConfigVisibility is the visibility of the share, "public" or "private".
+	ConfigVisibility = "visibility"
+	// ConfigDFS maps DFS link paths to their UNC targets, as a map of strings.
+	ConfigDFS = "dfs"
+)
+
+const defaultPort = "445"
+
+// NTFS attributes reported in metadata.
+const (
+	attributeReadOnly  = 0x1
+	attributeHidden    = 0x2
+	attributeSystem    = 0x4
+	attributeDirectory = 0x10
+	attributeArchive   = 0x20
+)
+
+// Options are the options of the adapter.
+type Options struct {
+	// Address is the address of the server, as host or host:port, port 445 being the default.
+	Address string
+	// Share is the name of the share.
+	Share string
+	// User, Password and Domain are the NTLM credentials.
+	User     string
+	Password string
+	Domain   string
+	// Visibility is the visibility of the files of the share, private if empty.
+	Visibility filesystem.Visibility
+	// DFS maps paths of the namespace to UNC targets, such as "\\server\share\dir", used instead of the share for
+	// those paths and the paths under them.
+	DFS map[string]string
+}
+
+type session struct {
+	conn    net.Conn
+	session *smb2.Session
+	shares  map[string]*smb2.Share
+}
+
+// Adapter is an adapter storing files on an SMB share.
+type Adapter struct {
+	opts  Options
+	links []link
+
+	mu       sync.Mutex
+	sessions map[string]*session
+}
+
+// link is a DFS link, mapping a path to a directory of a share.
+type link struct {
+	path    filesystem.Path
+	address string
+	share   string
+	dir     string
+}
+
+// New will create an adapter storing files on the share described by provided options. Connections are opened
+// on first use and closed by Close.
+func New(opts Options) (*Adapter, error) {
+	if opts.Visibility == "" {
+		opts.Visibility = filesystem.VisibilityPrivate
+	}
+	a := &Adapter{opts: opts, sessions: make(map[string]*session)}
+	for p, target := range opts.DFS {
+		l, err := parseLink(p, target)
+		if err != nil {
+			return nil, err
+		}
+		a.links = append(a.links, l)
+	}
+	// Longest paths first, to match the most specific link.
+	sort.Slice(a.links, func(i, j int) bool { return len(a.links[i].path) > len(a.links[j].path) })
+	return a, nil
+}
+
+func init() {
+	filesystem.RegisterAdapter("smb", func(_ context.Context, settings map[string]interface{}) (filesystem.Adapter, error) {
+		return NewFromConfig(settings)
+	})
+}
+
+// NewFromConfig will create an adapter from provided settings.
+func NewFromConfig(settings map[string]interface{}) (*Adapter, error) {
+	str := func(key string) string {
+		s, _ := settings[key].(string)
+		return s
+	}
+	opts := Options{
+		Address:  str(ConfigAddress),
+		Share:    str(ConfigShare),
+		User:     str(ConfigUser),
+		Password: str(ConfigPassword),
+		Domain:   str(ConfigDomain),
+	}
+	if v := str(ConfigVisibility); v != "" {
+		var err error
+		if opts.Visibility, err = filesystem.ParseVisibility(v); err != nil {
+			return nil, fmt.Errorf("smb: %w", err)
+		}
+	}
+	switch dfs := settings[ConfigDFS].(type) {
+	case map[string]string:
+		opts.DFS = dfs
+	case map[string]interface{}:
+		opts.DFS = make(map[string]string, len(dfs))
+		for k, v := range dfs {
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("smb: invalid DFS target of %s", k)
+			}
+			opts.DFS[k] = s
+		}
+	}
+	return New(opts)
+}
+
+func parseLink(p, target string) (link, error) {
+	lp, err := filesystem.NewPath(p)
+	if err != nil {
+		return link{}, err
+	}
+	parts := strings.SplitN(strings.TrimLeft(strings.ReplaceAll(target, "/", `\`), `\`), `\`, 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return link{}, fmt.Errorf("smb: invalid DFS target %q of %s", target, p)
+	}
+	l := link{path: lp, address: parts[0], share: parts[1]}
+	if len(parts) == 3 {
+		l.dir = strings.Trim(parts[2], `\`)
+	}
+	return l, nil
+}
+
+// locate will return the address and share holding the provided path, with the name of the file in that share.
+func (a *Adapter) locate(p filesystem.Path) (string, string, string) {
+	for _, l := range a.links {
+		if p != l.path && !strings.HasPrefix(string(p), string(l.path)+"/") {
+			continue
+		}
+		rest := strings.ReplaceAll(strings.TrimPrefix(string(p), string(l.path)), "/", `\`)
+		return l.address, l.share, strings.TrimPrefix(l.dir+rest, `\`)
+	}
+	return a.opts.Address, a.opts.Share, strings.ReplaceAll(string(p), "/", `\`)
+}
+
+// resolve will return the share holding the provided path, bound to ctx, with the name of the file in that share.
+func (a *Adapter) resolve(ctx context.Context, p filesystem.Path) (*smb2.Share, string, error) {
+	address, share, name := a.locate(p)
+	s, err := a.share(ctx, address, share)
+	if err != nil {
+		return nil, "", err
+	}
+	return s.WithContext(ctx), name, nil
+}
+
+// share will return the mounted share of the server at address, connecting when needed.
+func (a *Adapter) share(ctx context.Context, address, name string) (*smb2.Share, error) {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, defaultPort)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	s, ok := a.sessions[address]
+	if !ok {
+		var err error
+		if s, err = a.dial(ctx, address); err != nil {
+			return nil, err
+		}
+		a.sessions[address] = s
+	}
+	if share, ok := s.shares[name]; ok {
+		return share, nil
+	}
+	share, err := s.session.WithContext(ctx).Mount(name)
+	if err != nil {
+		return nil, err
+	}
+	s.shares[name] = share
+	return share, nil
+}
+
+func (a *Adapter) dial(ctx context.Context, address string) (*session, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	dialer := &smb2.Dialer{Initiator: &smb2.NTLMInitiator{User: a.opts.User, Password: a.opts.Password, Domain: a.opts.Domain}}
+	s, err := dialer.DialContext(ctx, conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &session{conn: conn, session: s, shares: make(map[string]*smb2.Share)}, nil
+}
+
+// Close will unmount the shares and log off every session.
+func (a *Adapter) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var errs []error
+	for address, s := range a.sessions {
+		for _, share := range s.shares {
+			errs = append(errs, share.Umount())
+		}
+		errs = append(errs, s.session.Logoff(), s.conn.Close())
+		delete(a.sessions, address)
+	}
+	return errors.Join(errs...)
+}
+
+func mapError(p filesystem.Path, err error) error {
+	if errors.Is(err, os.ErrNotExist) {
+		return filesystem.NewFileNotFoundError(p)
+	}
+	return err
+}
+
+// stat will retrieve the information of the file at path, failing with a FileNotFoundError for directories.
+func (a *Adapter) stat(ctx context.Context, p filesystem.Path) (os.FileInfo, error) {
+	share, name, err := a.resolve(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := share.Stat(name)
+	if err != nil {
+		return nil, mapError(p, err)
+	}
+	if fi.IsDir() {
+		return nil, filesystem.NewFileNotFoundError(p)
+	}
+	return fi, nil
+}
+
+func attributes(fi os.FileInfo) uint32 {
+	if st, ok := fi.Sys().(*smb2.FileStat); ok {
+		return st.FileAttributes
+	}
+	return 0
+}
+
+func (a *Adapter) metadata(p filesystem.Path, fi os.FileInfo) filesystem.Metadata {
+	attrs := attributes(fi)
+	md := &filesystem.FileInfo{
+		Path:       p,
+		Type:       filesystem.TypeFile,
+		Timestamp:  fi.ModTime(),
+		Visibility: a.opts.Visibility,
+		Extra: map[string]interface{}{
+			"hidden":   attrs&attributeHidden != 0,
+			"system":   attrs&attributeSystem != 0,
+			"readonly": attrs&attributeReadOnly != 0,
+			"archive":  attrs&attributeArchive != 0,
+		},
+	}
+	if attrs&attributeDirectory != 0 {
+		md.Type = filesystem.TypeDir
+	} else {
+		md.Size, md.MimeType = fi.Size(), mimeType(p)
+	}
+	if st, ok := fi.Sys().(*smb2.FileStat); ok {
+		md.Set("created", st.CreationTime)
+	}
+	return md
+}
+
+func mimeType(p filesystem.Path) string {
+	if mt := mime.TypeByExtension(p.Ext()); mt != "" {
+		return mt
+	}
+	return "application/octet-stream"
+}
+
+// put will write the content of r at path, creating the parent directories.
+func (a *Adapter) put(ctx context.Context, p filesystem.Path, r io.Reader) error {
+	share, name, err := a.resolve(ctx, p)
+	if err != nil {
+		return err
+	}
+	if i := strings.LastIndexByte(name, '\\'); i > 0 {
+		if err := share.MkdirAll(name[:i], 0755); err != nil {
+			return err
+		}
+	}
+	f, err := share.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Has will check if a file exists.
+func (a *Adapter) Has(ctx context.Context, path filesystem.Path) (bool, error) {
+	_, err := a.stat(ctx, path)
+	if filesystem.IsFileNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Read the file at provided path.
+func (a *Adapter) Read(ctx context.Context, path filesystem.Path) ([]byte, error) {
+	rc, err := a.ReadStream(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// ReadStream will read the file at provided path as a stream.
+func (a *Adapter) ReadStream(ctx context.Context, path filesystem.Path) (io.ReadCloser, error) {
+	if _, err := a.stat(ctx, path); err != nil {
+		return nil, err
+	}
+	share, name, err := a.resolve(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := share.Open(name)
+	if err != nil {
+		return nil, mapError(path, err)
+	}
+	return f, nil
+}
+
+// ReadRange will read length bytes of the file at provided path starting at offset, seeking the remote file.
+func (a *Adapter) ReadRange(ctx context.Context, path filesystem.Path, offset, length int64) (io.ReadCloser, error) {
+	rc, err := a.ReadStream(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return filesystem.SliceStream(rc, offset, length)
+}
+
+// Write the supplied content at supplied path, creating the file.
+func (a *Adapter) Write(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	return a.put(ctx, path, bytes.NewReader(content))
+}
+
+// WriteStream will write the content of provided reader at supplied path, creating the file.
+func (a *Adapter) WriteStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	return a.put(ctx, path, r)
+}
+
+// Update the supplied content at supplied path, returning an error if file does not exists.
+func (a *Adapter) Update(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	if _, err := a.stat(ctx, path); err != nil {
+		return err
+	}
+	return a.put(ctx, path, bytes.NewReader(content))
+}
+
+// Update with the content of supplied reader at supplied path, returning an error if file does not exists
+func (a *Adapter) UpdateStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	if _, err := a.stat(ctx, path); err != nil {
+		return err
+	}
+	return a.put(ctx, path, r)
+}
+
+// Put the supplied content at supplied path, creating the file if does not exists.
+func (a *Adapter) Put(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	return a.put(ctx, path, bytes.NewReader(content))
+}
+
+// Puth the content of supplied reader at supplied path, creating the file if does not exists.
+func (a *Adapter) PutStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	return a.put(ctx, path, r)
+}
+
+// Deletes a file at provided path.
+func (a *Adapter) Delete(ctx context.Context, path filesystem.Path) error {
+	if _, err := a.stat(ctx, path); err != nil {
+		return err
+	}
+	share, name, err := a.resolve(ctx, path)
+	if err != nil {
+		return err
+	}
+	return mapError(path, share.Remove(name))
+}
+
+// ReadAndDelete will read the file at provided path and delete after read.
+func (a *Adapter) ReadAndDelete(ctx context.Context, path filesystem.Path) ([]byte, error) {
+	content, err := a.Read(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return content, a.Delete(ctx, path)
+}
+
+// Move the file at supplied path to new path, renaming it when both are on the same share and copying it
+// otherwise.
+func (a *Adapter) Move(ctx context.Context, path, newpath filesystem.Path) error {
+	if _, err := a.stat(ctx, path); err != nil {
+		return err
+	}
+	address, shareName, _ := a.locate(path)
+	newaddress, newShareName, _ := a.locate(newpath)
+	if address != newaddress || !strings.EqualFold(shareName, newShareName) {
+		if err := a.Copy(ctx, path, newpath); err != nil {
+			return err
+		}
+		return a.Delete(ctx, path)
+	}
+	share, name, err := a.resolve(ctx, path)
+	if err != nil {
+		return err
+	}
+	_, newname, err := a.resolve(ctx, newpath)
+	if err != nil {
+		return err
+	}
+	if i := strings.LastIndexByte(newname, '\\'); i > 0 {
+		if err := share.MkdirAll(newname[:i], 0755); err != nil {
+			return err
+		}
+	}
+	// Rename refuses to replace an existing file.
+	if err := share.Remove(newname); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return mapError(path, share.Rename(name, newname))
+}
+
+// Copy the file at supplied path to new path.
+func (a *Adapter) Copy(ctx context.Context, path, newpath filesystem.Path) error {
+	rc, err := a.ReadStream(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return a.put(ctx, newpath, rc)
+}
+
+// GetMimeType will retrieve the mime type of file at supplied path, guessed from its extension.
+func (a *Adapter) GetMimeType(ctx context.Context, path filesystem.Path) (string, error) {
+	if _, err := a.stat(ctx, path); err != nil {
+		return "", err
+	}
+	return mimeType(path), nil
+}
+
+// GetTimestamp will retrieve the timestamp of file at supplied path.
+func (a *Adapter) GetTimestamp(ctx context.Context, path filesystem.Path) (time.Time, error) {
+	fi, err := a.stat(ctx, path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return fi.ModTime(), nil
+}
+
+// GetFileSize will retrieve the size of file at supplied path.
+func (a *Adapter) GetFileSize(ctx context.Context, path filesystem.Path) (int64, error) {
+	fi, err := a.stat(ctx, path)
+	if err != nil {
+		return 0, err
+	}
+	return fi.Size(), nil
+}
+
+// GetMetadata will retrieve the metadata of file at supplied path, including its NTFS attributes and "created"
+// time.
+func (a *Adapter) GetMetadata(ctx context.Context, path filesystem.Path) (filesystem.Metadata, error) {
+	fi, err := a.stat(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return a.metadata(path, fi), nil
+}
+
+// CreateDir will create a new directory at provided path, with its parents.
+func (a *Adapter) CreateDir(ctx context.Context, path filesystem.Path, cfg filesystem.Config) error {
+	share, name, err := a.resolve(ctx, path)
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		return nil
+	}
+	return share.MkdirAll(name, 0755)
+}
+
+// DeleteDir will delete the directory at provided path, with its contents.
+func (a *Adapter) DeleteDir(ctx context.Context, path filesystem.Path) error {
+	share, name, err := a.resolve(ctx, path)
+	if err != nil {
+		return err
+	}
+	fi, err := share.Stat(name)
+	if err != nil {
+		return mapError(path, err)
+	}
+	if !fi.IsDir() {
+		return filesystem.NewFileNotFoundError(path)
+	}
+	return share.RemoveAll(name)
+}
+
+// Get the visibility of file at supplied path, which is the visibility configured for the share.
+func (a *Adapter) GetVisibility(ctx context.Context, path filesystem.Path) (filesystem.Visibility, error) {
+	if _, err := a.stat(ctx, path); err != nil {
+		return "", err
+	}
+	return a.opts.Visibility, nil
+}
+
+// Set the visibility of file at supplied path, failing unless it is the visibility of the share.
+func (a *Adapter) SetVisibility(ctx context.Context, path filesystem.Path, v filesystem.Visibility) error {
+	current, err := a.GetVisibility(ctx, path)
+	if err != nil || current == v {
+		return err
+	}
+	return fmt.Errorf("smb: unable to make %s %s, visibility is set by the share ACL", path, v)
+}
+
+// List the contents of given path.
+func (a *Adapter) ListContents(ctx context.Context, path filesystem.Path, recursive bool) ([]filesystem.Metadata, error) {
+	share, name, err := a.resolve(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	infos, err := share.ReadDir(name)
+	if err != nil {
+		return nil, mapError(path, err)
+	}
+	var result []filesystem.Metadata
+	for _, fi := range infos {
+		p := path.Join(fi.Name())
+		result = append(result, a.metadata(p, fi))
+		if recursive && fi.IsDir() {
+			children, err := a.ListContents(ctx, p, true)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, children...)
+		}
+	}
+	return result, nil
+}
+
+// Capabilities will report the capabilities of the adapter. Moves are renames within a share only.
+func (a *Adapter) Capabilities() filesystem.Capabilities {
+	return filesystem.Capabilities{NativeMove: true, DirectoryMetadata: true, RangeReads: true}
+}
@@ -0,0 +1,266 @@
+package filesystem
+
+import (
+	"bufio"
+	"container/heap"
+	"context"
+	"encoding/gob"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SortField is the field listings are sorted by.
+type SortField int
+
+// SortField values. Entries with equal fields are sorted by path.
+const (
+	// Unsorted leaves entries in the order adapter lists them.
+	Unsorted SortField = iota
+	SortByName
+	SortBySize
+	SortByTimestamp
+)
+
+// SortDirection is the direction of a sort.
+type SortDirection int
+
+// SortDirection values (ascending and descending).
+const (
+	Ascending SortDirection = iota
+	Descending
+)
+
+// Sort is the order of the entries of a listing.
+type Sort struct {
+	Field     SortField
+	Direction SortDirection
+}
+
+// SortBy will return the sort by provided field, in provided direction.
+func SortBy(field SortField, direction SortDirection) Sort {
+	return Sort{Field: field, Direction: direction}
+}
+
+// Less will check if entry a comes before entry b.
+func (s Sort) Less(a, b Metadata) bool {
+	var c int
+	switch s.Field {
+	case SortBySize:
+		c = compareInt64(a.Size, b.Size)
+	case SortByTimestamp:
+		c = compareInt64(a.Timestamp.UnixNano(), b.Timestamp.UnixNano())
+	}
+	if c == 0 {
+		c = strings.Compare(string(a.Path), string(b.Path))
+	}
+	if s.Direction == Descending {
+		return c > 0
+	}
+	return c < 0
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// SortedIterator is implemented by adapters able to list contents in a given order natively. Adapters may return
+// an UnsupportedOperationError for the sorts they cannot apply, which are then applied by merging sorted runs.
+type SortedIterator interface {
+	IterateSorted(ctx context.Context, path Path, recursive bool, s Sort) (Iterator, error)
+}
+
+// DefaultSortBuffer is the number of entries held in memory while sorting when ListOptions do not set a buffer.
+const DefaultSortBuffer = 10000
+
+func init() {
+	// Extra metadata is spooled to disk when sorting, times being the most common values besides basic types.
+	gob.Register(time.Time{})
+}
+
+// iterateSorted will iterate over the contents of given path in provided order, natively when adapter supports it
+// and otherwise sorting the entries with at most buffer of them in memory. The returned iterator must be closed
+// with closeIterator.
+func (fs *filesystem) iterateSorted(ctx context.Context, path Path, recursive bool, s Sort, buffer int) (Iterator, error) {
+	if s.Field == Unsorted {
+		return fs.IterateContents(ctx, path, recursive)
+	}
+	if si, ok := fs.adapter.(SortedIterator); ok {
+		it, err := si.IterateSorted(ctx, path, recursive, s)
+		if !IsUnsupportedOperationError(err) {
+			return it, err
+		}
+	}
+	it, err := fs.IterateContents(ctx, path, recursive)
+	if err != nil {
+		return nil, err
+	}
+	if buffer <= 0 {
+		buffer = DefaultSortBuffer
+	}
+	return SortIterator(it, s, buffer)
+}
+
+// SortIterator will sort the entries of provided iterator holding at most buffer of them in memory. When there are
+// more, sorted runs of buffer entries are spooled to a temporary file and merged, the returned iterator implementing
+// io.Closer to remove the file; Extra metadata values must be gob encodable.
+func SortIterator(it Iterator, s Sort, buffer int) (Iterator, error) {
+	run, err := nextRun(it, s, buffer)
+	if err != nil {
+		return nil, err
+	}
+	if len(run) < buffer {
+		return SliceIterator(run), nil
+	}
+	f, err := os.CreateTemp("", "sort-")
+	if err != nil {
+		return nil, err
+	}
+	m := &mergeIterator{file: f, sort: s}
+	var offset int64
+	for len(run) > 0 {
+		bw := bufio.NewWriter(f)
+		w := &countingWriter{w: bw}
+		enc := gob.NewEncoder(w)
+		for _, md := range run {
+			if err = enc.Encode(md); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			err = bw.Flush()
+		}
+		if err != nil {
+			m.Close()
+			return nil, err
+		}
+		m.runs = append(m.runs, &sortRun{dec: gob.NewDecoder(bufio.NewReader(io.NewSectionReader(f, offset, w.n)))})
+		offset += w.n
+		if run, err = nextRun(it, s, buffer); err != nil {
+			m.Close()
+			return nil, err
+		}
+	}
+	if err := m.init(); err != nil {
+		m.Close()
+		return nil, err
+	}
+	return m, nil
+}
+
+// nextRun will read and sort at most buffer entries of it.
+func nextRun(it Iterator, s Sort, buffer int) ([]Metadata, error) {
+	var run []Metadata
+	for len(run) < buffer {
+		md, err := it.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		run = append(run, md)
+	}
+	sort.Slice(run, func(i, j int) bool { return s.Less(run[i], run[j]) })
+	return run, nil
+}
+
+// closeIterator will release the resources held by it, if any.
+func closeIterator(it Iterator) error {
+	if c, ok := it.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+// sortRun is a sorted run spooled to disk, head being its next entry.
+type sortRun struct {
+	dec  *gob.Decoder
+	head Metadata
+}
+
+func (r *sortRun) advance() error {
+	md := new(FileInfo)
+	if err := r.dec.Decode(md); err != nil {
+		r.head = nil
+		return err
+	}
+	r.head = md
+	return nil
+}
+
+// mergeIterator merges sorted runs, keeping the runs with entries left in a heap ordered by their head.
+type mergeIterator struct {
+	file *os.File
+	sort Sort
+	runs []*sortRun
+}
+
+func (m *mergeIterator) init() error {
+	for _, r := range m.runs {
+		if err := r.advance(); err != nil {
+			return err
+		}
+	}
+	heap.Init(m)
+	return nil
+}
+
+func (m *mergeIterator) Len() int           { return len(m.runs) }
+func (m *mergeIterator) Less(i, j int) bool { return m.sort.Less(m.runs[i].head, m.runs[j].head) }
+func (m *mergeIterator) Swap(i, j int)      { m.runs[i], m.runs[j] = m.runs[j], m.runs[i] }
+func (m *mergeIterator) Push(x interface{}) { m.runs = append(m.runs, x.(*sortRun)) }
+
+func (m *mergeIterator) Pop() interface{} {
+	r := m.runs[len(m.runs)-1]
+	m.runs = m.runs[:len(m.runs)-1]
+	return r
+}
+
+func (m *mergeIterator) Next() (Metadata, error) {
+	if len(m.runs) == 0 {
+		m.Close()
+		return nil, io.EOF
+	}
+	r := m.runs[0]
+	md := r.head
+	switch err := r.advance(); err {
+	case nil:
+		heap.Fix(m, 0)
+	case io.EOF:
+		heap.Pop(m)
+	default:
+		return nil, err
+	}
+	return md, nil
+}
+
+// Close will remove the temporary file of the runs.
+func (m *mergeIterator) Close() error {
+	if m.file == nil {
+		return nil
+	}
+	m.file.Close()
+	err := os.Remove(m.file.Name())
+	m.file = nil
+	return err
+}
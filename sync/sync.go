@@ -0,0 +1,205 @@
+// Package sync synchronizes the files of a destination file system with the ones of a source file system, copying
+// the files that differ and, in mirror mode, deleting the ones missing from the source: rsync over any two adapters.
+package sync
+
+import (
+	"context"
+	"sort"
+
+	"github.com/maurofran/filesystem"
+	"github.com/maurofran/filesystem/internal/glob"
+)
+
+// Mode is the synchronization mode.
+type Mode int
+
+// Mode values.
+const (
+	// OneWay copies new and changed files to the destination, leaving the files missing from the source alone.
+	OneWay Mode = iota
+	// Mirror makes the destination a copy of the source, deleting the files missing from the source too.
+	Mirror
+)
+
+// SyncOptions configures a synchronization.
+type SyncOptions struct {
+	// Mode is the synchronization mode, OneWay by default.
+	Mode Mode
+	// DryRun reports the files that would be copied and deleted, without changing the destination.
+	DryRun bool
+	// Concurrency is the number of files copied or deleted in parallel, 1 if not positive.
+	Concurrency int
+	// Include holds the glob patterns of the files to synchronize, every file if empty. Patterns follow path.Match
+	// syntax segment by segment, "**" matching any number of segments.
+	Include []string
+	// Exclude holds the glob patterns of the files not to synchronize, taking precedence over Include. Excluded
+	// files are never deleted from the destination.
+	Exclude []string
+	// Ignore leaves out the files matched by ignore rules, such as the ones of the .fsignore file of the source
+	// loaded with filesystem.LoadIgnoreRules. Ignored files are never deleted from the destination.
+	Ignore *filesystem.IgnoreRules
+	// Checksum is the algorithm whose checksums tell apart files of the same size, if not zero. Otherwise files of
+	// the same size differ when the source one is newer.
+	Checksum filesystem.ChecksumAlgo
+	// Progress is called as files are copied, with "Sync" operation, if not nil. With concurrency, it is called
+	// from several goroutines.
+	Progress func(filesystem.ProgressEvent)
+}
+
+// Report is the outcome of a synchronization.
+type Report struct {
+	// Copied holds the files copied to the destination, sorted.
+	Copied []filesystem.Path
+	// Deleted holds the files deleted from the destination, sorted.
+	Deleted []filesystem.Path
+	// Unchanged is the number of files already in sync.
+	Unchanged int
+	// Failed holds the files that could not be copied or deleted, with the error raised.
+	Failed map[filesystem.Path]error
+}
+
+type action int
+
+const (
+	actionCopy action = iota
+	actionDelete
+)
+
+type task struct {
+	action action
+	path   filesystem.Path
+	size   int64
+}
+
+type result struct {
+	task
+	err error
+}
+
+// Sync will synchronize the files of dst with the ones of src, planning with filesystem.Diff. The errors raised by
+// single files are collected in the report; the returned error is the one of patterns, listings and checksums,
+// aborting the synchronization.
+func Sync(ctx context.Context, src, dst filesystem.Interface, opts SyncOptions) (Report, error) {
+	report := Report{Failed: make(map[filesystem.Path]error)}
+	for _, pattern := range append(append([]string(nil), opts.Include...), opts.Exclude...) {
+		if err := glob.Validate(pattern); err != nil {
+			return report, err
+		}
+	}
+	changes, err := filesystem.Diff(ctx, dst, src, filesystem.RootPath, filesystem.DiffOptions{
+		Checksum:  opts.Checksum,
+		Filter:    func(path filesystem.Path) bool { return selected(path, opts) },
+		Unchanged: true,
+	})
+	if err != nil {
+		return report, err
+	}
+	var tasks []task
+	for _, c := range changes {
+		switch c.Kind {
+		case filesystem.ChangeAdded, filesystem.ChangeModified:
+			tasks = append(tasks, task{actionCopy, c.Path, c.New.Size})
+		case filesystem.ChangeRemoved:
+			if opts.Mode == Mirror {
+				tasks = append(tasks, task{actionDelete, c.Path, -1})
+			}
+		default:
+			report.Unchanged++
+		}
+	}
+	for _, r := range run(ctx, src, dst, tasks, opts) {
+		switch {
+		case r.err != nil:
+			report.Failed[r.path] = r.err
+		case r.action == actionCopy:
+			report.Copied = append(report.Copied, r.path)
+		default:
+			report.Deleted = append(report.Deleted, r.path)
+		}
+	}
+	sort.Slice(report.Copied, func(i, j int) bool { return report.Copied[i] < report.Copied[j] })
+	sort.Slice(report.Deleted, func(i, j int) bool { return report.Deleted[i] < report.Deleted[j] })
+	return report, nil
+}
+
+// SyncMounts will synchronize the files of the file system mounted by mm with prefix dst with the ones of the file
+// system mounted with prefix src, as Sync does. Since files already in sync are skipped, running it again after an
+// interruption resumes the synchronization.
+func SyncMounts(ctx context.Context, mm filesystem.MountManager, src, dst string, opts SyncOptions) (Report, error) {
+	from, err := mm.Mounted(src)
+	if err != nil {
+		return Report{Failed: make(map[filesystem.Path]error)}, err
+	}
+	to, err := mm.Mounted(dst)
+	if err != nil {
+		return Report{Failed: make(map[filesystem.Path]error)}, err
+	}
+	return Sync(ctx, from, to, opts)
+}
+
+func selected(path filesystem.Path, opts SyncOptions) bool {
+	if opts.Ignore.Ignored(path, false) {
+		return false
+	}
+	for _, pattern := range opts.Exclude {
+		if glob.Match(pattern, string(path)) {
+			return false
+		}
+	}
+	if len(opts.Include) == 0 {
+		return true
+	}
+	for _, pattern := range opts.Include {
+		if glob.Match(pattern, string(path)) {
+			return true
+		}
+	}
+	return false
+}
+
+// run will perform tasks with opts.Concurrency workers, returning their results.
+func run(ctx context.Context, src, dst filesystem.Interface, tasks []task, opts SyncOptions) []result {
+	workers := opts.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+	queue := make(chan task)
+	results := make(chan result)
+	for i := 0; i < workers; i++ {
+		go func() {
+			for t := range queue {
+				results <- result{t, perform(ctx, src, dst, t, opts)}
+			}
+		}()
+	}
+	go func() {
+		for _, t := range tasks {
+			queue <- t
+		}
+		close(queue)
+	}()
+	out := make([]result, 0, len(tasks))
+	for range tasks {
+		out = append(out, <-results)
+	}
+	return out
+}
+
+func perform(ctx context.Context, src, dst filesystem.Interface, t task, opts SyncOptions) error {
+	if opts.DryRun {
+		return nil
+	}
+	if t.action == actionDelete {
+		_, err := dst.Delete(ctx, t.path)
+		return err
+	}
+	r, err := src.ReadStream(ctx, t.path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if opts.Progress == nil {
+		return dst.PutStream(ctx, t.path, r)
+	}
+	return dst.PutStream(ctx, t.path, filesystem.NewProgressReader(r, "Sync", t.path, t.size, opts.Progress))
+}
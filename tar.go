@@ -0,0 +1,127 @@
+package filesystem
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"io"
+	"strings"
+)
+
+// TarOptions are the options of tar exports and imports.
+type TarOptions struct {
+	// Gzip enables gzip compression of the tar stream.
+	Gzip bool
+}
+
+// ExportTar will stream the directory at path of file system to w as a tar archive, with entry names relative to
+// path. Visibility is mapped to permissions, public files being world readable.
+func ExportTar(ctx context.Context, fs Interface, path Path, w io.Writer, opts TarOptions) error {
+	if opts.Gzip {
+		gz := gzip.NewWriter(w)
+		if err := ExportTar(ctx, fs, path, gz, TarOptions{}); err != nil {
+			return err
+		}
+		return gz.Close()
+	}
+	it, err := fs.IterateContents(ctx, path, true)
+	if err != nil {
+		return err
+	}
+	defer closeIterator(it)
+	tw := tar.NewWriter(w)
+	for {
+		md, err := it.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := exportTarEntry(ctx, fs, path, md, tw); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+func exportTarEntry(ctx context.Context, fs Interface, base Path, md Metadata, tw *tar.Writer) error {
+	name := strings.TrimPrefix(string(md.Path), string(base)+"/")
+	if base == RootPath {
+		name = string(md.Path)
+	}
+	hdr := &tar.Header{Name: name, ModTime: md.Timestamp, Mode: 0644}
+	if md.Visibility != "" && md.Visibility != VisibilityPublic {
+		hdr.Mode = 0600
+	}
+	if md.IsDir() {
+		hdr.Typeflag = tar.TypeDir
+		hdr.Name += "/"
+		hdr.Mode |= 0111
+		return tw.WriteHeader(hdr)
+	}
+	hdr.Typeflag = tar.TypeReg
+	hdr.Size = md.Size
+	if md.Size < 0 {
+		size, err := fs.GetFileSize(ctx, md.Path)
+		if err != nil {
+			return err
+		}
+		hdr.Size = size
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	rc, err := fs.ReadStream(ctx, md.Path)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	_, err = io.Copy(tw, rc)
+	return err
+}
+
+// ImportTar will extract the tar archive read from r under the directory at path of file system, overwriting
+// existing files. Entry names escaping path are refused with a PathError; entries other than regular files and
+// directories, such as links, are skipped.
+func ImportTar(ctx context.Context, fs Interface, path Path, r io.Reader, opts TarOptions) error {
+	if opts.Gzip {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name, err := NewPath(hdr.Name)
+		if err != nil {
+			return err
+		}
+		if name == RootPath {
+			continue
+		}
+		target := path.Join(string(name))
+		visibility := WithVisibility(VisibilityPrivate)
+		if hdr.FileInfo().Mode().Perm()&0004 != 0 {
+			visibility = WithVisibility(VisibilityPublic)
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = fs.CreateDir(ctx, target, visibility)
+		case tar.TypeReg:
+			err = fs.PutStream(ctx, target, tr, visibility)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package throttle
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// bucket is a token bucket refilled at rate tokens per second, holding up to a second worth of tokens. Tokens are
+// taken even when missing, the bucket going in debt, so that takers wait in turn for the debt to be refilled.
+type bucket struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+// newBucket will create a full bucket refilled at provided rate, or return nil, which never waits, if the rate is
+// not positive.
+func newBucket(rate float64) *bucket {
+	if rate <= 0 {
+		return nil
+	}
+	return &bucket{rate: rate, tokens: rate, last: time.Now()}
+}
+
+// wait will take n tokens, waiting until they are refilled or the context is done.
+func (b *bucket) wait(ctx context.Context, n float64) error {
+	if b == nil || n <= 0 {
+		return nil
+	}
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens = min(b.rate, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens -= n
+	debt := -b.tokens
+	b.mu.Unlock()
+	if debt <= 0 {
+		return nil
+	}
+	t := time.NewTimer(time.Duration(debt / b.rate * float64(time.Second)))
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,473 @@
+// Package throttle provides an adapter decorator limiting the rate of operations and the bandwidth of transfers with
+// token buckets, separately for reads and writes, so that bulk jobs do not saturate the links of the backend.
+package throttle
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/maurofran/filesystem"
+)
+
+// Limits are the rates operations are limited to. Zero values mean no limit.
+type Limits struct {
+	// ReadBytesPerSecond limits the bandwidth of the content read.
+	ReadBytesPerSecond int64
+	// WriteBytesPerSecond limits the bandwidth of the content written.
+	WriteBytesPerSecond int64
+	// ReadOpsPerSecond limits the rate of the operations reading files, metadata and listings.
+	ReadOpsPerSecond float64
+	// WriteOpsPerSecond limits the rate of the operations modifying the file system.
+	WriteOpsPerSecond float64
+}
+
+// Wrap will decorate provided adapter so that operations wait for their turn according to provided limits. Bursts
+// of up to a second worth of operations or bytes are allowed. Streams are throttled as they are consumed, and
+// operations waiting fail with the error of the context when it is done.
+func Wrap(a filesystem.Adapter, l Limits) filesystem.Adapter {
+	return &adapter{
+		Adapter:    a,
+		readBytes:  newBucket(float64(l.ReadBytesPerSecond)),
+		writeBytes: newBucket(float64(l.WriteBytesPerSecond)),
+		readOps:    newBucket(l.ReadOpsPerSecond),
+		writeOps:   newBucket(l.WriteOpsPerSecond),
+	}
+}
+
+type adapter struct {
+	filesystem.Adapter
+	readBytes  *bucket
+	writeBytes *bucket
+	readOps    *bucket
+	writeOps   *bucket
+}
+
+// reader throttles the bytes read through it.
+type reader struct {
+	ctx context.Context
+	r   io.Reader
+	b   *bucket
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	if r.b != nil && len(p) > int(r.b.rate) {
+		// Reading no more than a second worth of bytes at once keeps the transfer smooth.
+		p = p[:max(1, int(r.b.rate))]
+	}
+	n, err := r.r.Read(p)
+	if werr := r.b.wait(r.ctx, float64(n)); werr != nil && err == nil {
+		err = werr
+	}
+	return n, err
+}
+
+type readCloser struct {
+	*reader
+	io.Closer
+}
+
+func throttleReader(ctx context.Context, r io.Reader, b *bucket) io.Reader {
+	if b == nil {
+		return r
+	}
+	return &reader{ctx, r, b}
+}
+
+func throttleReadCloser(ctx context.Context, rc io.ReadCloser, b *bucket) io.ReadCloser {
+	if b == nil {
+		return rc
+	}
+	return readCloser{&reader{ctx, rc, b}, rc}
+}
+
+// readStream will throttle opening a stream with open and reading it.
+func (a *adapter) readStream(ctx context.Context, open func() (io.ReadCloser, error)) (io.ReadCloser, error) {
+	if err := a.readOps.wait(ctx, 1); err != nil {
+		return nil, err
+	}
+	rc, err := open()
+	if err != nil {
+		return nil, err
+	}
+	return throttleReadCloser(ctx, rc, a.readBytes), nil
+}
+
+// writeContent will throttle writing provided content with fn.
+func (a *adapter) writeContent(ctx context.Context, content []byte, fn func() error) error {
+	if err := a.writeOps.wait(ctx, 1); err != nil {
+		return err
+	}
+	if err := a.writeBytes.wait(ctx, float64(len(content))); err != nil {
+		return err
+	}
+	return fn()
+}
+
+func (a *adapter) Has(ctx context.Context, path filesystem.Path) (bool, error) {
+	if err := a.readOps.wait(ctx, 1); err != nil {
+		return false, err
+	}
+	return a.Adapter.Has(ctx, path)
+}
+
+func (a *adapter) Read(ctx context.Context, path filesystem.Path) ([]byte, error) {
+	if err := a.readOps.wait(ctx, 1); err != nil {
+		return nil, err
+	}
+	content, err := a.Adapter.Read(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.readBytes.wait(ctx, float64(len(content))); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+func (a *adapter) ReadStream(ctx context.Context, path filesystem.Path) (io.ReadCloser, error) {
+	return a.readStream(ctx, func() (io.ReadCloser, error) {
+		return a.Adapter.ReadStream(ctx, path)
+	})
+}
+
+func (a *adapter) Write(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	return a.writeContent(ctx, content, func() error {
+		return a.Adapter.Write(ctx, path, content, cfg)
+	})
+}
+
+func (a *adapter) WriteStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	if err := a.writeOps.wait(ctx, 1); err != nil {
+		return err
+	}
+	return a.Adapter.WriteStream(ctx, path, throttleReader(ctx, r, a.writeBytes), cfg)
+}
+
+func (a *adapter) Update(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	return a.writeContent(ctx, content, func() error {
+		return a.Adapter.Update(ctx, path, content, cfg)
+	})
+}
+
+func (a *adapter) UpdateStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	if err := a.writeOps.wait(ctx, 1); err != nil {
+		return err
+	}
+	return a.Adapter.UpdateStream(ctx, path, throttleReader(ctx, r, a.writeBytes), cfg)
+}
+
+func (a *adapter) Put(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	return a.writeContent(ctx, content, func() error {
+		return a.Adapter.Put(ctx, path, content, cfg)
+	})
+}
+
+func (a *adapter) PutStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	if err := a.writeOps.wait(ctx, 1); err != nil {
+		return err
+	}
+	return a.Adapter.PutStream(ctx, path, throttleReader(ctx, r, a.writeBytes), cfg)
+}
+
+func (a *adapter) Delete(ctx context.Context, path filesystem.Path) error {
+	if err := a.writeOps.wait(ctx, 1); err != nil {
+		return err
+	}
+	return a.Adapter.Delete(ctx, path)
+}
+
+// ReadAndDelete counts as a write operation, its content being throttled as read.
+func (a *adapter) ReadAndDelete(ctx context.Context, path filesystem.Path) ([]byte, error) {
+	if err := a.writeOps.wait(ctx, 1); err != nil {
+		return nil, err
+	}
+	content, err := a.Adapter.ReadAndDelete(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.readBytes.wait(ctx, float64(len(content))); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+func (a *adapter) Move(ctx context.Context, path, newpath filesystem.Path) error {
+	if err := a.writeOps.wait(ctx, 1); err != nil {
+		return err
+	}
+	return a.Adapter.Move(ctx, path, newpath)
+}
+
+// Copy counts as a write operation. Its content is not throttled, as the adapter may copy it natively.
+func (a *adapter) Copy(ctx context.Context, path, newpath filesystem.Path) error {
+	if err := a.writeOps.wait(ctx, 1); err != nil {
+		return err
+	}
+	return a.Adapter.Copy(ctx, path, newpath)
+}
+
+func (a *adapter) GetMimeType(ctx context.Context, path filesystem.Path) (string, error) {
+	if err := a.readOps.wait(ctx, 1); err != nil {
+		return "", err
+	}
+	return a.Adapter.GetMimeType(ctx, path)
+}
+
+func (a *adapter) GetTimestamp(ctx context.Context, path filesystem.Path) (time.Time, error) {
+	if err := a.readOps.wait(ctx, 1); err != nil {
+		return time.Time{}, err
+	}
+	return a.Adapter.GetTimestamp(ctx, path)
+}
+
+func (a *adapter) GetFileSize(ctx context.Context, path filesystem.Path) (int64, error) {
+	if err := a.readOps.wait(ctx, 1); err != nil {
+		return 0, err
+	}
+	return a.Adapter.GetFileSize(ctx, path)
+}
+
+func (a *adapter) GetMetadata(ctx context.Context, path filesystem.Path) (filesystem.Metadata, error) {
+	if err := a.readOps.wait(ctx, 1); err != nil {
+		return nil, err
+	}
+	return a.Adapter.GetMetadata(ctx, path)
+}
+
+func (a *adapter) CreateDir(ctx context.Context, path filesystem.Path, cfg filesystem.Config) error {
+	if err := a.writeOps.wait(ctx, 1); err != nil {
+		return err
+	}
+	return a.Adapter.CreateDir(ctx, path, cfg)
+}
+
+func (a *adapter) DeleteDir(ctx context.Context, path filesystem.Path) error {
+	if err := a.writeOps.wait(ctx, 1); err != nil {
+		return err
+	}
+	return a.Adapter.DeleteDir(ctx, path)
+}
+
+func (a *adapter) GetVisibility(ctx context.Context, path filesystem.Path) (filesystem.Visibility, error) {
+	if err := a.readOps.wait(ctx, 1); err != nil {
+		return "", err
+	}
+	return a.Adapter.GetVisibility(ctx, path)
+}
+
+func (a *adapter) SetVisibility(ctx context.Context, path filesystem.Path, v filesystem.Visibility) error {
+	if err := a.writeOps.wait(ctx, 1); err != nil {
+		return err
+	}
+	return a.Adapter.SetVisibility(ctx, path, v)
+}
+
+func (a *adapter) ListContents(ctx context.Context, path filesystem.Path, recursive bool) ([]filesystem.Metadata, error) {
+	if err := a.readOps.wait(ctx, 1); err != nil {
+		return nil, err
+	}
+	return a.Adapter.ListContents(ctx, path, recursive)
+}
+
+// ReadRange will read the range natively when the decorated adapter supports it, throttling the bytes read.
+func (a *adapter) ReadRange(ctx context.Context, path filesystem.Path, offset, length int64) (io.ReadCloser, error) {
+	r, ok := a.Adapter.(filesystem.RangeReader)
+	if !ok {
+		rc, err := a.ReadStream(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		return filesystem.SliceStream(rc, offset, length)
+	}
+	return a.readStream(ctx, func() (io.ReadCloser, error) {
+		return r.ReadRange(ctx, path, offset, length)
+	})
+}
+
+// GetChecksum will return the checksum natively provided by the decorated adapter, if any.
+func (a *adapter) GetChecksum(ctx context.Context, path filesystem.Path, algo filesystem.ChecksumAlgo) (string, error) {
+	p, ok := a.Adapter.(filesystem.ChecksumProvider)
+	if !ok {
+		return "", filesystem.NewUnsupportedChecksumError(path, algo)
+	}
+	if err := a.readOps.wait(ctx, 1); err != nil {
+		return "", err
+	}
+	return p.GetChecksum(ctx, path, algo)
+}
+
+// FileExists will check if a file exists natively when the decorated adapter supports it.
+func (a *adapter) FileExists(ctx context.Context, path filesystem.Path) (bool, error) {
+	c, ok := a.Adapter.(filesystem.ExistenceChecker)
+	if !ok {
+		return false, filesystem.NewUnsupportedOperationError(path, "FileExists")
+	}
+	if err := a.readOps.wait(ctx, 1); err != nil {
+		return false, err
+	}
+	return c.FileExists(ctx, path)
+}
+
+// DirectoryExists will check if a directory exists natively when the decorated adapter supports it.
+func (a *adapter) DirectoryExists(ctx context.Context, path filesystem.Path) (bool, error) {
+	c, ok := a.Adapter.(filesystem.ExistenceChecker)
+	if !ok {
+		return false, filesystem.NewUnsupportedOperationError(path, "DirectoryExists")
+	}
+	if err := a.readOps.wait(ctx, 1); err != nil {
+		return false, err
+	}
+	return c.DirectoryExists(ctx, path)
+}
+
+// GetDirectoryMetadata will describe the directory natively when the decorated adapter supports it.
+func (a *adapter) GetDirectoryMetadata(ctx context.Context, path filesystem.Path) (filesystem.DirectoryInfo, error) {
+	p, ok := a.Adapter.(filesystem.DirectoryMetadataProvider)
+	if !ok {
+		return filesystem.DirectoryInfo{}, filesystem.NewUnsupportedOperationError(path, "GetDirectoryMetadata")
+	}
+	if err := a.readOps.wait(ctx, 1); err != nil {
+		return filesystem.DirectoryInfo{}, err
+	}
+	return p.GetDirectoryMetadata(ctx, path)
+}
+
+// ListPage will list a page natively when the decorated adapter supports it.
+func (a *adapter) ListPage(ctx context.Context, path filesystem.Path, recursive bool, cursor string, limit int) (filesystem.Page, error) {
+	l, ok := a.Adapter.(filesystem.PageLister)
+	if !ok {
+		return filesystem.Page{}, filesystem.NewUnsupportedOperationError(path, "ListPage")
+	}
+	if err := a.readOps.wait(ctx, 1); err != nil {
+		return filesystem.Page{}, err
+	}
+	return l.ListPage(ctx, path, recursive, cursor, limit)
+}
+
+// IterateSorted will iterate in order natively when the decorated adapter supports it.
+func (a *adapter) IterateSorted(ctx context.Context, path filesystem.Path, recursive bool, s filesystem.Sort) (filesystem.Iterator, error) {
+	si, ok := a.Adapter.(filesystem.SortedIterator)
+	if !ok {
+		return nil, filesystem.NewUnsupportedOperationError(path, "IterateSorted")
+	}
+	if err := a.readOps.wait(ctx, 1); err != nil {
+		return nil, err
+	}
+	return si.IterateSorted(ctx, path, recursive, s)
+}
+
+// GetUserMetadata will return the user metadata from the decorated adapter, if it supports it.
+func (a *adapter) GetUserMetadata(ctx context.Context, path filesystem.Path) (map[string]string, error) {
+	p, ok := a.Adapter.(filesystem.UserMetadataProvider)
+	if !ok {
+		return nil, filesystem.NewUnsupportedOperationError(path, "GetUserMetadata")
+	}
+	if err := a.readOps.wait(ctx, 1); err != nil {
+		return nil, err
+	}
+	return p.GetUserMetadata(ctx, path)
+}
+
+// SetUserMetadata will set the user metadata through the decorated adapter, if it supports it.
+func (a *adapter) SetUserMetadata(ctx context.Context, path filesystem.Path, metadata map[string]string) error {
+	p, ok := a.Adapter.(filesystem.UserMetadataProvider)
+	if !ok {
+		return filesystem.NewUnsupportedOperationError(path, "SetUserMetadata")
+	}
+	if err := a.writeOps.wait(ctx, 1); err != nil {
+		return err
+	}
+	return p.SetUserMetadata(ctx, path, metadata)
+}
+
+// WriteAtomic will write atomically through the decorated adapter, if it has a dedicated mechanism.
+func (a *adapter) WriteAtomic(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	w, ok := a.Adapter.(filesystem.AtomicWriter)
+	if !ok {
+		return filesystem.NewUnsupportedOperationError(path, "WriteAtomic")
+	}
+	if err := a.writeOps.wait(ctx, 1); err != nil {
+		return err
+	}
+	return w.WriteAtomic(ctx, path, throttleReader(ctx, r, a.writeBytes), cfg)
+}
+
+// WriteAt will write part of a file in place through the decorated adapter, if it supports it.
+func (a *adapter) WriteAt(ctx context.Context, path filesystem.Path, p []byte, offset int64) error {
+	w, ok := a.Adapter.(filesystem.RandomWriter)
+	if !ok {
+		return filesystem.NewUnsupportedOperationError(path, "WriteAt")
+	}
+	return a.writeContent(ctx, p, func() error {
+		return w.WriteAt(ctx, path, p, offset)
+	})
+}
+
+// WriteIf will write conditionally through the decorated adapter, if it supports it.
+func (a *adapter) WriteIf(ctx context.Context, path filesystem.Path, content []byte, cond filesystem.Precondition, cfg filesystem.Config) error {
+	w, ok := a.Adapter.(filesystem.ConditionalWriter)
+	if !ok {
+		return filesystem.NewUnsupportedOperationError(path, "WriteIf")
+	}
+	return a.writeContent(ctx, content, func() error {
+		return w.WriteIf(ctx, path, content, cond, cfg)
+	})
+}
+
+// DeleteIf will delete conditionally through the decorated adapter, if it supports it.
+func (a *adapter) DeleteIf(ctx context.Context, path filesystem.Path, cond filesystem.Precondition) error {
+	w, ok := a.Adapter.(filesystem.ConditionalWriter)
+	if !ok {
+		return filesystem.NewUnsupportedOperationError(path, "DeleteIf")
+	}
+	if err := a.writeOps.wait(ctx, 1); err != nil {
+		return err
+	}
+	return w.DeleteIf(ctx, path, cond)
+}
+
+// ListVersions will list the versions through the decorated adapter, if it keeps versions.
+func (a *adapter) ListVersions(ctx context.Context, path filesystem.Path) ([]filesystem.Metadata, error) {
+	v, ok := a.Adapter.(filesystem.Versioned)
+	if !ok {
+		return nil, filesystem.NewUnsupportedOperationError(path, "ListVersions")
+	}
+	if err := a.readOps.wait(ctx, 1); err != nil {
+		return nil, err
+	}
+	return v.ListVersions(ctx, path)
+}
+
+// ReadVersion will read the version through the decorated adapter, if it keeps versions.
+func (a *adapter) ReadVersion(ctx context.Context, path filesystem.Path, versionID string) (io.ReadCloser, error) {
+	v, ok := a.Adapter.(filesystem.Versioned)
+	if !ok {
+		return nil, filesystem.NewUnsupportedOperationError(path, "ReadVersion")
+	}
+	return a.readStream(ctx, func() (io.ReadCloser, error) {
+		return v.ReadVersion(ctx, path, versionID)
+	})
+}
+
+// RestoreVersion will restore the version through the decorated adapter, if it keeps versions.
+func (a *adapter) RestoreVersion(ctx context.Context, path filesystem.Path, versionID string) error {
+	v, ok := a.Adapter.(filesystem.Versioned)
+	if !ok {
+		return filesystem.NewUnsupportedOperationError(path, "RestoreVersion")
+	}
+	if err := a.writeOps.wait(ctx, 1); err != nil {
+		return err
+	}
+	return v.RestoreVersion(ctx, path, versionID)
+}
+
+// Close will close the decorated adapter.
+func (a *adapter) Close() error {
+	return filesystem.Close(a.Adapter)
+}
+
+// Capabilities will report the capabilities of the decorated adapter.
+func (a *adapter) Capabilities() filesystem.Capabilities {
+	return filesystem.CapabilitiesOf(a.Adapter)
+}
@@ -0,0 +1,69 @@
+// Package tracing provides OpenTelemetry instrumentation of file systems, creating a span per operation.
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/maurofran/filesystem"
+)
+
+const instrumentationName = "github.com/maurofran/filesystem/tracing"
+
+// Attribute keys set on spans.
+const (
+	AttributePath    = attribute.Key("filesystem.path")
+	AttributeNewPath = attribute.Key("filesystem.new_path")
+	AttributeBytes   = attribute.Key("filesystem.bytes")
+	AttributeAdapter = attribute.Key("filesystem.adapter")
+)
+
+// Options configures the instrumentation.
+type Options struct {
+	// TracerProvider creates the tracer, the global provider if nil.
+	TracerProvider trace.TracerProvider
+	// Adapter is the name of the adapter, e.g. "s3", set as attribute of every span when not empty.
+	Adapter string
+}
+
+// Middleware will create a middleware starting a span named "filesystem.<Operation>" for each operation, child of
+// the span carried by the context of the call. The context of the span is handed down to the adapter, so that
+// remote calls instrumented on their own are nested under it.
+func Middleware(opts Options) filesystem.Middleware {
+	tp := opts.TracerProvider
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	tracer := tp.Tracer(instrumentationName)
+	return func(next filesystem.Handler) filesystem.Handler {
+		return func(ctx context.Context, op *filesystem.Operation) error {
+			attrs := []attribute.KeyValue{AttributePath.String(string(op.Path))}
+			if op.NewPath != "" {
+				attrs = append(attrs, AttributeNewPath.String(string(op.NewPath)))
+			}
+			if opts.Adapter != "" {
+				attrs = append(attrs, AttributeAdapter.String(opts.Adapter))
+			}
+			ctx, span := tracer.Start(ctx, "filesystem."+op.Name, trace.WithAttributes(attrs...))
+			defer span.End()
+			err := next(ctx, op)
+			if op.Bytes >= 0 {
+				span.SetAttributes(AttributeBytes.Int64(op.Bytes))
+			}
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			return err
+		}
+	}
+}
+
+// Wrap will instrument provided file system with tracing middleware.
+func Wrap(fs filesystem.Interface, opts Options) filesystem.Interface {
+	return filesystem.WithMiddleware(fs, Middleware(opts))
+}
@@ -0,0 +1,169 @@
+package filesystem
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+)
+
+// ErrTransactionDone is the error returned when using a transaction that was already committed or rolled back.
+var ErrTransactionDone = errors.New("Transaction already committed or rolled back")
+
+// Transaction stages writes and deletes of several files, to apply them all on Commit or none of them.
+//
+// Commit applies the staged operations in order, writing each file atomically and keeping a copy of the file it
+// replaces or deletes, next to it, as journal. If an operation fails, the applied ones are undone from the journal.
+// Readers may still observe the files changed by a commit in progress, but never a partially written file.
+type Transaction interface {
+	// Write will stage the write of supplied content at supplied path, replacing the file if any.
+	Write(path Path, content []byte, opts ...WriteOption) error
+	// Delete will stage the delete of the file at supplied path.
+	Delete(path Path) error
+	// Commit will apply the staged operations, undoing the applied ones if one fails.
+	Commit(ctx context.Context) error
+	// Rollback will discard the staged operations.
+	Rollback(ctx context.Context) error
+}
+
+// txOp is an operation staged in a transaction, a delete if content is nil.
+type txOp struct {
+	path    Path
+	content []byte
+	opts    []WriteOption
+}
+
+// txEntry is the journal entry of an applied operation: backup is the copy of the file it replaced, or empty if
+// there was none.
+type txEntry struct {
+	path   Path
+	backup Path
+}
+
+type transaction struct {
+	fs   Interface
+	ops  []txOp
+	done bool
+}
+
+// NewTransaction will begin a transaction on top of provided file system. It is meant for Interface implementations;
+// use the BeginTransaction method otherwise.
+func NewTransaction(fs Interface) Transaction {
+	return &transaction{fs: fs}
+}
+
+func (t *transaction) Write(path Path, content []byte, opts ...WriteOption) error {
+	if t.done {
+		return ErrTransactionDone
+	}
+	if content == nil {
+		content = []byte{}
+	}
+	t.ops = append(t.ops, txOp{path: path, content: bytes.Clone(content), opts: opts})
+	return nil
+}
+
+func (t *transaction) Delete(path Path) error {
+	if t.done {
+		return ErrTransactionDone
+	}
+	t.ops = append(t.ops, txOp{path: path})
+	return nil
+}
+
+func (t *transaction) Commit(ctx context.Context) error {
+	if t.done {
+		return ErrTransactionDone
+	}
+	t.done = true
+	journal := make([]txEntry, 0, len(t.ops))
+	for _, op := range t.ops {
+		entry, err := t.apply(ctx, op)
+		if err != nil {
+			t.undo(ctx, journal)
+			return err
+		}
+		journal = append(journal, entry)
+	}
+	for _, entry := range journal {
+		if entry.backup != "" {
+			t.fs.Delete(ctx, entry.backup)
+		}
+	}
+	return nil
+}
+
+func (t *transaction) Rollback(ctx context.Context) error {
+	if t.done {
+		return ErrTransactionDone
+	}
+	t.done = true
+	t.ops = nil
+	return nil
+}
+
+// apply will apply op, backing up the file it replaces or deletes.
+func (t *transaction) apply(ctx context.Context, op txOp) (txEntry, error) {
+	entry := txEntry{path: op.path}
+	exists, err := t.fs.FileExists(ctx, op.path)
+	if err != nil {
+		return entry, err
+	}
+	if exists {
+		if entry.backup, err = t.backupPath(op.path); err != nil {
+			return entry, err
+		}
+		if err := t.fs.Copy(ctx, op.path, entry.backup); err != nil {
+			return entry, err
+		}
+	}
+	if op.content == nil {
+		if exists {
+			_, err = t.fs.Delete(ctx, op.path)
+		}
+	} else {
+		err = t.fs.WriteAtomic(ctx, op.path, bytes.NewReader(op.content), op.opts...)
+	}
+	if err != nil && entry.backup != "" {
+		t.fs.Delete(ctx, entry.backup)
+	}
+	return entry, err
+}
+
+// undo will undo the applied operations of journal, last first, restoring the files from their backups.
+func (t *transaction) undo(ctx context.Context, journal []txEntry) {
+	for i := len(journal) - 1; i >= 0; i-- {
+		entry := journal[i]
+		if entry.backup == "" {
+			t.fs.Delete(ctx, entry.path)
+		} else {
+			t.fs.Move(ctx, entry.backup, entry.path)
+		}
+	}
+}
+
+// backupPath will return a random hidden path next to path, for the copy of the file to be kept at. The path is
+// built without being normalized, as it may be a mount path.
+func (t *transaction) backupPath(path Path) (Path, error) {
+	id, err := randomID()
+	if err != nil {
+		return "", err
+	}
+	base := path.Base()
+	return Path(strings.TrimSuffix(string(path), base) + "." + base + ".txn-" + id), nil
+}
+
+// BeginTransaction will begin a transaction staging writes and deletes, to apply them all at once on commit.
+func (fs *filesystem) BeginTransaction() Transaction {
+	return NewTransaction(fs)
+}
+
+// BeginTransaction will begin a transaction, whose operations may span several mounts.
+func (mm *mountManager) BeginTransaction() Transaction {
+	return NewTransaction(mm)
+}
+
+// BeginTransaction will begin a transaction, whose operations go through the middlewares on commit.
+func (m *middlewareFS) BeginTransaction() Transaction {
+	return NewTransaction(m)
+}
@@ -0,0 +1,321 @@
+// Package trash provides an adapter decorator making deletes recoverable: deleted files are moved into a trash
+// directory, from where they can be restored until purged.
+//
+// Each delete moves the file, or the directory with its content, to Prefix/<deletion time>/<original path>, the
+// deletion time being in nanoseconds. The trash directory is hidden from listings.
+package trash
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maurofran/filesystem"
+)
+
+// DefaultPrefix is the trash directory used when none is provided to Wrap.
+const DefaultPrefix filesystem.Path = ".trash"
+
+// ConflictError is the error returned when restoring a file over an existing one.
+type ConflictError interface {
+	error
+	Path() filesystem.Path
+}
+
+type conflictError struct {
+	path filesystem.Path
+}
+
+// Path is the path of the existing file.
+func (e conflictError) Path() filesystem.Path {
+	return e.path
+}
+
+func (e conflictError) Error() string {
+	return fmt.Sprintf("Unable to restore %s: file already exists", e.path)
+}
+
+// IsConflictError will check if provided error is a conflict error.
+func IsConflictError(err error) bool {
+	_, ok := err.(ConflictError)
+	return ok
+}
+
+// Item is a file in the trash.
+type Item struct {
+	// Path is the path the file was deleted from.
+	Path filesystem.Path
+	// TrashPath is the path the file is kept at in the trash.
+	TrashPath filesystem.Path
+	// DeletedAt is the time the file was deleted.
+	DeletedAt time.Time
+	// Size is the size of the file, negative if not known.
+	Size int64
+}
+
+// Adapter is an adapter decorator moving deleted files into the trash.
+type Adapter struct {
+	filesystem.Adapter
+	prefix filesystem.Path
+	// Now is the clock used for deletion times, time.Now if nil.
+	Now func() time.Time
+}
+
+// Wrap will decorate provided adapter so that deleted files are moved into the trash directory at prefix, or
+// DefaultPrefix if empty. Deleting files within the trash directory deletes them for good.
+func Wrap(a filesystem.Adapter, prefix filesystem.Path) *Adapter {
+	if prefix == filesystem.RootPath {
+		prefix = DefaultPrefix
+	}
+	return &Adapter{Adapter: a, prefix: prefix}
+}
+
+func (a *Adapter) now() time.Time {
+	if a.Now != nil {
+		return a.Now()
+	}
+	return time.Now()
+}
+
+// hidden will check if path is within the trash directory.
+func (a *Adapter) hidden(path filesystem.Path) bool {
+	return path == a.prefix || strings.HasPrefix(string(path), string(a.prefix)+"/")
+}
+
+// trashDir will return a new directory for the files deleted now.
+func (a *Adapter) trashDir() filesystem.Path {
+	return a.prefix.Join(fmt.Sprintf("%020d", a.now().UnixNano()))
+}
+
+// WriteAtomic will write the content of provided reader at supplied path atomically, the temporary file of the
+// write, if any, being deleted for good on failure.
+func (a *Adapter) WriteAtomic(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	return filesystem.WriteAtomic(ctx, a.Adapter, path, r, cfg)
+}
+
+// Delete will move the file at provided path into the trash.
+func (a *Adapter) Delete(ctx context.Context, path filesystem.Path) error {
+	if a.hidden(path) {
+		return a.Adapter.Delete(ctx, path)
+	}
+	return a.Adapter.Move(ctx, path, a.trashDir().Join(string(path)))
+}
+
+// ReadAndDelete will read the file at provided path and move it into the trash.
+func (a *Adapter) ReadAndDelete(ctx context.Context, path filesystem.Path) ([]byte, error) {
+	content, err := a.Adapter.Read(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return content, a.Delete(ctx, path)
+}
+
+// DeleteDir will move the directory at provided path, with all of its content, into the trash. Deleting a directory
+// holding the trash directory, such as the root, moves every other entry into the trash.
+func (a *Adapter) DeleteDir(ctx context.Context, path filesystem.Path) error {
+	if a.hidden(path) {
+		return a.Adapter.DeleteDir(ctx, path)
+	}
+	return a.trashDirectory(ctx, path, a.trashDir())
+}
+
+// holdsTrash will check if the directory at path is the root or an ancestor of the trash directory.
+func (a *Adapter) holdsTrash(path filesystem.Path) bool {
+	return path == filesystem.RootPath || strings.HasPrefix(string(a.prefix), string(path)+"/")
+}
+
+// trashDirectory will move the directory at path into the trash directory dir, the entries of the directories
+// holding the trash being moved one by one.
+func (a *Adapter) trashDirectory(ctx context.Context, path, dir filesystem.Path) error {
+	if a.holdsTrash(path) {
+		listing, err := a.Adapter.ListContents(ctx, path, false)
+		if err != nil {
+			return err
+		}
+		for _, md := range listing {
+			switch {
+			case a.hidden(md.Path):
+			case md.IsDir():
+				err = a.trashDirectory(ctx, md.Path, dir)
+			default:
+				err = a.Adapter.Move(ctx, md.Path, dir.Join(string(md.Path)))
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	target := dir.Join(string(path))
+	if m, ok := a.Adapter.(filesystem.DirMover); ok {
+		return m.MoveDir(ctx, path, target)
+	}
+	listing, err := a.Adapter.ListContents(ctx, path, true)
+	if err != nil {
+		return err
+	}
+	for _, md := range listing {
+		if md.IsDir() {
+			continue
+		}
+		rel := strings.TrimPrefix(string(md.Path), string(path)+"/")
+		if err := a.Adapter.Move(ctx, md.Path, target.Join(rel)); err != nil {
+			return err
+		}
+	}
+	return a.Adapter.DeleteDir(ctx, path)
+}
+
+// visible will drop the entries of the trash directory from provided listing.
+func (a *Adapter) visible(listing []filesystem.Metadata) []filesystem.Metadata {
+	out := listing[:0]
+	for _, md := range listing {
+		if !a.hidden(md.Path) {
+			out = append(out, md)
+		}
+	}
+	return out
+}
+
+// ListContents will list the contents of given path, the trash excluded.
+func (a *Adapter) ListContents(ctx context.Context, path filesystem.Path, recursive bool) ([]filesystem.Metadata, error) {
+	listing, err := a.Adapter.ListContents(ctx, path, recursive)
+	if err != nil {
+		return nil, err
+	}
+	if a.hidden(path) {
+		return listing, nil
+	}
+	return a.visible(listing), nil
+}
+
+// IterateContents will iterate over the contents of given path, the trash excluded, lazily when the decorated
+// adapter supports it.
+func (a *Adapter) IterateContents(ctx context.Context, path filesystem.Path, recursive bool) (filesystem.Iterator, error) {
+	ci, ok := a.Adapter.(filesystem.ContentsIterator)
+	if !ok {
+		listing, err := a.ListContents(ctx, path, recursive)
+		if err != nil {
+			return nil, err
+		}
+		return filesystem.SliceIterator(listing), nil
+	}
+	it, err := ci.IterateContents(ctx, path, recursive)
+	if err != nil || a.hidden(path) {
+		return it, err
+	}
+	return filesystem.IteratorFunc(func() (filesystem.Metadata, error) {
+		for {
+			md, err := it.Next()
+			if err != nil || !a.hidden(md.Path) {
+				return md, err
+			}
+		}
+	}), nil
+}
+
+// ListTrash will list the files in the trash, most recently deleted first.
+func (a *Adapter) ListTrash(ctx context.Context) ([]Item, error) {
+	listing, err := a.Adapter.ListContents(ctx, a.prefix, true)
+	if filesystem.IsFileNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var items []Item
+	for _, md := range listing {
+		if md.IsDir() {
+			continue
+		}
+		id, original, ok := strings.Cut(strings.TrimPrefix(string(md.Path), string(a.prefix)+"/"), "/")
+		ns, err := strconv.ParseInt(id, 10, 64)
+		if !ok || err != nil {
+			continue
+		}
+		items = append(items, Item{
+			Path:      filesystem.Path(original),
+			TrashPath: md.Path,
+			DeletedAt: time.Unix(0, ns),
+			Size:      md.Size,
+		})
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].DeletedAt.After(items[j].DeletedAt) })
+	return items, nil
+}
+
+// Restore will move the most recently deleted file at supplied path back from the trash, failing with a
+// ConflictError if a file exists at path.
+func (a *Adapter) Restore(ctx context.Context, path filesystem.Path) error {
+	path, err := filesystem.NewPath(string(path))
+	if err != nil {
+		return err
+	}
+	items, err := a.ListTrash(ctx)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		if item.Path != path {
+			continue
+		}
+		exists, err := a.Adapter.Has(ctx, path)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return conflictError{path}
+		}
+		if err := a.Adapter.Move(ctx, item.TrashPath, path); err != nil {
+			return err
+		}
+		return a.clean(ctx, item)
+	}
+	return filesystem.NewFileNotFoundError(path)
+}
+
+// PurgeTrash will delete for good the files deleted before olderThan ago, returning how many were purged.
+func (a *Adapter) PurgeTrash(ctx context.Context, olderThan time.Duration) (int, error) {
+	items, err := a.ListTrash(ctx)
+	if err != nil {
+		return 0, err
+	}
+	limit := a.now().Add(-olderThan)
+	var purged int
+	for _, item := range items {
+		if !item.DeletedAt.Before(limit) {
+			continue
+		}
+		if err := a.Adapter.Delete(ctx, item.TrashPath); err != nil && !filesystem.IsFileNotFound(err) {
+			return purged, err
+		}
+		purged++
+		if err := a.clean(ctx, item); err != nil {
+			return purged, err
+		}
+	}
+	return purged, nil
+}
+
+// clean will delete the trash directory item was deleted in once it holds no more files.
+func (a *Adapter) clean(ctx context.Context, item Item) error {
+	id, _, _ := strings.Cut(strings.TrimPrefix(string(item.TrashPath), string(a.prefix)+"/"), "/")
+	dir := a.prefix.Join(id)
+	listing, err := a.Adapter.ListContents(ctx, dir, true)
+	if filesystem.IsFileNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, md := range listing {
+		if !md.IsDir() {
+			return nil
+		}
+	}
+	return a.Adapter.DeleteDir(ctx, dir)
+}
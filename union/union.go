@@ -0,0 +1,415 @@
+// Package union provides an adapter overlaying layers of adapters, e.g. to serve a base image with per-tenant
+// overrides: files are read from the first layer having them, and every change goes to a single writable layer on
+// top of the others, which are never modified.
+//
+// Deleting a file or a directory of the lower layers records a whiteout on the writable layer, an empty file named
+// after the deleted entry with a ".wh." prefix, hiding it from the union. A directory created again where a directory
+// was deleted is made opaque, by a ".wh..wh..opq" file inside it, so that the content of the lower layers does not
+// show through. Whiteouts are hidden from listings.
+package union
+
+import (
+	"context"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/maurofran/filesystem"
+)
+
+const (
+	whiteoutPrefix = ".wh."
+	opaqueMarker   = ".wh..wh..opq"
+)
+
+// New will create an adapter overlaying writable on top of layers, given in priority order.
+func New(writable filesystem.Adapter, layers ...filesystem.Adapter) filesystem.Adapter {
+	return &union{writable: writable, layers: layers}
+}
+
+type union struct {
+	writable filesystem.Adapter
+	layers   []filesystem.Adapter
+}
+
+func whiteout(path filesystem.Path) filesystem.Path {
+	dir, name := path.Split()
+	return dir.Join(whiteoutPrefix + name)
+}
+
+func isMarker(path filesystem.Path) bool {
+	return strings.HasPrefix(path.Base(), whiteoutPrefix)
+}
+
+// ancestors will return the directories containing path, from the top level one.
+func ancestors(path filesystem.Path) []filesystem.Path {
+	var dirs []filesystem.Path
+	for i, c := range path {
+		if c == '/' {
+			dirs = append(dirs, path[:i])
+		}
+	}
+	return dirs
+}
+
+// masked will check if the lower layers are hidden at path, by a whiteout of path or one of its ancestors, or by
+// an opaque ancestor.
+func (u *union) masked(ctx context.Context, path filesystem.Path) (bool, error) {
+	for _, dir := range ancestors(path) {
+		for _, marker := range []filesystem.Path{whiteout(dir), dir.Join(opaqueMarker)} {
+			if ok, err := u.writable.Has(ctx, marker); err != nil || ok {
+				return ok, err
+			}
+		}
+	}
+	return u.writable.Has(ctx, whiteout(path))
+}
+
+// lower will return the first lower layer having the file at path, or nil if none has it or it is masked.
+func (u *union) lower(ctx context.Context, path filesystem.Path) (filesystem.Adapter, error) {
+	if masked, err := u.masked(ctx, path); err != nil || masked {
+		return nil, err
+	}
+	for _, layer := range u.layers {
+		if ok, err := layer.Has(ctx, path); err != nil || ok {
+			return layer, err
+		}
+	}
+	return nil, nil
+}
+
+// lowerEntry will check if a lower layer shows a file or a directory at path.
+func (u *union) lowerEntry(ctx context.Context, path filesystem.Path) (bool, error) {
+	if masked, err := u.masked(ctx, path); err != nil || masked {
+		return false, err
+	}
+	for _, layer := range u.layers {
+		if ok, err := layer.Has(ctx, path); err != nil || ok {
+			return ok, err
+		}
+		if _, err := layer.GetMetadata(ctx, path); err == nil {
+			return true, nil
+		}
+		if listing, err := layer.ListContents(ctx, path, false); err == nil && len(listing) > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// read will run fn on the writable layer and then, if the file is missing there, on the first lower layer having it.
+func read[T any](ctx context.Context, u *union, path filesystem.Path, fn func(a filesystem.Adapter) (T, error)) (T, error) {
+	v, err := fn(u.writable)
+	if !filesystem.IsFileNotFound(err) {
+		return v, err
+	}
+	layer, err := u.lower(ctx, path)
+	if err != nil {
+		return v, err
+	}
+	if layer == nil {
+		return v, filesystem.NewFileNotFoundError(path)
+	}
+	return fn(layer)
+}
+
+// reveal will remove the whiteouts hiding path, making the directories whose whiteout is removed opaque, path
+// included when it is a directory.
+func (u *union) reveal(ctx context.Context, path filesystem.Path, dir bool) error {
+	for _, p := range append(ancestors(path), path) {
+		ok, err := u.writable.Has(ctx, whiteout(p))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if err := u.writable.Delete(ctx, whiteout(p)); err != nil {
+			return err
+		}
+		if p != path || dir {
+			if err := u.writable.Put(ctx, p.Join(opaqueMarker), nil, *filesystem.EmptyConfig()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// hide will record a whiteout of path on the writable layer.
+func (u *union) hide(ctx context.Context, path filesystem.Path) error {
+	return u.writable.Put(ctx, whiteout(path), nil, *filesystem.EmptyConfig())
+}
+
+// copyUp will copy the file at path to the writable layer if only a lower layer has it.
+func (u *union) copyUp(ctx context.Context, path filesystem.Path) error {
+	if ok, err := u.writable.Has(ctx, path); err != nil || ok {
+		return err
+	}
+	layer, err := u.lower(ctx, path)
+	if err != nil || layer == nil {
+		return err
+	}
+	return copyFile(ctx, layer, path, u.writable, path)
+}
+
+// copyFile will copy the file at path of src to newpath of dst, keeping its visibility.
+func copyFile(ctx context.Context, src filesystem.Adapter, path filesystem.Path, dst filesystem.Adapter, newpath filesystem.Path) error {
+	rc, err := src.ReadStream(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	cfg := filesystem.EmptyConfig()
+	if v, err := src.GetVisibility(ctx, path); err == nil {
+		cfg.Set(filesystem.ConfigVisibility, v)
+	}
+	return dst.PutStream(ctx, newpath, rc, *cfg)
+}
+
+func (u *union) Has(ctx context.Context, path filesystem.Path) (bool, error) {
+	ok, err := u.writable.Has(ctx, path)
+	if err != nil || ok {
+		return ok, err
+	}
+	layer, err := u.lower(ctx, path)
+	return layer != nil, err
+}
+
+func (u *union) Read(ctx context.Context, path filesystem.Path) ([]byte, error) {
+	return read(ctx, u, path, func(a filesystem.Adapter) ([]byte, error) {
+		return a.Read(ctx, path)
+	})
+}
+
+func (u *union) ReadStream(ctx context.Context, path filesystem.Path) (io.ReadCloser, error) {
+	return read(ctx, u, path, func(a filesystem.Adapter) (io.ReadCloser, error) {
+		return a.ReadStream(ctx, path)
+	})
+}
+
+func (u *union) Write(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	if err := u.reveal(ctx, path, false); err != nil {
+		return err
+	}
+	return u.writable.Write(ctx, path, content, cfg)
+}
+
+func (u *union) WriteStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	if err := u.reveal(ctx, path, false); err != nil {
+		return err
+	}
+	return u.writable.WriteStream(ctx, path, r, cfg)
+}
+
+func (u *union) Update(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	if err := u.copyUp(ctx, path); err != nil {
+		return err
+	}
+	return u.writable.Update(ctx, path, content, cfg)
+}
+
+func (u *union) UpdateStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	if err := u.copyUp(ctx, path); err != nil {
+		return err
+	}
+	return u.writable.UpdateStream(ctx, path, r, cfg)
+}
+
+func (u *union) Put(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	if err := u.reveal(ctx, path, false); err != nil {
+		return err
+	}
+	return u.writable.Put(ctx, path, content, cfg)
+}
+
+func (u *union) PutStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	if err := u.reveal(ctx, path, false); err != nil {
+		return err
+	}
+	return u.writable.PutStream(ctx, path, r, cfg)
+}
+
+// Delete will delete the file from the writable layer, and hide it with a whiteout if a lower layer has it.
+func (u *union) Delete(ctx context.Context, path filesystem.Path) error {
+	layer, err := u.lower(ctx, path)
+	if err != nil {
+		return err
+	}
+	err = u.writable.Delete(ctx, path)
+	if layer == nil || err != nil && !filesystem.IsFileNotFound(err) {
+		return err
+	}
+	return u.hide(ctx, path)
+}
+
+func (u *union) ReadAndDelete(ctx context.Context, path filesystem.Path) ([]byte, error) {
+	content, err := u.Read(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return content, u.Delete(ctx, path)
+}
+
+func (u *union) Move(ctx context.Context, path, newpath filesystem.Path) error {
+	if err := u.Copy(ctx, path, newpath); err != nil {
+		return err
+	}
+	return u.Delete(ctx, path)
+}
+
+// Copy will copy the file on the writable layer, natively if it is there.
+func (u *union) Copy(ctx context.Context, path, newpath filesystem.Path) error {
+	ok, err := u.writable.Has(ctx, path)
+	if err != nil {
+		return err
+	}
+	if err := u.reveal(ctx, newpath, false); err != nil {
+		return err
+	}
+	if ok {
+		return u.writable.Copy(ctx, path, newpath)
+	}
+	layer, err := u.lower(ctx, path)
+	if err != nil {
+		return err
+	}
+	if layer == nil {
+		return filesystem.NewFileNotFoundError(path)
+	}
+	return copyFile(ctx, layer, path, u.writable, newpath)
+}
+
+func (u *union) GetMimeType(ctx context.Context, path filesystem.Path) (string, error) {
+	return read(ctx, u, path, func(a filesystem.Adapter) (string, error) {
+		return a.GetMimeType(ctx, path)
+	})
+}
+
+func (u *union) GetTimestamp(ctx context.Context, path filesystem.Path) (time.Time, error) {
+	return read(ctx, u, path, func(a filesystem.Adapter) (time.Time, error) {
+		return a.GetTimestamp(ctx, path)
+	})
+}
+
+func (u *union) GetFileSize(ctx context.Context, path filesystem.Path) (int64, error) {
+	return read(ctx, u, path, func(a filesystem.Adapter) (int64, error) {
+		return a.GetFileSize(ctx, path)
+	})
+}
+
+func (u *union) GetMetadata(ctx context.Context, path filesystem.Path) (filesystem.Metadata, error) {
+	return read(ctx, u, path, func(a filesystem.Adapter) (filesystem.Metadata, error) {
+		return a.GetMetadata(ctx, path)
+	})
+}
+
+func (u *union) CreateDir(ctx context.Context, path filesystem.Path, cfg filesystem.Config) error {
+	if err := u.reveal(ctx, path, true); err != nil {
+		return err
+	}
+	return u.writable.CreateDir(ctx, path, cfg)
+}
+
+// DeleteDir will delete the directory from the writable layer, and hide it with a whiteout if a lower layer has it.
+func (u *union) DeleteDir(ctx context.Context, path filesystem.Path) error {
+	lower, err := u.lowerEntry(ctx, path)
+	if err != nil {
+		return err
+	}
+	err = u.writable.DeleteDir(ctx, path)
+	if !lower || err != nil && !filesystem.IsFileNotFound(err) {
+		return err
+	}
+	return u.hide(ctx, path)
+}
+
+func (u *union) GetVisibility(ctx context.Context, path filesystem.Path) (filesystem.Visibility, error) {
+	return read(ctx, u, path, func(a filesystem.Adapter) (filesystem.Visibility, error) {
+		return a.GetVisibility(ctx, path)
+	})
+}
+
+func (u *union) SetVisibility(ctx context.Context, path filesystem.Path, v filesystem.Visibility) error {
+	if err := u.copyUp(ctx, path); err != nil {
+		return err
+	}
+	return u.writable.SetVisibility(ctx, path, v)
+}
+
+// ListContents will merge the listings of the layers, the upper ones winning for entries several layers have, leaving
+// out the entries hidden by whiteouts and opaque directories.
+func (u *union) ListContents(ctx context.Context, path filesystem.Path, recursive bool) ([]filesystem.Metadata, error) {
+	upper, err := u.writable.ListContents(ctx, path, recursive)
+	if err != nil && !filesystem.IsFileNotFound(err) {
+		return nil, err
+	}
+	found := err == nil
+	seen := make(map[filesystem.Path]bool)
+	hidden := make(map[filesystem.Path]bool)
+	var result []filesystem.Metadata
+	for _, md := range upper {
+		switch {
+		case md.Path.Base() == opaqueMarker:
+			hidden[md.Path.Dir()] = true
+		case isMarker(md.Path):
+			hidden[md.Path.Dir().Join(strings.TrimPrefix(md.Path.Base(), whiteoutPrefix))] = true
+		default:
+			seen[md.Path] = true
+			result = append(result, md)
+		}
+	}
+	masked, err := u.masked(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if !masked && !hidden[path] {
+		for _, layer := range u.layers {
+			listing, err := layer.ListContents(ctx, path, recursive)
+			if filesystem.IsFileNotFound(err) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			found = true
+			for _, md := range listing {
+				if seen[md.Path] || isMarker(md.Path) || isHidden(md.Path, hidden) {
+					continue
+				}
+				seen[md.Path] = true
+				result = append(result, md)
+			}
+		}
+	}
+	if !found {
+		return nil, filesystem.NewFileNotFoundError(path)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Path < result[j].Path
+	})
+	return result, nil
+}
+
+// isHidden will check if the lower layers are hidden at path, by a whiteout or an opaque directory of the writable
+// listing.
+func isHidden(path filesystem.Path, hidden map[filesystem.Path]bool) bool {
+	for _, dir := range ancestors(path) {
+		if hidden[dir] {
+			return true
+		}
+	}
+	return hidden[path]
+}
+
+// Close will close every layer, returning the first error encountered.
+func (u *union) Close() error {
+	err := filesystem.Close(u.writable)
+	for _, layer := range u.layers {
+		if err2 := filesystem.Close(layer); err == nil {
+			err = err2
+		}
+	}
+	return err
+}
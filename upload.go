@@ -0,0 +1,265 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// UploadState identifies an upload session, so that it can be resumed, even by another process, with ResumeUpload.
+// It is meant to be serialized, e.g. as JSON.
+type UploadState struct {
+	// Path is the path of the file being uploaded.
+	Path Path `json:"path"`
+	// ID is the identifier of the session.
+	ID string `json:"id"`
+	// Offset is the number of bytes received so far.
+	Offset int64 `json:"offset"`
+}
+
+// UploadSession is an upload receiving the content of a file in chunks, possibly across process restarts, the file
+// only changing once the session is committed.
+type UploadSession interface {
+	// State will return the state of the session, to resume it later.
+	State() UploadState
+	// WriteChunk will append provided chunk to the content, failing with an UploadOffsetError if offset is not the
+	// number of bytes received so far.
+	WriteChunk(ctx context.Context, offset int64, chunk []byte) error
+	// Commit will write the file with the content received.
+	Commit(ctx context.Context) error
+	// Abort will discard the session and the content received.
+	Abort(ctx context.Context) error
+}
+
+// ResumableUploader is implemented by adapters with a dedicated mechanism to upload files in resumable sessions.
+// Decorators may return an UnsupportedOperationError when the adapter they decorate is not a ResumableUploader, so
+// that uploads fall back as BeginUpload describes.
+type ResumableUploader interface {
+	BeginUpload(ctx context.Context, path Path, cfg Config) (UploadSession, error)
+	ResumeUpload(ctx context.Context, state UploadState, cfg Config) (UploadSession, error)
+}
+
+// BeginUpload will begin an upload session of the file at supplied path through adapter. ResumableUploader adapters
+// upload natively; otherwise chunks are written as files of a hidden directory next to path, and streamed into the
+// file with WriteAtomic on commit. It is meant for decorators; use the method of Interface otherwise.
+func BeginUpload(ctx context.Context, a Adapter, path Path, cfg Config) (UploadSession, error) {
+	if u, ok := a.(ResumableUploader); ok {
+		s, err := u.BeginUpload(ctx, path, cfg)
+		if !IsUnsupportedOperationError(err) {
+			return s, err
+		}
+	}
+	id, err := randomID()
+	if err != nil {
+		return nil, err
+	}
+	return newStagedUpload(a, path, id, cfg), nil
+}
+
+// ResumeUpload will resume the upload session with provided state through adapter, as BeginUpload began it. The
+// offset of the resumed session is the one of the content actually received, which may differ from the offset of
+// the state. It is meant for decorators; use the method of Interface otherwise.
+func ResumeUpload(ctx context.Context, a Adapter, state UploadState, cfg Config) (UploadSession, error) {
+	if u, ok := a.(ResumableUploader); ok {
+		s, err := u.ResumeUpload(ctx, state, cfg)
+		if !IsUnsupportedOperationError(err) {
+			return s, err
+		}
+	}
+	if !isRandomID(state.ID) {
+		return nil, invalidUploadIDError(state.Path)
+	}
+	s := newStagedUpload(a, state.Path, state.ID, cfg)
+	if err := s.load(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// stagedUpload is an upload session staging chunks as files named after their offset.
+type stagedUpload struct {
+	adapter Adapter
+	path    Path
+	id      string
+	dir     Path
+	cfg     Config
+	offset  int64
+	chunks  []Path
+}
+
+func newStagedUpload(a Adapter, path Path, id string, cfg Config) *stagedUpload {
+	return &stagedUpload{
+		adapter: a,
+		path:    path,
+		id:      id,
+		dir:     path.Dir().Join("." + path.Base() + ".upload-" + id),
+		cfg:     cfg,
+	}
+}
+
+func (s *stagedUpload) State() UploadState {
+	return UploadState{Path: s.path, ID: s.id, Offset: s.offset}
+}
+
+func (s *stagedUpload) WriteChunk(ctx context.Context, offset int64, chunk []byte) error {
+	if offset != s.offset {
+		return NewUploadOffsetError(s.path, s.offset)
+	}
+	if len(chunk) == 0 {
+		return nil
+	}
+	p := s.dir.Join(fmt.Sprintf("%020d", offset))
+	if err := s.adapter.Write(ctx, p, chunk, *EmptyConfig()); err != nil {
+		return err
+	}
+	s.offset += int64(len(chunk))
+	s.chunks = append(s.chunks, p)
+	return nil
+}
+
+func (s *stagedUpload) Commit(ctx context.Context) error {
+	r := &chunksReader{ctx: ctx, adapter: s.adapter, chunks: s.chunks}
+	err := WriteAtomic(ctx, s.adapter, s.path, r, s.cfg)
+	r.close()
+	if err != nil {
+		return err
+	}
+	return s.Abort(ctx)
+}
+
+func (s *stagedUpload) Abort(ctx context.Context) error {
+	if len(s.chunks) == 0 {
+		return nil
+	}
+	if err := s.adapter.DeleteDir(ctx, s.dir); err != nil && !IsFileNotFound(err) {
+		return err
+	}
+	s.chunks = nil
+	return nil
+}
+
+// load will rebuild the offset and chunks of the session from the staged chunks, keeping the contiguous ones.
+func (s *stagedUpload) load(ctx context.Context) error {
+	contents, err := s.adapter.ListContents(ctx, s.dir, false)
+	if err != nil {
+		if IsFileNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	sort.Slice(contents, func(i, j int) bool { return contents[i].Path < contents[j].Path })
+	for _, md := range contents {
+		if md.IsDir() {
+			continue
+		}
+		offset, err := strconv.ParseInt(md.Path.Base(), 10, 64)
+		if err != nil || offset != s.offset {
+			break
+		}
+		s.offset += md.Size
+		s.chunks = append(s.chunks, md.Path)
+	}
+	return nil
+}
+
+// chunksReader reads the content of staged chunks one after the other, opening each one when reached.
+type chunksReader struct {
+	ctx     context.Context
+	adapter Adapter
+	chunks  []Path
+	current io.ReadCloser
+}
+
+func (r *chunksReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.chunks) == 0 {
+				return 0, io.EOF
+			}
+			rc, err := r.adapter.ReadStream(r.ctx, r.chunks[0])
+			if err != nil {
+				return 0, err
+			}
+			r.current, r.chunks = rc, r.chunks[1:]
+		}
+		n, err := r.current.Read(p)
+		if err == io.EOF {
+			r.close()
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (r *chunksReader) close() {
+	if r.current != nil {
+		r.current.Close()
+		r.current = nil
+	}
+}
+
+// BeginUpload will begin an upload session of the file at supplied path, writing it with provided options on
+// commit.
+func (fs *filesystem) BeginUpload(ctx context.Context, path Path, opts ...WriteOption) (UploadSession, error) {
+	path, err := NewPath(string(path))
+	if err != nil {
+		return nil, err
+	}
+	return BeginUpload(ctx, fs.adapter, path, fs.writeConfig(opts))
+}
+
+// ResumeUpload will resume the upload session with provided state, writing the file with provided options on
+// commit.
+func (fs *filesystem) ResumeUpload(ctx context.Context, state UploadState, opts ...WriteOption) (UploadSession, error) {
+	path, err := NewPath(string(state.Path))
+	if err != nil {
+		return nil, err
+	}
+	state.Path = path
+	return ResumeUpload(ctx, fs.adapter, state, fs.writeConfig(opts))
+}
+
+// BeginUpload will begin an upload session of the file at supplied path.
+func (mm *mountManager) BeginUpload(ctx context.Context, path Path, opts ...WriteOption) (UploadSession, error) {
+	mgr, subPath, err := mm.managerFor(path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := mgr.BeginUpload(ctx, subPath, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return mountedUpload{s, path}, nil
+}
+
+// ResumeUpload will resume the upload session with provided state.
+func (mm *mountManager) ResumeUpload(ctx context.Context, state UploadState, opts ...WriteOption) (UploadSession, error) {
+	mgr, subPath, err := mm.managerFor(state.Path)
+	if err != nil {
+		return nil, err
+	}
+	path := state.Path
+	state.Path = subPath
+	s, err := mgr.ResumeUpload(ctx, state, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return mountedUpload{s, path}, nil
+}
+
+// mountedUpload is an upload session of a mounted file system, whose state holds the path including the mount.
+type mountedUpload struct {
+	UploadSession
+	path Path
+}
+
+func (u mountedUpload) State() UploadState {
+	state := u.UploadSession.State()
+	state.Path = u.path
+	return state
+}
@@ -0,0 +1,56 @@
+package filesystem
+
+import "context"
+
+// UserMetadataProvider is implemented by adapters able to attach user defined key/value metadata to files, such as
+// S3 object metadata.
+type UserMetadataProvider interface {
+	GetUserMetadata(ctx context.Context, path Path) (map[string]string, error)
+	SetUserMetadata(ctx context.Context, path Path, metadata map[string]string) error
+}
+
+// GetUserMetadata will retrieve the user defined metadata of file at supplied path, failing with an
+// UnsupportedOperationError if adapter does not support user metadata.
+func (fs *filesystem) GetUserMetadata(ctx context.Context, path Path) (map[string]string, error) {
+	path, err := NewPath(string(path))
+	if err != nil {
+		return nil, err
+	}
+	p, ok := fs.adapter.(UserMetadataProvider)
+	if !ok {
+		return nil, NewUnsupportedOperationError(path, "GetUserMetadata")
+	}
+	return p.GetUserMetadata(ctx, path)
+}
+
+// SetUserMetadata will replace the user defined metadata of file at supplied path, failing with an
+// UnsupportedOperationError if adapter does not support user metadata.
+func (fs *filesystem) SetUserMetadata(ctx context.Context, path Path, metadata map[string]string) error {
+	path, err := NewPath(string(path))
+	if err != nil {
+		return err
+	}
+	p, ok := fs.adapter.(UserMetadataProvider)
+	if !ok {
+		return NewUnsupportedOperationError(path, "SetUserMetadata")
+	}
+	return p.SetUserMetadata(ctx, path, metadata)
+}
+
+// GetUserMetadata will retrieve the user defined metadata of file at supplied path.
+func (mm *mountManager) GetUserMetadata(ctx context.Context, path Path) (map[string]string, error) {
+	mgr, subPath, err := mm.managerFor(path)
+	if err != nil {
+		return nil, err
+	}
+	return mgr.GetUserMetadata(ctx, subPath)
+}
+
+// SetUserMetadata will replace the user defined metadata of file at supplied path.
+func (mm *mountManager) SetUserMetadata(ctx context.Context, path Path, metadata map[string]string) error {
+	mgr, subPath, err := mm.managerFor(path)
+	if err != nil {
+		return err
+	}
+	return mgr.SetUserMetadata(ctx, subPath, metadata)
+}
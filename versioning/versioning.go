@@ -0,0 +1,381 @@
+// Package versioning provides an adapter decorator keeping the previous versions of files, for adapters without
+// native versioning.
+//
+// Before a file is overwritten or deleted, its content is copied under the hidden Prefix directory, at
+// Prefix/<path>/<version ID>, which listings of the decorated adapter do not show. Version IDs start with the
+// timestamps of the versions in nanoseconds, followed by a digest of their ETag when the adapter provides one, and
+// get a counter appended when a previous version already has the ID, such as after two writes within the timestamp
+// resolution of an adapter without ETags, so that no version is ever overwritten.
+package versioning
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maurofran/filesystem"
+)
+
+// Prefix is the directory previous versions are kept under.
+const Prefix filesystem.Path = ".versions"
+
+// Wrap will decorate provided adapter so that the previous versions of files are kept, up to keep of them per
+// file; a keep of zero or less keeps every version.
+func Wrap(a filesystem.Adapter, keep int) filesystem.Adapter {
+	return &adapter{Adapter: a, keep: keep}
+}
+
+type adapter struct {
+	filesystem.Adapter
+	keep int
+}
+
+// hidden will check if path is where versions are kept.
+func hidden(path filesystem.Path) bool {
+	return path == Prefix || strings.HasPrefix(string(path), string(Prefix)+"/")
+}
+
+// versionDir is the directory the versions of file at path are kept in.
+func versionDir(path filesystem.Path) filesystem.Path {
+	return Prefix.Join(string(path))
+}
+
+// versionID will identify the version of file with provided metadata, padded so that IDs sort as timestamps do.
+func versionID(md filesystem.Metadata) string {
+	id := fmt.Sprintf("%020d", md.Timestamp.UnixNano())
+	if md.ETag != "" {
+		sum := sha256.Sum256([]byte(md.ETag))
+		id += "-" + hex.EncodeToString(sum[:8])
+	}
+	return id
+}
+
+// parseVersionID will return the timestamp of the version with provided ID, reporting false for malformed IDs.
+func parseVersionID(id string) (time.Time, bool) {
+	ts, rest, _ := strings.Cut(id, "-")
+	ns, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || strings.Trim(rest, "0123456789abcdef-") != "" {
+		return time.Time{}, false
+	}
+	return time.Unix(0, ns), true
+}
+
+// current will return the metadata of file at path, or nil if there is none.
+func (a *adapter) current(ctx context.Context, path filesystem.Path) (filesystem.Metadata, error) {
+	md, err := a.Adapter.GetMetadata(ctx, path)
+	if filesystem.IsFileNotFound(err) || (err == nil && md.IsDir()) {
+		return nil, nil
+	}
+	return md, err
+}
+
+// archive will copy the file at path, if any, among its versions.
+func (a *adapter) archive(ctx context.Context, path filesystem.Path) error {
+	if hidden(path) {
+		return nil
+	}
+	md, err := a.current(ctx, path)
+	if md == nil || err != nil {
+		return err
+	}
+	id, err := a.currentID(ctx, path, md)
+	if err != nil {
+		return err
+	}
+	return a.Adapter.Copy(ctx, path, versionDir(path).Join(id))
+}
+
+// currentID will return the ID of the current version of file at path with provided metadata, the one it is
+// archived with: its versionID, with a counter appended if a previous version already has it.
+func (a *adapter) currentID(ctx context.Context, path filesystem.Path, md filesystem.Metadata) (string, error) {
+	base := versionID(md)
+	id := base
+	for n := 1; ; n++ {
+		exists, err := a.Adapter.Has(ctx, versionDir(path).Join(id))
+		if err != nil || !exists {
+			return id, err
+		}
+		id = fmt.Sprintf("%s-%d", base, n)
+	}
+}
+
+// prune will delete the oldest versions of file at path exceeding the number to keep.
+func (a *adapter) prune(ctx context.Context, path filesystem.Path) error {
+	if a.keep <= 0 || hidden(path) {
+		return nil
+	}
+	ids, err := a.versionIDs(ctx, path)
+	if err != nil || len(ids) <= a.keep {
+		return err
+	}
+	for _, id := range ids[:len(ids)-a.keep] {
+		if err := a.Adapter.Delete(ctx, versionDir(path).Join(id)); err != nil && !filesystem.IsFileNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// versionIDs will list the IDs of the previous versions of file at path, oldest first.
+func (a *adapter) versionIDs(ctx context.Context, path filesystem.Path) ([]string, error) {
+	listing, err := a.Adapter.ListContents(ctx, versionDir(path), false)
+	if filesystem.IsFileNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, md := range listing {
+		if !md.IsDir() {
+			ids = append(ids, md.Path.Base())
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// write will run fn, which replaces or removes the file at path, keeping its current version.
+func (a *adapter) write(ctx context.Context, path filesystem.Path, fn func() error) error {
+	if err := a.archive(ctx, path); err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		return err
+	}
+	return a.prune(ctx, path)
+}
+
+// Write the supplied content at supplied path, keeping the version it replaces.
+func (a *adapter) Write(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	return a.write(ctx, path, func() error {
+		return a.Adapter.Write(ctx, path, content, cfg)
+	})
+}
+
+// WriteStream will write the content of provided reader at supplied path, keeping the version it replaces.
+func (a *adapter) WriteStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	return a.write(ctx, path, func() error {
+		return a.Adapter.WriteStream(ctx, path, r, cfg)
+	})
+}
+
+// WriteAtomic will write the content of provided reader at supplied path atomically, keeping the version it
+// replaces. The temporary file of the write, if any, is not versioned.
+func (a *adapter) WriteAtomic(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	return a.write(ctx, path, func() error {
+		return filesystem.WriteAtomic(ctx, a.Adapter, path, r, cfg)
+	})
+}
+
+// Update the supplied content at supplied path, keeping the version it replaces.
+func (a *adapter) Update(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	return a.write(ctx, path, func() error {
+		return a.Adapter.Update(ctx, path, content, cfg)
+	})
+}
+
+// UpdateStream will update with the content of supplied reader at supplied path, keeping the version it replaces.
+func (a *adapter) UpdateStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	return a.write(ctx, path, func() error {
+		return a.Adapter.UpdateStream(ctx, path, r, cfg)
+	})
+}
+
+// Put the supplied content at supplied path, keeping the version it replaces.
+func (a *adapter) Put(ctx context.Context, path filesystem.Path, content []byte, cfg filesystem.Config) error {
+	return a.write(ctx, path, func() error {
+		return a.Adapter.Put(ctx, path, content, cfg)
+	})
+}
+
+// PutStream will put the content of supplied reader at supplied path, keeping the version it replaces.
+func (a *adapter) PutStream(ctx context.Context, path filesystem.Path, r io.Reader, cfg filesystem.Config) error {
+	return a.write(ctx, path, func() error {
+		return a.Adapter.PutStream(ctx, path, r, cfg)
+	})
+}
+
+// Delete will delete the file at provided path, keeping its last version.
+func (a *adapter) Delete(ctx context.Context, path filesystem.Path) error {
+	return a.write(ctx, path, func() error {
+		return a.Adapter.Delete(ctx, path)
+	})
+}
+
+// ReadAndDelete will read the file at provided path and delete it, keeping its last version.
+func (a *adapter) ReadAndDelete(ctx context.Context, path filesystem.Path) (content []byte, err error) {
+	err = a.write(ctx, path, func() error {
+		content, err = a.Adapter.ReadAndDelete(ctx, path)
+		return err
+	})
+	return content, err
+}
+
+// Move the file at supplied path to new path, keeping the last version of the file and the version it replaces.
+func (a *adapter) Move(ctx context.Context, path, newpath filesystem.Path) error {
+	if err := a.archive(ctx, path); err != nil {
+		return err
+	}
+	return a.write(ctx, newpath, func() error {
+		if err := a.Adapter.Move(ctx, path, newpath); err != nil {
+			return err
+		}
+		return a.prune(ctx, path)
+	})
+}
+
+// Copy the file at supplied path to new path, keeping the version it replaces.
+func (a *adapter) Copy(ctx context.Context, path, newpath filesystem.Path) error {
+	return a.write(ctx, newpath, func() error {
+		return a.Adapter.Copy(ctx, path, newpath)
+	})
+}
+
+// visible will drop the entries where versions are kept from provided listing.
+func visible(listing []filesystem.Metadata) []filesystem.Metadata {
+	out := listing[:0]
+	for _, md := range listing {
+		if !hidden(md.Path) {
+			out = append(out, md)
+		}
+	}
+	return out
+}
+
+// ListContents will list the contents of given path, versions excluded.
+func (a *adapter) ListContents(ctx context.Context, path filesystem.Path, recursive bool) ([]filesystem.Metadata, error) {
+	listing, err := a.Adapter.ListContents(ctx, path, recursive)
+	if err != nil {
+		return nil, err
+	}
+	return visible(listing), nil
+}
+
+// IterateContents will iterate over the contents of given path, versions excluded, lazily when the decorated
+// adapter supports it.
+func (a *adapter) IterateContents(ctx context.Context, path filesystem.Path, recursive bool) (filesystem.Iterator, error) {
+	ci, ok := a.Adapter.(filesystem.ContentsIterator)
+	if !ok {
+		listing, err := a.ListContents(ctx, path, recursive)
+		if err != nil {
+			return nil, err
+		}
+		return filesystem.SliceIterator(listing), nil
+	}
+	it, err := ci.IterateContents(ctx, path, recursive)
+	if err != nil {
+		return nil, err
+	}
+	return filesystem.IteratorFunc(func() (filesystem.Metadata, error) {
+		for {
+			md, err := it.Next()
+			if err != nil || !hidden(md.Path) {
+				return md, err
+			}
+		}
+	}), nil
+}
+
+// ListVersions will list the versions of file at supplied path, newest first, the current one included.
+func (a *adapter) ListVersions(ctx context.Context, path filesystem.Path) ([]filesystem.Metadata, error) {
+	var versions []filesystem.Metadata
+	md, err := a.current(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	var currentID string
+	if md != nil {
+		if currentID, err = a.currentID(ctx, path, md); err != nil {
+			return nil, err
+		}
+		md = md.Clone()
+		md.VersionID = currentID
+		versions = append(versions, md)
+	}
+	listing, err := a.Adapter.ListContents(ctx, versionDir(path), false)
+	if err != nil && !filesystem.IsFileNotFound(err) {
+		return nil, err
+	}
+	sort.Slice(listing, func(i, j int) bool { return listing[i].Path > listing[j].Path })
+	for _, v := range listing {
+		id := v.Path.Base()
+		timestamp, ok := parseVersionID(id)
+		if v.IsDir() || !ok || id == currentID {
+			continue
+		}
+		versions = append(versions, &filesystem.FileInfo{
+			Path:      path,
+			Type:      filesystem.TypeFile,
+			Size:      v.Size,
+			MimeType:  v.MimeType,
+			Timestamp: timestamp,
+			ETag:      v.ETag,
+			VersionID: id,
+		})
+	}
+	if len(versions) == 0 {
+		return nil, filesystem.NewFileNotFoundError(path)
+	}
+	return versions, nil
+}
+
+// version will return the path provided version of file at path is kept at, or path itself for the current
+// version.
+func (a *adapter) version(ctx context.Context, path filesystem.Path, id string) (filesystem.Path, bool, error) {
+	if _, ok := parseVersionID(id); !ok {
+		return "", false, filesystem.NewFileNotFoundError(path)
+	}
+	md, err := a.current(ctx, path)
+	if err != nil {
+		return "", false, err
+	}
+	if md != nil {
+		currentID, err := a.currentID(ctx, path, md)
+		if err != nil {
+			return "", false, err
+		}
+		if id == currentID {
+			return path, true, nil
+		}
+	}
+	return versionDir(path).Join(id), false, nil
+}
+
+// ReadVersion will read provided version of file at supplied path as a stream.
+func (a *adapter) ReadVersion(ctx context.Context, path filesystem.Path, id string) (io.ReadCloser, error) {
+	p, _, err := a.version(ctx, path, id)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := a.Adapter.ReadStream(ctx, p)
+	if filesystem.IsFileNotFound(err) {
+		return nil, filesystem.NewFileNotFoundError(path)
+	}
+	return rc, err
+}
+
+// RestoreVersion will copy provided version of file at supplied path over the current one, which is kept as a
+// previous version.
+func (a *adapter) RestoreVersion(ctx context.Context, path filesystem.Path, id string) error {
+	p, current, err := a.version(ctx, path, id)
+	if err != nil || current {
+		return err
+	}
+	ok, err := a.Adapter.Has(ctx, p)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return filesystem.NewFileNotFoundError(path)
+	}
+	return a.write(ctx, path, func() error {
+		return a.Adapter.Copy(ctx, p, path)
+	})
+}
@@ -0,0 +1,86 @@
+package filesystem
+
+import (
+	"context"
+	"io"
+)
+
+// Versioned is implemented by adapters keeping the previous versions of files, such as S3 buckets with versioning
+// enabled. Versions are identified by the VersionID of their metadata.
+type Versioned interface {
+	// ListVersions will list the versions of file at supplied path, newest first.
+	ListVersions(ctx context.Context, path Path) ([]Metadata, error)
+	// ReadVersion will read provided version of file at supplied path as a stream.
+	ReadVersion(ctx context.Context, path Path, versionID string) (io.ReadCloser, error)
+	// RestoreVersion will make provided version the current content of file at supplied path.
+	RestoreVersion(ctx context.Context, path Path, versionID string) error
+}
+
+// ListVersions will list the versions of file at supplied path, newest first, failing with an
+// UnsupportedOperationError if adapter does not keep versions.
+func (fs *filesystem) ListVersions(ctx context.Context, path Path) ([]Metadata, error) {
+	path, err := NewPath(string(path))
+	if err != nil {
+		return nil, err
+	}
+	v, ok := fs.adapter.(Versioned)
+	if !ok {
+		return nil, NewUnsupportedOperationError(path, "ListVersions")
+	}
+	return v.ListVersions(ctx, path)
+}
+
+// ReadVersion will read provided version of file at supplied path, failing with an UnsupportedOperationError if
+// adapter does not keep versions.
+func (fs *filesystem) ReadVersion(ctx context.Context, path Path, versionID string) (io.ReadCloser, error) {
+	path, err := NewPath(string(path))
+	if err != nil {
+		return nil, err
+	}
+	v, ok := fs.adapter.(Versioned)
+	if !ok {
+		return nil, NewUnsupportedOperationError(path, "ReadVersion")
+	}
+	return v.ReadVersion(ctx, path, versionID)
+}
+
+// RestoreVersion will make provided version the current content of file at supplied path, failing with an
+// UnsupportedOperationError if adapter does not keep versions.
+func (fs *filesystem) RestoreVersion(ctx context.Context, path Path, versionID string) error {
+	path, err := NewPath(string(path))
+	if err != nil {
+		return err
+	}
+	v, ok := fs.adapter.(Versioned)
+	if !ok {
+		return NewUnsupportedOperationError(path, "RestoreVersion")
+	}
+	return v.RestoreVersion(ctx, path, versionID)
+}
+
+// ListVersions will list the versions of file at supplied path.
+func (mm *mountManager) ListVersions(ctx context.Context, path Path) ([]Metadata, error) {
+	mgr, subPath, err := mm.managerFor(path)
+	if err != nil {
+		return nil, err
+	}
+	return mgr.ListVersions(ctx, subPath)
+}
+
+// ReadVersion will read provided version of file at supplied path.
+func (mm *mountManager) ReadVersion(ctx context.Context, path Path, versionID string) (io.ReadCloser, error) {
+	mgr, subPath, err := mm.managerFor(path)
+	if err != nil {
+		return nil, err
+	}
+	return mgr.ReadVersion(ctx, subPath, versionID)
+}
+
+// RestoreVersion will make provided version the current content of file at supplied path.
+func (mm *mountManager) RestoreVersion(ctx context.Context, path Path, versionID string) error {
+	mgr, subPath, err := mm.managerFor(path)
+	if err != nil {
+		return err
+	}
+	return mgr.RestoreVersion(ctx, subPath, versionID)
+}
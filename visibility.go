@@ -1,16 +1,50 @@
 package filesystem
 
-// Visibility enumeration.
-type Visibility int
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Visibility is the visibility of a file. Besides public and private, adapters may support provider specific
+// visibilities, such as "authenticated-read", registered with RegisterVisibility. The empty visibility means it is
+// not known.
+type Visibility string
 
-// Visibility values (public and private).
+// Visibility values (public and private), supported by every adapter.
 const (
-	VisibilityPublic Visibility = iota + 1
-	VisibilityPrivate
+	VisibilityPublic  Visibility = "public"
+	VisibilityPrivate Visibility = "private"
 )
 
-var visibilities = [...]string{"Public", "Private"}
+var visibilities = struct {
+	sync.RWMutex
+	registered map[Visibility]bool
+}{registered: map[Visibility]bool{VisibilityPublic: true, VisibilityPrivate: true}}
+
+// RegisterVisibility will register a provider specific visibility, so that ParseVisibility accepts it.
+func RegisterVisibility(v Visibility) {
+	visibilities.Lock()
+	defer visibilities.Unlock()
+	visibilities.registered[v] = true
+}
+
+// IsRegistered will check if the visibility is public, private or a registered provider specific visibility.
+func (v Visibility) IsRegistered() bool {
+	visibilities.RLock()
+	defer visibilities.RUnlock()
+	return visibilities.registered[v]
+}
+
+// ParseVisibility will convert provided string, case insensitively, to a registered visibility.
+func ParseVisibility(s string) (Visibility, error) {
+	v := Visibility(strings.ToLower(s))
+	if !v.IsRegistered() {
+		return "", fmt.Errorf("unknown visibility %q", s)
+	}
+	return v, nil
+}
 
 func (v Visibility) String() string {
-	return visibilities[v-1]
+	return string(v)
 }
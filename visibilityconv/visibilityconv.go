@@ -0,0 +1,155 @@
+// Package visibilityconv converts visibilities to the access controls of storage backends: unix permissions for
+// adapters writing to a file system, and ACL strings, such as S3 canned ACLs, for object stores.
+//
+// Conversions can be customized per mount by giving WithPermissions or WithACLs to filesystem.New: adapters read them
+// from the config of every write, falling back to their own defaults.
+package visibilityconv
+
+import (
+	"os"
+
+	"github.com/maurofran/filesystem"
+)
+
+// Config keys of the conversions, set by WithPermissions and WithACLs.
+const (
+	// ConfigPermissions is the Permissions used to convert visibilities to unix permissions.
+	ConfigPermissions = "visibility_permissions"
+	// ConfigACLs is the ACLs used to convert visibilities to ACL strings. Besides ACLs, a map with "public" and
+	// "private" string keys is accepted, as read from configuration files.
+	ConfigACLs = "visibility_acls"
+)
+
+// Permissions are the unix permissions of public and private files and directories.
+type Permissions struct {
+	FilePublic  os.FileMode
+	FilePrivate os.FileMode
+	DirPublic   os.FileMode
+	DirPrivate  os.FileMode
+}
+
+// DefaultPermissions are the permissions used when none are configured: world readable when public, owner only
+// when private.
+var DefaultPermissions = Permissions{FilePublic: 0644, FilePrivate: 0600, DirPublic: 0755, DirPrivate: 0700}
+
+// FileMode will convert provided visibility to the permissions of a file. Provider specific visibilities have no
+// unix equivalent and are given private permissions.
+func (p Permissions) FileMode(v filesystem.Visibility) os.FileMode {
+	if v == filesystem.VisibilityPublic {
+		return p.FilePublic
+	}
+	return p.FilePrivate
+}
+
+// DirMode will convert provided visibility to the permissions of a directory. Provider specific visibilities have
+// no unix equivalent and are given private permissions.
+func (p Permissions) DirMode(v filesystem.Visibility) os.FileMode {
+	if v == filesystem.VisibilityPublic {
+		return p.DirPublic
+	}
+	return p.DirPrivate
+}
+
+// FileVisibility will convert the permissions of a file to a visibility. Permissions other than the configured
+// ones are public when world readable.
+func (p Permissions) FileVisibility(mode os.FileMode) filesystem.Visibility {
+	return visibility(mode.Perm(), p.FilePublic, p.FilePrivate)
+}
+
+// DirVisibility will convert the permissions of a directory to a visibility. Permissions other than the
+// configured ones are public when world readable.
+func (p Permissions) DirVisibility(mode os.FileMode) filesystem.Visibility {
+	return visibility(mode.Perm(), p.DirPublic, p.DirPrivate)
+}
+
+func visibility(mode, public, private os.FileMode) filesystem.Visibility {
+	switch {
+	case mode == public.Perm():
+		return filesystem.VisibilityPublic
+	case mode == private.Perm():
+		return filesystem.VisibilityPrivate
+	case mode&0004 != 0:
+		return filesystem.VisibilityPublic
+	default:
+		return filesystem.VisibilityPrivate
+	}
+}
+
+// PermissionsFrom will read the permissions from provided config, returning def when not set.
+func PermissionsFrom(cfg *filesystem.Config, def Permissions) Permissions {
+	if cfg == nil {
+		return def
+	}
+	if p, ok := cfg.Get(ConfigPermissions, nil).(Permissions); ok {
+		return p
+	}
+	return def
+}
+
+// WithPermissions will set the unix permissions of written files and created directories, for adapters storing
+// them on a file system.
+func WithPermissions(p Permissions) filesystem.Option {
+	return filesystem.WithSetting(ConfigPermissions, p)
+}
+
+// ACLs are the ACL strings of public and private files.
+type ACLs struct {
+	Public  string
+	Private string
+}
+
+// S3ACLs are the canned ACLs of Amazon S3.
+var S3ACLs = ACLs{Public: "public-read", Private: "private"}
+
+// ACL will convert provided visibility to an ACL string. Provider specific visibilities are ACL strings already
+// and are returned as they are.
+func (a ACLs) ACL(v filesystem.Visibility) string {
+	switch v {
+	case filesystem.VisibilityPublic:
+		return a.Public
+	case filesystem.VisibilityPrivate:
+		return a.Private
+	}
+	return string(v)
+}
+
+// Visibility will convert provided ACL string to a visibility. ACL strings other than the public and private ones
+// are returned as provider specific visibilities, along with false.
+func (a ACLs) Visibility(acl string) (filesystem.Visibility, bool) {
+	switch acl {
+	case a.Public:
+		return filesystem.VisibilityPublic, true
+	case a.Private:
+		return filesystem.VisibilityPrivate, true
+	}
+	return filesystem.Visibility(acl), false
+}
+
+// ACLsFrom will read the ACLs from provided config, taking the ones not set from def.
+func ACLsFrom(cfg *filesystem.Config, def ACLs) ACLs {
+	if cfg == nil {
+		return def
+	}
+	acls := def
+	switch v := cfg.Get(ConfigACLs, nil).(type) {
+	case ACLs:
+		acls = v
+	case map[string]string:
+		acls.Public, acls.Private = v["public"], v["private"]
+	case map[string]interface{}:
+		acls.Public, _ = v["public"].(string)
+		acls.Private, _ = v["private"].(string)
+	}
+	if acls.Public == "" {
+		acls.Public = def.Public
+	}
+	if acls.Private == "" {
+		acls.Private = def.Private
+	}
+	return acls
+}
+
+// WithACLs will set the ACL strings of written files, for adapters storing them on an object store.
+func WithACLs(acls ACLs) filesystem.Option {
+	return filesystem.WithSetting(ConfigACLs, acls)
+}
@@ -0,0 +1,119 @@
+package filesystem
+
+import (
+	"context"
+	"io"
+	"io/fs"
+)
+
+// SkipDir and SkipAll are the values WalkFunc returns to skip entries, as with filepath.WalkDir.
+var (
+	// SkipDir skips the directory the function was called for or, if called for a file, the remaining entries of
+	// its directory.
+	SkipDir = fs.SkipDir
+	// SkipAll skips every remaining entry.
+	SkipAll = fs.SkipAll
+)
+
+// WalkFunc is the function called by Walk for each entry. Returning an error other than SkipDir or SkipAll stops
+// the walk, the error being returned by Walk.
+type WalkFunc func(md Metadata) error
+
+// WalkOption is an option of Walk.
+type WalkOption func(opts *walkOptions)
+
+type walkOptions struct {
+	ignore *IgnoreRules
+}
+
+// WithWalkIgnore will leave out of the walk the entries matched by provided ignore rules, ignored directories not
+// being descended into.
+func WithWalkIgnore(rules *IgnoreRules) WalkOption {
+	return func(opts *walkOptions) {
+		opts.ignore = rules
+	}
+}
+
+// Walk will walk the tree rooted at path depth first, calling fn for each entry, the root included. Directories are
+// listed one at a time with IterateContents, so that huge trees are never held in memory, and entries are visited
+// in the order the adapter lists them.
+func Walk(ctx context.Context, fsys Interface, path Path, fn WalkFunc, opts ...WalkOption) error {
+	var o walkOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.ignore != nil {
+		next := fn
+		fn = func(md Metadata) error {
+			if o.ignore.Ignored(md.Path, md.IsDir()) {
+				if md.IsDir() {
+					return SkipDir
+				}
+				return nil
+			}
+			return next(md)
+		}
+	}
+	path, err := NewPath(string(path))
+	if err != nil {
+		return err
+	}
+	isDir, err := fsys.DirectoryExists(ctx, path)
+	if err != nil {
+		return err
+	}
+	if !isDir {
+		md, err := fsys.GetMetadata(ctx, path)
+		if err != nil {
+			return err
+		}
+		return skipped(fn(md))
+	}
+	return skipped(walkDir(ctx, fsys, &FileInfo{Path: path, Type: TypeDir, Size: -1}, fn))
+}
+
+// skipped will turn the skip values into a successful walk.
+func skipped(err error) error {
+	if err == SkipDir || err == SkipAll {
+		return nil
+	}
+	return err
+}
+
+// walkDir will call fn for directory dir and walk its content, returning SkipAll if the walk must stop.
+func walkDir(ctx context.Context, fsys Interface, dir Metadata, fn WalkFunc) error {
+	if err := fn(dir); err != nil {
+		if err == SkipDir {
+			return nil
+		}
+		return err
+	}
+	it, err := fsys.IterateContents(ctx, dir.Path, false)
+	if err != nil {
+		return err
+	}
+	defer closeIterator(it)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		md, err := it.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if md.IsDir() {
+			err = walkDir(ctx, fsys, md, fn)
+		} else {
+			err = fn(md)
+		}
+		if err == SkipDir {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
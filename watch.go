@@ -0,0 +1,150 @@
+package filesystem
+
+import (
+	"context"
+	"time"
+)
+
+// EventType is the type of a change of a file.
+type EventType string
+
+// Event types of Event.
+const (
+	EventCreate EventType = "create"
+	EventUpdate EventType = "update"
+	EventDelete EventType = "delete"
+)
+
+// Event is a change of a file observed by Watch.
+type Event struct {
+	// Type is the type of the change.
+	Type EventType
+	// Path is the path of the changed file.
+	Path Path
+	// Metadata is the metadata of the file after the change, or nil for deletes or if adapter does not provide it.
+	Metadata Metadata
+}
+
+// Watcher is implemented by adapters notified of changes by their backend. Watchers send the changes of the files
+// in the directory at supplied path until the context is done, closing the channel then. Decorators may return an
+// UnsupportedOperationError when the adapter they decorate is not a Watcher, so that the directory is polled instead.
+type Watcher interface {
+	Watch(ctx context.Context, path Path, recursive bool) (<-chan Event, error)
+}
+
+// DefaultWatchInterval is the interval between listings of the directories polled by Watch.
+const DefaultWatchInterval = 5 * time.Second
+
+// ConfigWatchInterval is the time.Duration between listings of the directories polled by Watch, set by
+// WithWatchInterval.
+const ConfigWatchInterval = "watch.interval"
+
+// WithWatchInterval will make Watch list the directories it polls every d.
+func WithWatchInterval(d time.Duration) NewOption {
+	return newOption(func(cfg *Config) {
+		cfg.Set(ConfigWatchInterval, d)
+	})
+}
+
+// Watch will watch the files in the directory at supplied path, and in its subdirectories if recursive, sending
+// their changes until cancelled. Watcher adapters are notified natively; others have the directory listed every
+// watch interval, changes being detected from size, timestamp and ETag of files, and listings failing being skipped.
+func (fs *filesystem) Watch(ctx context.Context, path Path, recursive bool) (<-chan Event, context.CancelFunc, error) {
+	path, err := NewPath(string(path))
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	if w, ok := fs.adapter.(Watcher); ok {
+		events, err := w.Watch(ctx, path, recursive)
+		if err == nil {
+			return events, cancel, nil
+		}
+		if !IsUnsupportedOperationError(err) {
+			cancel()
+			return nil, nil, err
+		}
+	}
+	interval, _ := fs.Config().Get(ConfigWatchInterval, DefaultWatchInterval).(time.Duration)
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	snapshot, err := fs.watchSnapshot(ctx, path, recursive)
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	events := make(chan Event)
+	go fs.poll(ctx, path, recursive, interval, snapshot, events)
+	return events, cancel, nil
+}
+
+// poll will list the directory at supplied path every interval, sending the changes from the previous listing.
+func (fs *filesystem) poll(ctx context.Context, path Path, recursive bool, interval time.Duration, snapshot map[Path]Metadata, events chan<- Event) {
+	defer close(events)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		current, err := fs.watchSnapshot(ctx, path, recursive)
+		if err != nil {
+			continue
+		}
+		for _, e := range diffSnapshots(snapshot, current) {
+			select {
+			case events <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+		snapshot = current
+	}
+}
+
+// watchSnapshot will list the files in the directory at supplied path, by path.
+func (fs *filesystem) watchSnapshot(ctx context.Context, path Path, recursive bool) (map[Path]Metadata, error) {
+	contents, err := fs.adapter.ListContents(ctx, path, recursive)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := make(map[Path]Metadata, len(contents))
+	for _, md := range contents {
+		if !md.IsDir() {
+			snapshot[md.Path] = md
+		}
+	}
+	return snapshot, nil
+}
+
+// diffSnapshots will return the changes turning the previous snapshot into the current one.
+func diffSnapshots(previous, current map[Path]Metadata) []Event {
+	var events []Event
+	for p, md := range current {
+		old, ok := previous[p]
+		switch {
+		case !ok:
+			events = append(events, Event{Type: EventCreate, Path: p, Metadata: md})
+		case old.Size != md.Size || !old.Timestamp.Equal(md.Timestamp) || old.ETag != md.ETag:
+			events = append(events, Event{Type: EventUpdate, Path: p, Metadata: md})
+		}
+	}
+	for p := range previous {
+		if _, ok := current[p]; !ok {
+			events = append(events, Event{Type: EventDelete, Path: p})
+		}
+	}
+	return events
+}
+
+// Watch will watch the files in the directory at supplied path.
+func (mm *mountManager) Watch(ctx context.Context, path Path, recursive bool) (<-chan Event, context.CancelFunc, error) {
+	mgr, subPath, err := mm.managerFor(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return mgr.Watch(ctx, subPath, recursive)
+}
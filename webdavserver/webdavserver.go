@@ -0,0 +1,290 @@
+// Package webdavserver exposes a file system as a WebDAV endpoint, built on golang.org/x/net/webdav, so that S3,
+// SFTP or mounted file systems can be mounted in Finder, Explorer or any WebDAV client.
+package webdavserver
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"golang.org/x/net/webdav"
+
+	"github.com/maurofran/filesystem"
+)
+
+// Handler will return a WebDAV handler serving fsys under provided URL prefix, locks being held in memory.
+func Handler(fsys filesystem.Interface, prefix string) http.Handler {
+	return &webdav.Handler{
+		Prefix:     prefix,
+		FileSystem: FileSystem(fsys),
+		LockSystem: webdav.NewMemLS(),
+	}
+}
+
+// FileSystem will adapt fsys to a webdav.FileSystem. Files opened for writing are written back when closed, as
+// filesystem.Open describes.
+func FileSystem(fsys filesystem.Interface) webdav.FileSystem {
+	return &fileSystem{fsys: fsys}
+}
+
+type fileSystem struct {
+	fsys filesystem.Interface
+}
+
+// resolve will convert a WebDAV name to a path.
+func resolve(op, name string) (filesystem.Path, error) {
+	p, err := filesystem.NewPath(strings.TrimPrefix(path.Clean("/"+name), "/"))
+	if err != nil {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return p, nil
+}
+
+// pathError will convert err to the error expected by the webdav package, missing files being fs.ErrNotExist.
+func pathError(op, name string, err error) error {
+	if filesystem.IsFileNotFound(err) {
+		err = fs.ErrNotExist
+	}
+	return &fs.PathError{Op: op, Path: name, Err: err}
+}
+
+// stat will describe the entry at p, which is a directory if it is not a file.
+func (w *fileSystem) stat(ctx context.Context, p filesystem.Path) (filesystem.Metadata, error) {
+	if p == filesystem.RootPath {
+		return &filesystem.FileInfo{Path: p, Type: filesystem.TypeDir}, nil
+	}
+	md, err := w.fsys.GetMetadata(ctx, p)
+	if err == nil || !filesystem.IsFileNotFound(err) {
+		return md, err
+	}
+	ok, err := w.fsys.DirectoryExists(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, filesystem.NewFileNotFoundError(p)
+	}
+	return &filesystem.FileInfo{Path: p, Type: filesystem.TypeDir}, nil
+}
+
+func (w *fileSystem) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	p, err := resolve("mkdir", name)
+	if err != nil {
+		return err
+	}
+	if _, err := w.stat(ctx, p); err == nil {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrExist}
+	}
+	if md, err := w.stat(ctx, p.Dir()); err != nil || !md.IsDir() {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrNotExist}
+	}
+	if err := w.fsys.CreateDir(ctx, p); err != nil {
+		return pathError("mkdir", name, err)
+	}
+	return nil
+}
+
+func (w *fileSystem) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	p, err := resolve("open", name)
+	if err != nil {
+		return nil, err
+	}
+	md, err := w.stat(ctx, p)
+	if err != nil && !filesystem.IsFileNotFound(err) {
+		return nil, pathError("open", name, err)
+	}
+	if err == nil && md.IsDir() {
+		if flag&(os.O_WRONLY|os.O_RDWR) != 0 {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+		}
+		return &dir{fsys: w.fsys, ctx: ctx, name: name, info: fileInfo{md}}, nil
+	}
+	f, err := w.fsys.Open(ctx, p, flag)
+	if err != nil {
+		return nil, pathError("open", name, err)
+	}
+	return &file{File: f, fsys: w.fsys}, nil
+}
+
+func (w *fileSystem) RemoveAll(ctx context.Context, name string) error {
+	p, err := resolve("remove", name)
+	if err != nil {
+		return err
+	}
+	md, err := w.stat(ctx, p)
+	if err != nil {
+		if filesystem.IsFileNotFound(err) {
+			return nil
+		}
+		return pathError("remove", name, err)
+	}
+	if md.IsDir() {
+		err = w.fsys.DeleteDir(ctx, p)
+	} else {
+		_, err = w.fsys.Delete(ctx, p)
+	}
+	if err != nil && !filesystem.IsFileNotFound(err) {
+		return pathError("remove", name, err)
+	}
+	return nil
+}
+
+func (w *fileSystem) Rename(ctx context.Context, oldName, newName string) error {
+	oldPath, err := resolve("rename", oldName)
+	if err != nil {
+		return err
+	}
+	newPath, err := resolve("rename", newName)
+	if err != nil {
+		return err
+	}
+	md, err := w.stat(ctx, oldPath)
+	if err != nil {
+		return pathError("rename", oldName, err)
+	}
+	if md.IsDir() {
+		err = w.fsys.MoveDir(ctx, oldPath, newPath)
+	} else {
+		err = w.fsys.Move(ctx, oldPath, newPath)
+	}
+	if err != nil {
+		return pathError("rename", oldName, err)
+	}
+	return nil
+}
+
+func (w *fileSystem) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	p, err := resolve("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	md, err := w.stat(ctx, p)
+	if err != nil {
+		return nil, pathError("stat", name, err)
+	}
+	return fileInfo{md}, nil
+}
+
+// file is an open file, reporting the ETag and mime type of the file system to the webdav package.
+type file struct {
+	filesystem.File
+	fsys filesystem.Interface
+}
+
+func (f *file) Readdir(count int) ([]fs.FileInfo, error) {
+	return nil, &fs.PathError{Op: "readdir", Path: string(f.Path()), Err: fs.ErrInvalid}
+}
+
+// ETag will return the ETag of the file, if the adapter provides one.
+func (f *file) ETag(ctx context.Context) (string, error) {
+	md, err := f.fsys.GetMetadata(ctx, f.Path())
+	if err != nil || md.ETag == "" {
+		return "", webdav.ErrNotImplemented
+	}
+	return `"` + md.ETag + `"`, nil
+}
+
+// ContentType will return the mime type of the file, if known.
+func (f *file) ContentType(ctx context.Context) (string, error) {
+	mt, err := f.fsys.GetMimeType(ctx, f.Path())
+	if err != nil || mt == "" {
+		return "", webdav.ErrNotImplemented
+	}
+	return mt, nil
+}
+
+// dir is an open directory, whose entries are listed on first Readdir.
+type dir struct {
+	fsys    filesystem.Interface
+	ctx     context.Context
+	name    string
+	info    fileInfo
+	entries []fs.FileInfo
+	listed  bool
+}
+
+func (d *dir) Readdir(count int) ([]fs.FileInfo, error) {
+	if !d.listed {
+		contents, err := d.fsys.ListContents(d.ctx, d.info.md.Path, false)
+		if err != nil {
+			return nil, pathError("readdir", d.name, err)
+		}
+		for _, md := range contents {
+			d.entries = append(d.entries, fileInfo{md})
+		}
+		d.listed = true
+	}
+	if count <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	n := min(count, len(d.entries))
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
+
+func (d *dir) Stat() (fs.FileInfo, error) {
+	return d.info, nil
+}
+
+func (d *dir) Read(p []byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: fs.ErrInvalid}
+}
+
+func (d *dir) Write(p []byte) (int, error) {
+	return 0, &fs.PathError{Op: "write", Path: d.name, Err: fs.ErrInvalid}
+}
+
+func (d *dir) Seek(offset int64, whence int) (int64, error) {
+	return 0, nil
+}
+
+func (d *dir) Close() error {
+	return nil
+}
+
+// fileInfo describes an entry to the webdav package.
+type fileInfo struct {
+	md filesystem.Metadata
+}
+
+func (i fileInfo) Name() string {
+	if i.md.Path == filesystem.RootPath {
+		return "/"
+	}
+	return i.md.Path.Base()
+}
+
+func (i fileInfo) Size() int64 {
+	return max(i.md.Size, 0)
+}
+
+func (i fileInfo) Mode() fs.FileMode {
+	if i.IsDir() {
+		return fs.ModeDir | 0755
+	}
+	return 0644
+}
+
+func (i fileInfo) ModTime() time.Time {
+	return i.md.Timestamp
+}
+
+func (i fileInfo) IsDir() bool {
+	return i.md.IsDir()
+}
+
+// Sys will return the metadata of the entry.
+func (i fileInfo) Sys() interface{} {
+	return i.md
+}
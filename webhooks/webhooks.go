@@ -0,0 +1,243 @@
+// Package webhooks provides an emitter posting the events of file systems to HTTP endpoints, as JSON payloads signed
+// with HMAC-SHA256, so that external systems are notified of storage changes.
+//
+// Each request carries the hexadecimal HMAC-SHA256 of its body, computed with the secret of the endpoint, in the
+// SignatureHeader header, prefixed with "sha256=". Receivers check it with Verify.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/maurofran/filesystem/events"
+	"github.com/maurofran/filesystem/retry"
+)
+
+// Headers set on every request.
+const (
+	SignatureHeader = "X-Filesystem-Signature"
+	EventHeader     = "X-Filesystem-Event"
+)
+
+// DefaultQueueSize is the number of pending events kept when Options do not set one.
+const DefaultQueueSize = 100
+
+// ErrQueueFull is the error reported when an event is dropped because too many are pending.
+var ErrQueueFull = errors.New("webhooks: queue full, event dropped")
+
+// ErrClosed is the error reported when an event is emitted after the emitter was closed.
+var ErrClosed = errors.New("webhooks: emitter closed")
+
+// Endpoint is an HTTP endpoint events are posted to.
+type Endpoint struct {
+	// URL is the URL of the endpoint.
+	URL string
+	// Secret is the key the payloads are signed with.
+	Secret []byte
+	// Types are the types of the events posted to the endpoint, every after event if empty.
+	Types []events.Type
+}
+
+func (ep Endpoint) accepts(t events.Type) bool {
+	if len(ep.Types) == 0 {
+		return t != events.BeforeWrite
+	}
+	for _, et := range ep.Types {
+		if et == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Options configures an emitter.
+type Options struct {
+	// Client sends the requests, http.DefaultClient if nil.
+	Client *http.Client
+	// Retry is the policy deliveries are retried with. Responses with status 429 or 5xx are retried, other
+	// responses that are not 2xx are not.
+	Retry retry.Policy
+	// QueueSize is the number of pending events kept, DefaultQueueSize if not positive. Events are dropped once the
+	// queue is full.
+	QueueSize int
+	// OnError is called with the payloads that could not be delivered to an endpoint, if not nil.
+	OnError func(ep Endpoint, p Payload, err error)
+}
+
+// Payload is the JSON body posted for an event.
+type Payload struct {
+	Type      events.Type `json:"type"`
+	Operation string      `json:"operation"`
+	Path      string      `json:"path"`
+	NewPath   string      `json:"new_path,omitempty"`
+	Size      int64       `json:"size"`
+	Time      time.Time   `json:"time"`
+}
+
+// StatusError is the error of a delivery answered with a status that is not 2xx.
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("webhooks: %s answered with status %d", e.URL, e.StatusCode)
+}
+
+// Temporary reports whether the delivery is worth retrying.
+func (e *StatusError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// Emitter posts events to endpoints from a queue, one at a time, in the background.
+type Emitter struct {
+	endpoints []Endpoint
+	opts      Options
+	queue     chan Payload
+	done      chan struct{}
+	mu        sync.RWMutex
+	closed    bool
+}
+
+// New will create an emitter posting events to provided endpoints, starting its background delivery.
+func New(endpoints []Endpoint, opts Options) *Emitter {
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = DefaultQueueSize
+	}
+	e := &Emitter{
+		endpoints: endpoints,
+		opts:      opts,
+		queue:     make(chan Payload, opts.QueueSize),
+		done:      make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+// Subscribe will subscribe the emitter to the after events of provided bus, returning the function unsubscribing
+// it.
+func (e *Emitter) Subscribe(b *events.Bus) func() {
+	var unsubscribe []func()
+	for _, t := range []events.Type{events.AfterWrite, events.AfterDelete, events.AfterMove} {
+		unsubscribe = append(unsubscribe, b.Subscribe(t, e.Listener))
+	}
+	return func() {
+		for _, fn := range unsubscribe {
+			fn()
+		}
+	}
+}
+
+// Listener will queue provided event for delivery, without waiting for it. It never fails, so that the operation
+// emitting the event goes on; dropped events are reported to OnError.
+func (e *Emitter) Listener(ctx context.Context, ev events.Event) error {
+	p := Payload{
+		Type:      ev.Type,
+		Operation: ev.Operation,
+		Path:      string(ev.Path),
+		NewPath:   string(ev.NewPath),
+		Size:      ev.Size,
+		Time:      time.Now().UTC(),
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closed {
+		e.report(p, ErrClosed)
+		return nil
+	}
+	select {
+	case e.queue <- p:
+	default:
+		e.report(p, ErrQueueFull)
+	}
+	return nil
+}
+
+// Close will stop accepting events, waiting for the pending ones to be delivered.
+func (e *Emitter) Close() error {
+	e.mu.Lock()
+	if !e.closed {
+		e.closed = true
+		close(e.queue)
+	}
+	e.mu.Unlock()
+	<-e.done
+	return nil
+}
+
+func (e *Emitter) run() {
+	defer close(e.done)
+	for p := range e.queue {
+		body, err := json.Marshal(p)
+		if err != nil {
+			e.report(p, err)
+			continue
+		}
+		for _, ep := range e.endpoints {
+			if !ep.accepts(p.Type) {
+				continue
+			}
+			err := e.opts.Retry.Do(context.Background(), func() error {
+				return e.post(ep, p.Type, body)
+			})
+			if err != nil && e.opts.OnError != nil {
+				e.opts.OnError(ep, p, err)
+			}
+		}
+	}
+}
+
+// report will report provided payload as not delivered to any endpoint accepting it.
+func (e *Emitter) report(p Payload, err error) {
+	if e.opts.OnError == nil {
+		return
+	}
+	for _, ep := range e.endpoints {
+		if ep.accepts(p.Type) {
+			e.opts.OnError(ep, p, err)
+		}
+	}
+}
+
+func (e *Emitter) post(ep Endpoint, t events.Type, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, ep.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, string(t))
+	req.Header.Set(SignatureHeader, Sign(ep.Secret, body))
+	resp, err := e.opts.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &StatusError{URL: ep.URL, StatusCode: resp.StatusCode}
+	}
+	return nil
+}
+
+// Sign will compute the signature of provided body with secret, as set in SignatureHeader.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify will check that signature is the one of provided body with secret, in constant time.
+func Verify(secret, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
@@ -0,0 +1,109 @@
+package filesystem
+
+import (
+	"archive/zip"
+	"compress/flate"
+	"context"
+	"io"
+	"strings"
+
+	"github.com/maurofran/filesystem/internal/glob"
+)
+
+// ZipOptions are the options of zip exports.
+type ZipOptions struct {
+	// Include holds the glob patterns of the files to archive, relative to the exported directory, every file if
+	// empty. Patterns follow path.Match syntax segment by segment, "**" matching any number of segments.
+	Include []string
+	// Exclude holds the glob patterns of the files not to archive, taking precedence over Include.
+	Exclude []string
+	// Level is the compression level, from 1 for the fastest to 9 for the best compression, the default level if
+	// zero. Files are stored uncompressed if negative.
+	Level int
+}
+
+// selects will check if the file with provided name, relative to the exported directory, is archived.
+func (opts ZipOptions) selects(name string) bool {
+	for _, pattern := range opts.Exclude {
+		if glob.Match(pattern, name) {
+			return false
+		}
+	}
+	if len(opts.Include) == 0 {
+		return true
+	}
+	for _, pattern := range opts.Include {
+		if glob.Match(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExportZip will stream the directory at path of file system to w as a zip archive, with entry names relative to
+// path. Directories are archived, so that empty ones are kept, unless Include is given or they are excluded.
+func ExportZip(ctx context.Context, fs Interface, path Path, w io.Writer, opts ZipOptions) error {
+	for _, pattern := range append(append([]string(nil), opts.Include...), opts.Exclude...) {
+		if err := glob.Validate(pattern); err != nil {
+			return err
+		}
+	}
+	it, err := fs.IterateContents(ctx, path, true)
+	if err != nil {
+		return err
+	}
+	defer closeIterator(it)
+	zw := zip.NewWriter(w)
+	if opts.Level > 0 {
+		zw.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
+			return flate.NewWriter(out, min(opts.Level, flate.BestCompression))
+		})
+	}
+	for {
+		md, err := it.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		name := strings.TrimPrefix(string(md.Path), string(path)+"/")
+		if path == RootPath {
+			name = string(md.Path)
+		}
+		if md.IsDir() {
+			if len(opts.Include) == 0 && opts.selects(name) {
+				_, err = zw.CreateHeader(&zip.FileHeader{Name: name + "/", Modified: md.Timestamp})
+			}
+		} else if opts.selects(name) {
+			err = exportZipEntry(ctx, fs, md, name, zw, opts)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func exportZipEntry(ctx context.Context, fs Interface, md Metadata, name string, zw *zip.Writer, opts ZipOptions) error {
+	hdr := &zip.FileHeader{Name: name, Modified: md.Timestamp, Method: zip.Deflate}
+	if opts.Level < 0 {
+		hdr.Method = zip.Store
+	}
+	if md.Visibility != "" && md.Visibility != VisibilityPublic {
+		hdr.SetMode(0600)
+	} else {
+		hdr.SetMode(0644)
+	}
+	w, err := zw.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+	rc, err := fs.ReadStream(ctx, md.Path)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	_, err = io.Copy(w, rc)
+	return err
+}